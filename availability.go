@@ -0,0 +1,182 @@
+package hqtt
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hooks"
+	"github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// availabilityWriter is implemented by Component so AvailabilityManager can publish availability for components holding
+// any type of Platform.
+type availabilityWriter interface {
+	writeAvailability(ctx context.Context, w mqtt.Writer, available bool) error
+	AvailabilityWill() (mqtt.Will, error)
+}
+
+func (c *Component[TPlatform]) writeAvailability(ctx context.Context, w mqtt.Writer, available bool) error {
+	if c.Availability == nil {
+		return nil
+	}
+
+	return mqtt.Error(c.Availability.Write(ctx, w, c.TopicPrefix, availabilityValue(c.CustomAvailabilityValues, available)))
+}
+
+// availabilityValue returns the value to publish for the provided availability, preferring custom values if set.
+func availabilityValue(custom hass.CustomAvailability, available bool) hass.Availability {
+	if available {
+		return cmp.Or(custom.Available, hass.Available)
+	}
+
+	return cmp.Or(custom.Unavailable, hass.Unavailable)
+}
+
+// AvailabilityManager publishes the availability of a Device and its components so applications do not have to write
+// it by hand. Start publishes online (and again every time the adapter reconnects to the broker), and Stop publishes
+// offline when the application shuts down gracefully. Register Will with the adapter when connecting so the broker
+// publishes offline if the application crashes or loses its connection.
+//
+// Construct one with NewAvailabilityManager.
+type AvailabilityManager struct {
+	w mqtt.Writer
+
+	device     *Device
+	components map[string]json.MarshalerTo
+
+	mu         sync.Mutex
+	unregister func()
+	stopped    bool
+
+	log *slog.Logger
+}
+
+// NewAvailabilityManager constructs an AvailabilityManager that publishes availability with the provided mqtt.Writer
+// for the provided Device and components. The components map uses the same format as Device.Configure. The Device may
+// be nil if every Component configures its own Availability. Components without their own Availability are skipped,
+// since they inherit the availability of the Device.
+func NewAvailabilityManager(w mqtt.Writer, d *Device, components map[string]json.MarshalerTo) *AvailabilityManager {
+	return &AvailabilityManager{
+		w: w,
+
+		device:     d,
+		components: maps.Clone(components),
+
+		log: log.ForComponent("availability_manager"),
+	}
+}
+
+// Will returns the mqtt.Will to register when connecting to the broker. If the Device configures Availability, its will
+// is used (see Device.AvailabilityWill). Otherwise, the will of the first Component (ordered by key) that configures
+// Availability is used. MQTT only supports one will per connection, so components that do not inherit the
+// availability of the Device should share an Absolute Availability topic. If nothing configures Availability,
+// ErrNoAvailability is returned.
+func (m *AvailabilityManager) Will() (mqtt.Will, error) {
+	if m.device != nil && m.device.Availability != nil {
+		return m.device.AvailabilityWill()
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(m.components)) {
+		if c, ok := m.components[k].(availabilityWriter); ok {
+			if will, err := c.AvailabilityWill(); !errors.Is(err, ErrNoAvailability) {
+				return will, err
+			}
+		}
+	}
+
+	return mqtt.Will{}, ErrNoAvailability
+}
+
+// Start publishes online for the Device and every Component, and registers a hook (see hooks.Hooks.OnConnectionUp)
+// that publishes it again every time the adapter reconnects to the broker, since the broker publishes the Will when the
+// connection drops. Reconnects of other adapters are ignored, unless they do not identify themselves (see
+// hooks.ConnectionEvent.Client). The hook publishes with the provided context until Stop is called. Errors publishing
+// after a reconnect are logged.
+func (m *AvailabilityManager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopped = false
+	if m.unregister == nil {
+		m.unregister = hooks.Register(hooks.Hooks{
+			OnConnectionUp: func(_ context.Context, e hooks.ConnectionEvent) {
+				if !e.Reconnect || (e.Client != nil && e.Client != any(m.w)) {
+					return
+				}
+
+				// Hooks must not block, and publishing waits for the broker to acknowledge the message
+				go func() {
+					m.mu.Lock()
+					defer m.mu.Unlock()
+
+					// Stop may have been called after the hook fired, in which case offline was already published
+					if m.stopped {
+						return
+					}
+
+					if err := m.Online(ctx); err != nil {
+						m.log.With(log.Error(err)).WarnContext(ctx, "Failed to publish availability after reconnecting")
+					}
+				}()
+			},
+		})
+	}
+
+	return m.Online(ctx)
+}
+
+// Stop stops publishing online when an adapter reconnects and publishes offline for the Device and every Component.
+// Call it during graceful shutdown, before disconnecting from the broker.
+func (m *AvailabilityManager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopped = true
+	if m.unregister != nil {
+		m.unregister()
+		m.unregister = nil
+	}
+
+	return m.Offline(ctx)
+}
+
+// Online publishes online for the Device and every Component. Every value is attempted even if others fail.
+func (m *AvailabilityManager) Online(ctx context.Context) error {
+	return m.publish(ctx, true)
+}
+
+// Offline publishes offline for the Device and every Component. Every value is attempted even if others fail.
+func (m *AvailabilityManager) Offline(ctx context.Context) error {
+	return m.publish(ctx, false)
+}
+
+func (m *AvailabilityManager) publish(ctx context.Context, available bool) error {
+	m.log.With(slog.Bool("available", available)).DebugContext(ctx, "Publishing availability")
+
+	var errs []error
+	if m.device != nil && m.device.Availability != nil {
+		a := availabilityValue(m.device.CustomAvailabilityValues, available)
+		if err := mqtt.Error(m.device.Availability.Write(ctx, m.w, m.device.TopicPrefix, a)); err != nil {
+			errs = append(errs, fmt.Errorf("device: %w", err))
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(m.components)) {
+		if c, ok := m.components[k].(availabilityWriter); ok {
+			if err := c.writeAvailability(ctx, m.w, available); err != nil {
+				errs = append(errs, fmt.Errorf("component %s: %w", k, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package hqtt
+
+import (
+	"context"
+	"encoding/json/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hooks"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newAvailabilityComponents() (*Device, map[string]json.MarshalerTo) {
+	d := &Device{
+		Identifiers:  []string{"foo"},
+		TopicPrefix:  "foo",
+		Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+	}
+
+	return d, map[string]json.MarshalerTo{
+		"inherited": &Component[*platform.Sensor[string, any]]{
+			UniqueID:    "inherited",
+			TopicPrefix: "foo/inherited",
+		},
+		"custom": &Component[*platform.Sensor[string, any]]{
+			UniqueID:                 "custom",
+			TopicPrefix:              "foo/custom",
+			Availability:             mqtt.NewValue("available", hass.AvailabilityMarshaler),
+			CustomAvailabilityValues: hass.CustomAvailability{Available: "alive", Unavailable: "dead"},
+		},
+	}
+}
+
+func TestAvailabilityManager(t *testing.T) {
+	w := &hqtttest.Writer{}
+	d, components := newAvailabilityComponents()
+	sut := NewAvailabilityManager(w, d, components)
+
+	require.NoError(t, sut.Start(t.Context()))
+	w.AssertPublished(t, "foo/available", []byte("online"))
+	w.AssertPublished(t, "foo/custom/available", []byte("alive"))
+	w.AssertNotPublished(t, "foo/inherited/available")
+
+	w.Reset()
+	require.NoError(t, sut.Stop(t.Context()))
+	w.AssertPublished(t, "foo/available", []byte("offline"))
+	w.AssertPublished(t, "foo/custom/available", []byte("dead"))
+}
+
+func TestAvailabilityManager_Reconnect(t *testing.T) {
+	w := &hqtttest.Writer{}
+	d, components := newAvailabilityComponents()
+	sut := NewAvailabilityManager(w, d, components)
+
+	require.NoError(t, sut.Start(t.Context()))
+	w.Reset()
+
+	// The first connection is established before Start is called, and other adapters are not managed by sut
+	hooks.ConnectionUp(t.Context(), hooks.ConnectionEvent{Client: w})
+	hooks.ConnectionUp(t.Context(), hooks.ConnectionEvent{Client: &hqtttest.Writer{}, Reconnect: true})
+	hooks.ConnectionUp(t.Context(), hooks.ConnectionEvent{Client: w, Reconnect: true})
+
+	require.Eventually(t, func() bool {
+		m, ok := w.LastWrite("foo/custom/available")
+		return ok && string(m.Payload) == "alive"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, w.Messages(), 2)
+
+	require.NoError(t, sut.Stop(t.Context()))
+	w.Reset()
+
+	hooks.ConnectionUp(t.Context(), hooks.ConnectionEvent{Client: w, Reconnect: true})
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, w.Messages(), "availability should not be published after Stop")
+}
+
+func TestAvailabilityManager_StopAfterReconnect(t *testing.T) {
+	w := &hqtttest.Writer{}
+	d, components := newAvailabilityComponents()
+	sut := NewAvailabilityManager(w, d, components)
+
+	require.NoError(t, sut.Start(t.Context()))
+
+	// The hook publishes from a goroutine that may not run until after Stop, which must not publish online again
+	hooks.ConnectionUp(t.Context(), hooks.ConnectionEvent{Client: w, Reconnect: true})
+	require.NoError(t, sut.Stop(t.Context()))
+	time.Sleep(50 * time.Millisecond)
+
+	m, ok := w.LastWrite("foo/available")
+	require.True(t, ok)
+	assert.Equal(t, "offline", string(m.Payload))
+}
+
+func TestAvailabilityManager_Will(t *testing.T) {
+	t.Run("Device", func(t *testing.T) {
+		d, components := newAvailabilityComponents()
+
+		will, err := NewAvailabilityManager(nil, d, components).Will()
+		require.NoError(t, err)
+		assert.Equal(t, "foo/available", will.Topic)
+		assert.Equal(t, "offline", string(will.Payload))
+	})
+
+	t.Run("Component", func(t *testing.T) {
+		_, components := newAvailabilityComponents()
+
+		will, err := NewAvailabilityManager(nil, nil, components).Will()
+		require.NoError(t, err)
+		assert.Equal(t, "foo/custom/available", will.Topic)
+		assert.Equal(t, "dead", string(will.Payload))
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		_, err := NewAvailabilityManager(nil, nil, map[string]json.MarshalerTo{
+			"inherited": &Component[*platform.Sensor[string, any]]{UniqueID: "inherited"},
+		}).Will()
+		require.ErrorIs(t, err, ErrNoAvailability)
+	})
+}
+
+func TestAvailabilityManager_Broker(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, _, _ := b.Connect(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	d := &Device{
+		Identifiers:  []string{"foo"},
+		TopicPrefix:  "foo",
+		Availability: mqtt.NewValueWithOptions("available", hass.AvailabilityMarshaler, mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}),
+	}
+
+	sut := NewAvailabilityManager(w, d, nil)
+	require.NoError(t, sut.Start(ctx))
+
+	payload, ok := b.Retained("foo/available")
+	require.True(t, ok)
+	assert.Equal(t, "online", string(payload))
+
+	require.NoError(t, sut.Stop(ctx))
+
+	payload, ok = b.Retained("foo/available")
+	require.True(t, ok)
+	assert.Equal(t, "offline", string(payload))
+}
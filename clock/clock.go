@@ -0,0 +1,89 @@
+package clock
+
+import "time"
+
+// Clock tells the time and creates timers and tickers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a Timer that sends the current time on its channel after at least d has elapsed.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a Ticker that sends the current time on its channel every d. It panics if d is not positive.
+	NewTicker(d time.Duration) Ticker
+
+	// AfterFunc waits for d to elapse and then calls f in its own goroutine. The returned Timer can be used to cancel
+	// the call. Its channel is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event, like time.Timer.
+type Timer interface {
+	// C returns the channel the time is delivered on.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. It returns false if the timer already fired or was stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire after d. It returns true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker.
+type Ticker interface {
+	// C returns the channel ticks are delivered on.
+	C() <-chan time.Time
+
+	// Stop turns off the Ticker. No more ticks will be sent.
+	Stop()
+
+	// Reset stops the Ticker and resets its period to d.
+	Reset(d time.Duration)
+}
+
+// Real is the Clock backed by the time package.
+var Real Clock = realClock{}
+
+// Or returns c if it is not nil, and Real otherwise. Types with an optional Clock field use it to default to Real.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -0,0 +1,4 @@
+// Package clock provides an injectable source of time for hqtt. Types with time-based behavior (such as the Watchdog
+// heartbeat, periodic rediscovery, and BinarySensor off_delay emulation) accept a Clock so tests can control time
+// instead of sleeping. hqtttest.Clock provides a fake implementation.
+package clock
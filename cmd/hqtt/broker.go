@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/nlowe/hqtt/mqtt"
+	adapter "github.com/nlowe/hqtt/mqtt/adapter/autopaho"
+)
+
+// passwordEnv is the environment variable commands read the broker password from, so it does not need to be passed on
+// the command line.
+const passwordEnv = "HQTT_PASSWORD"
+
+// brokerFlags holds the flags shared by commands that connect to a broker.
+type brokerFlags struct {
+	broker   *string
+	username *string
+	clientID *string
+}
+
+func addBrokerFlags(fs *flag.FlagSet, command string) *brokerFlags {
+	return &brokerFlags{
+		broker:   fs.String("broker", "mqtt://localhost:1883", "The URL of the MQTT broker"),
+		username: fs.String("username", "", "The username to connect to the broker with. The password is read from $"+passwordEnv),
+		clientID: fs.String("client-id", fmt.Sprintf("hqtt-%s-%d", command, os.Getpid()), "The client ID to connect to the broker with"),
+	}
+}
+
+// dial connects to the configured broker. The connection is closed when ctx is done, or when the returned function is
+// called.
+func (b *brokerFlags) dial(ctx context.Context) (mqtt.Writer, mqtt.Subscriber, *url.URL, func(), error) {
+	u, err := url.Parse(*b.broker)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("broker: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w, s, disconnect, err := adapter.DialMQTT(ctx, autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{u},
+		KeepAlive:                     20,
+		CleanStartOnInitialConnection: true,
+		ConnectUsername:               *b.username,
+		ConnectPassword:               []byte(os.Getenv(passwordEnv)),
+		ClientConfig:                  paho.ClientConfig{ClientID: *b.clientID},
+	})
+	if err != nil {
+		cancel()
+		return nil, nil, nil, nil, fmt.Errorf("connect to %s: %w", u.Redacted(), err)
+	}
+
+	return w, s, u, func() {
+		defer cancel()
+
+		ctx, cancelDisconnect := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancelDisconnect()
+
+		_ = disconnect(ctx)
+	}, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+const checkSummary = "Check that a broker is reachable and optionally that Home Assistant is online"
+
+// Exit codes returned by the check command, in addition to 1 for other errors and 2 for invalid usage.
+const (
+	exitBrokerUnreachable        = 3
+	exitHomeAssistantUnavailable = 4
+)
+
+// runCheck connects to a broker and, if requested, waits for Home Assistant to report that it is online on its status
+// topic. It exits with a distinct status code for each failure so it can be used in readiness probes and deploy scripts.
+func runCheck(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("check", "", checkSummary, stderr)
+	broker := addBrokerFlags(fs, "check")
+	waitForHass := fs.Bool("hass", false, "Also wait for Home Assistant to report that it is online")
+	prefix := fs.String("discovery-prefix", discovery.DefaultPrefix, "The Home Assistant discovery prefix")
+	timeout := fs.Duration("timeout", 10*time.Second, "How long to wait for the broker and Home Assistant")
+
+	usage := fs.Usage
+	fs.Usage = func() {
+		usage()
+		_, _ = fmt.Fprintf(stderr, "\nExit status:\n  0  healthy\n  %d  the broker is unreachable\n  %d  Home Assistant is not online\n",
+			exitBrokerUnreachable, exitHomeAssistantUnavailable)
+	}
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	_, s, u, disconnect, err := broker.dial(ctx)
+	if err != nil {
+		return &exitError{code: exitBrokerUnreachable, err: err}
+	}
+
+	defer disconnect()
+
+	_, _ = fmt.Fprintf(stdout, "Connected to %s\n", u.Redacted())
+	if !*waitForHass {
+		return nil
+	}
+
+	// Watch before subscribing so the retained status is not missed
+	var (
+		once   sync.Once
+		online = make(chan struct{})
+		status = discovery.HomeAssistantAvailability(*prefix)
+	)
+
+	status.Watch(func(a hass.Availability) {
+		if a == hass.Available {
+			once.Do(func() {
+				close(online)
+			})
+		}
+	})
+
+	if err = s.Subscribe(ctx, status, mqtt.Subscription{Topic: status.FullyQualifiedTopic("")}); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	select {
+	case <-online:
+	case <-ctx.Done():
+		err = errors.New("Home Assistant has not published its status")
+		if a, ok := status.Get(); ok {
+			err = fmt.Errorf("Home Assistant is %s", a)
+		}
+
+		return &exitError{code: exitHomeAssistantUnavailable, err: fmt.Errorf("%w after %s", err, *timeout)}
+	}
+
+	_, _ = fmt.Fprintln(stdout, "Home Assistant is online")
+	return nil
+}
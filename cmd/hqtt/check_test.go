@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestCheck(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, _, _ := b.Connect(t)
+
+	check := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+
+		var stdout, stderr bytes.Buffer
+		err := run(t.Context(), append([]string{"check", "-broker", b.URL().String(), "-timeout", "200ms"}, args...), &stdout, &stderr)
+
+		return stdout.String(), err
+	}
+
+	requireExitCode := func(t *testing.T, err error, code int) {
+		t.Helper()
+
+		var exit *exitError
+		require.ErrorAs(t, err, &exit)
+		assert.Equal(t, code, exit.code)
+	}
+
+	t.Run("Broker", func(t *testing.T) {
+		stdout, err := check(t)
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "Connected to mqtt://")
+	})
+
+	t.Run("No Status", func(t *testing.T) {
+		_, err := check(t, "-hass")
+		requireExitCode(t, err, exitHomeAssistantUnavailable)
+		assert.ErrorContains(t, err, "Home Assistant has not published its status after 200ms")
+	})
+
+	t.Run("Offline", func(t *testing.T) {
+		require.NoError(t, w.WriteTopic(t.Context(), "homeassistant/status", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, []byte("offline")))
+
+		_, err := check(t, "-hass")
+		requireExitCode(t, err, exitHomeAssistantUnavailable)
+		assert.ErrorContains(t, err, "Home Assistant is offline")
+	})
+
+	t.Run("Online", func(t *testing.T) {
+		require.NoError(t, w.WriteTopic(t.Context(), "homeassistant/status", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, []byte("online")))
+
+		stdout, err := check(t, "-hass")
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "Home Assistant is online")
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		unreachable := hqtttest.NewBroker(t)
+		unreachable.Close()
+
+		var stdout, stderr bytes.Buffer
+		err := run(t.Context(), []string{"check", "-broker", unreachable.URL().String(), "-timeout", "200ms"}, &stdout, &stderr)
+		requireExitCode(t, err, exitBrokerUnreachable)
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nlowe/hqtt/config"
+	"github.com/nlowe/hqtt/discovery"
+)
+
+const diffSummary = "Compare the discovery payloads on a broker with those for devices in a config file"
+
+// runDiff loads the devices in a config file (see config.LoadFile), reads the retained discovery payload of each device
+// from a broker, and prints how publishing the config would change them (see discovery.Diff), so changes can be
+// previewed before they are rolled out.
+func runDiff(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("diff", "<config>", diffSummary, stderr)
+	broker := addBrokerFlags(fs, "diff")
+	prefix := fs.String("discovery-prefix", discovery.DefaultPrefix, "The Home Assistant discovery prefix")
+	wait := fs.Duration("wait", 2*time.Second, "How long to wait for retained discovery payloads after the last one arrives")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	devices, err := config.LoadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	topics := make([]string, len(devices))
+	rendered := make(map[string][]byte, len(devices))
+	for i, d := range devices {
+		payload, err := d.Device.RenderDiscovery(d.Components)
+		if err != nil {
+			return fmt.Errorf("render %s: %w", d.Device.ID(), err)
+		}
+
+		topics[i] = d.Device.DiscoveryTopic(*prefix)
+		rendered[topics[i]] = payload
+	}
+
+	_, s, _, disconnect, err := broker.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer disconnect()
+
+	retained, err := collectRetained(ctx, s, topics, *wait)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, topic := range topics {
+		current, published := retained[topic]
+
+		changes, err := discovery.Diff(current, rendered[topic])
+		if err != nil {
+			return fmt.Errorf("diff %s: %w", topic, err)
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		changed++
+		if !published {
+			topic += " (not published)"
+		}
+
+		if _, err = fmt.Fprintln(stdout, topic); err != nil {
+			return err
+		}
+
+		for _, c := range changes {
+			if _, err = fmt.Fprintf(stdout, "  %s\n", c); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintf(stderr, "%d of %d device(s) would change\n", changed, len(devices))
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/config"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestDiff(t *testing.T) {
+	const topic = "homeassistant/device/garage-bridge__Garage/config"
+
+	devices, err := config.LoadFile("testdata/garage.yaml")
+	require.NoError(t, err)
+
+	payload, err := devices[0].Device.RenderDiscovery(devices[0].Components)
+	require.NoError(t, err)
+
+	b := hqtttest.NewBroker(t)
+	w, _, _ := b.Connect(t)
+	args := []string{"diff", "-broker", b.URL().String(), "-wait", "100ms", "testdata/garage.yaml"}
+
+	publish := func(payload []byte) {
+		t.Helper()
+
+		require.NoError(t, w.WriteTopic(t.Context(), topic, mqtt.WriteOptions{Retain: true}, payload))
+		require.Eventually(t, func() bool {
+			got, _ := b.Retained(topic)
+			return bytes.Equal(got, payload)
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("Not Published", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		require.NoError(t, run(t.Context(), args, &stdout, &stderr))
+
+		assert.True(t, strings.HasPrefix(stdout.String(), topic+" (not published)\n  + components: "), stdout.String())
+		assert.Contains(t, stderr.String(), "1 of 1 device(s) would change")
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		publish(bytes.Replace(payload, []byte(`"dev_cla":"door"`), []byte(`"dev_cla":"garage_door","ic":"mdi:garage"`), 1))
+
+		var stdout, stderr bytes.Buffer
+		require.NoError(t, run(t.Context(), args, &stdout, &stderr))
+
+		assert.Equal(t, topic+"\n"+
+			`  ~ components.garage.door.device_class: "garage_door" -> "door"`+"\n"+
+			`  - components.garage.door.icon: "mdi:garage"`+"\n",
+			stdout.String())
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		publish(payload)
+
+		var stdout, stderr bytes.Buffer
+		require.NoError(t, run(t.Context(), args, &stdout, &stderr))
+
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, stderr.String(), "0 of 1 device(s) would change")
+	})
+}
@@ -0,0 +1,125 @@
+// Command hqtt provides tools for working with hqtt devices without running a bridge. Run "hqtt help" for a list of
+// subcommands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+)
+
+// errUsage is the error returned when hqtt is invoked incorrectly. The usage has already been printed.
+var errUsage = errors.New("usage")
+
+// exitError is an error that makes hqtt exit with a specific status code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// command is a subcommand of hqtt.
+type command struct {
+	// A one-line description of the command
+	summary string
+	// Runs the command with the provided arguments (not including the command name)
+	run func(ctx context.Context, args []string, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"check":    {summary: checkSummary, run: runCheck},
+	"diff":     {summary: diffSummary, run: runDiff},
+	"purge":    {summary: purgeSummary, run: runPurge},
+	"render":   {summary: renderSummary, run: runRender},
+	"replay":   {summary: replaySummary, run: runReplay},
+	"simulate": {summary: simulateSummary, run: runSimulate},
+	"sniff":    {summary: sniffSummary, run: runSniff},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	switch {
+	case err == nil || errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "hqtt: %v\n", err)
+
+		var exit *exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
+
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return errUsage
+		}
+
+		return nil
+	}
+
+	c, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "hqtt: unknown command %q\n", args[0])
+		usage(stderr)
+		return errUsage
+	}
+
+	return c.run(ctx, args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: hqtt <command> [flags] [args]\n\nCommands:")
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
+
+// newFlagSet constructs a flag.FlagSet for the named command that reports errors instead of exiting.
+func newFlagSet(name, args, summary string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("hqtt "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: %s\n\n%s.\n\nFlags:\n", strings.TrimSpace("hqtt "+name+" [flags] "+args), summary)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parse parses the provided arguments with fs, returning errUsage if they are invalid and flag.ErrHelp if help was
+// requested.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+
+		return errUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/mqtt"
+	adapter "github.com/nlowe/hqtt/mqtt/adapter/autopaho"
+)
+
+const purgeSummary = "List and clear retained messages under one or more topic prefixes"
+
+// runPurge subscribes to every topic under the provided prefixes, collects the retained messages the broker sends in
+// response, and clears them by publishing an empty retained message to each topic. This cleans up discovery payloads,
+// state, and availability left behind by devices that were renamed or removed.
+func runPurge(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("purge", "<prefix> [prefix...]", purgeSummary, stderr)
+	broker := addBrokerFlags(fs, "purge")
+	dryRun := fs.Bool("dry-run", false, "List retained topics without clearing them")
+	wait := fs.Duration("wait", 2*time.Second, "How long to wait for retained messages after the last one arrives")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	prefixes := make([]string, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		// Purging everything on the broker is never what you want, so every prefix must be explicit
+		p := mqtt.TrimTopic(arg)
+		if p == "" || p == mqtt.MultiLevelWildcard {
+			_, _ = fmt.Fprintf(stderr, "hqtt purge: invalid prefix %q\n", arg)
+			fs.Usage()
+			return errUsage
+		}
+
+		prefixes = append(prefixes, p)
+	}
+
+	if len(prefixes) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	w, s, _, disconnect, err := broker.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer disconnect()
+
+	retained, err := collectRetained(ctx, s, prefixes, *wait)
+	if err != nil {
+		return err
+	}
+
+	topics := slices.Sorted(maps.Keys(retained))
+
+	var errs []error
+	for _, topic := range topics {
+		if _, err = fmt.Fprintln(stdout, topic); err != nil {
+			return err
+		}
+
+		if !*dryRun {
+			// An empty retained message removes the retained message for the topic
+			errs = append(errs, w.WriteTopic(ctx, topic, mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, nil))
+		}
+	}
+
+	if err = errors.Join(errs...); err != nil {
+		return fmt.Errorf("clear retained messages: %w", err)
+	}
+
+	if *dryRun {
+		_, _ = fmt.Fprintf(stderr, "Found %d retained topic(s), run without -dry-run to clear them\n", len(topics))
+	} else {
+		_, _ = fmt.Fprintf(stderr, "Cleared %d retained topic(s)\n", len(topics))
+	}
+
+	return nil
+}
+
+// collectRetained subscribes to every topic under the provided prefixes (including the prefixes themselves) and returns
+// the latest payload of every retained message received until no more arrive for the provided duration. Brokers send
+// retained messages with the retain flag set as soon as the subscription is established. Messages other clients publish
+// under the prefixes in the meantime are ignored.
+func collectRetained(ctx context.Context, s mqtt.Subscriber, prefixes []string, wait time.Duration) (map[string][]byte, error) {
+	var (
+		mu       sync.Mutex
+		retained = map[string][]byte{}
+		received = make(chan struct{}, 1)
+	)
+
+	handler := mqtt.HandlerFunc(func(w mqtt.Writer, topic string, payload []byte) {
+		if !adapter.Retained(w) {
+			return
+		}
+
+		mu.Lock()
+		if len(payload) == 0 {
+			// An empty message clears the retained message for the topic
+			delete(retained, topic)
+		} else {
+			retained[topic] = slices.Clone(payload)
+		}
+		mu.Unlock()
+
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+	})
+
+	subscriptions := make([]mqtt.Subscription, len(prefixes))
+	filters := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		filters[i] = mqtt.JoinTopic(p, mqtt.MultiLevelWildcard)
+		subscriptions[i] = mqtt.Subscription{Topic: filters[i]}
+	}
+
+	if err := s.Subscribe(ctx, handler, subscriptions...); err != nil {
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+
+	defer func() {
+		_ = s.Unsubscribe(context.WithoutCancel(ctx), filters...)
+	}()
+
+	idle := time.NewTimer(wait)
+	defer idle.Stop()
+
+collect:
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-received:
+			idle.Reset(wait)
+		case <-idle.C:
+			break collect
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return retained, nil
+}
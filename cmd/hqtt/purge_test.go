@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestPurge(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, _, _ := b.Connect(t)
+
+	for _, topic := range []string{
+		"homeassistant/device/old/config",
+		"hqtt/old/lamp/state",
+		"hqtt/old/lamp/available",
+		"hqtt/older/lamp/state",
+		"hqtt/new/lamp/state",
+	} {
+		require.NoError(t, w.WriteTopic(t.Context(), topic, mqtt.WriteOptions{Retain: true}, []byte("x")))
+	}
+
+	args := []string{"purge", "-broker", b.URL().String(), "-wait", "100ms", "homeassistant/device/old", "/hqtt/old/"}
+	purged := []string{"homeassistant/device/old/config", "hqtt/old/lamp/available", "hqtt/old/lamp/state"}
+
+	t.Run("Dry Run", func(t *testing.T) {
+		// Messages published while collecting are not retained, so they should not be listed or cleared
+		live := time.NewTicker(10 * time.Millisecond)
+		defer live.Stop()
+
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-live.C:
+					_ = w.WriteTopic(t.Context(), "hqtt/old/lamp/live", mqtt.WriteOptions{}, []byte("x"))
+				}
+			}
+		}()
+
+		var stdout, stderr bytes.Buffer
+		err := run(t.Context(), append([]string{args[0], "-dry-run"}, args[1:]...), &stdout, &stderr)
+		close(done)
+		require.NoError(t, err)
+
+		assert.Equal(t, "homeassistant/device/old/config\nhqtt/old/lamp/available\nhqtt/old/lamp/state\n", stdout.String())
+		assert.Contains(t, stderr.String(), "Found 3 retained topic(s)")
+
+		for _, topic := range purged {
+			_, ok := b.Retained(topic)
+			assert.True(t, ok, "%s should not be cleared in a dry run", topic)
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		require.NoError(t, run(t.Context(), args, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "Cleared 3 retained topic(s)")
+
+		require.Eventually(t, func() bool {
+			for _, topic := range purged {
+				if _, ok := b.Retained(topic); ok {
+					return false
+				}
+			}
+
+			return true
+		}, time.Second, 10*time.Millisecond)
+
+		for _, topic := range []string{"hqtt/older/lamp/state", "hqtt/new/lamp/state"} {
+			_, ok := b.Retained(topic)
+			assert.True(t, ok, "%s is not under a purged prefix", topic)
+		}
+	})
+
+	t.Run("Invalid Prefix", func(t *testing.T) {
+		for _, prefix := range []string{"", "/", "#"} {
+			var stdout, stderr bytes.Buffer
+			require.ErrorIs(t, run(t.Context(), []string{"purge", "-broker", b.URL().String(), prefix}, &stdout, &stderr), errUsage)
+			assert.Contains(t, stderr.String(), "invalid prefix")
+		}
+	})
+}
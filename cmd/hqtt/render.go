@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"fmt"
+	"io"
+
+	"github.com/nlowe/hqtt/config"
+	"github.com/nlowe/hqtt/discovery"
+)
+
+const renderSummary = "Print the discovery payloads for devices in a config file"
+
+// rendered is the output of the render command for a single device.
+type rendered struct {
+	// The topic the payload would be published to
+	Topic string `json:"topic"`
+	// The payload exactly as it would be published, with abbreviated keys
+	Abbreviated jsontext.Value `json:"abbreviated"`
+	// The payload with every key expanded to its full name, as Home Assistant interprets it
+	Expanded map[string]any `json:"expanded"`
+}
+
+// runRender loads the devices in a config file (see config.LoadFile) and prints the discovery payload for each of them
+// without connecting to a broker, so payloads can be validated in CI. With -schema, it prints the schema of each device
+// instead (see hqtt.DeviceSchema), so documentation can be generated from the same config.
+func runRender(_ context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("render", "<config>", renderSummary, stderr)
+	prefix := fs.String("discovery-prefix", discovery.DefaultPrefix, "The Home Assistant discovery prefix")
+	compact := fs.Bool("compact", false, "Print one device per line instead of indenting the output")
+	schema := fs.Bool("schema", false, "Print the topics of each device and the schema of their payloads instead of the discovery payload")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	devices, err := config.LoadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	opts := []json.Options{json.Deterministic(true)}
+	if !*compact {
+		opts = append(opts, jsontext.WithIndent("  "))
+	}
+
+	for _, d := range devices {
+		var r any
+		if *schema {
+			s, err := d.Device.Schema(*prefix, d.Components)
+			if err != nil {
+				return fmt.Errorf("schema %s: %w", d.Device.ID(), err)
+			}
+
+			r = s
+		} else {
+			payload, err := d.Device.RenderDiscovery(d.Components)
+			if err != nil {
+				return fmt.Errorf("render %s: %w", d.Device.ID(), err)
+			}
+
+			expanded, err := discovery.Expand(payload)
+			if err != nil {
+				return fmt.Errorf("expand %s: %w", d.Device.ID(), err)
+			}
+
+			r = rendered{Topic: d.Device.DiscoveryTopic(*prefix), Abbreviated: payload, Expanded: expanded}
+		}
+
+		if err = json.MarshalWrite(stdout, r, opts...); err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(stdout, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
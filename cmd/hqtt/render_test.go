@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestRender(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, run(t.Context(), []string{"render", "-compact", "-discovery-prefix", "ha", "testdata/garage.yaml"}, &stdout, &stderr))
+	assert.Empty(t, stderr.String())
+
+	lines := bytes.Split(bytes.TrimSpace(stdout.Bytes()), []byte("\n"))
+	require.Len(t, lines, 1, "compact output should have one line per device")
+
+	var got struct {
+		Topic       string         `json:"topic"`
+		Abbreviated jsontext.Value `json:"abbreviated"`
+		Expanded    map[string]any `json:"expanded"`
+	}
+	require.NoError(t, json.Unmarshal(lines[0], &got))
+
+	assert.Equal(t, "ha/device/garage-bridge__Garage/config", got.Topic)
+	assert.Contains(t, string(got.Abbreviated), `"dev_cla":"door"`, "payload should be printed as published")
+
+	components, ok := got.Expanded["components"].(map[string]any)
+	require.True(t, ok, "expanded payload should have full key names")
+	assert.Equal(t, "°C", components["garage.temperature"].(map[string]any)["unit_of_measurement"])
+}
+
+func TestRender_Schema(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, run(t.Context(), []string{"render", "-schema", "-compact", "testdata/garage.yaml"}, &stdout, &stderr))
+	assert.Empty(t, stderr.String())
+
+	var got hqtt.DeviceSchema
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &got))
+
+	assert.Equal(t, "homeassistant/device/garage-bridge__Garage/config", got.DiscoveryTopic)
+
+	temperature := got.Components["garage.temperature"]
+	assert.Equal(t, "sensor", temperature.Platform)
+	assert.Equal(t, "°C", temperature.Discovery["unit_of_measurement"])
+	require.NotEmpty(t, temperature.Topics)
+
+	state := temperature.Topics[len(temperature.Topics)-1]
+	assert.Equal(t, "State", state.Field)
+	assert.Equal(t, "bridge/garage/garage__temperature/state", state.Topic)
+	assert.Equal(t, mqtt.AuditDirectionPublish, state.Direction)
+
+	// Values of the Sensor embedded in a BinarySensor are included
+	door := got.Components["garage.door"]
+	require.NotEmpty(t, door.Topics)
+	assert.Equal(t, "bridge/garage/garage__door/state", door.Topics[len(door.Topics)-1].Topic)
+}
+
+func TestRender_Errors(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("devices:\n  - name: Broken\n    components:\n      - platform: sensor\n"), 0o600))
+
+	for name, args := range map[string][]string{
+		"No Command":      nil,
+		"Unknown Command": {"frobnicate"},
+		"No Config":       {"render"},
+		"Unknown Flag":    {"render", "-frobnicate", "testdata/garage.yaml"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			require.ErrorIs(t, run(t.Context(), args, &stdout, &stderr), errUsage)
+			assert.Contains(t, stderr.String(), "Usage: hqtt")
+			assert.Empty(t, stdout.String())
+		})
+	}
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		require.Error(t, run(t.Context(), []string{"render", invalid}, &stdout, &stderr))
+		assert.Empty(t, stdout.String())
+	})
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+const replaySummary = "Replay the commands in a recording against a running bridge and compare its responses"
+
+// replayed is a message published by the bridge during a replay.
+type replayed struct {
+	topic   string
+	payload []byte
+}
+
+func (r replayed) String() string {
+	return fmt.Sprintf("%s: %q", r.topic, r.payload)
+}
+
+// runReplay publishes the messages received in a recording written by mqtt.Recorder to a broker with the same timing,
+// so a running bridge receives the same commands it did when the recording was made. Every message the bridge
+// publishes to a topic it published to in the recording is printed and compared with the recording. Messages published
+// before the first received message in the recording are assumed to be published when the bridge starts, and are
+// not compared.
+func runReplay(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("replay", "<recording>", replaySummary, stderr)
+	broker := addBrokerFlags(fs, "replay")
+	speed := fs.Float64("speed", 1, "How fast to replay the recording, 0 publishes every command without waiting")
+	wait := fs.Duration("wait", 2*time.Second, "How long to wait for responses after publishing the last command")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *speed < 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	recording, err := mqtt.ReadRecording(f)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	first := slices.IndexFunc(recording, func(m mqtt.RecordedMessage) bool {
+		return m.Direction == mqtt.AuditDirectionReceive
+	})
+	if first < 0 {
+		return errors.New("the recording does not contain any received messages")
+	}
+
+	var (
+		commands []mqtt.RecordedMessage
+		want     []replayed
+		subs     []mqtt.Subscription
+	)
+
+	for _, m := range recording[first:] {
+		if m.Direction == mqtt.AuditDirectionReceive {
+			commands = append(commands, m)
+			continue
+		}
+
+		want = append(want, replayed{topic: m.Topic, payload: m.Payload})
+		if !slices.ContainsFunc(subs, func(s mqtt.Subscription) bool { return s.Topic == m.Topic }) {
+			// Only messages published in response to the replay are compared, not what the topic currently retains
+			subs = append(subs, mqtt.Subscription{Topic: m.Topic, Options: mqtt.ReadOptions{
+				QoS:            mqtt.QOSAtLeastOnce,
+				NoLocal:        true,
+				RetainHandling: mqtt.RetainHandlingIgnoreRetained,
+			}})
+		}
+	}
+
+	w, s, u, disconnect, err := broker.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer disconnect()
+
+	var (
+		mu  sync.Mutex
+		got []replayed
+	)
+
+	if len(subs) > 0 {
+		err = s.Subscribe(ctx, mqtt.HandlerFunc(func(_ mqtt.Writer, topic string, payload []byte) {
+			m := replayed{topic: topic, payload: bytes.Clone(payload)}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			got = append(got, m)
+			_, _ = fmt.Fprintf(stdout, "< %s\n", m)
+		}), subs...)
+		if err != nil {
+			return fmt.Errorf("subscribe: %w", err)
+		}
+	}
+
+	_, _ = fmt.Fprintf(stderr, "Replaying %d commands on %s\n", len(commands), u.Redacted())
+
+	start := time.Now()
+	for _, m := range commands {
+		if *speed > 0 {
+			due := start.Add(time.Duration(float64(m.Offset-recording[first].Offset) / *speed))
+			if err = sleep(ctx, time.Until(due)); err != nil {
+				return err
+			}
+		}
+
+		mu.Lock()
+		_, _ = fmt.Fprintf(stdout, "> %s\n", replayed{topic: m.Topic, payload: m.Payload})
+		mu.Unlock()
+
+		if err = w.WriteTopic(ctx, m.Topic, mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, m.Payload); err != nil {
+			return fmt.Errorf("publish %s: %w", m.Topic, err)
+		}
+	}
+
+	if err = sleep(ctx, *wait); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range max(len(want), len(got)) {
+		switch {
+		case i >= len(got):
+			return fmt.Errorf("expected %d responses but only received %d, the next should have been %s", len(want), len(got), want[i])
+		case i >= len(want):
+			return fmt.Errorf("expected %d responses but received %d, the first unexpected one was %s", len(want), len(got), got[i])
+		case got[i].topic != want[i].topic || !bytes.Equal(got[i].payload, want[i].payload):
+			return fmt.Errorf("response %d does not match the recording: expected %s, got %s", i+1, want[i], got[i])
+		}
+	}
+
+	_, _ = fmt.Fprintf(stderr, "All %d responses match the recording\n", len(want))
+	return nil
+}
+
+// sleep waits for d, returning early with the cause of the cancellation if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestReplay(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+
+	// A lamp that reports the state it was commanded to, unless it is broken
+	var broken atomic.Bool
+	broken.Store(true)
+
+	w, s, _ := b.Connect(t)
+	require.NoError(t, s.Subscribe(t.Context(), mqtt.HandlerFunc(func(_ mqtt.Writer, _ string, payload []byte) {
+		if broken.Load() && string(payload) == "OFF" {
+			payload = []byte("ON")
+		}
+
+		_ = w.WriteTopic(t.Context(), "lamp/state", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, payload)
+	}), mqtt.Subscription{Topic: "lamp/set", Options: mqtt.ReadOptions{QoS: mqtt.QOSAtLeastOnce}}))
+
+	replay := func(t *testing.T) (string, string, error) {
+		t.Helper()
+
+		var stdout, stderr bytes.Buffer
+		err := run(t.Context(), []string{"replay", "-broker", b.URL().String(), "-speed", "0", "-wait", "200ms", "testdata/lamp.jsonl"}, &stdout, &stderr)
+
+		return stdout.String(), stderr.String(), err
+	}
+
+	t.Run("Broken", func(t *testing.T) {
+		_, _, err := replay(t)
+		require.ErrorContains(t, err, `response 2 does not match the recording: expected lamp/state: "OFF", got lamp/state: "ON"`)
+	})
+
+	t.Run("Fixed", func(t *testing.T) {
+		broken.Store(false)
+
+		stdout, stderr, err := replay(t)
+		require.NoError(t, err)
+		// Without waiting between commands, responses may be printed after the next command
+		for _, line := range []string{`> lamp/set: "ON"`, `< lamp/state: "ON"`, `> lamp/set: "OFF"`, `< lamp/state: "OFF"`} {
+			assert.Contains(t, stdout, line+"\n")
+		}
+
+		assert.Contains(t, stderr, "All 2 responses match the recording")
+	})
+
+	t.Run("No Commands", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "startup.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte(`{"offset":"0s","direction":"publish","topic":"lamp/state","payload":"OFF"}`), 0o644))
+
+		var stdout, stderr bytes.Buffer
+		err := run(t.Context(), []string{"replay", "-broker", b.URL().String(), path}, &stdout, &stderr)
+		require.EqualError(t, err, "the recording does not contain any received messages")
+	})
+}
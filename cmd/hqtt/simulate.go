@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/config"
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+const simulateSummary = "Run the devices in a config file as fake devices controlled from the terminal"
+
+// simulateHelp is printed by the help command of the simulator.
+const simulateHelp = `Commands:
+  list                       Print every entity and its state
+  set <unique id> <state>    Publish a new state for a sensor, binary sensor, or light
+  toggle <unique id>         Flip the state of a binary sensor or light
+  online                     Mark every entity as available
+  offline                    Mark every entity as unavailable
+  help                       Print this help
+  quit                       Mark every entity as unavailable and exit
+`
+
+// stdin is where the simulate command reads commands from.
+var stdin io.Reader = os.Stdin
+
+// simulated is a component of a simulated device that can be controlled from the terminal.
+type simulated struct {
+	platform string
+	// Returns the current state of the entity
+	state func() string
+	// Publishes a new state for the entity
+	set func(ctx context.Context, w mqtt.Writer, state string) error
+	// Publishes the opposite of the current state, nil if the entity does not have an on/off state
+	toggle func(ctx context.Context, w mqtt.Writer) error
+	// Publishes the current state again after Home Assistant restarts
+	republish func(ctx context.Context, w mqtt.Writer) error
+}
+
+// simulator runs the devices in a config file, responding to commands from Home Assistant and from the terminal.
+type simulator struct {
+	w        mqtt.Writer
+	devices  []*config.Device
+	entities map[string]simulated
+
+	mu     sync.Mutex
+	stdout io.Writer
+}
+
+// runSimulate builds the devices in a config file (see config.LoadFile), publishes their discovery payloads, and runs
+// them until ctx is done or the user quits. State is set from commands read from stdin, and commands sent by Home
+// Assistant are printed and applied to the state of the entity, so dashboards and automations can be tested without
+// real hardware.
+func runSimulate(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("simulate", "<config>", simulateSummary, stderr)
+	broker := addBrokerFlags(fs, "simulate")
+	prefix := fs.String("discovery-prefix", discovery.DefaultPrefix, "The Home Assistant discovery prefix")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	devices, err := config.LoadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w, s, u, disconnect, err := broker.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer disconnect()
+
+	sim := &simulator{w: w, devices: devices, entities: map[string]simulated{}, stdout: stdout}
+	for _, d := range devices {
+		sim.add(ctx, d)
+		if err = d.Subscribe(ctx, s); err != nil {
+			return fmt.Errorf("subscribe %s: %w", d.Device.ID(), err)
+		}
+	}
+
+	defer func() {
+		// Leave the entities unavailable rather than showing stale state in Home Assistant
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		_ = sim.availability(shutdownCtx, hass.Unavailable)
+	}()
+
+	hassAvailability := discovery.HomeAssistantAvailability(*prefix)
+	hassAvailability.Watch(func(a hass.Availability) {
+		if a != hass.Available {
+			return
+		}
+
+		// Home Assistant forgets entities that are not retained when it restarts, so announce them again
+		if err := sim.announce(ctx, *prefix); err != nil {
+			sim.printf(stderr, "hqtt simulate: announce: %v\n", err)
+		}
+	})
+
+	if err = s.Subscribe(ctx, hassAvailability, mqtt.Subscription{Topic: hassAvailability.FullyQualifiedTopic("")}); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	if err = sim.announce(ctx, *prefix); err != nil {
+		return err
+	}
+
+	sim.printf(stderr, "Simulating %d entities on %s, type help for a list of commands\n", len(sim.entities), u.Redacted())
+	return sim.run(ctx, stdin, stderr)
+}
+
+// add tracks the components of the provided device, watching the commands of those that accept them.
+func (sim *simulator) add(ctx context.Context, d *config.Device) {
+	for id, c := range d.Sensors {
+		sim.entities[id] = simulated{
+			platform: c.Platform.PlatformName(),
+			state:    stateOf(c.Platform.State),
+			set: func(ctx context.Context, w mqtt.Writer, state string) error {
+				return mqtt.Error(c.Platform.State.Write(ctx, w, c.TopicPrefix, state))
+			},
+			republish: republishState(c, c.Platform.State),
+		}
+	}
+
+	for id, c := range d.BinarySensors {
+		sim.entities[id] = powerState(c, c.Platform.State)
+	}
+
+	for id, c := range d.Lights {
+		sim.entities[id] = powerState(c, c.Platform.State)
+
+		l := c.Platform
+		watchCommand(ctx, sim, id, "state", c.TopicPrefix, l.Optimistic, l.Command, l.State)
+		watchCommand(ctx, sim, id, "brightness", c.TopicPrefix, l.Optimistic, l.BrightnessCommand, l.Brightness)
+		watchCommand(ctx, sim, id, "effect", c.TopicPrefix, l.Optimistic, l.EffectCommand, l.Effect)
+	}
+
+	for id, c := range d.Switches {
+		sim.entities[id] = powerState(c, c.Platform.State)
+		watchCommand(ctx, sim, id, "state", c.TopicPrefix, c.Platform.Optimistic, c.Platform.Command, c.Platform.State)
+	}
+
+	for id, c := range d.Numbers {
+		sim.entities[id] = simulated{
+			platform: c.Platform.PlatformName(),
+			state:    stateOf(c.Platform.State),
+			set: func(ctx context.Context, w mqtt.Writer, state string) error {
+				v, err := strconv.ParseFloat(state, 64)
+				if err != nil {
+					return fmt.Errorf("invalid state %q: %w", state, err)
+				}
+
+				return mqtt.Error(c.Platform.State.Write(ctx, w, c.TopicPrefix, v))
+			},
+			republish: republishState(c, c.Platform.State),
+		}
+
+		watchCommand(ctx, sim, id, "state", c.TopicPrefix, c.Platform.Optimistic, c.Platform.Command, c.Platform.State)
+	}
+
+	for id, c := range d.Selects {
+		sim.entities[id] = simulated{
+			platform: c.Platform.PlatformName(),
+			state:    stateOf(c.Platform.State),
+			set: func(ctx context.Context, w mqtt.Writer, state string) error {
+				if !slices.Contains(c.Platform.Options, state) {
+					return fmt.Errorf("invalid state %q: must be one of %s", state, strings.Join(c.Platform.Options, ", "))
+				}
+
+				return mqtt.Error(c.Platform.State.Write(ctx, w, c.TopicPrefix, state))
+			},
+			republish: republishState(c, c.Platform.State),
+		}
+
+		watchCommand(ctx, sim, id, "state", c.TopicPrefix, c.Platform.Optimistic, c.Platform.Command, c.Platform.State)
+	}
+}
+
+// watchCommand prints every command received for the named field of an entity, and applies it to the corresponding
+// state. Optimistic entities already echo commands to their state (see hqtt.OptimisticPlatform).
+func watchCommand[T any](ctx context.Context, sim *simulator, id, field, prefix string, optimistic bool, command *mqtt.RemoteValue[T], state *mqtt.Value[T]) {
+	if command == nil {
+		return
+	}
+
+	command.Watch(func(v T) {
+		sim.printf(sim.stdout, "%s: received %s command %v\n", id, field, v)
+		if state == nil || optimistic {
+			return
+		}
+
+		if _, err := state.Write(ctx, sim.w, prefix, v); err != nil {
+			sim.printf(sim.stdout, "%s: failed to update %s: %v\n", id, field, err)
+		}
+	})
+}
+
+// powerState constructs the simulated entity for a component with an on/off state.
+func powerState[TPlatform hqtt.Platform](c *hqtt.Component[TPlatform], state *mqtt.Value[hass.PowerState]) simulated {
+	set := func(ctx context.Context, w mqtt.Writer, s hass.PowerState) error {
+		return mqtt.Error(state.Write(ctx, w, c.TopicPrefix, s))
+	}
+
+	return simulated{
+		platform: c.Platform.PlatformName(),
+		state:    stateOf(state),
+		set: func(ctx context.Context, w mqtt.Writer, s string) error {
+			switch p := hass.PowerState(strings.ToUpper(s)); p {
+			case hass.PowerStateOn, hass.PowerStateOff:
+				return set(ctx, w, p)
+			default:
+				return fmt.Errorf("invalid state %q: must be %s or %s", s, hass.PowerStateOn, hass.PowerStateOff)
+			}
+		},
+		toggle: func(ctx context.Context, w mqtt.Writer) error {
+			if current, _ := state.Get(); current == hass.PowerStateOn {
+				return set(ctx, w, hass.PowerStateOff)
+			}
+
+			return set(ctx, w, hass.PowerStateOn)
+		},
+		republish: republishState(c, state),
+	}
+}
+
+// stateOf returns a function that formats the current state of a value, or "unknown" if it has never been written.
+func stateOf[T any](v *mqtt.Value[T]) func() string {
+	return func() string {
+		if current, ok := v.Get(); ok {
+			return fmt.Sprint(current)
+		}
+
+		return "unknown"
+	}
+}
+
+// republishState returns a function that publishes the current state of a value again, if it has been written.
+func republishState[TPlatform hqtt.Platform, T any](c *hqtt.Component[TPlatform], v *mqtt.Value[T]) func(ctx context.Context, w mqtt.Writer) error {
+	return func(ctx context.Context, w mqtt.Writer) error {
+		if _, err := v.Republish(ctx, w, c.TopicPrefix); err != nil && !errors.Is(err, mqtt.ErrNeverWritten) {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// announce publishes the discovery payload of every device, marks every entity as available, and publishes the state
+// of every entity that has one.
+func (sim *simulator) announce(ctx context.Context, discoveryPrefix string) error {
+	var errs []error
+	for _, d := range sim.devices {
+		errs = append(errs, d.Configure(ctx, sim.w, discoveryPrefix))
+	}
+
+	errs = append(errs, sim.availability(ctx, hass.Available))
+	for _, e := range sim.entities {
+		errs = append(errs, e.republish(ctx, sim.w))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (sim *simulator) availability(ctx context.Context, a hass.Availability) error {
+	var errs []error
+	for _, d := range sim.devices {
+		errs = append(errs, d.SetAvailability(ctx, sim.w, a))
+	}
+
+	return errors.Join(errs...)
+}
+
+// run reads commands from r until it is exhausted, the user quits, or ctx is done. Invalid commands are reported to
+// stderr and do not stop the simulator.
+func (sim *simulator) run(ctx context.Context, r io.Reader, stderr io.Writer) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+
+			quit, err := sim.exec(ctx, strings.Fields(line))
+			if err != nil {
+				sim.printf(stderr, "hqtt simulate: %v\n", err)
+			}
+
+			if quit {
+				return nil
+			}
+		}
+	}
+}
+
+// exec runs a single command read from the terminal, returning true if the simulator should exit.
+func (sim *simulator) exec(ctx context.Context, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "list":
+		for _, id := range slices.Sorted(maps.Keys(sim.entities)) {
+			e := sim.entities[id]
+			sim.printf(sim.stdout, "%s (%s): %s\n", id, e.platform, e.state())
+		}
+	case "set":
+		if len(args) < 3 {
+			return false, errors.New("usage: set <unique id> <state>")
+		}
+
+		e, err := sim.entity(args[1])
+		if err != nil {
+			return false, err
+		}
+
+		// Sensor states may contain spaces
+		return false, e.set(ctx, sim.w, strings.Join(args[2:], " "))
+	case "toggle":
+		if len(args) != 2 {
+			return false, errors.New("usage: toggle <unique id>")
+		}
+
+		e, err := sim.entity(args[1])
+		if err != nil {
+			return false, err
+		}
+
+		if e.toggle == nil {
+			return false, fmt.Errorf("%s: a %s cannot be toggled", args[1], e.platform)
+		}
+
+		return false, e.toggle(ctx, sim.w)
+	case "online":
+		return false, sim.availability(ctx, hass.Available)
+	case "offline":
+		return false, sim.availability(ctx, hass.Unavailable)
+	case "help":
+		sim.printf(sim.stdout, "%s", simulateHelp)
+	case "quit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %q, type help for a list of commands", args[0])
+	}
+
+	return false, nil
+}
+
+func (sim *simulator) entity(id string) (simulated, error) {
+	e, ok := sim.entities[id]
+	if !ok {
+		return simulated{}, fmt.Errorf("unknown entity %q", id)
+	}
+
+	return e, nil
+}
+
+// printf writes to the provided writer, serializing output from commands and from Home Assistant.
+func (sim *simulator) printf(w io.Writer, format string, args ...any) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	_, _ = fmt.Fprintf(w, format, args...)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestSimulate(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, _, _ := b.Connect(t)
+
+	r, input := io.Pipe()
+	previous := stdin
+	stdin = r
+	t.Cleanup(func() {
+		stdin = previous
+	})
+
+	var stdout, stderr syncBuffer
+	done := make(chan error)
+	go func() {
+		done <- run(t.Context(), []string{"simulate", "-broker", b.URL().String(), "testdata/desk.yaml"}, &stdout, &stderr)
+	}()
+
+	requireRetained := func(topic, want string) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			got, ok := b.Retained(topic)
+			return ok && string(got) == want
+		}, 5*time.Second, 10*time.Millisecond, "%s should be %q", topic, want)
+	}
+
+	send := func(line string) {
+		t.Helper()
+
+		_, err := io.WriteString(input, line+"\n")
+		require.NoError(t, err)
+	}
+
+	// Commands are only read once the devices are announced
+	send("set desk_temperature 21.5")
+	require.Eventually(t, func() bool {
+		_, ok := b.Retained("homeassistant/device/desk/config")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond, "discovery payload should be published")
+	requireRetained("sim/desk/desk_lamp/available", "online")
+	requireRetained("sim/desk/desk_temperature/state", "21.5")
+
+	send("toggle desk_motion")
+	requireRetained("sim/desk/desk_motion/state", "ON")
+	send("toggle desk_motion")
+	requireRetained("sim/desk/desk_motion/state", "OFF")
+
+	// Commands from Home Assistant are applied to the state of the light
+	require.NoError(t, w.WriteTopic(t.Context(), "sim/desk/desk_lamp/command", mqtt.WriteOptions{}, []byte("ON")))
+	require.NoError(t, w.WriteTopic(t.Context(), "sim/desk/desk_lamp/brightness/set", mqtt.WriteOptions{}, []byte("128")))
+	requireRetained("sim/desk/desk_lamp/state", "ON")
+	requireRetained("sim/desk/desk_lamp/brightness", "128")
+
+	send("set desk_lamp dim")
+	send("toggle desk_temperature")
+	send("list")
+	require.Eventually(t, func() bool {
+		return len(stdout.lines()) == 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	send("quit")
+	require.NoError(t, <-done)
+	requireRetained("sim/desk/desk_lamp/available", "offline")
+
+	assert.Equal(t, []string{
+		"desk_lamp: received state command ON",
+		"desk_lamp: received brightness command 128",
+		"desk_lamp (light): ON",
+		"desk_motion (binary_sensor): OFF",
+		"desk_temperature (sensor): 21.5",
+	}, stdout.lines())
+
+	assert.Contains(t, stderr.buf.String(), `invalid state "dim"`)
+	assert.Contains(t, stderr.buf.String(), "desk_temperature: a sensor cannot be toggled")
+}
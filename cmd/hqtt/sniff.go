@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+const sniffSummary = "Print discovery payloads published to a broker as they appear"
+
+// sniffed is the output of the sniff command for a single discovery message.
+type sniffed struct {
+	// The topic the payload was published to
+	Topic string `json:"topic"`
+	// The payload with every key expanded to its full name, as Home Assistant interprets it
+	Config map[string]any `json:"config,omitempty"`
+	// Whether the message was empty, which removes the device or component from Home Assistant
+	Removed bool `json:"removed,omitempty"`
+	// Why the payload could not be expanded, along with the payload itself
+	Error   string `json:"error,omitempty"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// runSniff subscribes to discovery topics on a broker and prints every discovery payload (retained or new) with its
+// abbreviations expanded until ctx is done.
+func runSniff(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("sniff", "", sniffSummary, stderr)
+	broker := addBrokerFlags(fs, "sniff")
+	prefix := fs.String("discovery-prefix", discovery.DefaultPrefix, "The Home Assistant discovery prefix")
+	compact := fs.Bool("compact", false, "Print one payload per line instead of indenting the output")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	opts := []json.Options{json.Deterministic(true)}
+	if !*compact {
+		opts = append(opts, jsontext.WithIndent("  "))
+	}
+
+	_, s, u, disconnect, err := broker.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer disconnect()
+
+	var (
+		mu       sync.Mutex
+		printErr error
+	)
+
+	handler := mqtt.HandlerFunc(func(_ mqtt.Writer, topic string, payload []byte) {
+		out := sniffed{Topic: topic, Removed: len(payload) == 0}
+		if !out.Removed {
+			config, err := discovery.Expand(payload)
+			if err != nil {
+				out.Error, out.Payload = err.Error(), string(payload)
+			}
+
+			out.Config = config
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		printErr = errors.Join(printErr, json.MarshalWrite(stdout, out, opts...), mqtt.Error(io.WriteString(stdout, "\n")))
+	})
+
+	// Device discovery uses <prefix>/device/<id>/config, and single component discovery uses
+	// <prefix>/<platform>/[<node id>/]<object id>/config
+	err = s.Subscribe(ctx, handler,
+		mqtt.Subscription{Topic: mqtt.JoinTopic(*prefix, "+/+/config")},
+		mqtt.Subscription{Topic: mqtt.JoinTopic(*prefix, "+/+/+/config")},
+	)
+	if err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(stderr, "Watching %s for discovery payloads, press Ctrl+C to stop\n", u.Redacted())
+	<-ctx.Done()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return printErr
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/v2"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return strings.FieldsFunc(s.buf.String(), func(r rune) bool {
+		return r == '\n'
+	})
+}
+
+func TestSniff(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, _, _ := b.Connect(t)
+
+	retained := mqtt.WriteOptions{Retain: true}
+	require.NoError(t, w.WriteTopic(t.Context(), "homeassistant/device/lamp/config", retained, []byte(`{"dev":{"ids":["lamp"]},"o":{"name":"test"},"cmps":{"light":{"p":"light","cmd_t":"~/set","~":"lamp"}}}`)))
+	require.NoError(t, w.WriteTopic(t.Context(), "homeassistant/sensor/node/broken/config", retained, []byte(`{"stat_t":`)))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	var stdout, stderr syncBuffer
+	done := make(chan error)
+	go func() {
+		done <- run(ctx, []string{"sniff", "-compact", "-broker", b.URL().String()}, &stdout, &stderr)
+	}()
+
+	// Retained payloads are printed as soon as the sniffer subscribes
+	require.Eventually(t, func() bool {
+		return len(stdout.lines()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, w.WriteTopic(t.Context(), "homeassistant/status", mqtt.WriteOptions{}, []byte("online")))
+	require.NoError(t, w.WriteTopic(t.Context(), "homeassistant/device/lamp/config", retained, nil))
+
+	require.Eventually(t, func() bool {
+		return len(stdout.lines()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Contains(t, stderr.buf.String(), "Watching mqtt://")
+
+	got := map[string][]sniffed{}
+	for _, line := range stdout.lines() {
+		var s sniffed
+		require.NoError(t, json.Unmarshal([]byte(line), &s))
+		got[s.Topic] = append(got[s.Topic], s)
+	}
+
+	require.NotContains(t, got, "homeassistant/status", "only discovery topics should be printed")
+
+	lamp := got["homeassistant/device/lamp/config"]
+	require.Len(t, lamp, 2)
+	assert.Equal(t, map[string]any{
+		"device":     map[string]any{"identifiers": []any{"lamp"}},
+		"origin":     map[string]any{"name": "test"},
+		"components": map[string]any{"light": map[string]any{"platform": "light", "command_topic": "lamp/set"}},
+	}, lamp[0].Config)
+	assert.Equal(t, sniffed{Topic: "homeassistant/device/lamp/config", Removed: true}, lamp[1])
+
+	broken := got["homeassistant/sensor/node/broken/config"]
+	require.Len(t, broken, 1)
+	assert.NotEmpty(t, broken[0].Error)
+	assert.Equal(t, `{"stat_t":`, broken[0].Payload)
+}
@@ -1,15 +1,20 @@
 package hqtt
 
 import (
+	"cmp"
 	"context"
 	"encoding/json/jsontext"
 	"encoding/json/v2"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/url"
+	"reflect"
 	"strings"
 
 	"github.com/nlowe/hqtt/discovery"
 	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/log"
 	"github.com/nlowe/hqtt/mqtt"
 )
 
@@ -17,6 +22,15 @@ import (
 // Component.Unsubscribe first.
 var ErrComponentAlreadySubscribed = errors.New("component already subscribed")
 
+// ErrNoAvailability is the error returned by Component.AvailabilityWill and Device.AvailabilityWill when Availability
+// is not configured.
+var ErrNoAvailability = errors.New("availability not configured")
+
+var (
+	componentLog      = log.ForComponent("component")
+	componentWarnings log.Limiter
+)
+
 // Component exposes HomeAssistant components (sensors, switches, lights, etc.) associated with a given device. It
 // implements json.MarshalerTo by encoding the component for a Home Assistant Device Discovery payload.
 type Component[TPlatform Platform] struct {
@@ -35,10 +49,13 @@ type Component[TPlatform Platform] struct {
 	// Picture URL for the entity.
 	Picture *url.URL
 
-	// Identifies to home assistant whether this entity is available
-	Availability *mqtt.Value[hass.Availability] `hqtt:"required"`
+	// Identifies to home assistant whether this entity is available. Required unless the Device this Component is
+	// configured with has its own Availability, which is inherited when this is nil.
+	Availability *mqtt.Value[hass.Availability]
 	// Custom values to use for available and unavailable states
 	CustomAvailabilityValues hass.CustomAvailability
+	// Extracts the availability from messages published to Availability
+	AvailabilityTemplate hass.Template
 
 	// Use this value instead of name for automatic generation of the entity ID. For example, `light.foobar`. When used
 	// without a UniqueID, the entity ID will update during restart or reload if the entity ID is available. If the
@@ -57,6 +74,35 @@ type Component[TPlatform Platform] struct {
 	WriteOptions mqtt.WriteOptions
 
 	subscribedTopics []string
+	handler          mqtt.Handler
+}
+
+// availabilityConfigurer is implemented by Component so Device can check whether components that do not configure
+// their own availability can inherit it from the Device.
+type availabilityConfigurer interface {
+	hasAvailability() bool
+}
+
+func (c *Component[TPlatform]) hasAvailability() bool {
+	return c.Availability != nil
+}
+
+// AvailabilityWill returns an mqtt.Will that marks this Component unavailable, using the unavailable value from
+// CustomAvailabilityValues if set. Register it when connecting to the broker (for example with the Will option of the
+// autopaho adapter) so Home Assistant sees the Component go offline if the client drops. If Availability is nil,
+// ErrNoAvailability is returned, use Device.AvailabilityWill for components that inherit their availability.
+//
+// MQTT only supports one will per connection, so components that share a connection should share their availability.
+func (c *Component[TPlatform]) AvailabilityWill() (mqtt.Will, error) {
+	return availabilityWill(c.Availability, c.TopicPrefix, c.CustomAvailabilityValues)
+}
+
+func availabilityWill(availability *mqtt.Value[hass.Availability], prefix string, custom hass.CustomAvailability) (mqtt.Will, error) {
+	if availability == nil {
+		return mqtt.Will{}, ErrNoAvailability
+	}
+
+	return availability.Will(prefix, availabilityValue(custom, false))
 }
 
 func (c *Component[TPlatform]) ForRemoval() RemoveComponent {
@@ -66,10 +112,31 @@ func (c *Component[TPlatform]) ForRemoval() RemoveComponent {
 // Subscribe registers MQTT Subscriptions for fields in use by this Component using the provided
 // mqtt.SubscriptionManager. The subscriptions can be removed by calling Unsubscribe.
 //
-// TODO: Wire LWT to availability.
+// Messages received on topics under TopicPrefix are routed to the Platform with the prefix removed. Messages for values
+// with absolute topics (see mqtt.RemoteValue.Absolute) are routed with their full topic.
+//
+// If the Platform implements OptimisticPlatform, received commands are echoed to their state values after the platform
+// handles them.
+//
+// Subscribing does not register a will for Availability, see AvailabilityWill.
 func (c *Component[TPlatform]) Subscribe(ctx context.Context, s mqtt.Subscriber) error {
+	r, err := c.subscribeRequest()
+	if err != nil {
+		return err
+	}
+
+	if err = s.Subscribe(ctx, r.Handler, r.Subscriptions...); err != nil {
+		c.resetSubscription()
+		return err
+	}
+
+	return nil
+}
+
+// subscribeRequest marks this Component as subscribed and returns the handler and subscriptions to register for it.
+func (c *Component[TPlatform]) subscribeRequest() (mqtt.SubscribeRequest, error) {
 	if len(c.subscribedTopics) != 0 {
-		return ErrComponentAlreadySubscribed
+		return mqtt.SubscribeRequest{}, ErrComponentAlreadySubscribed
 	}
 
 	subscriptions := c.Platform.Subscriptions(c.TopicPrefix)
@@ -78,30 +145,167 @@ func (c *Component[TPlatform]) Subscribe(ctx context.Context, s mqtt.Subscriber)
 		c.subscribedTopics[i] = subscription.Topic
 	}
 
-	return s.Subscribe(ctx, mqtt.HandlerFunc(func(w mqtt.Writer, topic string, payload []byte) {
-		rest, ok := strings.CutPrefix(topic, mqtt.TrimTopic(c.TopicPrefix))
-		if !ok {
-			return
+	c.handler = &componentHandler[TPlatform]{
+		c:        c,
+		absolute: c.absoluteTopics(),
+		prefix:   mqtt.TrimTopic(c.TopicPrefix),
+		echoCtx:  log.WithAttrs(context.Background(), c.logAttrs()...),
+	}
+
+	return mqtt.SubscribeRequest{Handler: c.handler, Subscriptions: subscriptions}, nil
+}
+
+// resetSubscription marks this Component as not subscribed after registering the request returned by subscribeRequest
+// failed.
+func (c *Component[TPlatform]) resetSubscription() {
+	c.subscribedTopics, c.handler = nil, nil
+}
+
+// componentHandler routes messages received for a Component to its Platform. It is registered by pointer so
+// Unsubscribe can release it without releasing other Handlers subscribed to the same topics.
+type componentHandler[TPlatform Platform] struct {
+	c        *Component[TPlatform]
+	absolute map[string]struct{}
+	prefix   string
+	echoCtx  context.Context
+}
+
+func (h *componentHandler[TPlatform]) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	// Values with absolute topics are routed with their full topic, even if it starts with the prefix
+	rest := topic
+	if _, ok := h.absolute[topic]; !ok {
+		rest = relativeTopic(topic, h.prefix)
+	}
+
+	h.c.Platform.ServeMQTT(w, rest, payload)
+
+	if p, ok := any(h.c.Platform).(OptimisticPlatform); ok {
+		if err := p.EchoCommand(h.echoCtx, w, h.c.TopicPrefix, rest); err != nil {
+			componentWarnings.Log(h.echoCtx, componentLog, slog.LevelWarn, h.c.UniqueID, "Failed to echo optimistic command",
+				slog.String("topic", topic), log.Error(err),
+			)
+		}
+	}
+}
+
+// absoluteTopics returns the topics of values with absolute topics (see mqtt.RemoteValue.Absolute) the Platform
+// subscribes to. Unlike other topics, they are the same regardless of the prefix.
+func (c *Component[TPlatform]) absoluteTopics() map[string]struct{} {
+	if mqtt.TrimTopic(c.TopicPrefix) == "" {
+		return nil
+	}
+
+	prefixed := map[string]struct{}{}
+	for _, s := range c.Platform.Subscriptions(c.TopicPrefix) {
+		prefixed[s.Topic] = struct{}{}
+	}
+
+	absolute := map[string]struct{}{}
+	for _, s := range c.Platform.Subscriptions("") {
+		if _, ok := prefixed[s.Topic]; ok {
+			absolute[s.Topic] = struct{}{}
 		}
+	}
 
-		c.Platform.ServeMQTT(w, mqtt.TrimTopic(rest), payload)
-	}), c.Platform.Subscriptions(c.TopicPrefix)...)
+	return absolute
+}
+
+// relativeTopic removes the provided prefix from topic if topic is under it. Topics outside the prefix are returned
+// unmodified.
+func relativeTopic(topic, prefix string) string {
+	if prefix == "" {
+		return mqtt.TrimTopic(topic)
+	}
+
+	if rest, ok := strings.CutPrefix(topic, prefix+mqtt.TopicSeparator); ok {
+		return mqtt.TrimTopic(rest)
+	}
+
+	return topic
+}
+
+func (c *Component[TPlatform]) logAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String(log.UniqueIDKey, c.UniqueID),
+		slog.String(log.PlatformKey, c.Platform.PlatformName()),
+	}
+}
+
+// Logger returns a slog.Logger that includes the unique ID and platform of this Component on every record. Use
+// log.WithAttrs to attribute records logged with a context in the same way.
+func (c *Component[TPlatform]) Logger() *slog.Logger {
+	return slog.New(componentLog.Handler().WithAttrs(c.logAttrs()))
+}
+
+// Stats returns the combined mqtt.TopicStats tracked by the provided mqtt.Stats for every topic under TopicPrefix.
+// Values with absolute topics are not included.
+func (c *Component[TPlatform]) Stats(s *mqtt.Stats) mqtt.TopicStats {
+	return s.Prefix(c.TopicPrefix)
 }
 
 // Unsubscribe removes MQTT Subscriptions for fields in use by this Component from the provided
-// mqtt.SubscriptionManager.
+// mqtt.SubscriptionManager. If it implements mqtt.HandlerUnsubscriber, only the handler registered by Subscribe is
+// released, so other Components subscribed to the same topics keep receiving messages.
 func (c *Component[TPlatform]) Unsubscribe(ctx context.Context, s mqtt.Subscriber) error {
 	if len(c.subscribedTopics) == 0 {
 		return nil
 	}
 
-	topics := c.subscribedTopics
-	c.subscribedTopics = nil
+	topics, handler := c.subscribedTopics, c.handler
+	c.subscribedTopics, c.handler = nil, nil
+
+	return mqtt.UnsubscribeHandler(ctx, s, handler, topics...)
+}
+
+// Close closes every mqtt.RemoteValue of the Platform (see mqtt.RemoteValue.Close) so pending Await calls return
+// mqtt.ErrClosed and watchers are no longer called, then waits until watchers that were already running return or the
+// provided context is done, in which case the cause of the cancellation is returned. It does not unsubscribe, see
+// Unsubscribe. Called from a watcher of this Component, Close blocks until the context is done since that watcher
+// cannot return before Close does.
+func (c *Component[TPlatform]) Close(ctx context.Context) error {
+	return awaitDone(ctx, c.closeValues(nil))
+}
+
+// closeValues closes every mqtt.Closer of the Platform, appending the channels returned by their Done method to
+// existing.
+func (c *Component[TPlatform]) closeValues(existing []<-chan struct{}) []<-chan struct{} {
+	valueFields(reflect.ValueOf(c.Platform), func(_ string, i mqtt.Inspector) {
+		if closer, ok := i.(mqtt.Closer); ok {
+			closer.Close()
+			existing = append(existing, closer.Done())
+		}
+	})
 
-	return s.Unsubscribe(ctx, topics...)
+	return existing
+}
+
+// awaitDone waits until every channel in done is closed, or until the provided context is done.
+func awaitDone(ctx context.Context, done []<-chan struct{}) error {
+	for _, d := range done {
+		select {
+		case <-d:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+
+	return nil
 }
 
 func (c *Component[TPlatform]) MarshalJSONTo(e *jsontext.Encoder) error {
+	return errors.Join(
+		e.WriteToken(jsontext.BeginObject),
+
+		discovery.MarshalStdComparable("platform", e, discovery.FieldPlatform, c.Platform.PlatformName()),
+		c.marshalDiscoveryFields(e),
+
+		e.WriteToken(jsontext.EndObject),
+	)
+}
+
+// marshalDiscoveryFields writes every field of the discovery payload for this Component except the platform, which is
+// part of the topic for component-based discovery.
+func (c *Component[TPlatform]) marshalDiscoveryFields(e *jsontext.Encoder) error {
 	// TODO: Name: Home Assistant docs say "Can be set to `null` if only the device name is relevant." Does this mean
 	//       omitted? The value should be a literal json null? The string "null"?
 	nameToken := jsontext.Null
@@ -110,10 +314,6 @@ func (c *Component[TPlatform]) MarshalJSONTo(e *jsontext.Encoder) error {
 	}
 
 	return errors.Join(
-		e.WriteToken(jsontext.BeginObject),
-
-		discovery.MarshalStdComparable("platform", e, discovery.FieldPlatform, c.Platform.PlatformName()),
-
 		e.WriteToken(jsontext.String("name")),
 		e.WriteToken(nameToken),
 
@@ -121,9 +321,10 @@ func (c *Component[TPlatform]) MarshalJSONTo(e *jsontext.Encoder) error {
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldIcon, c.Icon),
 		discovery.MaybeMarshalStd(e, discovery.FieldPicture, c.Picture),
 
-		discovery.MarshalRequiredValueTopic("availability", e, discovery.FieldAvailabilityTopic, c.Availability, c.TopicPrefix),
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldAvailabilityTopic, c.Availability, c.TopicPrefix),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadAvailable, c.CustomAvailabilityValues.Available),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadNotAvailable, c.CustomAvailabilityValues.Unavailable),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldAvailabilityTemplate, c.AvailabilityTemplate),
 
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldDefaultEntityID, c.DefaultEntityID),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldUniqueID, c.UniqueID),
@@ -131,17 +332,109 @@ func (c *Component[TPlatform]) MarshalJSONTo(e *jsontext.Encoder) error {
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldRetain, c.WriteOptions.Retain),
 
 		c.Platform.MarshalDiscoveryTo(e, c.TopicPrefix),
+	)
+}
 
-		e.WriteToken(jsontext.EndObject),
+// DiscoveryTopic returns the MQTT Topic that the component-based discovery payload for this Component is published to
+// under the specified discovery prefix (see Configure). The ID of the provided Device is used as the node ID, and the
+// sanitized UniqueID as the object ID. If d is nil, the node ID is omitted. If discoveryPrefix is empty,
+// discovery.Prefix is used.
+func (c *Component[TPlatform]) DiscoveryTopic(discoveryPrefix string, d *Device) string {
+	objectID := discovery.SanitizeID(c.UniqueID)
+	if d == nil {
+		return mqtt.JoinTopic(discovery.PrefixOr(discoveryPrefix), c.Platform.PlatformName(), objectID, "config")
+	}
+
+	return mqtt.JoinTopic(discovery.PrefixOr(discoveryPrefix), c.Platform.PlatformName(), d.ID(), objectID, "config")
+}
+
+// RenderDiscovery marshals the component-based discovery payload for this Component without publishing it (see
+// Configure). Like Device.RenderDiscovery, the result is canonicalized. If d is nil, the payload does not include
+// device information and uses DefaultOrigin. If this Component does not configure Availability, it inherits the
+// Availability of the Device, which must be configured. Device.FailFastDiscovery applies to the payload as well.
+func (c *Component[TPlatform]) RenderDiscovery(d *Device) ([]byte, error) {
+	if c.UniqueID == "" {
+		return nil, fmt.Errorf("unique id: %w", discovery.ErrValueRequired)
+	}
+
+	origin := &DefaultOrigin
+	var inherited *Device
+	if d != nil {
+		if err := d.Valid(); err != nil {
+			return nil, err
+		}
+
+		origin = cmp.Or(d.Origin, origin)
+		if c.Availability == nil {
+			inherited = d
+		}
+	}
+
+	if c.Availability == nil && (inherited == nil || inherited.Availability == nil) {
+		return nil, fmt.Errorf("availability: %w", discovery.ErrTopicRequired)
+	}
+
+	return renderDiscovery(nil, d != nil && d.FailFastDiscovery, func(r *discoveryRenderer) {
+		r.field(func(e *jsontext.Encoder) error {
+			return errors.Join(
+				maybeMarshalDevice(e, d),
+				discovery.MarshalStd("origin", e, discovery.FieldOrigin, origin),
+				maybeMarshalInheritedAvailability(e, inherited),
+			)
+		})
+
+		r.field(c.marshalDiscoveryFields)
+	})
+}
+
+func maybeMarshalDevice(e *jsontext.Encoder, d *Device) error {
+	if d == nil {
+		return nil
+	}
+
+	return discovery.MarshalStd("device", e, discovery.FieldDevice, d)
+}
+
+func maybeMarshalInheritedAvailability(e *jsontext.Encoder, d *Device) error {
+	if d == nil {
+		return nil
+	}
+
+	return errors.Join(
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldAvailabilityTopic, d.Availability, d.TopicPrefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadAvailable, d.CustomAvailabilityValues.Available),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadNotAvailable, d.CustomAvailabilityValues.Unavailable),
 	)
 }
 
+// Configure publishes the component-based discovery payload for this Component to its DiscoveryTopic, for applications
+// migrating from the per-entity discovery topics Home Assistant supported before device-based discovery. New
+// applications should use Device.Configure, which configures every component of a device with a single payload. The
+// payload is rendered with RenderDiscovery and retained. If d is nil, the payload does not include device information.
+// If discoveryPrefix is empty, discovery.Prefix is used.
+//
+// To remove the Component from Home Assistant, publish an empty retained payload to its DiscoveryTopic.
+func (c *Component[TPlatform]) Configure(ctx context.Context, w mqtt.Writer, discoveryPrefix string, d *Device) error {
+	ctx = log.WithAttrs(ctx, c.logAttrs()...)
+
+	data, err := c.RenderDiscovery(d)
+	if err != nil {
+		return fmt.Errorf("configure: %w", err)
+	}
+
+	return w.WriteTopic(ctx, c.DiscoveryTopic(discoveryPrefix, d), mqtt.WriteOptions{Retain: true}, data)
+}
+
 // RemoveComponent is used to remove a Component from device discovery. Construct a RemoveComponent with the appropriate
 // platform name manually or use Component.ForRemoval.
 type RemoveComponent struct {
 	Platform string `json:"platform"`
 }
 
+// removeComponent has the same fields as RemoveComponent without its methods so it can be marshaled without recursing
+// into RemoveComponent.MarshalJSONTo.
+type removeComponent RemoveComponent
+
 func (r RemoveComponent) MarshalJSONTo(e *jsontext.Encoder) error {
-	return json.MarshalEncode(e, &r)
+	return json.MarshalEncode(e, (*removeComponent)(&r))
 }
@@ -0,0 +1,154 @@
+package hqtt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newComponentDiscovery() (*Component[*platform.Sensor[string, any]], *Device) {
+	c := &Component[*platform.Sensor[string, any]]{
+		UniqueID:    "Desk Lamp",
+		TopicPrefix: "lamp",
+		Platform:    &platform.Sensor[string, any]{State: mqtt.NewValue("state", mqtt.StringMarshaler)},
+	}
+
+	d := &Device{
+		Name:         "foo",
+		Identifiers:  []string{"foo"},
+		Origin:       &Origin{Name: "test"},
+		TopicPrefix:  "foo",
+		Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+	}
+
+	return c, d
+}
+
+func TestComponent_DiscoveryTopic(t *testing.T) {
+	c, d := newComponentDiscovery()
+
+	assert.Equal(t, "homeassistant/sensor/foo__foo/desk_lamp/config", c.DiscoveryTopic("", d))
+	assert.Equal(t, "custom/sensor/desk_lamp/config", c.DiscoveryTopic("custom", nil))
+}
+
+func TestComponent_RenderDiscovery(t *testing.T) {
+	t.Run("Inherits Availability", func(t *testing.T) {
+		c, d := newComponentDiscovery()
+
+		payload, err := c.RenderDiscovery(d)
+		require.NoError(t, err)
+		assert.Equal(t, `{"avty_t":"foo/available","dev":{"ids":["foo"],"name":"foo"},"name":null,"o":{"name":"test"},"stat_t":"lamp/state","uniq_id":"Desk Lamp"}`, string(payload))
+	})
+
+	t.Run("Without Device", func(t *testing.T) {
+		c, _ := newComponentDiscovery()
+		c.Availability = mqtt.NewValue("available", hass.AvailabilityMarshaler)
+
+		payload, err := c.RenderDiscovery(nil)
+		require.NoError(t, err)
+		hqtttest.RequireField(t, payload, "availability_topic", "lamp/available")
+		hqtttest.RequireField(t, payload, "origin.name", DefaultOrigin.Name)
+		hqtttest.RequireNoField(t, payload, "device")
+		hqtttest.RequireNoField(t, payload, "platform")
+	})
+
+	t.Run("Requires Availability", func(t *testing.T) {
+		c, _ := newComponentDiscovery()
+
+		_, err := c.RenderDiscovery(&Device{Identifiers: []string{"foo"}})
+		require.ErrorIs(t, err, discovery.ErrTopicRequired)
+	})
+
+	t.Run("Fail Fast", func(t *testing.T) {
+		c, d := newComponentDiscovery()
+		c.Platform.State = nil
+		c.Platform.ValueTemplate = "{{ value_json.state | frobnicate }}"
+
+		_, err := c.RenderDiscovery(d)
+		require.ErrorIs(t, err, discovery.ErrTopicRequired)
+		require.ErrorIs(t, err, hass.ErrInvalidTemplate)
+
+		d.FailFastDiscovery = true
+		_, err = c.RenderDiscovery(d)
+		require.ErrorIs(t, err, discovery.ErrTopicRequired)
+		require.NotErrorIs(t, err, hass.ErrInvalidTemplate)
+	})
+
+	t.Run("Requires Unique ID", func(t *testing.T) {
+		c, d := newComponentDiscovery()
+		c.UniqueID = ""
+
+		_, err := c.RenderDiscovery(d)
+		require.ErrorIs(t, err, discovery.ErrValueRequired)
+	})
+}
+
+func TestComponent_Configure(t *testing.T) {
+	c, d := newComponentDiscovery()
+	w := &hqtttest.Writer{}
+
+	require.NoError(t, c.Configure(t.Context(), w, "", d))
+
+	m, ok := w.LastWrite("homeassistant/sensor/foo__foo/desk_lamp/config")
+	require.True(t, ok)
+	assert.True(t, m.Options.Retain)
+	hqtttest.RequireField(t, m.Payload, "state_topic", "lamp/state")
+}
+
+func TestComponent_Subscribe(t *testing.T) {
+	for name, tt := range map[string]struct {
+		command string
+		inject  string
+	}{
+		"Relative":                   {command: "set", inject: "zigbee/lamp/set"},
+		"Absolute Under Prefix":      {command: "zigbee/lamp/set", inject: "zigbee/lamp/set"},
+		"Absolute Sharing Prefix":    {command: "zigbee/lampx/set", inject: "zigbee/lampx/set"},
+		"Absolute Outside Of Prefix": {command: "other/set", inject: "other/set"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			command := mqtt.NewRemoteValue(tt.command, hass.PowerStateUnmarshaler)
+			if name != "Relative" {
+				command.Absolute()
+			}
+
+			c := &Component[*platform.Switch]{
+				UniqueID:    "lamp",
+				TopicPrefix: "zigbee/lamp",
+				Platform:    &platform.Switch{Command: command},
+			}
+
+			s := &hqtttest.Subscriber{}
+			require.NoError(t, c.Subscribe(t.Context(), s))
+			s.AssertSubscribed(t, tt.inject)
+
+			assert.Equal(t, 1, s.Inject(&hqtttest.Writer{}, tt.inject, []byte("ON")))
+			got, ok := command.Get()
+			require.True(t, ok)
+			assert.Equal(t, hass.PowerStateOn, got)
+		})
+	}
+}
+
+func TestComponent_Subscribe_Error(t *testing.T) {
+	c := &Component[*platform.Switch]{
+		UniqueID:    "lamp",
+		TopicPrefix: "zigbee/lamp",
+		Platform:    &platform.Switch{Command: mqtt.NewRemoteValue("set", hass.PowerStateUnmarshaler)},
+	}
+
+	boom := errors.New("boom")
+	require.ErrorIs(t, c.Subscribe(t.Context(), failingSubscriber{err: boom}), boom)
+
+	// A failed subscription can be retried
+	s := &hqtttest.Subscriber{}
+	require.NoError(t, c.Subscribe(t.Context(), s))
+	s.AssertSubscribed(t, "zigbee/lamp/set")
+}
@@ -0,0 +1,358 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+var (
+	// ErrUnknownPlatform is the error returned by File.Build when a component specifies a platform that is not
+	// supported by this package.
+	ErrUnknownPlatform = errors.New("unknown platform")
+	// ErrMissingUniqueID is the error returned by File.Build when a component does not specify a unique ID.
+	ErrMissingUniqueID = errors.New("unique_id is required")
+	// ErrMissingOptions is the error returned by File.Build when a select does not specify its options.
+	ErrMissingOptions = errors.New("options are required")
+	// ErrInvalidQoS is the error returned by File.Build when a component specifies a QoS other than 0, 1, or 2.
+	ErrInvalidQoS = errors.New("qos must be 0, 1, or 2")
+	// ErrDuplicateTopicPrefix is the error returned by File.Build when two components of the same device resolve to the
+	// same topic prefix, for example because their unique IDs only differ by characters that are sanitized away.
+	ErrDuplicateTopicPrefix = errors.New("topic prefix already used by another component")
+)
+
+const (
+	// DefaultAvailabilityTopic is the topic (relative to the component topic prefix) used for availability when a
+	// component does not specify one.
+	DefaultAvailabilityTopic = "available"
+	// DefaultStateTopic is the topic (relative to the component topic prefix) used for state when a component does not
+	// specify one.
+	DefaultStateTopic = "state"
+	// DefaultCommandTopic is the topic (relative to the component topic prefix) used for commands when a component
+	// does not specify one.
+	DefaultCommandTopic = "command"
+)
+
+// File is the root of a declarative device configuration document. For example:
+//
+//	devices:
+//	  - name: Garage
+//	    identifiers: [garage-bridge]
+//	    topic_prefix: bridge/garage
+//	    components:
+//	      - platform: sensor
+//	        unique_id: garage.temperature
+//	        name: Temperature
+//	        device_class: temperature
+//	        unit_of_measurement: °C
+//	      - platform: binary_sensor
+//	        unique_id: garage.door
+//	        name: Door
+//	        device_class: door
+//	      - platform: select
+//	        unique_id: garage.mode
+//	        name: Mode
+//	        options: [home, away]
+//
+// The sensor, binary_sensor, light, switch, number, and select platforms are supported.
+type File struct {
+	Devices []DeviceSpec `yaml:"devices" json:"devices"`
+}
+
+// ConnectionSpec describes a hqtt.DeviceConnection.
+type ConnectionSpec struct {
+	Kind  string `yaml:"kind" json:"kind"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// DeviceSpec describes a hqtt.Device and its components.
+type DeviceSpec struct {
+	// Used for hqtt.Device.DiscoveryID
+	ID string `yaml:"id" json:"id"`
+	// Used for hqtt.Device.StrictID. Also sanitizes the default topic prefix of components with discovery.SanitizeID.
+	StrictID bool `yaml:"strict_id" json:"strict_id"`
+
+	Name             string           `yaml:"name" json:"name"`
+	Serial           string           `yaml:"serial" json:"serial"`
+	Manufacturer     string           `yaml:"manufacturer" json:"manufacturer"`
+	Model            string           `yaml:"model" json:"model"`
+	ModelID          string           `yaml:"model_id" json:"model_id"`
+	ConfigurationURL string           `yaml:"configuration_url" json:"configuration_url"`
+	Connections      []ConnectionSpec `yaml:"connections" json:"connections"`
+	HardwareVersion  string           `yaml:"hw_version" json:"hw_version"`
+	FirmwareVersion  string           `yaml:"sw_version" json:"sw_version"`
+	Identifiers      []string         `yaml:"identifiers" json:"identifiers"`
+	SuggestedArea    string           `yaml:"suggested_area" json:"suggested_area"`
+	ViaDevice        string           `yaml:"via_device" json:"via_device"`
+
+	// The topic prefix shared by all components of this device. Defaults to "hqtt/<device id>".
+	TopicPrefix string `yaml:"topic_prefix" json:"topic_prefix"`
+
+	Components []ComponentSpec `yaml:"components" json:"components"`
+}
+
+// ComponentSpec describes a hqtt.Component. Platform specific fields are ignored by platforms that do not support
+// them. All topics are relative to the component's topic prefix.
+type ComponentSpec struct {
+	Platform string `yaml:"platform" json:"platform"`
+
+	UniqueID        string `yaml:"unique_id" json:"unique_id"`
+	Name            string `yaml:"name" json:"name"`
+	Icon            string `yaml:"icon" json:"icon"`
+	EntityCategory  string `yaml:"entity_category" json:"entity_category"`
+	DefaultEntityID string `yaml:"default_entity_id" json:"default_entity_id"`
+
+	// The topic prefix for this component. Defaults to the unique ID (sanitized, see DeviceSpec.StrictID) under the
+	// device topic prefix.
+	TopicPrefix string `yaml:"topic_prefix" json:"topic_prefix"`
+
+	QoS    mqtt.QualityOfService `yaml:"qos" json:"qos"`
+	Retain bool                  `yaml:"retain" json:"retain"`
+
+	AvailabilityTopic string `yaml:"availability_topic" json:"availability_topic"`
+	StateTopic        string `yaml:"state_topic" json:"state_topic"`
+	CommandTopic      string `yaml:"command_topic" json:"command_topic"`
+	AttributesTopic   string `yaml:"json_attributes_topic" json:"json_attributes_topic"`
+
+	Optimistic bool `yaml:"optimistic" json:"optimistic"`
+
+	// Sensor, BinarySensor, Switch, and Number
+	DeviceClass               string        `yaml:"device_class" json:"device_class"`
+	StateClass                string        `yaml:"state_class" json:"state_class"`
+	UnitOfMeasurement         string        `yaml:"unit_of_measurement" json:"unit_of_measurement"`
+	SuggestedDisplayPrecision uint          `yaml:"suggested_display_precision" json:"suggested_display_precision"`
+	ExpireAfter               time.Duration `yaml:"expire_after" json:"expire_after"`
+	ForceUpdate               bool          `yaml:"force_update" json:"force_update"`
+	OffDelay                  time.Duration `yaml:"off_delay" json:"off_delay"`
+
+	// Sensor (enum) and Select
+	Options []string `yaml:"options" json:"options"`
+
+	// Number
+	Min  *float64 `yaml:"min" json:"min"`
+	Max  *float64 `yaml:"max" json:"max"`
+	Step float64  `yaml:"step" json:"step"`
+	Mode string   `yaml:"mode" json:"mode"`
+
+	// Light
+	BrightnessStateTopic   string   `yaml:"brightness_state_topic" json:"brightness_state_topic"`
+	BrightnessCommandTopic string   `yaml:"brightness_command_topic" json:"brightness_command_topic"`
+	BrightnessScale        uint     `yaml:"brightness_scale" json:"brightness_scale"`
+	Effects                []string `yaml:"effect_list" json:"effect_list"`
+	EffectStateTopic       string   `yaml:"effect_state_topic" json:"effect_state_topic"`
+	EffectCommandTopic     string   `yaml:"effect_command_topic" json:"effect_command_topic"`
+}
+
+func (c ComponentSpec) writeOptions() mqtt.WriteOptions {
+	return mqtt.WriteOptions{QoS: c.QoS, Retain: c.Retain}
+}
+
+func (c ComponentSpec) readOptions() mqtt.ReadOptions {
+	return mqtt.ReadOptions{QoS: c.QoS}
+}
+
+func (c ComponentSpec) topicOr(topic, fallback string) string {
+	if topic != "" {
+		return topic
+	}
+
+	return fallback
+}
+
+// Parse decodes a File from the provided YAML or JSON document. Since YAML is a superset of JSON, both formats are
+// accepted.
+func Parse(data []byte) (*File, error) {
+	var f File
+
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true)
+
+	if err := d.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("config: parse: %w", err)
+	}
+
+	return &f, nil
+}
+
+// Load reads a File from the provided reader and builds it. See Parse and File.Build.
+func Load(r io.Reader) ([]*Device, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("config: read: %w", err)
+	}
+
+	f, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Build()
+}
+
+// LoadFile reads and builds the File at the specified path. See Load.
+func LoadFile(path string) ([]*Device, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return Load(f)
+}
+
+// Build constructs a Device for each DeviceSpec in this File.
+func (f *File) Build() ([]*Device, error) {
+	result := make([]*Device, 0, len(f.Devices))
+	for i, spec := range f.Devices {
+		d, err := spec.Build()
+		if err != nil {
+			return nil, fmt.Errorf("config: device %d: %w", i, err)
+		}
+
+		result = append(result, d)
+	}
+
+	return result, nil
+}
+
+// Build constructs a Device from this DeviceSpec.
+func (s DeviceSpec) Build() (*Device, error) {
+	d := &hqtt.Device{
+		DiscoveryID:     s.ID,
+		StrictID:        s.StrictID,
+		Name:            s.Name,
+		Serial:          s.Serial,
+		Manufacturer:    s.Manufacturer,
+		Model:           s.Model,
+		ModelID:         s.ModelID,
+		HardwareVersion: s.HardwareVersion,
+		FirmwareVersion: s.FirmwareVersion,
+		Identifiers:     s.Identifiers,
+		SuggestedArea:   s.SuggestedArea,
+		ViaDevice:       s.ViaDevice,
+	}
+
+	if s.ConfigurationURL != "" {
+		u, err := url.Parse(s.ConfigurationURL)
+		if err != nil {
+			return nil, fmt.Errorf("configuration_url: %w", err)
+		}
+
+		d.ConfigurationURL = u
+	}
+
+	for _, c := range s.Connections {
+		d.Connections = append(d.Connections, hqtt.DeviceConnection{Kind: c.Kind, Value: c.Value})
+	}
+
+	if err := d.Valid(); err != nil {
+		return nil, err
+	}
+
+	result := &Device{
+		Device:      d,
+		TopicPrefix: s.TopicPrefix,
+		Components:  make(map[string]json.MarshalerTo, len(s.Components)),
+
+		Sensors:       map[string]*hqtt.Component[*platform.Sensor[string, map[string]any]]{},
+		BinarySensors: map[string]*hqtt.Component[*platform.BinarySensor[map[string]any]]{},
+		Lights:        map[string]*hqtt.Component[*platform.Light]{},
+		Switches:      map[string]*hqtt.Component[*platform.Switch]{},
+		Numbers:       map[string]*hqtt.Component[*platform.Number[float64]]{},
+		Selects:       map[string]*hqtt.Component[*platform.Select[string]]{},
+	}
+
+	if result.TopicPrefix == "" {
+		result.TopicPrefix = mqtt.JoinTopic("hqtt", d.ID())
+	}
+
+	sanitize := discovery.IDSanitizer.Replace
+	if s.StrictID {
+		sanitize = discovery.SanitizeID
+	}
+
+	prefixes := make(map[string]string, len(s.Components))
+	for i, c := range s.Components {
+		if c.UniqueID == "" {
+			return nil, fmt.Errorf("component %d: %w", i, ErrMissingUniqueID)
+		}
+
+		if _, ok := result.Components[c.UniqueID]; ok {
+			return nil, fmt.Errorf("component %s: %w", c.UniqueID, hqtt.ErrDuplicateUniqueID)
+		}
+
+		if c.QoS > mqtt.QOSExactlyOnce {
+			return nil, fmt.Errorf("component %s: %w: %d", c.UniqueID, ErrInvalidQoS, c.QoS)
+		}
+
+		build, ok := builders[c.Platform]
+		if !ok {
+			return nil, fmt.Errorf("component %s: %w: %q", c.UniqueID, ErrUnknownPlatform, c.Platform)
+		}
+
+		if c.TopicPrefix == "" {
+			c.TopicPrefix = mqtt.JoinTopic(result.TopicPrefix, sanitize(c.UniqueID))
+		}
+
+		if owner, ok := prefixes[c.TopicPrefix]; ok {
+			return nil, fmt.Errorf("component %s: %s (owned by %s): %w", c.UniqueID, c.TopicPrefix, owner, ErrDuplicateTopicPrefix)
+		}
+
+		prefixes[c.TopicPrefix] = c.UniqueID
+
+		component, err := build(c, result)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", c.UniqueID, err)
+		}
+
+		result.Components[c.UniqueID] = component
+	}
+
+	return result, nil
+}
+
+// newComponent constructs the hqtt.Component fields shared by all platforms and tracks its availability on the provided
+// Device.
+func newComponent[TPlatform hqtt.Platform](c ComponentSpec, d *Device, p TPlatform) *hqtt.Component[TPlatform] {
+	component := &hqtt.Component[TPlatform]{
+		Platform:    p,
+		TopicPrefix: c.TopicPrefix,
+
+		Name:            c.Name,
+		EntityCategory:  c.EntityCategory,
+		Icon:            c.Icon,
+		DefaultEntityID: c.DefaultEntityID,
+		UniqueID:        c.UniqueID,
+
+		Availability: mqtt.NewValueWithOptions(
+			c.topicOr(c.AvailabilityTopic, DefaultAvailabilityTopic),
+			hass.AvailabilityMarshaler,
+			mqtt.WriteOptions{QoS: c.QoS, Retain: true},
+		),
+
+		WriteOptions: c.writeOptions(),
+	}
+
+	d.availability = append(d.availability, func(ctx context.Context, w mqtt.Writer, a hass.Availability) error {
+		return mqtt.Error(component.Availability.Write(ctx, w, component.TopicPrefix, a))
+	})
+
+	return component
+}
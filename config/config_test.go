@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+const testDocument = `
+devices:
+  - name: Garage
+    identifiers: [garage-bridge]
+    topic_prefix: bridge/garage
+    components:
+      - platform: sensor
+        unique_id: garage.temperature
+        name: Temperature
+        device_class: temperature
+        unit_of_measurement: °C
+        expire_after: 5m
+      - platform: binary_sensor
+        unique_id: garage.door
+        name: Door
+        device_class: door
+      - platform: light
+        unique_id: garage.light
+        name: Light
+        optimistic: true
+        brightness_command_topic: brightness/set
+      - platform: switch
+        unique_id: garage.heater
+        name: Heater
+        device_class: outlet
+      - platform: number
+        unique_id: garage.target
+        name: Target
+        min: 5
+        max: 30
+        step: 0.5
+        mode: box
+        unit_of_measurement: °C
+      - platform: select
+        unique_id: garage.mode
+        name: Mode
+        optimistic: true
+        options: [home, away]
+`
+
+type recordingWriter map[string]string
+
+func (r recordingWriter) WriteTopic(_ context.Context, topic string, _ mqtt.WriteOptions, value []byte) error {
+	r[topic] = string(value)
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	devices, err := Load(strings.NewReader(testDocument))
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+
+	sut := devices[0]
+	assert.Equal(t, "bridge/garage", sut.TopicPrefix)
+	assert.Len(t, sut.Components, 6)
+
+	require.Contains(t, sut.Sensors, "garage.temperature")
+	temperature := sut.Sensors["garage.temperature"]
+	assert.Equal(t, "bridge/garage/garage__temperature", temperature.TopicPrefix)
+	assert.Equal(t, "temperature", temperature.Platform.DeviceClass)
+	assert.Equal(t, 5*time.Minute, temperature.Platform.ExpireMeasurementsAfter)
+
+	require.Contains(t, sut.BinarySensors, "garage.door")
+	require.Contains(t, sut.Lights, "garage.light")
+	assert.True(t, sut.Lights["garage.light"].Platform.Optimistic)
+	assert.NotNil(t, sut.Lights["garage.light"].Platform.BrightnessCommand)
+
+	require.Contains(t, sut.Switches, "garage.heater")
+	heater := sut.Switches["garage.heater"]
+	assert.Equal(t, "outlet", heater.Platform.DeviceClass)
+	assert.Equal(t, "bridge/garage/garage__heater/command", heater.Platform.Command.FullyQualifiedTopic(heater.TopicPrefix))
+
+	require.Contains(t, sut.Numbers, "garage.target")
+	target := sut.Numbers["garage.target"].Platform
+	require.NotNil(t, target.Min)
+	require.NotNil(t, target.Max)
+	assert.InDelta(t, 5, *target.Min, 0)
+	assert.InDelta(t, 30, *target.Max, 0)
+	assert.InDelta(t, 0.5, target.Step, 0)
+	assert.Equal(t, platform.NumberModeBox, target.Mode)
+	assert.Equal(t, "°C", target.UnitOfMeasurement)
+
+	require.Contains(t, sut.Selects, "garage.mode")
+	mode := sut.Selects["garage.mode"].Platform
+	assert.True(t, mode.Optimistic)
+	assert.Equal(t, []string{"home", "away"}, mode.Options)
+
+	w := recordingWriter{}
+	require.NoError(t, sut.Configure(t.Context(), w, "homeassistant"))
+	assert.Contains(t, w["homeassistant/device/garage-bridge__Garage/config"], `"garage.temperature":{"avty_t":"bridge/garage/garage__temperature/available"`)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Run("Unknown Platform", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "components": [{"platform": "foo", "unique_id": "bar"}]}]}`))
+		require.ErrorIs(t, err, ErrUnknownPlatform)
+	})
+
+	t.Run("Missing Unique ID", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "components": [{"platform": "sensor"}]}]}`))
+		require.ErrorIs(t, err, ErrMissingUniqueID)
+	})
+
+	t.Run("Select Without Options", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "components": [{"platform": "select", "unique_id": "bar"}]}]}`))
+		require.ErrorIs(t, err, ErrMissingOptions)
+	})
+
+	t.Run("Duplicate Unique ID", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "components": [{"platform": "sensor", "unique_id": "bar"}, {"platform": "switch", "unique_id": "bar"}]}]}`))
+		require.ErrorIs(t, err, hqtt.ErrDuplicateUniqueID)
+	})
+
+	t.Run("Duplicate Topic Prefix", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "components": [{"platform": "sensor", "unique_id": "garage.temp"}, {"platform": "sensor", "unique_id": "garage temp"}]}]}`))
+		require.ErrorIs(t, err, ErrDuplicateTopicPrefix)
+
+		_, err = Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "strict_id": true, "components": [{"platform": "sensor", "unique_id": "garage.temp"}, {"platform": "sensor", "unique_id": "garage_temp"}]}]}`))
+		require.ErrorIs(t, err, ErrDuplicateTopicPrefix)
+	})
+
+	t.Run("Invalid QoS", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "components": [{"platform": "sensor", "unique_id": "bar", "qos": 3}]}]}`))
+		require.ErrorIs(t, err, ErrInvalidQoS)
+	})
+
+	t.Run("Unknown Field", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"devices": [{"identifiers": ["foo"], "foo": "bar"}]}`))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+// Device is a hqtt.Device built from a DeviceSpec, along with its components. Components is suitable for passing to
+// hqtt.Device.Configure. Components are also indexed by unique ID in typed maps for each platform so applications can
+// read commands and write state.
+type Device struct {
+	Device      *hqtt.Device
+	TopicPrefix string
+
+	Components map[string]json.MarshalerTo
+
+	Sensors       map[string]*hqtt.Component[*platform.Sensor[string, map[string]any]]
+	BinarySensors map[string]*hqtt.Component[*platform.BinarySensor[map[string]any]]
+	Lights        map[string]*hqtt.Component[*platform.Light]
+	Switches      map[string]*hqtt.Component[*platform.Switch]
+	Numbers       map[string]*hqtt.Component[*platform.Number[float64]]
+	Selects       map[string]*hqtt.Component[*platform.Select[string]]
+
+	availability []func(ctx context.Context, w mqtt.Writer, a hass.Availability) error
+}
+
+type subscriber interface {
+	Subscribe(ctx context.Context, s mqtt.Subscriber) error
+	Unsubscribe(ctx context.Context, s mqtt.Subscriber) error
+}
+
+// Subscribe calls hqtt.Component.Subscribe for every component of this Device.
+func (d *Device) Subscribe(ctx context.Context, s mqtt.Subscriber) error {
+	var err error
+	for _, c := range d.Components {
+		if sub, ok := c.(subscriber); ok {
+			err = errors.Join(err, sub.Subscribe(ctx, s))
+		}
+	}
+
+	return err
+}
+
+// Unsubscribe calls hqtt.Component.Unsubscribe for every component of this Device.
+func (d *Device) Unsubscribe(ctx context.Context, s mqtt.Subscriber) error {
+	var err error
+	for _, c := range d.Components {
+		if sub, ok := c.(subscriber); ok {
+			err = errors.Join(err, sub.Unsubscribe(ctx, s))
+		}
+	}
+
+	return err
+}
+
+// Configure publishes the discovery payload for this Device. See hqtt.Device.Configure.
+func (d *Device) Configure(ctx context.Context, w mqtt.Writer, discoveryPrefix string) error {
+	return d.Device.Configure(ctx, w, discoveryPrefix, d.Components)
+}
+
+// SetAvailability writes the provided hass.Availability for every component of this Device.
+func (d *Device) SetAvailability(ctx context.Context, w mqtt.Writer, a hass.Availability) error {
+	var err error
+	for _, write := range d.availability {
+		err = errors.Join(err, write(ctx, w, a))
+	}
+
+	return err
+}
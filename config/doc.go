@@ -0,0 +1,3 @@
+// Package config builds hqtt Devices and Components from a declarative YAML or JSON document so simple bridges can
+// define entities without writing per-entity Go code. See File for the document format.
+package config
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/json/v2"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+// builder constructs a component for a platform from the provided ComponentSpec, indexing it on the provided Device.
+type builder func(c ComponentSpec, d *Device) (json.MarshalerTo, error)
+
+var builders = map[string]builder{
+	"sensor":        buildSensor,
+	"binary_sensor": buildBinarySensor,
+	"light":         buildLight,
+	"switch":        buildSwitch,
+	"number":        buildNumber,
+	"select":        buildSelect,
+}
+
+func maybeAttributes(c ComponentSpec) *mqtt.Value[map[string]any] {
+	if c.AttributesTopic == "" {
+		return nil
+	}
+
+	return mqtt.NewValueWithOptions(c.AttributesTopic, mqtt.JsonValueMarshaler[map[string]any](), c.writeOptions())
+}
+
+func buildSensor(c ComponentSpec, d *Device) (json.MarshalerTo, error) {
+	component := newComponent(c, d, &platform.Sensor[string, map[string]any]{
+		DeviceClass:               c.DeviceClass,
+		ExpireMeasurementsAfter:   c.ExpireAfter,
+		ForceUpdate:               c.ForceUpdate,
+		Attributes:                maybeAttributes(c),
+		EnumOptions:               c.Options,
+		SuggestedDisplayPrecision: c.SuggestedDisplayPrecision,
+		StateClass:                hass.StateClass(c.StateClass),
+		State:                     mqtt.NewValueWithOptions(c.topicOr(c.StateTopic, DefaultStateTopic), mqtt.StringMarshaler, c.writeOptions()),
+		UnitOfMeasurement:         c.UnitOfMeasurement,
+	})
+
+	d.Sensors[c.UniqueID] = component
+	return component, nil
+}
+
+func buildBinarySensor(c ComponentSpec, d *Device) (json.MarshalerTo, error) {
+	p := platform.NewBinarySensor(
+		mqtt.NewValueWithOptions(c.topicOr(c.StateTopic, DefaultStateTopic), hass.PowerStateMarshaler, c.writeOptions()),
+		maybeAttributes(c),
+	)
+	p.DeviceClass = c.DeviceClass
+	p.ExpireMeasurementsAfter = c.ExpireAfter
+	p.ForceUpdate = c.ForceUpdate
+	p.OffDelay = c.OffDelay
+
+	component := newComponent(c, d, p)
+
+	d.BinarySensors[c.UniqueID] = component
+	return component, nil
+}
+
+func buildLight(c ComponentSpec, d *Device) (json.MarshalerTo, error) {
+	p := &platform.Light{
+		Optimistic: c.Optimistic,
+
+		State:   mqtt.NewValueWithOptions(c.topicOr(c.StateTopic, DefaultStateTopic), hass.PowerStateMarshaler, c.writeOptions()),
+		Command: mqtt.NewRemoteValueWithOptions(c.topicOr(c.CommandTopic, DefaultCommandTopic), hass.PowerStateUnmarshaler, c.readOptions()),
+
+		BrightnessScale: c.BrightnessScale,
+		PossibleEffects: c.Effects,
+	}
+
+	if c.BrightnessStateTopic != "" || c.BrightnessCommandTopic != "" {
+		p.Brightness = mqtt.NewValueWithOptions(c.topicOr(c.BrightnessStateTopic, "brightness"), mqtt.UintMarshaler, c.writeOptions())
+		p.BrightnessCommand = mqtt.NewRemoteValueWithOptions(c.topicOr(c.BrightnessCommandTopic, "brightness/set"), mqtt.UintUnmarshaler, c.readOptions())
+	}
+
+	if c.EffectStateTopic != "" || c.EffectCommandTopic != "" || len(c.Effects) > 0 {
+		p.Effect = mqtt.NewValueWithOptions(c.topicOr(c.EffectStateTopic, "effect"), mqtt.StringMarshaler, c.writeOptions())
+		p.EffectCommand = mqtt.NewRemoteValueWithOptions(c.topicOr(c.EffectCommandTopic, "effect/set"), mqtt.StringUnmarshaler, c.readOptions())
+	}
+
+	component := newComponent(c, d, p)
+
+	d.Lights[c.UniqueID] = component
+	return component, nil
+}
+
+func buildSwitch(c ComponentSpec, d *Device) (json.MarshalerTo, error) {
+	component := newComponent(c, d, &platform.Switch{
+		Optimistic:  c.Optimistic,
+		DeviceClass: c.DeviceClass,
+
+		State:   mqtt.NewValueWithOptions(c.topicOr(c.StateTopic, DefaultStateTopic), hass.PowerStateMarshaler, c.writeOptions()),
+		Command: mqtt.NewRemoteValueWithOptions(c.topicOr(c.CommandTopic, DefaultCommandTopic), hass.PowerStateUnmarshaler, c.readOptions()),
+	})
+
+	d.Switches[c.UniqueID] = component
+	return component, nil
+}
+
+func buildNumber(c ComponentSpec, d *Device) (json.MarshalerTo, error) {
+	component := newComponent(c, d, &platform.Number[float64]{
+		Optimistic:  c.Optimistic,
+		DeviceClass: c.DeviceClass,
+
+		State:   mqtt.NewValueWithOptions(c.topicOr(c.StateTopic, DefaultStateTopic), mqtt.FloatMarshaler, c.writeOptions()),
+		Command: mqtt.NewRemoteValueWithOptions(c.topicOr(c.CommandTopic, DefaultCommandTopic), mqtt.FloatUnmarshaler, c.readOptions()),
+
+		Min:               c.Min,
+		Max:               c.Max,
+		Step:              c.Step,
+		Mode:              platform.NumberMode(c.Mode),
+		UnitOfMeasurement: c.UnitOfMeasurement,
+	})
+
+	d.Numbers[c.UniqueID] = component
+	return component, nil
+}
+
+func buildSelect(c ComponentSpec, d *Device) (json.MarshalerTo, error) {
+	if len(c.Options) == 0 {
+		return nil, ErrMissingOptions
+	}
+
+	component := newComponent(c, d, &platform.Select[string]{
+		Optimistic: c.Optimistic,
+
+		State:   mqtt.NewValueWithOptions(c.topicOr(c.StateTopic, DefaultStateTopic), mqtt.StringMarshaler, c.writeOptions()),
+		Command: mqtt.NewRemoteValueWithOptions(c.topicOr(c.CommandTopic, DefaultCommandTopic), mqtt.StringUnmarshaler, c.readOptions()),
+
+		Options: c.Options,
+	})
+
+	d.Selects[c.UniqueID] = component
+	return component, nil
+}
@@ -1,7 +1,6 @@
 package hqtt
 
 import (
-	"bytes"
 	"cmp"
 	"context"
 	"encoding/json/jsontext"
@@ -13,12 +12,17 @@ import (
 	"strings"
 
 	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hooks"
+	"github.com/nlowe/hqtt/log"
 	"github.com/nlowe/hqtt/mqtt"
 )
 
 // ErrInvalidDevice is the error returned by Device.Configure and Device.Valid if it is not properly configured.
 var ErrInvalidDevice = errors.New("device must have at least one identifying value in 'identifiers' and/or 'connections'")
 
+var deviceLog = log.ForComponent("device")
+
 // DeviceConnection maps this Device to the outside world. For example:
 //
 //	DeviceConnection{
@@ -105,16 +109,41 @@ type Device struct {
 	// Identifier of a device that routes messages between this device and Home Assistant. Examples of such devices are
 	// hubs, or parent devices of a sub-device. This is used to show device topology in Home Assistant.
 	ViaDevice string `json:"via_device,omitempty"`
+
+	// The prefix for topics of values owned by the Device itself (e.g. Availability).
+	TopicPrefix string `json:"-"`
+
+	// Identifies to Home Assistant whether the entities provided by this device are available. Components that do not
+	// configure their own Availability inherit this value. If nil, every Component must configure its own Availability.
+	Availability *mqtt.Value[hass.Availability] `json:"-"`
+	// Custom values to use for available and unavailable states of Availability
+	CustomAvailabilityValues hass.CustomAvailability `json:"-"`
+
+	// If set, rendering the discovery payload stops at the first invalid component or field and only that error is
+	// returned. Otherwise, every invalid field is reported.
+	FailFastDiscovery bool `json:"-"`
+
+	// If set, ID sanitizes the fields it is calculated from with discovery.SanitizeID, which produces IDs Home
+	// Assistant accepts for every input. Otherwise, discovery.IDSanitizer is used so the IDs of existing devices do not
+	// change. Setting it on an existing device changes its DiscoveryTopic, so Remove the device with StrictID unset
+	// first, or Home Assistant keeps the old device around.
+	StrictID bool `json:"-"`
 }
 
 // ID calculates an identifier for this device. If the Device.DiscoveryID is specified, that value will be used.
-// Otherwise, if any of the following fields are set, they are used (separated by discovery.IDSep): All
-// Device.Identifiers, Device.Name, Device.Serial, Device.Manufacturer, Device.Model, and Device.ModelID.
+// Otherwise, if any of the following fields are set, they are sanitized (see Device.StrictID) and used (separated by
+// discovery.IDSep): All Device.Identifiers, Device.Name, Device.Serial, Device.Manufacturer, Device.Model, and
+// Device.ModelID.
 func (d *Device) ID() string {
 	if d.DiscoveryID != "" {
 		return d.DiscoveryID
 	}
 
+	sanitize := discovery.IDSanitizer.Replace
+	if d.StrictID {
+		sanitize = discovery.SanitizeID
+	}
+
 	var result strings.Builder
 
 	writeSep := func() {
@@ -125,7 +154,7 @@ func (d *Device) ID() string {
 
 	if len(d.Identifiers) > 0 {
 		for i, ident := range d.Identifiers {
-			result.WriteString(discovery.IDSanitizer.Replace(ident))
+			result.WriteString(sanitize(ident))
 			if i < len(d.Identifiers)-1 {
 				writeSep()
 			}
@@ -134,27 +163,27 @@ func (d *Device) ID() string {
 
 	if d.Name != "" {
 		writeSep()
-		result.WriteString(discovery.IDSanitizer.Replace(d.Name))
+		result.WriteString(sanitize(d.Name))
 	}
 
 	if d.Serial != "" {
 		writeSep()
-		result.WriteString(discovery.IDSanitizer.Replace(d.Serial))
+		result.WriteString(sanitize(d.Serial))
 	}
 
 	if d.Manufacturer != "" {
 		writeSep()
-		result.WriteString(discovery.IDSanitizer.Replace(d.Manufacturer))
+		result.WriteString(sanitize(d.Manufacturer))
 	}
 
 	if d.Model != "" {
 		writeSep()
-		result.WriteString(discovery.IDSanitizer.Replace(d.Model))
+		result.WriteString(sanitize(d.Model))
 	}
 
 	if d.ModelID != "" {
 		writeSep()
-		result.WriteString(discovery.IDSanitizer.Replace(d.ModelID))
+		result.WriteString(sanitize(d.ModelID))
 	}
 
 	return result.String()
@@ -170,45 +199,111 @@ func (d *Device) Valid() error {
 	return nil
 }
 
-// Configure updates the device discovery payload for this device and the provided components, which are associated with
-// this Device. To remove components from the device, replace the component in the map with a RemoveComponent when
-// calling Configure.
+// AvailabilityWill returns an mqtt.Will that marks this Device and every Component inheriting its Availability
+// unavailable, using the unavailable value from CustomAvailabilityValues if set. If Availability is nil,
+// ErrNoAvailability is returned. See Component.AvailabilityWill.
+func (d *Device) AvailabilityWill() (mqtt.Will, error) {
+	return availabilityWill(d.Availability, d.TopicPrefix, d.CustomAvailabilityValues)
+}
+
+// DiscoveryTopic returns the MQTT Topic that the discovery payload for this Device is published to under the
+// specified discovery prefix. If discoveryPrefix is empty, discovery.Prefix is used.
+func (d *Device) DiscoveryTopic(discoveryPrefix string) string {
+	return mqtt.JoinTopic(discovery.PrefixOr(discoveryPrefix), "device", d.ID(), "config")
+}
+
+// RenderDiscovery marshals the device discovery payload for this device and the provided components without publishing
+// it. The result is canonicalized (object keys are sorted and numbers are normalized, see jsontext.Value.Canonicalize)
+// so it is stable across calls, making it suitable for golden-file tests and diffing payloads.
 //
-// The device must pass validation performed by Device.Valid.
-func (d *Device) Configure(ctx context.Context, w mqtt.Writer, discoveryPrefix string, components map[string]json.MarshalerTo) error {
+// The device must pass validation performed by Device.Valid. If the device does not configure Availability, every
+// Component must configure its own.
+func (d *Device) RenderDiscovery(components map[string]json.MarshalerTo) ([]byte, error) {
+	return d.AppendDiscovery(nil, components)
+}
+
+// AppendDiscovery is like RenderDiscovery, but appends the payload to dst and returns the extended slice. Callers that
+// render many payloads can reuse dst between calls to avoid allocating a new payload each time.
+func (d *Device) AppendDiscovery(dst []byte, components map[string]json.MarshalerTo) ([]byte, error) {
 	// Validation
 	if err := d.Valid(); err != nil {
-		return err
+		return dst, err
 	}
 
-	// Write Device
-	var buf bytes.Buffer
-	e := jsontext.NewEncoder(
-		&buf,
-		jsontext.CanonicalizeRawInts(true),
-		jsontext.CanonicalizeRawFloats(true),
-	)
+	if d.Availability == nil {
+		for k, c := range components {
+			if a, ok := c.(availabilityConfigurer); ok && !a.hasAvailability() {
+				return dst, fmt.Errorf("component %s: availability: %w", k, discovery.ErrTopicRequired)
+			}
+		}
+	}
 
-	err := errors.Join(
-		e.WriteToken(jsontext.BeginObject),
+	return renderDiscovery(dst, d.FailFastDiscovery, func(r *discoveryRenderer) {
+		r.field(func(e *jsontext.Encoder) error {
+			return errors.Join(
+				discovery.MarshalStd("device", e, discovery.FieldDevice, d),
+				discovery.MarshalStd("origin", e, discovery.FieldOrigin, cmp.Or(d.Origin, &DefaultOrigin)),
+				maybeMarshalInheritedAvailability(e, d),
+			)
+		})
 
-		discovery.MarshalStd("device", e, discovery.FieldDevice, d),
-		discovery.MarshalStd("origin", e, discovery.FieldOrigin, cmp.Or(d.Origin, &DefaultOrigin)),
+		// TODO: Shared QoS?
+		r.components(discovery.FieldComponents, components)
+	})
+}
 
-		e.WriteToken(jsontext.String(discovery.FieldComponents)),
-		e.WriteToken(jsontext.BeginObject),
+// Logger returns a slog.Logger that includes the ID of this Device on every record. Records logged by hqtt while
+// configuring the device are attributed to it in the same way.
+func (d *Device) Logger() *slog.Logger {
+	return slog.New(deviceLog.Handler().WithAttrs([]slog.Attr{slog.String(log.DeviceKey, d.ID())}))
+}
 
-		discovery.MaybeInlineMarshalStd(e, components),
+// Configure updates the device discovery payload for this device and the provided components, which are associated with
+// this Device. To remove components from the device, replace the component in the map with a RemoveComponent when
+// calling Configure. The payload is rendered with RenderDiscovery. If discoveryPrefix is empty, discovery.Prefix is
+// used.
+//
+// The payload is retained with a QoS of 0, see ConfigureWithOptions. The device must pass validation performed by
+// Device.Valid.
+func (d *Device) Configure(ctx context.Context, w mqtt.Writer, discoveryPrefix string, components map[string]json.MarshalerTo) error {
+	return d.ConfigureWithOptions(ctx, w, discoveryPrefix, components, mqtt.WriteOptions{Retain: true})
+}
 
-		e.WriteToken(jsontext.EndObject),
-		// TODO: Shared QoS?
-		e.WriteToken(jsontext.EndObject),
-	)
+// ConfigureWithOptions is like Configure, but publishes the discovery payload with the provided mqtt.WriteOptions. Home
+// Assistant forgets entities whose discovery payloads are not retained when it restarts, so disable
+// mqtt.WriteOptions.Retain only if the payload is republished when Home Assistant comes online (see AutoRediscover).
+func (d *Device) ConfigureWithOptions(ctx context.Context, w mqtt.Writer, discoveryPrefix string, components map[string]json.MarshalerTo, opts mqtt.WriteOptions) error {
+	ctx = log.WithAttrs(ctx, slog.String(log.DeviceKey, d.ID()))
 
+	// Writers and hooks may hold on to the payload, so it is rendered into a new slice instead of a reused buffer.
+	data, err := d.RenderDiscovery(components)
 	if err != nil {
-		return fmt.Errorf("configure: marshal discovery config: %w", err)
+		return fmt.Errorf("configure: %w", err)
+	}
+
+	topic := d.DiscoveryTopic(discoveryPrefix)
+	err = w.WriteTopic(ctx, topic, opts, data)
+
+	if hooks.Enabled() {
+		hooks.DiscoveryPublished(ctx, hooks.DiscoveryEvent{DeviceID: d.ID(), Topic: topic, Payload: data, Err: err})
+	}
+
+	return err
+}
+
+// Remove deletes the device discovery payload for this device by publishing an empty retained payload to its
+// DiscoveryTopic, which makes Home Assistant remove the device and all of its entities. Use it when decommissioning a
+// device, or RemoveComponent with Configure to remove individual components. If discoveryPrefix is empty,
+// discovery.Prefix is used. Other retained messages (such as state or availability) are not removed.
+func (d *Device) Remove(ctx context.Context, w mqtt.Writer, discoveryPrefix string) error {
+	ctx = log.WithAttrs(ctx, slog.String(log.DeviceKey, d.ID()))
+
+	topic := d.DiscoveryTopic(discoveryPrefix)
+	err := w.WriteTopic(ctx, topic, mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, nil)
+
+	if hooks.Enabled() {
+		hooks.DiscoveryPublished(ctx, hooks.DiscoveryEvent{DeviceID: d.ID(), Topic: topic, Err: err})
 	}
 
-	topic := fmt.Sprintf(`%s/device/%s/config`, discoveryPrefix, d.ID())
-	return w.WriteTopic(ctx, topic, mqtt.WriteOptions{Retain: true}, buf.Bytes())
+	return err
 }
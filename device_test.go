@@ -0,0 +1,323 @@
+package hqtt
+
+import (
+	"context"
+	"encoding/json/v2"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func TestDevice_RenderDiscovery(t *testing.T) {
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := (&Device{}).RenderDiscovery(nil)
+		require.ErrorIs(t, err, ErrInvalidDevice)
+	})
+
+	t.Run("Canonical", func(t *testing.T) {
+		d := &Device{Name: "foo", Identifiers: []string{"foo"}, Origin: &Origin{Name: "test"}}
+		components := map[string]json.MarshalerTo{
+			"b": RemoveComponent{Platform: "sensor"},
+			"a": &Component[*platform.Sensor[string, any]]{
+				UniqueID:     "a",
+				Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+				Platform:     &platform.Sensor[string, any]{State: mqtt.NewValue("state", mqtt.StringMarshaler)},
+			},
+		}
+
+		first, err := d.RenderDiscovery(components)
+		require.NoError(t, err)
+
+		for range 10 {
+			again, err := d.RenderDiscovery(components)
+			require.NoError(t, err)
+			require.Equal(t, string(first), string(again))
+		}
+
+		assert.JSONEq(t, `{
+			"cmps": {
+				"a": {"p": "sensor", "name": null, "avty_t": "available", "uniq_id": "a", "stat_t": "state"},
+				"b": {"platform": "sensor"}
+			},
+			"dev": {"ids": ["foo"], "name": "foo"},
+			"o": {"name": "test"}
+		}`, string(first))
+		assert.Equal(t, `{"cmps":{"a":{"avty_t":"available","name":null,"p":"sensor","stat_t":"state","uniq_id":"a"},"b":{"platform":"sensor"}},"dev":{"ids":["foo"],"name":"foo"},"o":{"name":"test"}}`, string(first))
+	})
+}
+
+func TestDevice_FailFastDiscovery(t *testing.T) {
+	d := &Device{Name: "foo", Identifiers: []string{"foo"}, Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler)}
+	components := map[string]json.MarshalerTo{
+		"a": &Component[*platform.Light]{UniqueID: "a", Platform: &platform.Light{}},
+		"b": &Component[*platform.Light]{UniqueID: "b", Platform: &platform.Light{}},
+	}
+
+	_, err := d.RenderDiscovery(components)
+	require.ErrorIs(t, err, discovery.ErrTopicRequired)
+	assert.Equal(t, 2, strings.Count(err.Error(), "command: topic is required"), "every invalid component should be reported")
+
+	d.FailFastDiscovery = true
+	_, err = d.RenderDiscovery(components)
+	require.ErrorIs(t, err, discovery.ErrTopicRequired)
+	// Components are rendered in order, so the first invalid component is always reported
+	assert.Equal(t, "marshal discovery config: /cmps/a: command: topic is required", err.Error())
+
+	// The encoder is reused after a fail-fast render, and must report every error again
+	d.FailFastDiscovery = false
+	_, err = d.RenderDiscovery(components)
+	assert.Equal(t, 2, strings.Count(err.Error(), "command: topic is required"))
+}
+
+func TestDevice_FailFastDiscovery_Concurrent(t *testing.T) {
+	components := map[string]json.MarshalerTo{
+		"a": &Component[*platform.Light]{UniqueID: "a", Platform: &platform.Light{}},
+		"b": &Component[*platform.Light]{UniqueID: "b", Platform: &platform.Light{}},
+	}
+
+	// Fail-fast mode belongs to the render, so it must not leak onto renders of other devices sharing pooled encoders
+	var wg sync.WaitGroup
+	for i := range 50 {
+		d := &Device{Name: "foo", Identifiers: []string{"foo"}, Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler)}
+		d.FailFastDiscovery = i%2 == 0
+
+		wg.Go(func() {
+			want := 2
+			if d.FailFastDiscovery {
+				want = 1
+			}
+
+			_, err := d.RenderDiscovery(components)
+			assert.Equal(t, want, strings.Count(err.Error(), "command: topic is required"))
+		})
+	}
+
+	wg.Wait()
+}
+
+func TestDevice_AppendDiscovery(t *testing.T) {
+	d, components := newBenchmarkDevice()
+
+	want, err := d.RenderDiscovery(components)
+	require.NoError(t, err)
+
+	got, err := d.AppendDiscovery([]byte("prefix"), components)
+	require.NoError(t, err)
+	assert.Equal(t, "prefix"+string(want), string(got))
+
+	// Reusing the buffer must not leak the previous payload
+	got, err = d.AppendDiscovery(got[:0], components)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	_, err = (&Device{}).AppendDiscovery(nil, components)
+	require.ErrorIs(t, err, ErrInvalidDevice)
+}
+
+func TestDevice_RenderDiscovery_Availability(t *testing.T) {
+	components := map[string]json.MarshalerTo{
+		"a": &Component[*platform.Sensor[string, any]]{
+			UniqueID: "a",
+			Platform: &platform.Sensor[string, any]{State: mqtt.NewValue("state", mqtt.StringMarshaler)},
+		},
+	}
+
+	t.Run("Required", func(t *testing.T) {
+		_, err := (&Device{Identifiers: []string{"foo"}}).RenderDiscovery(components)
+		require.ErrorIs(t, err, discovery.ErrTopicRequired)
+	})
+
+	t.Run("Inherited", func(t *testing.T) {
+		d := &Device{
+			Identifiers:  []string{"foo"},
+			TopicPrefix:  "foo",
+			Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+		}
+
+		payload, err := d.RenderDiscovery(components)
+		require.NoError(t, err)
+		assert.Contains(t, string(payload), `"avty_t":"foo/available"`)
+		assert.NotContains(t, string(payload), `"a":{"avty_t"`)
+	})
+}
+
+func TestAvailabilityWill(t *testing.T) {
+	opts := mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}
+
+	t.Run("Device", func(t *testing.T) {
+		d := &Device{
+			TopicPrefix:  "foo",
+			Availability: mqtt.NewValueWithOptions("available", hass.AvailabilityMarshaler, opts),
+		}
+
+		will, err := d.AvailabilityWill()
+		require.NoError(t, err)
+		assert.Equal(t, mqtt.Will{Topic: "foo/available", Payload: []byte("offline"), Options: opts}, will)
+	})
+
+	t.Run("Component", func(t *testing.T) {
+		c := &Component[*platform.Sensor[string, any]]{
+			TopicPrefix:              "bar",
+			Availability:             mqtt.NewValueWithOptions("available", hass.AvailabilityMarshaler, opts),
+			CustomAvailabilityValues: hass.CustomAvailability{Unavailable: "dead"},
+		}
+
+		will, err := c.AvailabilityWill()
+		require.NoError(t, err)
+		assert.Equal(t, mqtt.Will{Topic: "bar/available", Payload: []byte("dead"), Options: opts}, will)
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		_, err := (&Device{}).AvailabilityWill()
+		require.ErrorIs(t, err, ErrNoAvailability)
+
+		_, err = (&Component[*platform.Sensor[string, any]]{}).AvailabilityWill()
+		require.ErrorIs(t, err, ErrNoAvailability)
+	})
+}
+
+func TestDevice_ID(t *testing.T) {
+	require.Equal(t, "Custom ID", (&Device{DiscoveryID: "Custom ID", Name: "Garage"}).ID())
+	require.Equal(t, "garage-bridge__AA__BB__CC__Garage__Café__ACME", (&Device{
+		Identifiers:  []string{"garage-bridge", "AA:BB:CC"},
+		Name:         "Garage Café",
+		Manufacturer: "ACME",
+	}).ID())
+	require.Equal(t, "garage_bridge__aa_bb_cc__garage_cafe__acme", (&Device{
+		Identifiers:  []string{"garage-bridge", "AA:BB:CC"},
+		Name:         "Garage Café",
+		Manufacturer: "ACME",
+		StrictID:     true,
+	}).ID())
+}
+
+func TestDevice_DiscoveryTopic(t *testing.T) {
+	require.Equal(t, "homeassistant/device/foo/config", (&Device{DiscoveryID: "foo"}).DiscoveryTopic("homeassistant"))
+}
+
+// discardWriter drops every message so benchmarks only measure hqtt itself.
+type discardWriter struct{}
+
+func (discardWriter) WriteTopic(context.Context, string, mqtt.WriteOptions, []byte) error {
+	return nil
+}
+
+func newBenchmarkDevice() (*Device, map[string]json.MarshalerTo) {
+	d := &Device{
+		Name:         "foo",
+		Identifiers:  []string{"foo"},
+		Origin:       &Origin{Name: "test"},
+		TopicPrefix:  "foo",
+		Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+	}
+	components := map[string]json.MarshalerTo{
+		"light": &Component[*platform.Light]{
+			UniqueID: "light",
+			Platform: &platform.Light{
+				State:             mqtt.NewValue("state", hass.PowerStateMarshaler),
+				Command:           mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+				Brightness:        mqtt.NewValue("brightness", mqtt.UintMarshaler),
+				BrightnessCommand: mqtt.NewRemoteValue("brightness/set", mqtt.UintUnmarshaler),
+			},
+		},
+		"sensor": &Component[*platform.Sensor[string, any]]{
+			UniqueID: "sensor",
+			Platform: &platform.Sensor[string, any]{State: mqtt.NewValue("state", mqtt.StringMarshaler)},
+		},
+	}
+
+	return d, components
+}
+
+func TestDevice_ConfigureWithOptions(t *testing.T) {
+	d, components := newBenchmarkDevice()
+
+	t.Run("Default", func(t *testing.T) {
+		w := &hqtttest.Writer{}
+		require.NoError(t, d.Configure(t.Context(), w, "", components))
+
+		m, ok := w.LastWrite(d.DiscoveryTopic(""))
+		require.True(t, ok)
+		assert.Equal(t, mqtt.WriteOptions{Retain: true}, m.Options)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		w := &hqtttest.Writer{}
+		opts := mqtt.WriteOptions{QoS: mqtt.QOSExactlyOnce}
+		require.NoError(t, d.ConfigureWithOptions(t.Context(), w, "", components, opts))
+
+		m, ok := w.LastWrite(d.DiscoveryTopic(""))
+		require.True(t, ok)
+		assert.Equal(t, opts, m.Options)
+	})
+}
+
+// retainingWriter keeps the payloads it is called with without copying them.
+type retainingWriter struct {
+	payloads [][]byte
+}
+
+func (w *retainingWriter) WriteTopic(_ context.Context, _ string, _ mqtt.WriteOptions, value []byte) error {
+	w.payloads = append(w.payloads, value)
+	return nil
+}
+
+func TestDevice_Configure_RetainedPayload(t *testing.T) {
+	d, components := newBenchmarkDevice()
+	other := &Device{Name: "bar", Identifiers: []string{"bar"}, TopicPrefix: "bar", Availability: d.Availability}
+
+	expected, err := d.RenderDiscovery(components)
+	require.NoError(t, err)
+
+	// Writers may keep the payload, which must not be overwritten when another device is configured
+	w := &retainingWriter{}
+	require.NoError(t, d.Configure(t.Context(), w, "", components))
+	require.NoError(t, other.Configure(t.Context(), w, "", map[string]json.MarshalerTo{}))
+
+	require.Len(t, w.payloads, 2)
+	assert.JSONEq(t, string(expected), string(w.payloads[0]))
+}
+
+func TestDevice_Remove(t *testing.T) {
+	d, components := newBenchmarkDevice()
+	w := &hqtttest.Writer{}
+
+	require.NoError(t, d.Configure(t.Context(), w, "", components))
+	require.NoError(t, d.Remove(t.Context(), w, ""))
+
+	m, ok := w.LastWrite(d.DiscoveryTopic(""))
+	require.True(t, ok)
+	assert.Empty(t, m.Payload)
+	assert.True(t, m.Options.Retain)
+	assert.Empty(t, w.Retained(), "the broker should no longer retain the discovery payload")
+}
+
+func BenchmarkDevice_Configure(b *testing.B) {
+	d, components := newBenchmarkDevice()
+	w := discardWriter{}
+
+	for b.Loop() {
+		if err := d.Configure(b.Context(), w, "", components); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDevice_Configure_Allocs(t *testing.T) {
+	d, components := newBenchmarkDevice()
+	w := discardWriter{}
+
+	require.NoError(t, d.Configure(t.Context(), w, "", components))
+	hqtttest.AssertAllocs(t, 51, func() {
+		_ = d.Configure(t.Context(), w, "", components)
+	})
+}
@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"encoding/json/v2"
+	"fmt"
+	"strings"
+)
+
+// BaseTopic is the key of the field holding a base topic. Home Assistant replaces BaseTopic at the start or end of
+// any topic field in the same payload with the value of the base topic field.
+const BaseTopic = "~"
+
+// Expand parses a discovery payload and expands abbreviated field keys (including those of the device, origin, and
+// every component) to their full names, the same way Home Assistant does. Topic fields that start or end with BaseTopic
+// are expanded with the base topic of their payload. Keys that are not abbreviations are left unchanged.
+func Expand(data []byte) (map[string]any, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("expand discovery payload: %w", err)
+	}
+
+	return expandPayload(payload), nil
+}
+
+func expandPayload(payload map[string]any) map[string]any {
+	result := expandKeys(payload, Abbreviations)
+
+	if d, ok := result["device"].(map[string]any); ok {
+		result["device"] = expandKeys(d, DeviceAbbreviations)
+	}
+
+	if o, ok := result["origin"].(map[string]any); ok {
+		result["origin"] = expandKeys(o, OriginAbbreviations)
+	}
+
+	if cmps, ok := result["components"].(map[string]any); ok {
+		for id, c := range cmps {
+			if c, ok := c.(map[string]any); ok {
+				cmps[id] = expandPayload(c)
+			}
+		}
+	}
+
+	if avty, ok := result["availability"].([]any); ok {
+		for i, a := range avty {
+			if a, ok := a.(map[string]any); ok {
+				avty[i] = expandKeys(a, Abbreviations)
+			}
+		}
+	}
+
+	base, ok := result[BaseTopic].(string)
+	if !ok {
+		return result
+	}
+
+	delete(result, BaseTopic)
+	for k, v := range result {
+		if topic, ok := v.(string); ok && (k == "topic" || strings.HasSuffix(k, "_topic")) {
+			result[k] = expandBaseTopic(base, topic)
+		}
+	}
+
+	if avty, ok := result["availability"].([]any); ok {
+		for _, a := range avty {
+			if a, ok := a.(map[string]any); ok {
+				if topic, ok := a["topic"].(string); ok {
+					a["topic"] = expandBaseTopic(base, topic)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+func expandKeys(m map[string]any, abbreviations map[string]string) map[string]any {
+	result := make(map[string]any, len(m))
+	for k, v := range m {
+		if full, ok := abbreviations[k]; ok {
+			k = full
+		}
+
+		result[k] = v
+	}
+
+	return result
+}
+
+func expandBaseTopic(base, topic string) string {
+	if rest, ok := strings.CutPrefix(topic, BaseTopic); ok {
+		return base + rest
+	}
+
+	if rest, ok := strings.CutSuffix(topic, BaseTopic); ok {
+		return rest + base
+	}
+
+	return topic
+}
@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	expanded, err := Expand([]byte(`{
+		"dev": {"ids": ["foo"], "mf": "bar"},
+		"o": {"name": "test", "sw": "1.0"},
+		"cmps": {
+			"light": {"p": "light", "~": "hqtt/light", "cmd_t": "~/set", "bri_stat_t": "~/brightness", "custom": "~"}
+		},
+		"avty": [{"t": "hqtt/available"}]
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"device": map[string]any{"identifiers": []any{"foo"}, "manufacturer": "bar"},
+		"origin": map[string]any{"name": "test", "sw_version": "1.0"},
+		"components": map[string]any{
+			"light": map[string]any{
+				"platform":               "light",
+				"command_topic":          "hqtt/light/set",
+				"brightness_state_topic": "hqtt/light/brightness",
+				"custom":                 "~",
+			},
+		},
+		"availability": []any{map[string]any{"topic": "hqtt/available"}},
+	}, expanded)
+
+	_, err = Expand([]byte(`[]`))
+	require.Error(t, err)
+}
+
+func FuzzExpand(f *testing.F) {
+	for _, seed := range []string{
+		`{}`,
+		`null`,
+		`[]`,
+		`{"~": "hqtt", "cmd_t": "~/set", "stat_t": "state/~"}`,
+		`{"dev": "foo", "o": [], "cmps": {"a": null}, "avty": {"t": 1}}`,
+		`{"cmps": {"light": {"p": "light", "~": 1, "cmd_t": "~"}}}`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = Expand(data)
+	})
+}
@@ -1,8 +0,0 @@
-package discovery
-
-// Constants for component (entity) discovery fields.
-const (
-	FieldAvailabilityTopic   = "avty_t"
-	FieldPayloadAvailable    = "pl_avail"
-	FieldPayloadNotAvailable = "pl_not_avail"
-)
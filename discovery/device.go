@@ -2,40 +2,31 @@ package discovery
 
 import (
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/nlowe/hqtt/mqtt"
 )
 
-// Constants for device fields and other fields shared by all platforms
 const (
-	FieldStateTopic   = "stat_t"
-	FieldCommandTopic = "cmd_t"
-
-	FieldDevice          = "dev"
-	FieldOrigin          = "o"
-	FieldComponents      = "cmps"
-	FieldEntityCategory  = "ent_cat"
-	FieldIcon            = "ic"
-	FieldPicture         = "picture"
-	FieldPlatform        = "p"
-	FieldDefaultEntityID = "def_ent_id"
-	FieldUniqueID        = "uniq_id"
-
-	FieldPayloadOn  = "pl_on"
-	FieldPayloadOff = "pl_off"
-
-	FieldOnCommandType = "on_cmd_type"
+	// IDSep is the separator used to separate various parts of a device ID. Since SanitizeID collapses repeated
+	// underscores, it never appears within a sanitized part.
+	IDSep = "__"
 
-	FieldOptimistic = "opt"
+	// MaxIDLength is the maximum length of an ID returned by SanitizeID. Home Assistant limits entity IDs to 255
+	// characters including the platform and a separating ".", and the longest MQTT platform is alarm_control_panel.
+	MaxIDLength = 255 - len("alarm_control_panel.")
 
-	// IDSep is the separator used to separate various parts of a device ID. It is also used as a replacement for tokens
-	// that are not allowed in an ID string.
-	IDSep = "__"
+	// UnknownID is returned by SanitizeID for strings that do not contain any character allowed in an ID, like Home
+	// Assistant does.
+	UnknownID = "unknown"
 )
 
 var (
-	// IDSanitizer is a strings.Replacer that sanitizes a device ID for use in an MQTT Topic.
-	// TODO: Are there any other tokens we need to replace?
+	// IDSanitizer is a strings.Replacer that replaces a handful of tokens that are not allowed in an MQTT Topic. It does
+	// not produce IDs that Home Assistant accepts for every input, so prefer SanitizeID for new IDs. hqtt.Device.ID
+	// still uses it unless hqtt.Device.StrictID is set, so the IDs of existing devices do not change.
 	IDSanitizer = strings.NewReplacer(
 		" ", IDSep,
 		":", IDSep,
@@ -44,4 +35,82 @@ var (
 		"?", IDSep,
 		mqtt.TopicSeparator, IDSep,
 	)
+
+	// transliterations spells letters that do not decompose into an ASCII letter and combining marks with ASCII
+	// letters, like the unidecode library Home Assistant uses.
+	transliterations = map[rune]string{
+		'ß': "ss",
+		'æ': "ae", 'Æ': "ae",
+		'œ': "oe", 'Œ': "oe",
+		'ø': "o", 'Ø': "o",
+		'ł': "l", 'Ł': "l",
+		'đ': "d", 'Đ': "d",
+		'ð': "d", 'Ð': "d",
+		'þ': "th", 'Þ': "th",
+		'ı': "i",
+	}
 )
+
+// SanitizeID converts s into an ID that is valid as a node ID or object ID in a discovery topic and as the object ID
+// of an entity ID, following the same rules Home Assistant uses to generate entity IDs from names:
+//
+//   - Letters are lowercased, and accented Latin letters are transliterated to ASCII ("Café Lämp" becomes "cafe_lamp")
+//   - Every run of other characters is replaced with a single underscore, and leading and trailing underscores are
+//     removed ("Garage -- Door!" becomes "garage_door")
+//   - IDs are truncated to MaxIDLength characters
+//   - UnknownID is returned if nothing is left
+//
+// Letters from other scripts are not transliterated, and are treated like any other character that is not allowed.
+func SanitizeID(s string) string {
+	var result strings.Builder
+	result.Grow(min(len(s), MaxIDLength))
+
+	separate := false
+	write := func(allowed string) bool {
+		n := len(allowed)
+		if separate && result.Len() > 0 {
+			n++
+		}
+
+		if result.Len()+n > MaxIDLength {
+			return false
+		}
+
+		if n > len(allowed) {
+			result.WriteByte('_')
+		}
+
+		result.WriteString(allowed)
+		separate = false
+
+		return true
+	}
+
+	// Decomposing letters splits off their accents as combining marks, which are dropped
+	for _, r := range norm.NFKD.String(s) {
+		var ok bool
+		switch t, transliterated := transliterations[r]; {
+		case transliterated:
+			ok = write(t)
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			ok = write(string(r))
+		case r >= 'A' && r <= 'Z':
+			ok = write(string(unicode.ToLower(r)))
+		case unicode.Is(unicode.Mn, r):
+			continue
+		default:
+			separate = true
+			continue
+		}
+
+		if !ok {
+			break
+		}
+	}
+
+	if result.Len() == 0 {
+		return UnknownID
+	}
+
+	return result.String()
+}
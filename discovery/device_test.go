@@ -0,0 +1,37 @@
+package discovery
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeID(t *testing.T) {
+	for name, tt := range map[string]struct {
+		input string
+		want  string
+	}{
+		"Already Valid":      {input: "garage_door", want: "garage_door"},
+		"Lowercase":          {input: "Garage Door", want: "garage_door"},
+		"Accents":            {input: "Café Lämp", want: "cafe_lamp"},
+		"Transliteration":    {input: "Straße Øst", want: "strasse_ost"},
+		"Compatibility":      {input: "ﬁrst ²", want: "first_2"},
+		"Collapse":           {input: "Garage -- Door!", want: "garage_door"},
+		"Collapse Separator": {input: "garage__door", want: "garage_door"},
+		"Trim":               {input: "  !garage door?  ", want: "garage_door"},
+		"Topic":              {input: "home/garage:door.1", want: "home_garage_door_1"},
+		"Other Scripts":      {input: "Лампа 2", want: "2"},
+		"Empty":              {input: "", want: UnknownID},
+		"Nothing Allowed":    {input: "!!!", want: UnknownID},
+		"Truncated":          {input: strings.Repeat("a", MaxIDLength+10), want: strings.Repeat("a", MaxIDLength)},
+		"Truncated Before Separator": {
+			input: strings.Repeat("a", MaxIDLength-1) + " b",
+			want:  strings.Repeat("a", MaxIDLength-1),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeID(tt.input))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package discovery
+
+import (
+	"encoding/json/v2"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ChangeKind describes how a field differs between two discovery payloads.
+type ChangeKind int
+
+const (
+	// FieldAdded means the field is only present in the new payload.
+	FieldAdded ChangeKind = iota
+	// FieldRemoved means the field is only present in the old payload.
+	FieldRemoved
+	// FieldChanged means the field is present in both payloads with different values.
+	FieldChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case FieldAdded:
+		return "added"
+	case FieldRemoved:
+		return "removed"
+	case FieldChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a single difference between two discovery payloads.
+type Change struct {
+	// The expanded field names leading to the field, for example ["components", "my_light", "command_topic"]. Objects
+	// are compared field by field, while any other value (including arrays) is compared as a whole.
+	Path []string
+	Kind ChangeKind
+
+	// The value of the field in each payload, nil if the field is not present
+	Old, New any
+}
+
+// String formats the change on a single line, prefixed with "+", "-", or "~" like a diff. Values are formatted as JSON.
+func (c Change) String() string {
+	path := strings.Join(c.Path, ".")
+	switch c.Kind {
+	case FieldAdded:
+		return fmt.Sprintf("+ %s: %s", path, formatDiffValue(c.New))
+	case FieldRemoved:
+		return fmt.Sprintf("- %s: %s", path, formatDiffValue(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", path, formatDiffValue(c.Old), formatDiffValue(c.New))
+	}
+}
+
+func formatDiffValue(v any) string {
+	data, err := json.Marshal(v, json.Deterministic(true))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(data)
+}
+
+// Diff expands both discovery payloads (see Expand) and returns the changes from the first to the second, sorted by
+// path. Since publishing an empty payload removes a device or component from Home Assistant, an empty payload is
+// treated as an empty object, so every field of the other payload is reported as added or removed. This is useful to
+// preview what publishing a payload would change, for example by comparing the retained payload on a broker with the
+// output of hqtt.Device.RenderDiscovery.
+func Diff(from, to []byte) ([]Change, error) {
+	o, err := expandOrEmpty(from)
+	if err != nil {
+		return nil, fmt.Errorf("old: %w", err)
+	}
+
+	n, err := expandOrEmpty(to)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+
+	return DiffExpanded(o, n), nil
+}
+
+func expandOrEmpty(data []byte) (map[string]any, error) {
+	if len(data) == 0 {
+		return map[string]any{}, nil
+	}
+
+	return Expand(data)
+}
+
+// DiffExpanded returns the differences between two payloads that have already been expanded. See Diff.
+func DiffExpanded(from, to map[string]any) []Change {
+	var changes []Change
+	diffObjects(&changes, nil, from, to)
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return slices.Compare(a.Path, b.Path)
+	})
+
+	return changes
+}
+
+func diffObjects(changes *[]Change, path []string, from, to map[string]any) {
+	keys := slices.Collect(maps.Keys(from))
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	for _, k := range keys {
+		p := append(slices.Clip(path), k)
+		o, inOld := from[k]
+		n, inNew := to[k]
+
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Path: p, Kind: FieldAdded, New: n})
+		case !inNew:
+			*changes = append(*changes, Change{Path: p, Kind: FieldRemoved, Old: o})
+		default:
+			oo, oIsObject := o.(map[string]any)
+			no, nIsObject := n.(map[string]any)
+			if oIsObject && nIsObject {
+				diffObjects(changes, p, oo, no)
+			} else if !reflect.DeepEqual(o, n) {
+				*changes = append(*changes, Change{Path: p, Kind: FieldChanged, Old: o, New: n})
+			}
+		}
+	}
+}
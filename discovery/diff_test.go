@@ -0,0 +1,52 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	changes, err := Diff(
+		[]byte(`{"dev": {"ids": ["lamp"], "sw": "1.0"}, "cmps": {"light": {"p": "light", "~": "old", "cmd_t": "~/set", "ic": "mdi:lamp"}}}`),
+		[]byte(`{"dev": {"ids": ["lamp"], "sw": "1.1"}, "cmps": {"light": {"p": "light", "cmd_t": "old/set", "bri_cmd_t": "old/brightness"}, "sensor": {"p": "sensor"}}}`),
+	)
+	require.NoError(t, err)
+
+	// The base topic is expanded before comparing, so the command topic is unchanged
+	assert.Equal(t, []Change{
+		{Path: []string{"components", "light", "brightness_command_topic"}, Kind: FieldAdded, New: "old/brightness"},
+		{Path: []string{"components", "light", "icon"}, Kind: FieldRemoved, Old: "mdi:lamp"},
+		{Path: []string{"components", "sensor"}, Kind: FieldAdded, New: map[string]any{"platform": "sensor"}},
+		{Path: []string{"device", "sw_version"}, Kind: FieldChanged, Old: "1.0", New: "1.1"},
+	}, changes)
+
+	assert.Equal(t, []string{
+		`+ components.light.brightness_command_topic: "old/brightness"`,
+		`- components.light.icon: "mdi:lamp"`,
+		`+ components.sensor: {"platform":"sensor"}`,
+		`~ device.sw_version: "1.0" -> "1.1"`,
+	}, []string{changes[0].String(), changes[1].String(), changes[2].String(), changes[3].String()})
+
+	t.Run("Empty", func(t *testing.T) {
+		changes, err := Diff(nil, []byte(`{"dev": {"ids": ["lamp"]}, "cmps": {}}`))
+		require.NoError(t, err)
+		assert.Equal(t, []Change{
+			{Path: []string{"components"}, Kind: FieldAdded, New: map[string]any{}},
+			{Path: []string{"device"}, Kind: FieldAdded, New: map[string]any{"identifiers": []any{"lamp"}}},
+		}, changes)
+
+		changes, err = Diff([]byte(`{"cmps": {}}`), []byte(`{"cmps": {}}`))
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := Diff([]byte(`{`), nil)
+		require.ErrorContains(t, err, "old")
+
+		_, err = Diff(nil, []byte(`[]`))
+		require.ErrorContains(t, err, "new")
+	})
+}
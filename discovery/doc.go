@@ -4,4 +4,10 @@
 //
 // See https://www.home-assistant.io/integrations/mqtt/#supported-abbreviations-in-mqtt-discovery-messages for a full
 // list of abbreviations. Not all abbreviations are provided as constants by this package.
+//
+// The abbreviation tables and Field constants are generated from a copy of Home Assistant's abbreviations.py and the
+// fields listed in fields.yaml. To add a field, list it in fields.yaml and run go generate. To pick up abbreviations
+// added to Home Assistant, run go run ./internal/gen -fetch.
 package discovery
+
+//go:generate go run ./internal/gen
@@ -1,6 +1,8 @@
 package discovery
 
 import (
+	"sync/atomic"
+
 	"github.com/nlowe/hqtt/hass"
 	"github.com/nlowe/hqtt/mqtt"
 )
@@ -12,10 +14,39 @@ const (
 	StatusTopic = "status"
 )
 
+var prefix atomic.Pointer[string]
+
+// SetPrefix configures the discovery prefix used by hqtt when callers do not provide one (by passing the empty string).
+// Installations that configured a non-default discovery prefix in Home Assistant can call this once at startup instead
+// of passing the prefix everywhere. Passing the empty string restores DefaultPrefix.
+func SetPrefix(p string) {
+	p = mqtt.TrimTopic(p)
+	prefix.Store(&p)
+}
+
+// Prefix returns the discovery prefix configured with SetPrefix, or DefaultPrefix if one has not been configured.
+func Prefix() string {
+	if p := prefix.Load(); p != nil && *p != "" {
+		return *p
+	}
+
+	return DefaultPrefix
+}
+
+// PrefixOr returns the provided discovery prefix if it is not empty. Otherwise, it returns Prefix.
+func PrefixOr(p string) string {
+	if p != "" {
+		return p
+	}
+
+	return Prefix()
+}
+
 // HomeAssistantAvailability constructs a mqtt.RemoteValue that monitor's Home Assistant's availability topic. Subscribe
-// to changes to this value to be notified when Home Assistant restarts.
+// to changes to this value to be notified when Home Assistant restarts. If discoveryPrefix is empty, Prefix is used. The
+// value is mqtt.RemoteValue.Passive, since Home Assistant's status is not a command.
 //
 // See https://www.home-assistant.io/integrations/mqtt/#birth-and-last-will-messages.
 func HomeAssistantAvailability(discoveryPrefix string) *mqtt.RemoteValue[hass.Availability] {
-	return mqtt.NewRemoteValue(mqtt.JoinTopic(discoveryPrefix, StatusTopic), hass.AvailabilityUnmarshaler)
+	return mqtt.NewRemoteValue(mqtt.JoinTopic(PrefixOr(discoveryPrefix), StatusTopic), hass.AvailabilityUnmarshaler).Passive()
 }
@@ -22,6 +22,17 @@ func TestHomeAssistantAvailability(t *testing.T) {
 		require.Equal(t, "custom/status", sut.FullyQualifiedTopic(""))
 	})
 
+	t.Run("Global Prefix", func(t *testing.T) {
+		SetPrefix("global")
+		t.Cleanup(func() {
+			SetPrefix("")
+		})
+
+		sut := HomeAssistantAvailability("")
+
+		require.Equal(t, "global/status", sut.FullyQualifiedTopic(""))
+	})
+
 	t.Run("Unmarshaler", func(t *testing.T) {
 		sut := HomeAssistantAvailability(DefaultPrefix)
 
@@ -35,3 +46,20 @@ func TestHomeAssistantAvailability(t *testing.T) {
 		assert.EqualValues(t, hass.Available, v)
 	})
 }
+
+func TestPrefix(t *testing.T) {
+	require.Equal(t, DefaultPrefix, Prefix())
+	require.Equal(t, "custom", PrefixOr("custom"))
+
+	SetPrefix("/global/")
+	t.Cleanup(func() {
+		SetPrefix("")
+	})
+
+	require.Equal(t, "global", Prefix())
+	require.Equal(t, "global", PrefixOr(""))
+	require.Equal(t, "custom", PrefixOr("custom"))
+
+	SetPrefix("")
+	require.Equal(t, DefaultPrefix, Prefix())
+}
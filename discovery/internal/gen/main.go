@@ -0,0 +1,418 @@
+// Command gen generates the abbreviation tables and Field constants of the discovery package from a copy of Home
+// Assistant's abbreviations.py and the fields hqtt supports, listed in fields.yaml. It is run by go generate in the
+// discovery package. To pick up new abbreviations from Home Assistant, run it with -fetch to refresh abbreviations.py
+// first:
+//
+//	go run ./internal/gen -fetch
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// upstream is where Home Assistant publishes the abbreviations it expands in discovery payloads.
+const upstream = "https://raw.githubusercontent.com/home-assistant/core/dev/homeassistant/components/mqtt/abbreviations.py"
+
+func main() {
+	abbreviations := flag.String("abbreviations", "abbreviations.py", "Path to a copy of Home Assistant's abbreviations.py")
+	fields := flag.String("fields", "fields.yaml", "Path to the list of fields to generate constants for")
+	out := flag.String("out", ".", "Directory to write generated files to")
+	fetch := flag.Bool("fetch", false, "Download the latest abbreviations.py from "+upstream+" before generating")
+	flag.Parse()
+
+	if err := run(*abbreviations, *fields, *out, *fetch); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(abbreviationsPath, fieldsPath, out string, fetch bool) error {
+	if fetch {
+		if err := download(abbreviationsPath); err != nil {
+			return fmt.Errorf("fetch abbreviations: %w", err)
+		}
+	}
+
+	files, err := generateFiles(abbreviationsPath, fieldsPath)
+	if err != nil {
+		return err
+	}
+
+	for name, src := range files {
+		if err = os.WriteFile(filepath.Join(out, name), src, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func download(path string) error {
+	resp, err := http.Get(upstream)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// generateFiles returns the contents of every generated file, keyed by file name.
+func generateFiles(abbreviationsPath, fieldsPath string) (map[string][]byte, error) {
+	py, err := os.ReadFile(abbreviationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := parseTables(py)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", abbreviationsPath, err)
+	}
+
+	raw, err := os.ReadFile(fieldsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var s spec
+	if err = yaml.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", fieldsPath, err)
+	}
+
+	abbreviations, err := render(abbreviationsTemplate, tables)
+	if err != nil {
+		return nil, fmt.Errorf("generate abbreviations: %w", err)
+	}
+
+	model, err := s.resolve(tables[0])
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", fieldsPath, err)
+	}
+
+	fields, err := render(fieldsTemplate, model)
+	if err != nil {
+		return nil, fmt.Errorf("generate fields: %w", err)
+	}
+
+	return map[string][]byte{
+		"abbreviations_gen.go": abbreviations,
+		"fields_gen.go":        fields,
+	}, nil
+}
+
+// table is one of the abbreviation dictionaries in abbreviations.py, in source order.
+type table struct {
+	Name    string
+	Doc     string
+	Entries []entry
+}
+
+type entry struct {
+	Key, Full string
+}
+
+// abbreviation returns the abbreviated key for the provided full field name, if Home Assistant abbreviates it.
+func (t table) abbreviation(full string) (string, bool) {
+	for _, e := range t.Entries {
+		if e.Full == full {
+			return e.Key, true
+		}
+	}
+
+	return "", false
+}
+
+var (
+	dictPattern  = regexp.MustCompile(`(?ms)^([A-Z_]+) = \{(.*?)\}`)
+	entryPattern = regexp.MustCompile(`"([^"]+)":\s*"([^"]+)"`)
+
+	// The dictionaries in abbreviations.py and the Go variables they are generated as
+	tables = []table{
+		{
+			Name: "Abbreviations",
+			Doc: "Abbreviations maps abbreviated field keys to the full field names understood by Home Assistant. It mirrors " +
+				"the table Home Assistant uses to expand discovery payloads.",
+		},
+		{
+			Name: "DeviceAbbreviations",
+			Doc:  "DeviceAbbreviations maps abbreviated device field keys to the full field names understood by Home Assistant.",
+		},
+		{
+			Name: "OriginAbbreviations",
+			Doc:  "OriginAbbreviations maps abbreviated origin field keys to the full field names understood by Home Assistant.",
+		},
+	}
+	pythonNames = []string{"ABBREVIATIONS", "DEVICE_ABBREVIATIONS", "ORIGIN_ABBREVIATIONS"}
+)
+
+// parseTables extracts the abbreviation dictionaries from abbreviations.py. It only understands the simple literal
+// dictionaries Home Assistant uses, which is all the file has ever contained.
+func parseTables(py []byte) ([]table, error) {
+	dicts := map[string]string{}
+	for _, m := range dictPattern.FindAllSubmatch(py, -1) {
+		dicts[string(m[1])] = string(m[2])
+	}
+
+	result := slices.Clone(tables)
+	for i, name := range pythonNames {
+		body, ok := dicts[name]
+		if !ok {
+			return nil, fmt.Errorf("missing %s", name)
+		}
+
+		for _, m := range entryPattern.FindAllStringSubmatch(body, -1) {
+			result[i].Entries = append(result[i].Entries, entry{Key: m[1], Full: m[2]})
+		}
+
+		if len(result[i].Entries) == 0 {
+			return nil, fmt.Errorf("%s is empty", name)
+		}
+	}
+
+	return result, nil
+}
+
+// spec is the structure of fields.yaml.
+type spec struct {
+	Groups    []group             `yaml:"groups"`
+	Platforms map[string][]string `yaml:"platforms"`
+}
+
+type group struct {
+	Name   string  `yaml:"name"`
+	Doc    string  `yaml:"doc"`
+	Fields []field `yaml:"fields"`
+}
+
+type field struct {
+	Field   string   `yaml:"field"`
+	Name    string   `yaml:"name"`
+	Key     string   `yaml:"key"`
+	Aliases []string `yaml:"aliases"`
+}
+
+// UnmarshalYAML accepts either the full name of a field or a mapping.
+func (f *field) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&f.Field)
+	}
+
+	type plain field
+	return n.Decode((*plain)(f))
+}
+
+// fieldsModel is the data fieldsTemplate is executed with.
+type fieldsModel struct {
+	Groups    []constGroup
+	Platforms []platform
+}
+
+type constGroup struct {
+	Doc    string
+	Consts []constant
+}
+
+type constant struct {
+	Name, Value string
+}
+
+type platform struct {
+	Name string
+	Keys []string
+}
+
+// resolve maps every field in s to its abbreviated key and constant name.
+func (s spec) resolve(abbreviations table) (fieldsModel, error) {
+	var (
+		model    fieldsModel
+		errs     []error
+		keys     = map[string]string{}
+		declared = map[string]string{}
+		groups   = map[string][]string{}
+	)
+
+	for _, g := range s.Groups {
+		var consts []constant
+		for _, f := range g.Fields {
+			key := f.Key
+			if key == "" {
+				var ok bool
+				if key, ok = abbreviations.abbreviation(f.Field); !ok {
+					// Catch typos in field names, fields Home Assistant does not abbreviate need an explicit key
+					errs = append(errs, fmt.Errorf("field %s has no abbreviation", f.Field))
+					continue
+				}
+			}
+
+			groups[g.Name] = append(groups[g.Name], key)
+			if existing, ok := keys[f.Field]; ok {
+				if existing != key {
+					errs = append(errs, fmt.Errorf("field %s has conflicting keys %q and %q", f.Field, existing, key))
+				}
+
+				continue
+			}
+
+			keys[f.Field] = key
+
+			name := "Field" + cmp.Or(f.Name, constantName(f.Field))
+			if other, ok := declared[name]; ok {
+				errs = append(errs, fmt.Errorf("fields %s and %s would both be declared as %s", other, f.Field, name))
+				continue
+			}
+
+			declared[name] = f.Field
+			consts = append(consts, constant{Name: name, Value: fmt.Sprintf("%q", key)})
+			for _, alias := range f.Aliases {
+				consts = append(consts, constant{Name: "Field" + alias, Value: name})
+			}
+		}
+
+		if len(consts) > 0 {
+			model.Groups = append(model.Groups, constGroup{Doc: g.Doc, Consts: consts})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.Platforms)) {
+		p := platform{Name: name}
+		for _, g := range s.Platforms[name] {
+			fields, ok := groups[g]
+			if !ok {
+				errs = append(errs, fmt.Errorf("platform %s: unknown group %s", name, g))
+			}
+
+			p.Keys = append(p.Keys, fields...)
+		}
+
+		slices.Sort(p.Keys)
+		p.Keys = slices.Compact(p.Keys)
+		model.Platforms = append(model.Platforms, p)
+	}
+
+	return model, errors.Join(errs...)
+}
+
+// initialisms are words that are upper case in Go names, and words that hqtt spells out.
+var initialisms = map[string]string{
+	"hs":    "HueSat",
+	"id":    "ID",
+	"json":  "JSON",
+	"qos":   "QoS",
+	"rgb":   "RGB",
+	"rgbw":  "RGBW",
+	"rgbww": "RGBWW",
+	"temp":  "Temperature",
+	"url":   "URL",
+	"xy":    "XY",
+}
+
+// constantName converts the full name of a field to the name of its constant without the Field prefix.
+func constantName(full string) string {
+	var sb strings.Builder
+	for word := range strings.SplitSeq(full, "_") {
+		if s, ok := initialisms[word]; ok {
+			sb.WriteString(s)
+		} else if word != "" {
+			sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	return sb.String()
+}
+
+func render(t *template.Template, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+const header = `// Code generated by go run ./internal/gen; DO NOT EDIT.
+
+package discovery
+`
+
+var (
+	funcs = template.FuncMap{"wrap": wrap}
+
+	abbreviationsTemplate = template.Must(template.New("abbreviations").Funcs(funcs).Parse(header + `
+{{ range . }}
+{{ wrap .Doc }}
+var {{ .Name }} = map[string]string{
+{{- range .Entries }}
+	{{ printf "%q" .Key }}: {{ printf "%q" .Full }},
+{{- end }}
+}
+{{ end }}`))
+
+	fieldsTemplate = template.Must(template.New("fields").Funcs(funcs).Parse(header + `
+{{ range .Groups }}
+{{ wrap .Doc }}
+const (
+{{- range .Consts }}
+	{{ .Name }} = {{ .Value }}
+{{- end }}
+)
+{{ end }}
+// PlatformFields holds the keys each platform may emit in the discovery payload of its components, including the
+// fields emitted by hqtt.Component for every platform. It is keyed by platform name.
+var PlatformFields = map[string][]string{
+{{- range .Platforms }}
+	{{ printf "%q" .Name }}: {
+	{{- range .Keys }}
+		{{ printf "%q" . }},
+	{{- end }}
+	},
+{{- end }}
+}
+`))
+)
+
+// wrap formats a doc comment, wrapping it at 120 columns.
+func wrap(doc string) string {
+	var (
+		lines []string
+		line  = "//"
+	)
+
+	for word := range strings.FieldsSeq(doc) {
+		if len(line)+1+len(word) > 120 {
+			lines = append(lines, line)
+			line = "//"
+		}
+
+		line += " " + word
+	}
+
+	return strings.Join(append(lines, line), "\n")
+}
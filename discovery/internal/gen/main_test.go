@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerated(t *testing.T) {
+	files, err := generateFiles("../../abbreviations.py", "../../fields.yaml")
+	require.NoError(t, err)
+
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join("../..", name))
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got), "%s is out of date, run go generate ./discovery", name)
+	}
+}
+
+func TestConstantName(t *testing.T) {
+	for full, want := range map[string]string{
+		"state_topic":            "StateTopic",
+		"default_entity_id":      "DefaultEntityID",
+		"hs_command_topic":       "HueSatCommandTopic",
+		"color_temp_state_topic": "ColorTemperatureStateTopic",
+		"rgbww_command_topic":    "RGBWWCommandTopic",
+	} {
+		assert.Equal(t, want, constantName(full), full)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	abbreviations := table{Entries: []entry{{Key: "stat_t", Full: "state_topic"}}}
+
+	_, err := spec{Groups: []group{{Name: "sensor", Fields: []field{{Field: "stat_topic"}}}}}.resolve(abbreviations)
+	require.ErrorContains(t, err, "field stat_topic has no abbreviation")
+
+	_, err = spec{Platforms: map[string][]string{"sensor": {"sensor"}}}.resolve(abbreviations)
+	require.ErrorContains(t, err, "unknown group sensor")
+
+	model, err := spec{
+		Groups:    []group{{Name: "sensor", Fields: []field{{Field: "state_topic", Aliases: []string{"State"}}}}},
+		Platforms: map[string][]string{"sensor": {"sensor"}},
+	}.resolve(abbreviations)
+	require.NoError(t, err)
+	assert.Equal(t, []constant{{Name: "FieldStateTopic", Value: `"stat_t"`}, {Name: "FieldState", Value: "FieldStateTopic"}}, model.Groups[0].Consts)
+	assert.Equal(t, []platform{{Name: "sensor", Keys: []string{"stat_t"}}}, model.Platforms)
+}
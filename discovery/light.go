@@ -1,40 +0,0 @@
-package discovery
-
-// Constants for the light platform
-const (
-	FieldColorModeStateTopic   = "clrm_stat_t"
-	FieldColorModeCommandTopic = "clrm_cmd_t"
-	FieldSupportedColorModes   = "sup_clrm"
-
-	FieldBrightnessCommandTopic = "bri_cmd_t"
-	FieldBrightnessStateTopic   = "bri_stat_t"
-	FieldBrightnessScale        = "bri_scl"
-
-	FieldColorTemperatureCommandTopic = "clr_temp_cmd_t"
-	FieldColorTemperatureStateTopic   = "clr_temp_stat_t"
-	FieldColorTemperatureInKelvin     = "clr_temp_k"
-	FieldMinKelvin                    = "min_k"
-	FieldMaxKelvin                    = "max_k"
-	FieldMinMireds                    = "min_mirs"
-	FieldMaxMireds                    = "max_mirs"
-
-	FieldHueSatCommandTopic = "hs_cmd_t"
-	FieldHueSatStateTopic   = "hs_stat_t"
-
-	FieldXYCommandTopic = "xy_cmd_t"
-	FieldXYStateTopic   = "xy_stat_t"
-
-	FieldRGBCommandTopic   = "rgb_cmd_t"
-	FieldRGBStateTopic     = "rgb_stat_t"
-	FieldRGBWCommandTopic  = "rgbW_cmd_t"
-	FieldRGBWStateTopic    = "rgbW_stat_t"
-	FieldRGBWWCommandTopic = "rgbWW_cmd_t"
-	FieldRGBWWStateTopic   = "rgbWW_stat_t"
-
-	FieldWhiteCommandTopic = "whit_cmd_t"
-	FieldWhiteScale        = "whit_scl"
-
-	FieldEffectCommandTopic = "fx_cmd_t"
-	FieldEffectStateTopic   = "fx_stat_t"
-	FieldEffectList         = "fx_list"
-)
@@ -21,6 +21,9 @@ var (
 	// ErrMissingStateOrCommandTopic is the error returned by MaybeMarshalStateAndCommandTopics if either the state
 	// topic or the command topic (but not both) are specified.
 	ErrMissingStateOrCommandTopic = errors.New("state and command topics must both be configured")
+	// ErrConflictingTopics is the error returned by MarshalExclusiveTopic if both of the mutually exclusive topics are
+	// specified.
+	ErrConflictingTopics = errors.New("only one of the topics may be configured")
 
 	// Marshalers contains json.Marshalers for types from the standard library to make them conform to the Home
 	// Assistant MQTT Device Discovery schema (e.g. render URLs as strings).
@@ -101,6 +104,20 @@ func MaybeMarshalStateAndCommandTopics[T any](name string, e *jsontext.Encoder,
 	)
 }
 
+// MarshalExclusiveTopic encodes exactly one of two mutually exclusive topics. It returns ErrTopicRequired if neither
+// topic is specified, and ErrConflictingTopics if both are.
+func MarshalExclusiveTopic(name string, e *jsontext.Encoder, k1, topic1, k2, topic2 string) error {
+	if topic1 != "" && topic2 != "" {
+		return fmt.Errorf("%s: %w", name, ErrConflictingTopics)
+	}
+
+	if topic1 != "" {
+		return MaybeMarshalTopic(e, k1, topic1)
+	}
+
+	return MarshalRequiredTopic(name, e, k2, topic2)
+}
+
 // MarshalStd marshals the specified value using json.MarshalEncode with Marshalers. If the provided value is nil, it
 // returns ErrValueRequired.
 func MarshalStd[T any](name string, e *jsontext.Encoder, k string, v *T) error {
@@ -123,6 +140,16 @@ func MaybeMarshalStd[T any](e *jsontext.Encoder, k string, v *T) error {
 	)
 }
 
+// MarshalStdSlice marshals the provided slice of values using json.MarshalEncode with Marshalers. If the slice is
+// empty, it returns ErrValueRequired.
+func MarshalStdSlice[T any](name string, e *jsontext.Encoder, k string, v []T) error {
+	if len(v) == 0 {
+		return fmt.Errorf("%s: %w", name, ErrValueRequired)
+	}
+
+	return MaybeMarshalStdSlice(e, k, v)
+}
+
 // MaybeMarshalStdSlice marshals the provided slice of values using json.MarshalEncode with Marshalers if it is not
 // empty.
 func MaybeMarshalStdSlice[T any](e *jsontext.Encoder, k string, v []T) error {
@@ -154,6 +181,13 @@ func MaybeMarshalStdComparable[T comparable](e *jsontext.Encoder, k string, v T)
 		return nil
 	}
 
+	return maybeMarshalStdValue(e, k, v)
+}
+
+// maybeMarshalStdValue is MaybeMarshalStd for a value instead of a pointer. Taking the address of v here instead of in
+// MaybeMarshalStdComparable means v only escapes to the heap for fields that are set, so optional fields that are
+// left empty do not allocate.
+func maybeMarshalStdValue[T any](e *jsontext.Encoder, k string, v T) error {
 	return MaybeMarshalStd(e, k, &v)
 }
 
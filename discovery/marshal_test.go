@@ -184,6 +184,36 @@ func TestMaybeMarshalStateAndCommandTopics(t *testing.T) {
 	})
 }
 
+func TestMarshalExclusiveTopic(t *testing.T) {
+	t.Run("Neither", func(t *testing.T) {
+		e, b := capturingEncoder()
+
+		require.ErrorIs(t, MarshalExclusiveTopic("sut", e, "first", "", "second", ""), ErrTopicRequired)
+		require.Empty(t, b.Bytes())
+	})
+
+	t.Run("Both", func(t *testing.T) {
+		e, b := capturingEncoder()
+
+		require.ErrorIs(t, MarshalExclusiveTopic("sut", e, "first", "a", "second", "b"), ErrConflictingTopics)
+		require.Empty(t, b.Bytes())
+	})
+
+	t.Run("First", func(t *testing.T) {
+		e, b := capturingEncoder()
+
+		require.NoError(t, MarshalExclusiveTopic("sut", e, "first", "a", "second", ""))
+		require.EqualValues(t, "\"first\"\n\"a\"\n", b.String())
+	})
+
+	t.Run("Second", func(t *testing.T) {
+		e, b := capturingEncoder()
+
+		require.NoError(t, MarshalExclusiveTopic("sut", e, "first", "", "second", "b"))
+		require.EqualValues(t, "\"second\"\n\"b\"\n", b.String())
+	})
+}
+
 func TestMarshalStd(t *testing.T) {
 	t.Run("nil", func(t *testing.T) {
 		e, b := capturingEncoder()
@@ -226,6 +256,28 @@ func TestMaybeMarshalStd(t *testing.T) {
 	})
 }
 
+func TestMarshalStdSlice(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		e, b := capturingEncoder()
+
+		require.ErrorIs(
+			t,
+			MarshalStdSlice[int]("sut", e, "foo", nil),
+			ErrValueRequired,
+		)
+		require.Empty(t, b.Bytes())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		e, b := capturingEncoder()
+
+		require.NoError(t, MarshalStdSlice[int]("sut", e, "foo", []int{123}))
+		require.EqualValues(t, `"foo"
+[123]
+`, b.String())
+	})
+}
+
 func TestMaybeMarshalStdSlice(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		t.Run("no elements", func(t *testing.T) {
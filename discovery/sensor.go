@@ -1,14 +0,0 @@
-package discovery
-
-// Generic Sensor Constants
-const (
-	FieldExpireMeasurementsAfter   = "exp_after"
-	FieldForceUpdate               = "frc_upd"
-	FieldAttributesTopic           = "json_attr_t"
-	FieldOptions                   = "opts"
-	FieldSuggestedDisplayPrecision = "sug_dsp_prc"
-	FieldStateClass                = "stat_cla"
-	FieldUnitOfMeasurement         = "unit_of_meas"
-
-	FieldOffDelay = "off_dly"
-)
@@ -29,7 +29,23 @@ func main() {
 		panic(err)
 	}
 
-	w, sm, hassAvailability, disconnect, err := configureMQTT(ctx, brokerURL)
+	topicPrefix := "hqtt/example"
+
+	// The device owns availability for every component, so the broker can mark all of them offline if we crash
+	d := &hqtt.Device{
+		Name:        "Example Device",
+		Identifiers: []string{"hqtt/example/fake_light"},
+		TopicPrefix: topicPrefix,
+
+		Availability: mqtt.NewValueWithOptions[hass.Availability]("available", hass.AvailabilityMarshaler, mqtt.WriteOptions{Retain: true}),
+	}
+
+	will, err := d.AvailabilityWill()
+	if err != nil {
+		panic(err)
+	}
+
+	w, sm, hassAvailability, disconnect, err := configureMQTT(ctx, brokerURL, will)
 	if err != nil {
 		panic(err)
 	}
@@ -56,8 +72,6 @@ func main() {
 
 	log.Info("Home Assistant is now available")
 
-	topicPrefix := "hqtt/example"
-
 	// Setup Discovery
 	log.Info("Setting up device")
 	l := hqtt.Component[*platform.Light]{
@@ -68,8 +82,6 @@ func main() {
 		DefaultEntityID: "light.foo",
 		Icon:            "mdi:light",
 
-		Availability: mqtt.NewValueWithOptions[hass.Availability]("available", hass.AvailabilityMarshaler, mqtt.WriteOptions{Retain: true}),
-
 		Platform: &platform.Light{
 			OnCommandType: platform.LightOnCommandTypeLast,
 
@@ -117,13 +129,6 @@ func main() {
 			mqtt.NewValueWithOptions[hass.PowerState]("state", hass.PowerStateMarshaler, mqtt.WriteOptions{Retain: true}),
 			platform.NewSensorAttributeValue[map[string]any]("attributes", nil),
 		),
-
-		Availability: mqtt.NewValueWithOptions[hass.Availability]("available", hass.AvailabilityMarshaler, mqtt.WriteOptions{Retain: true}),
-	}
-
-	d := &hqtt.Device{
-		Name:        "Example Device",
-		Identifiers: []string{"hqtt/example/fake_light"},
 	}
 
 	log.Info("Watching Command Topics")
@@ -155,40 +160,41 @@ func main() {
 		s.UniqueID: &s,
 	}
 
-	// TODO: Plumb through retain
-	rediscover := func() error {
-		log.Info("Re-sending discovery info")
-		return d.Configure(ctx, w, discovery.DefaultPrefix, components)
+	log.Info("Sending discovery info")
+	if err = d.Configure(ctx, w, discovery.DefaultPrefix, components); err != nil {
+		panic(err)
 	}
 
-	republish := func() error {
-		log.Info("Republishing state/availability")
-		return errors.Join(
-			mqtt.Error(l.Platform.State.Write(ctx, w, l.TopicPrefix, hass.PowerStateOff)),
-			mqtt.Error(l.Availability.Write(ctx, w, l.TopicPrefix, hass.Available)),
-			mqtt.Error(s.Platform.State.Write(ctx, w, s.TopicPrefix, hass.PowerStateOff)),
-			mqtt.Error(s.Availability.Write(ctx, w, s.TopicPrefix, hass.Available)),
-		)
+	availability := hqtt.NewAvailabilityManager(w, d, components)
+	if err = errors.Join(
+		mqtt.Error(l.Platform.State.Write(ctx, w, l.TopicPrefix, hass.PowerStateOff)),
+		mqtt.Error(s.Platform.State.Write(ctx, w, s.TopicPrefix, hass.PowerStateOff)),
+		availability.Start(ctx),
+	); err != nil {
+		panic(err)
 	}
 
+	// Re-send discovery info and republish state/availability whenever Home Assistant restarts
 	log.Info("Watching Home Assistant state")
-	hassAvailability.Watch(func(availability hass.Availability) {
-		log.With("availability", availability).Info("Home Assistant state changed")
-		if availability == hass.Available {
-			if err := errors.Join(rediscover(), republish()); err != nil {
-				panic(err)
-			}
-		}
-	})
-
-	if err = rediscover(); err != nil {
+	if err = hqtt.AutoRediscover(ctx, w, sm, d, components); err != nil {
 		panic(err)
 	}
 
-	if err = republish(); err != nil {
-		panic(err)
+	<-ctx.Done()
+
+	// Stop reacting to commands before disconnecting
+	hassAvailability.Close()
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer closeCancel()
+
+	if err = l.Close(closeCtx); err != nil {
+		log.With(hqttlog.Error(err)).Warn("Timed out waiting for light watchers")
+	}
+
+	// Mark everything offline before disconnecting, since the broker only publishes the will if we crash
+	if err = availability.Stop(closeCtx); err != nil {
+		log.With(hqttlog.Error(err)).Warn("Failed to publish availability")
 	}
 
-	<-ctx.Done()
 	log.Info("Goodbye!")
 }
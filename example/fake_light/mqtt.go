@@ -18,7 +18,7 @@ import (
 
 type disconnectFunc func(context.Context) error
 
-func configureMQTT(ctx context.Context, brokerURL *url.URL) (mqtt.Writer, mqtt.Subscriber, *mqtt.RemoteValue[hass.Availability], disconnectFunc, error) {
+func configureMQTT(ctx context.Context, brokerURL *url.URL, will mqtt.Will) (mqtt.Writer, mqtt.Subscriber, *mqtt.RemoteValue[hass.Availability], disconnectFunc, error) {
 	log := hqttlog.ForComponent("mqtt")
 
 	mqttConfig := autopaho.ClientConfig{
@@ -63,7 +63,7 @@ func configureMQTT(ctx context.Context, brokerURL *url.URL) (mqtt.Writer, mqtt.S
 	}
 
 	log.With(slog.String("broker", brokerURL.String())).Info("Connecting to mqtt")
-	w, s, disconnect, err := adapter.DialMQTT(ctx, mqttConfig)
+	w, s, disconnect, err := adapter.DialMQTTWithOptions(ctx, mqttConfig, adapter.Options{Will: &will})
 	if err != nil {
 		return nil, nil, nil, nil, fmt.Errorf("mqtt: connect: %w", err)
 	}
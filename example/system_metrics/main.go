@@ -0,0 +1,259 @@
+// Command system_metrics exposes host CPU, memory, disk, and uptime metrics to Home Assistant as a hqtt Device full of
+// sensors. Run it on every machine you want to monitor:
+//
+//	go run ./system_metrics -broker mqtt://broker:1883
+package main
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	hqttlog "github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+	adapter "github.com/nlowe/hqtt/mqtt/adapter/autopaho"
+	"github.com/nlowe/hqtt/platform"
+)
+
+// Values are retained so Home Assistant shows the latest metrics immediately after it restarts
+var retained = mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}
+
+// gauge is a sensor for a single numeric metric.
+type gauge struct {
+	component *hqtt.Component[*platform.Sensor[float64, any]]
+	value     func(m metrics) float64
+}
+
+func newGauge(id, name, icon string, value func(m metrics) float64, configure func(s *platform.Sensor[float64, any])) gauge {
+	s := &platform.Sensor[float64, any]{
+		StateClass:                hass.StateClassMeasurement,
+		SuggestedDisplayPrecision: 1,
+	}
+
+	configure(s)
+
+	// Rounding like Home Assistant displays the state avoids publishing changes nobody can see
+	s.State = mqtt.NewValueWithOptions("state", mqtt.PrecisionFloatMarshaler(s.SuggestedDisplayPrecision), retained)
+
+	return gauge{
+		component: &hqtt.Component[*platform.Sensor[float64, any]]{
+			Platform: s,
+			Name:     name,
+			Icon:     icon,
+			UniqueID: id,
+		},
+		value: value,
+	}
+}
+
+const gib = 1 << 30
+
+func main() {
+	brokerURL := flag.String("broker", "mqtt://localhost:1883", "The URL of the MQTT broker")
+	interval := flag.Duration("interval", 30*time.Second, "How often to publish metrics")
+	disk := flag.String("disk", "/", "The mount point to report disk usage for")
+	flag.Parse()
+
+	hqttlog.To(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	log := hqttlog.ForComponent("system_metrics")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, log, *brokerURL, *interval, *disk); err != nil && !errors.Is(err, context.Canceled) {
+		log.With(hqttlog.Error(err)).Error("Failed to run")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, log *slog.Logger, brokerURL string, interval time.Duration, disk string) error {
+	u, err := url.Parse(brokerURL)
+	if err != nil {
+		return fmt.Errorf("broker: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("hostname: %w", err)
+	}
+
+	c := &collector{disk: disk}
+	if _, err = c.collect(); err != nil {
+		return err
+	}
+
+	id := discovery.SanitizeID(hostname)
+	topicPrefix := mqtt.JoinTopic("hqtt", "system_metrics", id)
+
+	// Every sensor inherits the availability of the device. The broker marks it offline with the last will if this
+	// process dies without disconnecting.
+	availability := mqtt.NewValueWithOptions("available", hass.AvailabilityMarshaler, retained)
+
+	d := &hqtt.Device{
+		Name:         hostname,
+		Identifiers:  []string{"hqtt-system-metrics-" + id},
+		Manufacturer: "hqtt",
+		Model:        "System Metrics",
+		TopicPrefix:  topicPrefix,
+		Availability: availability,
+	}
+
+	gauges := []gauge{
+		newGauge(id+".cpu", "CPU usage", "mdi:cpu-64-bit", func(m metrics) float64 { return m.CPUPercent }, func(s *platform.Sensor[float64, any]) {
+			s.UnitOfMeasurement = "%"
+		}),
+		newGauge(id+".load", "Load average", "mdi:gauge", func(m metrics) float64 { return m.Load1 }, func(s *platform.Sensor[float64, any]) {
+			s.SuggestedDisplayPrecision = 2
+		}),
+		newGauge(id+".memory_used", "Memory usage", "mdi:memory", func(m metrics) float64 { return m.MemoryUsedPercent }, func(s *platform.Sensor[float64, any]) {
+			s.UnitOfMeasurement = "%"
+		}),
+		newGauge(id+".memory_available", "Memory available", "mdi:memory", func(m metrics) float64 { return float64(m.MemoryAvailable) / gib }, func(s *platform.Sensor[float64, any]) {
+			s.DeviceClass = "data_size"
+			s.UnitOfMeasurement = "GiB"
+		}),
+		newGauge(id+".disk_used", "Disk usage", "mdi:harddisk", func(m metrics) float64 { return m.DiskUsedPercent }, func(s *platform.Sensor[float64, any]) {
+			s.UnitOfMeasurement = "%"
+		}),
+		newGauge(id+".disk_free", "Disk free", "mdi:harddisk", func(m metrics) float64 { return float64(m.DiskFree) / gib }, func(s *platform.Sensor[float64, any]) {
+			s.DeviceClass = "data_size"
+			s.UnitOfMeasurement = "GiB"
+		}),
+	}
+
+	// Boot time rarely changes and is mostly useful when troubleshooting, so it is a diagnostic entity. Home Assistant
+	// displays timestamps relative to now, which shows the uptime.
+	boot := &hqtt.Component[*platform.Sensor[string, any]]{
+		Platform: &platform.Sensor[string, any]{
+			DeviceClass: "timestamp",
+			State:       mqtt.NewValueWithOptions("state", mqtt.StringMarshaler, retained),
+		},
+		TopicPrefix:    mqtt.JoinTopic(topicPrefix, "boot"),
+		Name:           "Last boot",
+		UniqueID:       id + ".boot",
+		EntityCategory: hass.EntityCategoryDiagnostic,
+	}
+
+	watchdog := hqtt.NewWatchdog(id+".watchdog", mqtt.JoinTopic(topicPrefix, "watchdog"), 3*interval)
+
+	components := map[string]json.MarshalerTo{
+		boot.UniqueID:               boot,
+		watchdog.Component.UniqueID: watchdog.Component,
+	}
+
+	for _, g := range gauges {
+		g.component.TopicPrefix = mqtt.JoinTopic(topicPrefix, discovery.SanitizeID(g.component.UniqueID))
+		components[g.component.UniqueID] = g.component
+	}
+
+	// The connection is closed when its context is done, so keep it open until the device is marked as unavailable
+	connCtx, closeConn := context.WithCancel(context.WithoutCancel(ctx))
+	defer closeConn()
+
+	w, s, disconnect, err := adapter.DialMQTT(connCtx, autopaho.ClientConfig{
+		ServerUrls: []*url.URL{u},
+		KeepAlive:  20,
+		WillMessage: &paho.WillMessage{
+			Topic:   availability.FullyQualifiedTopic(topicPrefix),
+			Payload: []byte(hass.Unavailable),
+			QoS:     byte(retained.QoS),
+			Retain:  retained.Retain,
+		},
+		ClientConfig: paho.ClientConfig{ClientID: "hqtt:example:system_metrics:" + id},
+	})
+	if err != nil {
+		return fmt.Errorf("mqtt: connect: %w", err)
+	}
+
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer shutdownCancel()
+
+		// A graceful shutdown does not trigger the last will, so mark the device as unavailable first
+		_ = mqtt.Error(availability.Write(shutdownCtx, w, topicPrefix, hass.Unavailable))
+		if err := disconnect(shutdownCtx); err != nil {
+			log.With(hqttlog.Error(err)).Error("Failed to disconnect from mqtt")
+		}
+	}()
+
+	m := hqtt.NewDeviceManager(w)
+	m.RediscoveryInterval = time.Hour
+	m.RediscoveryJitter = 5 * time.Minute
+
+	if err = m.Register(d, components); err != nil {
+		return err
+	}
+
+	announce := func(ctx context.Context) error {
+		return errors.Join(m.ConfigureAll(ctx), mqtt.Error(availability.Write(ctx, w, topicPrefix, hass.Available)))
+	}
+
+	// Home Assistant forgets entities that are not retained when it restarts, so announce the device again
+	hassAvailability := discovery.HomeAssistantAvailability("")
+	m.RediscoverOnBirth(ctx, hassAvailability, func(ctx context.Context) error {
+		return mqtt.Error(availability.Write(ctx, w, topicPrefix, hass.Available))
+	})
+
+	if err = s.Subscribe(ctx, hassAvailability, mqtt.Subscription{Topic: hassAvailability.FullyQualifiedTopic("")}); err != nil {
+		return fmt.Errorf("subscribe to home assistant status: %w", err)
+	}
+
+	if err = announce(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		_ = m.RunRediscovery(ctx)
+	}()
+
+	go func() {
+		_ = watchdog.Run(ctx, w)
+	}()
+
+	publish := func() error {
+		snapshot, err := c.collect()
+		if err != nil {
+			return err
+		}
+
+		errs := []error{mqtt.Error(boot.Platform.State.Write(ctx, w, boot.TopicPrefix, snapshot.BootTime.UTC().Format(time.RFC3339)))}
+		for _, g := range gauges {
+			errs = append(errs, mqtt.Error(g.component.Platform.State.Write(ctx, w, g.component.TopicPrefix, g.value(snapshot))))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	log.With(slog.String("broker", u.Redacted()), slog.Duration("interval", interval)).Info("Publishing system metrics")
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err = publish(); err != nil {
+			log.With(hqttlog.Error(err)).Warn("Failed to publish metrics")
+		} else {
+			watchdog.Feed()
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-t.C:
+		}
+	}
+}
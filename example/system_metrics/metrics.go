@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// errUnsupported is returned by collector.collect on platforms this example does not know how to read metrics on.
+var errUnsupported = errors.New("system metrics are not supported on this platform")
+
+// metrics is a snapshot of the host metrics exposed by this example.
+type metrics struct {
+	// Percentage of CPU time spent doing work since the previous snapshot. Zero for the first snapshot.
+	CPUPercent float64
+	// The 1 minute load average
+	Load1 float64
+
+	MemoryUsedPercent float64
+	MemoryAvailable   uint64
+
+	DiskUsedPercent float64
+	DiskFree        uint64
+
+	BootTime time.Time
+}
@@ -0,0 +1,160 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// collector reads host metrics from procfs. CPU usage is calculated from the difference between consecutive calls to
+// collect, so the collector must be reused.
+type collector struct {
+	// The mount point to report disk usage for
+	disk string
+
+	prevIdle, prevTotal uint64
+}
+
+func (c *collector) collect() (metrics, error) {
+	var (
+		m   metrics
+		err error
+	)
+
+	if err = c.readStat(&m); err != nil {
+		return m, err
+	}
+
+	if err = readLoad(&m); err != nil {
+		return m, err
+	}
+
+	if err = readMemory(&m); err != nil {
+		return m, err
+	}
+
+	var fs syscall.Statfs_t
+	if err = syscall.Statfs(c.disk, &fs); err != nil {
+		return m, fmt.Errorf("statfs %s: %w", c.disk, err)
+	}
+
+	// Like df, usage excludes blocks reserved for root
+	used := (fs.Blocks - fs.Bfree) * uint64(fs.Bsize)
+	m.DiskFree = fs.Bavail * uint64(fs.Bsize)
+	if used+m.DiskFree > 0 {
+		m.DiskUsedPercent = 100 * float64(used) / float64(used+m.DiskFree)
+	}
+
+	return m, nil
+}
+
+// readStat reads CPU usage and boot time from /proc/stat.
+func (c *collector) readStat(m *metrics) error {
+	return scanProc("/proc/stat", func(fields []string) error {
+		switch fields[0] {
+		case "cpu":
+			// user nice system idle iowait irq softirq steal, guest time is already included in user and nice
+			var idle, total uint64
+			for i, f := range fields[1:min(len(fields), 9)] {
+				v, err := strconv.ParseUint(f, 10, 64)
+				if err != nil {
+					return err
+				}
+
+				total += v
+				if i == 3 || i == 4 {
+					idle += v
+				}
+			}
+
+			if c.prevTotal > 0 && total > c.prevTotal {
+				m.CPUPercent = 100 * (1 - float64(idle-c.prevIdle)/float64(total-c.prevTotal))
+			}
+
+			c.prevIdle, c.prevTotal = idle, total
+		case "btime":
+			boot, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			m.BootTime = time.Unix(boot, 0)
+		}
+
+		return nil
+	})
+}
+
+func readLoad(m *metrics) error {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("/proc/loadavg: unexpected format %q", data)
+	}
+
+	m.Load1, err = strconv.ParseFloat(fields[0], 64)
+	return err
+}
+
+func readMemory(m *metrics) error {
+	var total, available uint64
+	err := scanProc("/proc/meminfo", func(fields []string) error {
+		var err error
+		switch fields[0] {
+		case "MemTotal:":
+			total, err = strconv.ParseUint(fields[1], 10, 64)
+		case "MemAvailable:":
+			available, err = strconv.ParseUint(fields[1], 10, 64)
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Values in /proc/meminfo are in KiB
+	m.MemoryAvailable = available * 1024
+	if total > 0 {
+		m.MemoryUsedPercent = 100 * float64(total-available) / float64(total)
+	}
+
+	return nil
+}
+
+// scanProc calls fn with the whitespace separated fields of every line in the named procfs file that has at least two
+// fields.
+func scanProc(path string, fn func(fields []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		if err = fn(fields); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return s.Err()
+}
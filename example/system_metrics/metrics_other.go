@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+// collector is not implemented on this platform.
+type collector struct {
+	disk string
+}
+
+func (c *collector) collect() (metrics, error) {
+	return metrics{}, errUnsupported
+}
@@ -0,0 +1,269 @@
+// Command thermostat simulates a thermostat and exposes it to Home Assistant as a hqtt Device with a climate entity, a
+// temperature sensor, and a select for its preset. Commands sent from Home Assistant change the simulated thermostat,
+// which then publishes its new state:
+//
+//	go run ./thermostat -broker mqtt://broker:1883
+package main
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	hqttlog "github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+	adapter "github.com/nlowe/hqtt/mqtt/adapter/autopaho"
+	"github.com/nlowe/hqtt/platform"
+)
+
+const topicPrefix = "hqtt/example/thermostat"
+
+// State is retained so Home Assistant shows the thermostat immediately after it restarts
+var retained = mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}
+
+func main() {
+	brokerURL := flag.String("broker", "mqtt://localhost:1883", "The URL of the MQTT broker")
+	interval := flag.Duration("interval", 10*time.Second, "How often to advance the simulation")
+	flag.Parse()
+
+	hqttlog.To(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	log := hqttlog.ForComponent("thermostat")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, log, *brokerURL, *interval); err != nil && !errors.Is(err, context.Canceled) {
+		log.With(hqttlog.Error(err)).Error("Failed to run")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, log *slog.Logger, brokerURL string, interval time.Duration) error {
+	u, err := url.Parse(brokerURL)
+	if err != nil {
+		return fmt.Errorf("broker: %w", err)
+	}
+
+	// Every entity inherits the availability of the device
+	d := &hqtt.Device{
+		Name:         "Thermostat",
+		Identifiers:  []string{"hqtt-example-thermostat"},
+		Manufacturer: "hqtt",
+		Model:        "Simulated Thermostat",
+		TopicPrefix:  topicPrefix,
+		Availability: mqtt.NewValueWithOptions("available", hass.AvailabilityMarshaler, retained),
+	}
+
+	minTemperature, maxTemperature := minTarget, maxTarget
+	climate := &hqtt.Component[*platform.Climate]{
+		UniqueID:    "example.thermostat.climate",
+		TopicPrefix: mqtt.JoinTopic(topicPrefix, "climate"),
+
+		Platform: &platform.Climate{
+			Action:                   mqtt.NewValueWithOptions("action", hass.HVACActionMarshaler, retained),
+			CurrentTemperature:       mqtt.NewValueWithOptions("temperature/current", mqtt.PrecisionFloatMarshaler(1), retained),
+			Mode:                     mqtt.NewValueWithOptions("mode", hass.HVACModeMarshaler, retained),
+			ModeCommand:              mqtt.NewRemoteValue("mode/set", hass.HVACModeUnmarshaler),
+			Modes:                    []hass.HVACMode{hass.HVACModeOff, hass.HVACModeHeat, hass.HVACModeCool},
+			TargetTemperature:        mqtt.NewValueWithOptions("temperature", mqtt.FloatMarshaler, retained),
+			TargetTemperatureCommand: mqtt.NewRemoteValue("temperature/set", mqtt.FloatUnmarshaler),
+			MinTemperature:           &minTemperature,
+			MaxTemperature:           &maxTemperature,
+			TemperatureStep:          0.5,
+			TemperatureUnit:          platform.ClimateTemperatureUnitCelsius,
+			PresetMode:               mqtt.NewValueWithOptions("preset", mqtt.StringMarshaler, retained),
+			PresetModeCommand:        mqtt.NewRemoteValue("preset/set", mqtt.StringUnmarshaler),
+			PresetModes:              presets,
+		},
+	}
+
+	sensor := &hqtt.Component[*platform.Sensor[float64, any]]{
+		UniqueID:    "example.thermostat.temperature",
+		TopicPrefix: mqtt.JoinTopic(topicPrefix, "temperature"),
+		Name:        "Temperature",
+
+		Platform: &platform.Sensor[float64, any]{
+			DeviceClass:               "temperature",
+			StateClass:                hass.StateClassMeasurement,
+			UnitOfMeasurement:         "°C",
+			SuggestedDisplayPrecision: 1,
+			State:                     mqtt.NewValueWithOptions("state", mqtt.PrecisionFloatMarshaler(1), retained),
+		},
+	}
+
+	// The preset can be changed from the climate entity or from this select, both are kept in sync
+	preset := &hqtt.Component[*platform.Select[string]]{
+		UniqueID:    "example.thermostat.preset",
+		TopicPrefix: mqtt.JoinTopic(topicPrefix, "preset"),
+		Name:        "Preset",
+		Icon:        "mdi:tune",
+
+		Platform: &platform.Select[string]{
+			State:   mqtt.NewValueWithOptions("state", mqtt.StringMarshaler, retained),
+			Command: mqtt.NewRemoteValue("set", mqtt.StringUnmarshaler),
+			Options: presets,
+		},
+	}
+
+	components := map[string]json.MarshalerTo{
+		climate.UniqueID: climate,
+		sensor.UniqueID:  sensor,
+		preset.UniqueID:  preset,
+	}
+
+	// The broker publishes the will if this process dies without disconnecting, so Home Assistant marks the thermostat
+	// unavailable
+	will, err := d.AvailabilityWill()
+	if err != nil {
+		return err
+	}
+
+	// The connection is closed when its context is done, so keep it open until the device is marked as unavailable
+	connCtx, closeConn := context.WithCancel(context.WithoutCancel(ctx))
+	defer closeConn()
+
+	w, s, disconnect, err := adapter.DialMQTTWithOptions(connCtx, autopaho.ClientConfig{
+		ServerUrls:   []*url.URL{u},
+		KeepAlive:    20,
+		ClientConfig: paho.ClientConfig{ClientID: "hqtt:example:thermostat"},
+	}, adapter.Options{Will: &will})
+	if err != nil {
+		return fmt.Errorf("mqtt: connect: %w", err)
+	}
+
+	m := hqtt.NewDeviceManager(w)
+	if err = m.Register(d, components); err != nil {
+		return err
+	}
+
+	availability := hqtt.NewAvailabilityManager(w, d, components)
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer shutdownCancel()
+
+		// Stop handling commands, then mark the thermostat unavailable since a graceful shutdown does not trigger the will
+		if err := m.Close(shutdownCtx); err != nil {
+			log.With(hqttlog.Error(err)).Warn("Timed out waiting for command handlers")
+		}
+
+		if err := availability.Stop(shutdownCtx); err != nil {
+			log.With(hqttlog.Error(err)).Warn("Failed to publish availability")
+		}
+
+		if err := disconnect(shutdownCtx); err != nil {
+			log.With(hqttlog.Error(err)).Error("Failed to disconnect from mqtt")
+		}
+	}()
+
+	t := newThermostat()
+	handleCommands(ctx, log, w, t, climate, preset)
+
+	log.Info("Sending discovery info")
+	if err = m.ConfigureAll(ctx); err != nil {
+		return err
+	}
+
+	if err = errors.Join(publishSettings(ctx, w, t, climate, preset), publishReading(ctx, w, t, climate, sensor)); err != nil {
+		return err
+	}
+
+	if err = errors.Join(m.SubscribeAll(ctx, s), availability.Start(ctx)); err != nil {
+		return err
+	}
+
+	// Home Assistant forgets entities that are not retained when it restarts, so announce the thermostat again
+	status := discovery.HomeAssistantAvailability("")
+	m.RediscoverOnBirth(ctx, status, m.RepublishAll)
+	if err = s.Subscribe(ctx, status, mqtt.Subscription{Topic: status.FullyQualifiedTopic("")}); err != nil {
+		return fmt.Errorf("subscribe to home assistant status: %w", err)
+	}
+
+	log.Info("Running simulation")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+
+		if err = publishReading(ctx, w, t, climate, sensor); err != nil {
+			log.With(hqttlog.Error(err)).Warn("Failed to publish temperature")
+		}
+	}
+}
+
+// handleCommands applies the commands sent from Home Assistant to the thermostat and publishes its new settings. The
+// climate entity is not optimistic: commands that are not supported are logged and the previous state is kept.
+func handleCommands(ctx context.Context, log *slog.Logger, w mqtt.Writer, t *thermostat, climate *hqtt.Component[*platform.Climate], preset *hqtt.Component[*platform.Select[string]]) {
+	publish := func() {
+		if err := publishSettings(ctx, w, t, climate, preset); err != nil {
+			log.With(hqttlog.Error(err)).Warn("Failed to publish settings")
+		}
+	}
+
+	climate.Platform.ModeCommand.Watch(func(mode hass.HVACMode) {
+		log.With(slog.Any("mode", mode)).Info("Home Assistant set mode")
+		if !t.setMode(mode) {
+			log.With(slog.Any("mode", mode)).Warn("Unsupported mode")
+		}
+
+		publish()
+	})
+
+	climate.Platform.TargetTemperatureCommand.Watch(func(target float64) {
+		log.With(slog.Float64("target", target), slog.Float64("clamped", t.setTarget(target))).Info("Home Assistant set target temperature")
+		publish()
+	})
+
+	setPreset := func(p string) {
+		log.With(slog.String("preset", p)).Info("Home Assistant set preset")
+		if _, ok := t.setPreset(p); !ok {
+			log.With(slog.String("preset", p)).Warn("Unknown preset")
+		}
+
+		publish()
+	}
+
+	climate.Platform.PresetModeCommand.Watch(setPreset)
+	preset.Platform.Command.Watch(setPreset)
+}
+
+// publishSettings publishes the mode, preset, and target temperature of the thermostat.
+func publishSettings(ctx context.Context, w mqtt.Writer, t *thermostat, climate *hqtt.Component[*platform.Climate], preset *hqtt.Component[*platform.Select[string]]) error {
+	mode, p, target := t.state()
+
+	return errors.Join(
+		mqtt.Error(climate.Platform.Mode.Write(ctx, w, climate.TopicPrefix, mode)),
+		mqtt.Error(climate.Platform.TargetTemperature.Write(ctx, w, climate.TopicPrefix, target)),
+		mqtt.Error(climate.Platform.PresetMode.Write(ctx, w, climate.TopicPrefix, p)),
+		mqtt.Error(preset.Platform.State.Write(ctx, w, preset.TopicPrefix, p)),
+	)
+}
+
+// publishReading advances the simulation and publishes the resulting temperature and action.
+func publishReading(ctx context.Context, w mqtt.Writer, t *thermostat, climate *hqtt.Component[*platform.Climate], sensor *hqtt.Component[*platform.Sensor[float64, any]]) error {
+	current, action := t.step()
+
+	return errors.Join(
+		mqtt.Error(climate.Platform.CurrentTemperature.Write(ctx, w, climate.TopicPrefix, current)),
+		mqtt.Error(climate.Platform.Action.Write(ctx, w, climate.TopicPrefix, action)),
+		mqtt.Error(sensor.Platform.State.Write(ctx, w, sensor.TopicPrefix, current)),
+	)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/nlowe/hqtt/hass"
+)
+
+// Presets of the thermostat, along with the target temperature each of them selects
+const (
+	presetComfort = "comfort"
+	presetEco     = "eco"
+	presetAway    = "away"
+)
+
+var (
+	presets       = []string{presetComfort, presetEco, presetAway}
+	presetTargets = map[string]float64{presetComfort: 21, presetEco: 18, presetAway: 15}
+)
+
+const (
+	minTarget = 7.0
+	maxTarget = 30.0
+
+	// How far the temperature may drift from the target before the thermostat heats or cools again
+	hysteresis = 0.25
+	// How much the temperature changes every step while heating or cooling
+	rate = 0.5
+	// How much the temperature drifts toward the outside temperature every step otherwise
+	drift = 0.1
+)
+
+// thermostat simulates a thermostat heating or cooling a room toward its target temperature. It is safe for concurrent
+// use, since commands from Home Assistant are handled while the simulation is running.
+type thermostat struct {
+	mu sync.Mutex
+
+	mode    hass.HVACMode
+	preset  string
+	target  float64
+	current float64
+	outside float64
+}
+
+func newThermostat() *thermostat {
+	return &thermostat{
+		mode:    hass.HVACModeHeat,
+		preset:  presetComfort,
+		target:  presetTargets[presetComfort],
+		current: 19,
+		outside: 12,
+	}
+}
+
+// setMode changes the mode of the thermostat, reporting false if it does not support the mode.
+func (t *thermostat) setMode(mode hass.HVACMode) bool {
+	switch mode {
+	case hass.HVACModeOff, hass.HVACModeHeat, hass.HVACModeCool:
+	default:
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.mode = mode
+	return true
+}
+
+// setTarget changes the target temperature, returning it after clamping it to the supported range.
+func (t *thermostat) setTarget(target float64) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.target = min(max(target, minTarget), maxTarget)
+	return t.target
+}
+
+// setPreset selects a preset and its target temperature, reporting false if there is no such preset.
+func (t *thermostat) setPreset(preset string) (float64, bool) {
+	if !slices.Contains(presets, preset) {
+		return 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.preset = preset
+	t.target = presetTargets[preset]
+	return t.target, true
+}
+
+// state returns the current settings of the thermostat.
+func (t *thermostat) state() (mode hass.HVACMode, preset string, target float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.mode, t.preset, t.target
+}
+
+// step advances the simulation, returning the resulting temperature and what the thermostat is doing.
+func (t *thermostat) step() (float64, hass.HVACAction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	action := hass.HVACActionIdle
+	switch {
+	case t.mode == hass.HVACModeOff:
+		action = hass.HVACActionOff
+	case t.mode == hass.HVACModeHeat && t.current < t.target-hysteresis:
+		action = hass.HVACActionHeating
+	case t.mode == hass.HVACModeCool && t.current > t.target+hysteresis:
+		action = hass.HVACActionCooling
+	}
+
+	switch action {
+	case hass.HVACActionHeating:
+		t.current += rate
+	case hass.HVACActionCooling:
+		t.current -= rate
+	default:
+		t.current += math.Copysign(min(drift, math.Abs(t.outside-t.current)), t.outside-t.current)
+	}
+
+	return t.current, action
+}
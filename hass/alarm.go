@@ -0,0 +1,80 @@
+package hass
+
+import (
+	"log/slog"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// AlarmState represents the state of an alarm control panel.
+type AlarmState string
+
+var (
+	AlarmStateMarshaler mqtt.ValueMarshaler[AlarmState] = func(v AlarmState) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	AlarmStateUnmarshaler mqtt.ValueUnmarshaler[AlarmState] = func(bytes []byte) (AlarmState, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return AlarmState(v), err
+	}
+)
+
+const (
+	AlarmStateDisarmed          AlarmState = "disarmed"
+	AlarmStateArmedHome         AlarmState = "armed_home"
+	AlarmStateArmedAway         AlarmState = "armed_away"
+	AlarmStateArmedNight        AlarmState = "armed_night"
+	AlarmStateArmedVacation     AlarmState = "armed_vacation"
+	AlarmStateArmedCustomBypass AlarmState = "armed_custom_bypass"
+	AlarmStatePending           AlarmState = "pending"
+	AlarmStateTriggered         AlarmState = "triggered"
+	AlarmStateArming            AlarmState = "arming"
+	AlarmStateDisarming         AlarmState = "disarming"
+)
+
+// AlarmCommand represents a command Home Assistant sends to an alarm control panel.
+type AlarmCommand string
+
+var (
+	AlarmCommandMarshaler mqtt.ValueMarshaler[AlarmCommand] = func(v AlarmCommand) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	AlarmCommandUnmarshaler mqtt.ValueUnmarshaler[AlarmCommand] = func(bytes []byte) (AlarmCommand, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return AlarmCommand(v), err
+	}
+)
+
+const (
+	AlarmCommandArmHome         AlarmCommand = "ARM_HOME"
+	AlarmCommandArmAway         AlarmCommand = "ARM_AWAY"
+	AlarmCommandArmNight        AlarmCommand = "ARM_NIGHT"
+	AlarmCommandArmVacation     AlarmCommand = "ARM_VACATION"
+	AlarmCommandArmCustomBypass AlarmCommand = "ARM_CUSTOM_BYPASS"
+	AlarmCommandDisarm          AlarmCommand = "DISARM"
+	AlarmCommandTrigger         AlarmCommand = "TRIGGER"
+)
+
+// CustomAlarmCommand provides a way to configure custom values for the commands of an alarm control panel. It
+// implements slog.LogValuer.
+type CustomAlarmCommand struct {
+	ArmHome         AlarmCommand
+	ArmAway         AlarmCommand
+	ArmNight        AlarmCommand
+	ArmVacation     AlarmCommand
+	ArmCustomBypass AlarmCommand
+	Disarm          AlarmCommand
+	Trigger         AlarmCommand
+}
+
+func (c CustomAlarmCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("arm_home_value", string(c.ArmHome)),
+		slog.String("arm_away_value", string(c.ArmAway)),
+		slog.String("arm_night_value", string(c.ArmNight)),
+		slog.String("arm_vacation_value", string(c.ArmVacation)),
+		slog.String("arm_custom_bypass_value", string(c.ArmCustomBypass)),
+		slog.String("disarm_value", string(c.Disarm)),
+		slog.String("trigger_value", string(c.Trigger)),
+	)
+}
@@ -26,6 +26,12 @@ const (
 	Unavailable Availability = "offline"
 )
 
+// AvailabilityState implements hooks.Availability so writes that change availability fire
+// hooks.Hooks.OnAvailabilityChanged.
+func (a Availability) AvailabilityState() string {
+	return string(a)
+}
+
 // CustomAvailability instructs Home Assistant to use different values to determine availability state. It implements
 // slog.LogValuer.
 type CustomAvailability struct {
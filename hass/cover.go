@@ -0,0 +1,82 @@
+package hass
+
+import (
+	"log/slog"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// CoverState represents the state of a cover, like a garage door or blinds.
+type CoverState string
+
+var (
+	CoverStateMarshaler mqtt.ValueMarshaler[CoverState] = func(v CoverState) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	CoverStateUnmarshaler mqtt.ValueUnmarshaler[CoverState] = func(bytes []byte) (CoverState, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return CoverState(v), err
+	}
+)
+
+const (
+	CoverStateOpen    CoverState = "open"
+	CoverStateOpening CoverState = "opening"
+	CoverStateClosed  CoverState = "closed"
+	CoverStateClosing CoverState = "closing"
+	CoverStateStopped CoverState = "stopped"
+)
+
+// CustomCoverState provides a way to configure custom values for the states of a cover. It implements slog.LogValuer.
+type CustomCoverState struct {
+	Open    CoverState
+	Opening CoverState
+	Closed  CoverState
+	Closing CoverState
+	Stopped CoverState
+}
+
+func (c CustomCoverState) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("open_value", string(c.Open)),
+		slog.String("opening_value", string(c.Opening)),
+		slog.String("closed_value", string(c.Closed)),
+		slog.String("closing_value", string(c.Closing)),
+		slog.String("stopped_value", string(c.Stopped)),
+	)
+}
+
+// CoverCommand represents a command Home Assistant sends to a cover.
+type CoverCommand string
+
+var (
+	CoverCommandMarshaler mqtt.ValueMarshaler[CoverCommand] = func(v CoverCommand) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	CoverCommandUnmarshaler mqtt.ValueUnmarshaler[CoverCommand] = func(bytes []byte) (CoverCommand, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return CoverCommand(v), err
+	}
+)
+
+const (
+	CoverCommandOpen  CoverCommand = "OPEN"
+	CoverCommandClose CoverCommand = "CLOSE"
+	CoverCommandStop  CoverCommand = "STOP"
+)
+
+// CustomCoverCommand provides a way to configure custom values for the commands of a cover. It implements
+// slog.LogValuer.
+type CustomCoverCommand struct {
+	Open  CoverCommand
+	Close CoverCommand
+	Stop  CoverCommand
+}
+
+func (c CustomCoverCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("open_value", string(c.Open)),
+		slog.String("close_value", string(c.Close)),
+		slog.String("stop_value", string(c.Stop)),
+	)
+}
@@ -0,0 +1,9 @@
+package hass
+
+const (
+	// EntityCategoryConfig is the entity category for entities that allow changing the configuration of a device.
+	EntityCategoryConfig = "config"
+	// EntityCategoryDiagnostic is the entity category for entities that expose configuration parameters or diagnostics
+	// of a device but do not allow changing them.
+	EntityCategoryDiagnostic = "diagnostic"
+)
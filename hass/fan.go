@@ -0,0 +1,57 @@
+package hass
+
+import (
+	"log/slog"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Oscillation represents whether a fan is oscillating.
+type Oscillation string
+
+var (
+	OscillationMarshaler mqtt.ValueMarshaler[Oscillation] = func(v Oscillation) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	OscillationUnmarshaler mqtt.ValueUnmarshaler[Oscillation] = func(bytes []byte) (Oscillation, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return Oscillation(v), err
+	}
+)
+
+const (
+	OscillationOn  Oscillation = "oscillate_on"
+	OscillationOff Oscillation = "oscillate_off"
+)
+
+// CustomOscillation provides a way to configure custom values for the oscillation of a fan. It implements
+// slog.LogValuer.
+type CustomOscillation struct {
+	On  Oscillation
+	Off Oscillation
+}
+
+func (c CustomOscillation) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("on_value", string(c.On)),
+		slog.String("off_value", string(c.Off)),
+	)
+}
+
+// FanDirection represents the direction a fan is spinning in.
+type FanDirection string
+
+var (
+	FanDirectionMarshaler mqtt.ValueMarshaler[FanDirection] = func(v FanDirection) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	FanDirectionUnmarshaler mqtt.ValueUnmarshaler[FanDirection] = func(bytes []byte) (FanDirection, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return FanDirection(v), err
+	}
+)
+
+const (
+	FanDirectionForward FanDirection = "forward"
+	FanDirectionReverse FanDirection = "reverse"
+)
@@ -0,0 +1,92 @@
+package hass_test
+
+import (
+	"testing"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+)
+
+func FuzzAlarmCommandUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.AlarmCommandUnmarshaler, hass.AlarmCommandMarshaler)
+}
+
+func FuzzAlarmStateUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.AlarmStateUnmarshaler, hass.AlarmStateMarshaler)
+}
+
+func FuzzAvailabilityUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.AvailabilityUnmarshaler, hass.AvailabilityMarshaler)
+}
+
+func FuzzColorModeUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.ColorModeUnmarshaler, hass.ColorModeMarshaler)
+}
+
+func FuzzCoverCommandUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.CoverCommandUnmarshaler, hass.CoverCommandMarshaler)
+}
+
+func FuzzCoverStateUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.CoverStateUnmarshaler, hass.CoverStateMarshaler)
+}
+
+func FuzzFanDirectionUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.FanDirectionUnmarshaler, hass.FanDirectionMarshaler)
+}
+
+func FuzzHumidifierActionUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.HumidifierActionUnmarshaler, hass.HumidifierActionMarshaler)
+}
+
+func FuzzHVACActionUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.HVACActionUnmarshaler, hass.HVACActionMarshaler)
+}
+
+func FuzzHVACModeUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.HVACModeUnmarshaler, hass.HVACModeMarshaler)
+}
+
+func FuzzLawnMowerActivityUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.LawnMowerActivityUnmarshaler, hass.LawnMowerActivityMarshaler)
+}
+
+func FuzzLockCommandUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.LockCommandUnmarshaler, hass.LockCommandMarshaler)
+}
+
+func FuzzLockStateUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.LockStateUnmarshaler, hass.LockStateMarshaler)
+}
+
+func FuzzOscillationUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.OscillationUnmarshaler, hass.OscillationMarshaler)
+}
+
+func FuzzPowerStateUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.PowerStateUnmarshaler, hass.PowerStateMarshaler)
+}
+
+func FuzzStateClassUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.StateClassUnmarshaler, hass.StateClassMarshaler)
+}
+
+func FuzzVacuumActivityUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.VacuumActivityUnmarshaler, hass.VacuumActivityMarshaler)
+}
+
+func FuzzVacuumCommandUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.VacuumCommandUnmarshaler, hass.VacuumCommandMarshaler)
+}
+
+func FuzzValveCommandUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.ValveCommandUnmarshaler, hass.ValveCommandMarshaler)
+}
+
+func FuzzValveStateUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.ValveStateUnmarshaler, hass.ValveStateMarshaler)
+}
+
+func FuzzWaterHeaterModeUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, hass.WaterHeaterModeUnmarshaler, hass.WaterHeaterModeMarshaler)
+}
@@ -0,0 +1,23 @@
+package hass
+
+import "github.com/nlowe/hqtt/mqtt"
+
+// HumidifierAction represents what a humidifier is currently doing.
+type HumidifierAction string
+
+var (
+	HumidifierActionMarshaler mqtt.ValueMarshaler[HumidifierAction] = func(v HumidifierAction) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	HumidifierActionUnmarshaler mqtt.ValueUnmarshaler[HumidifierAction] = func(bytes []byte) (HumidifierAction, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return HumidifierAction(v), err
+	}
+)
+
+const (
+	HumidifierActionOff         HumidifierAction = "off"
+	HumidifierActionHumidifying HumidifierAction = "humidifying"
+	HumidifierActionDrying      HumidifierAction = "drying"
+	HumidifierActionIdle        HumidifierAction = "idle"
+)
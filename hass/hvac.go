@@ -0,0 +1,51 @@
+package hass
+
+import "github.com/nlowe/hqtt/mqtt"
+
+// HVACMode represents the operation mode of a climate device, like a thermostat.
+type HVACMode string
+
+var (
+	HVACModeMarshaler mqtt.ValueMarshaler[HVACMode] = func(v HVACMode) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	HVACModeUnmarshaler mqtt.ValueUnmarshaler[HVACMode] = func(bytes []byte) (HVACMode, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return HVACMode(v), err
+	}
+)
+
+const (
+	HVACModeAuto     HVACMode = "auto"
+	HVACModeOff      HVACMode = "off"
+	HVACModeCool     HVACMode = "cool"
+	HVACModeHeat     HVACMode = "heat"
+	HVACModeDry      HVACMode = "dry"
+	HVACModeFanOnly  HVACMode = "fan_only"
+	HVACModeHeatCool HVACMode = "heat_cool"
+)
+
+// HVACAction represents what a climate device is currently doing, which may differ from its HVACMode. For example, a
+// thermostat in HVACModeHeat is HVACActionIdle once the target temperature is reached.
+type HVACAction string
+
+var (
+	HVACActionMarshaler mqtt.ValueMarshaler[HVACAction] = func(v HVACAction) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	HVACActionUnmarshaler mqtt.ValueUnmarshaler[HVACAction] = func(bytes []byte) (HVACAction, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return HVACAction(v), err
+	}
+)
+
+const (
+	HVACActionOff        HVACAction = "off"
+	HVACActionHeating    HVACAction = "heating"
+	HVACActionCooling    HVACAction = "cooling"
+	HVACActionDrying     HVACAction = "drying"
+	HVACActionIdle       HVACAction = "idle"
+	HVACActionFan        HVACAction = "fan"
+	HVACActionPreheating HVACAction = "preheating"
+	HVACActionDefrosting HVACAction = "defrosting"
+)
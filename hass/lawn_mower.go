@@ -0,0 +1,24 @@
+package hass
+
+import "github.com/nlowe/hqtt/mqtt"
+
+// LawnMowerActivity represents what a lawn mower is currently doing.
+type LawnMowerActivity string
+
+var (
+	LawnMowerActivityMarshaler mqtt.ValueMarshaler[LawnMowerActivity] = func(v LawnMowerActivity) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	LawnMowerActivityUnmarshaler mqtt.ValueUnmarshaler[LawnMowerActivity] = func(bytes []byte) (LawnMowerActivity, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return LawnMowerActivity(v), err
+	}
+)
+
+const (
+	LawnMowerActivityMowing    LawnMowerActivity = "mowing"
+	LawnMowerActivityPaused    LawnMowerActivity = "paused"
+	LawnMowerActivityDocked    LawnMowerActivity = "docked"
+	LawnMowerActivityReturning LawnMowerActivity = "returning"
+	LawnMowerActivityError     LawnMowerActivity = "error"
+)
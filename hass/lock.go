@@ -0,0 +1,88 @@
+package hass
+
+import (
+	"log/slog"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// LockState represents the state of a lock.
+type LockState string
+
+var (
+	LockStateMarshaler mqtt.ValueMarshaler[LockState] = func(v LockState) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	LockStateUnmarshaler mqtt.ValueUnmarshaler[LockState] = func(bytes []byte) (LockState, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return LockState(v), err
+	}
+)
+
+const (
+	LockStateLocked    LockState = "LOCKED"
+	LockStateLocking   LockState = "LOCKING"
+	LockStateUnlocked  LockState = "UNLOCKED"
+	LockStateUnlocking LockState = "UNLOCKING"
+	LockStateJammed    LockState = "JAMMED"
+	LockStateOpen      LockState = "OPEN"
+	LockStateOpening   LockState = "OPENING"
+)
+
+// CustomLockState provides a way to configure custom values for the states of a lock. It implements slog.LogValuer.
+type CustomLockState struct {
+	Locked    LockState
+	Locking   LockState
+	Unlocked  LockState
+	Unlocking LockState
+	Jammed    LockState
+	Open      LockState
+	Opening   LockState
+}
+
+func (c CustomLockState) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("locked_value", string(c.Locked)),
+		slog.String("locking_value", string(c.Locking)),
+		slog.String("unlocked_value", string(c.Unlocked)),
+		slog.String("unlocking_value", string(c.Unlocking)),
+		slog.String("jammed_value", string(c.Jammed)),
+		slog.String("open_value", string(c.Open)),
+		slog.String("opening_value", string(c.Opening)),
+	)
+}
+
+// LockCommand represents a command Home Assistant sends to a lock.
+type LockCommand string
+
+var (
+	LockCommandMarshaler mqtt.ValueMarshaler[LockCommand] = func(v LockCommand) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	LockCommandUnmarshaler mqtt.ValueUnmarshaler[LockCommand] = func(bytes []byte) (LockCommand, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return LockCommand(v), err
+	}
+)
+
+const (
+	LockCommandLock   LockCommand = "LOCK"
+	LockCommandUnlock LockCommand = "UNLOCK"
+	LockCommandOpen   LockCommand = "OPEN"
+)
+
+// CustomLockCommand provides a way to configure custom values for the commands of a lock. It implements
+// slog.LogValuer.
+type CustomLockCommand struct {
+	Lock   LockCommand
+	Unlock LockCommand
+	Open   LockCommand
+}
+
+func (c CustomLockCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("lock_value", string(c.Lock)),
+		slog.String("unlock_value", string(c.Unlock)),
+		slog.String("open_value", string(c.Open)),
+	)
+}
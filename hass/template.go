@@ -0,0 +1,297 @@
+package hass
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTemplate is wrapped by the errors returned by Template.Validate.
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// TemplateFilters is the set of filters Template.Validate accepts: the builtin Jinja filters and the filters Home
+// Assistant adds. Add the filters of custom integrations to it before validating templates that use them.
+var TemplateFilters = map[string]bool{
+	// Jinja
+	"abs": true, "attr": true, "batch": true, "capitalize": true, "center": true, "count": true, "d": true,
+	"default": true, "dictsort": true, "e": true, "escape": true, "filesizeformat": true, "first": true, "float": true,
+	"forceescape": true, "format": true, "groupby": true, "indent": true, "int": true, "items": true, "join": true,
+	"last": true, "length": true, "list": true, "lower": true, "map": true, "max": true, "min": true, "pprint": true,
+	"random": true, "reject": true, "rejectattr": true, "replace": true, "reverse": true, "round": true, "safe": true,
+	"select": true, "selectattr": true, "slice": true, "sort": true, "string": true, "striptags": true, "sum": true,
+	"title": true, "tojson": true, "trim": true, "truncate": true, "unique": true, "upper": true, "urlencode": true,
+	"urlize": true, "wordcount": true, "wordwrap": true, "xmlattr": true,
+
+	// Home Assistant
+	"acos": true, "add": true, "apply": true, "area_devices": true, "area_entities": true, "area_id": true,
+	"area_name": true, "as_datetime": true, "as_function": true, "as_local": true, "as_timedelta": true,
+	"as_timestamp": true, "asin": true, "atan": true, "atan2": true, "average": true, "base64_decode": true,
+	"base64_encode": true, "bitwise_and": true, "bitwise_or": true, "bitwise_xor": true, "bool": true, "closest": true,
+	"combine": true, "contains": true, "cos": true, "device_attr": true, "device_entities": true, "device_id": true,
+	"device_name": true, "difference": true, "expand": true, "flatten": true, "floor_areas": true,
+	"floor_entities": true, "floor_id": true, "floor_name": true, "from_json": true, "has_value": true, "iif": true,
+	"integration_entities": true, "intersect": true, "is_defined": true, "is_device_attr": true, "is_number": true,
+	"is_state": true, "is_state_attr": true, "label_areas": true, "label_devices": true, "label_entities": true,
+	"label_id": true, "label_name": true, "log": true, "md5": true, "median": true, "multiply": true, "ordinal": true,
+	"pack": true, "regex_findall": true, "regex_findall_index": true, "regex_match": true, "regex_replace": true,
+	"regex_search": true, "relative_time": true, "sha1": true, "sha256": true, "sha512": true, "shuffle": true,
+	"sin": true, "slugify": true, "sqrt": true, "state_attr": true, "state_translated": true, "statistical_mode": true,
+	"symmetric_difference": true, "tan": true, "time_since": true, "time_until": true, "timestamp_custom": true,
+	"timestamp_local": true, "timestamp_utc": true, "to_json": true, "typeof": true, "union": true, "unpack": true,
+	"version": true,
+}
+
+// Template is a Home Assistant template (https://www.home-assistant.io/docs/configuration/templating/), like the
+// value_template of a sensor. It implements json.MarshalerTo by validating the template (see Validate) before encoding
+// it as a string, so discovery payloads with broken templates fail to render instead of Home Assistant silently
+// ignoring the entity.
+type Template string
+
+// MarshalJSONTo implements json.MarshalerTo.
+func (t Template) MarshalJSONTo(e *jsontext.Encoder) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	return e.WriteToken(jsontext.String(string(t)))
+}
+
+// blockEnds maps the tags that start a block to the tags that end them.
+var blockEnds = map[string]string{
+	"if":     "endif",
+	"for":    "endfor",
+	"macro":  "endmacro",
+	"call":   "endcall",
+	"filter": "endfilter",
+	"set":    "endset",
+	"with":   "endwith",
+	"block":  "endblock",
+}
+
+// Validate performs basic sanity checks on the template without evaluating it: every expression, statement, and
+// comment is closed, brackets and string literals inside them are balanced, blocks like {% if %} are closed with the
+// matching end tag, and every filter is in TemplateFilters. It returns an error wrapping ErrInvalidTemplate for the
+// first problem it finds. It cannot catch errors that only occur when Home Assistant renders the template, like
+// referencing an entity that does not exist.
+func (t Template) Validate() error {
+	v := templateValidator{s: string(t)}
+	return v.validate()
+}
+
+// openingBrackets maps closing brackets to the brackets they close.
+var openingBrackets = map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+// templateValidator scans a template for Template.Validate.
+type templateValidator struct {
+	s      string
+	blocks []string
+}
+
+func (v *templateValidator) errorf(offset int, format string, args ...any) error {
+	return fmt.Errorf("%w: offset %d: %s", ErrInvalidTemplate, offset, fmt.Sprintf(format, args...))
+}
+
+func (v *templateValidator) validate() error {
+	for i := 0; i < len(v.s); {
+		start := strings.Index(v.s[i:], "{")
+		if start < 0 || i+start+1 >= len(v.s) {
+			break
+		}
+
+		start += i
+		var (
+			end int
+			err error
+		)
+
+		switch v.s[start+1] {
+		case '{':
+			end, err = v.tag(start, "}}", false)
+		case '%':
+			end, err = v.tag(start, "%}", true)
+		case '#':
+			closing := strings.Index(v.s[start+2:], "#}")
+			if closing < 0 {
+				return v.errorf(start, "comment is not closed")
+			}
+
+			end = start + 2 + closing + 2
+		default:
+			end = start + 1
+		}
+
+		if err != nil {
+			return err
+		}
+
+		i = end
+	}
+
+	if len(v.blocks) > 0 {
+		return v.errorf(len(v.s), "{%% %s %%} is not closed", v.blocks[len(v.blocks)-1])
+	}
+
+	return nil
+}
+
+// tag scans the expression or statement starting at start until the closing delimiter, returning the offset after it.
+func (v *templateValidator) tag(start int, closing string, statement bool) (int, error) {
+	var brackets []byte
+	var words []string
+
+	i := start + 2
+	for i < len(v.s) {
+		c := v.s[i]
+
+		switch {
+		case len(brackets) == 0 && strings.HasPrefix(v.s[i:], closing):
+			body := strings.Trim(v.s[start+2:i], "-+ \t\r\n")
+			if body == "" {
+				return 0, v.errorf(start, "empty %s", v.kind(statement))
+			}
+
+			end := i + len(closing)
+			if statement {
+				return v.statement(start, end, words)
+			}
+
+			return end, nil
+		case c == '\'' || c == '"':
+			closingQuote := v.stringEnd(i)
+			if closingQuote < 0 {
+				return 0, v.errorf(i, "string literal is not closed")
+			}
+
+			i = closingQuote + 1
+			continue
+		case c == '(' || c == '[' || c == '{':
+			brackets = append(brackets, c)
+		case c == ')' || c == ']' || c == '}':
+			if len(brackets) > 0 && brackets[len(brackets)-1] != openingBrackets[c] && strings.HasPrefix(v.s[i:], closing) {
+				return 0, v.errorf(start, "%q is not closed", brackets[len(brackets)-1])
+			}
+
+			if len(brackets) == 0 || brackets[len(brackets)-1] != openingBrackets[c] {
+				return 0, v.errorf(i, "unexpected %q", c)
+			}
+
+			brackets = brackets[:len(brackets)-1]
+		case c == '|':
+			name, next := v.identifier(i + 1)
+			if name == "" {
+				return 0, v.errorf(i, "filter name is missing")
+			}
+
+			if !TemplateFilters[name] {
+				return 0, v.errorf(i, "unknown filter %q", name)
+			}
+
+			i = next
+			continue
+		case isIdentifierStart(c):
+			word, next := v.identifier(i)
+			words = append(words, word)
+
+			i = next
+			continue
+		}
+
+		i++
+	}
+
+	if len(brackets) > 0 {
+		return 0, v.errorf(start, "%q is not closed", brackets[len(brackets)-1])
+	}
+
+	return 0, v.errorf(start, "%s is not closed", v.kind(statement))
+}
+
+func (v *templateValidator) kind(statement bool) string {
+	if statement {
+		return "statement"
+	}
+
+	return "expression"
+}
+
+// statement checks that the statement starting at start with the provided words opens or closes a block correctly, and
+// returns the offset to continue scanning at.
+func (v *templateValidator) statement(start, end int, words []string) (int, error) {
+	if len(words) == 0 {
+		return end, nil
+	}
+
+	tag := words[0]
+	switch {
+	case tag == "raw":
+		// The contents of raw blocks are not parsed
+		closing := strings.Index(v.s[end:], "endraw")
+		if closing < 0 {
+			return 0, v.errorf(start, "{%% raw %%} is not closed")
+		}
+
+		closeTag := strings.Index(v.s[end+closing:], "%}")
+		if closeTag < 0 {
+			return 0, v.errorf(end+closing, "statement is not closed")
+		}
+
+		return end + closing + closeTag + 2, nil
+	case tag == "set" && strings.Contains(v.s[start:end], "="):
+		// {% set x = y %} does not start a block
+		return end, nil
+	case blockEnds[tag] != "":
+		v.blocks = append(v.blocks, tag)
+	case tag == "elif" || tag == "else":
+		if len(v.blocks) == 0 || (v.blocks[len(v.blocks)-1] != "if" && v.blocks[len(v.blocks)-1] != "for") {
+			return 0, v.errorf(start, "{%% %s %%} outside of {%% if %%} or {%% for %%}", tag)
+		}
+	case strings.HasPrefix(tag, "end"):
+		if len(v.blocks) == 0 {
+			return 0, v.errorf(start, "unexpected {%% %s %%}", tag)
+		}
+
+		open := v.blocks[len(v.blocks)-1]
+		if blockEnds[open] != tag {
+			return 0, v.errorf(start, "{%% %s %%} closes {%% %s %%}, expected {%% %s %%}", tag, open, blockEnds[open])
+		}
+
+		v.blocks = v.blocks[:len(v.blocks)-1]
+	}
+
+	return end, nil
+}
+
+// stringEnd returns the offset of the quote closing the string literal starting at start, or -1 if it is not closed.
+func (v *templateValidator) stringEnd(start int) int {
+	quote := v.s[start]
+	for i := start + 1; i < len(v.s); i++ {
+		switch v.s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// identifier returns the identifier at start after skipping whitespace, and the offset after it.
+func (v *templateValidator) identifier(start int) (string, int) {
+	i := start
+	for i < len(v.s) && strings.IndexByte(" \t\r\n", v.s[i]) >= 0 {
+		i++
+	}
+
+	begin := i
+	for i < len(v.s) && (isIdentifierStart(v.s[i]) || (i > begin && v.s[i] >= '0' && v.s[i] <= '9')) {
+		i++
+	}
+
+	return v.s[begin:i], i
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,76 @@
+package hass_test
+
+import (
+	"encoding/json/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+)
+
+func TestTemplate_Validate(t *testing.T) {
+	for name, template := range map[string]hass.Template{
+		"Plain Text":         "ON",
+		"Expression":         "{{ value_json.temperature | float(0) | round(1) }}",
+		"Whitespace Control": "{{- value -}}",
+		"Brackets":           "{{ value_json['state'] in ['on', 'ON'] and (value | length) > 0 }}",
+		"Dict Literal":       `{{ {'brightness': value | int, 'state': "ON"} | to_json }}`,
+		"Quotes":             `{{ "}}|{%" ~ 'it\'s' }}`,
+		"Blocks":             "{% if value == 'on' %}ON{% elif value == 'off' %}OFF{% else %}{{ value | upper }}{% endif %}",
+		"Nested Blocks":      "{% for k, v in value_json.items() %}{% if v %}{{ k }}{% endif %}{% else %}none{% endfor %}",
+		"Set":                "{% set t = value | float %}{{ t * 10 }}",
+		"Set Block":          "{% set t %}{{ value }}{% endset %}{{ t }}",
+		"Comment":            "{# {{ unclosed #}{{ value }}",
+		"Raw":                "{% raw %}{{ not | checked {% endraw %}",
+		"Literal Braces":     "{ not a tag } {{ value }}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, template.Validate())
+		})
+	}
+
+	for name, tt := range map[string]struct {
+		template hass.Template
+		err      string
+	}{
+		"Unclosed Expression": {template: "{{ value", err: "offset 0: expression is not closed"},
+		"Unclosed Statement":  {template: "{% if value %}on", err: "offset 16: {% if %} is not closed"},
+		"Unclosed Comment":    {template: "{{ value }}{# todo", err: "offset 11: comment is not closed"},
+		"Unclosed String":     {template: "{{ value == 'on }}", err: "offset 12: string literal is not closed"},
+		"Unclosed Bracket":    {template: "{{ (value | int }}", err: `offset 0: '(' is not closed`},
+		"Unexpected Bracket":  {template: "{{ value] }}", err: `offset 8: unexpected ']'`},
+		"Empty Expression":    {template: "{{ }}", err: "offset 0: empty expression"},
+		"Unknown Filter":      {template: "{{ value | frobnicate }}", err: `offset 9: unknown filter "frobnicate"`},
+		"Missing Filter":      {template: "{{ value | }}", err: "offset 9: filter name is missing"},
+		"Mismatched End":      {template: "{% if value %}{% endfor %}", err: "offset 14: {% endfor %} closes {% if %}, expected {% endif %}"},
+		"Unexpected End":      {template: "{% endif %}", err: "offset 0: unexpected {% endif %}"},
+		"Stray Else":          {template: "{% else %}", err: "offset 0: {% else %} outside of {% if %} or {% for %}"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tt.template.Validate()
+			require.ErrorIs(t, err, hass.ErrInvalidTemplate)
+			assert.EqualError(t, err, "invalid template: "+tt.err)
+		})
+	}
+
+	t.Run("Custom Filter", func(t *testing.T) {
+		template := hass.Template("{{ value | frobnicate }}")
+		require.Error(t, template.Validate())
+
+		hass.TemplateFilters["frobnicate"] = true
+		t.Cleanup(func() { delete(hass.TemplateFilters, "frobnicate") })
+
+		require.NoError(t, template.Validate())
+	})
+}
+
+func TestTemplate_MarshalJSONTo(t *testing.T) {
+	got, err := json.Marshal(hass.Template("{{ value | int }}"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `"{{ value | int }}"`, string(got))
+
+	_, err = json.Marshal(hass.Template("{{ value | int"))
+	require.ErrorIs(t, err, hass.ErrInvalidTemplate)
+}
@@ -0,0 +1,73 @@
+package hass
+
+import (
+	"log/slog"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// VacuumActivity represents what a vacuum is currently doing.
+type VacuumActivity string
+
+var (
+	VacuumActivityMarshaler mqtt.ValueMarshaler[VacuumActivity] = func(v VacuumActivity) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	VacuumActivityUnmarshaler mqtt.ValueUnmarshaler[VacuumActivity] = func(bytes []byte) (VacuumActivity, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return VacuumActivity(v), err
+	}
+)
+
+const (
+	VacuumActivityCleaning  VacuumActivity = "cleaning"
+	VacuumActivityDocked    VacuumActivity = "docked"
+	VacuumActivityPaused    VacuumActivity = "paused"
+	VacuumActivityIdle      VacuumActivity = "idle"
+	VacuumActivityReturning VacuumActivity = "returning"
+	VacuumActivityError     VacuumActivity = "error"
+)
+
+// VacuumCommand represents a command Home Assistant sends to a vacuum.
+type VacuumCommand string
+
+var (
+	VacuumCommandMarshaler mqtt.ValueMarshaler[VacuumCommand] = func(v VacuumCommand) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	VacuumCommandUnmarshaler mqtt.ValueUnmarshaler[VacuumCommand] = func(bytes []byte) (VacuumCommand, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return VacuumCommand(v), err
+	}
+)
+
+const (
+	VacuumCommandStart        VacuumCommand = "start"
+	VacuumCommandPause        VacuumCommand = "pause"
+	VacuumCommandStop         VacuumCommand = "stop"
+	VacuumCommandReturnToBase VacuumCommand = "return_to_base"
+	VacuumCommandCleanSpot    VacuumCommand = "clean_spot"
+	VacuumCommandLocate       VacuumCommand = "locate"
+)
+
+// CustomVacuumCommand provides a way to configure custom values for the commands of a vacuum. It implements
+// slog.LogValuer.
+type CustomVacuumCommand struct {
+	Start        VacuumCommand
+	Pause        VacuumCommand
+	Stop         VacuumCommand
+	ReturnToBase VacuumCommand
+	CleanSpot    VacuumCommand
+	Locate       VacuumCommand
+}
+
+func (c CustomVacuumCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("start_value", string(c.Start)),
+		slog.String("pause_value", string(c.Pause)),
+		slog.String("stop_value", string(c.Stop)),
+		slog.String("return_to_base_value", string(c.ReturnToBase)),
+		slog.String("clean_spot_value", string(c.CleanSpot)),
+		slog.String("locate_value", string(c.Locate)),
+	)
+}
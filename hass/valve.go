@@ -0,0 +1,79 @@
+package hass
+
+import (
+	"log/slog"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// ValveState represents the state of a valve that does not report its position.
+type ValveState string
+
+var (
+	ValveStateMarshaler mqtt.ValueMarshaler[ValveState] = func(v ValveState) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	ValveStateUnmarshaler mqtt.ValueUnmarshaler[ValveState] = func(bytes []byte) (ValveState, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return ValveState(v), err
+	}
+)
+
+const (
+	ValveStateOpen    ValveState = "open"
+	ValveStateOpening ValveState = "opening"
+	ValveStateClosed  ValveState = "closed"
+	ValveStateClosing ValveState = "closing"
+)
+
+// CustomValveState provides a way to configure custom values for the states of a valve. It implements slog.LogValuer.
+type CustomValveState struct {
+	Open    ValveState
+	Opening ValveState
+	Closed  ValveState
+	Closing ValveState
+}
+
+func (c CustomValveState) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("open_value", string(c.Open)),
+		slog.String("opening_value", string(c.Opening)),
+		slog.String("closed_value", string(c.Closed)),
+		slog.String("closing_value", string(c.Closing)),
+	)
+}
+
+// ValveCommand represents a command Home Assistant sends to a valve that does not report its position.
+type ValveCommand string
+
+var (
+	ValveCommandMarshaler mqtt.ValueMarshaler[ValveCommand] = func(v ValveCommand) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	ValveCommandUnmarshaler mqtt.ValueUnmarshaler[ValveCommand] = func(bytes []byte) (ValveCommand, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return ValveCommand(v), err
+	}
+)
+
+const (
+	ValveCommandOpen  ValveCommand = "OPEN"
+	ValveCommandClose ValveCommand = "CLOSE"
+	ValveCommandStop  ValveCommand = "STOP"
+)
+
+// CustomValveCommand provides a way to configure custom values for the commands of a valve. It implements
+// slog.LogValuer.
+type CustomValveCommand struct {
+	Open  ValveCommand
+	Close ValveCommand
+	Stop  ValveCommand
+}
+
+func (c CustomValveCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("open_value", string(c.Open)),
+		slog.String("close_value", string(c.Close)),
+		slog.String("stop_value", string(c.Stop)),
+	)
+}
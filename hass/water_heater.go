@@ -0,0 +1,26 @@
+package hass
+
+import "github.com/nlowe/hqtt/mqtt"
+
+// WaterHeaterMode represents the operation mode of a water heater.
+type WaterHeaterMode string
+
+var (
+	WaterHeaterModeMarshaler mqtt.ValueMarshaler[WaterHeaterMode] = func(v WaterHeaterMode) ([]byte, error) {
+		return mqtt.StringMarshaler(string(v))
+	}
+	WaterHeaterModeUnmarshaler mqtt.ValueUnmarshaler[WaterHeaterMode] = func(bytes []byte) (WaterHeaterMode, error) {
+		v, err := mqtt.StringUnmarshaler(bytes)
+		return WaterHeaterMode(v), err
+	}
+)
+
+const (
+	WaterHeaterModeOff         WaterHeaterMode = "off"
+	WaterHeaterModeEco         WaterHeaterMode = "eco"
+	WaterHeaterModeElectric    WaterHeaterMode = "electric"
+	WaterHeaterModeGas         WaterHeaterMode = "gas"
+	WaterHeaterModeHeatPump    WaterHeaterMode = "heat_pump"
+	WaterHeaterModeHighDemand  WaterHeaterMode = "high_demand"
+	WaterHeaterModePerformance WaterHeaterMode = "performance"
+)
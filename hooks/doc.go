@@ -0,0 +1,4 @@
+// Package hooks provides lifecycle hooks for hqtt. Applications can register callbacks with Register to observe
+// discovery payloads being published, commands being received, state being written, and availability changing without
+// wrapping every Value. This is useful for metrics, auditing, and custom automation.
+package hooks
@@ -0,0 +1,240 @@
+package hooks
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// DiscoveryEvent describes a device discovery payload being published. It implements slog.LogValuer.
+type DiscoveryEvent struct {
+	// The ID of the Device the payload was published for
+	DeviceID string
+	// The topic the payload was published to
+	Topic string
+	// The discovery payload
+	Payload []byte
+	// The error returned when publishing the payload, if any
+	Err error
+}
+
+func (e DiscoveryEvent) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("device", e.DeviceID),
+		slog.String("topic", e.Topic),
+		slog.Int("size", len(e.Payload)),
+		slog.Any("error", e.Err),
+	)
+}
+
+// CommandEvent describes a message received by a RemoteValue. It implements slog.LogValuer.
+type CommandEvent struct {
+	// The topic of the message as it was routed to the RemoteValue. This is relative to the Component's topic prefix
+	// unless the RemoteValue has an absolute topic.
+	Topic string
+	// The message payload. Like the message passed to mqtt.Handler, it is only valid until the callback returns, so
+	// copy it if it must be retained.
+	Payload []byte
+	// The error returned when unmarshalling the payload, if any
+	Err error
+}
+
+func (e CommandEvent) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("topic", e.Topic),
+		slog.Int("size", len(e.Payload)),
+		slog.Any("error", e.Err),
+	)
+}
+
+// StateEvent describes a Value being written to MQTT. It implements slog.LogValuer.
+type StateEvent struct {
+	// The topic the value was written to
+	Topic string
+	// The Quality of Service the value was written with
+	QoS uint8
+	// Whether the value was retained
+	Retain bool
+	// The marshaled value. Streaming values (see mqtt.NewStreamingValue) reuse their buffer, so it is only valid until
+	// the callback returns. Copy it if it must be retained.
+	Payload []byte
+	// The error returned when writing the value, if any
+	Err error
+}
+
+func (e StateEvent) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("topic", e.Topic),
+		slog.Int("qos", int(e.QoS)),
+		slog.Bool("retain", e.Retain),
+		slog.Int("size", len(e.Payload)),
+		slog.Any("error", e.Err),
+	)
+}
+
+// AvailabilityEvent describes a change in availability written to MQTT. It implements slog.LogValuer.
+type AvailabilityEvent struct {
+	// The availability topic
+	Topic string
+	// The previous availability, or the empty string if availability was not previously written
+	Previous string
+	// The new availability
+	Current string
+}
+
+func (e AvailabilityEvent) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("topic", e.Topic),
+		slog.String("previous", e.Previous),
+		slog.String("current", e.Current),
+	)
+}
+
+// ConnectionEvent describes a connection to the MQTT broker being established. It implements slog.LogValuer.
+type ConnectionEvent struct {
+	// The adapter that connected, such as the mqtt.Writer returned by mqtt/adapter/autopaho.DialMQTT. It identifies which
+	// connection the event is for when an application connects to more than one broker. Nil if the adapter does not
+	// identify itself.
+	Client any
+
+	// Whether the connection was re-established after it was lost, rather than established for the first time
+	Reconnect bool
+}
+
+func (e ConnectionEvent) LogValue() slog.Value {
+	return slog.GroupValue(slog.Bool("reconnect", e.Reconnect))
+}
+
+// Availability is implemented by types that represent availability (such as hass.Availability). Writing a Value that
+// holds one of these types fires Hooks.OnAvailabilityChanged when the availability changes.
+type Availability interface {
+	AvailabilityState() string
+}
+
+// Hooks holds callbacks for hqtt lifecycle events. Any callback may be nil. Callbacks are called synchronously from the
+// goroutine that caused the event, so they must not block.
+type Hooks struct {
+	// OnDiscoveryPublished is called after a Device discovery payload is published
+	OnDiscoveryPublished func(ctx context.Context, e DiscoveryEvent)
+	// OnCommandReceived is called after a RemoteValue receives a command, even if it could not be unmarshalled. It is
+	// not called for passive values (see mqtt.RemoteValue.Passive), such as Home Assistant's status topic.
+	OnCommandReceived func(ctx context.Context, e CommandEvent)
+	// OnStateWritten is called after a Value is written
+	OnStateWritten func(ctx context.Context, e StateEvent)
+	// OnAvailabilityChanged is called after a Value holding an Availability is written with a different availability
+	OnAvailabilityChanged func(ctx context.Context, e AvailabilityEvent)
+	// OnConnectionUp is called after an adapter (such as mqtt/adapter/autopaho) connects to the broker
+	OnConnectionUp func(ctx context.Context, e ConnectionEvent)
+}
+
+type registration struct {
+	hooks Hooks
+}
+
+var (
+	mu         sync.Mutex
+	registered atomic.Pointer[[]*registration]
+)
+
+// Register adds the provided Hooks. Call the returned function to remove them.
+func Register(h Hooks) (unregister func()) {
+	r := &registration{hooks: h}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var next []*registration
+	if current := registered.Load(); current != nil {
+		next = append(next, *current...)
+	}
+
+	next = append(next, r)
+	registered.Store(&next)
+
+	return sync.OnceFunc(func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		current := registered.Load()
+		if current == nil {
+			return
+		}
+
+		var next []*registration
+		for _, candidate := range *current {
+			if candidate != r {
+				next = append(next, candidate)
+			}
+		}
+
+		registered.Store(&next)
+	})
+}
+
+// Enabled returns true if any Hooks are registered. It is used to avoid building events when nobody is listening.
+func Enabled() bool {
+	current := registered.Load()
+	return current != nil && len(*current) > 0
+}
+
+func each(f func(h *Hooks)) {
+	current := registered.Load()
+	if current == nil {
+		return
+	}
+
+	for _, r := range *current {
+		f(&r.hooks)
+	}
+}
+
+// DiscoveryPublished calls every registered OnDiscoveryPublished hook. It is called by hqtt and applications typically
+// do not need to call it.
+func DiscoveryPublished(ctx context.Context, e DiscoveryEvent) {
+	each(func(h *Hooks) {
+		if h.OnDiscoveryPublished != nil {
+			h.OnDiscoveryPublished(ctx, e)
+		}
+	})
+}
+
+// CommandReceived calls every registered OnCommandReceived hook. It is called by hqtt and applications typically do
+// not need to call it.
+func CommandReceived(ctx context.Context, e CommandEvent) {
+	each(func(h *Hooks) {
+		if h.OnCommandReceived != nil {
+			h.OnCommandReceived(ctx, e)
+		}
+	})
+}
+
+// StateWritten calls every registered OnStateWritten hook. It is called by hqtt and applications typically do not need
+// to call it.
+func StateWritten(ctx context.Context, e StateEvent) {
+	each(func(h *Hooks) {
+		if h.OnStateWritten != nil {
+			h.OnStateWritten(ctx, e)
+		}
+	})
+}
+
+// AvailabilityChanged calls every registered OnAvailabilityChanged hook. It is called by hqtt and applications
+// typically do not need to call it.
+func AvailabilityChanged(ctx context.Context, e AvailabilityEvent) {
+	each(func(h *Hooks) {
+		if h.OnAvailabilityChanged != nil {
+			h.OnAvailabilityChanged(ctx, e)
+		}
+	})
+}
+
+// ConnectionUp calls every registered OnConnectionUp hook. It is called by adapters and applications typically do not
+// need to call it.
+func ConnectionUp(ctx context.Context, e ConnectionEvent) {
+	each(func(h *Hooks) {
+		if h.OnConnectionUp != nil {
+			h.OnConnectionUp(ctx, e)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package hooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	require.False(t, Enabled())
+
+	var got []StateEvent
+	unregister := Register(Hooks{
+		OnStateWritten: func(_ context.Context, e StateEvent) {
+			got = append(got, e)
+		},
+	})
+
+	require.True(t, Enabled())
+
+	// Hooks without a callback for an event are skipped
+	defer Register(Hooks{})()
+
+	StateWritten(t.Context(), StateEvent{Topic: "foo"})
+	DiscoveryPublished(t.Context(), DiscoveryEvent{Topic: "bar"})
+
+	unregister()
+	unregister()
+
+	StateWritten(t.Context(), StateEvent{Topic: "fizz"})
+
+	assert.Equal(t, []StateEvent{{Topic: "foo"}}, got)
+}
@@ -0,0 +1,181 @@
+package prometheus
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hooks"
+)
+
+// DefaultNamespace is the namespace of every metric unless Options.Namespace is set.
+const DefaultNamespace = "hqtt"
+
+// Options configures the metrics exported by a Collector.
+type Options struct {
+	// Namespace is prepended to the name of every metric, DefaultNamespace if empty.
+	Namespace string
+
+	// ConstLabels are added to every metric, for example to identify the bridge when several run on one host.
+	ConstLabels prometheus.Labels
+}
+
+// Collector is a prometheus.Collector that counts the events reported to hqtt hooks. Register it with a
+// prometheus.Registerer, then call Collector.Register to start counting events. It exports the following metrics
+// (without their namespace):
+//
+//   - discovery_published_total{device}: Device discovery payloads published
+//   - discovery_publish_errors_total{device}: Device discovery payloads that could not be published
+//   - state_published_total{topic}: Values written
+//   - state_publish_errors_total{topic}: Values that could not be written
+//   - state_published_bytes_total{topic}: Bytes of the payloads of Values written successfully
+//   - commands_received_total{topic}: Messages received by RemoteValues
+//   - command_errors_total{topic}: Messages received by RemoteValues that could not be unmarshalled
+//   - commands_received_bytes_total{topic}: Bytes of the payloads of messages received by RemoteValues
+//   - available{topic}: 1 if the availability last written to a topic was online, and 0 otherwise
+//   - connections_total: Connections established to the broker, including reconnects
+//   - reconnects_total: Connections re-established to the broker after the connection was lost
+//
+// The topic of commands is the topic the message was routed to the RemoteValue with, which is relative to the topic
+// prefix of its Component unless the RemoteValue has an absolute topic. Topics are used as labels, so the number of
+// series grows with the number of entities a bridge exposes.
+type Collector struct {
+	discoveryPublished    *prometheus.CounterVec
+	discoveryPublishErrs  *prometheus.CounterVec
+	statePublished        *prometheus.CounterVec
+	statePublishErrs      *prometheus.CounterVec
+	statePublishedBytes   *prometheus.CounterVec
+	commandsReceived      *prometheus.CounterVec
+	commandErrs           *prometheus.CounterVec
+	commandsReceivedBytes *prometheus.CounterVec
+	available             *prometheus.GaugeVec
+	connections           prometheus.Counter
+	reconnects            prometheus.Counter
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector constructs a Collector with the provided Options.
+func NewCollector(opts Options) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = DefaultNamespace
+	}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: opts.ConstLabels,
+		}, labels)
+	}
+
+	return &Collector{
+		discoveryPublished:    counter("discovery_published_total", "Device discovery payloads published.", "device"),
+		discoveryPublishErrs:  counter("discovery_publish_errors_total", "Device discovery payloads that could not be published.", "device"),
+		statePublished:        counter("state_published_total", "Values written.", "topic"),
+		statePublishErrs:      counter("state_publish_errors_total", "Values that could not be written.", "topic"),
+		statePublishedBytes:   counter("state_published_bytes_total", "Bytes of the payloads of values written successfully.", "topic"),
+		commandsReceived:      counter("commands_received_total", "Messages received by remote values.", "topic"),
+		commandErrs:           counter("command_errors_total", "Messages received by remote values that could not be unmarshalled.", "topic"),
+		commandsReceivedBytes: counter("commands_received_bytes_total", "Bytes of the payloads of messages received by remote values.", "topic"),
+		available: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "available",
+			Help:        "Whether the availability last written to a topic was online.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"topic"}),
+		connections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "connections_total",
+			Help:        "Connections established to the broker, including reconnects.",
+			ConstLabels: opts.ConstLabels,
+		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "reconnects_total",
+			Help:        "Connections re-established to the broker after the connection was lost.",
+			ConstLabels: opts.ConstLabels,
+		}),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.discoveryPublished,
+		c.discoveryPublishErrs,
+		c.statePublished,
+		c.statePublishErrs,
+		c.statePublishedBytes,
+		c.commandsReceived,
+		c.commandErrs,
+		c.commandsReceivedBytes,
+		c.available,
+		c.connections,
+		c.reconnects,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+// Hooks returns the hooks that count events for this Collector. Use Register to register them, or combine them with
+// other hooks.
+func (c *Collector) Hooks() hooks.Hooks {
+	return hooks.Hooks{
+		OnDiscoveryPublished: func(_ context.Context, e hooks.DiscoveryEvent) {
+			c.discoveryPublished.WithLabelValues(e.DeviceID).Inc()
+			if e.Err != nil {
+				c.discoveryPublishErrs.WithLabelValues(e.DeviceID).Inc()
+			}
+		},
+		OnCommandReceived: func(_ context.Context, e hooks.CommandEvent) {
+			c.commandsReceived.WithLabelValues(e.Topic).Inc()
+			c.commandsReceivedBytes.WithLabelValues(e.Topic).Add(float64(len(e.Payload)))
+			if e.Err != nil {
+				c.commandErrs.WithLabelValues(e.Topic).Inc()
+			}
+		},
+		OnStateWritten: func(_ context.Context, e hooks.StateEvent) {
+			c.statePublished.WithLabelValues(e.Topic).Inc()
+			if e.Err != nil {
+				c.statePublishErrs.WithLabelValues(e.Topic).Inc()
+				return
+			}
+
+			c.statePublishedBytes.WithLabelValues(e.Topic).Add(float64(len(e.Payload)))
+		},
+		OnAvailabilityChanged: func(_ context.Context, e hooks.AvailabilityEvent) {
+			available := 0.0
+			if e.Current == string(hass.Available) {
+				available = 1
+			}
+
+			c.available.WithLabelValues(e.Topic).Set(available)
+		},
+		OnConnectionUp: func(_ context.Context, e hooks.ConnectionEvent) {
+			c.connections.Inc()
+			if e.Reconnect {
+				c.reconnects.Inc()
+			}
+		},
+	}
+}
+
+// Register registers the hooks of this Collector (see Hooks) so it starts counting events. Call the returned function
+// to stop counting.
+func (c *Collector) Register() (unregister func()) {
+	return hooks.Register(c.Hooks())
+}
@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hooks"
+)
+
+func TestCollector(t *testing.T) {
+	sut := NewCollector(Options{ConstLabels: prometheus.Labels{"bridge": "garage"}})
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(sut))
+
+	defer sut.Register()()
+
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	hooks.DiscoveryPublished(ctx, hooks.DiscoveryEvent{DeviceID: "garage", Payload: []byte("{}")})
+	hooks.DiscoveryPublished(ctx, hooks.DiscoveryEvent{DeviceID: "garage", Err: boom})
+	hooks.StateWritten(ctx, hooks.StateEvent{Topic: "garage/door/state", Payload: []byte("open")})
+	hooks.StateWritten(ctx, hooks.StateEvent{Topic: "garage/door/state", Payload: []byte("closed"), Err: boom})
+	hooks.CommandReceived(ctx, hooks.CommandEvent{Topic: "command", Payload: []byte("OPEN")})
+	hooks.CommandReceived(ctx, hooks.CommandEvent{Topic: "command", Payload: []byte("?"), Err: boom})
+	hooks.AvailabilityChanged(ctx, hooks.AvailabilityEvent{Topic: "garage/available", Current: "online"})
+	hooks.AvailabilityChanged(ctx, hooks.AvailabilityEvent{Topic: "garage/door/available", Previous: "online", Current: "offline"})
+	hooks.ConnectionUp(ctx, hooks.ConnectionEvent{})
+	hooks.ConnectionUp(ctx, hooks.ConnectionEvent{Reconnect: true})
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP hqtt_available Whether the availability last written to a topic was online.
+# TYPE hqtt_available gauge
+hqtt_available{bridge="garage",topic="garage/available"} 1
+hqtt_available{bridge="garage",topic="garage/door/available"} 0
+# HELP hqtt_command_errors_total Messages received by remote values that could not be unmarshalled.
+# TYPE hqtt_command_errors_total counter
+hqtt_command_errors_total{bridge="garage",topic="command"} 1
+# HELP hqtt_commands_received_bytes_total Bytes of the payloads of messages received by remote values.
+# TYPE hqtt_commands_received_bytes_total counter
+hqtt_commands_received_bytes_total{bridge="garage",topic="command"} 5
+# HELP hqtt_commands_received_total Messages received by remote values.
+# TYPE hqtt_commands_received_total counter
+hqtt_commands_received_total{bridge="garage",topic="command"} 2
+# HELP hqtt_connections_total Connections established to the broker, including reconnects.
+# TYPE hqtt_connections_total counter
+hqtt_connections_total{bridge="garage"} 2
+# HELP hqtt_discovery_publish_errors_total Device discovery payloads that could not be published.
+# TYPE hqtt_discovery_publish_errors_total counter
+hqtt_discovery_publish_errors_total{bridge="garage",device="garage"} 1
+# HELP hqtt_discovery_published_total Device discovery payloads published.
+# TYPE hqtt_discovery_published_total counter
+hqtt_discovery_published_total{bridge="garage",device="garage"} 2
+# HELP hqtt_reconnects_total Connections re-established to the broker after the connection was lost.
+# TYPE hqtt_reconnects_total counter
+hqtt_reconnects_total{bridge="garage"} 1
+# HELP hqtt_state_publish_errors_total Values that could not be written.
+# TYPE hqtt_state_publish_errors_total counter
+hqtt_state_publish_errors_total{bridge="garage",topic="garage/door/state"} 1
+# HELP hqtt_state_published_bytes_total Bytes of the payloads of values written successfully.
+# TYPE hqtt_state_published_bytes_total counter
+hqtt_state_published_bytes_total{bridge="garage",topic="garage/door/state"} 4
+# HELP hqtt_state_published_total Values written.
+# TYPE hqtt_state_published_total counter
+hqtt_state_published_total{bridge="garage",topic="garage/door/state"} 2
+`)))
+
+	t.Run("Namespace", func(t *testing.T) {
+		c := NewCollector(Options{Namespace: "bridge"})
+		c.Hooks().OnConnectionUp(ctx, hooks.ConnectionEvent{})
+
+		require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP bridge_connections_total Connections established to the broker, including reconnects.
+# TYPE bridge_connections_total counter
+bridge_connections_total 1
+`), "bridge_connections_total"))
+	})
+}
@@ -0,0 +1,4 @@
+// Package prometheus exports hqtt lifecycle hooks (see package hooks) as Prometheus metrics, so bridges built with
+// hqtt can be monitored and dashboarded like any other service. It is a separate module so applications that do not
+// use Prometheus do not depend on its client library.
+package prometheus
@@ -0,0 +1,25 @@
+package hqtttest
+
+import "testing"
+
+// AllocsRuns is the number of times AssertAllocs calls the function under test to measure its allocations.
+const AllocsRuns = 100
+
+// AssertAllocs fails the test if calling f allocates more than budget times on average, as measured by
+// testing.AllocsPerRun. Use it to keep allocations on hot paths from regressing. The check is skipped when the race
+// detector is enabled because it changes how much code allocates. It returns whether the check passed.
+func AssertAllocs(t testing.TB, budget float64, f func()) bool {
+	t.Helper()
+
+	if raceEnabled {
+		t.Log("hqtttest: skipping allocation check with the race detector enabled")
+		return true
+	}
+
+	if allocs := testing.AllocsPerRun(AllocsRuns, f); allocs > budget {
+		t.Errorf("allocations per run: got %v, want at most %v", allocs, budget)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,23 @@
+package hqtttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sink []byte
+
+func TestAssertAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation checks are skipped with the race detector enabled")
+	}
+
+	assert.True(t, AssertAllocs(t, 0, func() {}))
+
+	r := &recordingTB{TB: t}
+	assert.False(t, AssertAllocs(r, 0, func() {
+		sink = make([]byte, 64)
+	}))
+	assert.Len(t, r.errors, 1)
+}
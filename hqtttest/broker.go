@@ -0,0 +1,401 @@
+package hqtttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/nlowe/hqtt/mqtt"
+	adapter "github.com/nlowe/hqtt/mqtt/adapter/autopaho"
+)
+
+// disconnectWithWill is the DISCONNECT reason code a client uses to request its will message be published.
+const disconnectWithWill = 0x04
+
+// Broker is a minimal in-process MQTT v5 broker for hermetic tests. It supports publishing and subscribing at all QoS
+// levels (including wildcards, retained messages, retain handling, NoLocal, RetainAsPublished, and will messages), but
+// does not persist sessions, retransmit messages, or support shared subscriptions, authentication, or TLS.
+//
+// Construct one with NewBroker.
+type Broker struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	clients  map[*brokerClient]struct{}
+	retained map[string]*packets.Publish
+
+	wg          sync.WaitGroup
+	closed      atomic.Bool
+	connections atomic.Int64
+}
+
+type brokerClient struct {
+	conn net.Conn
+	id   string
+
+	// subscriptions is guarded by Broker.mu
+	subscriptions map[string]packets.SubOptions
+	will          *packets.Publish
+
+	lastPacketID atomic.Uint32
+}
+
+// NewBroker starts a Broker listening on a random port on the loopback interface. The broker is closed when the test
+// completes.
+func NewBroker(t testing.TB) *Broker {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("hqtttest: start broker: %v", err)
+	}
+
+	b := &Broker{
+		ln:       ln,
+		clients:  map[*brokerClient]struct{}{},
+		retained: map[string]*packets.Publish{},
+	}
+
+	b.wg.Go(b.serve)
+	t.Cleanup(b.Close)
+
+	return b
+}
+
+// URL returns the URL clients can use to connect to this Broker.
+func (b *Broker) URL() *url.URL {
+	return &url.URL{Scheme: "mqtt", Host: b.ln.Addr().String()}
+}
+
+// Retained returns the payload of the message currently retained for the specified topic. If no message is retained,
+// the second return value will be false.
+func (b *Broker) Retained(topic string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p, ok := b.retained[topic]
+	if !ok {
+		return nil, false
+	}
+
+	return slices.Clone(p.Payload), true
+}
+
+// Connect connects a new client to this Broker with the autopaho adapter, returning the connected mqtt.Writer and
+// mqtt.Subscriber along with a function that disconnects the client. The client is disconnected when the test completes
+// if it has not been disconnected already. The test fails immediately if the client cannot connect.
+func (b *Broker) Connect(t testing.TB) (mqtt.Writer, mqtt.Subscriber, func(ctx context.Context) error) {
+	t.Helper()
+
+	// The connection lives until ctx is cancelled, so only cancel it early if the client cannot connect in time
+	ctx, cancel := context.WithCancel(context.Background())
+	timeout := time.AfterFunc(10*time.Second, cancel)
+
+	w, s, disconnect, err := adapter.DialMQTT(ctx, autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{b.URL()},
+		KeepAlive:                     20,
+		CleanStartOnInitialConnection: true,
+		ConnectTimeout:                5 * time.Second,
+		ClientConfig: paho.ClientConfig{
+			ClientID: fmt.Sprintf("hqtttest-%s-%d", t.Name(), b.connections.Add(1)),
+		},
+	})
+	if !timeout.Stop() || err != nil {
+		cancel()
+		t.Fatalf("hqtttest: connect to broker: %v", errors.Join(err, context.Cause(ctx)))
+	}
+
+	var disconnected atomic.Bool
+	cleanup := func(ctx context.Context) error {
+		if disconnected.Swap(true) {
+			return nil
+		}
+
+		defer cancel()
+		return disconnect(ctx)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = cleanup(ctx)
+	})
+
+	return w, s, cleanup
+}
+
+// Drop closes the connection of every connected client as if the network failed, so their will messages are published
+// and they can test reconnecting. The Broker keeps accepting new connections.
+func (b *Broker) Drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for c := range b.clients {
+		_ = c.conn.Close()
+	}
+}
+
+// Close stops accepting new connections and disconnects every connected client without publishing their will messages.
+func (b *Broker) Close() {
+	if b.closed.Swap(true) {
+		return
+	}
+
+	_ = b.ln.Close()
+
+	b.mu.Lock()
+	for c := range b.clients {
+		_ = c.conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+func (b *Broker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		b.wg.Go(func() {
+			b.handle(packets.NewThreadSafeConn(conn))
+		})
+	}
+}
+
+func (b *Broker) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	cp, err := packets.ReadPacket(conn)
+	if err != nil {
+		return
+	}
+
+	connect, ok := cp.Content.(*packets.Connect)
+	if !ok {
+		return
+	}
+
+	c := &brokerClient{conn: conn, id: connect.ClientID, subscriptions: map[string]packets.SubOptions{}}
+	connack := &packets.Connack{Properties: &packets.Properties{SharedSubAvailable: new(byte)}}
+	if c.id == "" {
+		c.id = fmt.Sprintf("hqtttest-%p", c)
+		connack.Properties.AssignedClientID = c.id
+	}
+
+	if connect.WillFlag {
+		c.will = &packets.Publish{
+			Topic:      connect.WillTopic,
+			Payload:    connect.WillMessage,
+			QoS:        connect.WillQOS,
+			Retain:     connect.WillRetain,
+			Properties: &packets.Properties{},
+		}
+	}
+
+	b.mu.Lock()
+	if b.closed.Load() {
+		b.mu.Unlock()
+		return
+	}
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+
+	if _, err = connack.WriteTo(conn); err == nil {
+		err = b.read(c)
+	}
+
+	b.mu.Lock()
+	delete(b.clients, c)
+	b.mu.Unlock()
+
+	// Clients that disconnect normally do not publish their will, nor do clients disconnected by Close
+	if c.will != nil && !errors.Is(err, errDisconnected) && !b.closed.Load() {
+		b.publish(nil, c.will)
+	}
+}
+
+// errDisconnected is returned by Broker.read when a client disconnects without requesting its will be published.
+var errDisconnected = errors.New("client disconnected")
+
+func (b *Broker) read(c *brokerClient) error {
+	for {
+		cp, err := packets.ReadPacket(c.conn)
+		if err != nil {
+			return err
+		}
+
+		switch p := cp.Content.(type) {
+		case *packets.Publish:
+			err = b.receive(c, p)
+		case *packets.Pubrel:
+			_, err = (&packets.Pubcomp{PacketID: p.PacketID, Properties: &packets.Properties{}}).WriteTo(c.conn)
+		case *packets.Pubrec:
+			_, err = (&packets.Pubrel{PacketID: p.PacketID, Properties: &packets.Properties{}}).WriteTo(c.conn)
+		case *packets.Subscribe:
+			err = b.subscribe(c, p)
+		case *packets.Unsubscribe:
+			err = b.unsubscribe(c, p)
+		case *packets.Pingreq:
+			_, err = (&packets.Pingresp{}).WriteTo(c.conn)
+		case *packets.Disconnect:
+			if p.ReasonCode == disconnectWithWill {
+				return nil
+			}
+
+			return errDisconnected
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (b *Broker) receive(c *brokerClient, p *packets.Publish) error {
+	b.publish(c, p)
+
+	switch p.QoS {
+	case 1:
+		_, err := (&packets.Puback{PacketID: p.PacketID, Properties: &packets.Properties{}}).WriteTo(c.conn)
+		return err
+	case 2:
+		_, err := (&packets.Pubrec{PacketID: p.PacketID, Properties: &packets.Properties{}}).WriteTo(c.conn)
+		return err
+	default:
+		return nil
+	}
+}
+
+type delivery struct {
+	c      *brokerClient
+	qos    byte
+	retain bool
+}
+
+func (b *Broker) publish(from *brokerClient, p *packets.Publish) {
+	b.mu.Lock()
+
+	if p.Retain {
+		if len(p.Payload) == 0 {
+			delete(b.retained, p.Topic)
+		} else {
+			b.retained[p.Topic] = &packets.Publish{Topic: p.Topic, Payload: slices.Clone(p.Payload), QoS: p.QoS, Properties: &packets.Properties{}}
+		}
+	}
+
+	var deliveries []delivery
+	for c := range b.clients {
+		d := delivery{c: c}
+		matched := false
+
+		for filter, opts := range c.subscriptions {
+			if (opts.NoLocal && c == from) || !mqtt.MatchTopic(filter, p.Topic) {
+				continue
+			}
+
+			// Overlapping subscriptions receive a single copy at the highest matching QoS
+			matched = true
+			d.qos = max(d.qos, min(opts.QoS, p.QoS))
+			d.retain = d.retain || (opts.RetainAsPublished && p.Retain)
+		}
+
+		if matched {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	b.mu.Unlock()
+
+	// Deliver without holding the lock so a slow client cannot block the broker
+	for _, d := range deliveries {
+		_ = d.c.send(p.Topic, p.Payload, d.qos, d.retain)
+	}
+}
+
+func (c *brokerClient) send(topic string, payload []byte, qos byte, retain bool) error {
+	p := &packets.Publish{Topic: topic, Payload: payload, QoS: qos, Retain: retain, Properties: &packets.Properties{}}
+	if qos > 0 {
+		// Packet IDs must be non-zero
+		p.PacketID = uint16(c.lastPacketID.Add(1)%0xFFFF) + 1
+	}
+
+	_, err := p.WriteTo(c.conn)
+	return err
+}
+
+func (b *Broker) subscribe(c *brokerClient, p *packets.Subscribe) error {
+	suback := &packets.Suback{PacketID: p.PacketID, Properties: &packets.Properties{}}
+
+	type pending struct {
+		p   *packets.Publish
+		qos byte
+	}
+
+	var retained []pending
+
+	b.mu.Lock()
+	for _, s := range p.Subscriptions {
+		_, existed := c.subscriptions[s.Topic]
+		c.subscriptions[s.Topic] = s
+		suback.Reasons = append(suback.Reasons, s.QoS)
+
+		if s.RetainHandling == packets.RetainDoNotSend || (s.RetainHandling == packets.RetainSendOnSubscribeIfNew && existed) {
+			continue
+		}
+
+		for topic, r := range b.retained {
+			if mqtt.MatchTopic(s.Topic, topic) {
+				retained = append(retained, pending{p: r, qos: min(s.QoS, r.QoS)})
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	if _, err := suback.WriteTo(c.conn); err != nil {
+		return err
+	}
+
+	for _, r := range retained {
+		if err := c.send(r.p.Topic, r.p.Payload, r.qos, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Broker) unsubscribe(c *brokerClient, p *packets.Unsubscribe) error {
+	unsuback := &packets.Unsuback{PacketID: p.PacketID, Properties: &packets.Properties{}}
+
+	b.mu.Lock()
+	for _, topic := range p.Topics {
+		if _, ok := c.subscriptions[topic]; !ok {
+			unsuback.Reasons = append(unsuback.Reasons, packets.UnsubackNoSubscriptionFound)
+			continue
+		}
+
+		delete(c.subscriptions, topic)
+		unsuback.Reasons = append(unsuback.Reasons, packets.UnsubackSuccess)
+	}
+	b.mu.Unlock()
+
+	_, err := unsuback.WriteTo(c.conn)
+	return err
+}
@@ -0,0 +1,58 @@
+package hqtttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestBroker(t *testing.T) {
+	b := NewBroker(t)
+
+	w, _, _ := b.Connect(t)
+	_, s, _ := b.Connect(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	// Retained messages are delivered to new subscriptions
+	state := mqtt.NewValueWithOptions("state", mqtt.StringMarshaler, mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true})
+	_, err := state.Write(ctx, w, "prefix", "on")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, ok := b.Retained("prefix/state")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	remote := mqtt.NewRemoteValueWithOptions("+/state", mqtt.StringUnmarshaler, mqtt.ReadOptions{QoS: mqtt.QOSExactlyOnce})
+	received := make(chan string, 2)
+	remote.Watch(func(v string) {
+		received <- v
+	})
+
+	require.NoError(t, s.Subscribe(ctx, mqtt.HandlerFunc(func(w mqtt.Writer, _ string, payload []byte) {
+		remote.ServeMQTT(w, "+/state", payload)
+	}), remote.AppendSubscribeOptions(nil, "")...))
+
+	assert.Equal(t, "on", <-received)
+
+	// Live messages are routed to matching subscriptions
+	_, err = state.Write(ctx, w, "prefix", "off")
+	require.NoError(t, err)
+	assert.Equal(t, "off", <-received)
+
+	// Empty retained payloads clear the retained message
+	require.NoError(t, w.WriteTopic(ctx, "prefix/state", mqtt.WriteOptions{Retain: true}, nil))
+	require.Eventually(t, func() bool {
+		_, ok := b.Retained("prefix/state")
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.Unsubscribe(ctx, "+/state"))
+}
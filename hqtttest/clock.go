@@ -0,0 +1,225 @@
+package hqtttest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+)
+
+// Clock is a fake clock.Clock for testing time-based behavior without sleeping. Time only moves when Advance or Set is
+// called, which fires every timer and ticker that is due in chronological order. Functions scheduled with AfterFunc are
+// called synchronously, so their effects are visible when Advance returns. Values sent on timer and ticker channels are
+// received by other goroutines, so use BlockUntil to wait for code under test to create its timers before advancing
+// the clock.
+//
+// Construct one with NewClock. It is safe for concurrent use.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+	changed chan struct{}
+}
+
+var _ clock.Clock = &Clock{}
+
+// NewClock constructs a Clock whose current time is start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start, changed: make(chan struct{})}
+}
+
+// Now implements clock.Clock by returning the current fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer implements clock.Clock.
+func (c *Clock) NewTimer(d time.Duration) clock.Timer {
+	return c.add(&fakeTimer{c: c, ch: make(chan time.Time, 1)}, d)
+}
+
+// NewTicker implements clock.Clock.
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("hqtttest: non-positive interval for NewTicker")
+	}
+
+	return fakeTicker{c.add(&fakeTimer{c: c, ch: make(chan time.Time, 1), period: d}, d)}
+}
+
+// AfterFunc implements clock.Clock. The function is called synchronously by Advance or Set.
+func (c *Clock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return c.add(&fakeTimer{c: c, f: f}, d)
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker that becomes due.
+func (c *Clock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to the provided time, firing every timer and ticker that becomes due. Moving the clock backwards
+// does not fire anything.
+func (c *Clock) Set(t time.Time) {
+	for {
+		c.mu.Lock()
+
+		next := c.next(t)
+		if next == nil {
+			if t.After(c.now) {
+				c.now = t
+			}
+
+			c.mu.Unlock()
+			return
+		}
+
+		c.now = next.when
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			c.remove(next)
+		}
+
+		now := c.now
+		c.mu.Unlock()
+
+		if next.f != nil {
+			next.f()
+			continue
+		}
+
+		// Like time.Ticker, drop ticks for slow receivers
+		select {
+		case next.ch <- now:
+		default:
+		}
+	}
+}
+
+// Waiters returns the number of active timers and tickers.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+// BlockUntil waits until at least n timers and tickers are active, which signals that the code under test is ready
+// for the clock to be advanced. If the provided context is done first, the cause of the cancellation is returned.
+func (c *Clock) BlockUntil(ctx context.Context, n int) error {
+	for {
+		c.mu.Lock()
+		active, changed := len(c.waiters), c.changed
+		c.mu.Unlock()
+
+		if active >= n {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-changed:
+		}
+	}
+}
+
+// next returns the earliest timer due at or before t, or nil if none are due. c.mu must be held.
+func (c *Clock) next(t time.Time) *fakeTimer {
+	var next *fakeTimer
+	for _, w := range c.waiters {
+		if !w.when.After(t) && (next == nil || w.when.Before(next.when)) {
+			next = w
+		}
+	}
+
+	return next
+}
+
+func (c *Clock) add(t *fakeTimer, d time.Duration) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t.when = c.now.Add(d)
+	c.waiters = append(c.waiters, t)
+	c.notify()
+
+	return t
+}
+
+// remove deactivates the provided timer, returning true if it was active. c.mu must be held.
+func (c *Clock) remove(t *fakeTimer) bool {
+	i := slices.Index(c.waiters, t)
+	if i < 0 {
+		return false
+	}
+
+	c.waiters = slices.Delete(c.waiters, i, i+1)
+	c.notify()
+
+	return true
+}
+
+// notify wakes up goroutines in BlockUntil. c.mu must be held.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+type fakeTimer struct {
+	c *Clock
+
+	when   time.Time
+	period time.Duration
+
+	ch chan time.Time
+	f  func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+
+	return t.c.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+
+	active := t.c.remove(t)
+	if t.period > 0 {
+		t.period = d
+	}
+
+	t.when = t.c.now.Add(d)
+	t.c.waiters = append(t.c.waiters, t)
+	t.c.notify()
+
+	return active
+}
+
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("hqtttest: non-positive interval for Ticker.Reset")
+	}
+
+	t.fakeTimer.Reset(d)
+}
@@ -0,0 +1,65 @@
+package hqtttest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sut := NewClock(start)
+
+	timer := sut.NewTimer(time.Minute)
+	ticker := sut.NewTicker(30 * time.Second)
+
+	var calls []time.Time
+	sut.AfterFunc(45*time.Second, func() {
+		calls = append(calls, sut.Now())
+	})
+
+	require.NoError(t, sut.BlockUntil(t.Context(), 3))
+	assert.Equal(t, 3, sut.Waiters())
+
+	sut.Advance(40 * time.Second)
+	assert.Equal(t, start.Add(40*time.Second), sut.Now())
+	assert.Equal(t, start.Add(30*time.Second), <-ticker.C())
+	assert.Empty(t, calls)
+	assert.Empty(t, timer.C())
+
+	sut.Advance(20 * time.Second)
+	assert.Equal(t, []time.Time{start.Add(45 * time.Second)}, calls)
+	assert.Equal(t, start.Add(time.Minute), <-timer.C())
+	assert.Equal(t, start.Add(time.Minute), <-ticker.C(), "ticker should fire again")
+	assert.Equal(t, 1, sut.Waiters(), "only the ticker should be active")
+
+	t.Run("Reset", func(t *testing.T) {
+		assert.False(t, timer.Reset(time.Second))
+		assert.True(t, timer.Reset(time.Minute))
+
+		sut.Advance(time.Second)
+		assert.Empty(t, timer.C())
+
+		sut.Advance(time.Minute)
+		assert.Equal(t, start.Add(2*time.Minute), <-timer.C())
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		// Ticks are dropped while the channel is full, so only one was buffered by the Reset subtest
+		<-ticker.C()
+
+		ticker.Stop()
+		assert.Zero(t, sut.Waiters())
+
+		sut.Advance(time.Hour)
+		assert.Empty(t, ticker.C())
+	})
+
+	t.Run("Backwards", func(t *testing.T) {
+		now := sut.Now()
+		sut.Set(start)
+		assert.Equal(t, now, sut.Now())
+	})
+}
@@ -0,0 +1,81 @@
+package hqtttest
+
+import (
+	"encoding/json/v2"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nlowe/hqtt/discovery"
+)
+
+// Discovery is a discovery payload with abbreviated field keys expanded to their full names (see discovery.Expand).
+type Discovery map[string]any
+
+// ParseDiscovery parses and expands the provided discovery payload. The test fails immediately if the payload is not
+// a valid JSON object.
+func ParseDiscovery(t testing.TB, payload []byte) Discovery {
+	t.Helper()
+
+	expanded, err := discovery.Expand(payload)
+	if err != nil {
+		t.Fatalf("hqtttest: %v", err)
+	}
+
+	return expanded
+}
+
+// Field returns the value of the field at the provided path. The path is a sequence of expanded field names separated
+// by ".", for example "device.name" or "components.my_light.brightness_command_topic". If the field does not exist,
+// the second return value will be false.
+func (d Discovery) Field(path string) (any, bool) {
+	var current any = map[string]any(d)
+	for key := range strings.SplitSeq(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// RequireField fails the test immediately unless the field at the provided path (see Discovery.Field) of the provided
+// discovery payload equals want. Values are compared by their JSON representation, so want may be any value that
+// marshals to the expected JSON, for example a string, a number of any type, or a slice.
+func RequireField(t testing.TB, payload []byte, path string, want any) {
+	t.Helper()
+
+	got, ok := ParseDiscovery(t, payload).Field(path)
+	if !ok {
+		t.Fatalf("expected discovery field %s to be %v, but it is not set", path, want)
+	}
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("hqtttest: marshal expected value for %s: %v", path, err)
+	}
+
+	var normalized any
+	if err = json.Unmarshal(data, &normalized); err != nil {
+		t.Fatalf("hqtttest: unmarshal expected value for %s: %v", path, err)
+	}
+
+	if !reflect.DeepEqual(normalized, got) {
+		t.Fatalf("expected discovery field %s to be %v, but got %v", path, normalized, got)
+	}
+}
+
+// RequireNoField fails the test immediately if the field at the provided path (see Discovery.Field) of the provided
+// discovery payload is set.
+func RequireNoField(t testing.TB, payload []byte, path string) {
+	t.Helper()
+
+	if got, ok := ParseDiscovery(t, payload).Field(path); ok {
+		t.Fatalf("expected discovery field %s to not be set, but got %v", path, got)
+	}
+}
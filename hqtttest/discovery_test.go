@@ -0,0 +1,29 @@
+package hqtttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscovery(t *testing.T) {
+	payload := []byte(`{
+		"dev": {"ids": ["foo"], "name": "Foo"},
+		"cmps": {"light": {"p": "light", "bri_cmd_t": "foo/brightness/set", "bri_scl": 100, "sup_clrm": ["brightness"]}}
+	}`)
+
+	d := ParseDiscovery(t, payload)
+	v, ok := d.Field("device.name")
+	assert.True(t, ok)
+	assert.Equal(t, "Foo", v)
+
+	_, ok = d.Field("device.name.first")
+	assert.False(t, ok)
+	_, ok = d.Field("components.fan")
+	assert.False(t, ok)
+
+	RequireField(t, payload, "components.light.brightness_command_topic", "foo/brightness/set")
+	RequireField(t, payload, "components.light.brightness_scale", uint8(100))
+	RequireField(t, payload, "components.light.supported_color_modes", []string{"brightness"})
+	RequireNoField(t, payload, "components.light.rgb_command_topic")
+}
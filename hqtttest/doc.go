@@ -0,0 +1,2 @@
+// Package hqtttest provides utilities for testing code built with hqtt without connecting to an MQTT broker.
+package hqtttest
@@ -0,0 +1,89 @@
+package hqtttest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// SeedPayloads returns a seed corpus of MQTT payloads for fuzzing a mqtt.ValueUnmarshaler. It covers the payload
+// shapes used by hqtt's own unmarshalers (power states, availability, integers, comma-separated colors, and JSON) along
+// with empty, invalid UTF-8, and oversized payloads. A new slice is returned on every call, so callers may modify it.
+func SeedPayloads() [][]byte {
+	return [][]byte{
+		{},
+		[]byte("ON"),
+		[]byte("OFF"),
+		[]byte("on"),
+		[]byte("online"),
+		[]byte("offline"),
+		[]byte("0"),
+		[]byte("-1"),
+		[]byte("255"),
+		[]byte("256"),
+		[]byte("18446744073709551615"),
+		[]byte("18446744073709551616"),
+		[]byte("1.5"),
+		[]byte(" 1"),
+		[]byte("1,2,3"),
+		[]byte("255,255,255"),
+		[]byte("1,2"),
+		[]byte("1,2,3,4"),
+		[]byte(",,"),
+		[]byte("{}"),
+		[]byte("[]"),
+		[]byte("null"),
+		[]byte(`"online"`),
+		[]byte(`{"state":"ON","brightness":255,"color":{"r":1,"g":2,"b":3}}`),
+		[]byte(`{"state":`),
+		[]byte("\xff\xfe"),
+		[]byte("\x00"),
+		[]byte(strings.Repeat("9", 1024)),
+	}
+}
+
+// FuzzUnmarshaler fuzzes the provided mqtt.ValueUnmarshaler, seeding the corpus with SeedPayloads and any additional
+// seeds. The unmarshaler must not panic for any payload.
+//
+// If marshal is not nil, every successfully unmarshaled value is also checked to round-trip: the payload produced by
+// marshal must unmarshal without error, and marshaling the result again must produce the same payload. Call
+// FuzzUnmarshaler from a fuzz target:
+//
+//	func FuzzRGBUnmarshaler(f *testing.F) {
+//		hqtttest.FuzzUnmarshaler(f, platform.RGBUnmarshaler, platform.RGBMarshaler)
+//	}
+func FuzzUnmarshaler[T any](f *testing.F, unmarshal mqtt.ValueUnmarshaler[T], marshal mqtt.ValueMarshaler[T], seeds ...[]byte) {
+	f.Helper()
+
+	for _, seed := range append(SeedPayloads(), seeds...) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		v, err := unmarshal(payload)
+		if err != nil || marshal == nil {
+			return
+		}
+
+		encoded, err := marshal(v)
+		if err != nil {
+			t.Fatalf("marshal value unmarshaled from %q: %v", payload, err)
+		}
+
+		decoded, err := unmarshal(encoded)
+		if err != nil {
+			t.Fatalf("unmarshal %q (marshaled from %q): %v", encoded, payload, err)
+		}
+
+		reencoded, err := marshal(decoded)
+		if err != nil {
+			t.Fatalf("marshal value unmarshaled from %q: %v", encoded, err)
+		}
+
+		if !bytes.Equal(encoded, reencoded) {
+			t.Fatalf("value unmarshaled from %q does not round-trip: marshaled to %q, then %q", payload, encoded, reencoded)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package hqtttest
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to a non-empty value, causes RequireGoldenDiscovery to
+// write golden files instead of comparing against them. For example:
+//
+//	HQTT_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "HQTT_UPDATE_GOLDEN"
+
+// NormalizeDiscovery normalizes a discovery payload for comparison by canonicalizing it (sorting object keys and
+// formatting numbers canonically as described in RFC 8785) and indenting it with one field per line so differences are
+// easy to read.
+func NormalizeDiscovery(payload []byte) ([]byte, error) {
+	v := jsontext.Value(payload).Clone()
+	if err := v.Canonicalize(); err != nil {
+		return nil, fmt.Errorf("normalize discovery payload: %w", err)
+	}
+
+	if err := v.Indent(jsontext.WithIndent("  ")); err != nil {
+		return nil, fmt.Errorf("normalize discovery payload: %w", err)
+	}
+
+	return append(v, '\n'), nil
+}
+
+// RequireGoldenDiscovery fails the test immediately unless the normalized form of the provided discovery payload (see
+// NormalizeDiscovery) matches the golden file at the provided path, reporting a line diff if it does not. Paths are
+// typically relative to the package under test, for example "testdata/my_device.json". If the UpdateGoldenEnv
+// environment variable is set, the golden file is written (creating parent directories as needed) instead.
+func RequireGoldenDiscovery(t testing.TB, path string, payload []byte) {
+	t.Helper()
+
+	got, err := NormalizeDiscovery(payload)
+	if err != nil {
+		t.Fatalf("hqtttest: %v", err)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			err = os.WriteFile(path, got, 0o644)
+		}
+
+		if err != nil {
+			t.Fatalf("hqtttest: update golden file: %v", err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist, run with %s=1 to create it", path, UpdateGoldenEnv)
+	} else if err != nil {
+		t.Fatalf("hqtttest: read golden file: %v", err)
+	}
+
+	if string(got) != string(want) {
+		t.Fatalf("discovery payload does not match golden file %s (run with %s=1 to update it):\n%s", path, UpdateGoldenEnv, diff(string(want), string(got)))
+	}
+}
+
+// diff returns a line diff of want and got. Lines only in want are prefixed with "-", lines only in got are prefixed
+// with "+", and common lines are prefixed with " ".
+func diff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var result strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			result.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			result.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+
+	return result.String()
+}
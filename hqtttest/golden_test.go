@@ -0,0 +1,47 @@
+package hqtttest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeDiscovery(t *testing.T) {
+	got, err := NormalizeDiscovery([]byte(`{"p":"light","bri_scl":1e2,"dev":{"name":"foo","ids":["foo"]}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{
+  "bri_scl": 100,
+  "dev": {
+    "ids": [
+      "foo"
+    ],
+    "name": "foo"
+  },
+  "p": "light"
+}
+`, string(got))
+
+	_, err = NormalizeDiscovery([]byte(`{`))
+	require.Error(t, err)
+}
+
+func TestRequireGoldenDiscovery(t *testing.T) {
+	RequireGoldenDiscovery(t, filepath.Join("testdata", "light.json"), []byte(`{"p":"light","dev":{"ids":["foo"]}}`))
+
+	t.Run("Update", func(t *testing.T) {
+		t.Setenv(UpdateGoldenEnv, "1")
+
+		path := filepath.Join(t.TempDir(), "nested", "device.json")
+		RequireGoldenDiscovery(t, path, []byte(`{"p":"light"}`))
+
+		t.Setenv(UpdateGoldenEnv, "")
+		RequireGoldenDiscovery(t, path, []byte(`{ "p": "light" }`))
+	})
+}
+
+func TestDiff(t *testing.T) {
+	assert.Equal(t, " a\n-b\n+c\n d\n+e\n", diff("a\nb\nd\n", "a\nc\nd\ne\n"))
+}
@@ -0,0 +1,5 @@
+//go:build race
+
+package hqtttest
+
+const raceEnabled = true
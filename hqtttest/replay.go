@@ -0,0 +1,117 @@
+package hqtttest
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// LoadRecording reads a recording written by mqtt.Recorder from the file at the provided path, typically relative to
+// the package under test (for example "testdata/lamp.jsonl"). The test fails immediately if it cannot be read.
+func LoadRecording(t testing.TB, path string) []mqtt.RecordedMessage {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("hqtttest: %v", err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	messages, err := mqtt.ReadRecording(f)
+	if err != nil {
+		t.Fatalf("hqtttest: %s: %v", path, err)
+	}
+
+	return messages
+}
+
+// Replay replays a recording of the MQTT traffic of a real bridge (see mqtt.Recorder) against the code under test, so
+// command sequences seen in the wild can be turned into regression tests. Received messages are injected into
+// Subscriber in the order they were recorded, and every message published to Writer is expected to match the messages
+// published in the recording.
+//
+// A typical test constructs a new Writer and Subscriber, starts the code under test with them (so it publishes its
+// discovery payloads and subscribes to its commands like it did when the recording was made), then calls Run.
+type Replay struct {
+	// Messages is the recording to replay, usually loaded with LoadRecording.
+	Messages []mqtt.RecordedMessage
+
+	// Subscriber receives the recorded inbound messages with Subscriber.Inject.
+	Subscriber *Subscriber
+	// Writer is passed to handlers, and every message written to it (including before Run) is compared with the
+	// recorded outbound messages.
+	Writer *Writer
+
+	// Clock, if set, is advanced to the offset of each message (relative to the time when Run is called) as it is
+	// replayed, so debounces, timeouts, and heartbeats in the code under test fire like they did when the recording was
+	// made. Otherwise, messages are injected without waiting.
+	Clock *Clock
+}
+
+// Run injects every recorded inbound message, then reports a test error with a line diff unless the messages written
+// to Writer match the recorded outbound messages, including their order, QoS, and retain flag. It also reports an error
+// for every inbound message that no handler is subscribed to. It returns whether the replay matched.
+func (r *Replay) Run(t testing.TB) bool {
+	t.Helper()
+
+	ok := true
+
+	var start time.Time
+	if r.Clock != nil {
+		start = r.Clock.Now()
+	}
+
+	var want []string
+	for i, m := range r.Messages {
+		// Outbound messages also move the clock, so messages published by timers are published by the end of the replay
+		if r.Clock != nil {
+			r.Clock.Set(start.Add(m.Offset))
+		}
+
+		if m.Direction == mqtt.AuditDirectionPublish {
+			want = append(want, formatReplayed(m.Message))
+			continue
+		}
+
+		if r.Subscriber.Inject(r.Writer, m.Topic, m.Payload) == 0 {
+			t.Errorf("replay: message %d: nothing is subscribed to %s", i+1, m.Topic)
+			ok = false
+		}
+	}
+
+	var got []string
+	for _, m := range r.Writer.Messages() {
+		got = append(got, formatReplayed(m))
+	}
+
+	if w, g := strings.Join(want, "\n"), strings.Join(got, "\n"); w != g {
+		t.Errorf("replay: published messages do not match the recording:\n%s", diff(w, g))
+		ok = false
+	}
+
+	return ok
+}
+
+// formatReplayed formats a message on a single line for diffing.
+func formatReplayed(m mqtt.Message) string {
+	payload := strconv.Quote(string(m.Payload))
+	if !utf8.Valid(m.Payload) {
+		payload = fmt.Sprintf("%x", m.Payload)
+	}
+
+	retain := ""
+	if m.Options.Retain {
+		retain = " retained"
+	}
+
+	return fmt.Sprintf("%s (qos %d%s): %s", m.Topic, m.Options.QoS, retain, payload)
+}
@@ -0,0 +1,70 @@
+package hqtttest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/clock"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// startLamp starts a fake lamp that echoes commands to its state, and reports the last command a second after it stops
+// receiving them.
+func startLamp(t *testing.T, w *Writer, s *Subscriber, c *Clock) {
+	retained := mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}
+	require.NoError(t, w.WriteTopic(t.Context(), "lamp/state", retained, []byte("OFF")))
+
+	var report clock.Timer
+	require.NoError(t, s.Subscribe(t.Context(), mqtt.HandlerFunc(func(w mqtt.Writer, _ string, payload []byte) {
+		require.NoError(t, w.WriteTopic(t.Context(), "lamp/state", retained, payload))
+
+		if report != nil {
+			report.Stop()
+		}
+
+		report = c.AfterFunc(time.Second, func() {
+			require.NoError(t, w.WriteTopic(t.Context(), "lamp/last_command", mqtt.WriteOptions{}, payload))
+		})
+	}), mqtt.Subscription{Topic: "lamp/set"}))
+}
+
+func TestReplay(t *testing.T) {
+	recording := LoadRecording(t, filepath.Join("testdata", "lamp.jsonl"))
+	require.Len(t, recording, 7)
+
+	w, s, c := &Writer{}, &Subscriber{}, NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local))
+	startLamp(t, w, s, c)
+
+	sut := &Replay{Messages: recording, Subscriber: s, Writer: w, Clock: c}
+	assert.True(t, sut.Run(t))
+
+	t.Run("Mismatch", func(t *testing.T) {
+		w, s := &Writer{}, &Subscriber{}
+		startLamp(t, w, s, NewClock(time.Now()))
+
+		// Without the clock, the last command is never reported
+		tb := &recordingTB{TB: t}
+		sut := &Replay{Messages: recording, Subscriber: s, Writer: w}
+		assert.False(t, sut.Run(tb))
+		assert.Equal(t, []string{"replay: published messages do not match the recording:\n%s"}, tb.errors)
+	})
+
+	t.Run("Not Subscribed", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		sut := &Replay{Messages: recording[:2], Subscriber: &Subscriber{}, Writer: &Writer{}}
+		assert.False(t, sut.Run(tb))
+		assert.Equal(t, []string{
+			"replay: message %d: nothing is subscribed to %s",
+			"replay: published messages do not match the recording:\n%s",
+		}, tb.errors)
+	})
+}
+
+func TestFormatReplayed(t *testing.T) {
+	assert.Equal(t, `lamp/state (qos 1 retained): "ON"`, formatReplayed(mqtt.Message{Topic: "lamp/state", Options: mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, Payload: []byte("ON")}))
+	assert.Equal(t, "lamp/image (qos 0): ff00", formatReplayed(mqtt.Message{Topic: "lamp/image", Payload: []byte{0xff, 0x00}}))
+}
@@ -0,0 +1,93 @@
+package hqtttest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nlowe/hqtt/hooks"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Routable is the subset of hqtt.Platform checked by AssertRouting.
+type Routable interface {
+	mqtt.Handler
+
+	Subscriptions(prefix string) []mqtt.Subscription
+}
+
+// RoutingProbe is the payload AssertRouting delivers to every subscribed topic.
+var RoutingProbe = []byte("hqtttest-routing-probe")
+
+// AssertRouting checks that a Platform dispatches a message received on every topic it subscribes to, catching bugs
+// like a command topic that is subscribed but never routed to its mqtt.RemoteValue by ServeMQTT. Topics are routed the
+// same way hqtt.Component routes them: with the provided prefix removed. Wildcard levels are replaced with a literal
+// level before probing. If several subscriptions share a topic, the message must be dispatched once per subscription.
+//
+// Dispatch is detected with hooks.Hooks.OnCommandReceived, which RemoteValue.ServeMQTT fires for every message it
+// handles. Each probe delivers RoutingProbe, which updates (and calls the watchers of) RemoteValues whose unmarshaler
+// accepts it, so use a freshly constructed Platform. It returns whether every subscribed topic was dispatched.
+func AssertRouting(t testing.TB, p Routable, prefix string) bool {
+	t.Helper()
+
+	var (
+		mu         sync.Mutex
+		dispatched = map[string]int{}
+	)
+
+	unregister := hooks.Register(hooks.Hooks{
+		OnCommandReceived: func(_ context.Context, e hooks.CommandEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			dispatched[e.Topic]++
+		},
+	})
+	defer unregister()
+
+	want := map[string]int{}
+	var topics []string
+	for _, s := range p.Subscriptions(prefix) {
+		topic := probeTopic(s.Topic)
+		if want[topic] == 0 {
+			topics = append(topics, topic)
+		}
+
+		want[topic]++
+	}
+
+	ok := true
+	w := &Writer{}
+	for _, topic := range topics {
+		// Mirror hqtt.Component: topics outside the prefix belong to values with absolute topics and are routed as-is
+		rest, _ := strings.CutPrefix(topic, mqtt.TrimTopic(prefix))
+		rest = mqtt.TrimTopic(rest)
+
+		p.ServeMQTT(w, rest, RoutingProbe)
+
+		mu.Lock()
+		got := dispatched[rest]
+		delete(dispatched, rest)
+		mu.Unlock()
+
+		if got != want[topic] {
+			t.Errorf("subscribed topic %q (routed as %q): dispatched %d times, want %d", topic, rest, got, want[topic])
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// probeTopic replaces wildcard levels in the provided subscription filter so a message can be published to it.
+func probeTopic(filter string) string {
+	levels := strings.Split(filter, mqtt.TopicSeparator)
+	for i, level := range levels {
+		if level == mqtt.SingleLevelWildcard || level == mqtt.MultiLevelWildcard {
+			levels[i] = "probe"
+		}
+	}
+
+	return strings.Join(levels, mqtt.TopicSeparator)
+}
@@ -0,0 +1,72 @@
+package hqtttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// routingPlatform subscribes to both of its commands, but only routes messages for the ones in routed.
+type routingPlatform struct {
+	commands []*mqtt.RemoteValue[string]
+	routed   int
+}
+
+func (p *routingPlatform) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+	for _, c := range p.commands {
+		result = c.AppendSubscribeOptions(result, prefix)
+	}
+
+	return result
+}
+
+func (p *routingPlatform) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	for _, c := range p.commands[:p.routed] {
+		if topic == c.FullyQualifiedTopic("") {
+			c.ServeMQTT(w, topic, payload)
+			return
+		}
+	}
+}
+
+func TestAssertRouting(t *testing.T) {
+	newPlatform := func(routed int, topics ...string) *routingPlatform {
+		p := &routingPlatform{routed: routed}
+		for _, topic := range topics {
+			p.commands = append(p.commands, mqtt.NewRemoteValue(topic, mqtt.StringUnmarshaler))
+		}
+
+		return p
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		p := newPlatform(2, "command", "brightness/set")
+		p.commands[1].Absolute()
+
+		assert.True(t, AssertRouting(t, p, "light"))
+
+		v, ok := p.commands[0].Get()
+		assert.True(t, ok)
+		assert.Equal(t, string(RoutingProbe), v)
+	})
+
+	t.Run("Not Routed", func(t *testing.T) {
+		r := &recordingTB{TB: t}
+		assert.False(t, AssertRouting(r, newPlatform(1, "command", "brightness/set"), "light"))
+		assert.Len(t, r.errors, 1)
+	})
+
+	t.Run("Shared Topic", func(t *testing.T) {
+		r := &recordingTB{TB: t}
+		assert.False(t, AssertRouting(r, newPlatform(2, "command", "command"), "light"))
+		assert.Len(t, r.errors, 1)
+	})
+}
+
+func TestProbeTopic(t *testing.T) {
+	assert.Equal(t, "a/probe/b/probe", probeTopic("a/+/b/#"))
+	assert.Equal(t, "a/b", probeTopic("a/b"))
+}
@@ -0,0 +1,72 @@
+package hqtttest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Step is a single value fed into a RemoteValue by a Script. The Script waits for Delay before delivering Value.
+type Step[T any] struct {
+	Value T
+	Delay time.Duration
+}
+
+// Values constructs Steps for the provided values with no delay.
+func Values[T any](values ...T) []Step[T] {
+	steps := make([]Step[T], len(values))
+	for i, v := range values {
+		steps[i] = Step[T]{Value: v}
+	}
+
+	return steps
+}
+
+// Script feeds a scripted sequence of values into a RemoteValue as if they were received from MQTT, so application
+// logic that reacts to command streams (with RemoteValue.Watch or RemoteValue.Await) can be tested deterministically.
+// Each value is encoded with Marshal and delivered with RemoteValue.ServeMQTT, so it is decoded by the RemoteValue's own
+// unmarshaler exactly like a real message.
+type Script[T any] struct {
+	// Value is the RemoteValue to feed values into.
+	Value *mqtt.RemoteValue[T]
+	// Marshal encodes values before they are delivered to Value.
+	Marshal mqtt.ValueMarshaler[T]
+	// Writer is passed to RemoteValue.ServeMQTT. If nil, a new Writer is used.
+	Writer mqtt.Writer
+
+	Steps []Step[T]
+}
+
+// Run delivers every Step in order, waiting for the delay of each step before delivering it. Watchers of Value are
+// called synchronously, so when Run returns every watcher has observed every value. If the provided context is done
+// before all steps are delivered, the cause of the cancellation is returned.
+func (s *Script[T]) Run(ctx context.Context) error {
+	w := s.Writer
+	if w == nil {
+		w = &Writer{}
+	}
+
+	topic := s.Value.FullyQualifiedTopic("")
+	for i, step := range s.Steps {
+		if step.Delay > 0 {
+			t := time.NewTimer(step.Delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return context.Cause(ctx)
+			case <-t.C:
+			}
+		}
+
+		payload, err := s.Marshal(step.Value)
+		if err != nil {
+			return fmt.Errorf("script step %d: %w", i, err)
+		}
+
+		s.Value.ServeMQTT(w, topic, payload)
+	}
+
+	return nil
+}
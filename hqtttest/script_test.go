@@ -0,0 +1,39 @@
+package hqtttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestScript(t *testing.T) {
+	v := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+
+	var got []string
+	v.Watch(func(s string) {
+		got = append(got, s)
+	})
+
+	sut := &Script[string]{
+		Value:   v,
+		Marshal: mqtt.StringMarshaler,
+		Steps:   append(Values("on", "off"), Step[string]{Value: "on", Delay: time.Millisecond}),
+	}
+
+	require.NoError(t, sut.Run(t.Context()))
+	assert.Equal(t, []string{"on", "off", "on"}, got)
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		sut.Steps = []Step[string]{{Value: "off", Delay: time.Hour}}
+		require.ErrorIs(t, sut.Run(ctx), context.Canceled)
+		assert.Len(t, got, 3)
+	})
+}
@@ -0,0 +1,243 @@
+package hqtttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+const (
+	// DefaultStressWorkers is the number of goroutines used by Stress when Workers is not positive.
+	DefaultStressWorkers = 8
+	// DefaultStressIterations is the number of operations each worker performs when Iterations is not positive.
+	DefaultStressIterations = 200
+	// DefaultStressTimeout is how long Stress waits for its workers when Timeout is not positive.
+	DefaultStressTimeout = 10 * time.Second
+)
+
+// ErrStressDeadlock is the error returned by Stress.Run when its workers do not finish before the timeout.
+var ErrStressDeadlock = errors.New("stress workers did not finish, possible deadlock")
+
+// Stress hammers a Value and/or RemoteValue with concurrent operations to flush out data races and deadlocks. Run it
+// with the race detector enabled (go test -race) to get the most out of it.
+//
+// Workers concurrently Write, Republish, and Get the Value, and deliver values with ServeMQTT, Get, Watch, Unwatch,
+// and Await on the RemoteValue. When Reentrant is set, watchers registered by Stress call back into both values from
+// within the callback. Once every worker finishes, Stress checks that none of the watchers it registered are still
+// called, which catches Unwatch removing the wrong callback.
+type Stress[T any] struct {
+	// Value is written to and read from by workers. It may be nil if only Remote should be stressed.
+	Value *mqtt.Value[T]
+	// Remote receives values with RemoteValue.ServeMQTT and is watched by workers. It may be nil if only Value should
+	// be stressed.
+	Remote *mqtt.RemoteValue[T]
+	// Marshal encodes Values before they are delivered to Remote. It is required if Remote is set.
+	Marshal mqtt.ValueMarshaler[T]
+	// Writer is passed to Value.Write and RemoteValue.ServeMQTT. If nil, a new Writer is used.
+	Writer mqtt.Writer
+
+	// Values are written to Value and delivered to Remote in turn. At least one value is required.
+	Values []T
+
+	// Workers is the number of concurrent goroutines. If it is not positive, DefaultStressWorkers is used.
+	Workers int
+	// Iterations is the number of operations each worker performs. If it is not positive, DefaultStressIterations is
+	// used.
+	Iterations int
+	// Timeout is how long Run waits for workers to finish before reporting ErrStressDeadlock. If it is not positive,
+	// DefaultStressTimeout is used.
+	Timeout time.Duration
+
+	// Reentrant makes watchers registered by workers call Get on Value and Remote and add and remove another watcher on
+	// Remote, like application code that reads related state or waits for follow-up commands when a command arrives.
+	Reentrant bool
+}
+
+// Run starts the workers and waits for them to finish. It returns ErrStressDeadlock if they do not finish within
+// Timeout (the stuck goroutines are leaked), the cause of the cancellation if the provided context is done first, or an
+// error describing any other problem that was detected.
+func (s *Stress[T]) Run(ctx context.Context) error {
+	if s.Value == nil && s.Remote == nil {
+		return errors.New("stress: no values to stress")
+	}
+
+	if len(s.Values) == 0 {
+		return errors.New("stress: no values")
+	}
+
+	if s.Remote != nil && s.Marshal == nil {
+		return errors.New("stress: a marshaler is required to stress a RemoteValue")
+	}
+
+	w := s.Writer
+	if w == nil {
+		w = &Writer{}
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = DefaultStressWorkers
+	}
+
+	iterations := s.Iterations
+	if iterations <= 0 {
+		iterations = DefaultStressIterations
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStressTimeout
+	}
+
+	payloads := make([][]byte, len(s.Values))
+	if s.Remote != nil {
+		for i, v := range s.Values {
+			var err error
+			if payloads[i], err = s.Marshal(v); err != nil {
+				return fmt.Errorf("stress: marshal value %d: %w", i, err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Counts calls to watchers registered by workers after every worker has finished
+	var leaked atomic.Int64
+	var finished atomic.Bool
+
+	var errs []error
+	var errMu sync.Mutex
+	fail := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+
+		errs = append(errs, err)
+	}
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Go(func() {
+			for i := range iterations {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if err := s.step(ctx, w, worker+i, payloads, &finished, &leaked); err != nil {
+					fail(fmt.Errorf("worker %d iteration %d: %w", worker, i, err))
+				}
+			}
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case <-done:
+	case <-t.C:
+		return fmt.Errorf("stress: %w after %s", ErrStressDeadlock, timeout)
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+
+	if s.Remote != nil {
+		finished.Store(true)
+		s.Remote.ServeMQTT(w, s.Remote.FullyQualifiedTopic(""), payloads[0])
+
+		if n := leaked.Load(); n > 0 {
+			fail(fmt.Errorf("%d watchers were still called after they were removed", n))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("stress: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// step performs the n-th operation against the configured values.
+func (s *Stress[T]) step(ctx context.Context, w mqtt.Writer, n int, payloads [][]byte, finished *atomic.Bool, leaked *atomic.Int64) error {
+	var ops []func(v T, payload []byte) error
+	if s.Value != nil {
+		ops = append(ops,
+			func(v T, _ []byte) error {
+				_, err := s.Value.Write(ctx, w, "", v)
+				return err
+			},
+			func(T, []byte) error {
+				_, err := s.Value.Republish(ctx, w, "")
+				if errors.Is(err, mqtt.ErrNeverWritten) {
+					return nil
+				}
+
+				return err
+			},
+			func(T, []byte) error {
+				s.Value.Get()
+				return nil
+			},
+		)
+	}
+
+	if s.Remote != nil {
+		topic := s.Remote.FullyQualifiedTopic("")
+
+		ops = append(ops,
+			func(_ T, payload []byte) error {
+				s.Remote.ServeMQTT(w, topic, payload)
+				return nil
+			},
+			func(T, []byte) error {
+				s.Remote.Get()
+				return nil
+			},
+			func(_ T, payload []byte) error {
+				id := s.Remote.Watch(func(T) {
+					if finished.Load() {
+						leaked.Add(1)
+					}
+
+					if s.Reentrant {
+						s.Remote.Get()
+						s.Remote.Unwatch(s.Remote.Watch(func(T) {}))
+						if s.Value != nil {
+							s.Value.Get()
+						}
+					}
+				})
+
+				s.Remote.ServeMQTT(w, topic, payload)
+				s.Remote.Unwatch(id)
+				return nil
+			},
+			func(T, []byte) error {
+				// Other workers may not deliver a value in time, so only unexpected errors are reported
+				awaitCtx, cancel := context.WithTimeout(ctx, time.Millisecond)
+				defer cancel()
+
+				if _, err := s.Remote.Await(awaitCtx, func(T) bool { return true }); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+					return err
+				}
+
+				return nil
+			},
+		)
+	}
+
+	// Cycle through every operation, moving on to the next value after each full cycle
+	i := (n / len(ops)) % len(s.Values)
+	return ops[n%len(ops)](s.Values[i], payloads[i])
+}
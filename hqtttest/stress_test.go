@@ -0,0 +1,84 @@
+package hqtttest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestStress(t *testing.T) {
+	value := mqtt.NewValue("state", mqtt.StringMarshaler)
+	remote := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+
+	var calls int
+	remote.Watch(func(string) {
+		calls++
+	})
+
+	sut := &Stress[string]{
+		Value:   value,
+		Remote:  remote,
+		Marshal: mqtt.StringMarshaler,
+		Values:  []string{"on", "off"},
+	}
+
+	require.NoError(t, sut.Run(t.Context()))
+	assert.Positive(t, calls, "watchers registered outside of Stress should still be called")
+
+	got, ok := value.Get()
+	require.True(t, ok)
+	assert.Contains(t, sut.Values, got)
+
+	got, ok = remote.Get()
+	require.True(t, ok)
+	assert.Equal(t, "on", got)
+
+	t.Run("Reentrant", func(t *testing.T) {
+		sut := &Stress[string]{
+			Value:     mqtt.NewValue("state", mqtt.StringMarshaler),
+			Remote:    mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler),
+			Marshal:   mqtt.StringMarshaler,
+			Values:    []string{"on", "off"},
+			Reentrant: true,
+		}
+
+		require.NoError(t, sut.Run(t.Context()))
+	})
+
+	t.Run("LockFree", func(t *testing.T) {
+		sut := &Stress[string]{
+			Value:     mqtt.NewValue("state", mqtt.StringMarshaler).LockFree(),
+			Remote:    mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler).LockFree(),
+			Marshal:   mqtt.StringMarshaler,
+			Values:    []string{"on", "off"},
+			Reentrant: true,
+		}
+
+		require.NoError(t, sut.Run(t.Context()))
+	})
+
+	t.Run("Deadlock", func(t *testing.T) {
+		block := make(chan struct{})
+		t.Cleanup(func() {
+			close(block)
+		})
+
+		remote := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+		remote.Watch(func(string) {
+			<-block
+		})
+
+		sut := &Stress[string]{Remote: remote, Marshal: mqtt.StringMarshaler, Values: []string{"on"}, Timeout: 50 * time.Millisecond}
+		require.ErrorIs(t, sut.Run(t.Context()), ErrStressDeadlock)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		require.Error(t, (&Stress[string]{Values: []string{"on"}}).Run(t.Context()))
+		require.Error(t, (&Stress[string]{Value: value}).Run(t.Context()))
+		require.Error(t, (&Stress[string]{Remote: remote, Values: []string{"on"}}).Run(t.Context()))
+	})
+}
@@ -0,0 +1,163 @@
+package hqtttest
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+type subscription struct {
+	mqtt.Subscription
+	handlers []mqtt.Handler
+}
+
+// Subscriber is an mqtt.BatchSubscriber that records subscriptions instead of subscribing to a broker, and allows tests
+// to inject inbound messages into the registered handlers with Inject. Like the autopaho adapter, subscribing to a topic
+// that is already subscribed replaces the options of the subscription and adds the handler alongside the existing
+// ones. The zero value is ready to use, and it is safe for concurrent use.
+type Subscriber struct {
+	mu            sync.Mutex
+	subscriptions map[string]*subscription
+	requests      int
+}
+
+var (
+	_ mqtt.BatchSubscriber     = &Subscriber{}
+	_ mqtt.HandlerUnsubscriber = &Subscriber{}
+)
+
+// Subscribe implements mqtt.Subscriber by recording the provided subscriptions and handler.
+func (s *Subscriber) Subscribe(ctx context.Context, handler mqtt.Handler, subscriptions ...mqtt.Subscription) error {
+	return s.SubscribeBatch(ctx, mqtt.SubscribeRequest{Handler: handler, Subscriptions: subscriptions})
+}
+
+// SubscribeBatch implements mqtt.BatchSubscriber by recording the subscriptions and handler of every request.
+func (s *Subscriber) SubscribeBatch(_ context.Context, requests ...mqtt.SubscribeRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscriptions == nil {
+		s.subscriptions = map[string]*subscription{}
+	}
+
+	subscribed := false
+	for _, r := range requests {
+		for _, sub := range r.Subscriptions {
+			existing, ok := s.subscriptions[sub.Topic]
+			if !ok {
+				existing = &subscription{}
+				s.subscriptions[sub.Topic] = existing
+			}
+
+			existing.Subscription = sub
+			existing.handlers = append(existing.handlers, r.Handler)
+			subscribed = true
+		}
+	}
+
+	// Like a client, nothing is sent to the broker if there is nothing to subscribe to
+	if subscribed {
+		s.requests++
+	}
+
+	return nil
+}
+
+// Requests returns the number of times Subscribe and SubscribeBatch were called, which corresponds to the number of
+// SUBSCRIBE packets a client would send to a broker.
+func (s *Subscriber) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// Unsubscribe implements mqtt.Subscriber by removing subscriptions (and all of their handlers) for the provided topics.
+func (s *Subscriber) Unsubscribe(_ context.Context, topics ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range topics {
+		delete(s.subscriptions, t)
+	}
+
+	return nil
+}
+
+// UnsubscribeHandler implements mqtt.HandlerUnsubscriber by removing the provided handler from the subscriptions for
+// the provided topics. Subscriptions are removed once they have no handlers left.
+func (s *Subscriber) UnsubscribeHandler(_ context.Context, handler mqtt.Handler, topics ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range topics {
+		sub, ok := s.subscriptions[t]
+		if !ok {
+			continue
+		}
+
+		sub.handlers = slices.DeleteFunc(sub.handlers, func(h mqtt.Handler) bool {
+			return mqtt.SameHandler(h, handler)
+		})
+
+		if len(sub.handlers) == 0 {
+			delete(s.subscriptions, t)
+		}
+	}
+
+	return nil
+}
+
+// Subscriptions returns every active subscription, sorted by topic.
+func (s *Subscriber) Subscriptions() []mqtt.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]mqtt.Subscription, 0, len(s.subscriptions))
+	for _, t := range slices.Sorted(maps.Keys(s.subscriptions)) {
+		result = append(result, s.subscriptions[t].Subscription)
+	}
+
+	return result
+}
+
+// Inject delivers a message to the handlers of every subscription whose topic filter matches the provided topic (see
+// mqtt.MatchTopic), passing the provided Writer to the handlers. Handlers are called synchronously in order of their
+// subscription topic, and then in the order they subscribed. It returns the number of handlers called.
+func (s *Subscriber) Inject(w mqtt.Writer, topic string, payload []byte) int {
+	s.mu.Lock()
+	var handlers []mqtt.Handler
+	for _, t := range slices.Sorted(maps.Keys(s.subscriptions)) {
+		if mqtt.MatchTopic(t, topic) {
+			handlers = append(handlers, s.subscriptions[t].handlers...)
+		}
+	}
+	s.mu.Unlock()
+
+	// Handlers are called without holding the lock so they may subscribe or unsubscribe
+	for _, h := range handlers {
+		h.ServeMQTT(w, topic, slices.Clone(payload))
+	}
+
+	return len(handlers)
+}
+
+// AssertSubscribed reports a test error unless the specified topic filter is subscribed. It returns whether the
+// assertion passed.
+func (s *Subscriber) AssertSubscribed(t testing.TB, topic string) bool {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[topic]; !ok {
+		t.Errorf("expected %s to be subscribed, but it was not", topic)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+package hqtttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestSubscriber(t *testing.T) {
+	sut := &Subscriber{}
+
+	command := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+	require.NoError(t, sut.Subscribe(t.Context(), mqtt.HandlerFunc(func(w mqtt.Writer, topic string, payload []byte) {
+		command.ServeMQTT(w, "command", payload)
+		require.NoError(t, w.WriteTopic(t.Context(), "prefix/state", mqtt.WriteOptions{}, payload))
+	}), command.AppendSubscribeOptions(nil, "prefix")...))
+
+	var wildcard []string
+	require.NoError(t, sut.Subscribe(t.Context(), mqtt.HandlerFunc(func(_ mqtt.Writer, topic string, _ []byte) {
+		wildcard = append(wildcard, topic)
+	}), mqtt.Subscription{Topic: "prefix/#"}))
+
+	assert.Equal(t, []mqtt.Subscription{{Topic: "prefix/#"}, {Topic: "prefix/command"}}, sut.Subscriptions())
+	sut.AssertSubscribed(t, "prefix/command")
+
+	w := &Writer{}
+	assert.Equal(t, 2, sut.Inject(w, "prefix/command", []byte("on")))
+	assert.Equal(t, 0, sut.Inject(w, "other", []byte("on")))
+
+	v, ok := command.Get()
+	require.True(t, ok)
+	assert.Equal(t, "on", v)
+	w.AssertPublished(t, "prefix/state", []byte("on"))
+	assert.Equal(t, []string{"prefix/command"}, wildcard)
+
+	require.NoError(t, sut.Unsubscribe(t.Context(), "prefix/command"))
+	assert.Equal(t, 1, sut.Inject(w, "prefix/command", []byte("off")))
+
+	r := &recordingTB{TB: t}
+	assert.False(t, sut.AssertSubscribed(r, "prefix/command"))
+	assert.Len(t, r.errors, 1)
+
+	t.Run("Shared Topic", func(t *testing.T) {
+		sut := &Subscriber{}
+
+		var received []string
+		a := &namedHandler{name: "a", received: &received}
+		b := &namedHandler{name: "b", received: &received}
+
+		// Like the adapter, a second subscription to the same topic adds its handler instead of replacing the first
+		require.NoError(t, sut.Subscribe(t.Context(), a, mqtt.Subscription{Topic: "shared"}))
+		require.NoError(t, sut.Subscribe(t.Context(), b, mqtt.Subscription{Topic: "shared", Options: mqtt.ReadOptions{QoS: mqtt.QOSAtLeastOnce}}))
+		assert.Equal(t, []mqtt.Subscription{{Topic: "shared", Options: mqtt.ReadOptions{QoS: mqtt.QOSAtLeastOnce}}}, sut.Subscriptions())
+
+		assert.Equal(t, 2, sut.Inject(&Writer{}, "shared", nil))
+		assert.Equal(t, []string{"a", "b"}, received)
+
+		require.NoError(t, mqtt.UnsubscribeHandler(t.Context(), sut, a, "shared"))
+		assert.Equal(t, 1, sut.Inject(&Writer{}, "shared", nil))
+		assert.Equal(t, []string{"a", "b", "b"}, received)
+
+		require.NoError(t, mqtt.UnsubscribeHandler(t.Context(), sut, b, "shared"))
+		assert.Empty(t, sut.Subscriptions())
+	})
+}
+
+type namedHandler struct {
+	name     string
+	received *[]string
+}
+
+func (h *namedHandler) ServeMQTT(mqtt.Writer, string, []byte) {
+	*h.received = append(*h.received, h.name)
+}
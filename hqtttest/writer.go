@@ -0,0 +1,70 @@
+package hqtttest
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Writer is an mqtt.Writer that records every message written to it so tests can assert on MQTT output. It embeds
+// mqtt.DryRunWriter, so topics are validated and Messages, Retained, and Reset are available. The zero value is ready
+// to use, and it is safe for concurrent use.
+type Writer struct {
+	mqtt.DryRunWriter
+}
+
+var _ mqtt.Writer = &Writer{}
+
+// LastWrite returns the last message written to the specified topic. If no message was written to the topic, the
+// second return value will be false.
+func (w *Writer) LastWrite(topic string) (mqtt.Message, bool) {
+	for _, m := range slices.Backward(w.Messages()) {
+		if m.Topic == topic {
+			return m, true
+		}
+	}
+
+	return mqtt.Message{}, false
+}
+
+// AssertPublished reports a test error unless the specified payload was written to the specified topic at least once.
+// It returns whether the assertion passed.
+func (w *Writer) AssertPublished(t testing.TB, topic string, payload []byte) bool {
+	t.Helper()
+
+	var seen [][]byte
+	for _, m := range w.Messages() {
+		if m.Topic != topic {
+			continue
+		}
+
+		if bytes.Equal(m.Payload, payload) {
+			return true
+		}
+
+		seen = append(seen, m.Payload)
+	}
+
+	if len(seen) == 0 {
+		t.Errorf("expected %q to be published to %s, but nothing was published to it", payload, topic)
+	} else {
+		t.Errorf("expected %q to be published to %s, but only saw %q", payload, topic, seen)
+	}
+
+	return false
+}
+
+// AssertNotPublished reports a test error if anything was written to the specified topic. It returns whether the
+// assertion passed.
+func (w *Writer) AssertNotPublished(t testing.TB, topic string) bool {
+	t.Helper()
+
+	if m, ok := w.LastWrite(topic); ok {
+		t.Errorf("expected nothing to be published to %s, but saw %q", topic, m.Payload)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,55 @@
+package hqtttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// recordingTB captures errors reported by assertions under test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, format)
+}
+
+func TestWriter(t *testing.T) {
+	sut := &Writer{}
+
+	require.NoError(t, sut.WriteTopic(t.Context(), "foo", mqtt.WriteOptions{}, []byte("bar")))
+	require.NoError(t, sut.WriteTopic(t.Context(), "foo", mqtt.WriteOptions{Retain: true}, []byte("baz")))
+
+	t.Run("LastWrite", func(t *testing.T) {
+		m, ok := sut.LastWrite("foo")
+		require.True(t, ok)
+		assert.Equal(t, mqtt.Message{Topic: "foo", Options: mqtt.WriteOptions{Retain: true}, Payload: []byte("baz")}, m)
+
+		_, ok = sut.LastWrite("fizz")
+		assert.False(t, ok)
+	})
+
+	t.Run("AssertPublished", func(t *testing.T) {
+		assert.True(t, sut.AssertPublished(t, "foo", []byte("bar")))
+
+		r := &recordingTB{TB: t}
+		assert.False(t, sut.AssertPublished(r, "foo", []byte("buzz")))
+		assert.False(t, sut.AssertPublished(r, "fizz", []byte("buzz")))
+		assert.Len(t, r.errors, 2)
+	})
+
+	t.Run("AssertNotPublished", func(t *testing.T) {
+		assert.True(t, sut.AssertNotPublished(t, "fizz"))
+
+		r := &recordingTB{TB: t}
+		assert.False(t, sut.AssertNotPublished(r, "foo"))
+		assert.Len(t, r.errors, 1)
+	})
+}
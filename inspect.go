@@ -0,0 +1,151 @@
+package hqtt
+
+import (
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+	"github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// ValueInspection is a snapshot of a Value or RemoteValue of a Device registered with a DeviceManager, see
+// DeviceManager.Inspect.
+type ValueInspection struct {
+	// The ID of the device, see Device.ID
+	Device string `json:"device"`
+	// The key the component is registered with, or empty for values owned by the device itself
+	Component string `json:"component,omitempty"`
+	// The name of the field holding the value, for example "State" or "BrightnessCommand"
+	Field string `json:"field"`
+	// The fully qualified topic
+	Topic string `json:"topic"`
+	// Whether the bridge publishes to the topic, or receives messages from Home Assistant on it
+	Direction mqtt.AuditDirection `json:"direction"`
+
+	// The value last written or received, or nil if there was none
+	Value any `json:"value"`
+	// The number of writes that failed, or the number of payloads that could not be unmarshaled
+	Errors uint64 `json:"errors"`
+
+	// Statistics for the topic, only set if DeviceManager.Inspect was called with a non-nil mqtt.Stats
+	Stats *mqtt.TopicStats `json:"stats,omitempty"`
+}
+
+// valueInspector is implemented by Component so DeviceManager can inspect the values of components holding any type of
+// Platform.
+type valueInspector interface {
+	inspectValues(yield func(field, prefix string, i mqtt.Inspector))
+}
+
+func (c *Component[TPlatform]) inspectValues(yield func(field, prefix string, i mqtt.Inspector)) {
+	yield("Availability", c.TopicPrefix, c.Availability)
+	valueFields(reflect.ValueOf(c.Platform), func(field string, i mqtt.Inspector) {
+		yield(field, c.TopicPrefix, i)
+	})
+}
+
+// Inspect returns a ValueInspection for every Value and RemoteValue with a topic of every registered device and its
+// components, sorted by device ID, then component key, then the order the fields are declared in. If stats is not nil,
+// the statistics of each topic are included.
+func (m *DeviceManager) Inspect(stats *mqtt.Stats) []ValueInspection {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []ValueInspection
+	for _, id := range slices.Sorted(maps.Keys(m.devices)) {
+		md := m.devices[id]
+
+		add := func(component, field, prefix string, i mqtt.Inspector) {
+			td, ok := i.Describe(prefix)
+			if !ok {
+				return
+			}
+
+			snapshot := i.Inspect()
+			vi := ValueInspection{
+				Device:    id,
+				Component: component,
+				Field:     field,
+				Topic:     td.Topic,
+				Direction: td.Direction,
+				Value:     snapshot.Value,
+				Errors:    snapshot.Errors,
+			}
+
+			if stats != nil {
+				ts := stats.Topic(td.Topic)
+				vi.Stats = &ts
+			}
+
+			result = append(result, vi)
+		}
+
+		add("", "Availability", md.device.TopicPrefix, md.device.Availability)
+		for _, k := range slices.Sorted(maps.Keys(md.components)) {
+			c, ok := md.components[k].(valueInspector)
+			if !ok {
+				continue
+			}
+
+			c.inspectValues(func(field, prefix string, i mqtt.Inspector) {
+				add(k, field, prefix, i)
+			})
+		}
+	}
+
+	return result
+}
+
+// inspectionResponse is the document served by DeviceManager.DebugHandler.
+type inspectionResponse struct {
+	Time   time.Time         `json:"time"`
+	Values []ValueInspection `json:"values"`
+}
+
+// DebugHandler returns an http.Handler that serves the result of Inspect as an indented JSON document, similar to the
+// /debug/vars endpoint of expvar. This allows inspecting a running bridge without an MQTT client, for example by mounting
+// it on a http.ServeMux:
+//
+//	mux.Handle("/debug/hqtt", manager.DebugHandler(stats))
+//
+// The devices listed can be limited with the device query parameter, which may be repeated. Values that cannot be
+// encoded as JSON (like NaN) are formatted with fmt instead. The handler exposes everything the bridge publishes and
+// receives, so it should not be reachable by untrusted clients.
+func (m *DeviceManager) DebugHandler(stats *mqtt.Stats) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		resp := inspectionResponse{Time: clock.Or(m.Clock).Now(), Values: []ValueInspection{}}
+		devices := r.URL.Query()["device"]
+		for _, vi := range m.Inspect(stats) {
+			if len(devices) > 0 && !slices.Contains(devices, vi.Device) {
+				continue
+			}
+
+			if vi.Value != nil {
+				if _, err := json.Marshal(vi.Value); err != nil {
+					vi.Value = fmt.Sprintf("%v", vi.Value)
+				}
+			}
+
+			resp.Values = append(resp.Values, vi)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.MarshalWrite(w, resp, jsontext.WithIndent("  ")); err != nil {
+			m.log.With(log.Error(err)).WarnContext(r.Context(), "Failed to write debug response")
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package hqtt
+
+import (
+	"encoding/json/v2"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newInspectedManager(t *testing.T) (*DeviceManager, *mqtt.Stats, *hqtttest.Clock) {
+	t.Helper()
+
+	c := hqtttest.NewClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	stats := mqtt.NewStats(c)
+	w := stats.Writer(discardWriter{})
+
+	sut := NewDeviceManager(w)
+	sut.Clock = c
+
+	d := &Device{
+		Identifiers:  []string{"lamp"},
+		TopicPrefix:  "lamp",
+		Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+	}
+
+	light := &Component[*platform.Light]{
+		UniqueID:    "lamp.light",
+		TopicPrefix: "lamp/light",
+		Platform: &platform.Light{
+			State:             mqtt.NewValue("state", hass.PowerStateMarshaler),
+			BrightnessCommand: mqtt.NewRemoteValue("brightness/set", mqtt.UintUnmarshaler),
+		},
+	}
+
+	sensor := &Component[*platform.Sensor[float64, any]]{
+		UniqueID:    "lamp.power",
+		TopicPrefix: "lamp/power",
+		Platform: &platform.Sensor[float64, any]{
+			State: mqtt.NewValue("state", func(v float64) ([]byte, error) {
+				return []byte("unknown"), nil
+			}),
+		},
+	}
+
+	require.NoError(t, sut.Register(d, map[string]json.MarshalerTo{
+		"light":   light,
+		"power":   sensor,
+		"removed": RemoveComponent{Platform: "sensor"},
+	}))
+	require.NoError(t, sut.Register(&Device{Identifiers: []string{"other"}}, nil))
+
+	_, err := d.Availability.Write(t.Context(), w, d.TopicPrefix, hass.Available)
+	require.NoError(t, err)
+	_, err = light.Platform.State.Write(t.Context(), w, light.TopicPrefix, hass.PowerStateOn)
+	require.NoError(t, err)
+	_, err = sensor.Platform.State.Write(t.Context(), w, sensor.TopicPrefix, math.NaN())
+	require.NoError(t, err)
+	light.Platform.BrightnessCommand.ServeMQTT(nil, "brightness/set", []byte("bogus"))
+
+	return sut, stats, c
+}
+
+func TestDeviceManager_Inspect(t *testing.T) {
+	sut, stats, c := newInspectedManager(t)
+
+	got := sut.Inspect(nil)
+	require.Len(t, got, 4)
+
+	assert.Equal(t, ValueInspection{
+		Device:    "lamp",
+		Field:     "Availability",
+		Topic:     "lamp/available",
+		Direction: mqtt.AuditDirectionPublish,
+		Value:     hass.Available,
+	}, got[0])
+	assert.Equal(t, ValueInspection{
+		Device:    "lamp",
+		Component: "light",
+		Field:     "State",
+		Topic:     "lamp/light/state",
+		Direction: mqtt.AuditDirectionPublish,
+		Value:     hass.PowerStateOn,
+	}, got[1])
+	assert.Equal(t, ValueInspection{
+		Device:    "lamp",
+		Component: "light",
+		Field:     "BrightnessCommand",
+		Topic:     "lamp/light/brightness/set",
+		Direction: mqtt.AuditDirectionReceive,
+		Errors:    1,
+	}, got[2])
+	assert.Equal(t, "power", got[3].Component)
+	assert.True(t, math.IsNaN(got[3].Value.(float64)))
+
+	t.Run("Stats", func(t *testing.T) {
+		got := sut.Inspect(stats)
+		require.Len(t, got, 4)
+
+		require.NotNil(t, got[1].Stats)
+		assert.True(t, c.Now().Equal(got[1].Stats.LastActivity))
+		got[1].Stats.LastActivity = time.Time{}
+		assert.Equal(t, mqtt.TopicStats{Publishes: 1, PublishedBytes: 2}, *got[1].Stats)
+
+		require.NotNil(t, got[2].Stats)
+		assert.Equal(t, mqtt.TopicStats{}, *got[2].Stats)
+	})
+}
+
+func TestDeviceManager_DebugHandler(t *testing.T) {
+	sut, stats, _ := newInspectedManager(t)
+	handler := sut.DebugHandler(stats)
+
+	t.Run("Method Not Allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/hqtt", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+	})
+
+	t.Run("All", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/hqtt", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var got struct {
+			Time   time.Time        `json:"time"`
+			Values []map[string]any `json:"values"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+
+		assert.True(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Equal(got.Time))
+		require.Len(t, got.Values, 4)
+		assert.Equal(t, "online", got.Values[0]["value"])
+		assert.Equal(t, "publish", got.Values[0]["direction"])
+		assert.NotContains(t, got.Values[0], "component")
+		assert.Equal(t, "light", got.Values[1]["component"])
+
+		stats := got.Values[1]["stats"].(map[string]any)
+		lastActivity, err := time.Parse(time.RFC3339, stats["last_activity"].(string))
+		require.NoError(t, err)
+		assert.True(t, got.Time.Equal(lastActivity))
+		delete(stats, "last_activity")
+		assert.Equal(t, map[string]any{
+			"publishes":       1.0,
+			"published_bytes": 2.0,
+			"publish_errors":  0.0,
+			"receives":        0.0,
+			"received_bytes":  0.0,
+		}, stats)
+		assert.Nil(t, got.Values[2]["value"])
+		assert.InDelta(t, 1, got.Values[2]["errors"], 0)
+
+		// NaN cannot be encoded as JSON and is formatted instead
+		assert.Equal(t, "NaN", got.Values[3]["value"])
+	})
+
+	t.Run("Filter Devices", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/hqtt?device=other", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, []any{}, got["values"])
+	})
+}
@@ -0,0 +1,215 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+)
+
+const (
+	SuppressedKey = "suppressed"
+
+	// DefaultLimiterInterval is the default minimum amount of time between records logged by a Limiter for the same
+	// key.
+	DefaultLimiterInterval = 10 * time.Second
+)
+
+// Limiter rate limits repetitive log records, such as warnings for a topic that is flooded with invalid payloads. At
+// most one record is logged per key every Interval. Instead of logging records that are rate limited, the Limiter
+// counts them. When the Interval ends, the first suppressed record is logged as a summary with the count attached in an
+// attribute with the key SuppressedKey, so a flood that stops is still reported. Call Flush to log pending summaries
+// immediately, for example before exiting.
+//
+// The zero value is ready to use, and it is safe for concurrent use.
+type Limiter struct {
+	// Interval is the minimum amount of time between records logged for the same key. If it is not positive,
+	// DefaultLimiterInterval is used.
+	Interval time.Duration
+
+	// The Clock used to rate limit records and schedule summaries. If nil, clock.Real is used.
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*limiterEntry
+}
+
+type limiterEntry struct {
+	last       time.Time
+	suppressed int
+
+	// The first record suppressed by Log, logged as a summary when the interval ends
+	summary *limiterRecord
+	timer   clock.Timer
+}
+
+type limiterRecord struct {
+	ctx    context.Context
+	logger *slog.Logger
+	level  slog.Level
+	msg    string
+	attrs  []slog.Attr
+}
+
+func (r *limiterRecord) log(suppressed int) {
+	r.logger.LogAttrs(r.ctx, r.level, r.msg, append(r.attrs, slog.Int(SuppressedKey, suppressed))...)
+}
+
+func (l *Limiter) interval() time.Duration {
+	if l.Interval <= 0 {
+		return DefaultLimiterInterval
+	}
+
+	return l.Interval
+}
+
+// Allow reports whether a record for the provided key should be logged. If it should, the number of records suppressed
+// for the key since the last allowed record is also returned. Records suppressed by Allow are counted, but only Log
+// schedules a summary for them.
+func (l *Limiter) Allow(key string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ok, suppressed, _ := l.allow(key)
+	return ok, suppressed
+}
+
+// allow implements Allow, also returning the entry for the key. l.mu must be held.
+func (l *Limiter) allow(key string) (bool, int, *limiterEntry) {
+	now := clock.Or(l.Clock).Now()
+	interval := l.interval()
+
+	if l.entries == nil {
+		l.entries = map[string]*limiterEntry{}
+	}
+
+	e, ok := l.entries[key]
+	if !ok {
+		e = &limiterEntry{last: now}
+		l.entries[key] = e
+		return true, 0, e
+	}
+
+	if now.Sub(e.last) < interval {
+		e.suppressed++
+		return false, 0, e
+	}
+
+	suppressed := e.suppressed
+	e.last, e.suppressed = now, 0
+	e.stop()
+
+	// Drop entries for keys that are no longer noisy so the map does not grow without bound
+	for k, other := range l.entries {
+		if k != key && now.Sub(other.last) >= interval && other.suppressed == 0 {
+			delete(l.entries, k)
+		}
+	}
+
+	return true, suppressed, e
+}
+
+// schedule remembers the provided record as the summary of the suppressed records for key, and schedules logging it
+// when the interval of the key ends. l.mu must be held.
+func (l *Limiter) schedule(key string, e *limiterEntry, r *limiterRecord) {
+	c := clock.Or(l.Clock)
+
+	e.summary = r
+	e.timer = c.AfterFunc(l.interval()-c.Now().Sub(e.last), func() {
+		l.summarize(key)
+	})
+}
+
+// stop cancels the pending summary of this entry, if any.
+func (e *limiterEntry) stop() {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+
+	e.timer, e.summary = nil, nil
+}
+
+// take returns the pending summary of this entry along with the number of suppressed records, and resets both.
+func (e *limiterEntry) take() (*limiterRecord, int) {
+	r, suppressed := e.summary, e.suppressed
+	e.suppressed = 0
+	e.stop()
+
+	return r, suppressed
+}
+
+// summarize logs the summary for the provided key when its interval ends.
+func (l *Limiter) summarize(key string) {
+	l.mu.Lock()
+	e, ok := l.entries[key]
+	if !ok || e.summary == nil {
+		l.mu.Unlock()
+		return
+	}
+
+	// The summary is a logged record, so a flood that continues is summarized once per interval
+	r, suppressed := e.take()
+	e.last = clock.Or(l.Clock).Now()
+	l.mu.Unlock()
+
+	r.log(suppressed)
+}
+
+// Flush immediately logs the summary of every key with records suppressed by Log, instead of waiting for their
+// interval to end.
+func (l *Limiter) Flush() {
+	l.mu.Lock()
+	var summaries []*limiterRecord
+	var counts []int
+	for _, e := range l.entries {
+		if e.summary == nil {
+			continue
+		}
+
+		r, suppressed := e.take()
+		summaries = append(summaries, r)
+		counts = append(counts, suppressed)
+	}
+	l.mu.Unlock()
+
+	for i, r := range summaries {
+		r.log(counts[i])
+	}
+}
+
+// Log logs a record with the provided logger if the Limiter allows a record for the provided key. If records for the
+// key were suppressed since the last record was logged, their count is attached to the record with the key
+// SuppressedKey. Otherwise, the record is suppressed, and the first record suppressed in each interval is logged as a
+// summary when the interval ends.
+func (l *Limiter) Log(ctx context.Context, logger *slog.Logger, level slog.Level, key, msg string, attrs ...slog.Attr) {
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	l.mu.Lock()
+	ok, suppressed, e := l.allow(key)
+	if !ok && e.summary == nil {
+		// The summary is logged after Log returns, so it must not share attrs with the caller
+		l.schedule(key, e, &limiterRecord{
+			ctx:    context.WithoutCancel(ctx),
+			logger: logger,
+			level:  level,
+			msg:    msg,
+			attrs:  slices.Clone(attrs),
+		})
+	}
+	l.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if suppressed > 0 {
+		attrs = append(attrs, slog.Int(SuppressedKey, suppressed))
+	}
+
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
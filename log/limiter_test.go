@@ -0,0 +1,100 @@
+package log_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/log"
+)
+
+func newLimiterLogger() (*slog.Logger, func() []string) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))
+
+	return logger, func() []string {
+		defer buf.Reset()
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	c := hqtttest.NewClock(time.Now())
+	sut := &log.Limiter{Interval: time.Second, Clock: c}
+	logger, lines := newLimiterLogger()
+
+	for i := range 3 {
+		sut.Log(t.Context(), logger, slog.LevelWarn, "foo", "flood", slog.Int("n", i))
+	}
+	sut.Log(t.Context(), logger, slog.LevelWarn, "bar", "other")
+
+	assert.Equal(t, []string{
+		"level=WARN msg=flood n=0",
+		"level=WARN msg=other",
+	}, lines())
+
+	// The flood stopped, so the first suppressed record is logged as a summary when the interval ends
+	c.Advance(time.Second)
+	assert.Equal(t, []string{"level=WARN msg=flood n=1 suppressed=2"}, lines())
+
+	// The summary counts as a logged record, so a flood that continues is summarized once per interval
+	sut.Log(t.Context(), logger, slog.LevelWarn, "foo", "flood", slog.Int("n", 3))
+	sut.Log(t.Context(), logger, slog.LevelWarn, "bar", "other")
+	assert.Equal(t, []string{"level=WARN msg=other"}, lines())
+
+	c.Advance(time.Second)
+	assert.Equal(t, []string{"level=WARN msg=flood n=3 suppressed=1"}, lines())
+
+	c.Advance(time.Second)
+	sut.Log(t.Context(), logger, slog.LevelWarn, "foo", "flood", slog.Int("n", 4))
+	assert.Equal(t, []string{"level=WARN msg=flood n=4"}, lines())
+
+	t.Run("Allow", func(t *testing.T) {
+		sut := &log.Limiter{Interval: time.Second, Clock: c}
+
+		ok, _ := sut.Allow("foo")
+		assert.True(t, ok)
+		ok, _ = sut.Allow("foo")
+		assert.False(t, ok)
+
+		// Records suppressed by Allow are attached to the next allowed record instead of being summarized
+		assert.Zero(t, c.Waiters())
+		c.Advance(time.Second)
+		ok, suppressed := sut.Allow("foo")
+		assert.True(t, ok)
+		assert.Equal(t, 1, suppressed)
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		sut := &log.Limiter{Interval: time.Second, Clock: c}
+		logger, lines := newLimiterLogger()
+
+		for range 3 {
+			sut.Log(t.Context(), logger, slog.LevelWarn, "foo", "flood")
+		}
+
+		sut.Flush()
+		assert.Equal(t, []string{
+			"level=WARN msg=flood",
+			"level=WARN msg=flood suppressed=2",
+		}, lines())
+
+		// Flushing cancels the pending summary
+		assert.Zero(t, c.Waiters())
+		sut.Flush()
+		assert.Equal(t, []string{""}, lines())
+	})
+}
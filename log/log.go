@@ -3,12 +3,16 @@ package log
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync/atomic"
 )
 
 const (
 	ComponentKey = "component"
 	ErrorKey     = "error"
+	DeviceKey    = "device"
+	UniqueIDKey  = "unique_id"
+	PlatformKey  = "platform"
 )
 
 // Error returns a slog.Attr for the provided error. The key will be ErrorKey.
@@ -17,58 +21,134 @@ func Error(e error) slog.Attr {
 }
 
 // indirectHandler is a small wrapper around a slog.Handler that allows swapping out the underlying handler on demand.
+// Attributes and groups added with WithAttrs and WithGroup are recorded and re-applied to the current underlying
+// handler, so loggers derived before To is called (e.g. package-level loggers) keep their attributes afterward.
 type indirectHandler struct {
-	h atomic.Pointer[slog.Handler]
+	h *atomic.Pointer[slog.Handler]
+
+	derive  []func(slog.Handler) slog.Handler
+	derived atomic.Pointer[derivedHandler]
 }
 
-func (i *indirectHandler) Enabled(ctx context.Context, level slog.Level) bool {
+// derivedHandler caches the result of applying indirectHandler.derive to a given underlying handler.
+type derivedHandler struct {
+	base    *slog.Handler
+	handler slog.Handler
+}
+
+func (i *indirectHandler) handler() slog.Handler {
 	h := i.h.Load()
+	if h == nil {
+		return nil
+	}
+
+	if len(i.derive) == 0 {
+		return *h
+	}
+
+	if d := i.derived.Load(); d != nil && d.base == h {
+		return d.handler
+	}
+
+	result := *h
+	for _, f := range i.derive {
+		result = f(result)
+	}
+
+	i.derived.Store(&derivedHandler{base: h, handler: result})
+	return result
+}
+
+func (i *indirectHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	h := i.handler()
 	if h == nil {
 		return false
 	}
 
-	return (*h).Enabled(ctx, level)
+	return h.Enabled(ctx, level)
 }
 
 func (i *indirectHandler) Handle(ctx context.Context, record slog.Record) error {
-	h := i.h.Load()
+	h := i.handler()
 	if h == nil {
 		return nil
 	}
 
-	return (*h).Handle(ctx, record)
+	return h.Handle(ctx, record)
 }
 
-func (i *indirectHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	h := i.h.Load()
-	if h == nil {
-		return i
-	}
+func (i *indirectHandler) with(f func(slog.Handler) slog.Handler) *indirectHandler {
+	return &indirectHandler{h: i.h, derive: append(slices.Clip(i.derive), f)}
+}
 
-	return (*h).WithAttrs(attrs)
+func (i *indirectHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return i.with(func(h slog.Handler) slog.Handler {
+		return h.WithAttrs(attrs)
+	})
 }
 
 func (i *indirectHandler) WithGroup(name string) slog.Handler {
-	h := i.h.Load()
-	if h == nil {
-		return i
-	}
-
-	return (*h).WithGroup(name)
+	return i.with(func(h slog.Handler) slog.Handler {
+		return h.WithGroup(name)
+	})
 }
 
 var _ slog.Handler = &indirectHandler{}
 
 var (
-	sink = &indirectHandler{h: atomic.Pointer[slog.Handler]{}}
+	sink = &indirectHandler{h: &atomic.Pointer[slog.Handler]{}}
 )
 
 // To updates all slog.Logger objects used internally by hqtt to write logs to the provided slog.Handler. By default,
 // log values will be discarded unless To is called at least once with a non-discarding slog.Handler.
+//
+// Wherever a context.Context is available, hqtt logs with it (e.g. slog.Logger.DebugContext), so handlers that extract
+// trace IDs or other request-scoped attributes from the context will see the context passed to hqtt.
 func To(h slog.Handler) {
-	sink.h.Store(&h)
+	var wrapped slog.Handler = contextHandler{h: h}
+	sink.h.Store(&wrapped)
+}
+
+type contextAttrsKey struct{}
+
+// WithAttrs returns a copy of the provided context carrying the provided attributes in addition to any attributes
+// already added to the context. Records logged by hqtt with the returned context (or a context derived from it) include
+// these attributes. hqtt uses this to attribute records to a device or component (see DeviceKey, UniqueIDKey, and
+// PlatformKey).
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(contextAttrsKey{}).([]slog.Attr)
+	return context.WithValue(ctx, contextAttrsKey{}, append(slices.Clip(existing), attrs...))
+}
+
+// contextHandler adds attributes stored in the context with WithAttrs to every record before passing it to the
+// underlying slog.Handler.
+type contextHandler struct {
+	h slog.Handler
 }
 
+func (c contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return c.h.Enabled(ctx, level)
+}
+
+func (c contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(contextAttrsKey{}).([]slog.Attr); ok && len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+
+	return c.h.Handle(ctx, record)
+}
+
+func (c contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h: c.h.WithAttrs(attrs)}
+}
+
+func (c contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h: c.h.WithGroup(name)}
+}
+
+var _ slog.Handler = contextHandler{}
+
 // ForComponent constructs a slog.Logger for the specified component (which is stored in an attribute with the key
 // ComponentKey).
 func ForComponent(component string) *slog.Logger {
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTo(t *testing.T) {
+	// Loggers derived before To is called must keep their attributes
+	l := ForComponent("foo").With(slog.String(DeviceKey, "bar"))
+
+	buf := &bytes.Buffer{}
+	To(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))
+	defer To(slog.DiscardHandler)
+
+	ctx := WithAttrs(t.Context(), slog.String(UniqueIDKey, "fizz"))
+	ctx = WithAttrs(ctx, slog.String(PlatformKey, "light"))
+
+	l.Info("plain")
+	l.InfoContext(ctx, "attributed")
+
+	assert.Equal(t, []string{
+		"level=INFO msg=plain component=foo device=bar",
+		"level=INFO msg=attributed component=foo device=bar unique_id=fizz platform=light",
+	}, strings.Split(strings.TrimSpace(buf.String()), "\n"))
+}
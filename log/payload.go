@@ -0,0 +1,75 @@
+package log
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+const (
+	PayloadKey = "payload"
+
+	// DefaultMaxPayloadLength is the default number of bytes of an MQTT payload that are logged before the payload is
+	// truncated.
+	DefaultMaxPayloadLength = 1024
+)
+
+// PayloadOptions controls how MQTT payloads are rendered in logs written by hqtt.
+type PayloadOptions struct {
+	// MaxLength is the maximum number of bytes of a payload to log. Longer payloads are truncated. If MaxLength is
+	// negative, payloads are never truncated. If it is zero, DefaultMaxPayloadLength is used.
+	MaxLength int
+
+	// Redact is called with the topic of each logged payload. If it returns true, the payload is replaced with a
+	// placeholder that only includes its length. If Redact is nil, no payloads are redacted.
+	Redact func(topic string) bool
+}
+
+var payloadOptions atomic.Pointer[PayloadOptions]
+
+// SetPayloadOptions updates how MQTT payloads are rendered in logs written by hqtt. It may be called at any time.
+func SetPayloadOptions(o PayloadOptions) {
+	payloadOptions.Store(&o)
+}
+
+// Payload returns a slog.Attr for an MQTT payload received from or published to the provided topic. The key will be
+// PayloadKey. The payload is redacted or truncated according to the options configured with SetPayloadOptions, but only
+// if the record is actually handled.
+func Payload(topic string, payload []byte) slog.Attr {
+	return slog.Any(PayloadKey, payloadValue{topic: topic, payload: payload})
+}
+
+type payloadValue struct {
+	topic   string
+	payload []byte
+}
+
+// LogValue implements slog.LogValuer.
+func (p payloadValue) LogValue() slog.Value {
+	var o PayloadOptions
+	if po := payloadOptions.Load(); po != nil {
+		o = *po
+	}
+
+	if o.Redact != nil && o.Redact(p.topic) {
+		return slog.StringValue(fmt.Sprintf("<redacted %d bytes>", len(p.payload)))
+	}
+
+	limit := o.MaxLength
+	if limit == 0 {
+		limit = DefaultMaxPayloadLength
+	}
+
+	if limit < 0 || len(p.payload) <= limit {
+		return slog.StringValue(string(p.payload))
+	}
+
+	// Avoid splitting a multibyte rune
+	end := limit
+	for end > 0 && !utf8.RuneStart(p.payload[end]) {
+		end--
+	}
+
+	return slog.StringValue(fmt.Sprintf("%s... (%d bytes truncated)", p.payload[:end], len(p.payload)-end))
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	defer SetPayloadOptions(PayloadOptions{})
+
+	for _, tt := range []struct {
+		name     string
+		options  PayloadOptions
+		topic    string
+		payload  string
+		expected string
+	}{
+		{name: "Default", payload: "foo", expected: "foo"},
+		{name: "Truncated", options: PayloadOptions{MaxLength: 3}, payload: "foobar", expected: "foo... (3 bytes truncated)"},
+		{name: "Rune Boundary", options: PayloadOptions{MaxLength: 2}, payload: "a€b", expected: "a... (4 bytes truncated)"},
+		{name: "Unlimited", options: PayloadOptions{MaxLength: -1}, payload: "foobar", expected: "foobar"},
+		{
+			name:     "Redacted",
+			options:  PayloadOptions{Redact: func(topic string) bool { return topic == "secret" }},
+			topic:    "secret",
+			payload:  "hunter2",
+			expected: "<redacted 7 bytes>",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPayloadOptions(tt.options)
+
+			attr := Payload(tt.topic, []byte(tt.payload))
+			assert.Equal(t, PayloadKey, attr.Key)
+			assert.Equal(t, tt.expected, attr.Value.Resolve().String())
+		})
+	}
+}
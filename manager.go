@@ -0,0 +1,503 @@
+package hqtt
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+const (
+	// DefaultMaxConcurrentConfigures is the number of devices DeviceManager.ConfigureAll configures concurrently when
+	// DeviceManager.MaxConcurrentConfigures is not positive.
+	DefaultMaxConcurrentConfigures = 4
+	// DefaultBirthSettleWindow is how long Home Assistant must stay online before DeviceManager.RediscoverOnBirth
+	// republishes discovery payloads when DeviceManager.BirthSettleWindow is zero.
+	DefaultBirthSettleWindow = 2 * time.Second
+)
+
+var (
+	// ErrDuplicateDeviceID is the error returned by DeviceManager.Register when a Device with the same ID is already
+	// registered.
+	ErrDuplicateDeviceID = errors.New("device id already registered")
+	// ErrDuplicateUniqueID is the error returned by DeviceManager.Register when a Component shares its UniqueID with a
+	// Component that is already registered. Home Assistant raises an exception when it discovers duplicate unique IDs.
+	ErrDuplicateUniqueID = errors.New("component unique id already registered")
+)
+
+// uniqueIDer is implemented by Component so DeviceManager can detect UniqueID collisions without knowing the type of
+// Platform the Component holds.
+type uniqueIDer interface {
+	uniqueID() string
+}
+
+func (c *Component[TPlatform]) uniqueID() string {
+	return c.UniqueID
+}
+
+// subscribeRequester is implemented by Component so DeviceManager can batch subscriptions for components holding any
+// type of Platform.
+type subscribeRequester interface {
+	subscribeRequest() (mqtt.SubscribeRequest, error)
+	resetSubscription()
+}
+
+// valueCloser is implemented by Component so DeviceManager can close components holding any type of Platform.
+type valueCloser interface {
+	closeValues(existing []<-chan struct{}) []<-chan struct{}
+}
+
+type managedDevice struct {
+	device     *Device
+	components map[string]json.MarshalerTo
+}
+
+// DeviceErrors is the error returned by DeviceManager operations that act on many devices. It maps the ID of each
+// device that failed to the error it encountered.
+type DeviceErrors map[string]error
+
+func (d DeviceErrors) Error() string {
+	ids := slices.Sorted(maps.Keys(d))
+
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = fmt.Sprintf("%s: %v", id, d[id])
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (d DeviceErrors) Unwrap() []error {
+	return slices.Collect(maps.Values(d))
+}
+
+// DeviceManager tracks a set of Devices and their components for applications that expose more than one Device. It
+// detects ID collisions when devices are registered instead of letting Home Assistant raise exceptions at discovery
+// time. It is safe for concurrent use, but its exported fields must not be modified after it is in use.
+type DeviceManager struct {
+	// The prefix discovery payloads are published under. If empty, discovery.Prefix is used.
+	DiscoveryPrefix string
+
+	// The maximum number of devices to configure concurrently in ConfigureAll. If not positive,
+	// DefaultMaxConcurrentConfigures is used.
+	MaxConcurrentConfigures int
+
+	// The maximum number of values to republish concurrently in RepublishAll. If not positive,
+	// mqtt.DefaultRepublishParallelism is used.
+	MaxConcurrentRepublishes int
+
+	// How often RunRediscovery re-publishes discovery payloads for all registered devices. Periodic rediscovery helps
+	// self-heal brokers that lose retained messages. If not positive, RunRediscovery returns immediately.
+	RediscoveryInterval time.Duration
+	// The maximum random delay added to each RediscoveryInterval so many bridges (or many managers) restarted at the
+	// same time do not publish discovery payloads in lockstep.
+	RediscoveryJitter time.Duration
+
+	// How long Home Assistant must stay online before RediscoverOnBirth republishes discovery payloads. Home Assistant
+	// may flap its status topic while restarting, which would otherwise republish them several times in quick
+	// succession. If zero, DefaultBirthSettleWindow is used. If negative, they are republished immediately.
+	BirthSettleWindow time.Duration
+
+	// The Clock used to schedule periodic rediscovery, to wait for BirthSettleWindow, and to timestamp DebugHandler
+	// responses. If nil, clock.Real is used.
+	Clock clock.Clock
+
+	w mqtt.Writer
+
+	mu sync.RWMutex
+
+	devices   map[string]*managedDevice
+	uniqueIDs map[string]string
+
+	log *slog.Logger
+}
+
+// NewDeviceManager constructs an empty DeviceManager that publishes discovery payloads with the provided mqtt.Writer.
+func NewDeviceManager(w mqtt.Writer) *DeviceManager {
+	return &DeviceManager{
+		w: w,
+
+		devices:   map[string]*managedDevice{},
+		uniqueIDs: map[string]string{},
+
+		log: log.ForComponent("device_manager"),
+	}
+}
+
+// Register adds the provided Device and its components to this DeviceManager. The components map uses the same format
+// as Device.Configure. The Device must pass validation performed by Device.Valid, its ID must not match the ID of a
+// Device already registered (ErrDuplicateDeviceID), and no Component may share a UniqueID with a Component that is
+// already registered (ErrDuplicateUniqueID).
+func (m *DeviceManager) Register(d *Device, components map[string]json.MarshalerTo) error {
+	if err := d.Valid(); err != nil {
+		return err
+	}
+
+	id := d.ID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.devices[id]; exists {
+		return fmt.Errorf("register %s: %w", id, ErrDuplicateDeviceID)
+	}
+
+	seen := map[string]struct{}{}
+	for _, c := range components {
+		u, ok := c.(uniqueIDer)
+		if !ok || u.uniqueID() == "" {
+			continue
+		}
+
+		uid := u.uniqueID()
+		if owner, exists := m.uniqueIDs[uid]; exists {
+			return fmt.Errorf("register %s: %s (owned by %s): %w", id, uid, owner, ErrDuplicateUniqueID)
+		}
+
+		if _, exists := seen[uid]; exists {
+			return fmt.Errorf("register %s: %s: %w", id, uid, ErrDuplicateUniqueID)
+		}
+
+		seen[uid] = struct{}{}
+	}
+
+	m.devices[id] = &managedDevice{device: d, components: maps.Clone(components)}
+	for uid := range seen {
+		m.uniqueIDs[uid] = id
+	}
+
+	m.log.With(slog.String(log.DeviceKey, id), slog.Int("components", len(components))).Debug("Registered device")
+	return nil
+}
+
+// Deregister removes the provided Device and its components from this DeviceManager. It does not remove the device
+// from Home Assistant.
+func (m *DeviceManager) Deregister(d *Device) {
+	id := d.ID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.devices[id]; !exists {
+		return
+	}
+
+	delete(m.devices, id)
+	for uid, owner := range m.uniqueIDs {
+		if owner == id {
+			delete(m.uniqueIDs, uid)
+		}
+	}
+
+	m.log.With(slog.String(log.DeviceKey, id)).Debug("Deregistered device")
+}
+
+// Close closes every component of every registered device when the bridge shuts down, see Component.Close. Pending
+// Await calls return mqtt.ErrClosed, and watchers are no longer called. It then waits until watchers that were already
+// running return, or until the provided context is done, in which case the cause of the cancellation is returned.
+// Devices stay registered, and components stay subscribed.
+func (m *DeviceManager) Close(ctx context.Context) error {
+	m.mu.RLock()
+	var done []<-chan struct{}
+	for _, md := range m.devices {
+		for _, c := range md.components {
+			if closer, ok := c.(valueCloser); ok {
+				done = closer.closeValues(done)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	m.log.With(slog.Int("values", len(done))).DebugContext(ctx, "Waiting for watchers to return")
+	return awaitDone(ctx, done)
+}
+
+func (m *DeviceManager) discoveryPrefix() string {
+	return discovery.PrefixOr(m.DiscoveryPrefix)
+}
+
+// ConfigureAll publishes the discovery payload for every registered Device (see Device.Configure), configuring up to
+// MaxConcurrentConfigures devices at once. Every device is attempted even if others fail. If any device fails to
+// configure, a DeviceErrors is returned. If the provided context is done while waiting to configure a device, no more
+// devices are configured and the cause of the cancellation is returned without waiting for configures in progress.
+func (m *DeviceManager) ConfigureAll(ctx context.Context) error {
+	m.mu.RLock()
+	devices := slices.Collect(maps.Values(m.devices))
+	m.mu.RUnlock()
+
+	limit := m.MaxConcurrentConfigures
+	if limit <= 0 {
+		limit = DefaultMaxConcurrentConfigures
+	}
+
+	prefix := m.discoveryPrefix()
+	m.log.With(slog.Int("devices", len(devices)), slog.Int("concurrency", limit)).DebugContext(ctx, "Configuring all devices")
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, limit)
+		errs = DeviceErrors{}
+		mu   sync.Mutex
+	)
+
+	for _, md := range devices {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+
+		wg.Go(func() {
+			defer func() {
+				<-sem
+			}()
+
+			if err := md.device.Configure(ctx, m.w, prefix, md.components); err != nil {
+				mu.Lock()
+				errs[md.device.ID()] = err
+				mu.Unlock()
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		m.log.With(slog.Int("failed", len(errs)), log.Error(errs)).WarnContext(ctx, "Failed to configure some devices")
+		return errs
+	}
+
+	return nil
+}
+
+// SubscribeAll subscribes every registered Component (see Component.Subscribe) with as few requests to the broker as
+// possible. If the provided mqtt.Subscriber implements mqtt.BatchSubscriber, subscriptions for every component are
+// sent together instead of one SUBSCRIBE packet per component, which speeds up starting bridges that expose many
+// entities. Components are unsubscribed individually with Component.Unsubscribe.
+//
+// Components that fail to subscribe (for example, because they are already subscribed) do not prevent the others from
+// being subscribed, and are reported with a DeviceErrors. If the subscriber returns an error, no component is marked as
+// subscribed, so SubscribeAll can be retried.
+func (m *DeviceManager) SubscribeAll(ctx context.Context, s mqtt.Subscriber) error {
+	m.mu.RLock()
+	ids := slices.Sorted(maps.Keys(m.devices))
+	devices := make([]*managedDevice, len(ids))
+	for i, id := range ids {
+		devices[i] = m.devices[id]
+	}
+	m.mu.RUnlock()
+
+	var (
+		requests   []mqtt.SubscribeRequest
+		requesters []subscribeRequester
+	)
+
+	errs := DeviceErrors{}
+	for i, md := range devices {
+		for _, k := range slices.Sorted(maps.Keys(md.components)) {
+			c, ok := md.components[k].(subscribeRequester)
+			if !ok {
+				continue
+			}
+
+			r, err := c.subscribeRequest()
+			if err != nil {
+				errs[ids[i]] = errors.Join(errs[ids[i]], fmt.Errorf("component %s: %w", k, err))
+				continue
+			}
+
+			requests = append(requests, r)
+			requesters = append(requesters, c)
+		}
+	}
+
+	m.log.With(slog.Int("components", len(requests))).DebugContext(ctx, "Subscribing all components")
+	if err := mqtt.SubscribeBatch(ctx, s, requests...); err != nil {
+		for _, c := range requesters {
+			c.resetSubscription()
+		}
+
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	if len(errs) > 0 {
+		m.log.With(slog.Int("failed", len(errs)), log.Error(errs)).WarnContext(ctx, "Failed to subscribe some devices")
+		return errs
+	}
+
+	return nil
+}
+
+// RunRediscovery calls ConfigureAll every RediscoveryInterval (plus a random delay of up to RediscoveryJitter) until
+// the provided context is done, at which point the cause of the cancellation is returned. Errors from ConfigureAll are
+// logged and do not stop rediscovery. This is independent of Home Assistant birth messages, see RediscoverOnBirth.
+// If RediscoveryInterval is not positive, RunRediscovery returns nil immediately.
+func (m *DeviceManager) RunRediscovery(ctx context.Context) error {
+	if m.RediscoveryInterval <= 0 {
+		return nil
+	}
+
+	t := clock.Or(m.Clock).NewTimer(m.nextRediscovery())
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-t.C():
+		}
+
+		m.log.DebugContext(ctx, "Periodic rediscovery")
+		if err := m.ConfigureAll(ctx); err != nil {
+			m.log.With(log.Error(err)).WarnContext(ctx, "Periodic rediscovery failed")
+		}
+
+		t.Reset(m.nextRediscovery())
+	}
+}
+
+// RediscoverOnBirth watches the provided status value (see discovery.HomeAssistantAvailability), which must be
+// subscribed separately, and calls ConfigureAll whenever Home Assistant comes back online, since it forgets entities
+// whose discovery payloads were not retained when it restarts. If announce is not nil, it is called afterward, for
+// example to publish availability and state. Rediscovery waits until Home Assistant stayed online for
+// BirthSettleWindow, so a status topic that flaps while Home Assistant restarts only republishes discovery payloads
+// once. Errors are logged. Nothing is republished once the provided context is done. The returned ID can be passed to
+// mqtt.RemoteValue.Unwatch to stop watching.
+func (m *DeviceManager) RediscoverOnBirth(ctx context.Context, status *mqtt.RemoteValue[hass.Availability], announce func(context.Context) error) int {
+	rediscover := func(a hass.Availability) {
+		if a != hass.Available || ctx.Err() != nil {
+			return
+		}
+
+		m.log.DebugContext(ctx, "Home Assistant is online, rediscovering")
+		err := m.ConfigureAll(ctx)
+		if announce != nil {
+			err = errors.Join(err, announce(ctx))
+		}
+
+		if err != nil {
+			m.log.With(log.Error(err)).WarnContext(ctx, "Rediscovery after Home Assistant birth failed")
+		}
+	}
+
+	if window := m.birthSettleWindow(); window > 0 {
+		rediscover = mqtt.Debounce(m.Clock, window, rediscover)
+	}
+
+	return status.Watch(rediscover)
+}
+
+// republisher is implemented by mqtt.Value so RepublishAll can republish values holding any type.
+type republisher interface {
+	Republisher(prefix string) mqtt.Republisher
+}
+
+// RepublishAll republishes the current value of the Availability of every registered Device and of every Value of
+// their components (see mqtt.RepublishAll), so Home Assistant learns the state of entities after it restarts even if
+// the values are not retained. Up to MaxConcurrentRepublishes values are republished at once. Values that were never
+// written are skipped.
+func (m *DeviceManager) RepublishAll(ctx context.Context) error {
+	m.mu.RLock()
+	var values []mqtt.Republisher
+	for _, id := range slices.Sorted(maps.Keys(m.devices)) {
+		md := m.devices[id]
+		values = append(values, md.device.Availability.Republisher(md.device.TopicPrefix))
+
+		for _, k := range slices.Sorted(maps.Keys(md.components)) {
+			if c, ok := md.components[k].(valueInspector); ok {
+				c.inspectValues(func(_, prefix string, i mqtt.Inspector) {
+					if r, ok := i.(republisher); ok {
+						values = append(values, r.Republisher(prefix))
+					}
+				})
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	m.log.With(slog.Int("values", len(values))).DebugContext(ctx, "Republishing all values")
+	return mqtt.RepublishAll(ctx, m.w, m.MaxConcurrentRepublishes, values...)
+}
+
+// AutoRediscoverOptions configures AutoRediscoverWithOptions.
+type AutoRediscoverOptions struct {
+	// How long Home Assistant must stay online before the device is rediscovered, see DeviceManager.BirthSettleWindow.
+	// If zero, DefaultBirthSettleWindow is used. If negative, it is rediscovered immediately.
+	BirthSettleWindow time.Duration
+
+	// The Clock used to wait for BirthSettleWindow. If nil, clock.Real is used.
+	Clock clock.Clock
+}
+
+// AutoRediscover subscribes to the status topic of Home Assistant (see discovery.HomeAssistantAvailability) and
+// publishes the discovery payload of the provided Device and components every time Home Assistant comes online,
+// followed by the current value of every Value they hold (see DeviceManager.RepublishAll). Since Home Assistant retains
+// its status, this also configures the device shortly after subscribing if Home Assistant is already online. Once the
+// provided context is done, nothing is republished, the status value is closed, and its handler is released (see
+// mqtt.UnsubscribeHandler), so other handlers subscribed to the status topic are kept if the Subscriber supports it.
+// It uses default AutoRediscoverOptions.
+//
+// Applications that already subscribe to the status topic, or that expose more than one Device, should pass their
+// status value to DeviceManager.RediscoverOnBirth instead.
+func AutoRediscover(ctx context.Context, w mqtt.Writer, sub mqtt.Subscriber, d *Device, components map[string]json.MarshalerTo) error {
+	return AutoRediscoverWithOptions(ctx, w, sub, d, components, AutoRediscoverOptions{})
+}
+
+// AutoRediscoverWithOptions is like AutoRediscover, but uses the provided AutoRediscoverOptions.
+func AutoRediscoverWithOptions(ctx context.Context, w mqtt.Writer, sub mqtt.Subscriber, d *Device, components map[string]json.MarshalerTo, opts AutoRediscoverOptions) error {
+	m := NewDeviceManager(w)
+	m.BirthSettleWindow = opts.BirthSettleWindow
+	m.Clock = opts.Clock
+
+	if err := m.Register(d, components); err != nil {
+		return err
+	}
+
+	status := discovery.HomeAssistantAvailability("")
+	m.RediscoverOnBirth(ctx, status, m.RepublishAll)
+
+	topic := status.FullyQualifiedTopic("")
+	if err := sub.Subscribe(ctx, status, mqtt.Subscription{Topic: topic}); err != nil {
+		status.Close()
+		return fmt.Errorf("subscribe to home assistant status: %w", err)
+	}
+
+	context.AfterFunc(ctx, func() {
+		status.Close()
+
+		if err := mqtt.UnsubscribeHandler(context.WithoutCancel(ctx), sub, status, topic); err != nil {
+			m.log.With(slog.String("topic", topic), log.Error(err)).WarnContext(ctx, "Failed to unsubscribe from home assistant status")
+		}
+	})
+
+	return nil
+}
+
+func (m *DeviceManager) birthSettleWindow() time.Duration {
+	if m.BirthSettleWindow == 0 {
+		return DefaultBirthSettleWindow
+	}
+
+	return m.BirthSettleWindow
+}
+
+func (m *DeviceManager) nextRediscovery() time.Duration {
+	if m.RediscoveryJitter <= 0 {
+		return m.RediscoveryInterval
+	}
+
+	return m.RediscoveryInterval + rand.N(m.RediscoveryJitter)
+}
@@ -0,0 +1,626 @@
+package hqtt
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func TestDeviceManager_Register(t *testing.T) {
+	t.Run("Invalid Device", func(t *testing.T) {
+		require.ErrorIs(t, NewDeviceManager(nil).Register(&Device{Name: "foo"}, nil), ErrInvalidDevice)
+	})
+
+	t.Run("Duplicate Device ID", func(t *testing.T) {
+		sut := NewDeviceManager(nil)
+
+		require.NoError(t, sut.Register(&Device{Identifiers: []string{"foo"}}, nil))
+		require.ErrorIs(t, sut.Register(&Device{Identifiers: []string{"foo"}}, nil), ErrDuplicateDeviceID)
+	})
+
+	t.Run("Duplicate Unique ID", func(t *testing.T) {
+		t.Run("Same Device", func(t *testing.T) {
+			sut := NewDeviceManager(nil)
+
+			require.ErrorIs(t, sut.Register(&Device{Identifiers: []string{"foo"}}, map[string]json.MarshalerTo{
+				"a": &Component[*platform.Light]{UniqueID: "light"},
+				"b": &Component[*platform.Light]{UniqueID: "light"},
+			}), ErrDuplicateUniqueID)
+		})
+
+		t.Run("Across Devices", func(t *testing.T) {
+			sut := NewDeviceManager(nil)
+
+			require.NoError(t, sut.Register(&Device{Identifiers: []string{"foo"}}, map[string]json.MarshalerTo{
+				"a": &Component[*platform.Light]{UniqueID: "light"},
+			}))
+			require.ErrorIs(t, sut.Register(&Device{Identifiers: []string{"bar"}}, map[string]json.MarshalerTo{
+				"a": &Component[*platform.Light]{UniqueID: "light"},
+			}), ErrDuplicateUniqueID)
+		})
+	})
+
+	t.Run("Deregister releases IDs", func(t *testing.T) {
+		sut := NewDeviceManager(nil)
+
+		d := &Device{Identifiers: []string{"foo"}}
+		components := map[string]json.MarshalerTo{
+			"a": &Component[*platform.Light]{UniqueID: "light"},
+			"b": RemoveComponent{Platform: "light"},
+		}
+
+		require.NoError(t, sut.Register(d, components))
+		sut.Deregister(d)
+		require.NoError(t, sut.Register(d, components))
+	})
+}
+
+type failingWriter struct {
+	mu     sync.Mutex
+	fail   map[string]error
+	topics []string
+}
+
+func (f *failingWriter) WriteTopic(_ context.Context, topic string, _ mqtt.WriteOptions, _ []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.topics = append(f.topics, topic)
+	return f.fail[topic]
+}
+
+func TestDeviceManager_ConfigureAll(t *testing.T) {
+	boom := errors.New("boom")
+	w := &failingWriter{fail: map[string]error{"custom/device/bar/config": boom}}
+
+	sut := NewDeviceManager(w)
+	sut.DiscoveryPrefix = "custom"
+	sut.MaxConcurrentConfigures = 1
+
+	require.NoError(t, sut.Register(&Device{DiscoveryID: "foo", Identifiers: []string{"foo"}}, nil))
+	require.NoError(t, sut.Register(&Device{DiscoveryID: "bar", Identifiers: []string{"bar"}}, nil))
+	require.NoError(t, sut.Register(&Device{DiscoveryID: "fizz", Identifiers: []string{"fizz"}}, nil))
+
+	err := sut.ConfigureAll(t.Context())
+	require.ErrorIs(t, err, boom)
+
+	var errs DeviceErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs, "bar")
+
+	assert.ElementsMatch(t, []string{"custom/device/foo/config", "custom/device/bar/config", "custom/device/fizz/config"}, w.topics)
+}
+
+// blockingWriter blocks every write until release is closed, ignoring the context.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingWriter) WriteTopic(context.Context, string, mqtt.WriteOptions, []byte) error {
+	b.started <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func TestDeviceManager_ConfigureAll_Canceled(t *testing.T) {
+	w := &blockingWriter{started: make(chan struct{}, 2), release: make(chan struct{})}
+	defer close(w.release)
+
+	sut := NewDeviceManager(w)
+	sut.MaxConcurrentConfigures = 1
+
+	require.NoError(t, sut.Register(&Device{DiscoveryID: "foo", Identifiers: []string{"foo"}}, nil))
+	require.NoError(t, sut.Register(&Device{DiscoveryID: "bar", Identifiers: []string{"bar"}}, nil))
+
+	boom := errors.New("boom")
+	ctx, cancel := context.WithCancelCause(t.Context())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sut.ConfigureAll(ctx)
+	}()
+
+	// Stop while the first device is still being configured
+	<-w.started
+	cancel(boom)
+
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, boom)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "ConfigureAll did not return after the context was canceled")
+	}
+
+	assert.Empty(t, w.started, "no more devices should be configured once the context is done")
+}
+
+// newSubscribableLight returns a Component holding a Light that subscribes to its command topic under the provided
+// prefix.
+func newSubscribableLight(prefix string) *Component[*platform.Light] {
+	return &Component[*platform.Light]{
+		UniqueID:    prefix,
+		TopicPrefix: prefix,
+		Platform: &platform.Light{
+			Command: mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+		},
+	}
+}
+
+// unbatchedSubscriber hides the mqtt.BatchSubscriber implementation of the wrapped Subscriber.
+type unbatchedSubscriber struct {
+	s *hqtttest.Subscriber
+}
+
+func (u unbatchedSubscriber) Subscribe(ctx context.Context, handler mqtt.Handler, subscriptions ...mqtt.Subscription) error {
+	return u.s.Subscribe(ctx, handler, subscriptions...)
+}
+
+func (u unbatchedSubscriber) Unsubscribe(ctx context.Context, topics ...string) error {
+	return u.s.Unsubscribe(ctx, topics...)
+}
+
+// failingSubscriber fails every subscription with err.
+type failingSubscriber struct {
+	err error
+}
+
+func (f failingSubscriber) Subscribe(context.Context, mqtt.Handler, ...mqtt.Subscription) error {
+	return f.err
+}
+
+func (f failingSubscriber) Unsubscribe(context.Context, ...string) error {
+	return nil
+}
+
+func TestDeviceManager_SubscribeAll(t *testing.T) {
+	newManager := func(t *testing.T) (*DeviceManager, map[string]*Component[*platform.Light]) {
+		sut := NewDeviceManager(&hqtttest.Writer{})
+
+		lights := map[string]*Component[*platform.Light]{}
+		for _, id := range []string{"foo", "bar"} {
+			lights[id+"/a"] = newSubscribableLight(id + "/a")
+			lights[id+"/b"] = newSubscribableLight(id + "/b")
+
+			require.NoError(t, sut.Register(&Device{DiscoveryID: id, Identifiers: []string{id}}, map[string]json.MarshalerTo{
+				"a":       lights[id+"/a"],
+				"b":       lights[id+"/b"],
+				"removed": RemoveComponent{Platform: "light"},
+			}))
+		}
+
+		return sut, lights
+	}
+
+	t.Run("Batched", func(t *testing.T) {
+		sut, lights := newManager(t)
+		s := &hqtttest.Subscriber{}
+
+		require.NoError(t, sut.SubscribeAll(t.Context(), s))
+		assert.Equal(t, 1, s.Requests(), "every component should be subscribed with a single request")
+
+		for prefix, c := range lights {
+			s.AssertSubscribed(t, prefix+"/command")
+			assert.Equal(t, 1, s.Inject(nil, prefix+"/command", []byte("ON")))
+
+			v, ok := c.Platform.Command.Get()
+			assert.True(t, ok, prefix)
+			assert.Equal(t, hass.PowerStateOn, v, prefix)
+		}
+	})
+
+	t.Run("Unbatched", func(t *testing.T) {
+		sut, _ := newManager(t)
+		s := &hqtttest.Subscriber{}
+
+		require.NoError(t, sut.SubscribeAll(t.Context(), unbatchedSubscriber{s}))
+		assert.Equal(t, 4, s.Requests(), "subscribers that cannot batch should be called once per component")
+	})
+
+	t.Run("Already Subscribed", func(t *testing.T) {
+		sut, lights := newManager(t)
+		s := &hqtttest.Subscriber{}
+		require.NoError(t, lights["bar/a"].Subscribe(t.Context(), s))
+
+		err := sut.SubscribeAll(t.Context(), s)
+		require.ErrorIs(t, err, ErrComponentAlreadySubscribed)
+
+		var errs DeviceErrors
+		require.ErrorAs(t, err, &errs)
+		assert.Len(t, errs, 1)
+		assert.Contains(t, errs, "bar")
+
+		// The remaining components are still subscribed together
+		assert.Equal(t, 2, s.Requests())
+		s.AssertSubscribed(t, "foo/a/command")
+		s.AssertSubscribed(t, "bar/b/command")
+	})
+
+	t.Run("Subscriber Error", func(t *testing.T) {
+		sut, _ := newManager(t)
+		boom := errors.New("boom")
+
+		require.ErrorIs(t, sut.SubscribeAll(t.Context(), failingSubscriber{err: boom}), boom)
+
+		// No component is left marked as subscribed, so subscribing again succeeds
+		s := &hqtttest.Subscriber{}
+		require.NoError(t, sut.SubscribeAll(t.Context(), s))
+		assert.Len(t, s.Subscriptions(), 4)
+	})
+}
+
+type ctxKey struct{}
+
+// ctxHandler records the value stored under ctxKey for every record it handles.
+type ctxHandler struct {
+	mu     sync.Mutex
+	values []any
+}
+
+func (h *ctxHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *ctxHandler) Handle(ctx context.Context, _ slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.values = append(h.values, ctx.Value(ctxKey{}))
+	return nil
+}
+
+func (h *ctxHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *ctxHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestDeviceManager_ConfigureAll_LogsWithContext(t *testing.T) {
+	w := &failingWriter{fail: map[string]error{"homeassistant/device/foo/config": errors.New("boom")}}
+	sut := NewDeviceManager(w)
+	require.NoError(t, sut.Register(&Device{DiscoveryID: "foo", Identifiers: []string{"foo"}}, nil))
+
+	h := &ctxHandler{}
+	log.To(h)
+	defer log.To(slog.DiscardHandler)
+
+	require.Error(t, sut.ConfigureAll(context.WithValue(t.Context(), ctxKey{}, "trace")))
+
+	require.NotEmpty(t, h.values)
+	for _, v := range h.values {
+		assert.Equal(t, "trace", v)
+	}
+}
+
+func TestDeviceManager_RunRediscovery(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		require.NoError(t, NewDeviceManager(nil).RunRediscovery(t.Context()))
+	})
+
+	t.Run("Periodic", func(t *testing.T) {
+		w := &failingWriter{}
+
+		sut := NewDeviceManager(w)
+		sut.RediscoveryInterval = time.Millisecond
+		sut.RediscoveryJitter = time.Millisecond
+		require.NoError(t, sut.Register(&Device{DiscoveryID: "foo", Identifiers: []string{"foo"}}, nil))
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan error)
+		go func() {
+			done <- sut.RunRediscovery(ctx)
+		}()
+
+		require.Eventually(t, func() bool {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+
+			return len(w.topics) >= 3
+		}, time.Second, time.Millisecond)
+
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+	t.Run("Clock", func(t *testing.T) {
+		c := hqtttest.NewClock(time.Now())
+		w := &hqtttest.Writer{}
+
+		sut := NewDeviceManager(w)
+		sut.RediscoveryInterval = time.Hour
+		sut.Clock = c
+		require.NoError(t, sut.Register(&Device{DiscoveryID: "foo", Identifiers: []string{"foo"}}, nil))
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan error)
+		go func() {
+			done <- sut.RunRediscovery(ctx)
+		}()
+
+		require.NoError(t, c.BlockUntil(t.Context(), 1))
+		c.Advance(59 * time.Minute)
+		assert.Empty(t, w.Messages())
+
+		c.Advance(time.Minute)
+		require.Eventually(t, func() bool {
+			return len(w.Messages()) == 1
+		}, time.Second, time.Millisecond)
+
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+}
+
+func TestDeviceManager_RediscoverOnBirth(t *testing.T) {
+	setup := func(t *testing.T, window time.Duration) (*DeviceManager, *hqtttest.Writer, *hqtttest.Clock, *mqtt.RemoteValue[hass.Availability], *int) {
+		c := hqtttest.NewClock(time.Now())
+		w := &hqtttest.Writer{}
+
+		sut := NewDeviceManager(w)
+		sut.BirthSettleWindow = window
+		sut.Clock = c
+		require.NoError(t, sut.Register(&Device{DiscoveryID: "foo", Identifiers: []string{"foo"}}, nil))
+
+		announced := 0
+		status := mqtt.NewRemoteValue("homeassistant/status", hass.AvailabilityUnmarshaler)
+		sut.RediscoverOnBirth(t.Context(), status, func(context.Context) error {
+			announced++
+			return nil
+		})
+
+		return sut, w, c, status, &announced
+	}
+
+	t.Run("Settles", func(t *testing.T) {
+		_, w, c, status, announced := setup(t, 0)
+
+		// Home Assistant flapping its status while restarting only rediscovers once it settles
+		for _, payload := range []string{"online", "offline", "online", "online"} {
+			status.ServeMQTT(nil, "homeassistant/status", []byte(payload))
+			c.Advance(DefaultBirthSettleWindow / 2)
+		}
+
+		assert.Empty(t, w.Messages())
+
+		c.Advance(DefaultBirthSettleWindow / 2)
+		assert.Len(t, w.Messages(), 1)
+		assert.Equal(t, 1, *announced)
+	})
+
+	t.Run("Goes Offline", func(t *testing.T) {
+		_, w, c, status, announced := setup(t, time.Second)
+
+		status.ServeMQTT(nil, "homeassistant/status", []byte("online"))
+		c.Advance(500 * time.Millisecond)
+		status.ServeMQTT(nil, "homeassistant/status", []byte("offline"))
+		c.Advance(time.Hour)
+
+		assert.Empty(t, w.Messages())
+		assert.Zero(t, *announced)
+	})
+
+	t.Run("Immediate", func(t *testing.T) {
+		_, w, _, status, announced := setup(t, -1)
+
+		status.ServeMQTT(nil, "homeassistant/status", []byte("online"))
+		status.ServeMQTT(nil, "homeassistant/status", []byte("online"))
+
+		assert.Len(t, w.Messages(), 2)
+		assert.Equal(t, 2, *announced)
+	})
+}
+
+func TestDeviceManager_RepublishAll(t *testing.T) {
+	w := &hqtttest.Writer{}
+	sut := NewDeviceManager(w)
+
+	d := &Device{
+		Identifiers:  []string{"foo"},
+		TopicPrefix:  "foo",
+		Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+	}
+	light := &Component[*platform.Light]{
+		UniqueID:    "light",
+		TopicPrefix: "foo/light",
+		Platform: &platform.Light{
+			State:      mqtt.NewValue("state", hass.PowerStateMarshaler),
+			Brightness: mqtt.NewValue("brightness", mqtt.UintMarshaler),
+			Command:    mqtt.NewRemoteValue("set", hass.PowerStateUnmarshaler),
+		},
+	}
+	require.NoError(t, sut.Register(d, map[string]json.MarshalerTo{"light": light}))
+
+	require.NoError(t, mqtt.Error(d.Availability.Write(t.Context(), w, d.TopicPrefix, hass.Available)))
+	require.NoError(t, mqtt.Error(light.Platform.State.Write(t.Context(), w, light.TopicPrefix, hass.PowerStateOn)))
+	w.Reset()
+
+	require.NoError(t, sut.RepublishAll(t.Context()))
+	w.AssertPublished(t, "foo/available", []byte("online"))
+	w.AssertPublished(t, "foo/light/state", []byte("ON"))
+	w.AssertNotPublished(t, "foo/light/brightness")
+	assert.Len(t, w.Messages(), 2)
+}
+
+func TestDeviceManager_RepublishAll_MaxConcurrentRepublishes(t *testing.T) {
+	w := &blockingWriter{started: make(chan struct{}, 2), release: make(chan struct{})}
+	sut := NewDeviceManager(w)
+	sut.MaxConcurrentRepublishes = 1
+
+	d := &Device{
+		Identifiers:  []string{"foo"},
+		TopicPrefix:  "foo",
+		Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+	}
+	light := &Component[*platform.Light]{
+		UniqueID:    "light",
+		TopicPrefix: "foo/light",
+		Platform:    &platform.Light{State: mqtt.NewValue("state", hass.PowerStateMarshaler)},
+	}
+	require.NoError(t, sut.Register(d, map[string]json.MarshalerTo{"light": light}))
+
+	require.NoError(t, mqtt.Error(d.Availability.Write(t.Context(), &hqtttest.Writer{}, d.TopicPrefix, hass.Available)))
+	require.NoError(t, mqtt.Error(light.Platform.State.Write(t.Context(), &hqtttest.Writer{}, light.TopicPrefix, hass.PowerStateOn)))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sut.RepublishAll(t.Context())
+	}()
+
+	<-w.started
+	select {
+	case <-w.started:
+		require.Fail(t, "only one value should be republished at a time")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	require.NoError(t, <-done)
+	assert.Len(t, w.started, 1, "the second value should be republished after the first")
+}
+
+func TestAutoRediscover(t *testing.T) {
+	newSensor := func() (*Device, map[string]json.MarshalerTo, *Component[*platform.BinarySensor[any]]) {
+		d := &Device{
+			Identifiers:  []string{"foo"},
+			TopicPrefix:  "foo",
+			Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+		}
+		sensor := &Component[*platform.BinarySensor[any]]{
+			UniqueID:    "sensor",
+			TopicPrefix: "foo/sensor",
+			Platform:    platform.NewBinarySensor[any](mqtt.NewValue("state", hass.PowerStateMarshaler), nil),
+		}
+
+		return d, map[string]json.MarshalerTo{"sensor": sensor}, sensor
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		w := &hqtttest.Writer{}
+		s := &hqtttest.Subscriber{}
+		d, components, sensor := newSensor()
+
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		require.NoError(t, AutoRediscover(ctx, w, s, d, components))
+		s.AssertSubscribed(t, "homeassistant/status")
+
+		require.NoError(t, mqtt.Error(sensor.Platform.State.Write(t.Context(), w, sensor.TopicPrefix, hass.PowerStateOn)))
+		w.Reset()
+
+		require.Equal(t, 1, s.Inject(w, "homeassistant/status", []byte("online")))
+		require.Eventually(t, func() bool {
+			_, republished := w.LastWrite("foo/sensor/state")
+			return republished
+		}, 5*time.Second, 10*time.Millisecond)
+
+		_, configured := w.LastWrite(d.DiscoveryTopic(""))
+		assert.True(t, configured, "the device should be configured before republishing state")
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		w := &hqtttest.Writer{}
+		s := &hqtttest.Subscriber{}
+		c := hqtttest.NewClock(time.Now())
+		d, components, _ := newSensor()
+
+		ctx, cancel := context.WithCancel(t.Context())
+		require.NoError(t, AutoRediscoverWithOptions(ctx, w, s, d, components, AutoRediscoverOptions{
+			BirthSettleWindow: time.Minute,
+			Clock:             c,
+		}))
+
+		s.Inject(w, "homeassistant/status", []byte("online"))
+		c.Advance(time.Minute - time.Second)
+		assert.Empty(t, w.Messages())
+
+		c.Advance(time.Second)
+		_, configured := w.LastWrite(d.DiscoveryTopic(""))
+		assert.True(t, configured)
+
+		// The status value is closed once the context is done
+		cancel()
+		w.Reset()
+		require.Eventually(t, func() bool {
+			s.Inject(w, "homeassistant/status", []byte("online"))
+			c.Advance(time.Minute)
+			return len(w.Messages()) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Unsubscribes On Cancel", func(t *testing.T) {
+		s := &hqtttest.Subscriber{}
+		d, components, _ := newSensor()
+
+		ctx, cancel := context.WithCancel(t.Context())
+		require.NoError(t, AutoRediscover(ctx, &hqtttest.Writer{}, s, d, components))
+		s.AssertSubscribed(t, "homeassistant/status")
+
+		cancel()
+		require.Eventually(t, func() bool {
+			return len(s.Subscriptions()) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Subscribe Fails", func(t *testing.T) {
+		boom := errors.New("boom")
+		d, components, _ := newSensor()
+
+		err := AutoRediscover(t.Context(), &hqtttest.Writer{}, failingSubscriber{err: boom}, d, components)
+		require.ErrorIs(t, err, boom)
+	})
+}
+
+func TestDeviceManager_Close(t *testing.T) {
+	sut := NewDeviceManager(nil)
+
+	light := &Component[*platform.Light]{
+		UniqueID: "light",
+		Platform: &platform.Light{
+			Command:           mqtt.NewRemoteValue("set", hass.PowerStateUnmarshaler),
+			BrightnessCommand: mqtt.NewRemoteValue("brightness/set", mqtt.UintUnmarshaler),
+		},
+	}
+	sensor := &Component[*platform.BinarySensor[any]]{UniqueID: "sensor", Platform: &platform.BinarySensor[any]{}}
+
+	require.NoError(t, sut.Register(&Device{Identifiers: []string{"foo"}}, map[string]json.MarshalerTo{
+		"light":   light,
+		"sensor":  sensor,
+		"removed": RemoveComponent{Platform: "sensor"},
+	}))
+
+	errs := make(chan error)
+	go func() {
+		_, err := light.Platform.Command.Await(t.Context(), mqtt.DesiredValue(hass.PowerStateOn))
+		errs <- err
+	}()
+
+	release := make(chan struct{})
+	light.Platform.BrightnessCommand.Watch(func(uint) {
+		<-release
+	})
+	go light.Platform.BrightnessCommand.ServeMQTT(nil, "brightness/set", []byte("42"))
+	require.Eventually(t, func() bool {
+		_, ok := light.Platform.BrightnessCommand.Get()
+		return ok
+	}, 5*time.Second, time.Millisecond)
+
+	t.Run("Times Out Waiting For Watchers", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, sut.Close(ctx), context.DeadlineExceeded)
+		require.ErrorIs(t, <-errs, mqtt.ErrClosed)
+	})
+
+	close(release)
+	require.NoError(t, sut.Close(t.Context()))
+}
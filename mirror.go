@@ -0,0 +1,179 @@
+package hqtt
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// MirrorManager publishes the same Devices to every target of an mqtt.Mirror, for example a production and a staging
+// Home Assistant instance. Each target gets its own DeviceManager, which publishes discovery payloads under the
+// DiscoveryPrefix of the target and rediscovers the devices when the Home Assistant instance using that target
+// restarts, and its own AvailabilityManager for every Device. Discovery payloads, availability, and republished state
+// are only published to the target they are for, so an outage of one broker or Home Assistant instance does not change
+// what the others see. State written through the Mirror still reaches every target, and commands received from any
+// target are handled once.
+//
+// Construct one with NewMirrorManager.
+type MirrorManager struct {
+	mirror *mqtt.Mirror
+
+	// Tracks every device so components are subscribed once, through the Mirror
+	all     *DeviceManager
+	targets []*mirroredTarget
+
+	mu sync.Mutex
+}
+
+// mirroredTarget holds the managers of a single target of the Mirror.
+type mirroredTarget struct {
+	mqtt.MirrorTarget
+
+	devices      *DeviceManager
+	availability []*AvailabilityManager
+}
+
+// NewMirrorManager constructs an empty MirrorManager for the targets of the provided mqtt.Mirror.
+func NewMirrorManager(m *mqtt.Mirror) *MirrorManager {
+	mm := &MirrorManager{
+		mirror: m,
+		all:    NewDeviceManager(m),
+	}
+
+	for _, t := range m.Targets() {
+		devices := NewDeviceManager(t.Writer)
+		devices.DiscoveryPrefix = t.DiscoveryPrefix
+
+		mm.targets = append(mm.targets, &mirroredTarget{MirrorTarget: t, devices: devices})
+	}
+
+	return mm
+}
+
+// Target returns the DeviceManager of the target with the provided name, or false if there is no such target. Use it
+// to configure rediscovery for that target before calling Start.
+func (m *MirrorManager) Target(name string) (*DeviceManager, bool) {
+	for _, t := range m.targets {
+		if t.Name == name {
+			return t.devices, true
+		}
+	}
+
+	return nil, false
+}
+
+// Register adds the provided Device and its components to the DeviceManager of every target (see
+// DeviceManager.Register), and constructs an AvailabilityManager for it on every target. Devices must be registered
+// before calling Start.
+func (m *MirrorManager) Register(d *Device, components map[string]json.MarshalerTo) error {
+	if err := m.all.Register(d, components); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.targets {
+		// Every target manager holds the same devices as all, so this cannot fail once all accepted the device
+		_ = t.devices.Register(d, components)
+		t.availability = append(t.availability, NewAvailabilityManager(t.Writer, d, components))
+	}
+
+	return nil
+}
+
+// ConfigureAll publishes the discovery payload of every registered Device to every target, under the DiscoveryPrefix
+// of the target (see DeviceManager.ConfigureAll). Targets are configured concurrently, so a slow target does not delay
+// the others. Every target is attempted even if others fail, their errors are joined and prefixed with the name of the
+// target.
+func (m *MirrorManager) ConfigureAll(ctx context.Context) error {
+	return m.each(func(t *mirroredTarget) error {
+		return t.devices.ConfigureAll(ctx)
+	})
+}
+
+// SubscribeAll subscribes every registered Component once, through the Mirror (see DeviceManager.SubscribeAll), so
+// commands received from any target are handled and echoed to all of them.
+func (m *MirrorManager) SubscribeAll(ctx context.Context) error {
+	return m.all.SubscribeAll(ctx, m.mirror)
+}
+
+// Start publishes online for every registered Device on every target, and keeps publishing it when an adapter
+// reconnects (see AvailabilityManager.Start). For every target with a Subscriber, it also subscribes to the status
+// topic of the Home Assistant instance using the DiscoveryPrefix of the target, and republishes the discovery payloads
+// and state of every Device to that target only when the instance comes back online (see
+// DeviceManager.RediscoverOnBirth). Once the provided context is done, nothing is republished. Start must only be
+// called once. Every target is attempted even if others fail, their errors are joined and prefixed with the name of the
+// target.
+func (m *MirrorManager) Start(ctx context.Context) error {
+	return m.each(func(t *mirroredTarget) error {
+		var errs []error
+		for _, a := range m.availabilityOf(t) {
+			errs = append(errs, a.Start(ctx))
+		}
+
+		if t.Subscriber == nil {
+			return errors.Join(errs...)
+		}
+
+		status := discovery.HomeAssistantAvailability(t.DiscoveryPrefix)
+		t.devices.RediscoverOnBirth(ctx, status, t.devices.RepublishAll)
+		context.AfterFunc(ctx, status.Close)
+
+		if err := t.Subscriber.Subscribe(ctx, status, mqtt.Subscription{Topic: status.FullyQualifiedTopic("")}); err != nil {
+			status.Close()
+			errs = append(errs, fmt.Errorf("subscribe to home assistant status: %w", err))
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+// Stop publishes offline for every registered Device on every target (see AvailabilityManager.Stop). Call it during
+// graceful shutdown, before disconnecting from the brokers.
+func (m *MirrorManager) Stop(ctx context.Context) error {
+	return m.each(func(t *mirroredTarget) error {
+		var errs []error
+		for _, a := range m.availabilityOf(t) {
+			errs = append(errs, a.Stop(ctx))
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+// Close closes every component of every registered device when the bridge shuts down, see DeviceManager.Close.
+func (m *MirrorManager) Close(ctx context.Context) error {
+	return m.all.Close(ctx)
+}
+
+func (m *MirrorManager) availabilityOf(t *mirroredTarget) []*AvailabilityManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return t.availability
+}
+
+// each calls f for every target concurrently, joining the errors it returns prefixed with the name of the target.
+func (m *MirrorManager) each(f func(t *mirroredTarget) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(m.targets))
+	)
+
+	for i, t := range m.targets {
+		wg.Go(func() {
+			if err := f(t); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", t.Name, err)
+			}
+		})
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,144 @@
+package hqtt
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newMirroredLight() (*Device, map[string]json.MarshalerTo) {
+	d := &Device{
+		Identifiers:  []string{"foo"},
+		TopicPrefix:  "foo",
+		Availability: mqtt.NewValue("available", hass.AvailabilityMarshaler),
+	}
+
+	return d, map[string]json.MarshalerTo{
+		"light": &Component[*platform.Light]{
+			UniqueID:    "light",
+			TopicPrefix: "foo/light",
+			Platform: &platform.Light{
+				Optimistic: true,
+				State:      mqtt.NewValue("state", hass.PowerStateMarshaler),
+				Command:    mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+			},
+		},
+	}
+}
+
+func TestMirrorManager(t *testing.T) {
+	prod, staging := &hqtttest.Writer{}, &hqtttest.Writer{}
+	prodSub, stagingSub := &hqtttest.Subscriber{}, &hqtttest.Subscriber{}
+
+	sut := NewMirrorManager(mqtt.NewMirror(
+		mqtt.MirrorTarget{Name: "prod", Writer: prod, Subscriber: prodSub},
+		mqtt.MirrorTarget{Name: "staging", DiscoveryPrefix: "staging", Writer: staging, Subscriber: stagingSub},
+	))
+
+	for _, name := range []string{"prod", "staging"} {
+		m, ok := sut.Target(name)
+		require.True(t, ok)
+		m.BirthSettleWindow = -1
+	}
+
+	_, ok := sut.Target("missing")
+	assert.False(t, ok)
+
+	d, components := newMirroredLight()
+	require.NoError(t, sut.Register(d, components))
+	require.ErrorIs(t, sut.Register(d, components), ErrDuplicateDeviceID)
+
+	require.NoError(t, sut.ConfigureAll(t.Context()))
+	prod.AssertNotPublished(t, d.DiscoveryTopic("staging"))
+	staging.AssertNotPublished(t, d.DiscoveryTopic(""))
+	_, configured := prod.LastWrite(d.DiscoveryTopic(""))
+	assert.True(t, configured, "prod should be configured under the default prefix")
+	_, configured = staging.LastWrite(d.DiscoveryTopic("staging"))
+	assert.True(t, configured, "staging should be configured under its own prefix")
+
+	require.NoError(t, sut.SubscribeAll(t.Context()))
+	require.NoError(t, sut.Start(t.Context()))
+	t.Cleanup(func() {
+		_ = sut.Stop(t.Context())
+	})
+
+	prodSub.AssertSubscribed(t, "foo/light/command")
+	prodSub.AssertSubscribed(t, "homeassistant/status")
+	stagingSub.AssertSubscribed(t, "foo/light/command")
+	stagingSub.AssertSubscribed(t, "staging/status")
+	assert.NotContains(t, stagingSub.Subscriptions(), "homeassistant/status")
+
+	prod.AssertPublished(t, "foo/available", []byte("online"))
+	staging.AssertPublished(t, "foo/available", []byte("online"))
+
+	t.Run("Commands", func(t *testing.T) {
+		prod.Reset()
+		staging.Reset()
+
+		// Commands from any target are handled once and their state reaches every target
+		assert.Equal(t, 1, stagingSub.Inject(staging, "foo/light/command", []byte("ON")))
+		prod.AssertPublished(t, "foo/light/state", []byte("ON"))
+		staging.AssertPublished(t, "foo/light/state", []byte("ON"))
+	})
+
+	t.Run("Birth", func(t *testing.T) {
+		prod.Reset()
+		staging.Reset()
+
+		// Only the instance that restarted is rediscovered
+		assert.Equal(t, 1, stagingSub.Inject(staging, "staging/status", []byte("online")))
+		_, configured := staging.LastWrite(d.DiscoveryTopic("staging"))
+		assert.True(t, configured)
+		staging.AssertPublished(t, "foo/available", []byte("online"))
+		staging.AssertPublished(t, "foo/light/state", []byte("ON"))
+		assert.Empty(t, prod.Messages())
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		require.NoError(t, sut.Stop(t.Context()))
+		prod.AssertPublished(t, "foo/available", []byte("offline"))
+		staging.AssertPublished(t, "foo/available", []byte("offline"))
+	})
+}
+
+func TestMirrorManager_Outage(t *testing.T) {
+	boom := errors.New("boom")
+	prod := &hqtttest.Writer{}
+	staging := &failingWriter{fail: map[string]error{
+		"staging/device/foo/config": boom,
+		"foo/available":             boom,
+	}}
+
+	sut := NewMirrorManager(mqtt.NewMirror(
+		mqtt.MirrorTarget{Name: "prod", Writer: prod},
+		mqtt.MirrorTarget{Name: "staging", DiscoveryPrefix: "staging", Writer: staging},
+	))
+
+	d, components := newMirroredLight()
+	require.NoError(t, sut.Register(d, components))
+
+	// A target that cannot be published to does not change what the others see
+	err := sut.ConfigureAll(t.Context())
+	require.ErrorIs(t, err, boom)
+	assert.ErrorContains(t, err, "staging: ")
+	assert.NotContains(t, err.Error(), "prod: ")
+	_, configured := prod.LastWrite(d.DiscoveryTopic(""))
+	assert.True(t, configured)
+
+	err = sut.Start(t.Context())
+	t.Cleanup(func() {
+		_ = sut.Stop(t.Context())
+	})
+
+	require.ErrorIs(t, err, boom)
+	assert.ErrorContains(t, err, "staging: ")
+	prod.AssertPublished(t, "foo/available", []byte("online"))
+}
@@ -4,43 +4,128 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	// TODO: Can we pull this out easily and make this an optional dependency without making the module too complicated?
 	"github.com/eclipse/paho.golang/autopaho"
 	"github.com/eclipse/paho.golang/paho"
 
+	"github.com/nlowe/hqtt/hooks"
 	hqttlog "github.com/nlowe/hqtt/log"
 	"github.com/nlowe/hqtt/mqtt"
 )
 
+// Options configures how the adapter constructed by DialMQTTWithOptions handles messages.
+type Options struct {
+	// CopyPayloads copies the payload of received messages into a reused buffer before handing it to mqtt.Handlers. By
+	// default, handlers receive the payload paho allocated for the packet, which is shared with other OnPublishReceived
+	// callbacks registered on the connection. Set CopyPayloads if such callbacks keep the payload while a handler may
+	// modify it in place.
+	CopyPayloads bool
+
+	// Will is registered with the broker when connecting, which publishes it if the connection drops without
+	// disconnecting cleanly. Use hqtt.Device.AvailabilityWill or hqtt.Component.AvailabilityWill so Home Assistant
+	// marks entities unavailable when the client goes offline. It overrides config.WillMessage if set.
+	Will *mqtt.Will
+}
+
 type adapter struct {
 	mu sync.Mutex
 
 	conn *autopaho.ConnectionManager
-	r    paho.Router
+	opts Options
 
 	subscriptions map[string]paho.SubscribeOptions
 
+	// routesMu guards the routing tables separately from mu, which is held while waiting for the broker to acknowledge
+	// a subscription and must not block delivering messages.
+	routesMu sync.RWMutex
+	exact    map[string][]mqtt.Handler
+	wildcard map[string][]mqtt.Handler
+	aliases  map[uint16]string
+
 	log *slog.Logger
 }
 
 var _ mqtt.Writer = &adapter{}
-var _ mqtt.Subscriber = &adapter{}
+var _ mqtt.BatchSubscriber = &adapter{}
+var _ mqtt.HandlerUnsubscriber = &adapter{}
+
+// publishes holds paho.Publish packets for reuse by WriteTopic. Publishing converts the packet before it is sent or
+// stored in the session, so it is not referenced after Publish returns.
+var publishes = sync.Pool{New: func() any { return new(paho.Publish) }}
+
+// payloads holds buffers for copies of received payloads, see Options.CopyPayloads.
+var payloads = sync.Pool{New: func() any { return new([]byte) }}
+
+// maxPooledPayload is the capacity above which payload buffers are not returned to the pool, so one unusually large
+// message does not pin its buffer in memory forever.
+const maxPooledPayload = 1 << 20
+
+// releasePayload returns a buffer obtained from payloads to the pool unless it grew too large.
+func releasePayload(bp *[]byte) {
+	if cap(*bp) > maxPooledPayload {
+		return
+	}
+
+	payloads.Put(bp)
+}
 
+// retainedWriter is the mqtt.Writer passed to handlers for messages delivered with the retain flag set, see Retained.
+type retainedWriter struct {
+	*adapter
+}
+
+// Retained reports whether the message a mqtt.Handler is serving was delivered with the retain flag set. Brokers set it
+// on retained messages sent in response to a new subscription, but not on messages forwarded as they are published. w
+// must be the mqtt.Writer the adapter passed to the handler; writers wrapped by middleware always report false.
+func Retained(w mqtt.Writer) bool {
+	_, ok := w.(retainedWriter)
+	return ok
+}
+
+// DialMQTT connects to a broker using the provided config and returns the connection as a mqtt.Writer and
+// mqtt.Subscriber, along with a function to disconnect. It uses default Options.
 func DialMQTT(ctx context.Context, config autopaho.ClientConfig) (mqtt.Writer, mqtt.Subscriber, func(ctx context.Context) error, error) {
+	return DialMQTTWithOptions(ctx, config, Options{})
+}
+
+// DialMQTTWithOptions is like DialMQTT, but uses the provided Options.
+//
+// If config.PublishHook is set, it must not retain the paho.Publish it is called with, since they are reused.
+func DialMQTTWithOptions(ctx context.Context, config autopaho.ClientConfig, opts Options) (mqtt.Writer, mqtt.Subscriber, func(ctx context.Context) error, error) {
 	a := &adapter{
-		r: paho.NewStandardRouter(),
+		opts: opts,
 
 		subscriptions: map[string]paho.SubscribeOptions{},
 
+		exact:    map[string][]mqtt.Handler{},
+		wildcard: map[string][]mqtt.Handler{},
+		aliases:  map[uint16]string{},
+
 		log: hqttlog.ForComponent("autopaho"),
 	}
 
+	bridgeLoggers(&config)
+
+	if opts.Will != nil {
+		config.WillMessage = &paho.WillMessage{
+			Retain:  opts.Will.Options.Retain,
+			QoS:     uint8(opts.Will.Options.QoS),
+			Topic:   opts.Will.Topic,
+			Payload: opts.Will.Payload,
+		}
+	}
+
 	// Overwrite the OnConnectionUp handler to deal with re-subscribing.
+	var connections atomic.Uint64
 	originalOnConnUp := config.OnConnectionUp
 	config.OnConnectionUp = func(manager *autopaho.ConnectionManager, connack *paho.Connack) {
 		a.onReconnect(ctx)
+		hooks.ConnectionUp(ctx, hooks.ConnectionEvent{Client: a, Reconnect: connections.Add(1) > 1})
 
 		if originalOnConnUp != nil {
 			originalOnConnUp(manager, connack)
@@ -50,7 +135,7 @@ func DialMQTT(ctx context.Context, config autopaho.ClientConfig) (mqtt.Writer, m
 	// Lock the adapter before starting the connection so the first OnConnectionUp callback (which calls a.onReconnect)
 	// blocks until after a.conn is assigned.
 	a.mu.Lock()
-	a.log.Info("Connecting to mqtt broker")
+	a.log.InfoContext(ctx, "Connecting to mqtt broker")
 	conn, err := autopaho.NewConnection(ctx, config)
 	if err != nil {
 		a.mu.Unlock()
@@ -60,20 +145,80 @@ func DialMQTT(ctx context.Context, config autopaho.ClientConfig) (mqtt.Writer, m
 	a.conn = conn
 	a.mu.Unlock()
 
-	a.log.Debug("Waiting for connection to be ready")
+	a.log.DebugContext(ctx, "Waiting for connection to be ready")
 	if err = conn.AwaitConnection(ctx); err != nil {
 		return nil, nil, nil, fmt.Errorf("mqtt: wait for connection: %w", err)
 	}
 
-	a.log.Debug("Connected to mqtt broker")
+	a.log.DebugContext(ctx, "Connected to mqtt broker")
 	conn.AddOnPublishReceived(func(rx autopaho.PublishReceived) (bool, error) {
-		a.r.Route(rx.Packet.Packet())
+		a.route(rx.Packet)
 		return true, nil
 	})
 
 	return a, a, conn.Disconnect, nil
 }
 
+// route delivers the provided message to every handler subscribed to a matching topic filter. Unlike paho.Router, it
+// works with the received paho.Publish directly instead of converting it to and from a packets.Publish.
+func (a *adapter) route(p *paho.Publish) {
+	topic := p.Topic
+	if p.Properties != nil && p.Properties.TopicAlias != nil {
+		topic = a.resolveAlias(*p.Properties.TopicAlias, topic)
+	}
+
+	payload := p.Payload
+	if a.opts.CopyPayloads {
+		bp := payloads.Get().(*[]byte)
+		defer releasePayload(bp)
+
+		*bp = append((*bp)[:0], p.Payload...)
+		payload = *bp
+	}
+
+	var w mqtt.Writer = a
+	if p.Retain {
+		w = retainedWriter{a}
+	}
+
+	a.routesMu.RLock()
+	defer a.routesMu.RUnlock()
+
+	for _, h := range a.exact[topic] {
+		h.ServeMQTT(w, topic, payload)
+	}
+
+	for filter, handlers := range a.wildcard {
+		if mqtt.MatchTopic(filter, topic) {
+			for _, h := range handlers {
+				h.ServeMQTT(w, topic, payload)
+			}
+		}
+	}
+}
+
+// resolveAlias records the topic for the provided alias if the broker sent one, returning the topic for the alias.
+func (a *adapter) resolveAlias(alias uint16, topic string) string {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+
+	if topic != "" {
+		a.aliases[alias] = topic
+		return topic
+	}
+
+	return a.aliases[alias]
+}
+
+// routes returns the routing table for the provided topic filter. a.routesMu must be held.
+func (a *adapter) routes(filter string) map[string][]mqtt.Handler {
+	if strings.ContainsAny(filter, mqtt.SingleLevelWildcard+mqtt.MultiLevelWildcard) {
+		return a.wildcard
+	}
+
+	return a.exact
+}
+
 func (a *adapter) onReconnect(ctx context.Context) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -90,58 +235,168 @@ func (a *adapter) onReconnect(ctx context.Context) {
 		sub.Subscriptions = append(sub.Subscriptions, s)
 	}
 
-	a.log.Debug("Reconnected to MQTT. Re-sending subscriptions.")
+	a.log.DebugContext(ctx, "Reconnected to MQTT. Re-sending subscriptions.")
 	_, err := a.conn.Subscribe(ctx, sub)
 	if err != nil {
 		// TODO: Retry? Somehow lift this failure to the consumer?
-		a.log.With(hqttlog.Error(err)).Error("Failed to re-subscribe to mqtt topics")
+		a.log.With(hqttlog.Error(err)).ErrorContext(ctx, "Failed to re-subscribe to mqtt topics")
 	}
 }
 
 func (a *adapter) WriteTopic(ctx context.Context, topic string, options mqtt.WriteOptions, value []byte) error {
-	a.log.With(slog.String("topic", topic), slog.Any("options", options), slog.String("payload", string(value))).Debug("Publishing payload")
+	if a.log.Enabled(ctx, slog.LevelDebug) {
+		a.log.With(slog.String("topic", topic), slog.Any("options", options), hqttlog.Payload(topic, value)).DebugContext(ctx, "Publishing payload")
+	}
 
-	_, err := a.conn.Publish(ctx, &paho.Publish{
-		QoS:     uint8(options.QoS),
-		Retain:  options.Retain,
-		Topic:   topic,
-		Payload: value,
-	})
+	p := publishes.Get().(*paho.Publish)
+	defer func() {
+		*p = paho.Publish{}
+		publishes.Put(p)
+	}()
 
+	p.QoS = uint8(options.QoS)
+	p.Retain = options.Retain
+	p.Topic = topic
+	p.Payload = value
+
+	_, err := a.conn.Publish(ctx, p)
 	return err
 }
 
 func (a *adapter) Subscribe(ctx context.Context, handler mqtt.Handler, subscriptions ...mqtt.Subscription) error {
+	return a.SubscribeBatch(ctx, mqtt.SubscribeRequest{Handler: handler, Subscriptions: subscriptions})
+}
+
+// SubscribeBatch implements mqtt.BatchSubscriber by sending the subscriptions for every request in a single SUBSCRIBE
+// packet. If several requests subscribe to the same topic filter, the options of the last one are used. If the broker
+// rejects the packet, the subscriptions and handlers added by this call are removed again.
+func (a *adapter) SubscribeBatch(ctx context.Context, requests ...mqtt.SubscribeRequest) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if len(subscriptions) == 0 {
-		return nil
+	sub := &paho.Subscribe{}
+	index := map[string]int{}
+
+	// previous holds the options each topic was subscribed with before this call so they can be restored on failure.
+	previous := map[string]*paho.SubscribeOptions{}
+
+	var subscriptions []mqtt.Subscription
+	for _, r := range requests {
+		for _, s := range r.Subscriptions {
+			opts := paho.SubscribeOptions{
+				Topic:             s.Topic,
+				QoS:               uint8(s.Options.QoS),
+				RetainHandling:    uint8(s.Options.RetainHandling),
+				NoLocal:           s.Options.NoLocal,
+				RetainAsPublished: s.Options.RetainAsPublished,
+			}
+
+			if i, ok := index[s.Topic]; ok {
+				sub.Subscriptions[i] = opts
+			} else {
+				if old, ok := a.subscriptions[s.Topic]; ok {
+					previous[s.Topic] = &old
+				} else {
+					previous[s.Topic] = nil
+				}
+
+				index[s.Topic] = len(sub.Subscriptions)
+				sub.Subscriptions = append(sub.Subscriptions, opts)
+			}
+
+			a.subscriptions[s.Topic] = opts
+			subscriptions = append(subscriptions, s)
+		}
 	}
 
-	sub := &paho.Subscribe{
-		Subscriptions: make([]paho.SubscribeOptions, len(subscriptions)),
+	if len(sub.Subscriptions) == 0 {
+		return nil
 	}
 
-	for i, s := range subscriptions {
-		opts := paho.SubscribeOptions{
-			Topic:             s.Topic,
-			QoS:               uint8(s.Options.QoS),
-			RetainHandling:    uint8(s.Options.RetainHandling),
-			NoLocal:           s.Options.NoLocal,
-			RetainAsPublished: s.Options.RetainAsPublished,
+	// Handlers are routed before subscribing so retained messages sent as soon as the broker accepts the subscription
+	// are not missed. Routes are only modified while a.mu is held, so on failure they can be truncated back to the
+	// number of handlers they had before.
+	routed := map[string]int{}
+
+	a.routesMu.Lock()
+	for _, r := range requests {
+		for _, s := range r.Subscriptions {
+			routes := a.routes(s.Topic)
+			if _, ok := routed[s.Topic]; !ok {
+				routed[s.Topic] = len(routes[s.Topic])
+			}
+
+			routes[s.Topic] = append(routes[s.Topic], r.Handler)
+		}
+	}
+	a.routesMu.Unlock()
+
+	a.log.With(slog.Any("subscriptions", subscriptions)).DebugContext(ctx, "Subscribing to MQTT Topic(s)")
+	if _, err := a.conn.Subscribe(ctx, sub); err != nil {
+		a.routesMu.Lock()
+		for topic, n := range routed {
+			routes := a.routes(topic)
+			if n == 0 {
+				delete(routes, topic)
+			} else {
+				routes[topic] = slices.Clip(routes[topic][:n])
+			}
+		}
+		a.routesMu.Unlock()
+
+		for topic, opts := range previous {
+			if opts == nil {
+				delete(a.subscriptions, topic)
+			} else {
+				a.subscriptions[topic] = *opts
+			}
 		}
 
-		a.subscriptions[s.Topic] = opts
-		sub.Subscriptions[i] = opts
+		return err
+	}
+
+	return nil
+}
 
-		a.r.RegisterHandler(s.Topic, func(publish *paho.Publish) {
-			handler.ServeMQTT(a, publish.Topic, publish.Payload)
+// UnsubscribeHandler implements mqtt.HandlerUnsubscriber. Only the provided Handler is released; topics are
+// unsubscribed from the broker once no Handlers remain for them.
+func (a *adapter) UnsubscribeHandler(ctx context.Context, handler mqtt.Handler, topics ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var unused []string
+	a.routesMu.Lock()
+	for _, t := range topics {
+		routes := a.routes(t)
+		handlers, ok := routes[t]
+		if !ok {
+			continue
+		}
+
+		handlers = slices.DeleteFunc(handlers, func(h mqtt.Handler) bool {
+			return mqtt.SameHandler(h, handler)
 		})
+
+		if len(handlers) != 0 {
+			routes[t] = handlers
+			continue
+		}
+
+		delete(routes, t)
+		delete(a.subscriptions, t)
+		unused = append(unused, t)
 	}
+	a.routesMu.Unlock()
+
+	if len(unused) == 0 {
+		return nil
+	}
+
+	a.log.With(slog.Any("topics", unused)).DebugContext(ctx, "Unsubscribing from MQTT Topic(s)")
+	_, err := a.conn.Unsubscribe(ctx, &paho.Unsubscribe{
+		Topics: unused,
+	})
 
-	a.log.With(slog.Any("subscriptions", subscriptions)).Debug("Subscribing to MQTT Topic(s)")
-	_, err := a.conn.Subscribe(ctx, sub)
 	return err
 }
 
@@ -149,11 +404,20 @@ func (a *adapter) Unsubscribe(ctx context.Context, topics ...string) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if len(topics) == 0 {
+		return nil
+	}
+
+	// Release handlers first so messages already in flight for these topics are no longer delivered and the handlers
+	// (and anything they reference) can be garbage collected.
+	a.routesMu.Lock()
 	for _, t := range topics {
 		delete(a.subscriptions, t)
+		delete(a.routes(t), t)
 	}
+	a.routesMu.Unlock()
 
-	a.log.With(slog.Any("topics", topics)).Debug("Unsubscribing from MQTT Topic(s)")
+	a.log.With(slog.Any("topics", topics)).DebugContext(ctx, "Unsubscribing from MQTT Topic(s)")
 	_, err := a.conn.Unsubscribe(ctx, &paho.Unsubscribe{
 		Topics: topics,
 	})
@@ -0,0 +1,320 @@
+package autopaho_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hooks"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	adapter "github.com/nlowe/hqtt/mqtt/adapter/autopaho"
+)
+
+func TestAdapter(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, s, disconnect := b.Connect(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan string, 1)
+	require.NoError(t, s.Subscribe(ctx, mqtt.HandlerFunc(func(_ mqtt.Writer, topic string, payload []byte) {
+		received <- topic + "=" + string(payload)
+	}), mqtt.Subscription{Topic: "foo/#"}))
+
+	require.NoError(t, w.WriteTopic(ctx, "foo/bar", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("baz")))
+	assert.Equal(t, "foo/bar=baz", <-received)
+
+	require.NoError(t, s.Unsubscribe(ctx, "foo/#"))
+	require.NoError(t, w.WriteTopic(ctx, "foo/bar", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("fizz")))
+
+	select {
+	case v := <-received:
+		assert.Failf(t, "received message after unsubscribing", "%s", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, disconnect(ctx))
+}
+
+func TestAdapter_Routing(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, s, _ := b.Connect(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan string, 3)
+	handler := func(name string) mqtt.Handler {
+		return mqtt.HandlerFunc(func(_ mqtt.Writer, topic string, payload []byte) {
+			received <- name + ":" + topic + "=" + string(payload)
+		})
+	}
+
+	// Every handler subscribed to a matching filter receives the message
+	require.NoError(t, s.Subscribe(ctx, handler("exact"), mqtt.Subscription{Topic: "foo/bar"}))
+	require.NoError(t, s.Subscribe(ctx, handler("wildcard"), mqtt.Subscription{Topic: "foo/+"}))
+	require.NoError(t, s.Subscribe(ctx, handler("other"), mqtt.Subscription{Topic: "other/#"}))
+
+	require.NoError(t, w.WriteTopic(ctx, "foo/bar", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("baz")))
+	assert.ElementsMatch(t, []string{"exact:foo/bar=baz", "wildcard:foo/bar=baz"}, []string{<-received, <-received})
+
+	select {
+	case v := <-received:
+		assert.Failf(t, "received message for a filter that does not match", "%s", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAdapter_CopyPayloads(t *testing.T) {
+	for _, copyPayloads := range []bool{false, true} {
+		t.Run(fmt.Sprintf("CopyPayloads=%t", copyPayloads), func(t *testing.T) {
+			b := hqtttest.NewBroker(t)
+			w, s := dial(t, b.URL(), adapter.Options{CopyPayloads: copyPayloads})
+
+			ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+			defer cancel()
+
+			received := make(chan string, 1)
+			require.NoError(t, s.Subscribe(ctx, mqtt.HandlerFunc(func(_ mqtt.Writer, _ string, payload []byte) {
+				received <- string(payload)
+			}), mqtt.Subscription{Topic: "foo"}))
+
+			for _, payload := range []string{"first", "2nd"} {
+				require.NoError(t, w.WriteTopic(ctx, "foo", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte(payload)))
+				assert.Equal(t, payload, <-received)
+			}
+		})
+	}
+}
+
+func BenchmarkAdapter_WriteTopic(b *testing.B) {
+	broker := hqtttest.NewBroker(b)
+	w, _, _ := broker.Connect(b)
+
+	payload := []byte("ON")
+	for b.Loop() {
+		if err := w.WriteTopic(b.Context(), "hqtt/light/state", mqtt.WriteOptions{}, payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// dial connects to the broker at the provided URL with the provided Options.
+func dial(t *testing.T, u *url.URL, opts adapter.Options) (mqtt.Writer, mqtt.Subscriber) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	w, s, disconnect, err := adapter.DialMQTTWithOptions(ctx, autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{u},
+		KeepAlive:                     20,
+		CleanStartOnInitialConnection: true,
+		ConnectTimeout:                5 * time.Second,
+		ClientConfig:                  paho.ClientConfig{ClientID: t.Name()},
+	}, opts)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = disconnect(ctx)
+	})
+
+	return w, s
+}
+
+func TestAdapter_Retained(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, s := dial(t, b.URL(), adapter.Options{})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, w.WriteTopic(ctx, "foo", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, []byte("retained")))
+
+	received := make(chan bool, 1)
+	require.NoError(t, s.Subscribe(ctx, mqtt.HandlerFunc(func(w mqtt.Writer, _ string, _ []byte) {
+		received <- adapter.Retained(w)
+	}), mqtt.Subscription{Topic: "foo"}))
+
+	assert.True(t, <-received, "messages sent on subscribe should be retained")
+
+	require.NoError(t, w.WriteTopic(ctx, "foo", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("live")))
+	assert.False(t, <-received, "forwarded messages should not be retained")
+}
+
+func TestAdapter_SubscribeBatch(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, s, _ := b.Connect(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	bs, ok := s.(mqtt.BatchSubscriber)
+	require.True(t, ok, "the adapter should support batching subscriptions")
+
+	received := make(chan string, 2)
+	handler := func(name string) mqtt.Handler {
+		return mqtt.HandlerFunc(func(_ mqtt.Writer, topic string, payload []byte) {
+			received <- name + ":" + topic + "=" + string(payload)
+		})
+	}
+
+	require.NoError(t, mqtt.SubscribeBatch(ctx, bs,
+		mqtt.SubscribeRequest{Handler: handler("a"), Subscriptions: []mqtt.Subscription{{Topic: "a/command"}}},
+		mqtt.SubscribeRequest{Handler: handler("b"), Subscriptions: []mqtt.Subscription{{Topic: "b/command"}}},
+	))
+
+	require.NoError(t, w.WriteTopic(ctx, "a/command", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("ON")))
+	assert.Equal(t, "a:a/command=ON", <-received)
+
+	require.NoError(t, w.WriteTopic(ctx, "b/command", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("OFF")))
+	assert.Equal(t, "b:b/command=OFF", <-received)
+}
+
+func TestAdapter_SubscribeBatchFailure(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, s, _ := b.Connect(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan string, 2)
+	failed := &recordingHandler{name: "failed", received: received}
+	ok := &recordingHandler{name: "ok", received: received}
+
+	canceled, cancelSubscribe := context.WithCancel(ctx)
+	cancelSubscribe()
+
+	// A failed subscription does not leave its handler routed
+	require.Error(t, mqtt.SubscribeBatch(canceled, s, mqtt.SubscribeRequest{
+		Handler:       failed,
+		Subscriptions: []mqtt.Subscription{{Topic: "batch/command"}},
+	}))
+
+	require.NoError(t, s.Subscribe(ctx, ok, mqtt.Subscription{Topic: "batch/command"}))
+	require.NoError(t, w.WriteTopic(ctx, "batch/command", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("ON")))
+	assert.Equal(t, "ok:batch/command=ON", <-received)
+
+	select {
+	case v := <-received:
+		assert.Failf(t, "received message for a handler whose subscription failed", "%s", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// recordingHandler is a comparable mqtt.Handler, so it can be released with mqtt.UnsubscribeHandler.
+type recordingHandler struct {
+	name     string
+	received chan<- string
+}
+
+func (h *recordingHandler) ServeMQTT(_ mqtt.Writer, topic string, payload []byte) {
+	h.received <- h.name + ":" + topic + "=" + string(payload)
+}
+
+func TestAdapter_UnsubscribeHandler(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+	w, s, _ := b.Connect(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan string, 2)
+	a := &recordingHandler{name: "a", received: received}
+	bh := &recordingHandler{name: "b", received: received}
+
+	require.NoError(t, s.Subscribe(ctx, a, mqtt.Subscription{Topic: "shared/command"}))
+	require.NoError(t, s.Subscribe(ctx, bh, mqtt.Subscription{Topic: "shared/command"}))
+
+	// Releasing one handler keeps the other subscribed
+	require.NoError(t, mqtt.UnsubscribeHandler(ctx, s, a, "shared/command"))
+	require.NoError(t, w.WriteTopic(ctx, "shared/command", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("ON")))
+	assert.Equal(t, "b:shared/command=ON", <-received)
+
+	// Releasing the last handler unsubscribes from the broker
+	require.NoError(t, mqtt.UnsubscribeHandler(ctx, s, bh, "shared/command"))
+	require.NoError(t, w.WriteTopic(ctx, "shared/command", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce}, []byte("OFF")))
+
+	select {
+	case v := <-received:
+		assert.Failf(t, "received message after releasing every handler", "%s", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAdapter_ConnectionHooks(t *testing.T) {
+	events := make(chan hooks.ConnectionEvent, 2)
+	defer hooks.Register(hooks.Hooks{
+		OnConnectionUp: func(_ context.Context, e hooks.ConnectionEvent) {
+			events <- e
+		},
+	})()
+
+	b := hqtttest.NewBroker(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	w, _, _, err := adapter.DialMQTT(ctx, autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{b.URL()},
+		KeepAlive:                     20,
+		CleanStartOnInitialConnection: true,
+		ConnectTimeout:                5 * time.Second,
+		ReconnectBackoff:              autopaho.NewConstantBackoff(10 * time.Millisecond),
+		ClientConfig:                  paho.ClientConfig{ClientID: t.Name()},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, hooks.ConnectionEvent{Client: w}, <-events)
+
+	b.Drop()
+
+	select {
+	case e := <-events:
+		assert.Equal(t, hooks.ConnectionEvent{Client: w, Reconnect: true}, e)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the adapter did not reconnect")
+	}
+}
+
+func TestAdapter_Will(t *testing.T) {
+	b := hqtttest.NewBroker(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	_, _, _, err := adapter.DialMQTTWithOptions(ctx, autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{b.URL()},
+		KeepAlive:                     20,
+		CleanStartOnInitialConnection: true,
+		ConnectTimeout:                5 * time.Second,
+		ReconnectBackoff:              autopaho.NewConstantBackoff(time.Minute),
+		ClientConfig:                  paho.ClientConfig{ClientID: t.Name()},
+	}, adapter.Options{Will: &mqtt.Will{
+		Topic:   "foo/available",
+		Payload: []byte("offline"),
+		Options: mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true},
+	}})
+	require.NoError(t, err)
+
+	_, ok := b.Retained("foo/available")
+	require.False(t, ok, "the will should not be published while connected")
+
+	b.Drop()
+
+	require.Eventually(t, func() bool {
+		payload, ok := b.Retained("foo/available")
+		return ok && string(payload) == "offline"
+	}, 5*time.Second, 10*time.Millisecond, "the broker should publish the will when the connection drops")
+}
@@ -1,3 +1,7 @@
 // Package autopaho adapts github.com/eclipse/paho.golang/autopaho for use with github.com/nlowe/hqtt. It wraps a
 // connection manager and exposes it as a mqtt.Writer and mqtt.Subscriber.
+//
+// Unless the Debug, Errors, PahoDebug, or PahoErrors loggers are set on the autopaho.ClientConfig passed to DialMQTT,
+// they are configured to write to the hqtt log sink (see log.To) so low-level client diagnostics end up in the same
+// structured stream as the rest of hqtt.
 package autopaho
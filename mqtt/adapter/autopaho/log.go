@@ -0,0 +1,56 @@
+package autopaho
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	paholog "github.com/eclipse/paho.golang/paho/log"
+
+	hqttlog "github.com/nlowe/hqtt/log"
+)
+
+// slogLogger adapts a slog.Logger to the logger interface used internally by paho and autopaho, logging every line at
+// the configured level.
+type slogLogger struct {
+	log   *slog.Logger
+	level slog.Level
+}
+
+var _ paholog.Logger = slogLogger{}
+
+func (l slogLogger) Println(v ...any) {
+	if l.log.Enabled(context.Background(), l.level) {
+		l.log.Log(context.Background(), l.level, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	}
+}
+
+func (l slogLogger) Printf(format string, v ...any) {
+	if l.log.Enabled(context.Background(), l.level) {
+		l.log.Log(context.Background(), l.level, strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+	}
+}
+
+// bridgeLoggers configures any paho or autopaho loggers that are not already set on the provided config to write to the
+// hqtt log sink, so low-level client diagnostics end up in the same structured stream as the rest of hqtt.
+func bridgeLoggers(config *autopaho.ClientConfig) {
+	autopahoLog := hqttlog.ForComponent("autopaho.client")
+	pahoLog := hqttlog.ForComponent("paho")
+
+	for _, l := range []struct {
+		logger *paholog.Logger
+		log    *slog.Logger
+		level  slog.Level
+	}{
+		{logger: &config.Debug, log: autopahoLog, level: slog.LevelDebug},
+		{logger: &config.Errors, log: autopahoLog, level: slog.LevelError},
+		{logger: &config.PahoDebug, log: pahoLog, level: slog.LevelDebug},
+		{logger: &config.PahoErrors, log: pahoLog, level: slog.LevelError},
+	} {
+		if *l.logger == nil {
+			*l.logger = slogLogger{log: l.log, level: l.level}
+		}
+	}
+}
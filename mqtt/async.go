@@ -0,0 +1,212 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/nlowe/hqtt/log"
+)
+
+// ErrWriterClosed is the error returned by AsyncWriter.WriteTopic and AsyncWriter.Flush after AsyncWriter.Close is
+// called.
+var ErrWriterClosed = errors.New("writer closed")
+
+const (
+	// DefaultAsyncInFlight is the number of messages an AsyncWriter publishes concurrently when
+	// AsyncWriterOptions.InFlight is not positive.
+	DefaultAsyncInFlight = 16
+	// DefaultAsyncQueueSize is the number of messages an AsyncWriter queues per in-flight publish when
+	// AsyncWriterOptions.QueueSize is not positive.
+	DefaultAsyncQueueSize = 64
+)
+
+// AsyncWriterOptions configures an AsyncWriter. The zero value uses DefaultAsyncInFlight and DefaultAsyncQueueSize,
+// and logs failed publishes.
+type AsyncWriterOptions struct {
+	// InFlight is the maximum number of messages published concurrently. Messages for the same topic are always
+	// published one at a time, in the order they were written.
+	InFlight int
+
+	// QueueSize is the number of messages that may wait for each in-flight publish. When the queue for a topic is full,
+	// WriteTopic blocks until there is room.
+	QueueSize int
+
+	// OnComplete is called with every message once it has been published, along with the error returned by the
+	// wrapped Writer. The payload of the message is only valid until OnComplete returns. Calls for messages on different
+	// topics may be concurrent. If nil, failed publishes are logged instead.
+	OnComplete func(m Message, err error)
+}
+
+// AsyncWriter is a Writer that queues messages and returns immediately instead of waiting for them to be published,
+// which allows publishes with a QoS above QOSAtMostOnce to be pipelined instead of blocking each state update on a
+// round trip to the broker. Messages are published by the wrapped Writer in the background, and the outcome of each
+// message is reported to AsyncWriterOptions.OnComplete.
+//
+// Since WriteTopic only reports errors queueing the message, Value.Write and hooks.StateWritten do not see errors from
+// the broker when writing through an AsyncWriter. It is safe for concurrent use.
+type AsyncWriter struct {
+	w    Writer
+	opts AsyncWriterOptions
+
+	seed   maphash.Seed
+	queues []chan asyncWrite
+
+	mu      sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+
+	log      *slog.Logger
+	warnings log.Limiter
+}
+
+var _ Writer = &AsyncWriter{}
+
+// asyncWrite is a queued message, or a request to be notified once every message queued before it was published.
+type asyncWrite struct {
+	ctx     context.Context
+	m       Message
+	flushed chan<- struct{}
+}
+
+// NewAsyncWriter constructs an AsyncWriter that publishes messages with the provided Writer using the provided options.
+// Call Close to publish any queued messages and stop the AsyncWriter.
+func NewAsyncWriter(w Writer, opts AsyncWriterOptions) *AsyncWriter {
+	if opts.InFlight <= 0 {
+		opts.InFlight = DefaultAsyncInFlight
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultAsyncQueueSize
+	}
+
+	a := &AsyncWriter{
+		w:    w,
+		opts: opts,
+
+		seed:   maphash.MakeSeed(),
+		queues: make([]chan asyncWrite, opts.InFlight),
+
+		log: log.ForComponent("mqtt.async"),
+	}
+
+	for i := range a.queues {
+		a.queues[i] = make(chan asyncWrite, opts.QueueSize)
+		a.workers.Go(func() {
+			a.work(a.queues[i])
+		})
+	}
+
+	return a
+}
+
+// WriteTopic implements Writer by queueing a copy of the provided message to be published. It returns once the message
+// is queued, or the cause if ctx is done while waiting for room in the queue. The message is published with a context
+// that carries the values of ctx, but is not canceled with it.
+func (a *AsyncWriter) WriteTopic(ctx context.Context, topic string, options WriteOptions, value []byte) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return ErrWriterClosed
+	}
+
+	// Every message for a topic goes through the same queue so they are published in order
+	queue := a.queues[maphash.String(a.seed, topic)%uint64(len(a.queues))]
+	write := asyncWrite{
+		ctx: context.WithoutCancel(ctx),
+		m:   Message{Topic: topic, Options: options, Payload: slices.Clone(value)},
+	}
+
+	select {
+	case queue <- write:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// Flush waits until every message queued before it was called has been published. It returns the cause if ctx is done
+// first.
+func (a *AsyncWriter) Flush(ctx context.Context) error {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return ErrWriterClosed
+	}
+
+	flushed := make(chan struct{}, len(a.queues))
+	for _, queue := range a.queues {
+		select {
+		case queue <- asyncWrite{flushed: flushed}:
+		case <-ctx.Done():
+			a.mu.RUnlock()
+			return context.Cause(ctx)
+		}
+	}
+	a.mu.RUnlock()
+
+	for range a.queues {
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+
+	return nil
+}
+
+// Close stops accepting messages and waits until every queued message has been published. It returns the cause if ctx
+// is done first, in which case the remaining messages are still published in the background. Close is idempotent.
+func (a *AsyncWriter) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		for _, queue := range a.queues {
+			close(queue)
+		}
+	}
+	a.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.workers.Wait()
+
+		// Report publish failures that were rate limited instead of waiting for their interval to end
+		a.warnings.Flush()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+func (a *AsyncWriter) work(queue <-chan asyncWrite) {
+	for write := range queue {
+		if write.flushed != nil {
+			write.flushed <- struct{}{}
+			continue
+		}
+
+		err := a.w.WriteTopic(write.ctx, write.m.Topic, write.m.Options, write.m.Payload)
+		if a.opts.OnComplete != nil {
+			a.opts.OnComplete(write.m, err)
+			continue
+		}
+
+		if err != nil {
+			// A broker that is down would otherwise fail every queued message with a log record of its own
+			a.warnings.Log(write.ctx, a.log, slog.LevelWarn, write.m.Topic, "Failed to publish message",
+				slog.String("topic", write.m.Topic), log.Error(err),
+			)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package mqtt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// gatedWriter holds every write until release is closed, signalling started when the first write begins.
+type gatedWriter struct {
+	hqtttest.Writer
+
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (g *gatedWriter) WriteTopic(ctx context.Context, topic string, options mqtt.WriteOptions, value []byte) error {
+	g.once.Do(func() {
+		close(g.started)
+	})
+
+	<-g.release
+	return g.Writer.WriteTopic(ctx, topic, options, value)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	t.Run("Returns Immediately", func(t *testing.T) {
+		w := newGatedWriter()
+		sut := mqtt.NewAsyncWriter(w, mqtt.AsyncWriterOptions{})
+
+		v := mqtt.NewValueWithOptions("state", mqtt.StringMarshaler, mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce})
+		require.NoError(t, mqtt.Error(v.Write(t.Context(), sut, "prefix", "ON")))
+
+		<-w.started
+		assert.Empty(t, w.Messages(), "message should still be waiting on the broker")
+
+		close(w.release)
+		require.NoError(t, sut.Flush(t.Context()))
+		w.AssertPublished(t, "prefix/state", []byte("ON"))
+
+		require.NoError(t, sut.Close(t.Context()))
+	})
+
+	t.Run("Pipelined", func(t *testing.T) {
+		const inFlight = 2
+
+		w := &concurrentWriter{full: make(chan struct{}, inFlight)}
+		sut := mqtt.NewAsyncWriter(w, mqtt.AsyncWriterOptions{InFlight: inFlight})
+
+		// With enough topics, some are published by different workers
+		for i := range 64 {
+			require.NoError(t, sut.WriteTopic(t.Context(), fmt.Sprintf("state/%d", i), mqtt.WriteOptions{}, []byte("ON")))
+		}
+
+		require.NoError(t, sut.Close(t.Context()))
+		assert.Len(t, w.Messages(), 64)
+		assert.Equal(t, inFlight, w.max)
+	})
+
+	t.Run("Ordered", func(t *testing.T) {
+		w := &hqtttest.Writer{}
+		sut := mqtt.NewAsyncWriter(w, mqtt.AsyncWriterOptions{InFlight: 4, QueueSize: 1})
+
+		for i := range 100 {
+			for _, topic := range []string{"a", "b"} {
+				require.NoError(t, sut.WriteTopic(t.Context(), topic, mqtt.WriteOptions{}, []byte(strconv.Itoa(i))))
+			}
+		}
+
+		require.NoError(t, sut.Close(t.Context()))
+
+		next := map[string]int{}
+		for _, m := range w.Messages() {
+			assert.Equal(t, strconv.Itoa(next[m.Topic]), string(m.Payload), "messages for %s out of order", m.Topic)
+			next[m.Topic]++
+		}
+
+		assert.Equal(t, map[string]int{"a": 100, "b": 100}, next)
+	})
+
+	t.Run("OnComplete", func(t *testing.T) {
+		errBoom := errors.New("boom")
+
+		var (
+			mu        sync.Mutex
+			completed = map[string]error{}
+		)
+
+		sut := mqtt.NewAsyncWriter(failingWriter{"fail": errBoom}, mqtt.AsyncWriterOptions{
+			OnComplete: func(m mqtt.Message, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				completed[m.Topic+"="+string(m.Payload)] = err
+			},
+		})
+
+		payload := []byte("1")
+		require.NoError(t, sut.WriteTopic(t.Context(), "ok", mqtt.WriteOptions{}, payload))
+		require.NoError(t, sut.WriteTopic(t.Context(), "fail", mqtt.WriteOptions{}, payload))
+
+		// Callers may reuse the payload once WriteTopic returns
+		payload[0] = '2'
+
+		require.NoError(t, sut.Close(t.Context()))
+		assert.Equal(t, map[string]error{"ok=1": nil, "fail=1": errBoom}, completed)
+	})
+
+	t.Run("Queue Full", func(t *testing.T) {
+		w := newGatedWriter()
+		defer close(w.release)
+
+		sut := mqtt.NewAsyncWriter(w, mqtt.AsyncWriterOptions{InFlight: 1, QueueSize: 1})
+
+		require.NoError(t, sut.WriteTopic(t.Context(), "state", mqtt.WriteOptions{}, []byte("1")))
+		<-w.started
+		require.NoError(t, sut.WriteTopic(t.Context(), "state", mqtt.WriteOptions{}, []byte("2")))
+
+		ctx, cancel := context.WithCancelCause(t.Context())
+		cancel(errors.New("gave up"))
+
+		require.ErrorIs(t, sut.WriteTopic(ctx, "state", mqtt.WriteOptions{}, []byte("3")), context.Cause(ctx))
+		require.ErrorIs(t, sut.Flush(ctx), context.Cause(ctx))
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		sut := mqtt.NewAsyncWriter(&hqtttest.Writer{}, mqtt.AsyncWriterOptions{})
+		require.NoError(t, sut.Close(t.Context()))
+		require.NoError(t, sut.Close(t.Context()))
+
+		require.ErrorIs(t, sut.WriteTopic(t.Context(), "state", mqtt.WriteOptions{}, []byte("ON")), mqtt.ErrWriterClosed)
+		require.ErrorIs(t, sut.Flush(t.Context()), mqtt.ErrWriterClosed)
+	})
+}
@@ -0,0 +1,150 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/nlowe/hqtt/log"
+)
+
+// AuditDirection describes whether an audited message was published or received.
+type AuditDirection string
+
+const (
+	AuditDirectionPublish AuditDirection = "publish"
+	AuditDirectionReceive AuditDirection = "receive"
+)
+
+const (
+	// AuditOutcomeOK is the outcome of messages that were published or delivered to a Handler successfully.
+	AuditOutcomeOK = "ok"
+	// AuditOutcomeError is the outcome of messages that could not be published.
+	AuditOutcomeError = "error"
+)
+
+// Auditor emits one structured record per MQTT message published or received to a dedicated slog.Handler, separate
+// from the human-oriented logs configured with log.To. Each record is logged at slog.LevelInfo with the message
+// "mqtt audit" and has the following attributes:
+//
+//   - topic: The topic the message was published or received on
+//   - direction: The AuditDirection of the message
+//   - size: The size of the payload in bytes
+//   - qos: The QoS of a published message, or of the subscription that received the message
+//   - retain: Whether a published message was retained
+//   - outcome: AuditOutcomeOK or AuditOutcomeError
+//   - error: The error returned when publishing failed, if any
+//
+// Payloads are never included. Wrap a Writer with Auditor.Writer and a Subscriber with Auditor.Subscriber to audit
+// their messages.
+type Auditor struct {
+	log *slog.Logger
+}
+
+// NewAuditor constructs an Auditor that writes audit records to the provided slog.Handler.
+func NewAuditor(h slog.Handler) *Auditor {
+	return &Auditor{log: slog.New(h)}
+}
+
+// Writer wraps the provided Writer so that every call to WriteTopic is audited.
+func (a *Auditor) Writer(w Writer) Writer {
+	return &auditWriter{a: a, w: w}
+}
+
+// Subscriber wraps the provided Subscriber so that every message delivered to handlers subscribed through it is
+// audited. Writers passed to those handlers are also audited.
+func (a *Auditor) Subscriber(s Subscriber) Subscriber {
+	return &auditSubscriber{a: a, s: s}
+}
+
+func (a *Auditor) record(ctx context.Context, attrs ...slog.Attr) {
+	a.log.LogAttrs(ctx, slog.LevelInfo, "mqtt audit", attrs...)
+}
+
+type auditWriter struct {
+	a *Auditor
+	w Writer
+}
+
+func (aw *auditWriter) WriteTopic(ctx context.Context, topic string, options WriteOptions, value []byte) error {
+	err := aw.w.WriteTopic(ctx, topic, options, value)
+
+	attrs := []slog.Attr{
+		slog.String("topic", topic),
+		slog.String("direction", string(AuditDirectionPublish)),
+		slog.Int("size", len(value)),
+		slog.Int("qos", int(options.QoS)),
+		slog.Bool("retain", options.Retain),
+		slog.String("outcome", AuditOutcomeOK),
+	}
+
+	if err != nil {
+		attrs[len(attrs)-1] = slog.String("outcome", AuditOutcomeError)
+		attrs = append(attrs, log.Error(err))
+	}
+
+	aw.a.record(ctx, attrs...)
+	return err
+}
+
+type auditSubscriber struct {
+	a *Auditor
+	s Subscriber
+}
+
+// Subscribe registers one Handler per QoS in the provided subscriptions, so records of received messages include the
+// QoS of the subscription that received them.
+func (as *auditSubscriber) Subscribe(ctx context.Context, handler Handler, subscriptions ...Subscription) error {
+	var requests []SubscribeRequest
+	index := map[QualityOfService]int{}
+	for _, s := range subscriptions {
+		i, ok := index[s.Options.QoS]
+		if !ok {
+			i = len(requests)
+			index[s.Options.QoS] = i
+			requests = append(requests, SubscribeRequest{Handler: auditHandler{a: as.a, h: handler, qos: s.Options.QoS}})
+		}
+
+		requests[i].Subscriptions = append(requests[i].Subscriptions, s)
+	}
+
+	return SubscribeBatch(ctx, as.s, requests...)
+}
+
+func (as *auditSubscriber) Unsubscribe(ctx context.Context, topics ...string) error {
+	return as.s.Unsubscribe(ctx, topics...)
+}
+
+func (as *auditSubscriber) UnsubscribeHandler(ctx context.Context, handler Handler, topics ...string) error {
+	if _, ok := as.s.(HandlerUnsubscriber); !ok {
+		return as.s.Unsubscribe(ctx, topics...)
+	}
+
+	// The QoS each topic was subscribed with is not known, so release the Handler registered for every QoS
+	var errs []error
+	for _, qos := range []QualityOfService{QOSAtMostOnce, QOSAtLeastOnce, QOSExactlyOnce} {
+		errs = append(errs, UnsubscribeHandler(ctx, as.s, auditHandler{a: as.a, h: handler, qos: qos}, topics...))
+	}
+
+	return errors.Join(errs...)
+}
+
+// auditHandler audits messages received by h through a subscription with the provided QoS. It is comparable if h is,
+// see SameHandler.
+type auditHandler struct {
+	a   *Auditor
+	h   Handler
+	qos QualityOfService
+}
+
+func (ah auditHandler) ServeMQTT(w Writer, topic string, message []byte) {
+	ah.a.record(context.Background(),
+		slog.String("topic", topic),
+		slog.String("direction", string(AuditDirectionReceive)),
+		slog.Int("size", len(message)),
+		slog.Int("qos", int(ah.qos)),
+		slog.String("outcome", AuditOutcomeOK),
+	)
+
+	ah.h.ServeMQTT(ah.a.Writer(w), topic, message)
+}
@@ -0,0 +1,86 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type errWriter struct {
+	err error
+}
+
+func (e errWriter) WriteTopic(context.Context, string, WriteOptions, []byte) error {
+	return e.err
+}
+
+// handlerSubscriber is a Subscriber that records the last Handler it was given.
+type handlerSubscriber struct {
+	handler Handler
+}
+
+func (h *handlerSubscriber) Subscribe(_ context.Context, handler Handler, _ ...Subscription) error {
+	h.handler = handler
+	return nil
+}
+
+func (h *handlerSubscriber) Unsubscribe(context.Context, ...string) error {
+	return nil
+}
+
+func auditRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for line := range bytes.Lines(buf.Bytes()) {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(line, &record))
+
+		delete(record, slog.TimeKey)
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestAuditor(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sut := NewAuditor(slog.NewJSONHandler(buf, nil))
+
+	t.Run("Publish", func(t *testing.T) {
+		defer buf.Reset()
+
+		w := sut.Writer(&capturingWriter{})
+		require.NoError(t, w.WriteTopic(t.Context(), "foo", WriteOptions{QoS: QOSAtLeastOnce, Retain: true}, []byte("bar")))
+
+		boom := errors.New("boom")
+		require.ErrorIs(t, sut.Writer(errWriter{err: boom}).WriteTopic(t.Context(), "fizz", WriteOptions{}, nil), boom)
+
+		assert.Equal(t, []map[string]any{
+			{"level": "INFO", "msg": "mqtt audit", "topic": "foo", "direction": "publish", "size": 3.0, "qos": 1.0, "retain": true, "outcome": "ok"},
+			{"level": "INFO", "msg": "mqtt audit", "topic": "fizz", "direction": "publish", "size": 0.0, "qos": 0.0, "retain": false, "outcome": "error", "error": "boom"},
+		}, auditRecords(t, buf))
+	})
+
+	t.Run("Receive", func(t *testing.T) {
+		defer buf.Reset()
+
+		s := &handlerSubscriber{}
+		require.NoError(t, sut.Subscriber(s).Subscribe(t.Context(), HandlerFunc(func(w Writer, topic string, _ []byte) {
+			require.NoError(t, w.WriteTopic(t.Context(), topic+"/state", WriteOptions{}, []byte("on")))
+		}), Subscription{Topic: "foo/+", Options: ReadOptions{QoS: QOSExactlyOnce}}))
+
+		s.handler.ServeMQTT(&capturingWriter{}, "foo/bar", []byte("on"))
+
+		assert.Equal(t, []map[string]any{
+			{"level": "INFO", "msg": "mqtt audit", "topic": "foo/bar", "direction": "receive", "size": 2.0, "qos": 2.0, "outcome": "ok"},
+			{"level": "INFO", "msg": "mqtt audit", "topic": "foo/bar/state", "direction": "publish", "size": 2.0, "qos": 0.0, "retain": false, "outcome": "ok"},
+		}, auditRecords(t, buf))
+	})
+}
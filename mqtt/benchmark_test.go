@@ -0,0 +1,178 @@
+package mqtt_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// discardWriter drops every message so benchmarks only measure hqtt itself.
+type discardWriter struct{}
+
+func (discardWriter) WriteTopic(context.Context, string, mqtt.WriteOptions, []byte) error {
+	return nil
+}
+
+func BenchmarkJoinTopic(b *testing.B) {
+	b.Run("Discovery", func(b *testing.B) {
+		for b.Loop() {
+			mqtt.JoinTopic("homeassistant", "light", "/foo/", "config")
+		}
+	})
+
+	// Value.Write and RemoteValue.FullyQualifiedTopic join a prefix and a topic for every message
+	b.Run("Publish", func(b *testing.B) {
+		for b.Loop() {
+			mqtt.JoinTopic("hqtt/living_room/lamp", "state")
+		}
+	})
+
+	b.Run("Absolute", func(b *testing.B) {
+		for b.Loop() {
+			mqtt.JoinTopic("", "zigbee2mqtt/lamp/set")
+		}
+	})
+}
+
+func BenchmarkMatchTopic(b *testing.B) {
+	for b.Loop() {
+		mqtt.MatchTopic("homeassistant/+/+/config", "homeassistant/light/foo/config")
+	}
+}
+
+func BenchmarkValue_Write(b *testing.B) {
+	v := mqtt.NewValue("state", mqtt.StringMarshaler)
+
+	for b.Loop() {
+		_, _ = v.Write(b.Context(), discardWriter{}, "prefix", "ON")
+	}
+}
+
+func BenchmarkValue_Write_Large(b *testing.B) {
+	type attributes struct {
+		Readings []string `json:"readings"`
+	}
+
+	value := attributes{Readings: make([]string, 1024)}
+	for i := range value.Readings {
+		value.Readings[i] = strings.Repeat("x", 32)
+	}
+
+	b.Run("Marshal", func(b *testing.B) {
+		v := mqtt.NewValue("attributes", mqtt.JsonValueMarshaler[attributes]())
+
+		b.ReportAllocs()
+		for b.Loop() {
+			_, _ = v.Write(b.Context(), discardWriter{}, "prefix", value)
+		}
+	})
+
+	b.Run("MarshalTo", func(b *testing.B) {
+		v := mqtt.NewStreamingValue("attributes", mqtt.JsonValueMarshalerTo[attributes]())
+
+		b.ReportAllocs()
+		for b.Loop() {
+			_, _ = v.Write(b.Context(), discardWriter{}, "prefix", value)
+		}
+	})
+}
+
+func BenchmarkValue_Get(b *testing.B) {
+	for name, v := range map[string]*mqtt.Value[string]{
+		"Locked":   mqtt.NewValue("state", mqtt.StringMarshaler),
+		"LockFree": mqtt.NewValue("state", mqtt.StringMarshaler).LockFree(),
+	} {
+		b.Run(name, func(b *testing.B) {
+			_, _ = v.Write(b.Context(), discardWriter{}, "prefix", "ON")
+
+			// Readers contend with a writer updating the value in the background
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						_, _ = v.Write(context.Background(), discardWriter{}, "prefix", "OFF")
+					}
+				}
+			}()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					v.Get()
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkRemoteValue_ServeMQTT(b *testing.B) {
+	v := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+	v.Watch(func(string) {})
+
+	payload := []byte("ON")
+	for b.Loop() {
+		v.ServeMQTT(discardWriter{}, "command", payload)
+	}
+}
+
+func TestAllocationBudgets(t *testing.T) {
+	t.Run("JoinTopic", func(t *testing.T) {
+		hqtttest.AssertAllocs(t, 1, func() {
+			mqtt.JoinTopic("homeassistant", "light", "/foo/", "config")
+		})
+
+		hqtttest.AssertAllocs(t, 0, func() {
+			mqtt.JoinTopic("", "zigbee2mqtt/lamp/set")
+		})
+	})
+
+	t.Run("MatchTopic", func(t *testing.T) {
+		hqtttest.AssertAllocs(t, 0, func() {
+			mqtt.MatchTopic("homeassistant/+/+/config", "homeassistant/light/foo/config")
+			mqtt.MatchTopic("hqtt/#", "hqtt/living_room/lamp/set")
+		})
+	})
+
+	t.Run("Value.Write", func(t *testing.T) {
+		v := mqtt.NewValue("state", mqtt.StringMarshaler)
+		hqtttest.AssertAllocs(t, 1, func() {
+			_, _ = v.Write(t.Context(), discardWriter{}, "prefix", "ON")
+		})
+
+		// Streaming values encode into a pooled buffer instead
+		s := mqtt.NewStreamingValue("state", func(w io.Writer, v string) error {
+			_, err := io.WriteString(w, v)
+			return err
+		})
+
+		hqtttest.AssertAllocs(t, 0, func() {
+			_, _ = s.Write(t.Context(), discardWriter{}, "prefix", "ON")
+		})
+	})
+
+	t.Run("FullyQualifiedTopic", func(t *testing.T) {
+		v := mqtt.NewValue("state", mqtt.StringMarshaler)
+		r := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+		hqtttest.AssertAllocs(t, 0, func() {
+			v.FullyQualifiedTopic("prefix")
+			r.FullyQualifiedTopic("prefix")
+		})
+	})
+
+	t.Run("RemoteValue.ServeMQTT", func(t *testing.T) {
+		v := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+		v.Watch(func(string) {})
+
+		payload := []byte("ON")
+		hqtttest.AssertAllocs(t, 15, func() {
+			v.ServeMQTT(discardWriter{}, "command", payload)
+		})
+	})
+}
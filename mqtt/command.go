@@ -0,0 +1,169 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/log"
+)
+
+// ErrCommandNotAcknowledged is the error reported by CommandHandler when the application does not confirm a command
+// was applied within the configured timeout.
+var ErrCommandNotAcknowledged = errors.New("command not acknowledged")
+
+// ErrCommandQueueFull is the error reported by CommandHandler when a command is received while CommandQueueSize
+// commands are already waiting to be applied. The command is dropped.
+var ErrCommandQueueFull = errors.New("command queue full")
+
+// DefaultCommandTimeout is the timeout used by CommandHandler when CommandHandler.Timeout is not positive.
+const DefaultCommandTimeout = 10 * time.Second
+
+// CommandQueueSize is the number of commands a CommandHandler holds while an earlier command is being applied.
+const CommandQueueSize = 16
+
+// CommandHandler applies commands received on a RemoteValue and only reflects them on the paired Value once the
+// application confirms they were applied. If Apply returns an error or does not return within Timeout, the previous
+// state is republished (so Home Assistant stops showing a state the device never reached) and the error is reported
+// to OnError.
+type CommandHandler[T any] struct {
+	// Home Assistant writes commands to this value
+	Command *RemoteValue[T]
+	// The state to update once a command is acknowledged
+	State *Value[T]
+
+	// How long Apply has to acknowledge a command. DefaultCommandTimeout is used if this is not positive.
+	Timeout time.Duration
+
+	// Apply applies the command to the device. Returning nil acknowledges the command, after which it is written to
+	// State. The provided context is cancelled when Timeout elapses. Apply is called from a single worker goroutine, so
+	// commands are applied serially in the order they are received. A command is only applied once Apply returned for
+	// the previous one, even if it timed out.
+	Apply func(ctx context.Context, v T) error
+
+	// OnError is called with the command and the reason it was not applied (ErrCommandNotAcknowledged if Apply timed
+	// out, ErrCommandQueueFull if it was dropped). It may be nil.
+	OnError func(v T, err error)
+
+	mu      sync.Mutex
+	watchID int
+	stop    chan struct{}
+
+	log *slog.Logger
+}
+
+// Start watches Command for new values, applying each with Apply and writing acknowledged values to State using the
+// provided Writer and prefix. Call Stop to stop watching.
+func (h *CommandHandler[T]) Start(w Writer, prefix string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop != nil {
+		return
+	}
+
+	if h.log == nil {
+		h.log = log.ForComponent("mqtt.command").With(slog.String("topic", h.Command.FullyQualifiedTopic(prefix)))
+	}
+
+	queue := make(chan T, CommandQueueSize)
+	stop := make(chan struct{})
+	h.stop = stop
+
+	go h.work(w, prefix, queue, stop)
+	h.watchID = h.Command.Watch(func(v T) {
+		select {
+		case <-stop:
+		case queue <- v:
+		default:
+			h.report(v, ErrCommandQueueFull)
+		}
+	})
+}
+
+// Stop stops watching Command for new values. Commands that are already being applied are not cancelled, commands
+// waiting to be applied are dropped.
+func (h *CommandHandler[T]) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop == nil {
+		return
+	}
+
+	h.Command.Unwatch(h.watchID)
+	close(h.stop)
+	h.stop = nil
+}
+
+// work applies commands from queue in order until stop is closed.
+func (h *CommandHandler[T]) work(w Writer, prefix string, queue <-chan T, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case v := <-queue:
+			running, err := h.handle(w, prefix, v)
+			if err != nil {
+				h.report(v, err)
+			}
+
+			// Do not apply the next command while Apply is still running for one that timed out
+			if running != nil {
+				<-running
+			}
+		}
+	}
+}
+
+func (h *CommandHandler[T]) report(v T, err error) {
+	h.log.With(slog.Any("v", v), log.Error(err)).Warn("Command was not applied")
+	if h.OnError != nil {
+		h.OnError(v, err)
+	}
+}
+
+// handle applies v and updates State accordingly. If Apply timed out, the returned channel receives its result once it
+// returns.
+func (h *CommandHandler[T]) handle(w Writer, prefix string, v T) (running <-chan error, err error) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	ctx, cancel := context.WithTimeoutCause(context.Background(), timeout, ErrCommandNotAcknowledged)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- h.Apply(ctx, v)
+	}()
+
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		running = result
+	}
+
+	// Commands acknowledged after the deadline are still considered failed
+	if err == nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+
+	writeCtx, writeCancel := context.WithTimeout(context.Background(), timeout)
+	defer writeCancel()
+
+	if err == nil {
+		return running, Error(h.State.Write(writeCtx, w, prefix, v))
+	}
+
+	// Restore the previous state so Home Assistant does not show a state the device never reached
+	if _, restoreErr := h.State.Republish(writeCtx, w, prefix); restoreErr != nil && !errors.Is(restoreErr, ErrNeverWritten) {
+		err = errors.Join(err, fmt.Errorf("restore previous state: %w", restoreErr))
+	}
+
+	return running, err
+}
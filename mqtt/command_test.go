@@ -0,0 +1,190 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type syncWriter struct {
+	mu sync.Mutex
+	capturingWriter
+}
+
+func (s *syncWriter) WriteTopic(ctx context.Context, topic string, options WriteOptions, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.capturingWriter.WriteTopic(ctx, topic, options, value)
+}
+
+func (s *syncWriter) payloads() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []string
+	for _, w := range s.writes {
+		result = append(result, w.payload)
+	}
+
+	return result
+}
+
+func TestCommandHandler(t *testing.T) {
+	setup := func(apply func(ctx context.Context, v string) error) (*syncWriter, *CommandHandler[string], chan error) {
+		w := &syncWriter{}
+		errs := make(chan error, 2*CommandQueueSize)
+
+		sut := &CommandHandler[string]{
+			Command: NewRemoteValue("command", StringUnmarshaler),
+			State:   NewValue("state", StringMarshaler),
+			Timeout: 50 * time.Millisecond,
+			Apply:   apply,
+			OnError: func(_ string, err error) {
+				errs <- err
+			},
+		}
+
+		_, err := sut.State.Write(t.Context(), w, "prefix", "previous")
+		require.NoError(t, err)
+
+		sut.Start(w, "prefix")
+		t.Cleanup(sut.Stop)
+
+		return w, sut, errs
+	}
+
+	t.Run("Acknowledged", func(t *testing.T) {
+		w, sut, _ := setup(func(_ context.Context, _ string) error {
+			return nil
+		})
+
+		sut.Command.ServeMQTT(w, "command", []byte("next"))
+
+		require.Eventually(t, func() bool {
+			v, _ := sut.State.Get()
+			return v == "next"
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []string{"previous", "next"}, w.payloads())
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		boom := errors.New("boom")
+		w, sut, errs := setup(func(_ context.Context, _ string) error {
+			return boom
+		})
+
+		sut.Command.ServeMQTT(w, "command", []byte("next"))
+
+		require.ErrorIs(t, <-errs, boom)
+		assert.Equal(t, []string{"previous", "previous"}, w.payloads())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		w, sut, errs := setup(func(ctx context.Context, _ string) error {
+			<-ctx.Done()
+			return nil
+		})
+
+		sut.Command.ServeMQTT(w, "command", []byte("next"))
+
+		require.ErrorIs(t, <-errs, ErrCommandNotAcknowledged)
+		assert.Equal(t, []string{"previous", "previous"}, w.payloads())
+	})
+	t.Run("Ordered", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			applied []string
+		)
+
+		sent := make(chan struct{})
+		w, sut, _ := setup(func(_ context.Context, v string) error {
+			// Hold the first command until every command was received, so the others queue up behind it
+			<-sent
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			applied = append(applied, v)
+			return nil
+		})
+
+		var want []string
+		for i := range 10 {
+			v := strings.Repeat("x", i+1)
+			want = append(want, v)
+			sut.Command.ServeMQTT(w, "command", []byte(v))
+		}
+		close(sent)
+
+		require.Eventually(t, func() bool {
+			return len(w.payloads()) == len(want)+1
+		}, time.Second, time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, want, applied)
+		assert.Equal(t, append([]string{"previous"}, want...), w.payloads())
+	})
+
+	t.Run("Timeout Blocks Next Command", func(t *testing.T) {
+		release := make(chan struct{})
+		var running atomic.Int32
+
+		w, sut, errs := setup(func(_ context.Context, v string) error {
+			assert.Equal(t, int32(1), running.Add(1), "commands must not be applied concurrently")
+			defer running.Add(-1)
+
+			if v == "slow" {
+				<-release
+			}
+
+			return nil
+		})
+
+		sut.Command.ServeMQTT(w, "command", []byte("slow"))
+		sut.Command.ServeMQTT(w, "command", []byte("next"))
+
+		require.ErrorIs(t, <-errs, ErrCommandNotAcknowledged)
+		assert.Equal(t, []string{"previous", "previous"}, w.payloads())
+
+		close(release)
+		require.Eventually(t, func() bool {
+			v, _ := sut.State.Get()
+			return v == "next"
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("Queue Full", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		w, sut, errs := setup(func(ctx context.Context, v string) error {
+			if v == "first" {
+				close(started)
+				<-release
+			}
+
+			return nil
+		})
+		t.Cleanup(func() {
+			close(release)
+		})
+
+		sut.Command.ServeMQTT(w, "command", []byte("first"))
+		<-started
+
+		for range CommandQueueSize {
+			sut.Command.ServeMQTT(w, "command", []byte("queued"))
+		}
+
+		sut.Command.ServeMQTT(w, "command", []byte("dropped"))
+		require.ErrorIs(t, <-errs, ErrCommandQueueFull)
+	})
+}
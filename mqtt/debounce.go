@@ -0,0 +1,48 @@
+package mqtt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+)
+
+// Debounce wraps the provided callback so it is only called once values stop arriving for the provided duration, and
+// only with the most recent value. This is useful with RemoteValue.Watch for commands that Home Assistant sends in
+// rapid bursts, like brightness sliders:
+//
+//	brightness.Watch(mqtt.Debounce(nil, 250*time.Millisecond, func(v uint) {
+//		// Only called once the slider stops moving
+//	}))
+//
+// The callback is called on its own goroutine. If c is nil, clock.Real is used.
+func Debounce[T any](c clock.Clock, d time.Duration, callback func(T)) func(T) {
+	c = clock.Or(c)
+
+	var (
+		mu     sync.Mutex
+		latest T
+		timer  clock.Timer
+	)
+
+	fire := func() {
+		mu.Lock()
+		v := latest
+		mu.Unlock()
+
+		callback(v)
+	}
+
+	return func(v T) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		latest = v
+		if timer == nil {
+			timer = c.AfterFunc(d, fire)
+			return
+		}
+
+		timer.Reset(d)
+	}
+}
@@ -0,0 +1,35 @@
+package mqtt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestDebounce(t *testing.T) {
+	c := hqtttest.NewClock(time.Now())
+
+	var got []uint
+	sut := mqtt.NewRemoteValue("brightness", mqtt.UintUnmarshaler)
+	sut.Watch(mqtt.Debounce(c, time.Second, func(v uint) {
+		got = append(got, v)
+	}))
+
+	for _, v := range []string{"10", "20", "30"} {
+		sut.ServeMQTT(nil, "brightness", []byte(v))
+		c.Advance(500 * time.Millisecond)
+	}
+
+	assert.Empty(t, got)
+
+	c.Advance(500 * time.Millisecond)
+	assert.Equal(t, []uint{30}, got)
+
+	sut.ServeMQTT(nil, "brightness", []byte("40"))
+	c.Advance(time.Second)
+	assert.Equal(t, []uint{30, 40}, got)
+}
@@ -0,0 +1,107 @@
+package mqtt
+
+import "reflect"
+
+// TopicDescription describes the topic of a Value or RemoteValue and the type of the values encoded in its payloads.
+// It is returned by Describer.
+type TopicDescription struct {
+	// Topic is the fully qualified topic for the prefix passed to Describe.
+	Topic string
+	// Direction is AuditDirectionPublish for a Value and AuditDirectionReceive for a RemoteValue.
+	Direction AuditDirection
+	// Type is the type of the values held by the Value or RemoteValue. How they are encoded depends on its marshaler.
+	Type reflect.Type
+	// QoS is the QoS messages are published with for a Value, or the maximum QoS subscribed with for a RemoteValue.
+	QoS QualityOfService
+	// Retain reports whether a Value publishes retained messages. It is always false for a RemoteValue.
+	Retain bool
+}
+
+// Describer is implemented by Value and RemoteValue so the topics of a Component and the types of their payloads can be
+// documented without knowing the type parameter of every value.
+type Describer interface {
+	// Describe returns a TopicDescription for the provided prefix. It reports false if the value is nil or does not
+	// have a topic.
+	Describe(prefix string) (TopicDescription, bool)
+}
+
+// Inspection is a snapshot of the state of a Value or RemoteValue, see Inspector.
+type Inspection struct {
+	// The value last written to a Value or received by a RemoteValue, or nil if Set is false
+	Value any
+	// Whether a value was ever written to a Value or received by a RemoteValue
+	Set bool
+	// The number of writes of a Value that failed, or the number of payloads a RemoteValue could not unmarshal
+	Errors uint64
+}
+
+// Inspector is implemented by Value and RemoteValue so tools can show what a running application holds without knowing
+// the type parameter of every value.
+type Inspector interface {
+	Describer
+
+	// Inspect returns a snapshot of the state of the value. It returns the zero Inspection for a nil value.
+	Inspect() Inspection
+}
+
+var (
+	_ Inspector = (*Value[string])(nil)
+	_ Inspector = (*RemoteValue[string])(nil)
+)
+
+// Describe implements Describer.
+func (v *Value[T]) Describe(prefix string) (TopicDescription, bool) {
+	if v == nil || v.topic == "" {
+		return TopicDescription{}, false
+	}
+
+	return TopicDescription{
+		Topic:     v.FullyQualifiedTopic(prefix),
+		Direction: AuditDirectionPublish,
+		Type:      reflect.TypeFor[T](),
+		QoS:       v.opts.QoS,
+		Retain:    v.opts.Retain,
+	}, true
+}
+
+// Describe implements Describer.
+func (v *RemoteValue[T]) Describe(prefix string) (TopicDescription, bool) {
+	if v == nil || v.topic == "" {
+		return TopicDescription{}, false
+	}
+
+	return TopicDescription{
+		Topic:     v.FullyQualifiedTopic(prefix),
+		Direction: AuditDirectionReceive,
+		Type:      reflect.TypeFor[T](),
+		QoS:       v.opts.QoS,
+	}, true
+}
+
+// Inspect implements Inspector.
+func (v *Value[T]) Inspect() Inspection {
+	if v == nil {
+		return Inspection{}
+	}
+
+	result := Inspection{Errors: v.errs.Load()}
+	if current, ok := v.Get(); ok {
+		result.Value, result.Set = current, true
+	}
+
+	return result
+}
+
+// Inspect implements Inspector.
+func (v *RemoteValue[T]) Inspect() Inspection {
+	if v == nil {
+		return Inspection{}
+	}
+
+	result := Inspection{Errors: v.errs.Load()}
+	if current, ok := v.Get(); ok {
+		result.Value, result.Set = current, true
+	}
+
+	return result
+}
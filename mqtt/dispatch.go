@@ -0,0 +1,185 @@
+package mqtt
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nlowe/hqtt/log"
+)
+
+// OverflowPolicy determines what a Dispatcher does when a callback is dispatched while its queue is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock waits for room in the queue, applying backpressure to the goroutine delivering messages. No
+	// callbacks are dropped. This is the default.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest queued callback to make room, so delivering messages never waits on slow
+	// watchers. Useful for state where only the most recent value matters.
+	OverflowDropOldest
+)
+
+// DefaultDispatcherQueueSize is the number of callbacks a Dispatcher queues if DispatcherOptions.QueueSize is not set.
+const DefaultDispatcherQueueSize = 64
+
+// DispatcherOptions configures a Dispatcher. The zero value uses a single worker, a queue of
+// DefaultDispatcherQueueSize callbacks, and OverflowBlock.
+type DispatcherOptions struct {
+	// Workers is the number of goroutines calling callbacks. With more than one worker, callbacks for consecutive
+	// values may run concurrently and complete out of order.
+	Workers int
+
+	// QueueSize is the number of callbacks that may be waiting for a worker before Overflow applies.
+	QueueSize int
+
+	// Overflow determines what happens when the queue is full.
+	Overflow OverflowPolicy
+}
+
+// Dispatcher calls watcher callbacks on a bounded pool of workers instead of the goroutine delivering MQTT messages, so
+// one slow consumer cannot back up message delivery for every other value. A single Dispatcher is typically shared by
+// every watcher in an application, see Dispatch. It is safe for concurrent use.
+type Dispatcher struct {
+	opts DispatcherOptions
+
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+
+	// queue is a ring buffer of len(queue) callbacks starting at head
+	queue  []func()
+	head   int
+	queued int
+	closed bool
+
+	workers sync.WaitGroup
+	dropped atomic.Uint64
+
+	log      *slog.Logger
+	warnings log.Limiter
+}
+
+// NewDispatcher constructs a Dispatcher with the provided options and starts its workers. Call Close to stop them.
+func NewDispatcher(opts DispatcherOptions) *Dispatcher {
+	opts.Workers = max(opts.Workers, 1)
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultDispatcherQueueSize
+	}
+
+	d := &Dispatcher{
+		opts:  opts,
+		queue: make([]func(), opts.QueueSize),
+
+		log: log.ForComponent("mqtt.dispatcher"),
+	}
+
+	d.notEmpty.L = &d.mu
+	d.notFull.L = &d.mu
+
+	for range opts.Workers {
+		d.workers.Go(d.work)
+	}
+
+	return d
+}
+
+// Dispatch wraps the provided callback so it is called on a worker of the provided Dispatcher instead of the goroutine
+// that calls it. This is useful with RemoteValue.Watch for watchers that may be slow:
+//
+//	d := mqtt.NewDispatcher(mqtt.DispatcherOptions{Overflow: mqtt.OverflowDropOldest})
+//	defer d.Close()
+//
+//	command.Watch(mqtt.Dispatch(d, func(v string) {
+//		// Does not block other RemoteValues from receiving messages
+//	}))
+//
+// With OverflowBlock, callbacks must not dispatch to the same Dispatcher (for example, by writing a Value whose
+// RemoteValue is watched with it), as all workers may end up waiting on a full queue.
+func Dispatch[T any](d *Dispatcher, callback func(T)) func(T) {
+	return func(v T) {
+		d.dispatch(func() {
+			callback(v)
+		})
+	}
+}
+
+// Dropped returns the number of callbacks discarded by OverflowDropOldest, or dispatched after Close.
+func (d *Dispatcher) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+// Close stops accepting new callbacks and waits for the workers to call every callback already queued.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	d.closed = true
+	d.notEmpty.Broadcast()
+	d.notFull.Broadcast()
+	d.mu.Unlock()
+
+	d.workers.Wait()
+	d.warnings.Flush()
+}
+
+// dispatch queues the provided callback, applying the configured OverflowPolicy if the queue is full.
+func (d *Dispatcher) dispatch(callback func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for !d.closed && d.queued == len(d.queue) {
+		if d.opts.Overflow == OverflowDropOldest {
+			d.pop()
+			d.drop("Dispatcher queue is full, dropping oldest callback")
+			break
+		}
+
+		d.notFull.Wait()
+	}
+
+	if d.closed {
+		d.drop("Dispatcher is closed, dropping callback")
+		return
+	}
+
+	d.queue[(d.head+d.queued)%len(d.queue)] = callback
+	d.queued++
+	d.notEmpty.Signal()
+}
+
+// pop removes and returns the oldest queued callback. d.mu must be held and the queue must not be empty.
+func (d *Dispatcher) pop() func() {
+	callback := d.queue[d.head]
+	d.queue[d.head] = nil
+	d.head = (d.head + 1) % len(d.queue)
+	d.queued--
+
+	return callback
+}
+
+// drop records a discarded callback. d.mu must be held.
+func (d *Dispatcher) drop(msg string) {
+	d.dropped.Add(1)
+	// Slow watchers on busy topics would otherwise drown out every other log record
+	d.warnings.Log(context.Background(), d.log, slog.LevelWarn, msg, msg, slog.Uint64("dropped", d.dropped.Load()))
+}
+
+func (d *Dispatcher) work() {
+	for {
+		d.mu.Lock()
+		for d.queued == 0 && !d.closed {
+			d.notEmpty.Wait()
+		}
+
+		if d.queued == 0 {
+			d.mu.Unlock()
+			return
+		}
+
+		callback := d.pop()
+		d.notFull.Signal()
+		d.mu.Unlock()
+
+		callback()
+	}
+}
@@ -0,0 +1,165 @@
+package mqtt_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// recorder collects dispatched values. Values equal to block wait for release before being recorded.
+type recorder struct {
+	mu  sync.Mutex
+	got []uint
+
+	block   uint
+	started chan struct{}
+	release chan struct{}
+}
+
+func newRecorder(block uint) *recorder {
+	return &recorder{block: block, started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (r *recorder) callback(v uint) {
+	if v == r.block {
+		close(r.started)
+		<-r.release
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.got = append(r.got, v)
+}
+
+func (r *recorder) values() []uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.got
+}
+
+func TestDispatch(t *testing.T) {
+	t.Run("Slow Watcher", func(t *testing.T) {
+		d := mqtt.NewDispatcher(mqtt.DispatcherOptions{})
+		defer d.Close()
+
+		slow := newRecorder(1)
+		defer close(slow.release)
+
+		slowValue := mqtt.NewRemoteValue("slow", mqtt.UintUnmarshaler)
+		slowValue.Watch(mqtt.Dispatch(d, slow.callback))
+
+		var fast []uint
+		fastValue := mqtt.NewRemoteValue("fast", mqtt.UintUnmarshaler)
+		fastValue.Watch(func(v uint) {
+			fast = append(fast, v)
+		})
+
+		slowValue.ServeMQTT(nil, "slow", []byte("1"))
+		<-slow.started
+
+		// The slow watcher is still running, but does not hold up delivering messages
+		fastValue.ServeMQTT(nil, "fast", []byte("2"))
+		assert.Equal(t, []uint{2}, fast)
+	})
+
+	t.Run("Drop Oldest", func(t *testing.T) {
+		d := mqtt.NewDispatcher(mqtt.DispatcherOptions{QueueSize: 2, Overflow: mqtt.OverflowDropOldest})
+		r := newRecorder(1)
+		sut := mqtt.Dispatch(d, r.callback)
+
+		sut(1)
+		<-r.started
+
+		for v := range uint(4) {
+			sut(v + 2)
+		}
+
+		close(r.release)
+		d.Close()
+
+		assert.Equal(t, []uint{1, 4, 5}, r.values())
+		assert.Equal(t, uint64(2), d.Dropped())
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		d := mqtt.NewDispatcher(mqtt.DispatcherOptions{QueueSize: 1})
+		r := newRecorder(1)
+		sut := mqtt.Dispatch(d, r.callback)
+
+		sut(1)
+		<-r.started
+		sut(2)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			sut(3)
+		}()
+
+		select {
+		case <-done:
+			require.Fail(t, "dispatch should block while the queue is full")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(r.release)
+		<-done
+		d.Close()
+
+		assert.Equal(t, []uint{1, 2, 3}, r.values())
+		assert.Zero(t, d.Dropped())
+	})
+
+	t.Run("Workers", func(t *testing.T) {
+		d := mqtt.NewDispatcher(mqtt.DispatcherOptions{Workers: 4})
+
+		var mu sync.Mutex
+		got := map[string]int{}
+		sut := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+		sut.Watch(mqtt.Dispatch(d, func(v string) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			got[v]++
+		}))
+
+		for i := range 100 {
+			sut.ServeMQTT(nil, "command", []byte(strconv.Itoa(i)))
+		}
+
+		d.Close()
+		assert.Len(t, got, 100)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		d := mqtt.NewDispatcher(mqtt.DispatcherOptions{})
+		r := newRecorder(1)
+		sut := mqtt.Dispatch(d, r.callback)
+
+		sut(1)
+		<-r.started
+		sut(2)
+
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			d.Close()
+		}()
+
+		close(r.release)
+		<-closed
+
+		// Callbacks queued before Close are still called, later ones are dropped
+		sut(3)
+		assert.Equal(t, []uint{1, 2}, r.values())
+		assert.Equal(t, uint64(1), d.Dropped())
+	})
+}
@@ -0,0 +1,88 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Message is a single message written to MQTT. It implements slog.LogValuer.
+type Message struct {
+	Topic   string
+	Options WriteOptions
+	Payload []byte
+}
+
+func (m Message) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("topic", m.Topic),
+		slog.Any("options", m.Options),
+		slog.Int("size", len(m.Payload)),
+	)
+}
+
+// DryRunWriter is a Writer that records every message written to it instead of publishing to a broker. Topics are
+// validated with ValidateTopic so invalid topics fail the same way they would against a real broker. This allows
+// validating the full MQTT contract of a bridge (discovery payloads, state, availability, etc.) in CI. The zero value
+// is ready to use, and it is safe for concurrent use.
+type DryRunWriter struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+var _ Writer = &DryRunWriter{}
+
+// WriteTopic implements Writer by recording a copy of the provided message.
+func (d *DryRunWriter) WriteTopic(_ context.Context, topic string, options WriteOptions, value []byte) error {
+	if err := ValidateTopic(topic); err != nil {
+		return fmt.Errorf("dry run: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.messages = append(d.messages, Message{Topic: topic, Options: options, Payload: slices.Clone(value)})
+	return nil
+}
+
+// Messages returns every message written to this DryRunWriter in the order they were written.
+func (d *DryRunWriter) Messages() []Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.messages)
+}
+
+// Retained returns the messages a broker would retain after every message written to this DryRunWriter: the last
+// retained message written to each topic. Like a broker, an empty retained payload clears the retained message for a
+// topic.
+func (d *DryRunWriter) Retained() map[string]Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := map[string]Message{}
+	for _, m := range d.messages {
+		if !m.Options.Retain {
+			continue
+		}
+
+		if len(m.Payload) == 0 {
+			delete(result, m.Topic)
+			continue
+		}
+
+		result[m.Topic] = m
+	}
+
+	return result
+}
+
+// Reset discards all recorded messages.
+func (d *DryRunWriter) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.messages = nil
+}
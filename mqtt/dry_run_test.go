@@ -0,0 +1,27 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunWriter(t *testing.T) {
+	sut := &DryRunWriter{}
+
+	require.NoError(t, sut.WriteTopic(t.Context(), "a", WriteOptions{Retain: true}, []byte("1")))
+	require.NoError(t, sut.WriteTopic(t.Context(), "a", WriteOptions{Retain: true}, []byte("2")))
+	require.NoError(t, sut.WriteTopic(t.Context(), "b", WriteOptions{}, []byte("3")))
+	require.NoError(t, sut.WriteTopic(t.Context(), "c", WriteOptions{Retain: true}, []byte("4")))
+	require.NoError(t, sut.WriteTopic(t.Context(), "c", WriteOptions{Retain: true}, nil))
+	require.ErrorIs(t, sut.WriteTopic(t.Context(), "d/#", WriteOptions{}, nil), ErrInvalidTopic)
+
+	assert.Len(t, sut.Messages(), 5)
+	assert.Equal(t, map[string]Message{
+		"a": {Topic: "a", Options: WriteOptions{Retain: true}, Payload: []byte("2")},
+	}, sut.Retained())
+
+	sut.Reset()
+	assert.Empty(t, sut.Messages())
+}
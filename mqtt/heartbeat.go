@@ -0,0 +1,38 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+	"github.com/nlowe/hqtt/log"
+)
+
+// Heartbeat republishes the current value of the provided Value every interval until the provided context is done, at
+// which point the cause of the cancellation is returned. This keeps entities configured with expire_after available
+// while the application is running, even if their state rarely changes. Ticks before the Value is first written are
+// skipped, and errors from Republish are logged and do not stop the heartbeat. If c is nil, clock.Real is used. If
+// interval is not positive, Heartbeat returns nil immediately.
+func Heartbeat[T any](ctx context.Context, c clock.Clock, w Writer, prefix string, v *Value[T], interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+
+	t := clock.Or(c).NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-t.C():
+		}
+
+		_, err := v.Republish(ctx, w, prefix)
+		if err != nil && !errors.Is(err, ErrNeverWritten) {
+			v.log.With(slog.String("topic", v.FullyQualifiedTopic(prefix)), log.Error(err)).WarnContext(ctx, "Heartbeat failed")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package mqtt_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestHeartbeat(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		require.NoError(t, mqtt.Heartbeat[string](t.Context(), nil, nil, "", nil, 0))
+	})
+
+	c := hqtttest.NewClock(time.Now())
+	w := &hqtttest.Writer{}
+	v := mqtt.NewValue("state", mqtt.StringMarshaler)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- mqtt.Heartbeat(ctx, c, w, "prefix", v, time.Minute)
+	}()
+
+	require.NoError(t, c.BlockUntil(t.Context(), 1))
+	require.NoError(t, mqtt.Error(v.Write(t.Context(), w, "prefix", "foo")))
+
+	for want := 2; want <= 3; want++ {
+		c.Advance(time.Minute)
+		require.Eventually(t, func() bool {
+			return len(w.Messages()) == want
+		}, time.Second, time.Millisecond)
+	}
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	for _, m := range w.Messages() {
+		assert.Equal(t, "prefix/state", m.Topic)
+		assert.Equal(t, []byte("foo"), m.Payload)
+	}
+}
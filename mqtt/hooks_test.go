@@ -0,0 +1,106 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hooks"
+)
+
+type testAvailability string
+
+func (a testAvailability) AvailabilityState() string {
+	return string(a)
+}
+
+func TestHooks(t *testing.T) {
+	var (
+		states       []string
+		commands     []string
+		availability []hooks.AvailabilityEvent
+	)
+
+	defer hooks.Register(hooks.Hooks{
+		OnStateWritten: func(_ context.Context, e hooks.StateEvent) {
+			states = append(states, e.Topic+"="+string(e.Payload))
+		},
+		OnCommandReceived: func(_ context.Context, e hooks.CommandEvent) {
+			commands = append(commands, e.Topic+"="+string(e.Payload))
+		},
+		OnAvailabilityChanged: func(_ context.Context, e hooks.AvailabilityEvent) {
+			availability = append(availability, e)
+		},
+	})()
+
+	w := &capturingWriter{}
+
+	state := NewValue("state", StringMarshaler)
+	_, err := state.Write(t.Context(), w, "prefix", "on")
+	require.NoError(t, err)
+
+	command := NewRemoteValue("command", StringUnmarshaler)
+	command.ServeMQTT(w, "command", []byte("off"))
+
+	avty := NewValue("available", func(v testAvailability) ([]byte, error) {
+		return []byte(v), nil
+	})
+	for _, a := range []testAvailability{"online", "online", "offline"} {
+		_, err = avty.Write(t.Context(), w, "prefix", a)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"prefix/state=on", "prefix/available=online", "prefix/available=online", "prefix/available=offline"}, states)
+	assert.Equal(t, []string{"command=off"}, commands)
+	assert.Equal(t, []hooks.AvailabilityEvent{
+		{Topic: "prefix/available", Current: "online"},
+		{Topic: "prefix/available", Previous: "online", Current: "offline"},
+	}, availability)
+}
+
+func TestHooks_CommandReceivedCanReadValue(t *testing.T) {
+	command := NewRemoteValue("command", StringUnmarshaler)
+
+	var seen []string
+	defer hooks.Register(hooks.Hooks{
+		OnCommandReceived: func(context.Context, hooks.CommandEvent) {
+			// Reading the value from the hook must not deadlock
+			v, _ := command.Get()
+			seen = append(seen, v)
+		},
+	})()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		command.ServeMQTT(&capturingWriter{}, "command", []byte("on"))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hook deadlocked reading the value")
+	}
+
+	assert.Equal(t, []string{"on"}, seen)
+}
+
+func TestHooks_PassiveValues(t *testing.T) {
+	var commands []hooks.CommandEvent
+	defer hooks.Register(hooks.Hooks{
+		OnCommandReceived: func(_ context.Context, e hooks.CommandEvent) {
+			commands = append(commands, e)
+		},
+	})()
+
+	status := NewRemoteValue("homeassistant/status", StringUnmarshaler).Passive()
+	status.ServeMQTT(&capturingWriter{}, "homeassistant/status", []byte("online"))
+
+	v, ok := status.Get()
+	require.True(t, ok)
+	assert.Equal(t, "online", v)
+	assert.Empty(t, commands)
+}
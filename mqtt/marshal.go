@@ -1,13 +1,25 @@
 package mqtt
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	jsonv2 "encoding/json/v2"
+	"errors"
+	"io"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // ValueMarshaler is a function that can convert values of type T to a byte slice for writing to an MQTT Topic.
 type ValueMarshaler[T any] func(v T) ([]byte, error)
 
+// ValueMarshalerTo is a function that encodes values of type T by writing them to w. Unlike ValueMarshaler, it does not
+// need to materialize the encoded value in its own byte slice, so large values (attribute blobs, images, etc.) can be
+// streamed into a buffer that is reused between writes. See NewStreamingValue.
+type ValueMarshalerTo[T any] func(w io.Writer, v T) error
+
 // ValueUnmarshaler is a function that can convert the byte slice payload from an MQTT Message to values of type T.
 type ValueUnmarshaler[T any] func([]byte) (T, error)
 
@@ -21,14 +33,93 @@ var (
 	}
 
 	UintMarshaler ValueMarshaler[uint] = func(v uint) ([]byte, error) {
-		return []byte(strconv.Itoa(int(v))), nil
+		return strconv.AppendUint(nil, uint64(v), 10), nil
 	}
 	UintUnmarshaler ValueUnmarshaler[uint] = func(bytes []byte) (uint, error) {
 		v, err := strconv.ParseUint(string(bytes), 10, 64)
 		return uint(v), err
 	}
+
+	// FloatMarshaler encodes values with the fewest digits that still represent them exactly. Values computed at
+	// runtime often carry noise in their last digits (0.1+0.2 is encoded as 0.30000000000000004), use
+	// PrecisionFloatMarshaler to round them instead.
+	FloatMarshaler ValueMarshaler[float64] = func(v float64) ([]byte, error) {
+		if err := checkFinite(v); err != nil {
+			return nil, err
+		}
+
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	}
+	FloatUnmarshaler ValueUnmarshaler[float64] = func(bytes []byte) (float64, error) {
+		v, err := strconv.ParseFloat(string(bytes), 64)
+		if err != nil {
+			return v, err
+		}
+
+		return v, checkFinite(v)
+	}
+
+	// BytesMarshaler publishes binary values, like images, as-is.
+	BytesMarshaler ValueMarshaler[[]byte] = func(v []byte) ([]byte, error) {
+		return v, nil
+	}
+	// BytesUnmarshaler returns a copy of the payload, since the client may reuse the payload buffer once the message
+	// has been handled.
+	BytesUnmarshaler ValueUnmarshaler[[]byte] = func(payload []byte) ([]byte, error) {
+		return bytes.Clone(payload), nil
+	}
+
+	// Base64Marshaler encodes binary values with standard base64 encoding, for consumers that cannot handle binary
+	// payloads.
+	Base64Marshaler ValueMarshaler[[]byte] = func(v []byte) ([]byte, error) {
+		return base64.StdEncoding.AppendEncode(nil, v), nil
+	}
+	// Base64MarshalerTo streams binary values with standard base64 encoding. The output is identical to that of
+	// Base64Marshaler.
+	Base64MarshalerTo ValueMarshalerTo[[]byte] = func(w io.Writer, v []byte) error {
+		e := base64.NewEncoder(base64.StdEncoding, w)
+		if _, err := e.Write(v); err != nil {
+			return err
+		}
+
+		return e.Close()
+	}
+	Base64Unmarshaler ValueUnmarshaler[[]byte] = func(payload []byte) ([]byte, error) {
+		return base64.StdEncoding.AppendDecode(nil, payload)
+	}
 )
 
+// ErrNotFinite is returned when marshaling or unmarshaling NaN or an infinite float, which Home Assistant does not
+// accept as the state of a numeric entity.
+var ErrNotFinite = errors.New("not a finite number")
+
+func checkFinite(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ErrNotFinite
+	}
+
+	return nil
+}
+
+// PrecisionFloatMarshaler returns a ValueMarshaler that rounds values to the provided number of decimals, and always
+// encodes exactly that many (21.5 is encoded as 21.50 with a precision of 2). Values that round to zero are encoded
+// without a sign. Use the same precision as the SuggestedDisplayPrecision of a sensor, so insignificant changes to a
+// measurement publish the same payload and Home Assistant does not record a new state for them.
+func PrecisionFloatMarshaler(precision uint) ValueMarshaler[float64] {
+	return func(v float64) ([]byte, error) {
+		if err := checkFinite(v); err != nil {
+			return nil, err
+		}
+
+		data := strconv.AppendFloat(nil, v, 'f', int(precision), 64)
+		if data[0] == '-' && strings.Trim(string(data[1:]), "0.") == "" {
+			data = data[1:]
+		}
+
+		return data, nil
+	}
+}
+
 // JsonValueMarshaler returns a ValueMarshaler for type T implemented by marshaling the value to Json.
 func JsonValueMarshaler[T any]() ValueMarshaler[T] {
 	return func(v T) ([]byte, error) {
@@ -36,6 +127,14 @@ func JsonValueMarshaler[T any]() ValueMarshaler[T] {
 	}
 }
 
+// JsonValueMarshalerTo returns a ValueMarshalerTo for type T that streams the Json representation of the value. The
+// output is identical to that of JsonValueMarshaler.
+func JsonValueMarshalerTo[T any]() ValueMarshalerTo[T] {
+	return func(w io.Writer, v T) error {
+		return jsonv2.MarshalWrite(w, v, json.DefaultOptionsV1())
+	}
+}
+
 // JsonValueUnmarshaler returns a ValueUnmarshaler for type T implemented by un-marshaling the payload from json.
 func JsonValueUnmarshaler[T any]() ValueUnmarshaler[T] {
 	return func(bytes []byte) (T, error) {
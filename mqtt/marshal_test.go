@@ -0,0 +1,120 @@
+package mqtt_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func FuzzStringUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, mqtt.StringUnmarshaler, mqtt.StringMarshaler)
+}
+
+func FuzzUintUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, mqtt.UintUnmarshaler, mqtt.UintMarshaler)
+}
+
+func FuzzFloatUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, mqtt.FloatUnmarshaler, mqtt.FloatMarshaler, []byte("21.5"), []byte("-1e-7"), []byte("NaN"))
+}
+
+func FuzzPrecisionFloatMarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, mqtt.FloatUnmarshaler, mqtt.PrecisionFloatMarshaler(2), []byte("21.456"), []byte("-0.001"))
+}
+
+func FuzzBytesUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, mqtt.BytesUnmarshaler, mqtt.BytesMarshaler, []byte{0xff, 0xd8, 0xff})
+}
+
+func FuzzBase64Unmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, mqtt.Base64Unmarshaler, mqtt.Base64Marshaler, []byte("/9j/"), []byte("aGk="))
+}
+
+func TestBytesUnmarshaler(t *testing.T) {
+	payload := []byte{0xff, 0xd8, 0xff}
+
+	got, err := mqtt.BytesUnmarshaler(payload)
+	require.NoError(t, err)
+
+	payload[0] = 0
+	assert.Equal(t, []byte{0xff, 0xd8, 0xff}, got)
+}
+
+func TestBase64MarshalerTo(t *testing.T) {
+	for _, v := range [][]byte{nil, {0xff}, {0xff, 0xd8}, {0xff, 0xd8, 0xff}} {
+		want, err := mqtt.Base64Marshaler(v)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, mqtt.Base64MarshalerTo(&buf, v))
+		assert.Equal(t, string(want), buf.String())
+	}
+}
+
+func TestFloatMarshaler(t *testing.T) {
+	// Adding constants is exact, so the noise only shows up when adding at runtime
+	a, b := 0.1, 0.2
+
+	got, err := mqtt.FloatMarshaler(a + b)
+	require.NoError(t, err)
+	assert.Equal(t, "0.30000000000000004", string(got))
+
+	got, err = mqtt.FloatMarshaler(1e21)
+	require.NoError(t, err)
+	assert.Equal(t, "1000000000000000000000", string(got))
+
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, err = mqtt.FloatMarshaler(v)
+		require.ErrorIs(t, err, mqtt.ErrNotFinite)
+	}
+
+	_, err = mqtt.FloatUnmarshaler([]byte("+Inf"))
+	require.ErrorIs(t, err, mqtt.ErrNotFinite)
+}
+
+func TestPrecisionFloatMarshaler(t *testing.T) {
+	a, b := 0.1, 0.2
+
+	for name, tt := range map[string]struct {
+		precision uint
+		value     float64
+		want      string
+	}{
+		"Noise":          {precision: 2, value: a + b, want: "0.30"},
+		"Padded":         {precision: 2, value: 21.5, want: "21.50"},
+		"Rounded":        {precision: 1, value: 21.46, want: "21.5"},
+		"No Decimals":    {precision: 0, value: 1013.7, want: "1014"},
+		"Negative":       {precision: 1, value: -3.14, want: "-3.1"},
+		"Negative Zero":  {precision: 2, value: -0.001, want: "0.00"},
+		"Signed Zero":    {precision: 0, value: math.Copysign(0, -1), want: "0"},
+		"Large":          {precision: 1, value: 1e21, want: "1000000000000000000000.0"},
+		"Small Negative": {precision: 3, value: -0.0005, want: "-0.001"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := mqtt.PrecisionFloatMarshaler(tt.precision)(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	_, err := mqtt.PrecisionFloatMarshaler(2)(math.NaN())
+	require.ErrorIs(t, err, mqtt.ErrNotFinite)
+}
+
+func FuzzJsonValueUnmarshaler(f *testing.F) {
+	type payload struct {
+		State      string            `json:"state"`
+		Brightness uint8             `json:"brightness"`
+		Color      map[string]uint8  `json:"color"`
+		Effects    []string          `json:"effects"`
+		Extra      map[string]string `json:"extra,omitempty"`
+	}
+
+	hqtttest.FuzzUnmarshaler(f, mqtt.JsonValueUnmarshaler[payload](), mqtt.JsonValueMarshaler[payload]())
+}
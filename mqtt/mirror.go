@@ -0,0 +1,149 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// MirrorTarget is one of the destinations of a Mirror, like a broker used by a production Home Assistant instance.
+type MirrorTarget struct {
+	// The name of the target, used in errors and by Mirror.Target
+	Name string
+
+	// The discovery prefix of the Home Assistant instance using this target. If empty, discovery.Prefix is used.
+	DiscoveryPrefix string
+
+	// The Writer messages are published to
+	Writer Writer
+	// The Subscriber commands are received from. If nil, subscriptions are not registered for this target, so it only
+	// receives the state published by the Mirror.
+	Subscriber Subscriber
+}
+
+// Mirror publishes the same devices to several targets at once, for example a production and a staging Home Assistant
+// instance connected to different brokers. It implements Writer by publishing every message to each target, and
+// BatchSubscriber by subscribing on each target that has a Subscriber. Handlers are passed the Mirror as their Writer,
+// so state echoed in response to a command from one target is published to all of them.
+//
+// A target that fails does not prevent the others from being published to or subscribed with, their errors are joined
+// and prefixed with the name of the target. Wrap the Writer of slow targets with NewAsyncWriter so they do not delay
+// the others.
+//
+// Discovery payloads and availability must be published per target instead, since each Home Assistant instance may use
+// its own discovery prefix and restarts independently, and each broker publishes its own will. Register the devices
+// with a hqtt.MirrorManager, which manages them for every target separately.
+type Mirror struct {
+	targets []MirrorTarget
+}
+
+var _ BatchSubscriber = (*Mirror)(nil)
+var _ HandlerUnsubscriber = (*Mirror)(nil)
+
+// NewMirror constructs a Mirror for the provided targets. Their names should be unique.
+func NewMirror(targets ...MirrorTarget) *Mirror {
+	return &Mirror{targets: targets}
+}
+
+// Target returns the Writer of the target with the provided name, or false if there is no such target.
+func (m *Mirror) Target(name string) (Writer, bool) {
+	for _, t := range m.targets {
+		if t.Name == name {
+			return t.Writer, true
+		}
+	}
+
+	return nil, false
+}
+
+// Targets returns a copy of the targets of the Mirror.
+func (m *Mirror) Targets() []MirrorTarget {
+	return slices.Clone(m.targets)
+}
+
+// WriteTopic implements Writer by publishing the message to every target.
+func (m *Mirror) WriteTopic(ctx context.Context, topic string, options WriteOptions, value []byte) error {
+	var errs []error
+	for _, t := range m.targets {
+		if err := t.Writer.WriteTopic(ctx, topic, options, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Subscribe implements Subscriber by subscribing the provided Handler on every target with a Subscriber.
+func (m *Mirror) Subscribe(ctx context.Context, handler Handler, subscriptions ...Subscription) error {
+	return m.SubscribeBatch(ctx, SubscribeRequest{Handler: handler, Subscriptions: subscriptions})
+}
+
+// SubscribeBatch implements BatchSubscriber by subscribing the provided requests on every target with a Subscriber.
+func (m *Mirror) SubscribeBatch(ctx context.Context, requests ...SubscribeRequest) error {
+	wrapped := make([]SubscribeRequest, len(requests))
+	for i, r := range requests {
+		wrapped[i] = SubscribeRequest{Handler: m.handler(r.Handler), Subscriptions: r.Subscriptions}
+	}
+
+	var errs []error
+	for _, t := range m.targets {
+		if t.Subscriber == nil {
+			continue
+		}
+
+		if err := SubscribeBatch(ctx, t.Subscriber, wrapped...); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Mirror) handler(h Handler) Handler {
+	return mirrorHandler{m: m, h: h}
+}
+
+// mirrorHandler passes the Mirror to h as its Writer, so responses are published to every target. It is comparable if
+// h is, see SameHandler.
+type mirrorHandler struct {
+	m *Mirror
+	h Handler
+}
+
+func (mh mirrorHandler) ServeMQTT(_ Writer, topic string, payload []byte) {
+	mh.h.ServeMQTT(mh.m, topic, payload)
+}
+
+// Unsubscribe implements Subscriber by unsubscribing the provided topics on every target with a Subscriber.
+func (m *Mirror) Unsubscribe(ctx context.Context, topics ...string) error {
+	var errs []error
+	for _, t := range m.targets {
+		if t.Subscriber == nil {
+			continue
+		}
+
+		if err := t.Subscriber.Unsubscribe(ctx, topics...); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// UnsubscribeHandler implements HandlerUnsubscriber by releasing the provided Handler on every target with a
+// Subscriber.
+func (m *Mirror) UnsubscribeHandler(ctx context.Context, handler Handler, topics ...string) error {
+	var errs []error
+	for _, t := range m.targets {
+		if t.Subscriber == nil {
+			continue
+		}
+
+		if err := UnsubscribeHandler(ctx, t.Subscriber, m.handler(handler), topics...); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
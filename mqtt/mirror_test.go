@@ -0,0 +1,82 @@
+package mqtt_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestMirror(t *testing.T) {
+	prod, staging := &hqtttest.Writer{}, &hqtttest.Writer{}
+	prodSub, stagingSub := &hqtttest.Subscriber{}, &hqtttest.Subscriber{}
+
+	sut := mqtt.NewMirror(
+		mqtt.MirrorTarget{Name: "prod", Writer: prod, Subscriber: prodSub},
+		mqtt.MirrorTarget{Name: "staging", Writer: staging, Subscriber: stagingSub},
+		mqtt.MirrorTarget{Name: "archive", Writer: &hqtttest.Writer{}},
+	)
+
+	t.Run("Target", func(t *testing.T) {
+		w, ok := sut.Target("staging")
+		require.True(t, ok)
+		assert.Same(t, staging, w)
+
+		_, ok = sut.Target("missing")
+		assert.False(t, ok)
+
+		targets := sut.Targets()
+		require.Len(t, targets, 3)
+		assert.Equal(t, "archive", targets[2].Name)
+	})
+
+	t.Run("WriteTopic", func(t *testing.T) {
+		state := mqtt.NewValue("state", mqtt.StringMarshaler)
+		require.NoError(t, mqtt.Error(state.Write(t.Context(), sut, "light", "ON")))
+
+		prod.AssertPublished(t, "light/state", []byte("ON"))
+		staging.AssertPublished(t, "light/state", []byte("ON"))
+	})
+
+	t.Run("WriteTopic Errors", func(t *testing.T) {
+		boom := errors.New("boom")
+		ok := &hqtttest.Writer{}
+		sut := mqtt.NewMirror(
+			mqtt.MirrorTarget{Name: "prod", Writer: failingWriter{"state": boom}},
+			mqtt.MirrorTarget{Name: "staging", Writer: ok},
+		)
+
+		err := sut.WriteTopic(t.Context(), "state", mqtt.WriteOptions{}, []byte("ON"))
+		require.ErrorIs(t, err, boom)
+		assert.EqualError(t, err, "prod: boom")
+
+		// Failing targets do not prevent publishing to the others
+		ok.AssertPublished(t, "state", []byte("ON"))
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		command := mqtt.NewRemoteValue("light/set", mqtt.StringUnmarshaler)
+		state := mqtt.NewValue("light/state", mqtt.StringMarshaler)
+
+		// Commands from either target are echoed to both
+		require.NoError(t, sut.Subscribe(t.Context(), mqtt.HandlerFunc(func(w mqtt.Writer, topic string, payload []byte) {
+			command.ServeMQTT(w, topic, payload)
+			assert.NoError(t, mqtt.Echo(t.Context(), w, "", command, state))
+		}), mqtt.Subscription{Topic: "light/set"}))
+
+		prodSub.AssertSubscribed(t, "light/set")
+		stagingSub.AssertSubscribed(t, "light/set")
+
+		assert.Equal(t, 1, stagingSub.Inject(staging, "light/set", []byte("OFF")))
+		prod.AssertPublished(t, "light/state", []byte("OFF"))
+		staging.AssertPublished(t, "light/state", []byte("OFF"))
+
+		require.NoError(t, sut.Unsubscribe(t.Context(), "light/set"))
+		assert.Empty(t, prodSub.Subscriptions())
+		assert.Empty(t, stagingSub.Subscriptions())
+	})
+}
@@ -0,0 +1,4 @@
+// Package otel instruments mqtt.Writer and mqtt.Subscriber implementations with OpenTelemetry traces and metrics that
+// follow the semantic conventions for messaging systems. It works with any adapter, and is a separate module so
+// applications that do not use OpenTelemetry do not depend on it.
+package otel
@@ -0,0 +1,207 @@
+package otel
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/semconv/v1.37.0/messagingconv"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// InstrumentationName is the name of the tracer and meter used by an Instrumenter.
+const InstrumentationName = "github.com/nlowe/hqtt/mqtt/otel"
+
+// System is the value of the messaging.system attribute of every span and metric.
+const System messagingconv.SystemAttr = "mqtt"
+
+const (
+	operationPublish = "publish"
+	operationProcess = "process"
+)
+
+// Attributes describing MQTT specific options of published messages.
+const (
+	QoSKey    = attribute.Key("mqtt.qos")
+	RetainKey = attribute.Key("mqtt.retain")
+)
+
+// Options configures an Instrumenter.
+type Options struct {
+	// TracerProvider creates the tracer for spans. If nil, the global TracerProvider is used.
+	TracerProvider trace.TracerProvider
+	// MeterProvider creates the meter for metrics. If nil, the global MeterProvider is used.
+	MeterProvider metric.MeterProvider
+}
+
+// Instrumenter records OpenTelemetry spans and metrics for MQTT messages. Like mqtt.Stats, wrap a Writer with
+// Instrumenter.Writer and a Subscriber with Instrumenter.Subscriber to instrument their messages:
+//
+//   - Every published message is recorded as a "publish <topic>" producer span, which is a child of the span in the
+//     context passed to WriteTopic. The messaging.client.sent.messages and messaging.client.operation.duration
+//     metrics are recorded for it.
+//   - Every received message is recorded as a "process <topic>" consumer span that covers the call to its Handler. The
+//     messaging.client.consumed.messages and messaging.process.duration metrics are recorded for it.
+//
+// Handlers do not receive a context, so their span is a root span, and messages they publish are not children of it.
+// It is safe for concurrent use.
+type Instrumenter struct {
+	tracer trace.Tracer
+
+	sent            messagingconv.ClientSentMessages
+	publishDuration messagingconv.ClientOperationDuration
+	consumed        messagingconv.ClientConsumedMessages
+	processDuration messagingconv.ProcessDuration
+}
+
+// NewInstrumenter constructs an Instrumenter with the provided Options. It returns an error if its metrics cannot be
+// created.
+func NewInstrumenter(opts Options) (*Instrumenter, error) {
+	tp := opts.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	mp := opts.MeterProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+
+	meter := mp.Meter(InstrumentationName)
+	i := &Instrumenter{tracer: tp.Tracer(InstrumentationName)}
+
+	var err error
+	if i.sent, err = messagingconv.NewClientSentMessages(meter); err != nil {
+		return nil, err
+	}
+
+	if i.publishDuration, err = messagingconv.NewClientOperationDuration(meter); err != nil {
+		return nil, err
+	}
+
+	if i.consumed, err = messagingconv.NewClientConsumedMessages(meter); err != nil {
+		return nil, err
+	}
+
+	if i.processDuration, err = messagingconv.NewProcessDuration(meter); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Writer wraps the provided Writer so that every call to WriteTopic is instrumented.
+func (i *Instrumenter) Writer(w mqtt.Writer) mqtt.Writer {
+	return &instrumentedWriter{i: i, w: w}
+}
+
+// Subscriber wraps the provided Subscriber so that every message delivered to handlers subscribed through it is
+// instrumented. Writers passed to those handlers are also instrumented. The returned Subscriber implements
+// mqtt.BatchSubscriber, falling back to Subscribe if the provided Subscriber does not.
+func (i *Instrumenter) Subscriber(s mqtt.Subscriber) mqtt.BatchSubscriber {
+	return &instrumentedSubscriber{i: i, s: s}
+}
+
+type instrumentedWriter struct {
+	i *Instrumenter
+	w mqtt.Writer
+}
+
+func (iw *instrumentedWriter) WriteTopic(ctx context.Context, topic string, options mqtt.WriteOptions, value []byte) error {
+	destination := semconv.MessagingDestinationName(topic)
+
+	ctx, span := iw.i.tracer.Start(ctx, operationPublish+" "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String(string(System)),
+			semconv.MessagingOperationName(operationPublish),
+			semconv.MessagingOperationTypeSend,
+			destination,
+			semconv.MessagingMessageBodySize(len(value)),
+			QoSKey.Int(int(options.QoS)),
+			RetainKey.Bool(options.Retain),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := iw.w.WriteTopic(ctx, topic, options, value)
+	elapsed := time.Since(start).Seconds()
+
+	attrs := []attribute.KeyValue{destination}
+	if err != nil {
+		attrs = append(attrs, semconv.ErrorType(err))
+		span.SetAttributes(semconv.ErrorType(err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	iw.i.sent.Add(ctx, 1, operationPublish, System, attrs...)
+	iw.i.publishDuration.Record(ctx, elapsed, operationPublish, System, attrs...)
+
+	return err
+}
+
+type instrumentedSubscriber struct {
+	i *Instrumenter
+	s mqtt.Subscriber
+}
+
+func (is *instrumentedSubscriber) Subscribe(ctx context.Context, handler mqtt.Handler, subscriptions ...mqtt.Subscription) error {
+	return is.SubscribeBatch(ctx, mqtt.SubscribeRequest{Handler: handler, Subscriptions: subscriptions})
+}
+
+func (is *instrumentedSubscriber) SubscribeBatch(ctx context.Context, requests ...mqtt.SubscribeRequest) error {
+	wrapped := make([]mqtt.SubscribeRequest, len(requests))
+	for n, r := range requests {
+		wrapped[n] = mqtt.SubscribeRequest{Handler: is.handler(r.Handler), Subscriptions: r.Subscriptions}
+	}
+
+	return mqtt.SubscribeBatch(ctx, is.s, wrapped...)
+}
+
+func (is *instrumentedSubscriber) handler(h mqtt.Handler) mqtt.Handler {
+	return instrumentedHandler{i: is.i, h: h}
+}
+
+func (is *instrumentedSubscriber) Unsubscribe(ctx context.Context, topics ...string) error {
+	return is.s.Unsubscribe(ctx, topics...)
+}
+
+func (is *instrumentedSubscriber) UnsubscribeHandler(ctx context.Context, handler mqtt.Handler, topics ...string) error {
+	return mqtt.UnsubscribeHandler(ctx, is.s, is.handler(handler), topics...)
+}
+
+// instrumentedHandler traces messages received by h. It is comparable if h is, see mqtt.SameHandler.
+type instrumentedHandler struct {
+	i *Instrumenter
+	h mqtt.Handler
+}
+
+func (ih instrumentedHandler) ServeMQTT(w mqtt.Writer, topic string, message []byte) {
+	destination := semconv.MessagingDestinationName(topic)
+
+	ctx, span := ih.i.tracer.Start(context.Background(), operationProcess+" "+topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String(string(System)),
+			semconv.MessagingOperationName(operationProcess),
+			semconv.MessagingOperationTypeProcess,
+			destination,
+			semconv.MessagingMessageBodySize(len(message)),
+		),
+	)
+	defer span.End()
+
+	ih.i.consumed.Add(ctx, 1, operationProcess, System, destination)
+
+	start := time.Now()
+	ih.h.ServeMQTT(ih.i.Writer(w), topic, message)
+	ih.i.processDuration.Record(ctx, time.Since(start).Seconds(), operationProcess, System, destination)
+}
@@ -0,0 +1,121 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/mqtt/otel"
+)
+
+// failingWriter fails every write.
+type failingWriter struct {
+	err error
+}
+
+func (f failingWriter) WriteTopic(context.Context, string, mqtt.WriteOptions, []byte) error {
+	return f.err
+}
+
+func TestInstrumenter(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	metrics := sdkmetric.NewManualReader()
+
+	sut, err := otel.NewInstrumenter(otel.Options{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(metrics)),
+	})
+	require.NoError(t, err)
+
+	s := &hqtttest.Subscriber{}
+
+	require.NoError(t, sut.Subscriber(s).Subscribe(t.Context(), mqtt.HandlerFunc(func(w mqtt.Writer, _ string, payload []byte) {
+		// Writers passed to handlers are instrumented too
+		require.NoError(t, w.WriteTopic(t.Context(), "lamp/state", mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}, payload))
+	}), mqtt.Subscription{Topic: "lamp/command"}))
+
+	s.Inject(&hqtttest.Writer{}, "lamp/command", []byte("ON"))
+
+	boom := errors.New("boom")
+	require.ErrorIs(t, sut.Writer(failingWriter{err: boom}).WriteTopic(t.Context(), "lamp/broken", mqtt.WriteOptions{}, nil), boom)
+
+	t.Run("Spans", func(t *testing.T) {
+		ended := spans.Ended()
+		require.Len(t, ended, 3)
+
+		// The publish in the handler ends before the span processing the command
+		publish, process, failed := ended[0], ended[1], ended[2]
+
+		assert.Equal(t, "process lamp/command", process.Name())
+		assert.Equal(t, trace.SpanKindConsumer, process.SpanKind())
+		assert.Subset(t, process.Attributes(), []attribute.KeyValue{
+			attribute.String("messaging.system", "mqtt"),
+			attribute.String("messaging.operation.name", "process"),
+			attribute.String("messaging.operation.type", "process"),
+			attribute.String("messaging.destination.name", "lamp/command"),
+			attribute.Int("messaging.message.body.size", 2),
+		})
+
+		assert.Equal(t, "publish lamp/state", publish.Name())
+		assert.Equal(t, trace.SpanKindProducer, publish.SpanKind())
+		assert.Subset(t, publish.Attributes(), []attribute.KeyValue{
+			attribute.String("messaging.operation.type", "send"),
+			attribute.String("messaging.destination.name", "lamp/state"),
+			attribute.Int("mqtt.qos", 1),
+			attribute.Bool("mqtt.retain", true),
+		})
+		assert.Equal(t, codes.Unset, publish.Status().Code)
+
+		assert.Equal(t, "publish lamp/broken", failed.Name())
+		assert.Equal(t, codes.Error, failed.Status().Code)
+		assert.Equal(t, "boom", failed.Status().Description)
+		assert.Contains(t, failed.Attributes(), attribute.String("error.type", "*errors.errorString"))
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		var rm metricdata.ResourceMetrics
+		require.NoError(t, metrics.Collect(t.Context(), &rm))
+		require.Len(t, rm.ScopeMetrics, 1)
+		assert.Equal(t, otel.InstrumentationName, rm.ScopeMetrics[0].Scope.Name)
+
+		counts := map[string]int64{}
+		histograms := map[string]uint64{}
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, p := range data.DataPoints {
+					destination, _ := p.Attributes.Value("messaging.destination.name")
+					counts[m.Name+" "+destination.AsString()] += p.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, p := range data.DataPoints {
+					destination, _ := p.Attributes.Value("messaging.destination.name")
+					histograms[m.Name+" "+destination.AsString()] += p.Count
+				}
+			}
+		}
+
+		assert.Equal(t, map[string]int64{
+			"messaging.client.consumed.messages lamp/command": 1,
+			"messaging.client.sent.messages lamp/state":       1,
+			"messaging.client.sent.messages lamp/broken":      1,
+		}, counts)
+		assert.Equal(t, map[string]uint64{
+			"messaging.process.duration lamp/command":         1,
+			"messaging.client.operation.duration lamp/state":  1,
+			"messaging.client.operation.duration lamp/broken": 1,
+		}, histograms)
+	})
+}
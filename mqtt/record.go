@@ -0,0 +1,318 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nlowe/hqtt/clock"
+)
+
+// RecordedMessage is a single message published or received through a Recorder.
+type RecordedMessage struct {
+	// The time since the Recorder was constructed
+	Offset    time.Duration
+	Direction AuditDirection
+
+	// Options are only recorded for published messages
+	Message
+}
+
+// recordedLine is the JSON form of a RecordedMessage. Payloads that are valid UTF-8 are stored as strings so recordings
+// are easy to read and edit by hand, and any other payload is stored as base64.
+type recordedLine struct {
+	Offset        string           `json:"offset"`
+	Direction     AuditDirection   `json:"direction"`
+	Topic         string           `json:"topic"`
+	QoS           QualityOfService `json:"qos,omitzero"`
+	Retain        bool             `json:"retain,omitzero"`
+	Payload       string           `json:"payload,omitzero"`
+	PayloadBase64 []byte           `json:"payload_base64,omitzero"`
+}
+
+// Recorder captures every MQTT message published or received by an application to a recording, one JSON object per
+// line, so real-world command and state exchanges can be replayed in tests (see ReadRecording and hqtttest.Replay).
+// Like Auditor, wrap a Writer with Recorder.Writer and a Subscriber with Recorder.Subscriber to record their messages.
+// Received messages are recorded before they are delivered to their Handler, so the recording preserves the order of
+// a command and the state published in response to it. Messages that could not be published are not recorded.
+//
+// Recording never fails the application. The first error writing the recording is available from Err, after which
+// nothing else is recorded. It is safe for concurrent use.
+type Recorder struct {
+	clock clock.Clock
+	start time.Time
+
+	mu  sync.Mutex
+	enc *jsontext.Encoder
+	err error
+}
+
+// NewRecorder constructs a Recorder that writes the recording to w, measuring offsets from now with the provided Clock.
+// If c is nil, clock.Real is used.
+func NewRecorder(w io.Writer, c clock.Clock) *Recorder {
+	c = clock.Or(c)
+	return &Recorder{clock: c, start: c.Now(), enc: jsontext.NewEncoder(w)}
+}
+
+// Writer wraps the provided Writer so that every message published with it is recorded.
+func (r *Recorder) Writer(w Writer) Writer {
+	return &recordWriter{r: r, w: w}
+}
+
+// Subscriber wraps the provided Subscriber so that every message delivered to handlers subscribed through it is
+// recorded once, even if several handlers are subscribed to the same topic filter. Writers passed to those handlers are
+// also recorded. The returned Subscriber implements BatchSubscriber, falling back to Subscribe if the provided
+// Subscriber does not.
+func (r *Recorder) Subscriber(s Subscriber) BatchSubscriber {
+	return &recordSubscriber{r: r, s: s, routes: map[string]*recordRoute{}}
+}
+
+// Err returns the first error encountered writing the recording, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *Recorder) record(direction AuditDirection, m Message) {
+	line := recordedLine{
+		Direction: direction,
+		Topic:     m.Topic,
+		QoS:       m.Options.QoS,
+		Retain:    m.Options.Retain,
+	}
+
+	if utf8.Valid(m.Payload) {
+		line.Payload = string(m.Payload)
+	} else {
+		line.PayloadBase64 = slices.Clone(m.Payload)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+
+	// Offsets are taken while holding the lock so they never decrease within a recording
+	line.Offset = r.clock.Now().Sub(r.start).String()
+	if err := json.MarshalEncode(r.enc, line); err != nil {
+		r.err = fmt.Errorf("record %s: %w", m.Topic, err)
+	}
+}
+
+// ReadRecording reads every message of a recording written by a Recorder, in the order they were recorded.
+func ReadRecording(rd io.Reader) ([]RecordedMessage, error) {
+	dec := jsontext.NewDecoder(rd)
+
+	var result []RecordedMessage
+	for i := 1; ; i++ {
+		var line recordedLine
+		if err := json.UnmarshalDecode(dec, &line); errors.Is(err, io.EOF) {
+			return result, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read recording: message %d: %w", i, err)
+		}
+
+		m, err := line.message()
+		if err != nil {
+			return nil, fmt.Errorf("read recording: message %d: %w", i, err)
+		}
+
+		result = append(result, m)
+	}
+}
+
+func (l recordedLine) message() (RecordedMessage, error) {
+	offset, err := time.ParseDuration(l.Offset)
+	if err != nil {
+		return RecordedMessage{}, fmt.Errorf("offset: %w", err)
+	}
+
+	if l.Direction != AuditDirectionPublish && l.Direction != AuditDirectionReceive {
+		return RecordedMessage{}, fmt.Errorf("invalid direction %q", l.Direction)
+	}
+
+	if l.Payload != "" && l.PayloadBase64 != nil {
+		return RecordedMessage{}, errors.New("payload and payload_base64 are mutually exclusive")
+	}
+
+	payload := l.PayloadBase64
+	if payload == nil {
+		payload = []byte(l.Payload)
+	}
+
+	return RecordedMessage{
+		Offset:    offset,
+		Direction: l.Direction,
+		Message:   Message{Topic: l.Topic, Options: WriteOptions{QoS: l.QoS, Retain: l.Retain}, Payload: payload},
+	}, nil
+}
+
+type recordWriter struct {
+	r *Recorder
+	w Writer
+}
+
+func (rw *recordWriter) WriteTopic(ctx context.Context, topic string, options WriteOptions, value []byte) error {
+	if err := rw.w.WriteTopic(ctx, topic, options, value); err != nil {
+		return err
+	}
+
+	rw.r.record(AuditDirectionPublish, Message{Topic: topic, Options: options, Payload: value})
+	return nil
+}
+
+// recordSubscriber subscribes a single recordRoute for each topic filter, so a message delivered to several handlers
+// subscribed to the same filter is only recorded once.
+type recordSubscriber struct {
+	r *Recorder
+	s Subscriber
+
+	mu     sync.Mutex
+	routes map[string]*recordRoute
+}
+
+func (rs *recordSubscriber) Subscribe(ctx context.Context, handler Handler, subscriptions ...Subscription) error {
+	return rs.SubscribeBatch(ctx, SubscribeRequest{Handler: handler, Subscriptions: subscriptions})
+}
+
+// SubscribeBatch adds the handler of each request to the route for each of its topic filters. Only filters that are not
+// yet subscribed through this Subscriber are subscribed with the wrapped Subscriber, so the options of later
+// subscriptions to the same filter are ignored. If that fails, the handlers added by this call are removed again.
+func (rs *recordSubscriber) SubscribeBatch(ctx context.Context, requests ...SubscribeRequest) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	var (
+		wrapped []SubscribeRequest
+		created []string
+
+		// previous holds the handlers of each existing route before this call so they can be restored on failure.
+		previous = map[*recordRoute][]Handler{}
+	)
+
+	for _, req := range requests {
+		for _, sub := range req.Subscriptions {
+			route, ok := rs.routes[sub.Topic]
+			if !ok {
+				route = &recordRoute{r: rs.r}
+				rs.routes[sub.Topic] = route
+				created = append(created, sub.Topic)
+				wrapped = append(wrapped, SubscribeRequest{Handler: route, Subscriptions: []Subscription{sub}})
+			} else if _, ok := previous[route]; !ok {
+				previous[route] = route.snapshot()
+			}
+
+			route.add(req.Handler)
+		}
+	}
+
+	if err := SubscribeBatch(ctx, rs.s, wrapped...); err != nil {
+		for route, handlers := range previous {
+			route.restore(handlers)
+		}
+
+		for _, topic := range created {
+			delete(rs.routes, topic)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (rs *recordSubscriber) Unsubscribe(ctx context.Context, topics ...string) error {
+	rs.mu.Lock()
+	for _, topic := range topics {
+		delete(rs.routes, topic)
+	}
+	rs.mu.Unlock()
+
+	return rs.s.Unsubscribe(ctx, topics...)
+}
+
+// UnsubscribeHandler removes the provided handler from the route for each topic. Routes are only unsubscribed from the
+// wrapped Subscriber once no handlers remain for them.
+func (rs *recordSubscriber) UnsubscribeHandler(ctx context.Context, handler Handler, topics ...string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	var errs []error
+	for _, topic := range topics {
+		route, ok := rs.routes[topic]
+		if !ok || route.remove(handler) != 0 {
+			continue
+		}
+
+		delete(rs.routes, topic)
+		errs = append(errs, UnsubscribeHandler(ctx, rs.s, route, topic))
+	}
+
+	return errors.Join(errs...)
+}
+
+// recordRoute records each message it receives once, then delivers it to every handler subscribed to its topic filter.
+// Handlers are replaced rather than modified in place, so messages are delivered without holding the lock.
+type recordRoute struct {
+	r *Recorder
+
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func (rr *recordRoute) add(h Handler) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.handlers = append(slices.Clip(rr.handlers), h)
+}
+
+// remove removes every handler that is the same as h (see SameHandler), returning the number of handlers left.
+func (rr *recordRoute) remove(h Handler) int {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.handlers = slices.DeleteFunc(slices.Clone(rr.handlers), func(existing Handler) bool {
+		return SameHandler(existing, h)
+	})
+
+	return len(rr.handlers)
+}
+
+func (rr *recordRoute) snapshot() []Handler {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+
+	return rr.handlers
+}
+
+func (rr *recordRoute) restore(handlers []Handler) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.handlers = handlers
+}
+
+func (rr *recordRoute) ServeMQTT(w Writer, topic string, message []byte) {
+	rr.mu.RLock()
+	handlers := rr.handlers
+	rr.mu.RUnlock()
+
+	rr.r.record(AuditDirectionReceive, Message{Topic: topic, Payload: message})
+
+	rw := rr.r.Writer(w)
+	for _, h := range handlers {
+		h.ServeMQTT(rw, topic, message)
+	}
+}
@@ -0,0 +1,136 @@
+package mqtt_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// brokenFile is an io.Writer that always fails.
+type brokenFile struct{}
+
+func (brokenFile) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecorder(t *testing.T) {
+	c := hqtttest.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local))
+	buf := &bytes.Buffer{}
+	sut := mqtt.NewRecorder(buf, c)
+
+	w := sut.Writer(&hqtttest.Writer{})
+	s := &hqtttest.Subscriber{}
+
+	state := mqtt.NewValueWithOptions("state", mqtt.StringMarshaler, mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true})
+	require.NoError(t, sut.Subscriber(s).Subscribe(t.Context(), mqtt.HandlerFunc(func(w mqtt.Writer, _ string, payload []byte) {
+		// Writers passed to handlers are recorded too
+		_, _ = state.Write(t.Context(), w, "lamp", string(payload))
+	}), mqtt.Subscription{Topic: "lamp/command"}))
+
+	_, err := state.Write(t.Context(), w, "lamp", "OFF")
+	require.NoError(t, err)
+
+	c.Advance(1500 * time.Millisecond)
+	s.Inject(&hqtttest.Writer{}, "lamp/command", []byte("ON"))
+
+	c.Advance(time.Second)
+	require.NoError(t, w.WriteTopic(t.Context(), "lamp/image", mqtt.WriteOptions{}, []byte{0xff, 0x00}))
+
+	// Messages that could not be published are not recorded
+	require.Error(t, w.WriteTopic(t.Context(), "lamp/#", mqtt.WriteOptions{}, []byte("invalid")))
+	require.NoError(t, sut.Err())
+
+	assert.Equal(t, `{"offset":"0s","direction":"publish","topic":"lamp/state","qos":1,"retain":true,"payload":"OFF"}
+{"offset":"1.5s","direction":"receive","topic":"lamp/command","payload":"ON"}
+{"offset":"1.5s","direction":"publish","topic":"lamp/state","qos":1,"retain":true,"payload":"ON"}
+{"offset":"2.5s","direction":"publish","topic":"lamp/image","payload_base64":"/wA="}
+`, buf.String())
+
+	t.Run("Read", func(t *testing.T) {
+		got, err := mqtt.ReadRecording(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+
+		retained := mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}
+		assert.Equal(t, []mqtt.RecordedMessage{
+			{Direction: mqtt.AuditDirectionPublish, Message: mqtt.Message{Topic: "lamp/state", Options: retained, Payload: []byte("OFF")}},
+			{Offset: 1500 * time.Millisecond, Direction: mqtt.AuditDirectionReceive, Message: mqtt.Message{Topic: "lamp/command", Payload: []byte("ON")}},
+			{Offset: 1500 * time.Millisecond, Direction: mqtt.AuditDirectionPublish, Message: mqtt.Message{Topic: "lamp/state", Options: retained, Payload: []byte("ON")}},
+			{Offset: 2500 * time.Millisecond, Direction: mqtt.AuditDirectionPublish, Message: mqtt.Message{Topic: "lamp/image", Payload: []byte{0xff, 0x00}}},
+		}, got)
+	})
+
+	t.Run("Err", func(t *testing.T) {
+		sut := mqtt.NewRecorder(brokenFile{}, c)
+		w := sut.Writer(&hqtttest.Writer{})
+
+		// Recording errors never fail publishing
+		require.NoError(t, w.WriteTopic(t.Context(), "foo", mqtt.WriteOptions{}, []byte("bar")))
+		require.ErrorContains(t, sut.Err(), "record foo: ")
+		require.ErrorContains(t, sut.Err(), "disk full")
+	})
+}
+
+func TestRecorder_SharedTopic(t *testing.T) {
+	c := hqtttest.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local))
+	buf := &bytes.Buffer{}
+	sut := mqtt.NewRecorder(buf, c)
+
+	s := &hqtttest.Subscriber{}
+	rs := sut.Subscriber(s)
+
+	var received []string
+	handler := func(name string) mqtt.Handler {
+		return mqtt.HandlerFunc(func(_ mqtt.Writer, _ string, payload []byte) {
+			received = append(received, name+"="+string(payload))
+		})
+	}
+
+	require.NoError(t, rs.Subscribe(t.Context(), handler("a"), mqtt.Subscription{Topic: "lamp/command"}))
+	require.NoError(t, rs.Subscribe(t.Context(), handler("b"), mqtt.Subscription{Topic: "lamp/command"}))
+
+	// A message delivered to both handlers is recorded once
+	s.Inject(&hqtttest.Writer{}, "lamp/command", []byte("ON"))
+	assert.Equal(t, []string{"a=ON", "b=ON"}, received)
+	assert.Equal(t, `{"offset":"0s","direction":"receive","topic":"lamp/command","payload":"ON"}
+`, buf.String())
+
+	// Replaying the recording delivers the message to each handler once
+	recording, err := mqtt.ReadRecording(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	received = nil
+	replay := &hqtttest.Replay{Messages: recording, Subscriber: s, Writer: &hqtttest.Writer{}}
+	require.True(t, replay.Run(t))
+	assert.Equal(t, []string{"a=ON", "b=ON"}, received)
+}
+
+func TestReadRecording(t *testing.T) {
+	got, err := mqtt.ReadRecording(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	for name, tt := range map[string]struct {
+		input string
+		err   string
+	}{
+		"Malformed":          {input: `{"offset":"0s"`, err: "message 1: "},
+		"Invalid Offset":     {input: `{"offset":"soon","direction":"publish","topic":"foo"}`, err: "message 1: offset: "},
+		"Invalid Direction":  {input: "{\"offset\":\"0s\",\"direction\":\"publish\",\"topic\":\"foo\"}\n{\"offset\":\"1s\",\"direction\":\"sideways\",\"topic\":\"foo\"}", err: `message 2: invalid direction "sideways"`},
+		"Ambiguous Payload":  {input: `{"offset":"0s","direction":"publish","topic":"foo","payload":"bar","payload_base64":"YmFy"}`, err: "message 1: payload and payload_base64 are mutually exclusive"},
+		"Unknown Field Type": {input: `{"offset":"0s","direction":"publish","topic":"foo","retain":"yes"}`, err: "message 1: "},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := mqtt.ReadRecording(strings.NewReader(tt.input))
+			require.ErrorContains(t, err, "read recording: ")
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultRepublishParallelism is the number of values RepublishAll republishes concurrently when the provided
+// parallelism is not positive.
+const DefaultRepublishParallelism = 8
+
+// Republisher republishes the current value of a Value to the provided Writer, see Value.Republisher.
+type Republisher func(ctx context.Context, w Writer) error
+
+// Republisher returns a Republisher for this Value that republishes it with the provided prefix, allowing values of
+// different types to be republished together with RepublishAll. If the Value is nil, it returns nil, which RepublishAll
+// ignores, so optional values of a platform can be passed as-is.
+func (v *Value[T]) Republisher(prefix string) Republisher {
+	if v == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, w Writer) error {
+		_, err := v.Republish(ctx, w, prefix)
+		if err != nil && !errors.Is(err, ErrNeverWritten) {
+			return fmt.Errorf("republish %s: %w", v.FullyQualifiedTopic(prefix), err)
+		}
+
+		return nil
+	}
+}
+
+// RepublishAll republishes every provided value to w, with up to parallelism values being published at once. This is
+// much faster than republishing each value in turn when notifying Home Assistant of the state of large devices after
+// reconnecting or receiving its birth message, since every publish with a QoS above QOSAtMostOnce waits for the broker.
+// If parallelism is not positive, DefaultRepublishParallelism is used.
+//
+// Values that were never written are skipped. Every value is attempted even if others fail, and the errors are joined
+// in the order the values were provided. Values are published in no particular order. If the context is done while
+// waiting for other values to finish publishing, the remaining values are skipped and the cause is included in the
+// returned error.
+func RepublishAll(ctx context.Context, w Writer, parallelism int, values ...Republisher) error {
+	if parallelism <= 0 {
+		parallelism = DefaultRepublishParallelism
+	}
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, parallelism)
+		errs = make([]error, len(values)+1)
+	)
+
+loop:
+	for i, republish := range values {
+		if republish == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			errs[len(values)] = context.Cause(ctx)
+			break loop
+		case sem <- struct{}{}:
+		}
+
+		wg.Go(func() {
+			defer func() {
+				<-sem
+			}()
+
+			errs[i] = republish(ctx, w)
+		})
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package mqtt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// concurrentWriter holds every write until parallelism writes are in flight at once, proving they run concurrently.
+type concurrentWriter struct {
+	hqtttest.Writer
+
+	mu       sync.Mutex
+	inFlight int
+	max      int
+	full     chan struct{}
+	once     sync.Once
+}
+
+func (c *concurrentWriter) WriteTopic(ctx context.Context, topic string, options mqtt.WriteOptions, value []byte) error {
+	c.mu.Lock()
+	c.inFlight++
+	c.max = max(c.max, c.inFlight)
+	if c.inFlight == cap(c.full) {
+		c.once.Do(func() {
+			close(c.full)
+		})
+	}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-c.full:
+	case <-time.After(time.Second):
+		return errors.New("writes were not concurrent")
+	}
+
+	return c.Writer.WriteTopic(ctx, topic, options, value)
+}
+
+// failingWriter fails writes to the configured topics.
+type failingWriter map[string]error
+
+func (f failingWriter) WriteTopic(_ context.Context, topic string, _ mqtt.WriteOptions, _ []byte) error {
+	return f[topic]
+}
+
+func TestRepublishAll(t *testing.T) {
+	const parallelism = 3
+
+	values := make([]*mqtt.Value[string], 10)
+	republishers := make([]mqtt.Republisher, 0, len(values)+2)
+	for i := range values {
+		values[i] = mqtt.NewValue(fmt.Sprintf("state/%d", i), mqtt.StringMarshaler)
+		require.NoError(t, mqtt.Error(values[i].Write(t.Context(), &mqtt.DryRunWriter{}, "prefix", "ON")))
+
+		republishers = append(republishers, values[i].Republisher("prefix"))
+	}
+
+	var optional *mqtt.Value[string]
+	republishers = append(republishers,
+		// Values that are not configured or never written are skipped
+		optional.Republisher("prefix"),
+		mqtt.NewValue("never/written", mqtt.StringMarshaler).Republisher("prefix"),
+	)
+
+	t.Run("Concurrent", func(t *testing.T) {
+		w := &concurrentWriter{full: make(chan struct{}, parallelism)}
+		require.NoError(t, mqtt.RepublishAll(t.Context(), w, parallelism, republishers...))
+
+		assert.Equal(t, parallelism, w.max, "should not exceed parallelism")
+
+		require.Len(t, w.Messages(), len(values))
+		for i := range values {
+			w.AssertPublished(t, fmt.Sprintf("prefix/state/%d", i), []byte("ON"))
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		w := failingWriter{"prefix/state/7": errBoom, "prefix/state/3": errBoom}
+
+		err := mqtt.RepublishAll(t.Context(), w, parallelism, republishers...)
+		require.ErrorIs(t, err, errBoom)
+		assert.EqualError(t, err, "republish prefix/state/3: boom\nrepublish prefix/state/7: boom")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(t.Context())
+		cancel(errors.New("shutting down"))
+
+		w := &mqtt.DryRunWriter{}
+		err := mqtt.RepublishAll(ctx, w, 1, republishers...)
+		require.ErrorIs(t, err, context.Cause(ctx))
+		assert.Less(t, len(w.Messages()), len(values))
+	})
+}
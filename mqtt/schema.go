@@ -0,0 +1,161 @@
+package mqtt
+
+import (
+	"encoding"
+	"encoding/json"
+	jsonv2 "encoding/json/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PayloadSchema is the subset of JSON Schema (https://json-schema.org) needed to describe the payloads of values. An
+// empty PayloadSchema accepts any value, and describes types whose encoding cannot be inferred (for example, types
+// that implement json.Marshaler).
+type PayloadSchema struct {
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	ContentEncoding      string                    `json:"contentEncoding,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Minimum              *int                      `json:"minimum,omitempty"`
+	Items                *PayloadSchema            `json:"items,omitempty"`
+	Properties           map[string]*PayloadSchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *PayloadSchema            `json:"additionalProperties,omitempty"`
+}
+
+// PayloadDescriber is implemented by types that are not encoded as JSON by their usual marshaler, so NewPayloadSchema
+// can describe their payload anyway.
+type PayloadDescriber interface {
+	DescribePayload() *PayloadSchema
+}
+
+var (
+	payloadDescriberType = reflect.TypeFor[PayloadDescriber]()
+	timeType             = reflect.TypeFor[time.Time]()
+	textMarshalerType    = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonMarshalerType    = reflect.TypeFor[json.Marshaler]()
+	marshalerType        = reflect.TypeFor[jsonv2.Marshaler]()
+	marshalerToType      = reflect.TypeFor[jsonv2.MarshalerTo]()
+)
+
+// NewPayloadSchema returns a PayloadSchema for the payloads of values of the provided type. Types that implement
+// PayloadDescriber describe themselves. Otherwise, the schema describes values as they are encoded by
+// JsonValueMarshaler, except that values with a string type are usually published as plain text (see StringMarshaler)
+// rather than as a JSON string.
+func NewPayloadSchema(t reflect.Type) *PayloadSchema {
+	return newPayloadSchema(t, map[reflect.Type]bool{})
+}
+
+func newPayloadSchema(t reflect.Type, visiting map[reflect.Type]bool) *PayloadSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Recursive types accept anything where they refer to themselves
+	if visiting[t] {
+		return &PayloadSchema{}
+	}
+
+	switch {
+	case t.Implements(payloadDescriberType):
+		return reflect.Zero(t).Interface().(PayloadDescriber).DescribePayload()
+	case reflect.PointerTo(t).Implements(payloadDescriberType):
+		return reflect.New(t).Interface().(PayloadDescriber).DescribePayload()
+	case t == timeType:
+		return &PayloadSchema{Type: "string", Format: "date-time"}
+	case implements(t, jsonMarshalerType, marshalerType, marshalerToType):
+		return &PayloadSchema{}
+	case implements(t, textMarshalerType):
+		return &PayloadSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &PayloadSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &PayloadSchema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0
+		return &PayloadSchema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &PayloadSchema{Type: "number"}
+	case reflect.String:
+		return &PayloadSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &PayloadSchema{Type: "string", ContentEncoding: "base64"}
+		}
+
+		return &PayloadSchema{Type: "array", Items: newPayloadSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return &PayloadSchema{Type: "object", AdditionalProperties: newPayloadSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		result := &PayloadSchema{Type: "object", Properties: map[string]*PayloadSchema{}}
+		addProperties(result, t, visiting)
+
+		return result
+	default:
+		return &PayloadSchema{}
+	}
+}
+
+// addProperties adds the fields of the struct type t to result like encoding/json marshals them, including the fields
+// of embedded structs without a name.
+func addProperties(result *PayloadSchema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		options := strings.Split(opts, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				addProperties(result, ft, visiting)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		schema := newPayloadSchema(f.Type, visiting)
+		if slices.Contains(options, "string") && (schema.Type == "integer" || schema.Type == "number" || schema.Type == "boolean") {
+			schema = &PayloadSchema{Type: "string"}
+		}
+
+		result.Properties[name] = schema
+		if !slices.Contains(options, "omitempty") && !slices.Contains(options, "omitzero") {
+			result.Required = append(result.Required, name)
+		}
+	}
+}
+
+// implements reports whether t or a pointer to t implements any of the provided interfaces.
+func implements(t reflect.Type, interfaces ...reflect.Type) bool {
+	for _, i := range interfaces {
+		if t.Implements(i) || reflect.PointerTo(t).Implements(i) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type describedPayload struct{}
+
+func (*describedPayload) DescribePayload() *PayloadSchema {
+	return &PayloadSchema{Type: "string", Pattern: "^described$"}
+}
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children,omitempty"`
+}
+
+type schemaEmbedded struct {
+	Embedded bool `json:"embedded"`
+}
+
+type schemaPayload struct {
+	schemaEmbedded
+
+	State      string           `json:"state"`
+	Brightness uint8            `json:"brightness,omitempty"`
+	Offset     int              `json:"offset,string"`
+	Ratio      float64          `json:"ratio,omitzero"`
+	Effects    []string         `json:"effects"`
+	Extra      map[string]any   `json:"extra,omitempty"`
+	Raw        json.RawMessage  `json:"raw,omitempty"`
+	Image      []byte           `json:"image,omitempty"`
+	Updated    time.Time        `json:"updated"`
+	Tree       *schemaNode      `json:"tree,omitempty"`
+	Described  describedPayload `json:"described"`
+	Untagged   bool
+	Ignored    string `json:"-"`
+	unexported string
+}
+
+func TestNewPayloadSchema(t *testing.T) {
+	zero := 0
+
+	for name, tt := range map[string]struct {
+		t    reflect.Type
+		want *PayloadSchema
+	}{
+		"String":    {t: reflect.TypeFor[string](), want: &PayloadSchema{Type: "string"}},
+		"Bool":      {t: reflect.TypeFor[bool](), want: &PayloadSchema{Type: "boolean"}},
+		"Int":       {t: reflect.TypeFor[int64](), want: &PayloadSchema{Type: "integer"}},
+		"Uint":      {t: reflect.TypeFor[uint](), want: &PayloadSchema{Type: "integer", Minimum: &zero}},
+		"Float":     {t: reflect.TypeFor[float32](), want: &PayloadSchema{Type: "number"}},
+		"Pointer":   {t: reflect.TypeFor[*string](), want: &PayloadSchema{Type: "string"}},
+		"Any":       {t: reflect.TypeFor[any](), want: &PayloadSchema{}},
+		"Described": {t: reflect.TypeFor[describedPayload](), want: &PayloadSchema{Type: "string", Pattern: "^described$"}},
+		"Map":       {t: reflect.TypeFor[map[string]float64](), want: &PayloadSchema{Type: "object", AdditionalProperties: &PayloadSchema{Type: "number"}}},
+		"Struct": {t: reflect.TypeFor[schemaPayload](), want: &PayloadSchema{
+			Type: "object",
+			Properties: map[string]*PayloadSchema{
+				"embedded":   {Type: "boolean"},
+				"state":      {Type: "string"},
+				"brightness": {Type: "integer", Minimum: &zero},
+				"offset":     {Type: "string"},
+				"ratio":      {Type: "number"},
+				"effects":    {Type: "array", Items: &PayloadSchema{Type: "string"}},
+				"extra":      {Type: "object", AdditionalProperties: &PayloadSchema{}},
+				"raw":        {},
+				"image":      {Type: "string", ContentEncoding: "base64"},
+				"updated":    {Type: "string", Format: "date-time"},
+				"tree": {Type: "object", Properties: map[string]*PayloadSchema{
+					"name":     {Type: "string"},
+					"children": {Type: "array", Items: &PayloadSchema{}},
+				}, Required: []string{"name"}},
+				"described": {Type: "string", Pattern: "^described$"},
+				"Untagged":  {Type: "boolean"},
+			},
+			Required: []string{"embedded", "state", "offset", "effects", "updated", "described", "Untagged"},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewPayloadSchema(tt.t))
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	var nilValue *Value[string]
+	_, ok := nilValue.Describe("foo")
+	require.False(t, ok)
+
+	_, ok = NewRemoteValue("", StringUnmarshaler).Describe("foo")
+	require.False(t, ok)
+
+	got, ok := NewValueWithOptions("state", UintMarshaler, WriteOptions{QoS: QOSAtLeastOnce, Retain: true}).Describe("foo")
+	require.True(t, ok)
+	assert.Equal(t, TopicDescription{
+		Topic:     "foo/state",
+		Direction: AuditDirectionPublish,
+		Type:      reflect.TypeFor[uint](),
+		QoS:       QOSAtLeastOnce,
+		Retain:    true,
+	}, got)
+
+	got, ok = NewRemoteValueWithOptions("bar/set", StringUnmarshaler, ReadOptions{QoS: QOSExactlyOnce}).Absolute().Describe("foo")
+	require.True(t, ok)
+	assert.Equal(t, TopicDescription{
+		Topic:     "bar/set",
+		Direction: AuditDirectionReceive,
+		Type:      reflect.TypeFor[string](),
+		QoS:       QOSExactlyOnce,
+	}, got)
+}
@@ -0,0 +1,241 @@
+package mqtt
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+)
+
+// TopicStats holds runtime statistics for MQTT messages published or received on one or more topics. It implements
+// slog.LogValuer.
+type TopicStats struct {
+	// The number of messages published successfully, and the total size of their payloads in bytes
+	Publishes      uint64 `json:"publishes"`
+	PublishedBytes uint64 `json:"published_bytes"`
+	// The number of messages that could not be published
+	PublishErrors uint64 `json:"publish_errors"`
+
+	// The number of messages received, and the total size of their payloads in bytes
+	Receives      uint64 `json:"receives"`
+	ReceivedBytes uint64 `json:"received_bytes"`
+
+	// When a message was last published or received, or the zero time if there was no activity
+	LastActivity time.Time `json:"last_activity,omitzero"`
+}
+
+func (t TopicStats) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Uint64("publishes", t.Publishes),
+		slog.Uint64("published_bytes", t.PublishedBytes),
+		slog.Uint64("publish_errors", t.PublishErrors),
+		slog.Uint64("receives", t.Receives),
+		slog.Uint64("received_bytes", t.ReceivedBytes),
+		slog.Time("last_activity", t.LastActivity),
+	)
+}
+
+// add returns the sum of t and other, keeping the most recent LastActivity.
+func (t TopicStats) add(other TopicStats) TopicStats {
+	t.Publishes += other.Publishes
+	t.PublishedBytes += other.PublishedBytes
+	t.PublishErrors += other.PublishErrors
+	t.Receives += other.Receives
+	t.ReceivedBytes += other.ReceivedBytes
+	if other.LastActivity.After(t.LastActivity) {
+		t.LastActivity = other.LastActivity
+	}
+
+	return t
+}
+
+// Stats tracks TopicStats for every topic published or received through it, so long-running applications can spot hot
+// topics and leaks. Like Auditor, wrap a Writer with Stats.Writer and a Subscriber with Stats.Subscriber to track their
+// messages. Statistics for a single Value are available with Topic, and for a Component with Prefix. It is safe for
+// concurrent use.
+type Stats struct {
+	clock clock.Clock
+
+	mu     sync.RWMutex
+	topics map[string]*topicCounters
+}
+
+type topicCounters struct {
+	publishes      atomic.Uint64
+	publishedBytes atomic.Uint64
+	publishErrors  atomic.Uint64
+	receives       atomic.Uint64
+	receivedBytes  atomic.Uint64
+	lastActivity   atomic.Int64
+}
+
+func (c *topicCounters) snapshot() TopicStats {
+	s := TopicStats{
+		Publishes:      c.publishes.Load(),
+		PublishedBytes: c.publishedBytes.Load(),
+		PublishErrors:  c.publishErrors.Load(),
+		Receives:       c.receives.Load(),
+		ReceivedBytes:  c.receivedBytes.Load(),
+	}
+
+	if last := c.lastActivity.Load(); last != 0 {
+		s.LastActivity = time.Unix(0, last)
+	}
+
+	return s
+}
+
+// NewStats constructs an empty Stats that uses the provided Clock to record activity. If c is nil, clock.Real is used.
+func NewStats(c clock.Clock) *Stats {
+	return &Stats{
+		clock:  clock.Or(c),
+		topics: map[string]*topicCounters{},
+	}
+}
+
+// Writer wraps the provided Writer so that every call to WriteTopic is counted.
+func (s *Stats) Writer(w Writer) Writer {
+	return &statsWriter{s: s, w: w}
+}
+
+// Subscriber wraps the provided Subscriber so that every message delivered to handlers subscribed through it is
+// counted. Writers passed to those handlers are also counted. The returned Subscriber implements BatchSubscriber,
+// falling back to Subscribe if the provided Subscriber does not.
+func (s *Stats) Subscriber(sub Subscriber) BatchSubscriber {
+	return &statsSubscriber{s: s, sub: sub}
+}
+
+// Topic returns the statistics for the provided topic, such as the FullyQualifiedTopic of a Value or RemoteValue.
+func (s *Stats) Topic(topic string) TopicStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if c, ok := s.topics[topic]; ok {
+		return c.snapshot()
+	}
+
+	return TopicStats{}
+}
+
+// Prefix returns the combined statistics for every topic under the provided prefix, such as the TopicPrefix of a
+// Component. Values with absolute topics are not included unless they are also under the prefix.
+func (s *Stats) Prefix(prefix string) TopicStats {
+	prefix = TrimTopic(prefix)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result TopicStats
+	for topic, c := range s.topics {
+		if rest, ok := strings.CutPrefix(topic, prefix); ok && (prefix == "" || rest == "" || strings.HasPrefix(rest, TopicSeparator)) {
+			result = result.add(c.snapshot())
+		}
+	}
+
+	return result
+}
+
+// Topics returns a snapshot of the statistics for every topic seen so far. A number of topics that keeps growing over
+// the lifetime of an application usually indicates a leak.
+func (s *Stats) Topics() map[string]TopicStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]TopicStats, len(s.topics))
+	for topic, c := range s.topics {
+		result[topic] = c.snapshot()
+	}
+
+	return result
+}
+
+// counters returns the counters for the provided topic, creating them if this is the first message on the topic.
+func (s *Stats) counters(topic string) *topicCounters {
+	s.mu.RLock()
+	c, ok := s.topics[topic]
+	s.mu.RUnlock()
+
+	if ok {
+		return c
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok = s.topics[topic]; !ok {
+		c = &topicCounters{}
+		s.topics[topic] = c
+	}
+
+	return c
+}
+
+type statsWriter struct {
+	s *Stats
+	w Writer
+}
+
+func (sw *statsWriter) WriteTopic(ctx context.Context, topic string, options WriteOptions, value []byte) error {
+	err := sw.w.WriteTopic(ctx, topic, options, value)
+
+	c := sw.s.counters(topic)
+	if err != nil {
+		c.publishErrors.Add(1)
+		return err
+	}
+
+	c.publishes.Add(1)
+	c.publishedBytes.Add(uint64(len(value)))
+	c.lastActivity.Store(sw.s.clock.Now().UnixNano())
+
+	return nil
+}
+
+type statsSubscriber struct {
+	s   *Stats
+	sub Subscriber
+}
+
+func (ss *statsSubscriber) Subscribe(ctx context.Context, handler Handler, subscriptions ...Subscription) error {
+	return ss.SubscribeBatch(ctx, SubscribeRequest{Handler: handler, Subscriptions: subscriptions})
+}
+
+func (ss *statsSubscriber) SubscribeBatch(ctx context.Context, requests ...SubscribeRequest) error {
+	wrapped := make([]SubscribeRequest, len(requests))
+	for i, r := range requests {
+		wrapped[i] = SubscribeRequest{Handler: ss.handler(r.Handler), Subscriptions: r.Subscriptions}
+	}
+
+	return SubscribeBatch(ctx, ss.sub, wrapped...)
+}
+
+func (ss *statsSubscriber) handler(h Handler) Handler {
+	return statsHandler{s: ss.s, h: h}
+}
+
+func (ss *statsSubscriber) Unsubscribe(ctx context.Context, topics ...string) error {
+	return ss.sub.Unsubscribe(ctx, topics...)
+}
+
+func (ss *statsSubscriber) UnsubscribeHandler(ctx context.Context, handler Handler, topics ...string) error {
+	return UnsubscribeHandler(ctx, ss.sub, ss.handler(handler), topics...)
+}
+
+// statsHandler counts messages received by h. It is comparable if h is, see SameHandler.
+type statsHandler struct {
+	s *Stats
+	h Handler
+}
+
+func (sh statsHandler) ServeMQTT(w Writer, topic string, message []byte) {
+	c := sh.s.counters(topic)
+	c.receives.Add(1)
+	c.receivedBytes.Add(uint64(len(message)))
+	c.lastActivity.Store(sh.s.clock.Now().UnixNano())
+
+	sh.h.ServeMQTT(sh.s.Writer(w), topic, message)
+}
@@ -0,0 +1,73 @@
+package mqtt_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+func TestStats(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	c := hqtttest.NewClock(start)
+	sut := mqtt.NewStats(c)
+
+	w := sut.Writer(&hqtttest.Writer{})
+	s := &hqtttest.Subscriber{}
+
+	state := mqtt.NewValue("state", mqtt.StringMarshaler)
+	command := mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler)
+	command.Watch(func(v string) {
+		// Writers passed to handlers are counted too
+		_, _ = state.Write(t.Context(), w, "lamp", v)
+	})
+
+	require.NoError(t, sut.Subscriber(s).Subscribe(t.Context(), mqtt.HandlerFunc(func(w mqtt.Writer, topic string, payload []byte) {
+		command.ServeMQTT(w, "command", payload)
+	}), mqtt.Subscription{Topic: "lamp/command"}))
+
+	require.NoError(t, mqtt.Error(state.Write(t.Context(), w, "lamp", "OFF")))
+	c.Advance(time.Minute)
+	s.Inject(&hqtttest.Writer{}, "lamp/command", []byte("ON"))
+
+	assert.Equal(t, mqtt.TopicStats{Publishes: 2, PublishedBytes: 5, LastActivity: start.Add(time.Minute)}, sut.Topic(state.FullyQualifiedTopic("lamp")))
+	assert.Equal(t, mqtt.TopicStats{Receives: 1, ReceivedBytes: 2, LastActivity: start.Add(time.Minute)}, sut.Topic(command.FullyQualifiedTopic("lamp")))
+	assert.Zero(t, sut.Topic("unknown"))
+
+	t.Run("Errors", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		v := mqtt.NewValue("broken", mqtt.StringMarshaler)
+
+		require.ErrorIs(t, mqtt.Error(v.Write(t.Context(), sut.Writer(failingWriter{"lamp/broken": errBoom}), "lamp", "ON")), errBoom)
+		assert.Equal(t, mqtt.TopicStats{PublishErrors: 1}, sut.Topic("lamp/broken"))
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		c.Advance(time.Minute)
+		require.NoError(t, mqtt.Error(mqtt.NewValue("state", mqtt.StringMarshaler).Write(t.Context(), w, "lamp2", "ON")))
+
+		// Topics that merely start with the same characters belong to a different component
+		assert.Equal(t, mqtt.TopicStats{
+			Publishes:      2,
+			PublishedBytes: 5,
+			PublishErrors:  1,
+			Receives:       1,
+			ReceivedBytes:  2,
+			LastActivity:   start.Add(time.Minute),
+		}, sut.Prefix("/lamp/"))
+
+		all := sut.Prefix("")
+		assert.Equal(t, uint64(3), all.Publishes)
+		assert.Equal(t, start.Add(2*time.Minute), all.LastActivity)
+	})
+
+	t.Run("Topics", func(t *testing.T) {
+		assert.Len(t, sut.Topics(), 4)
+		assert.Equal(t, uint64(1), sut.Topics()["lamp/command"].Receives)
+	})
+}
@@ -3,6 +3,8 @@ package mqtt
 import (
 	"context"
 	"log/slog"
+	"reflect"
+	"slices"
 )
 
 // Subscription holds metadata for a MQTT subscription for a given topic. It implements fmt.Stringer and slog.LogValuer.
@@ -42,12 +44,103 @@ func (f HandlerFunc) ServeMQTT(w Writer, topic string, message []byte) {
 	f(w, topic, message)
 }
 
+// Routes is a Handler that dispatches each message to the Handler registered for its exact topic with a single map
+// lookup. Messages for topics without a Handler are ignored. Platforms use it to route commands to their RemoteValues
+// without comparing the topic of every value; see RemoteValue.AddRoute. It is safe for concurrent use once it is no
+// longer modified.
+type Routes map[string]Handler
+
+func (r Routes) ServeMQTT(w Writer, topic string, message []byte) {
+	if h, ok := r[topic]; ok {
+		h.ServeMQTT(w, topic, message)
+	}
+}
+
 // Subscriber manages MQTT Subscriptions
 type Subscriber interface {
 	// Subscribe configures the underlying MQTT connection to send the client messages for the provided subscriptions.
 	// The provided Handler will be called for all subscribed topics in this call.
 	Subscribe(ctx context.Context, handler Handler, subscriptions ...Subscription) error
 
-	// Unsubscribe removes any subscriptions configured for the specified topics.
+	// Unsubscribe removes any subscriptions configured for the specified topics. Implementations must also release all
+	// Handlers registered for these topics by Subscribe so they are no longer called and can be garbage collected.
 	Unsubscribe(ctx context.Context, topics ...string) error
 }
+
+// HandlerUnsubscriber is implemented by Subscribers that can release the Handler registered by a single call to
+// Subscribe while other Handlers subscribed to the same topics keep receiving messages. Use UnsubscribeHandler to fall
+// back to Subscriber.Unsubscribe for Subscribers that do not implement it.
+type HandlerUnsubscriber interface {
+	Subscriber
+
+	// UnsubscribeHandler releases the provided Handler for the specified topics. Subscriptions are only removed from the
+	// broker once no Handlers remain for their topic. Handlers are found with SameHandler, so the provided Handler must
+	// be comparable (for example, a pointer) and equal to the one passed to Subscribe.
+	UnsubscribeHandler(ctx context.Context, handler Handler, topics ...string) error
+}
+
+// UnsubscribeHandler releases the provided Handler for the specified topics. If the provided Subscriber implements
+// HandlerUnsubscriber, other Handlers subscribed to the same topics are kept. Otherwise, Subscriber.Unsubscribe is
+// called, which releases every Handler for these topics.
+func UnsubscribeHandler(ctx context.Context, s Subscriber, handler Handler, topics ...string) error {
+	if len(topics) == 0 {
+		return nil
+	}
+
+	if u, ok := s.(HandlerUnsubscriber); ok {
+		return u.UnsubscribeHandler(ctx, handler, topics...)
+	}
+
+	return s.Unsubscribe(ctx, topics...)
+}
+
+// SameHandler reports whether a and b are the same Handler. Handlers that are not comparable, such as a HandlerFunc,
+// are never the same as any other Handler.
+func SameHandler(a, b Handler) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.ValueOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+// SubscribeRequest pairs a Handler with the Subscriptions it should receive messages for.
+type SubscribeRequest struct {
+	Handler       Handler
+	Subscriptions []Subscription
+}
+
+// BatchSubscriber is implemented by Subscribers that can register subscriptions for several handlers at once, sending
+// them to the broker in as few SUBSCRIBE packets as possible instead of one per Subscribe call. Use SubscribeBatch to
+// fall back to Subscribe for Subscribers that do not implement it.
+type BatchSubscriber interface {
+	Subscriber
+
+	// SubscribeBatch is like Subscribe, but each request configures its own Handler.
+	SubscribeBatch(ctx context.Context, requests ...SubscribeRequest) error
+}
+
+// SubscribeBatch registers every provided request with the provided Subscriber. If it implements BatchSubscriber, the
+// requests are sent together. Otherwise, Subscriber.Subscribe is called for each request, stopping at the first error.
+// Requests without subscriptions are ignored.
+func SubscribeBatch(ctx context.Context, s Subscriber, requests ...SubscribeRequest) error {
+	requests = slices.DeleteFunc(slices.Clone(requests), func(r SubscribeRequest) bool {
+		return len(r.Subscriptions) == 0
+	})
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	if b, ok := s.(BatchSubscriber); ok {
+		return b.SubscribeBatch(ctx, requests...)
+	}
+
+	for _, r := range requests {
+		if err := s.Subscribe(ctx, r.Handler, r.Subscriptions...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
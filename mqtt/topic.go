@@ -1,28 +1,134 @@
 package mqtt
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
 
-const TopicSeparator = "/"
+const (
+	TopicSeparator = "/"
+
+	// SingleLevelWildcard matches a single level of a topic in a subscription.
+	SingleLevelWildcard = "+"
+	// MultiLevelWildcard matches any number of levels at the end of a topic in a subscription.
+	MultiLevelWildcard = "#"
+
+	// MaxTopicLength is the maximum length of an MQTT Topic in bytes.
+	MaxTopicLength = 65535
+)
+
+// ErrInvalidTopic is the error returned by ValidateTopic for topics that cannot be published to.
+var ErrInvalidTopic = errors.New("invalid topic")
+
+// ValidateTopic checks that the provided topic can be published to. Topics must not be empty, must not contain
+// wildcards or null characters, must be valid UTF-8, and must not exceed MaxTopicLength bytes.
+func ValidateTopic(topic string) error {
+	switch {
+	case topic == "":
+		return fmt.Errorf("%w: topic is empty", ErrInvalidTopic)
+	case len(topic) > MaxTopicLength:
+		return fmt.Errorf("%w: topic exceeds %d bytes", ErrInvalidTopic, MaxTopicLength)
+	case !utf8.ValidString(topic):
+		return fmt.Errorf("%w: %q is not valid utf-8", ErrInvalidTopic, topic)
+	case strings.ContainsAny(topic, SingleLevelWildcard+MultiLevelWildcard+"\x00"):
+		return fmt.Errorf("%w: %q contains wildcards or null characters", ErrInvalidTopic, topic)
+	default:
+		return nil
+	}
+}
+
+// MatchTopic reports whether the provided topic matches the provided subscription filter, which may contain
+// SingleLevelWildcard and MultiLevelWildcard levels. Like a broker, wildcards at the first level do not match topics
+// starting with "$".
+func MatchTopic(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, SingleLevelWildcard) || strings.HasPrefix(filter, MultiLevelWildcard)) {
+		return false
+	}
+
+	// Walk both topics one level at a time instead of splitting them so routing messages does not allocate
+	for {
+		level, filterRest, moreFilter := strings.Cut(filter, TopicSeparator)
+		if level == MultiLevelWildcard {
+			return !moreFilter
+		}
+
+		topicLevel, topicRest, moreTopic := strings.Cut(topic, TopicSeparator)
+		if level != SingleLevelWildcard && level != topicLevel {
+			return false
+		}
+
+		if !moreFilter || !moreTopic {
+			// "a/#" also matches "a", so the filter may have a trailing multi-level wildcard left
+			return moreFilter == moreTopic || (moreFilter && filterRest == MultiLevelWildcard)
+		}
+
+		filter, topic = filterRest, topicRest
+	}
+}
 
 // TrimTopic trims TopicSeparator from the start and end of the specified topic.
 func TrimTopic(topic string) string {
 	return strings.Trim(topic, TopicSeparator)
 }
 
-// JoinTopic joins non-empty component parts with TopicSeparator, trimming each part as it is appended.
+// JoinTopic joins non-empty component parts with TopicSeparator, trimming each part as it is appended. Parts that are
+// empty after trimming are skipped. It allocates at most once, and not at all if only one part is non-empty.
 func JoinTopic(parts ...string) string {
+	n, nonEmpty, last := 0, 0, ""
+	for _, part := range parts {
+		if part = TrimTopic(part); part != "" {
+			n += len(part)
+			nonEmpty++
+			last = part
+		}
+	}
+
+	// The trimmed part is a substring of the original, so it can be returned as-is
+	if nonEmpty <= 1 {
+		return last
+	}
+
 	var result strings.Builder
+	result.Grow(n + (nonEmpty-1)*len(TopicSeparator))
 
-	for i, part := range parts {
-		if part == "" || part == TopicSeparator {
+	for _, part := range parts {
+		if part = TrimTopic(part); part == "" {
 			continue
 		}
-		result.WriteString(TrimTopic(part))
 
-		if i != len(parts)-1 {
+		if result.Len() > 0 {
 			result.WriteString(TopicSeparator)
 		}
+		result.WriteString(part)
 	}
 
 	return result.String()
 }
+
+// topicCache remembers the result of joining a topic to the most recently used prefix. Values are almost always used
+// with the same prefix (that of the Component they belong to), so this avoids building the same topic for every
+// message without growing when a value is used with many prefixes. It is safe for concurrent use.
+type topicCache struct {
+	last atomic.Pointer[cachedTopic]
+}
+
+type cachedTopic struct {
+	prefix string
+	topic  string
+}
+
+// join returns JoinTopic(prefix, topic), reusing the previous result if prefix has not changed. The topic must be the
+// same for every call.
+func (c *topicCache) join(prefix, topic string) string {
+	if last := c.last.Load(); last != nil && last.prefix == prefix {
+		return last.topic
+	}
+
+	joined := JoinTopic(prefix, topic)
+	c.last.Store(&cachedTopic{prefix: prefix, topic: joined})
+
+	return joined
+}
@@ -2,8 +2,10 @@ package mqtt
 
 import (
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,6 +40,9 @@ func TestJoinTopic(t *testing.T) {
 		{parts: []string{"", ""}, want: ""},
 		{parts: []string{"", "a"}, want: "a"},
 		{parts: []string{"", "a", "", "b"}, want: "a/b"},
+		{parts: []string{"a", ""}, want: "a"},
+		{parts: []string{"a", "b", ""}, want: "a/b"},
+		{parts: []string{"a", "//", "b"}, want: "a/b"},
 
 		// JoinTopic should trim each individual part
 		{parts: []string{"a", "/", "b"}, want: "a/b"},
@@ -54,3 +59,132 @@ func TestJoinTopic(t *testing.T) {
 		})
 	}
 }
+
+func TestTopicCache(t *testing.T) {
+	var sut topicCache
+
+	require.Equal(t, "a/state", sut.join("a", "state"))
+	require.Equal(t, "a/state", sut.join("a", "state"))
+
+	// Changing the prefix replaces the cached topic
+	require.Equal(t, "b/state", sut.join("b", "state"))
+	require.Equal(t, "state", sut.join("", "state"))
+	require.Equal(t, "a/state", sut.join("a", "state"))
+}
+
+func TestValidateTopic(t *testing.T) {
+	for _, tt := range []struct {
+		topic string
+		valid bool
+	}{
+		{topic: "", valid: false},
+		{topic: "a", valid: true},
+		{topic: "a/b/c", valid: true},
+		{topic: "a/+/c", valid: false},
+		{topic: "a/#", valid: false},
+		{topic: "a\x00b", valid: false},
+		{topic: "\xff", valid: false},
+	} {
+		t.Run(strconv.Quote(tt.topic), func(t *testing.T) {
+			err := ValidateTopic(tt.topic)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrInvalidTopic)
+			}
+		})
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	for _, tt := range []struct {
+		filter string
+		topic  string
+		match  bool
+	}{
+		{filter: "a/b", topic: "a/b", match: true},
+		{filter: "a/b", topic: "a/c", match: false},
+		{filter: "a/b", topic: "a/b/c", match: false},
+		{filter: "a/+/c", topic: "a/b/c", match: true},
+		{filter: "a/+", topic: "a/b/c", match: false},
+		{filter: "a/#", topic: "a", match: true},
+		{filter: "a/#", topic: "a/b/c", match: true},
+		{filter: "#", topic: "a/b", match: true},
+		{filter: "#", topic: "$SYS/foo", match: false},
+		{filter: "+/foo", topic: "$SYS/foo", match: false},
+		{filter: "$SYS/#", topic: "$SYS/foo", match: true},
+		{filter: "a/#/b", topic: "a/c/b", match: false},
+		{filter: "a/+", topic: "a", match: false},
+		{filter: "+/+", topic: "/", match: true},
+		{filter: "a/b/#", topic: "a", match: false},
+	} {
+		t.Run(tt.filter+" "+tt.topic, func(t *testing.T) {
+			assert.Equal(t, tt.match, MatchTopic(tt.filter, tt.topic))
+		})
+	}
+}
+
+func FuzzValidateTopic(f *testing.F) {
+	for _, seed := range []string{"", "a", "a/b", "/a/", "a/+", "a/#", "$SYS/a", "a\x00b", "\xff"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, topic string) {
+		if ValidateTopic(topic) != nil {
+			return
+		}
+
+		// Any topic that can be published to matches itself and is matched by a multi-level wildcard
+		assert.True(t, MatchTopic(topic, topic))
+		assert.Equal(t, !strings.HasPrefix(topic, "$"), MatchTopic(MultiLevelWildcard, topic))
+	})
+}
+
+func FuzzMatchTopic(f *testing.F) {
+	for _, seed := range [][2]string{{"a/b", "a/b"}, {"a/+", "a/b"}, {"a/#", "a"}, {"#", "$SYS/a"}, {"+/+", "/"}, {"a/#/b", "a/c/b"}} {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, filter, topic string) {
+		assert.Equal(t, matchTopicLevels(filter, topic), MatchTopic(filter, topic))
+	})
+}
+
+// matchTopicLevels is a straightforward implementation of MatchTopic that splits both topics into levels up front.
+func matchTopicLevels(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, SingleLevelWildcard) || strings.HasPrefix(filter, MultiLevelWildcard)) {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, TopicSeparator)
+	topicLevels := strings.Split(topic, TopicSeparator)
+
+	for i, level := range filterLevels {
+		if level == MultiLevelWildcard {
+			return i == len(filterLevels)-1
+		}
+
+		if i >= len(topicLevels) {
+			return false
+		}
+
+		if level != SingleLevelWildcard && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
+
+func FuzzJoinTopic(f *testing.F) {
+	for _, seed := range [][2]string{{"", ""}, {"a", "b"}, {"/a/", "/b/"}, {"/", "b"}, {"a", "//"}} {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, a, b string) {
+		assert.Equal(t, TrimTopic(a), JoinTopic(a))
+		assert.Equal(t, TrimTopic(TrimTopic(a)), TrimTopic(a))
+		joined := JoinTopic(a, b)
+		assert.Equal(t, TrimTopic(joined), joined)
+	})
+}
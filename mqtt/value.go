@@ -1,20 +1,27 @@
 package mqtt
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 
+	"github.com/nlowe/hqtt/hooks"
 	"github.com/nlowe/hqtt/log"
 )
 
 var (
-	// ErrNoMarshaler is the error returned when a Value does not have an associated ValueMarshaler, which is required
-	// to write the value to MQTT.
+	// ErrNoMarshaler is the error returned when a Value does not have an associated ValueMarshaler or
+	// ValueMarshalerTo, which is required to write the value to MQTT.
 	ErrNoMarshaler = fmt.Errorf("no marshaler configured")
 	// ErrNeverWritten is the error returned by Value.Republish when Value.Write was not previously called successfully.
 	ErrNeverWritten = fmt.Errorf("value was never written")
+	// ErrClosed is the error returned by RemoteValue.Await when the RemoteValue is closed while waiting, or was already
+	// closed.
+	ErrClosed = fmt.Errorf("remote value closed")
 )
 
 // QualityOfService determines what level of guarantee the broker should provide when delivering messages. It implements
@@ -72,17 +79,30 @@ func (w WriteOptions) LogValue() slog.Value {
 
 // Value holds a value that can be written to a mqtt topic.
 type Value[T any] struct {
-	topic string
+	topic    string
+	absolute bool
+	topics   topicCache
 
-	marshaler ValueMarshaler[T]
+	marshaler   ValueMarshaler[T]
+	marshalerTo ValueMarshalerTo[T]
 	// TODO: Self-subscribe to get the initial value if retained?
 	opts WriteOptions
 
-	mu sync.RWMutex
+	// publishMu serializes writes so messages are published in the same order the held value is updated, without
+	// holding mu (and blocking Get) while publishing.
+	publishMu sync.Mutex
+	mu        sync.RWMutex
 
 	v           T
 	initialized bool
 
+	// In LockFree mode, every update also publishes a copy of v that Get loads without locking mu
+	lockFree bool
+	latest   atomic.Pointer[T]
+
+	// The number of writes that failed, see Inspect
+	errs atomic.Uint64
+
 	log *slog.Logger
 }
 
@@ -105,19 +125,63 @@ func NewValueWithOptions[T any](topic string, marshal ValueMarshaler[T], opts Wr
 
 }
 
+// NewStreamingValue constructs a Value configured for the provided topic that streams values to mqtt with the provided
+// ValueMarshalerTo using default WriteOptions (QoS 0, no retain). Values are encoded into a pooled buffer instead of a
+// new byte slice for every Write, which avoids copying large payloads.
+func NewStreamingValue[T any](topic string, marshal ValueMarshalerTo[T]) *Value[T] {
+	return NewStreamingValueWithOptions(topic, marshal, WriteOptions{})
+}
+
+// NewStreamingValueWithOptions is like NewStreamingValue, but writes to mqtt using the provided WriteOptions.
+func NewStreamingValueWithOptions[T any](topic string, marshal ValueMarshalerTo[T], opts WriteOptions) *Value[T] {
+	return &Value[T]{
+		topic:       topic,
+		marshalerTo: marshal,
+		opts:        opts,
+
+		log: log.ForComponent("mqtt.value"),
+	}
+}
+
+// Absolute marks this Value as having an absolute topic. The prefix provided to FullyQualifiedTopic, Write, and other
+// methods is ignored for absolute values. This is useful when bridging existing devices whose topics cannot be moved
+// under a Component's topic prefix. It must be called before the Value is used, and returns the Value to allow chaining
+// with NewValue.
+func (v *Value[T]) Absolute() *Value[T] {
+	v.absolute = true
+	return v
+}
+
+// LockFree makes Get read the held value without locking, so reads on hot paths (dashboards, frequently derived values)
+// do not contend with writers. In exchange, every Write allocates a copy of the new value. It must be called before the
+// Value is used, and returns the Value to allow chaining with NewValue.
+func (v *Value[T]) LockFree() *Value[T] {
+	v.lockFree = true
+	return v
+}
+
 // FullyQualifiedTopic calculates the MQTT Topic for this value when given the specified prefix. If the underlying Value
-// (not the value it holds) is nil, the empty string is returned.
+// (not the value it holds) is nil, the empty string is returned. If the Value is Absolute, the prefix is ignored. The
+// topic for the most recently used prefix is cached, so calling this for every message does not allocate.
 func (v *Value[T]) FullyQualifiedTopic(prefix string) string {
 	if v == nil {
 		return ""
 	}
 
-	return JoinTopic(prefix, v.topic)
+	if v.absolute {
+		return TrimTopic(v.topic)
+	}
+
+	return v.topics.join(prefix, v.topic)
 }
 
 // Get returns the most recently written value and a bool indicating whether the most recent write was successful, which
 // will be false if the value has not yet been written.
 func (v *Value[T]) Get() (T, bool) {
+	if v.lockFree {
+		return loadLatest(&v.latest)
+	}
+
 	v.mu.RLock()
 	defer v.mu.RUnlock()
 
@@ -127,13 +191,10 @@ func (v *Value[T]) Get() (T, bool) {
 // Republish writes the current value held by this Value to MQTT. Useful if you're not using WriteOptions.Retain and
 // need to notify new subscribers of the current state.
 func (v *Value[T]) Republish(ctx context.Context, w Writer, prefix string) (T, error) {
-	// Copy the value while holding RLock, then release the lock so Write can grab the Lock.
-	v.mu.RLock()
-	currentValue, initialized := v.v, v.initialized
-	v.mu.RUnlock()
-
+	// Copy the value before writing it so Write can grab the Lock.
+	currentValue, initialized := v.Get()
 	if !initialized {
-		return v.v, ErrNeverWritten
+		return currentValue, ErrNeverWritten
 	}
 
 	return v.Write(ctx, w, prefix, currentValue)
@@ -141,22 +202,123 @@ func (v *Value[T]) Republish(ctx context.Context, w Writer, prefix string) (T, e
 
 // Write uses the configured marshaler for this value to encode the newValue to the configured topic. It then updates
 // the held value. After the call to Write succeeds, future calls to Get will start returning newValue.
+//
+// The held value is updated before the message is published, so Get and Republish do not block while waiting on a slow
+// broker. Concurrent calls to Write are serialized so messages are published in the same order the held value changes.
 func (v *Value[T]) Write(ctx context.Context, w Writer, prefix string, newValue T) (T, error) {
-	if v.marshaler == nil {
+	if v.marshaler == nil && v.marshalerTo == nil {
 		return newValue, ErrNoMarshaler
 	}
 
+	v.publishMu.Lock()
+	defer v.publishMu.Unlock()
+
+	data, buf, err := v.marshal(newValue)
+	defer releaseBuffer(buf)
+	if err != nil {
+		v.errs.Add(1)
+		current, _ := v.Get()
+		return current, fmt.Errorf("marshal %+v: %w", newValue, err)
+	}
+
 	v.mu.Lock()
-	defer v.mu.Unlock()
+	previous, hadPrevious := v.v, v.initialized
+	v.v, v.initialized = newValue, true
+	if v.lockFree {
+		storeLatest(&v.latest, newValue)
+	}
+	v.mu.Unlock()
+
+	topic := v.FullyQualifiedTopic(prefix)
+	err = w.WriteTopic(ctx, topic, v.opts, data)
+	if err != nil {
+		v.errs.Add(1)
+	}
+
+	if hooks.Enabled() {
+		fireWriteHooks(ctx, topic, v.opts, data, err, previous, hadPrevious, newValue)
+	}
+
+	return newValue, err
+}
+
+// marshal encodes the provided value with the configured marshaler. For streaming values, data is backed by buf, which
+// must be released with releaseBuffer once data is no longer used.
+func (v *Value[T]) marshal(value T) (data []byte, buf *bytes.Buffer, err error) {
+	if v.marshalerTo == nil {
+		data, err = v.marshaler(value)
+		return data, nil, err
+	}
+
+	buf = buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	err = v.marshalerTo(buf, value)
+	return buf.Bytes(), buf, err
+}
+
+// maxPooledBuffer is the capacity above which buffers are not returned to the pool, so one unusually large value does
+// not pin its buffer in memory forever.
+const maxPooledBuffer = 1 << 20
+
+// buffers holds buffers for streaming values, see NewStreamingValue.
+var buffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// releaseBuffer returns a buffer obtained by Value.marshal to the pool. It does nothing if buf is nil.
+func releaseBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBuffer {
+		return
+	}
+
+	buffers.Put(buf)
+}
+
+// storeLatest publishes a copy of the provided value for loadLatest. The copy is allocated here so values that are not
+// LockFree do not escape to the heap.
+func storeLatest[T any](latest *atomic.Pointer[T], value T) {
+	p := new(T)
+	*p = value
+	latest.Store(p)
+}
+
+// loadLatest returns the value most recently published by storeLatest, and false if nothing was published yet.
+func loadLatest[T any](latest *atomic.Pointer[T]) (T, bool) {
+	if p := latest.Load(); p != nil {
+		return *p, true
+	}
+
+	var zero T
+	return zero, false
+}
+
+// fireWriteHooks calls hooks.StateWritten for a write, and hooks.AvailabilityChanged if the written value represents
+// availability that is different from the previous value.
+func fireWriteHooks[T any](ctx context.Context, topic string, opts WriteOptions, data []byte, err error, previous T, hadPrevious bool, current T) {
+	hooks.StateWritten(ctx, hooks.StateEvent{
+		Topic:   topic,
+		QoS:     uint8(opts.QoS),
+		Retain:  opts.Retain,
+		Payload: data,
+		Err:     err,
+	})
 
-	data, err := v.marshaler(newValue)
 	if err != nil {
-		return v.v, fmt.Errorf("marshal %+v: %w", newValue, err)
+		return
+	}
+
+	c, ok := any(current).(hooks.Availability)
+	if !ok {
+		return
 	}
 
-	v.v = newValue
-	v.initialized = true
-	return v.v, w.WriteTopic(ctx, JoinTopic(prefix, v.topic), v.opts, data)
+	var p string
+	if hadPrevious {
+		p = any(previous).(hooks.Availability).AvailabilityState()
+	}
+
+	if p != c.AvailabilityState() {
+		hooks.AvailabilityChanged(ctx, hooks.AvailabilityEvent{Topic: topic, Previous: p, Current: c.AvailabilityState()})
+	}
 }
 
 // SubscriptionRetainHandling adjusts how MQTT sends retain values to subscribers. It implements fmt.Stringer and
@@ -223,17 +385,42 @@ func (r ReadOptions) LogValue() slog.Value {
 // RemoteValue holds a value that is populated from a mqtt topic subscription.
 type RemoteValue[T any] struct {
 	topic       string
+	absolute    bool
+	topics      topicCache
 	unmarshaler ValueUnmarshaler[T]
 	opts        ReadOptions
 
-	mu sync.RWMutex
+	// dispatchMu serializes ServeMQTT so watchers see values in the order they were received, without holding mu
+	// (and blocking Get, Watch, and Unwatch) while they are called.
+	dispatchMu sync.Mutex
+	mu         sync.RWMutex
 
-	watchers []func(T)
+	watchers      []watcher[T]
+	lastWatcherID int
 
 	v           T
 	initialized bool
+	// Whether the most recent message could not be unmarshalled, see LastReceived
+	failed bool
 
-	log *slog.Logger
+	// In LockFree mode, every update also publishes a copy of v that Get loads without locking mu
+	lockFree bool
+	latest   atomic.Pointer[T]
+
+	// Passive values do not fire hooks.Hooks.OnCommandReceived
+	passive bool
+
+	// The number of payloads that could not be unmarshalled, see Inspect
+	errs atomic.Uint64
+
+	// closing is closed by Close to wake up Await, and drained once watchers called before Close have returned. Both
+	// are created lazily, see signals.
+	closed  bool
+	closing chan struct{}
+	drained chan struct{}
+
+	log      *slog.Logger
+	warnings log.Limiter
 }
 
 // NewRemoteValue constructs a RemoteValue by subscribing to the specified topic on the provided SubscriptionRouter. It
@@ -259,16 +446,48 @@ func NewRemoteValueWithOptions[T any](topic string, unmarshaler ValueUnmarshaler
 // topic exactly matches the configured topic for this RemoteValue. It then invokes any watcher callbacks. If
 // unmarshalling fails, the watchers are not called and an error is logged. See the log package for details on
 // configuring this logger.
+//
+// Watchers are called after the value is updated and without holding the lock protecting it, so they may call Get,
+// Watch, and Unwatch on this RemoteValue. They are called on the goroutine delivering the message, so slow watchers
+// should be wrapped with Dispatch.
 func (v *RemoteValue[T]) ServeMQTT(_ Writer, topic string, payload []byte) {
 	if v == nil {
 		return
 	}
 
+	v.dispatchMu.Lock()
+	defer v.dispatchMu.Unlock()
+
+	parsed, watchers, matched, err := v.update(topic, payload)
+	if !matched {
+		return
+	}
+
+	// Like watchers, hooks are called without holding mu so they may read this RemoteValue
+	if !v.passive && hooks.Enabled() {
+		hooks.CommandReceived(context.Background(), hooks.CommandEvent{Topic: topic, Payload: payload, Err: err})
+	}
+
+	if err != nil {
+		return
+	}
+
+	v.log.With(slog.Int("count", len(watchers))).Debug("Updating watchers")
+	for _, w := range watchers {
+		w.callback(parsed)
+	}
+}
+
+// update stores the value unmarshalled from the provided payload if topic matches the topic for this RemoteValue. It
+// returns the new value and a snapshot of the watchers to call, whether the topic matched, and the error returned when
+// unmarshalling the payload. The value is only updated if the topic matched and err is nil.
+func (v *RemoteValue[T]) update(topic string, payload []byte) (T, []watcher[T], bool, error) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	if v.topic != topic {
-		return
+	var zero T
+	if v.closed || v.topic != topic {
+		return zero, nil, false, nil
 	}
 
 	if v.unmarshaler == nil {
@@ -276,29 +495,66 @@ func (v *RemoteValue[T]) ServeMQTT(_ Writer, topic string, payload []byte) {
 	}
 
 	parsed, err := v.unmarshaler(payload)
+	v.failed = err != nil
 	if err != nil {
-		v.log.With(log.Error(err)).Warn("Failed to unmarshal payload from mqtt")
+		v.errs.Add(1)
+
+		// Topics flooded with invalid payloads would otherwise drown out every other log record
+		v.warnings.Log(context.Background(), v.log, slog.LevelWarn, "unmarshal", "Failed to unmarshal payload from mqtt", log.Error(err))
 		// TODO: Can/should we expose this error with a callback?
-		return
+		return zero, nil, true, err
 	}
 
-	v.log.With(slog.Any("v", parsed)).Debug("Received new value from mqtt")
-	v.log.With(slog.Int("count", len(v.watchers))).Debug("Updating watchers")
+	v.log.With(log.Payload(topic, payload)).Debug("Received new value from mqtt")
 	v.v, v.initialized = parsed, true
-	for _, w := range v.watchers {
-		// TODO: Call in separate goroutine? Do something like signal.Notify?
-		w(v.v)
+	if v.lockFree {
+		storeLatest(&v.latest, parsed)
 	}
+
+	// Watchers may be added or removed while they are being called
+	return parsed, slices.Clone(v.watchers), true, nil
+}
+
+// Absolute marks this RemoteValue as having an absolute topic. The prefix provided to FullyQualifiedTopic and
+// AppendSubscribeOptions is ignored for absolute values, and ServeMQTT expects the full topic. This is useful when
+// bridging existing devices whose topics cannot be moved under a Component's topic prefix. It returns the RemoteValue
+// to allow chaining with NewRemoteValue. Like Value.Absolute, it must be called before the RemoteValue is used.
+func (v *RemoteValue[T]) Absolute() *RemoteValue[T] {
+	v.topic = TrimTopic(v.topic)
+	v.absolute = true
+	return v
+}
+
+// LockFree makes Get read the most recently received value without locking, so reads on hot paths do not contend with
+// messages being delivered. In exchange, every message received allocates a copy of the new value. It must be called
+// before the RemoteValue is used, and returns the RemoteValue to allow chaining with NewRemoteValue.
+func (v *RemoteValue[T]) LockFree() *RemoteValue[T] {
+	v.lockFree = true
+	return v
+}
+
+// Passive marks this RemoteValue as observing state published by another client (such as Home Assistant's status topic)
+// instead of receiving commands, so messages it receives do not fire hooks.Hooks.OnCommandReceived. It must be called
+// before the RemoteValue is used, and returns the RemoteValue to allow chaining with NewRemoteValue.
+func (v *RemoteValue[T]) Passive() *RemoteValue[T] {
+	v.passive = true
+	return v
 }
 
 // FullyQualifiedTopic calculates the MQTT Topic for this value when given the specified prefix. If the underlying
-// RemoteValue (not the value it holds) is nil, the empty string is returned.
+// RemoteValue (not the value it holds) is nil, the empty string is returned. If the RemoteValue is Absolute, the
+// prefix is ignored. The topic for the most recently used prefix is cached, so calling this for every message does not
+// allocate.
 func (v *RemoteValue[T]) FullyQualifiedTopic(prefix string) string {
 	if v == nil {
 		return ""
 	}
 
-	return JoinTopic(prefix, v.topic)
+	if v.absolute {
+		return v.topic
+	}
+
+	return v.topics.join(prefix, v.topic)
 }
 
 // AppendSubscribeOptions adds a paho.SubscribeOptions value to the slice of existing options if this RemoteValue is not
@@ -314,41 +570,119 @@ func (v *RemoteValue[T]) AppendSubscribeOptions(existing []Subscription, prefix
 	})
 }
 
+// AddRoute registers this RemoteValue in the provided Routes under its topic relative to a Component's topic prefix (or
+// its full topic if it is Absolute). It does nothing if this RemoteValue is nil, has no configured topic, or if another
+// Handler is already registered for the topic, so the first value added for a topic receives its messages. It returns
+// whether the route was added.
+func (v *RemoteValue[T]) AddRoute(routes Routes) bool {
+	if v == nil || v.topic == "" {
+		return false
+	}
+
+	topic := v.FullyQualifiedTopic("")
+	if _, ok := routes[topic]; ok {
+		return false
+	}
+
+	routes[topic] = v
+	return true
+}
+
 // Get returns the most recent value received from mqtt. If no value has been received yet, the second return value will
 // be false.
 func (v *RemoteValue[T]) Get() (T, bool) {
+	if v.lockFree {
+		return loadLatest(&v.latest)
+	}
+
 	v.mu.RLock()
 	defer v.mu.RUnlock()
 
 	return v.v, v.initialized
 }
 
+// LastReceived returns the value unmarshalled from the most recent message received from mqtt. Unlike Get, the second
+// return value is also false if the most recent message could not be unmarshalled, so code reacting to a message (such
+// as Echo) does not act on the previous value again.
+func (v *RemoteValue[T]) LastReceived() (T, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var zero T
+	if v.failed || !v.initialized {
+		return zero, false
+	}
+
+	return v.v, true
+}
+
+type watcher[T any] struct {
+	id       int
+	callback func(T)
+}
+
 // Watch registers a callback to execute when receiving new messages from mqtt. After receiving a new value from the
-// router, it calls all watchers serially using the new value. Watchers should not block, any long operations executed
-// in a watcher should start a new goroutine.
+// router, it calls all watchers serially in the order they were registered using the new value. Watchers should not
+// block, any long operations executed in a watcher should start a new goroutine. Watchers may call Get, Watch, and
+// Unwatch, but a watcher added or removed by another watcher only takes effect for the next value.
+//
+// The returned id can be passed to Unwatch to remove the callback, and remains valid when other watchers are removed.
+// If the RemoteValue is closed (see Close), the callback is not registered and the returned id is 0, which Unwatch
+// ignores.
 func (v *RemoteValue[T]) Watch(callback func(T)) int {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	v.log.Debug("Adding watcher")
+	if v.closed {
+		return 0
+	}
+
+	v.lastWatcherID++
+	v.log.With(slog.Int("id", v.lastWatcherID)).Debug("Adding watcher")
 
-	v.watchers = append(v.watchers, callback)
-	return len(v.watchers) - 1
+	v.watchers = append(v.watchers, watcher[T]{id: v.lastWatcherID, callback: callback})
+	return v.lastWatcherID
 }
 
-// Unwatch removes the specified callback from the watch list.
+// Unwatch removes the callback with the specified id from the watch list. It does nothing if the RemoteValue is closed,
+// since Close already removed every watcher.
 func (v *RemoteValue[T]) Unwatch(id int) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	if v.watchers == nil || id < 1 || id > len(v.watchers) {
+	if v.closed {
+		return
+	}
+
+	i := slices.IndexFunc(v.watchers, func(w watcher[T]) bool {
+		return w.id == id
+	})
+
+	if i < 0 {
 		v.log.With(slog.Int("id", id), slog.Int("count", len(v.watchers))).Warn("Tried to remove an invalid watcher")
 		return
 	}
 
 	v.log.With(slog.Int("id", id)).Debug("Removing watcher")
 
-	v.watchers = append(v.watchers[:id], v.watchers[id+1:]...)
+	v.watchers = slices.Delete(v.watchers, i, i+1)
+}
+
+// Echo writes the most recent value received by the provided RemoteValue to the provided Value. It does nothing if
+// either value is nil, if the RemoteValue has not yet received a value, or if the most recent message could not be
+// unmarshalled (see RemoteValue.LastReceived). This is typically used to mirror commands to state topics for platforms
+// configured in optimistic mode.
+func Echo[T any](ctx context.Context, w Writer, prefix string, command *RemoteValue[T], state *Value[T]) error {
+	if command == nil || state == nil {
+		return nil
+	}
+
+	v, ok := command.LastReceived()
+	if !ok {
+		return nil
+	}
+
+	return Error(state.Write(ctx, w, prefix, v))
 }
 
 // DesiredValue makes calling RemoteValue.Await on comparable remote values easier
@@ -359,7 +693,8 @@ func DesiredValue[T comparable](v T) func(T) bool {
 }
 
 // Await watches for updates to this RemoteValue. When updated values pass the desired filter, the updated value is
-// returned along with a nil error. Close the provided context to cancel. The watch is removed upon return.
+// returned along with a nil error. Close the provided context to cancel. If the RemoteValue is closed (see Close)
+// before a matching value is received, ErrClosed is returned. The watch is removed upon return.
 //
 // If the underlying type of this remote value is comparable, you can use DesiredValue to construct the check.
 //
@@ -369,15 +704,23 @@ func DesiredValue[T comparable](v T) func(T) bool {
 func (v *RemoteValue[T]) Await(ctx context.Context, desired func(T) bool) (T, error) {
 	done := make(chan struct{})
 
-	v.log.Debug("Awaiting value")
+	v.mu.Lock()
+	closing, _ := v.signals()
+	v.mu.Unlock()
+
+	v.log.DebugContext(ctx, "Awaiting value")
 
 	var got T
+	var once sync.Once
 	id := v.Watch(func(t T) {
 		if desired(t) {
-			v.log.Debug("Received expected value")
+			// Only the first matching value is returned, even if more arrive before the watch is removed
+			once.Do(func() {
+				v.log.DebugContext(ctx, "Received expected value")
 
-			got = t
-			close(done)
+				got = t
+				close(done)
+			})
 		}
 	})
 
@@ -388,8 +731,85 @@ func (v *RemoteValue[T]) Await(ctx context.Context, desired func(T) bool) (T, er
 	select {
 	case <-done:
 		return got, nil
+	case <-closing:
+		v.log.DebugContext(ctx, "Closed while waiting for value")
+
+		var zero T
+		return zero, ErrClosed
 	case <-ctx.Done():
-		v.log.Debug("Timeout waiting for value")
-		return got, context.Cause(ctx)
+		v.log.DebugContext(ctx, "Timeout waiting for value")
+
+		// got may be written concurrently by the watcher until it is removed
+		var zero T
+		return zero, context.Cause(ctx)
+	}
+}
+
+// Closer is implemented by RemoteValue so components can close all of their values without knowing their types.
+type Closer interface {
+	Close()
+	Done() <-chan struct{}
+}
+
+var _ Closer = (*RemoteValue[string])(nil)
+
+// Close stops this RemoteValue: watchers are removed, messages passed to ServeMQTT afterward are ignored, and every
+// pending Await returns ErrClosed. Watchers already being called for a message received before Close keep running,
+// the channel returned by Done is closed once they return. It is safe to call Close more than once, and from a watcher.
+// Close does not unsubscribe, see Component.Unsubscribe.
+func (v *RemoteValue[T]) Close() {
+	if v == nil {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.closed {
+		return
+	}
+
+	v.log.Debug("Closing")
+	closing, drained := v.signals()
+	v.closed = true
+	v.watchers = nil
+	close(closing)
+
+	// A watcher calling Close holds dispatchMu, so wait for the watchers in the background
+	go func() {
+		v.dispatchMu.Lock()
+		defer v.dispatchMu.Unlock()
+
+		close(drained)
+	}()
+}
+
+// Done returns a channel that is closed once Close was called and every watcher it did not interrupt has returned. A
+// nil RemoteValue is always done.
+func (v *RemoteValue[T]) Done() <-chan struct{} {
+	if v == nil {
+		return closedChannel
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, drained := v.signals()
+	return drained
+}
+
+// closedChannel is returned by Done for nil values.
+var closedChannel = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// signals returns the channels closed by Close, creating them if needed. v.mu must be held.
+func (v *RemoteValue[T]) signals() (closing, drained chan struct{}) {
+	if v.closing == nil {
+		v.closing, v.drained = make(chan struct{}), make(chan struct{})
 	}
+
+	return v.closing, v.drained
 }
@@ -0,0 +1,510 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type write struct {
+	topic   string
+	options WriteOptions
+	payload string
+}
+
+type capturingWriter struct {
+	writes []write
+}
+
+func (c *capturingWriter) WriteTopic(_ context.Context, topic string, options WriteOptions, value []byte) error {
+	c.writes = append(c.writes, write{topic: topic, options: options, payload: string(value)})
+	return nil
+}
+
+func TestEcho(t *testing.T) {
+	t.Run("nil values", func(t *testing.T) {
+		w := &capturingWriter{}
+
+		require.NoError(t, Echo[string](t.Context(), w, "prefix", nil, nil))
+		assert.Empty(t, w.writes)
+	})
+
+	t.Run("no command received", func(t *testing.T) {
+		w := &capturingWriter{}
+
+		command := NewRemoteValue("command", StringUnmarshaler)
+		state := NewValue("state", StringMarshaler)
+
+		require.NoError(t, Echo(t.Context(), w, "prefix", command, state))
+		assert.Empty(t, w.writes)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		w := &capturingWriter{}
+
+		command := NewRemoteValue("command", StringUnmarshaler)
+		state := NewValueWithOptions("state", StringMarshaler, WriteOptions{Retain: true})
+
+		command.ServeMQTT(w, "command", []byte("foo"))
+
+		require.NoError(t, Echo(t.Context(), w, "prefix", command, state))
+		require.Equal(t, []write{{topic: "prefix/state", options: WriteOptions{Retain: true}, payload: "foo"}}, w.writes)
+
+		v, ok := state.Get()
+		assert.True(t, ok)
+		assert.Equal(t, "foo", v)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		w := &capturingWriter{}
+
+		command := NewRemoteValue("command", UintUnmarshaler)
+		state := NewValue("state", UintMarshaler)
+
+		command.ServeMQTT(w, "command", []byte("1"))
+		require.NoError(t, Echo(t.Context(), w, "prefix", command, state))
+		require.Len(t, w.writes, 1)
+
+		// The previous command must not be echoed again for a payload that could not be unmarshalled
+		command.ServeMQTT(w, "command", []byte("garbage"))
+		require.NoError(t, Echo(t.Context(), w, "prefix", command, state))
+		assert.Len(t, w.writes, 1)
+
+		v, ok := command.Get()
+		assert.True(t, ok)
+		assert.Equal(t, uint(1), v)
+	})
+}
+
+func TestAbsolute(t *testing.T) {
+	t.Run("Value", func(t *testing.T) {
+		w := &capturingWriter{}
+		sut := NewValue("/zigbee2mqtt/foo/", StringMarshaler).Absolute()
+
+		require.Equal(t, "zigbee2mqtt/foo", sut.FullyQualifiedTopic("prefix"))
+
+		_, err := sut.Write(t.Context(), w, "prefix", "bar")
+		require.NoError(t, err)
+		require.Equal(t, []write{{topic: "zigbee2mqtt/foo", payload: "bar"}}, w.writes)
+	})
+
+	t.Run("RemoteValue", func(t *testing.T) {
+		sut := NewRemoteValue("/zigbee2mqtt/foo/set", StringUnmarshaler).Absolute()
+
+		require.Equal(t, "zigbee2mqtt/foo/set", sut.FullyQualifiedTopic("prefix"))
+		require.Equal(t, []Subscription{{Topic: "zigbee2mqtt/foo/set"}}, sut.AppendSubscribeOptions(nil, "prefix"))
+
+		sut.ServeMQTT(nil, "zigbee2mqtt/foo/set", []byte("bar"))
+		v, ok := sut.Get()
+		assert.True(t, ok)
+		assert.Equal(t, "bar", v)
+	})
+}
+
+func TestRemoteValue_Unwatch(t *testing.T) {
+	sut := NewRemoteValue("command", StringUnmarshaler)
+
+	var got []string
+	first := sut.Watch(func(s string) {
+		got = append(got, "first:"+s)
+	})
+	second := sut.Watch(func(s string) {
+		got = append(got, "second:"+s)
+	})
+	sut.Watch(func(s string) {
+		got = append(got, "third:"+s)
+	})
+
+	// Removing a watcher must not change the id of watchers registered after it
+	sut.Unwatch(first)
+	sut.Unwatch(second)
+	sut.Unwatch(second)
+
+	sut.ServeMQTT(nil, "command", []byte("foo"))
+	assert.Equal(t, []string{"third:foo"}, got)
+}
+
+func TestRemoteValue_ServeMQTT_ReentrantWatcher(t *testing.T) {
+	sut := NewRemoteValue("command", StringUnmarshaler)
+
+	var got []string
+	var id int
+	id = sut.Watch(func(s string) {
+		// Watchers are called without holding the lock, so they can read the value and manage watchers
+		v, ok := sut.Get()
+		assert.True(t, ok)
+		assert.Equal(t, s, v)
+
+		sut.Unwatch(id)
+		sut.Watch(func(s string) {
+			got = append(got, "added:"+s)
+		})
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		sut.ServeMQTT(nil, "command", []byte("foo"))
+		sut.ServeMQTT(nil, "command", []byte("bar"))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "ServeMQTT deadlocked calling a reentrant watcher")
+	}
+
+	// Watchers added by a watcher are only called for the next value
+	assert.Equal(t, []string{"added:bar"}, got)
+}
+
+func TestRemoteValue_Close(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		var sut *RemoteValue[string]
+		sut.Close()
+
+		select {
+		case <-sut.Done():
+		default:
+			require.FailNow(t, "nil RemoteValue is not done")
+		}
+	})
+
+	t.Run("Await", func(t *testing.T) {
+		sut := NewRemoteValue("command", StringUnmarshaler)
+
+		errs := make(chan error)
+		go func() {
+			_, err := sut.Await(t.Context(), DesiredValue("foo"))
+			errs <- err
+		}()
+
+		// Wait for Await to register its watcher
+		require.Eventually(t, func() bool {
+			sut.mu.RLock()
+			defer sut.mu.RUnlock()
+
+			return len(sut.watchers) == 1
+		}, 5*time.Second, time.Millisecond)
+
+		sut.Close()
+		sut.Close()
+		require.ErrorIs(t, <-errs, ErrClosed)
+
+		_, err := sut.Await(t.Context(), DesiredValue("foo"))
+		require.ErrorIs(t, err, ErrClosed)
+	})
+
+	t.Run("Ignores Messages", func(t *testing.T) {
+		sut := NewRemoteValue("command", StringUnmarshaler)
+
+		var got []string
+		sut.Watch(func(s string) {
+			got = append(got, s)
+		})
+
+		sut.ServeMQTT(nil, "command", []byte("foo"))
+		sut.Close()
+		sut.ServeMQTT(nil, "command", []byte("bar"))
+
+		v, _ := sut.Get()
+		assert.Equal(t, "foo", v)
+		assert.Equal(t, []string{"foo"}, got)
+		<-sut.Done()
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		sut := NewRemoteValue("command", StringUnmarshaler)
+		id := sut.Watch(func(string) {})
+		sut.Close()
+
+		assert.Zero(t, sut.Watch(func(string) {}))
+		assert.Empty(t, sut.watchers)
+
+		sut.Unwatch(id)
+		sut.Unwatch(0)
+	})
+
+	t.Run("Waits For Watchers", func(t *testing.T) {
+		sut := NewRemoteValue("command", StringUnmarshaler)
+
+		running, release := make(chan struct{}), make(chan struct{})
+		sut.Watch(func(string) {
+			close(running)
+			<-release
+		})
+
+		go sut.ServeMQTT(nil, "command", []byte("foo"))
+		<-running
+
+		sut.Close()
+		select {
+		case <-sut.Done():
+			require.FailNow(t, "RemoteValue is done while a watcher is running")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		close(release)
+		select {
+		case <-sut.Done():
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "RemoteValue is not done after its watchers returned")
+		}
+	})
+
+	t.Run("From Watcher", func(t *testing.T) {
+		sut := NewRemoteValue("command", StringUnmarshaler)
+		sut.Watch(func(string) {
+			sut.Close()
+		})
+
+		sut.ServeMQTT(nil, "command", []byte("foo"))
+		select {
+		case <-sut.Done():
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "RemoteValue closed by a watcher is not done")
+		}
+	})
+}
+
+func TestRemoteValue_AddRoute(t *testing.T) {
+	routes := Routes{}
+
+	var nilValue *RemoteValue[string]
+	assert.False(t, nilValue.AddRoute(routes))
+	assert.False(t, NewRemoteValue("", StringUnmarshaler).AddRoute(routes))
+
+	first := NewRemoteValue("command", StringUnmarshaler)
+	second := NewRemoteValue("command", StringUnmarshaler)
+	absolute := NewRemoteValue("/other/device/set", StringUnmarshaler).Absolute()
+	assert.True(t, first.AddRoute(routes))
+	assert.False(t, second.AddRoute(routes))
+	assert.True(t, absolute.AddRoute(routes))
+
+	routes.ServeMQTT(nil, "command", []byte("foo"))
+	routes.ServeMQTT(nil, "other/device/set", []byte("bar"))
+	routes.ServeMQTT(nil, "unknown", []byte("baz"))
+
+	v, ok := first.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "foo", v)
+
+	_, ok = second.Get()
+	assert.False(t, ok)
+
+	v, ok = absolute.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "bar", v)
+}
+
+func TestLockFree(t *testing.T) {
+	t.Run("Value", func(t *testing.T) {
+		sut := NewValue("state", StringMarshaler).LockFree()
+
+		_, ok := sut.Get()
+		assert.False(t, ok)
+
+		_, err := sut.Republish(t.Context(), &capturingWriter{}, "prefix")
+		require.ErrorIs(t, err, ErrNeverWritten)
+
+		w := &capturingWriter{}
+		require.NoError(t, Error(sut.Write(t.Context(), w, "prefix", "foo")))
+		require.NoError(t, Error(sut.Write(t.Context(), w, "prefix", "bar")))
+
+		v, ok := sut.Get()
+		assert.True(t, ok)
+		assert.Equal(t, "bar", v)
+	})
+
+	t.Run("RemoteValue", func(t *testing.T) {
+		sut := NewRemoteValue("command", StringUnmarshaler).LockFree()
+
+		_, ok := sut.Get()
+		assert.False(t, ok)
+
+		var watched string
+		sut.Watch(func(string) {
+			// The new value is visible to watchers
+			watched, _ = sut.Get()
+		})
+
+		sut.ServeMQTT(nil, "command", []byte("foo"))
+
+		v, ok := sut.Get()
+		assert.True(t, ok)
+		assert.Equal(t, "foo", v)
+		assert.Equal(t, "foo", watched)
+	})
+}
+
+func TestNewStreamingValue(t *testing.T) {
+	type attributes struct {
+		Name   string            `json:"name"`
+		Extra  map[string]string `json:"extra,omitempty"`
+		Blob   []byte            `json:"blob"`
+		Markup string            `json:"markup"`
+	}
+
+	t.Run("Json", func(t *testing.T) {
+		w := &capturingWriter{}
+		sut := NewStreamingValue("attributes", JsonValueMarshalerTo[attributes]())
+
+		values := []attributes{
+			{Name: "lamp", Extra: map[string]string{"b": "2", "a": "1"}, Blob: []byte{0xff, 0x00}, Markup: "<b>&</b>"},
+			// Shorter than the previous value, so stale bytes in the reused buffer would show up
+			{Name: "x"},
+		}
+
+		for _, v := range values {
+			require.NoError(t, Error(sut.Write(t.Context(), w, "prefix", v)))
+		}
+
+		require.Len(t, w.writes, len(values))
+		for i, v := range values {
+			want, err := JsonValueMarshaler[attributes]()(v)
+			require.NoError(t, err)
+
+			assert.Equal(t, "prefix/attributes", w.writes[i].topic)
+			assert.Equal(t, string(want), w.writes[i].payload, "streamed payload should match JsonValueMarshaler")
+		}
+
+		got, ok := sut.Get()
+		assert.True(t, ok)
+		assert.Equal(t, values[1], got)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		sut := NewStreamingValue("state", func(w io.Writer, v string) error {
+			if strings.HasPrefix(v, "bad") {
+				_, _ = io.WriteString(w, "partial")
+				return errBoom
+			}
+
+			_, err := io.WriteString(w, v)
+			return err
+		})
+
+		w := &capturingWriter{}
+		require.NoError(t, Error(sut.Write(t.Context(), w, "prefix", "good")))
+
+		current, err := sut.Write(t.Context(), w, "prefix", "bad")
+		require.ErrorIs(t, err, errBoom)
+		assert.Equal(t, "good", current)
+
+		// Partially written payloads are discarded instead of published
+		require.NoError(t, Error(sut.Write(t.Context(), w, "prefix", "ok")))
+		require.Len(t, w.writes, 2)
+		assert.Equal(t, "good", w.writes[0].payload)
+		assert.Equal(t, "ok", w.writes[1].payload)
+	})
+}
+
+// blockingWriter blocks every write until release is closed.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingWriter) WriteTopic(context.Context, string, WriteOptions, []byte) error {
+	close(b.started)
+	<-b.release
+	return nil
+}
+
+func TestValue_Write_DoesNotBlockGet(t *testing.T) {
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	sut := NewValue("state", StringMarshaler)
+
+	done := make(chan error)
+	go func() {
+		done <- Error(sut.Write(t.Context(), w, "prefix", "foo"))
+	}()
+
+	<-w.started
+
+	// The held value is updated before publishing, and reading it does not wait for the broker
+	v, ok := sut.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "foo", v)
+
+	close(w.release)
+	require.NoError(t, <-done)
+}
+
+func TestValue_Inspect(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		var v *Value[string]
+		assert.Equal(t, Inspection{}, v.Inspect())
+
+		var rv *RemoteValue[string]
+		assert.Equal(t, Inspection{}, rv.Inspect())
+	})
+
+	t.Run("Value", func(t *testing.T) {
+		sut := NewValue("state", FloatMarshaler)
+		assert.Equal(t, Inspection{}, sut.Inspect())
+
+		_, err := sut.Write(t.Context(), &capturingWriter{}, "foo", 1.5)
+		require.NoError(t, err)
+		assert.Equal(t, Inspection{Value: 1.5, Set: true}, sut.Inspect())
+
+		// Marshal errors keep the previous value
+		_, err = sut.Write(t.Context(), &capturingWriter{}, "foo", math.NaN())
+		require.ErrorIs(t, err, ErrNotFinite)
+		assert.Equal(t, Inspection{Value: 1.5, Set: true, Errors: 1}, sut.Inspect())
+
+		boom := errors.New("boom")
+		_, err = sut.Write(t.Context(), errWriter{err: boom}, "foo", 2)
+		require.ErrorIs(t, err, boom)
+		assert.Equal(t, Inspection{Value: 2.0, Set: true, Errors: 2}, sut.Inspect())
+	})
+
+	t.Run("RemoteValue", func(t *testing.T) {
+		sut := NewRemoteValue("command", UintUnmarshaler)
+		assert.Equal(t, Inspection{}, sut.Inspect())
+
+		sut.ServeMQTT(nil, "command", []byte("42"))
+		assert.Equal(t, Inspection{Value: uint(42), Set: true}, sut.Inspect())
+
+		sut.ServeMQTT(nil, "command", []byte("nope"))
+		assert.Equal(t, Inspection{Value: uint(42), Set: true, Errors: 1}, sut.Inspect())
+	})
+}
+
+func TestValue_Will(t *testing.T) {
+	t.Run("Marshals", func(t *testing.T) {
+		opts := WriteOptions{QoS: QOSAtLeastOnce, Retain: true}
+		sut := NewValueWithOptions("availability", StringMarshaler, opts)
+
+		will, err := sut.Will("prefix", "offline")
+		require.NoError(t, err)
+		assert.Equal(t, Will{Topic: "prefix/availability", Payload: []byte("offline"), Options: opts}, will)
+
+		_, initialized := sut.Get()
+		assert.False(t, initialized, "Will should not change the held value")
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		sut := NewStreamingValue("availability", JsonValueMarshalerTo[string]())
+
+		will, err := sut.Will("prefix", "offline")
+		require.NoError(t, err)
+		assert.Equal(t, `"offline"`, string(will.Payload))
+	})
+
+	t.Run("NoMarshaler", func(t *testing.T) {
+		sut := &Value[string]{topic: "availability"}
+
+		_, err := sut.Will("prefix", "offline")
+		require.ErrorIs(t, err, ErrNoMarshaler)
+	})
+}
@@ -0,0 +1,43 @@
+package mqtt
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+)
+
+// Will is a message the broker publishes on behalf of a client when its connection drops without disconnecting
+// cleanly, also known as the client's Last Will and Testament. It implements slog.LogValuer.
+type Will struct {
+	Topic   string
+	Payload []byte
+	Options WriteOptions
+}
+
+func (w Will) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("topic", w.Topic),
+		slog.String("payload", string(w.Payload)),
+		slog.Any("options", w.Options),
+	)
+}
+
+// Will returns a Will that publishes value to the topic of this Value for the specified prefix, using the configured
+// marshaler and WriteOptions. The held value is not changed.
+func (v *Value[T]) Will(prefix string, value T) (Will, error) {
+	if v.marshaler == nil && v.marshalerTo == nil {
+		return Will{}, ErrNoMarshaler
+	}
+
+	data, buf, err := v.marshal(value)
+	defer releaseBuffer(buf)
+	if err != nil {
+		return Will{}, fmt.Errorf("marshal %+v: %w", value, err)
+	}
+
+	return Will{
+		Topic:   v.FullyQualifiedTopic(prefix),
+		Payload: bytes.Clone(data),
+		Options: v.opts,
+	}, nil
+}
@@ -1,6 +1,7 @@
 package hqtt
 
 import (
+	"context"
 	"encoding/json/jsontext"
 
 	"github.com/nlowe/hqtt/mqtt"
@@ -23,3 +24,14 @@ type Platform interface {
 	// mqtt.Subscriber implementations may choose to group topics with wildcards.
 	Subscriptions(prefix string) []mqtt.Subscription
 }
+
+// OptimisticPlatform is implemented by Platform types that support Home Assistant's optimistic mode. When a platform is
+// optimistic, Home Assistant assumes commands are applied immediately. Component will call EchoCommand after routing
+// each message to the platform so state topics always reflect what Home Assistant assumes.
+type OptimisticPlatform interface {
+	Platform
+
+	// EchoCommand mirrors the command received on the specified topic to the corresponding state value if the platform
+	// is configured in optimistic mode. The topic is relative to prefix, as it is for ServeMQTT.
+	EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error
+}
@@ -0,0 +1,102 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Special values for AlarmControlPanel.Code that let the user enter a code in Home Assistant instead of validating it
+// against a fixed code. Use AlarmControlPanel.CommandTemplate to include the entered code in the command so the
+// device can validate it.
+const (
+	// The user enters a numeric code
+	AlarmControlPanelCodeRemote = "REMOTE_CODE"
+	// The user enters an alphanumeric code
+	AlarmControlPanelCodeRemoteText = "REMOTE_CODE_TEXT"
+)
+
+// AlarmControlPanelFeature is a feature an AlarmControlPanel supports. See
+// https://www.home-assistant.io/integrations/alarm_control_panel.mqtt/#supported_features.
+type AlarmControlPanelFeature string
+
+const (
+	AlarmControlPanelFeatureArmHome         AlarmControlPanelFeature = "arm_home"
+	AlarmControlPanelFeatureArmAway         AlarmControlPanelFeature = "arm_away"
+	AlarmControlPanelFeatureArmNight        AlarmControlPanelFeature = "arm_night"
+	AlarmControlPanelFeatureArmVacation     AlarmControlPanelFeature = "arm_vacation"
+	AlarmControlPanelFeatureArmCustomBypass AlarmControlPanelFeature = "arm_custom_bypass"
+	AlarmControlPanelFeatureTrigger         AlarmControlPanelFeature = "trigger"
+)
+
+// AlarmControlPanel is a hqtt.Platform that implements the alarm_control_panel.mqtt integration for Home Assistant.
+//
+// See https://www.home-assistant.io/integrations/alarm_control_panel.mqtt/
+type AlarmControlPanel struct {
+	// The current state of the alarm
+	State *mqtt.Value[hass.AlarmState] `hqtt:"required"`
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+	// Home Assistant will write commands for this entity to this value
+	Command *mqtt.RemoteValue[hass.AlarmCommand] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The command is available as action, and the code entered
+	// by the user as code.
+	CommandTemplate hass.Template
+
+	// The code Home Assistant validates before sending a command, or one of AlarmControlPanelCodeRemote and
+	// AlarmControlPanelCodeRemoteText to let the device validate it. If not set, no code is requested.
+	Code string
+	// Whether a code is required to arm the alarm. Defaults to true if nil.
+	CodeArmRequired *bool
+	// Whether a code is required to disarm the alarm. Defaults to true if nil.
+	CodeDisarmRequired *bool
+	// Whether a code is required to trigger the alarm. Defaults to true if nil.
+	CodeTriggerRequired *bool
+
+	// The features this alarm control panel supports. Home Assistant assumes all features are supported if not set.
+	SupportedFeatures []AlarmControlPanelFeature
+
+	// Custom values to use for payload commands
+	CustomCommandValues hass.CustomAlarmCommand
+}
+
+func (a *AlarmControlPanel) PlatformName() string {
+	return "alarm_control_panel"
+}
+
+func (a *AlarmControlPanel) Subscriptions(prefix string) []mqtt.Subscription {
+	return a.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (a *AlarmControlPanel) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	a.Command.ServeMQTT(w, topic, payload)
+}
+
+func (a *AlarmControlPanel) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalRequiredValueTopic("state", e, discovery.FieldStateTopic, a.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, a.ValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, a.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, a.CommandTemplate),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCode, a.Code),
+		discovery.MaybeMarshalStd(e, discovery.FieldCodeArmRequired, a.CodeArmRequired),
+		discovery.MaybeMarshalStd(e, discovery.FieldCodeDisarmRequired, a.CodeDisarmRequired),
+		discovery.MaybeMarshalStd(e, discovery.FieldCodeTriggerRequired, a.CodeTriggerRequired),
+
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldSupportedFeatures, a.SupportedFeatures),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadArmHome, a.CustomCommandValues.ArmHome),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadArmAway, a.CustomCommandValues.ArmAway),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadArmNight, a.CustomCommandValues.ArmNight),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadArmVacation, a.CustomCommandValues.ArmVacation),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadArmCustomBypass, a.CustomCommandValues.ArmCustomBypass),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadDisarm, a.CustomCommandValues.Disarm),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadTrigger, a.CustomCommandValues.Trigger),
+	)
+}
@@ -0,0 +1,62 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newAlarmControlPanel() *platform.AlarmControlPanel {
+	return &platform.AlarmControlPanel{
+		State:   mqtt.NewValue("state", hass.AlarmStateMarshaler),
+		Command: mqtt.NewRemoteValue("command", hass.AlarmCommandUnmarshaler),
+	}
+}
+
+func TestAlarmControlPanel_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newAlarmControlPanel(), "alarm_control_panel")
+}
+
+func TestAlarmControlPanel_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("State Required", func(t *testing.T) {
+		sut := newAlarmControlPanel()
+		sut.State = nil
+
+		_, err := marshalDiscovery(t, sut, "alarm")
+		require.Error(t, err)
+	})
+
+	t.Run("Command Required", func(t *testing.T) {
+		sut := newAlarmControlPanel()
+		sut.Command = nil
+
+		_, err := marshalDiscovery(t, sut, "alarm")
+		require.Error(t, err)
+	})
+
+	notRequired := false
+	sut := newAlarmControlPanel()
+	sut.Code = platform.AlarmControlPanelCodeRemote
+	sut.CodeArmRequired = &notRequired
+	sut.SupportedFeatures = []platform.AlarmControlPanelFeature{
+		platform.AlarmControlPanelFeatureArmHome, platform.AlarmControlPanelFeatureArmAway,
+	}
+	sut.CustomCommandValues = hass.CustomAlarmCommand{Disarm: "OFF"}
+
+	payload, err := marshalDiscovery(t, sut, "alarm")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "alarm/state")
+	hqtttest.RequireField(t, payload, "command_topic", "alarm/command")
+	hqtttest.RequireField(t, payload, "code", "REMOTE_CODE")
+	hqtttest.RequireField(t, payload, "code_arm_required", false)
+	hqtttest.RequireNoField(t, payload, "code_disarm_required")
+	hqtttest.RequireField(t, payload, "supported_features", []string{"arm_home", "arm_away"})
+	hqtttest.RequireField(t, payload, "payload_disarm", "OFF")
+	hqtttest.RequireNoField(t, payload, "payload_arm_home")
+}
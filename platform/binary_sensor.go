@@ -1,15 +1,32 @@
 package platform
 
 import (
+	"context"
 	"encoding/json/jsontext"
 	"errors"
+	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/nlowe/hqtt/clock"
 	"github.com/nlowe/hqtt/discovery"
 	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/log"
 	"github.com/nlowe/hqtt/mqtt"
 )
 
+var binarySensorLog = log.ForComponent("platform.binary_sensor")
+
+// Common device classes for BinarySensor. See https://www.home-assistant.io/integrations/binary_sensor/#device-class for
+// the full list.
+const (
+	BinarySensorDeviceClassConnectivity = "connectivity"
+	BinarySensorDeviceClassMotion       = "motion"
+	BinarySensorDeviceClassOccupancy    = "occupancy"
+	BinarySensorDeviceClassProblem      = "problem"
+	BinarySensorDeviceClassRunning      = "running"
+)
+
 // BinarySensor is a Sensor that uses hass.PowerState for its state type (i.e. hass.PowerStateOn or hass.PowerStateOff).
 //
 // See Sensor for details about state attributes, and https://www.home-assistant.io/integrations/binary_sensor.mqtt/ for
@@ -18,8 +35,54 @@ type BinarySensor[TAttributes any] struct {
 	Sensor[hass.PowerState, TAttributes]
 
 	// For sensors that only send on state updates (like PIRs), this variable sets a delay in seconds after which the
-	// sensor’s state will be updated back to off by Home Assistant.
+	// sensor’s state will be updated back to off by Home Assistant. See Trigger to emulate this in the published
+	// state.
 	OffDelay time.Duration
+
+	mu       sync.Mutex
+	offTimer clock.Timer
+	triggers uint64
+}
+
+// Trigger writes hass.PowerStateOn to State. If OffDelay is set, it then writes hass.PowerStateOff once OffDelay
+// elapses without the sensor being triggered again, emulating the off_delay behavior of Home Assistant so the state
+// published to MQTT (and the value returned by State.Get) agrees with the state shown in Home Assistant. Errors writing
+// the off state are logged. The Clock of the embedded Sensor is used to schedule the off state.
+func (s *BinarySensor[TAttributes]) Trigger(ctx context.Context, w mqtt.Writer, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := mqtt.Error(s.State.Write(ctx, w, prefix, hass.PowerStateOn)); err != nil {
+		return err
+	}
+
+	if s.OffDelay <= 0 {
+		return nil
+	}
+
+	if s.offTimer != nil {
+		s.offTimer.Stop()
+	}
+
+	s.triggers++
+	trigger := s.triggers
+
+	ctx = context.WithoutCancel(ctx)
+	s.offTimer = clock.Or(s.Clock).AfterFunc(s.OffDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// The sensor was triggered again after the timer fired but before the lock was acquired
+		if trigger != s.triggers {
+			return
+		}
+
+		if err := mqtt.Error(s.State.Write(ctx, w, prefix, hass.PowerStateOff)); err != nil {
+			binarySensorLog.With(slog.String("topic", s.State.FullyQualifiedTopic(prefix)), log.Error(err)).WarnContext(ctx, "Failed to write off state after delay")
+		}
+	})
+
+	return nil
 }
 
 func (s *BinarySensor[TAttributes]) PlatformName() string {
@@ -0,0 +1,46 @@
+package platform_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func TestBinarySensor_Trigger(t *testing.T) {
+	c := hqtttest.NewClock(time.Now())
+	w := &hqtttest.Writer{}
+
+	sut := platform.NewBinarySensor[any](mqtt.NewValue("state", hass.PowerStateMarshaler), nil)
+	sut.Clock = c
+	sut.OffDelay = time.Minute
+
+	require.NoError(t, sut.Trigger(t.Context(), w, "motion"))
+	w.AssertPublished(t, "motion/state", []byte(hass.PowerStateOn))
+
+	// Triggering again restarts the delay
+	c.Advance(45 * time.Second)
+	require.NoError(t, sut.Trigger(t.Context(), w, "motion"))
+	c.Advance(45 * time.Second)
+
+	state, _ := sut.State.Get()
+	assert.Equal(t, hass.PowerStateOn, state)
+
+	c.Advance(15 * time.Second)
+	w.AssertPublished(t, "motion/state", []byte(hass.PowerStateOff))
+	assert.Len(t, w.Messages(), 3)
+
+	t.Run("No Delay", func(t *testing.T) {
+		sut.OffDelay = 0
+		require.NoError(t, sut.Trigger(t.Context(), w, "motion"))
+
+		c.Advance(time.Hour)
+		w.AssertPublished(t, "motion/state", []byte(hass.PowerStateOn))
+	})
+}
@@ -0,0 +1,61 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Device classes for Button. See https://www.home-assistant.io/integrations/button/#device-class.
+const (
+	ButtonDeviceClassIdentify = "identify"
+	ButtonDeviceClassRestart  = "restart"
+	ButtonDeviceClassUpdate   = "update"
+)
+
+// DefaultButtonPayloadPress is the payload Home Assistant writes to Button.Command when PayloadPress is not set.
+const DefaultButtonPayloadPress = "PRESS"
+
+// Button is a hqtt.Platform that implements the button.mqtt integration for Home Assistant. Buttons have no state,
+// use mqtt.RemoteValue.Watch on Command to act on presses. Watchers are called for every press, even though the
+// payload does not change.
+//
+// See https://www.home-assistant.io/integrations/button.mqtt/
+type Button struct {
+	// The type/class of the button to set the icon in the frontend, see the ButtonDeviceClass constants.
+	DeviceClass string
+
+	// Home Assistant will write PayloadPress to this value when the button is pressed
+	Command *mqtt.RemoteValue[string] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The payload is available as value.
+	CommandTemplate hass.Template
+	// The payload Home Assistant writes to Command when the button is pressed. Defaults to DefaultButtonPayloadPress
+	// if not set.
+	PayloadPress string
+}
+
+func (b *Button) PlatformName() string {
+	return "button"
+}
+
+func (b *Button) Subscriptions(prefix string) []mqtt.Subscription {
+	return b.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (b *Button) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	b.Command.ServeMQTT(w, topic, payload)
+}
+
+func (b *Button) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, b.DeviceClass),
+
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, b.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, b.CommandTemplate),
+		discovery.MarshalStdIfNot(DefaultButtonPayloadPress, e, discovery.FieldPayloadPress, b.PayloadPress),
+	)
+}
@@ -0,0 +1,56 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newButton() *platform.Button {
+	return &platform.Button{Command: mqtt.NewRemoteValue("press", mqtt.StringUnmarshaler)}
+}
+
+func TestButton_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newButton(), "button")
+}
+
+func TestButton_Press(t *testing.T) {
+	sut := newButton()
+
+	var presses int
+	sut.Command.Watch(func(string) {
+		presses++
+	})
+
+	w := &hqtttest.Writer{}
+	sut.ServeMQTT(w, "press", []byte("PRESS"))
+	sut.ServeMQTT(w, "press", []byte("PRESS"))
+	assert.Equal(t, 2, presses)
+}
+
+func TestButton_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Button{}, "button")
+		require.Error(t, err)
+	})
+
+	sut := newButton()
+	sut.DeviceClass = platform.ButtonDeviceClassRestart
+
+	payload, err := marshalDiscovery(t, sut, "button")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "command_topic", "button/press")
+	hqtttest.RequireField(t, payload, "device_class", "restart")
+	hqtttest.RequireNoField(t, payload, "payload_press")
+
+	sut.PayloadPress = "reboot"
+	payload, err = marshalDiscovery(t, sut, "button")
+	require.NoError(t, err)
+	hqtttest.RequireField(t, payload, "payload_press", "reboot")
+}
@@ -0,0 +1,42 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// CameraImageEncodingBase64 tells Home Assistant that images published to Camera.Image are base64 encoded, see
+// mqtt.Base64Marshaler.
+const CameraImageEncodingBase64 = "b64"
+
+// Camera is a hqtt.Platform that implements the camera.mqtt integration for Home Assistant. Publish images to Image,
+// either as-is with mqtt.BytesMarshaler or base64 encoded with mqtt.Base64Marshaler and CameraImageEncodingBase64.
+// Images are usually large, consider constructing Image with mqtt.NewStreamingValue and mqtt.Base64MarshalerTo.
+//
+// See https://www.home-assistant.io/integrations/camera.mqtt/
+type Camera struct {
+	// The latest image from the camera
+	Image *mqtt.Value[[]byte] `hqtt:"required"`
+	// How images published to Image are encoded. If not set, Home Assistant expects raw image bytes.
+	ImageEncoding string
+}
+
+func (c *Camera) PlatformName() string {
+	return "camera"
+}
+
+func (c *Camera) Subscriptions(_ string) []mqtt.Subscription {
+	return nil
+}
+
+func (c *Camera) ServeMQTT(_ mqtt.Writer, _ string, _ []byte) {}
+
+func (c *Camera) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalRequiredValueTopic("image", e, discovery.FieldTopic, c.Image, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldImageEncoding, c.ImageEncoding),
+	)
+}
@@ -0,0 +1,47 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newCamera() *platform.Camera {
+	return &platform.Camera{
+		Image:         mqtt.NewStreamingValue("image", mqtt.Base64MarshalerTo),
+		ImageEncoding: platform.CameraImageEncodingBase64,
+	}
+}
+
+func TestCamera_Image(t *testing.T) {
+	sut := newCamera()
+
+	w := &hqtttest.Writer{}
+	_, err := sut.Image.Write(t.Context(), w, "camera", []byte{0xff, 0xd8, 0xff})
+	require.NoError(t, err)
+
+	w.AssertPublished(t, "camera/image", []byte("/9j/"))
+}
+
+func TestCamera_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Image Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Camera{}, "camera")
+		require.Error(t, err)
+	})
+
+	payload, err := marshalDiscovery(t, newCamera(), "camera")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "topic", "camera/image")
+	hqtttest.RequireField(t, payload, "image_encoding", "b64")
+
+	payload, err = marshalDiscovery(t, &platform.Camera{Image: mqtt.NewValue("image", mqtt.BytesMarshaler)}, "camera")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "topic", "camera/image")
+	hqtttest.RequireNoField(t, payload, "image_encoding")
+}
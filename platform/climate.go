@@ -0,0 +1,260 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Temperature units for Climate. See https://www.home-assistant.io/integrations/climate.mqtt/#temperature_unit.
+const (
+	ClimateTemperatureUnitCelsius    = "C"
+	ClimateTemperatureUnitFahrenheit = "F"
+)
+
+// Climate is a hqtt.Platform that implements the climate.mqtt integration for Home Assistant, for HVAC devices like
+// thermostats.
+//
+// See https://www.home-assistant.io/integrations/climate.mqtt/
+type Climate struct {
+	// Flag that defines if the climate device works in optimistic mode. When set, commands received from Home
+	// Assistant are automatically written to the corresponding state value.
+	Optimistic bool
+
+	// What the device is currently doing
+	Action *mqtt.Value[hass.HVACAction]
+	// Extracts the action from messages published to Action
+	ActionValueTemplate hass.Template
+
+	// The current temperature measured by the device
+	CurrentTemperature *mqtt.Value[float64]
+	// Extracts the temperature from messages published to CurrentTemperature
+	CurrentTemperatureValueTemplate hass.Template
+	// The current humidity measured by the device
+	CurrentHumidity *mqtt.Value[float64]
+	// Extracts the humidity from messages published to CurrentHumidity
+	CurrentHumidityValueTemplate hass.Template
+
+	// The current operation mode of the device
+	Mode *mqtt.Value[hass.HVACMode]
+	// Extracts the mode from messages published to Mode
+	ModeValueTemplate hass.Template
+	// Home Assistant will write the desired mode to this value
+	ModeCommand *mqtt.RemoteValue[hass.HVACMode]
+	// Renders the payload Home Assistant writes to ModeCommand. The mode is available as value.
+	ModeCommandTemplate hass.Template
+	// The list of modes this device supports. Home Assistant assumes all modes are supported if not set.
+	Modes []hass.HVACMode
+
+	// The target temperature
+	TargetTemperature *mqtt.Value[float64]
+	// Extracts the target temperature from messages published to TargetTemperature
+	TargetTemperatureValueTemplate hass.Template
+	// Home Assistant will write the desired target temperature to this value
+	TargetTemperatureCommand *mqtt.RemoteValue[float64]
+	// Renders the payload Home Assistant writes to TargetTemperatureCommand. The temperature is available as value.
+	TargetTemperatureCommandTemplate hass.Template
+
+	// The upper bound of the target temperature range, used in hass.HVACModeHeatCool
+	TargetTemperatureHigh *mqtt.Value[float64]
+	// Extracts the temperature from messages published to TargetTemperatureHigh
+	TargetTemperatureHighValueTemplate hass.Template
+	// Home Assistant will write the desired upper bound of the target temperature range to this value
+	TargetTemperatureHighCommand *mqtt.RemoteValue[float64]
+	// Renders the payload Home Assistant writes to TargetTemperatureHighCommand. The temperature is available as value.
+	TargetTemperatureHighCommandTemplate hass.Template
+
+	// The lower bound of the target temperature range, used in hass.HVACModeHeatCool
+	TargetTemperatureLow *mqtt.Value[float64]
+	// Extracts the temperature from messages published to TargetTemperatureLow
+	TargetTemperatureLowValueTemplate hass.Template
+	// Home Assistant will write the desired lower bound of the target temperature range to this value
+	TargetTemperatureLowCommand *mqtt.RemoteValue[float64]
+	// Renders the payload Home Assistant writes to TargetTemperatureLowCommand. The temperature is available as value.
+	TargetTemperatureLowCommandTemplate hass.Template
+
+	// The minimum target temperature. Home Assistant uses 7°C or 44.6°F if nil.
+	MinTemperature *float64
+	// The maximum target temperature. Home Assistant uses 35°C or 95°F if nil.
+	MaxTemperature *float64
+	// The step size of the target temperature. Defaults to 1 if not set.
+	TemperatureStep float64
+	// The precision temperatures are displayed with, one of 0.1, 0.5, or 1. Home Assistant uses 0.1 for Celsius and 1
+	// for Fahrenheit if not set.
+	Precision float64
+	// The unit temperatures are published and received in, see ClimateTemperatureUnitCelsius and
+	// ClimateTemperatureUnitFahrenheit. Home Assistant uses its configured unit system if not set.
+	TemperatureUnit string
+
+	// The current fan mode
+	FanMode *mqtt.Value[string]
+	// Extracts the fan mode from messages published to FanMode
+	FanModeValueTemplate hass.Template
+	// Home Assistant will write the desired fan mode to this value
+	FanModeCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to FanModeCommand. The fan mode is available as value.
+	FanModeCommandTemplate hass.Template
+	// The list of fan modes this device supports. Home Assistant uses auto, low, medium, and high if not set.
+	FanModes []string
+
+	// The current swing mode
+	SwingMode *mqtt.Value[string]
+	// Extracts the swing mode from messages published to SwingMode
+	SwingModeValueTemplate hass.Template
+	// Home Assistant will write the desired swing mode to this value
+	SwingModeCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to SwingModeCommand. The swing mode is available as value.
+	SwingModeCommandTemplate hass.Template
+	// The list of swing modes this device supports. Home Assistant uses on and off if not set.
+	SwingModes []string
+
+	// The current preset mode
+	PresetMode *mqtt.Value[string]
+	// Extracts the preset mode from messages published to PresetMode
+	PresetModeValueTemplate hass.Template
+	// Home Assistant will write the desired preset mode to this value
+	PresetModeCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to PresetModeCommand. The preset mode is available as value.
+	PresetModeCommandTemplate hass.Template
+	// The list of preset modes this device supports. Home Assistant reserves "none" to clear the preset, it must not be
+	// included.
+	PresetModes []string
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+	echoes     map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error
+}
+
+func (c *Climate) PlatformName() string {
+	return "climate"
+}
+
+func (c *Climate) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+
+	result = c.ModeCommand.AppendSubscribeOptions(result, prefix)
+	result = c.TargetTemperatureCommand.AppendSubscribeOptions(result, prefix)
+	result = c.TargetTemperatureHighCommand.AppendSubscribeOptions(result, prefix)
+	result = c.TargetTemperatureLowCommand.AppendSubscribeOptions(result, prefix)
+	result = c.FanModeCommand.AppendSubscribeOptions(result, prefix)
+	result = c.SwingModeCommand.AppendSubscribeOptions(result, prefix)
+	result = c.PresetModeCommand.AppendSubscribeOptions(result, prefix)
+
+	return result
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by routing it to the command value with a
+// matching topic. It is up to the user to ensure each configured mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
+func (c *Climate) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	c.routing()
+	c.routes.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the command received on the specified topic to the
+// corresponding state value if Optimistic is set.
+func (c *Climate) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !c.Optimistic {
+		return nil
+	}
+
+	c.routing()
+	if echo, ok := c.echoes[topic]; ok {
+		return echo(ctx, w, prefix)
+	}
+
+	return nil
+}
+
+// routing builds the tables used by ServeMQTT and EchoCommand to dispatch commands with a single lookup.
+func (c *Climate) routing() {
+	c.routesOnce.Do(func() {
+		c.routes = mqtt.Routes{}
+		c.echoes = map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error{}
+
+		addClimateRoute(c, c.ModeCommand, c.Mode)
+		addClimateRoute(c, c.TargetTemperatureCommand, c.TargetTemperature)
+		addClimateRoute(c, c.TargetTemperatureHighCommand, c.TargetTemperatureHigh)
+		addClimateRoute(c, c.TargetTemperatureLowCommand, c.TargetTemperatureLow)
+		addClimateRoute(c, c.FanModeCommand, c.FanMode)
+		addClimateRoute(c, c.SwingModeCommand, c.SwingMode)
+		addClimateRoute(c, c.PresetModeCommand, c.PresetMode)
+	})
+}
+
+// addClimateRoute routes the provided command to its RemoteValue, echoing it to the provided state in optimistic mode.
+func addClimateRoute[T any](c *Climate, command *mqtt.RemoteValue[T], state *mqtt.Value[T]) {
+	if !command.AddRoute(c.routes) || state == nil {
+		return
+	}
+
+	c.echoes[command.FullyQualifiedTopic("")] = func(ctx context.Context, w mqtt.Writer, prefix string) error {
+		return mqtt.Echo(ctx, w, prefix, command, state)
+	}
+}
+
+func (c *Climate) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, c.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldActionTopic, c.Action, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldActionTemplate, c.ActionValueTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldCurrentTemperatureTopic, c.CurrentTemperature, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCurrentTemperatureTemplate, c.CurrentTemperatureValueTemplate),
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldCurrentHumidityTopic, c.CurrentHumidity, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCurrentHumidityTemplate, c.CurrentHumidityValueTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldModeStateTopic, c.Mode, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldModeCommandTopic, c.ModeCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldModeStateTemplate, c.ModeValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldModeCommandTemplate, c.ModeCommandTemplate),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldModes, c.Modes),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldTemperatureStateTopic, c.TargetTemperature, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldTemperatureCommandTopic, c.TargetTemperatureCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureStateTemplate, c.TargetTemperatureValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureCommandTemplate, c.TargetTemperatureCommandTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldTemperatureHighStateTopic, c.TargetTemperatureHigh, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldTemperatureHighCommandTopic, c.TargetTemperatureHighCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureHighStateTemplate, c.TargetTemperatureHighValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureHighCommandTemplate, c.TargetTemperatureHighCommandTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldTemperatureLowStateTopic, c.TargetTemperatureLow, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldTemperatureLowCommandTopic, c.TargetTemperatureLowCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureLowStateTemplate, c.TargetTemperatureLowValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureLowCommandTemplate, c.TargetTemperatureLowCommandTemplate),
+
+		discovery.MaybeMarshalStd(e, discovery.FieldMinTemperature, c.MinTemperature),
+		discovery.MaybeMarshalStd(e, discovery.FieldMaxTemperature, c.MaxTemperature),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureStep, c.TemperatureStep),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPrecision, c.Precision),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureUnit, c.TemperatureUnit),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldFanModeStateTopic, c.FanMode, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldFanModeCommandTopic, c.FanModeCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldFanModeStateTemplate, c.FanModeValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldFanModeCommandTemplate, c.FanModeCommandTemplate),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldFanModes, c.FanModes),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldSwingModeStateTopic, c.SwingMode, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldSwingModeCommandTopic, c.SwingModeCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldSwingModeStateTemplate, c.SwingModeValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldSwingModeCommandTemplate, c.SwingModeCommandTemplate),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldSwingModes, c.SwingModes),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldPresetModeStateTopic, c.PresetMode, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldPresetModeCommandTopic, c.PresetModeCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPresetModeValueTemplate, c.PresetModeValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPresetModeCommandTemplate, c.PresetModeCommandTemplate),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldPresetModes, c.PresetModes),
+	)
+}
@@ -0,0 +1,110 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newClimate() *platform.Climate {
+	return &platform.Climate{
+		Action:                       mqtt.NewValue("action", hass.HVACActionMarshaler),
+		CurrentTemperature:           mqtt.NewValue("temperature/current", mqtt.FloatMarshaler),
+		CurrentHumidity:              mqtt.NewValue("humidity/current", mqtt.FloatMarshaler),
+		Mode:                         mqtt.NewValue("mode", hass.HVACModeMarshaler),
+		ModeCommand:                  mqtt.NewRemoteValue("mode/set", hass.HVACModeUnmarshaler),
+		TargetTemperature:            mqtt.NewValue("temperature", mqtt.FloatMarshaler),
+		TargetTemperatureCommand:     mqtt.NewRemoteValue("temperature/set", mqtt.FloatUnmarshaler),
+		TargetTemperatureHigh:        mqtt.NewValue("temperature/high", mqtt.FloatMarshaler),
+		TargetTemperatureHighCommand: mqtt.NewRemoteValue("temperature/high/set", mqtt.FloatUnmarshaler),
+		TargetTemperatureLow:         mqtt.NewValue("temperature/low", mqtt.FloatMarshaler),
+		TargetTemperatureLowCommand:  mqtt.NewRemoteValue("temperature/low/set", mqtt.FloatUnmarshaler),
+		FanMode:                      mqtt.NewValue("fan", mqtt.StringMarshaler),
+		FanModeCommand:               mqtt.NewRemoteValue("fan/set", mqtt.StringUnmarshaler),
+		SwingMode:                    mqtt.NewValue("swing", mqtt.StringMarshaler),
+		SwingModeCommand:             mqtt.NewRemoteValue("swing/set", mqtt.StringUnmarshaler),
+		PresetMode:                   mqtt.NewValue("preset", mqtt.StringMarshaler),
+		PresetModeCommand:            mqtt.NewRemoteValue("preset/set", mqtt.StringUnmarshaler),
+	}
+}
+
+func TestClimate_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newClimate(), "climate")
+}
+
+func TestClimate_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newClimate()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		for _, tt := range []struct {
+			command string
+			state   string
+			payload string
+		}{
+			{command: "mode/set", state: "climate/mode", payload: "heat"},
+			{command: "temperature/set", state: "climate/temperature", payload: "21.5"},
+			{command: "temperature/high/set", state: "climate/temperature/high", payload: "24"},
+			{command: "temperature/low/set", state: "climate/temperature/low", payload: "18"},
+			{command: "fan/set", state: "climate/fan", payload: "low"},
+			{command: "swing/set", state: "climate/swing", payload: "on"},
+			{command: "preset/set", state: "climate/preset", payload: "eco"},
+		} {
+			sut.ServeMQTT(w, tt.command, []byte(tt.payload))
+			require.NoError(t, sut.EchoCommand(t.Context(), w, "climate", tt.command))
+			w.AssertPublished(t, tt.state, []byte(tt.payload))
+		}
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newClimate()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "mode/set", []byte("heat"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "climate", "mode/set"))
+		w.AssertNotPublished(t, "climate/mode")
+	})
+}
+
+func TestClimate_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	minTemperature := 0.0
+	sut := &platform.Climate{
+		// A read-only mode and a write-only target temperature are both valid
+		Mode:                     mqtt.NewValue("mode", hass.HVACModeMarshaler),
+		TargetTemperatureCommand: mqtt.NewRemoteValue("temperature/set", mqtt.FloatUnmarshaler),
+		Modes:                    []hass.HVACMode{hass.HVACModeOff, hass.HVACModeHeat},
+		MinTemperature:           &minTemperature,
+		TemperatureStep:          0.5,
+		TemperatureUnit:          platform.ClimateTemperatureUnitCelsius,
+		PresetModes:              []string{"eco", "away"},
+	}
+
+	require.NoError(t, sut.MarshalDiscoveryTo(e, "climate"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "mode_state_topic", "climate/mode")
+	hqtttest.RequireNoField(t, payload, "mode_command_topic")
+	hqtttest.RequireField(t, payload, "temperature_command_topic", "climate/temperature/set")
+	hqtttest.RequireNoField(t, payload, "temperature_state_topic")
+	hqtttest.RequireField(t, payload, "modes", []string{"off", "heat"})
+	hqtttest.RequireField(t, payload, "min_temp", 0)
+	hqtttest.RequireNoField(t, payload, "max_temp")
+	hqtttest.RequireField(t, payload, "temp_step", 0.5)
+	hqtttest.RequireField(t, payload, "temperature_unit", "C")
+	hqtttest.RequireField(t, payload, "preset_modes", []string{"eco", "away"})
+	hqtttest.RequireNoField(t, payload, "fan_modes")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
@@ -0,0 +1,211 @@
+package platform
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Device classes for Cover. See https://www.home-assistant.io/integrations/cover/#device-class.
+const (
+	CoverDeviceClassAwning  = "awning"
+	CoverDeviceClassBlind   = "blind"
+	CoverDeviceClassCurtain = "curtain"
+	CoverDeviceClassDamper  = "damper"
+	CoverDeviceClassDoor    = "door"
+	CoverDeviceClassGarage  = "garage"
+	CoverDeviceClassGate    = "gate"
+	CoverDeviceClassShade   = "shade"
+	CoverDeviceClassShutter = "shutter"
+	CoverDeviceClassWindow  = "window"
+)
+
+// Cover is a hqtt.Platform that implements the cover.mqtt integration for Home Assistant, for devices like garage
+// doors and blinds.
+//
+// See https://www.home-assistant.io/integrations/cover.mqtt/
+type Cover struct {
+	// Flag that defines if the cover works in optimistic mode. When set, commands received on Command and
+	// PositionCommand are automatically written to State and Position.
+	Optimistic bool
+
+	// The type/class of the cover to set the icon in the frontend, see the CoverDeviceClass constants.
+	DeviceClass string
+
+	// The current state of the cover
+	State *mqtt.Value[hass.CoverState]
+	// Extracts the state from messages published to State or Position
+	ValueTemplate hass.Template
+	// Home Assistant will write open, close, and stop commands for this entity to this value
+	Command *mqtt.RemoteValue[hass.CoverCommand]
+
+	// Custom values to use for payload commands
+	CustomCommandValues hass.CustomCoverCommand
+	// Custom values to use for states
+	CustomStateValues hass.CustomCoverState
+
+	// The current position of the cover, between PositionClosed and PositionOpen
+	Position *mqtt.Value[uint]
+	// Extracts the position from messages published to Position
+	PositionTemplate hass.Template
+	// Home Assistant will write the desired position to this value
+	PositionCommand *mqtt.RemoteValue[uint]
+	// Renders the payload Home Assistant writes to PositionCommand. The position is available as position.
+	PositionCommandTemplate hass.Template
+	// The position that represents an open cover. Defaults to 100 if nil.
+	PositionOpen *uint
+	// The position that represents a closed cover. Defaults to 0 if nil.
+	PositionClosed *uint
+
+	// Flag that defines if the tilt works in optimistic mode. When set, commands received on TiltCommand are
+	// automatically written to Tilt.
+	TiltOptimistic bool
+	// The current tilt of the cover, between TiltMin and TiltMax
+	Tilt *mqtt.Value[uint]
+	// Extracts the tilt from messages published to Tilt
+	TiltTemplate hass.Template
+	// Home Assistant will write the desired tilt to this value
+	TiltCommand *mqtt.RemoteValue[uint]
+	// Renders the payload Home Assistant writes to TiltCommand. The tilt is available as tilt_position.
+	TiltCommandTemplate hass.Template
+	// The minimum tilt value. Defaults to 0 if nil.
+	TiltMin *uint
+	// The maximum tilt value. Defaults to 100 if nil.
+	TiltMax *uint
+	// The tilt Home Assistant writes to TiltCommand when the tilt is opened. Defaults to 100 if nil.
+	TiltOpened *uint
+	// The tilt Home Assistant writes to TiltCommand when the tilt is closed. Defaults to 0 if nil.
+	TiltClosed *uint
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+	echoes     map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error
+}
+
+func (c *Cover) PlatformName() string {
+	return "cover"
+}
+
+func (c *Cover) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+
+	result = c.Command.AppendSubscribeOptions(result, prefix)
+	result = c.PositionCommand.AppendSubscribeOptions(result, prefix)
+	result = c.TiltCommand.AppendSubscribeOptions(result, prefix)
+
+	return result
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by routing it to the command value with a
+// matching topic. It is up to the user to ensure each configured mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
+func (c *Cover) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	c.routing()
+	c.routes.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform. If Optimistic is set, open, close, and stop commands are written to
+// State as the open, closed, and stopped states, and positions are written to Position. If TiltOptimistic is set, tilt
+// commands are written to Tilt.
+func (c *Cover) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	c.routing()
+	if echo, ok := c.echoes[topic]; ok {
+		return echo(ctx, w, prefix)
+	}
+
+	return nil
+}
+
+// routing builds the tables used by ServeMQTT and EchoCommand to dispatch commands with a single lookup.
+func (c *Cover) routing() {
+	c.routesOnce.Do(func() {
+		c.routes = mqtt.Routes{}
+		c.echoes = map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error{}
+
+		if c.Command.AddRoute(c.routes) && c.Optimistic && c.State != nil {
+			c.echoes[c.Command.FullyQualifiedTopic("")] = c.echoState
+		}
+
+		addCoverRoute(c, c.PositionCommand, c.Position, c.Optimistic)
+		addCoverRoute(c, c.TiltCommand, c.Tilt, c.TiltOptimistic)
+	})
+}
+
+// echoState writes the state corresponding to the most recent command to State.
+func (c *Cover) echoState(ctx context.Context, w mqtt.Writer, prefix string) error {
+	command, ok := c.Command.LastReceived()
+	if !ok {
+		return nil
+	}
+
+	var state hass.CoverState
+	switch command {
+	case cmp.Or(c.CustomCommandValues.Open, hass.CoverCommandOpen):
+		state = cmp.Or(c.CustomStateValues.Open, hass.CoverStateOpen)
+	case cmp.Or(c.CustomCommandValues.Close, hass.CoverCommandClose):
+		state = cmp.Or(c.CustomStateValues.Closed, hass.CoverStateClosed)
+	case cmp.Or(c.CustomCommandValues.Stop, hass.CoverCommandStop):
+		state = cmp.Or(c.CustomStateValues.Stopped, hass.CoverStateStopped)
+	default:
+		return nil
+	}
+
+	return mqtt.Error(c.State.Write(ctx, w, prefix, state))
+}
+
+// addCoverRoute routes the provided command to its RemoteValue, echoing it to the provided state if optimistic is set.
+func addCoverRoute(c *Cover, command *mqtt.RemoteValue[uint], state *mqtt.Value[uint], optimistic bool) {
+	if !command.AddRoute(c.routes) || !optimistic || state == nil {
+		return
+	}
+
+	c.echoes[command.FullyQualifiedTopic("")] = func(ctx context.Context, w mqtt.Writer, prefix string) error {
+		return mqtt.Echo(ctx, w, prefix, command, state)
+	}
+}
+
+func (c *Cover) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, c.Optimistic),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, c.DeviceClass),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, c.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, c.ValueTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldCommandTopic, c.Command, prefix),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOpen, c.CustomCommandValues.Open),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadClose, c.CustomCommandValues.Close),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadStop, c.CustomCommandValues.Stop),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOpen, c.CustomStateValues.Open),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOpening, c.CustomStateValues.Opening),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateClosed, c.CustomStateValues.Closed),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateClosing, c.CustomStateValues.Closing),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateStopped, c.CustomStateValues.Stopped),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldPositionTopic, c.Position, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPositionTemplate, c.PositionTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldSetPositionTopic, c.PositionCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldSetPositionTemplate, c.PositionCommandTemplate),
+		discovery.MaybeMarshalStd(e, discovery.FieldPositionOpen, c.PositionOpen),
+		discovery.MaybeMarshalStd(e, discovery.FieldPositionClosed, c.PositionClosed),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTiltOptimistic, c.TiltOptimistic),
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldTiltStatusTopic, c.Tilt, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTiltStatusTemplate, c.TiltTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldTiltCommandTopic, c.TiltCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTiltCommandTemplate, c.TiltCommandTemplate),
+		discovery.MaybeMarshalStd(e, discovery.FieldTiltMin, c.TiltMin),
+		discovery.MaybeMarshalStd(e, discovery.FieldTiltMax, c.TiltMax),
+		discovery.MaybeMarshalStd(e, discovery.FieldTiltOpenedValue, c.TiltOpened),
+		discovery.MaybeMarshalStd(e, discovery.FieldTiltClosedValue, c.TiltClosed),
+	)
+}
@@ -0,0 +1,127 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newCover() *platform.Cover {
+	return &platform.Cover{
+		State:           mqtt.NewValue("state", hass.CoverStateMarshaler),
+		Command:         mqtt.NewRemoteValue("command", hass.CoverCommandUnmarshaler),
+		Position:        mqtt.NewValue("position", mqtt.UintMarshaler),
+		PositionCommand: mqtt.NewRemoteValue("position/set", mqtt.UintUnmarshaler),
+		Tilt:            mqtt.NewValue("tilt", mqtt.UintMarshaler),
+		TiltCommand:     mqtt.NewRemoteValue("tilt/set", mqtt.UintUnmarshaler),
+	}
+}
+
+func TestCover_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newCover(), "cover")
+}
+
+func TestCover_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newCover()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		for _, tt := range []struct {
+			command string
+			state   string
+		}{
+			{command: "OPEN", state: "open"},
+			{command: "CLOSE", state: "closed"},
+			{command: "STOP", state: "stopped"},
+		} {
+			sut.ServeMQTT(w, "command", []byte(tt.command))
+			require.NoError(t, sut.EchoCommand(t.Context(), w, "cover", "command"))
+			w.AssertPublished(t, "cover/state", []byte(tt.state))
+		}
+
+		sut.ServeMQTT(w, "position/set", []byte("42"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "cover", "position/set"))
+		w.AssertPublished(t, "cover/position", []byte("42"))
+
+		// Tilt is only echoed in tilt optimistic mode
+		sut.ServeMQTT(w, "tilt/set", []byte("10"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "cover", "tilt/set"))
+		w.AssertNotPublished(t, "cover/tilt")
+	})
+
+	t.Run("Custom Values", func(t *testing.T) {
+		sut := newCover()
+		sut.Optimistic = true
+		sut.CustomCommandValues = hass.CustomCoverCommand{Open: "UP"}
+		sut.CustomStateValues = hass.CustomCoverState{Open: "up"}
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("UP"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "cover", "command"))
+		w.AssertPublished(t, "cover/state", []byte("up"))
+
+		// Unknown commands are not echoed
+		w = &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("OPEN"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "cover", "command"))
+		w.AssertNotPublished(t, "cover/state")
+	})
+
+	t.Run("Tilt Optimistic", func(t *testing.T) {
+		sut := newCover()
+		sut.TiltOptimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "tilt/set", []byte("10"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "cover", "tilt/set"))
+		w.AssertPublished(t, "cover/tilt", []byte("10"))
+
+		sut.ServeMQTT(w, "command", []byte("OPEN"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "cover", "command"))
+		w.AssertNotPublished(t, "cover/state")
+	})
+}
+
+func TestCover_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	closed, maxTilt := uint(0), uint(180)
+	sut := newCover()
+	sut.DeviceClass = platform.CoverDeviceClassGarage
+	sut.CustomCommandValues = hass.CustomCoverCommand{Stop: "HALT"}
+	sut.CustomStateValues = hass.CustomCoverState{Closed: "down"}
+	sut.PositionClosed = &closed
+	sut.TiltMax = &maxTilt
+
+	require.NoError(t, sut.MarshalDiscoveryTo(e, "cover"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "device_class", "garage")
+	hqtttest.RequireField(t, payload, "state_topic", "cover/state")
+	hqtttest.RequireField(t, payload, "command_topic", "cover/command")
+	hqtttest.RequireField(t, payload, "payload_stop", "HALT")
+	hqtttest.RequireField(t, payload, "state_closed", "down")
+	hqtttest.RequireField(t, payload, "position_topic", "cover/position")
+	hqtttest.RequireField(t, payload, "set_position_topic", "cover/position/set")
+	hqtttest.RequireField(t, payload, "tilt_status_topic", "cover/tilt")
+	hqtttest.RequireField(t, payload, "tilt_command_topic", "cover/tilt/set")
+	hqtttest.RequireField(t, payload, "tilt_max", 180)
+
+	// Explicit zero values are marshaled, unset values are left to Home Assistant's defaults
+	hqtttest.RequireField(t, payload, "position_closed", 0)
+	hqtttest.RequireNoField(t, payload, "position_open")
+	hqtttest.RequireNoField(t, payload, "tilt_min")
+	hqtttest.RequireNoField(t, payload, "payload_open")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
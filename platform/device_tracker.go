@@ -0,0 +1,90 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+	"log/slog"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Source types for DeviceTracker. See https://www.home-assistant.io/integrations/device_tracker.mqtt/#source_type.
+const (
+	DeviceTrackerSourceTypeGPS         = "gps"
+	DeviceTrackerSourceTypeRouter      = "router"
+	DeviceTrackerSourceTypeBluetooth   = "bluetooth"
+	DeviceTrackerSourceTypeBluetoothLE = "bluetooth_le"
+)
+
+// States Home Assistant understands for DeviceTracker.State. The name of a zone may also be used.
+const (
+	DeviceTrackerStateHome    = "home"
+	DeviceTrackerStateNotHome = "not_home"
+)
+
+// GPSLocation is the payload published to DeviceTracker.Location. Accuracy is the radius of uncertainty in meters. It
+// implements slog.LogValuer.
+type GPSLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"gps_accuracy,omitzero"`
+}
+
+func (l GPSLocation) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Float64("latitude", l.Latitude),
+		slog.Float64("longitude", l.Longitude),
+		slog.Float64("gps_accuracy", l.Accuracy),
+	)
+}
+
+// DeviceTracker is a hqtt.Platform that implements the device_tracker.mqtt integration for Home Assistant. Publish the
+// presence of the tracked device to State, its coordinates to Location, or both. If only Location is configured, Home
+// Assistant determines the zone the device is in from its coordinates.
+//
+// See https://www.home-assistant.io/integrations/device_tracker.mqtt/
+type DeviceTracker struct {
+	// How the device is tracked, see DeviceTrackerSourceTypeGPS, DeviceTrackerSourceTypeRouter,
+	// DeviceTrackerSourceTypeBluetooth, and DeviceTrackerSourceTypeBluetoothLE.
+	SourceType string
+
+	// Where the device currently is, see DeviceTrackerStateHome and DeviceTrackerStateNotHome
+	State *mqtt.Value[string]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+
+	// The coordinates of the device, see NewDeviceTrackerLocationValue
+	Location *mqtt.Value[GPSLocation]
+	// Extracts the coordinates from messages published to Location
+	LocationTemplate hass.Template
+}
+
+// NewDeviceTrackerLocationValue constructs a mqtt.Value that publishes GPSLocation values as json.
+func NewDeviceTrackerLocationValue(topic string) *mqtt.Value[GPSLocation] {
+	return mqtt.NewValue(topic, mqtt.JsonValueMarshaler[GPSLocation]())
+}
+
+func (d *DeviceTracker) PlatformName() string {
+	return "device_tracker"
+}
+
+func (d *DeviceTracker) Subscriptions(_ string) []mqtt.Subscription {
+	return nil
+}
+
+func (d *DeviceTracker) ServeMQTT(_ mqtt.Writer, _ string, _ []byte) {}
+
+func (d *DeviceTracker) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldSourceType, d.SourceType),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, d.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, d.ValueTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldAttributesTopic, d.Location, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldAttributesTemplate, d.LocationTemplate),
+	)
+}
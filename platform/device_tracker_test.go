@@ -0,0 +1,46 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newDeviceTracker() *platform.DeviceTracker {
+	return &platform.DeviceTracker{
+		SourceType: platform.DeviceTrackerSourceTypeGPS,
+		State:      mqtt.NewValue("state", mqtt.StringMarshaler),
+		Location:   platform.NewDeviceTrackerLocationValue("location"),
+	}
+}
+
+func TestDeviceTracker_Location(t *testing.T) {
+	sut := newDeviceTracker()
+
+	w := &hqtttest.Writer{}
+	_, err := sut.Location.Write(t.Context(), w, "phone", platform.GPSLocation{Latitude: 52.37, Longitude: 4.89, Accuracy: 12})
+	require.NoError(t, err)
+
+	w.AssertPublished(t, "phone/location", []byte(`{"latitude":52.37,"longitude":4.89,"gps_accuracy":12}`))
+}
+
+func TestDeviceTracker_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	require.NoError(t, newDeviceTracker().MarshalDiscoveryTo(e, "phone"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "source_type", "gps")
+	hqtttest.RequireField(t, payload, "state_topic", "phone/state")
+	hqtttest.RequireField(t, payload, "json_attributes_topic", "phone/location")
+	hqtttest.RequireNoField(t, payload, "value_template")
+}
@@ -0,0 +1,83 @@
+package platform
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Trigger types Home Assistant translates for DeviceTrigger. Other types are shown as-is.
+const (
+	DeviceTriggerTypeButtonShortPress     = "button_short_press"
+	DeviceTriggerTypeButtonShortRelease   = "button_short_release"
+	DeviceTriggerTypeButtonLongPress      = "button_long_press"
+	DeviceTriggerTypeButtonLongRelease    = "button_long_release"
+	DeviceTriggerTypeButtonDoublePress    = "button_double_press"
+	DeviceTriggerTypeButtonTriplePress    = "button_triple_press"
+	DeviceTriggerTypeButtonQuadruplePress = "button_quadruple_press"
+	DeviceTriggerTypeButtonQuintuplePress = "button_quintuple_press"
+)
+
+// Trigger subtypes Home Assistant translates for DeviceTrigger. Other subtypes are shown as-is.
+const (
+	DeviceTriggerSubtypeTurnOn  = "turn_on"
+	DeviceTriggerSubtypeTurnOff = "turn_off"
+	DeviceTriggerSubtypeButton1 = "button_1"
+	DeviceTriggerSubtypeButton2 = "button_2"
+	DeviceTriggerSubtypeButton3 = "button_3"
+	DeviceTriggerSubtypeButton4 = "button_4"
+	DeviceTriggerSubtypeButton5 = "button_5"
+	DeviceTriggerSubtypeButton6 = "button_6"
+)
+
+// DeviceTrigger is a hqtt.Platform that implements the device_automation.mqtt integration for Home Assistant, which
+// exposes a trigger (like a button on a remote being pressed) to device automations. Home Assistant does not create an
+// entity for device triggers, use Fire to trigger automations.
+//
+// See https://www.home-assistant.io/integrations/device_trigger.mqtt/
+type DeviceTrigger struct {
+	// The type of the trigger, for example DeviceTriggerTypeButtonShortPress
+	Type string `hqtt:"required"`
+	// The subtype of the trigger, for example DeviceTriggerSubtypeButton1
+	Subtype string `hqtt:"required"`
+
+	// Messages published to this value fire the trigger
+	Topic *mqtt.Value[string] `hqtt:"required"`
+	// If set, only messages with this payload fire the trigger. This allows multiple triggers to share a Topic.
+	Payload string
+	// Extracts the payload compared with Payload from messages published to Topic
+	ValueTemplate hass.Template
+}
+
+func (d *DeviceTrigger) PlatformName() string {
+	return "device_automation"
+}
+
+func (d *DeviceTrigger) Subscriptions(_ string) []mqtt.Subscription {
+	return nil
+}
+
+func (d *DeviceTrigger) ServeMQTT(_ mqtt.Writer, _ string, _ []byte) {}
+
+// Fire publishes Payload to Topic, firing the trigger. If Payload is not set, Type is published instead, since Home
+// Assistant fires the trigger for any message.
+func (d *DeviceTrigger) Fire(ctx context.Context, w mqtt.Writer, prefix string) error {
+	return mqtt.Error(d.Topic.Write(ctx, w, prefix, cmp.Or(d.Payload, d.Type)))
+}
+
+func (d *DeviceTrigger) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalStdComparable("automation type", e, discovery.FieldAutomationType, "trigger"),
+		discovery.MarshalStdComparable("type", e, discovery.FieldType, d.Type),
+		discovery.MarshalStdComparable("subtype", e, discovery.FieldSubtype, d.Subtype),
+
+		discovery.MarshalRequiredValueTopic("topic", e, discovery.FieldTopic, d.Topic, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayload, d.Payload),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, d.ValueTemplate),
+	)
+}
@@ -0,0 +1,67 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newDeviceTrigger() *platform.DeviceTrigger {
+	return &platform.DeviceTrigger{
+		Type:    platform.DeviceTriggerTypeButtonShortPress,
+		Subtype: platform.DeviceTriggerSubtypeButton1,
+		Topic:   mqtt.NewValue("action", mqtt.StringMarshaler),
+	}
+}
+
+func TestDeviceTrigger_Fire(t *testing.T) {
+	t.Run("Payload", func(t *testing.T) {
+		sut := newDeviceTrigger()
+		sut.Payload = "1_single"
+
+		w := &hqtttest.Writer{}
+		require.NoError(t, sut.Fire(t.Context(), w, "remote"))
+		w.AssertPublished(t, "remote/action", []byte("1_single"))
+	})
+
+	t.Run("No Payload", func(t *testing.T) {
+		sut := newDeviceTrigger()
+
+		w := &hqtttest.Writer{}
+		require.NoError(t, sut.Fire(t.Context(), w, "remote"))
+		w.AssertPublished(t, "remote/action", []byte("button_short_press"))
+	})
+}
+
+func TestDeviceTrigger_MarshalDiscoveryTo(t *testing.T) {
+	for name, unset := range map[string]func(sut *platform.DeviceTrigger){
+		"Type":    func(sut *platform.DeviceTrigger) { sut.Type = "" },
+		"Subtype": func(sut *platform.DeviceTrigger) { sut.Subtype = "" },
+		"Topic":   func(sut *platform.DeviceTrigger) { sut.Topic = nil },
+	} {
+		t.Run(name+" Required", func(t *testing.T) {
+			sut := newDeviceTrigger()
+			unset(sut)
+
+			_, err := marshalDiscovery(t, sut, "remote")
+			require.Error(t, err)
+		})
+	}
+
+	sut := newDeviceTrigger()
+	sut.Payload = "1_single"
+
+	payload, err := marshalDiscovery(t, sut, "remote")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "automation_type", "trigger")
+	hqtttest.RequireField(t, payload, "type", "button_short_press")
+	hqtttest.RequireField(t, payload, "subtype", "button_1")
+	hqtttest.RequireField(t, payload, "topic", "remote/action")
+	hqtttest.RequireField(t, payload, "payload", "1_single")
+	hqtttest.RequireNoField(t, payload, "value_template")
+}
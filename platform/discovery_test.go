@@ -0,0 +1,340 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt"
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func TestPlatformFields(t *testing.T) {
+	light := newBenchmarkLight()
+	light.Optimistic = true
+	light.ColorTemperature = mqtt.NewValue("temperature", mqtt.UintMarshaler)
+	light.ColorTemperatureCommand = mqtt.NewRemoteValue("temperature/set", mqtt.UintUnmarshaler)
+	light.ColorTemperatureInKelvin = true
+	light.MinKelvin, light.MaxKelvin = 2000, 6500
+	light.PossibleEffects = []string{"fire"}
+	light.StateValueTemplate = "{{ value_json.state }}"
+	light.BrightnessValueTemplate = "{{ value_json.brightness }}"
+	light.BrightnessCommandTemplate = `{"brightness": {{ value }}}`
+	light.ColorTemperatureValueTemplate = "{{ value_json.color_temp }}"
+	light.ColorTemperatureCommandTemplate = `{"color_temp": {{ value }}}`
+	light.EffectValueTemplate = "{{ value_json.effect }}"
+	light.EffectCommandTemplate = `{"effect": "{{ value }}"}`
+
+	sensor := &platform.Sensor[string, any]{
+		ExpireMeasurementsAfter:   time.Minute,
+		DeviceClass:               "enum",
+		ForceUpdate:               true,
+		Attributes:                platform.NewSensorAttributeValue[any]("attributes", nil),
+		EnumOptions:               []string{"on", "off"},
+		SuggestedDisplayPrecision: 2,
+		State:                     mqtt.NewValue("state", mqtt.StringMarshaler),
+		ValueTemplate:             "{{ value_json.state }}",
+		AttributesTemplate:        "{{ value_json.attributes | to_json }}",
+	}
+
+	binarySensor := platform.NewBinarySensor[any](mqtt.NewValue("state", hass.PowerStateMarshaler), nil)
+	binarySensor.DeviceClass = "motion"
+	binarySensor.OffDelay = time.Minute
+
+	sw := newSwitch()
+	sw.Optimistic = true
+	sw.DeviceClass = platform.SwitchDeviceClassSwitch
+	sw.ValueTemplate = "{{ value_json.state }}"
+	sw.CommandTemplate = `{"state": "{{ value }}"}`
+	sw.CustomPowerStateValues = hass.CustomPowerState{On: "1", Off: "0"}
+
+	open, closed := uint(100), uint(0)
+	cover := newCover()
+	cover.Optimistic, cover.TiltOptimistic = true, true
+	cover.DeviceClass = platform.CoverDeviceClassGarage
+	cover.ValueTemplate = "{{ value_json.state }}"
+	cover.CustomCommandValues = hass.CustomCoverCommand{Open: "UP", Close: "DOWN", Stop: "HALT"}
+	cover.CustomStateValues = hass.CustomCoverState{Open: "up", Opening: "rising", Closed: "down", Closing: "falling", Stopped: "halted"}
+	cover.PositionTemplate = "{{ value_json.position }}"
+	cover.PositionCommandTemplate = `{"position": {{ position }}}`
+	cover.PositionOpen, cover.PositionClosed = &open, &closed
+	cover.TiltTemplate = "{{ value_json.tilt }}"
+	cover.TiltCommandTemplate = `{"tilt": {{ tilt_position }}}`
+	cover.TiltMin, cover.TiltMax, cover.TiltOpened, cover.TiltClosed = &closed, &open, &open, &closed
+
+	minTemperature, maxTemperature := 7.0, 35.0
+	climate := newClimate()
+	climate.Optimistic = true
+	climate.ActionValueTemplate = "{{ value_json.action }}"
+	climate.CurrentTemperatureValueTemplate = "{{ value_json.temperature }}"
+	climate.CurrentHumidityValueTemplate = "{{ value_json.humidity }}"
+	climate.ModeValueTemplate = "{{ value_json.mode }}"
+	climate.ModeCommandTemplate = `{"mode": "{{ value }}"}`
+	climate.Modes = []hass.HVACMode{hass.HVACModeOff, hass.HVACModeHeat, hass.HVACModeCool}
+	climate.TargetTemperatureValueTemplate = "{{ value_json.target }}"
+	climate.TargetTemperatureCommandTemplate = `{"target": {{ value }}}`
+	climate.TargetTemperatureHighValueTemplate = "{{ value_json.high }}"
+	climate.TargetTemperatureHighCommandTemplate = `{"high": {{ value }}}`
+	climate.TargetTemperatureLowValueTemplate = "{{ value_json.low }}"
+	climate.TargetTemperatureLowCommandTemplate = `{"low": {{ value }}}`
+	climate.MinTemperature, climate.MaxTemperature = &minTemperature, &maxTemperature
+	climate.TemperatureStep, climate.Precision = 0.5, 0.1
+	climate.TemperatureUnit = platform.ClimateTemperatureUnitCelsius
+	climate.FanModeValueTemplate = "{{ value_json.fan }}"
+	climate.FanModeCommandTemplate = `{"fan": "{{ value }}"}`
+	climate.FanModes = []string{"auto", "low"}
+	climate.SwingModeValueTemplate = "{{ value_json.swing }}"
+	climate.SwingModeCommandTemplate = `{"swing": "{{ value }}"}`
+	climate.SwingModes = []string{"on", "off"}
+	climate.PresetModeValueTemplate = "{{ value_json.preset }}"
+	climate.PresetModeCommandTemplate = `{"preset": "{{ value }}"}`
+	climate.PresetModes = []string{"eco"}
+
+	fan := newFan()
+	fan.Optimistic = true
+	fan.StateValueTemplate = "{{ value_json.state }}"
+	fan.CommandTemplate = `{"state": "{{ value }}"}`
+	fan.CustomPowerStateValues = hass.CustomPowerState{On: "1", Off: "0"}
+	fan.PercentageValueTemplate = "{{ value_json.speed }}"
+	fan.PercentageCommandTemplate = `{"speed": {{ value }}}`
+	fan.SpeedRangeMin, fan.SpeedRangeMax = 1, 3
+	fan.PresetModeValueTemplate = "{{ value_json.preset }}"
+	fan.PresetModeCommandTemplate = `{"preset": "{{ value }}"}`
+	fan.PresetModes = []string{"breeze"}
+	fan.OscillationValueTemplate = "{{ value_json.oscillation }}"
+	fan.OscillationCommandTemplate = `{"oscillation": "{{ value }}"}`
+	fan.CustomOscillationValues = hass.CustomOscillation{On: "1", Off: "0"}
+	fan.DirectionValueTemplate = "{{ value_json.direction }}"
+	fan.DirectionCommandTemplate = `{"direction": "{{ value }}"}`
+
+	lock := newLock()
+	lock.Optimistic, lock.SupportsOpen = true, true
+	lock.ValueTemplate = "{{ value_json.state }}"
+	lock.CommandTemplate = `{"command": "{{ value }}", "code": "{{ code }}"}`
+	lock.CodeFormat = `^\d{4}$`
+	lock.CustomCommandValues = hass.CustomLockCommand{Lock: "1", Unlock: "0", Open: "2"}
+	lock.CustomStateValues = hass.CustomLockState{
+		Locked: "locked", Locking: "locking", Unlocked: "unlocked", Unlocking: "unlocking",
+		Jammed: "jammed", Open: "open", Opening: "opening",
+	}
+
+	minValue, maxValue := 0.0, 10.0
+	number := newNumber()
+	number.Optimistic = true
+	number.DeviceClass = "temperature"
+	number.ValueTemplate = "{{ value_json.value }}"
+	number.CommandTemplate = `{"value": {{ value }}}`
+	number.Min, number.Max, number.Step = &minValue, &maxValue, 0.5
+	number.Mode = platform.NumberModeBox
+	number.UnitOfMeasurement = "°C"
+
+	sel := newSelect()
+	sel.Optimistic = true
+	sel.ValueTemplate = "{{ value_json.speed }}"
+	sel.CommandTemplate = `{"speed": "{{ value }}"}`
+
+	button := newButton()
+	button.DeviceClass = platform.ButtonDeviceClassIdentify
+	button.CommandTemplate = `{"action": "{{ value }}"}`
+	button.PayloadPress = "identify"
+
+	text := newText()
+	text.ValueTemplate = "{{ value_json.text }}"
+	text.CommandTemplate = `{"text": "{{ value }}"}`
+	text.MinLength, text.MaxLength = 1, 32
+	text.Pattern = "^[a-z]+$"
+	text.Mode = platform.TextModePassword
+
+	supported := true
+	siren := newSiren()
+	siren.Optimistic = true
+	siren.StateValueTemplate = "{{ value_json.state }}"
+	siren.CommandTemplate = `{"state": "{{ value }}", "tone": "{{ tone }}"}`
+	siren.CommandOffTemplate = `{"state": "{{ value }}"}`
+	siren.CustomPowerStateValues = hass.CustomPowerState{On: "1", Off: "0"}
+	siren.CustomStateValues = hass.CustomPowerState{On: "on", Off: "off"}
+	siren.AvailableTones = []string{"ping"}
+	siren.SupportDuration, siren.SupportVolumeSet = &supported, &supported
+
+	valve := newValve()
+	valve.Optimistic = true
+	valve.DeviceClass = platform.ValveDeviceClassGas
+	valve.ValueTemplate = "{{ value_json.state }}"
+	valve.CommandTemplate = `{"state": "{{ value }}"}`
+	valve.PositionOpen, valve.PositionClosed = &open, &closed
+	valve.CustomCommandValues = hass.CustomValveCommand{Open: "1", Close: "0", Stop: "2"}
+	valve.CustomStateValues = hass.CustomValveState{Open: "up", Opening: "rising", Closed: "down", Closing: "falling"}
+
+	positionValve := newPositionValve()
+	positionValve.Optimistic = true
+
+	vacuum := newVacuum()
+	vacuum.SupportedFeatures = []platform.VacuumFeature{platform.VacuumFeatureStart, platform.VacuumFeatureSendCommand}
+	vacuum.CustomCommandValues = hass.CustomVacuumCommand{
+		Start: "go", Pause: "wait", Stop: "halt", ReturnToBase: "dock", CleanSpot: "spot", Locate: "beep",
+	}
+	vacuum.FanSpeeds = []string{"min", "max"}
+
+	lawnMower := newLawnMower()
+	lawnMower.Optimistic = true
+	lawnMower.ActivityValueTemplate = "{{ value_json.activity }}"
+	lawnMower.StartMowingCommandTemplate = `{"action": "{{ value }}"}`
+	lawnMower.PauseCommandTemplate = `{"action": "{{ value }}"}`
+	lawnMower.DockCommandTemplate = `{"action": "{{ value }}"}`
+
+	minHumidity, maxHumidity := 30.0, 80.0
+	humidifier := newHumidifier()
+	humidifier.Optimistic = true
+	humidifier.DeviceClass = platform.HumidifierDeviceClassHumidifier
+	humidifier.ActionValueTemplate = "{{ value_json.action }}"
+	humidifier.StateValueTemplate = "{{ value_json.state }}"
+	humidifier.CommandTemplate = `{"state": "{{ value }}"}`
+	humidifier.CustomPowerStateValues = hass.CustomPowerState{On: "1", Off: "0"}
+	humidifier.CurrentHumidityValueTemplate = "{{ value_json.humidity }}"
+	humidifier.TargetHumidityValueTemplate = "{{ value_json.target }}"
+	humidifier.TargetHumidityCommandTemplate = `{"target": {{ value }}}`
+	humidifier.MinHumidity, humidifier.MaxHumidity = &minHumidity, &maxHumidity
+	humidifier.ModeValueTemplate = "{{ value_json.mode }}"
+	humidifier.ModeCommandTemplate = `{"mode": "{{ value }}"}`
+	humidifier.Modes = []string{"eco"}
+
+	waterHeater := newWaterHeater()
+	waterHeater.Optimistic = true
+	waterHeater.CurrentTemperatureValueTemplate = "{{ value_json.current }}"
+	waterHeater.ModeValueTemplate = "{{ value_json.mode }}"
+	waterHeater.ModeCommandTemplate = `{"mode": "{{ value }}"}`
+	waterHeater.Modes = []hass.WaterHeaterMode{hass.WaterHeaterModeOff, hass.WaterHeaterModeHeatPump}
+	waterHeater.TargetTemperatureValueTemplate = "{{ value_json.target }}"
+	waterHeater.TargetTemperatureCommandTemplate = `{"target": {{ value }}}`
+	waterHeater.MinTemperature, waterHeater.MaxTemperature = &minTemperature, &maxTemperature
+	waterHeater.Precision = 0.5
+	waterHeater.TemperatureUnit = platform.ClimateTemperatureUnitCelsius
+
+	alarm := newAlarmControlPanel()
+	alarm.ValueTemplate = "{{ value_json.state }}"
+	alarm.CommandTemplate = `{"action": "{{ action }}", "code": "{{ code }}"}`
+	alarm.Code = platform.AlarmControlPanelCodeRemoteText
+	alarm.CodeArmRequired, alarm.CodeDisarmRequired, alarm.CodeTriggerRequired = &supported, &supported, &supported
+	alarm.SupportedFeatures = []platform.AlarmControlPanelFeature{platform.AlarmControlPanelFeatureTrigger}
+	alarm.CustomCommandValues = hass.CustomAlarmCommand{
+		ArmHome: "home", ArmAway: "away", ArmNight: "night", ArmVacation: "vacation", ArmCustomBypass: "bypass",
+		Disarm: "off", Trigger: "panic",
+	}
+
+	image := &platform.Image{
+		Image:         mqtt.NewValue("image", mqtt.BytesMarshaler),
+		ImageEncoding: platform.CameraImageEncodingBase64,
+		ContentType:   "image/png",
+	}
+	imageURL := &platform.Image{
+		URL:              mqtt.NewValue("url", mqtt.StringMarshaler),
+		URLValueTemplate: "{{ value_json.url }}",
+	}
+
+	event := newEvent()
+	event.ValueTemplate = "{{ value_json }}"
+
+	tracker := newDeviceTracker()
+	tracker.ValueTemplate = "{{ value_json.state }}"
+	tracker.LocationTemplate = "{{ value_json.location | to_json }}"
+
+	trigger := newDeviceTrigger()
+	trigger.Payload = "1_single"
+	trigger.ValueTemplate = "{{ value_json.action }}"
+
+	update := newUpdate()
+	update.ValueTemplate = "{{ value_json | to_json }}"
+	update.LatestVersion = mqtt.NewValue("latest", mqtt.StringMarshaler)
+	update.LatestVersionTemplate = "{{ value_json.version }}"
+	update.PayloadInstall = "install"
+	update.Title = "Firmware"
+	update.ReleaseSummary = "Bug fixes"
+	update.ReleaseURL = "https://example.com/releases"
+	update.DisplayPrecision = 1
+
+	scene := newScene()
+	scene.PayloadOn = "movie"
+
+	notify := newNotify()
+	notify.CommandTemplate = `{"text": "{{ value }}"}`
+
+	tag := newTag()
+	tag.ValueTemplate = "{{ value_json.id }}"
+
+	templateLight := newTemplateLight()
+	templateLight.Optimistic = true
+	templateLight.StateTemplate = "{{ value_json.state }}"
+	templateLight.BrightnessTemplate = "{{ value_json.brightness }}"
+	templateLight.RedTemplate = "{{ value_json.color.r }}"
+	templateLight.GreenTemplate = "{{ value_json.color.g }}"
+	templateLight.BlueTemplate = "{{ value_json.color.b }}"
+	templateLight.ColorTemperatureTemplate = "{{ value_json.color_temp }}"
+	templateLight.EffectTemplate = "{{ value_json.effect }}"
+	templateLight.ColorTemperatureInKelvin = true
+	templateLight.MaxKelvin, templateLight.MinKelvin = 6500, 2700
+	templateLight.MaxMireds, templateLight.MinMireds = 370, 153
+	templateLight.PossibleEffects = []string{"rainbow"}
+
+	for _, p := range []hqtt.Platform{
+		light, sensor, binarySensor, sw, cover, climate, fan, lock, number, sel, button, text, siren, valve, positionValve,
+		vacuum, lawnMower, humidifier, waterHeater, alarm, newCamera(), image, imageURL, event, tracker, trigger,
+		update, scene, notify, tag, templateLight,
+	} {
+		t.Run(p.PlatformName(), func(t *testing.T) {
+			var buf bytes.Buffer
+			e := jsontext.NewEncoder(&buf)
+			require.NoError(t, e.WriteToken(jsontext.BeginObject))
+			require.NoError(t, p.MarshalDiscoveryTo(e, "prefix"))
+			require.NoError(t, e.WriteToken(jsontext.EndObject))
+
+			var fields map[string]jsontext.Value
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+			require.NotEmpty(t, fields)
+
+			// Fields missing from discovery/fields.yaml would not be regenerated with the rest of the constants
+			for key := range fields {
+				assert.Contains(t, discovery.PlatformFields[p.PlatformName()], key)
+			}
+		})
+	}
+}
+
+func TestPlatform_InvalidTemplate(t *testing.T) {
+	sensor := &platform.Sensor[string, any]{
+		State:         mqtt.NewValue("state", mqtt.StringMarshaler),
+		ValueTemplate: "{{ value_json.state | frobnicate }}",
+	}
+
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+	require.ErrorIs(t, sensor.MarshalDiscoveryTo(e, "prefix"), hass.ErrInvalidTemplate)
+}
+
+// discoveryMarshaler is implemented by every platform, see hqtt.Platform.
+type discoveryMarshaler interface {
+	MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error
+}
+
+// marshalDiscovery encodes the discovery fields of the provided platform under prefix as a standalone JSON object.
+func marshalDiscovery(t *testing.T, p discoveryMarshaler, prefix string) ([]byte, error) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+	if err := p.MarshalDiscoveryTo(e, prefix); err != nil {
+		return nil, err
+	}
+
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	return buf.Bytes(), nil
+}
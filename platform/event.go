@@ -0,0 +1,122 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Device classes for Event. See https://www.home-assistant.io/integrations/event/#device-class.
+const (
+	EventDeviceClassButton   = "button"
+	EventDeviceClassDoorbell = "doorbell"
+	EventDeviceClassMotion   = "motion"
+)
+
+// ErrEventAttributesNotObject is returned when marshaling an EventMessage whose attributes are not encoded as a json
+// object, since Home Assistant expects them next to the event type.
+var ErrEventAttributesNotObject = errors.New("event attributes must be a json object")
+
+// EventMessage is the payload published to Event.State. It is encoded as a json object with the event type in the
+// event_type field, and the fields of Attributes next to it. Attributes must not have an event_type field.
+type EventMessage[TAttributes any] struct {
+	EventType  string
+	Attributes TAttributes
+}
+
+func (m EventMessage[TAttributes]) MarshalJSONTo(e *jsontext.Encoder) error {
+	attributes, err := json.Marshal(m.Attributes)
+	if err != nil {
+		return err
+	}
+
+	if err = errors.Join(
+		e.WriteToken(jsontext.BeginObject),
+		e.WriteToken(jsontext.String("event_type")),
+		e.WriteToken(jsontext.String(m.EventType)),
+	); err != nil {
+		return err
+	}
+
+	// Attributes without any fields (like nil maps or struct{}) are omitted
+	if attributes := jsontext.Value(attributes); attributes.Kind() != 'n' {
+		if attributes.Kind() != '{' {
+			return fmt.Errorf("%w: got %s", ErrEventAttributesNotObject, attributes.Kind())
+		}
+
+		// Copy the members of the attributes object, skipping its delimiters
+		d := jsontext.NewDecoder(bytes.NewReader(attributes))
+		if _, err = d.ReadToken(); err != nil {
+			return err
+		}
+
+		for d.PeekKind() != '}' {
+			member, err := d.ReadValue()
+			if err != nil {
+				return err
+			}
+
+			if err = e.WriteValue(member); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.WriteToken(jsontext.EndObject)
+}
+
+// Event is a hqtt.Platform that implements the event.mqtt integration for Home Assistant. Events are stateless, use
+// Trigger to notify Home Assistant that one of the EventTypes occurred.
+//
+// See https://www.home-assistant.io/integrations/event.mqtt/
+type Event[TAttributes any] struct {
+	// The type/class of the event to set the icon in the frontend, see EventDeviceClassButton,
+	// EventDeviceClassDoorbell, and EventDeviceClassMotion.
+	DeviceClass string
+
+	// The event types this entity can trigger
+	EventTypes []string `hqtt:"required"`
+
+	// Events are published to this value, see NewEventValue
+	State *mqtt.Value[EventMessage[TAttributes]] `hqtt:"required"`
+	// Extracts the event from messages published to State, for devices that publish events in a format Home Assistant
+	// does not expect
+	ValueTemplate hass.Template
+}
+
+// NewEventValue constructs a mqtt.Value that publishes EventMessage values as json.
+func NewEventValue[TAttributes any](topic string) *mqtt.Value[EventMessage[TAttributes]] {
+	return mqtt.NewValue(topic, mqtt.JsonValueMarshaler[EventMessage[TAttributes]]())
+}
+
+func (ev *Event[TAttributes]) PlatformName() string {
+	return "event"
+}
+
+func (ev *Event[TAttributes]) Subscriptions(_ string) []mqtt.Subscription {
+	return nil
+}
+
+func (ev *Event[TAttributes]) ServeMQTT(_ mqtt.Writer, _ string, _ []byte) {}
+
+// Trigger publishes an event of the provided type with the provided attributes to State. The event type should be one
+// of EventTypes, Home Assistant ignores other events.
+func (ev *Event[TAttributes]) Trigger(ctx context.Context, w mqtt.Writer, prefix, eventType string, attributes TAttributes) error {
+	return mqtt.Error(ev.State.Write(ctx, w, prefix, EventMessage[TAttributes]{EventType: eventType, Attributes: attributes}))
+}
+
+func (ev *Event[TAttributes]) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, ev.DeviceClass),
+		discovery.MarshalStdSlice("event types", e, discovery.FieldEventTypes, ev.EventTypes),
+		discovery.MarshalRequiredValueTopic("state", e, discovery.FieldStateTopic, ev.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, ev.ValueTemplate),
+	)
+}
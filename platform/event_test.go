@@ -0,0 +1,75 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/platform"
+)
+
+type doorbellAttributes struct {
+	Button   string  `json:"button"`
+	Duration float64 `json:"duration,omitzero"`
+}
+
+func newEvent() *platform.Event[doorbellAttributes] {
+	return &platform.Event[doorbellAttributes]{
+		DeviceClass: platform.EventDeviceClassDoorbell,
+		EventTypes:  []string{"press", "hold"},
+		State:       platform.NewEventValue[doorbellAttributes]("event"),
+	}
+}
+
+func TestEvent_Trigger(t *testing.T) {
+	t.Run("Attributes", func(t *testing.T) {
+		sut := newEvent()
+
+		w := &hqtttest.Writer{}
+		require.NoError(t, sut.Trigger(t.Context(), w, "doorbell", "hold", doorbellAttributes{Button: "front", Duration: 1.5}))
+		w.AssertPublished(t, "doorbell/event", []byte(`{"event_type":"hold","button":"front","duration":1.5}`))
+	})
+
+	t.Run("No Attributes", func(t *testing.T) {
+		sut := &platform.Event[map[string]any]{State: platform.NewEventValue[map[string]any]("event")}
+
+		w := &hqtttest.Writer{}
+		require.NoError(t, sut.Trigger(t.Context(), w, "doorbell", "press", nil))
+		w.AssertPublished(t, "doorbell/event", []byte(`{"event_type":"press"}`))
+	})
+
+	t.Run("Attributes Not An Object", func(t *testing.T) {
+		sut := &platform.Event[[]string]{State: platform.NewEventValue[[]string]("event")}
+
+		w := &hqtttest.Writer{}
+		require.ErrorIs(t, sut.Trigger(t.Context(), w, "doorbell", "press", []string{"front"}), platform.ErrEventAttributesNotObject)
+		w.AssertNotPublished(t, "doorbell/event")
+	})
+}
+
+func TestEvent_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Event Types Required", func(t *testing.T) {
+		sut := newEvent()
+		sut.EventTypes = nil
+
+		_, err := marshalDiscovery(t, sut, "doorbell")
+		require.Error(t, err)
+	})
+
+	t.Run("State Required", func(t *testing.T) {
+		sut := newEvent()
+		sut.State = nil
+
+		_, err := marshalDiscovery(t, sut, "doorbell")
+		require.Error(t, err)
+	})
+
+	payload, err := marshalDiscovery(t, newEvent(), "doorbell")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "device_class", "doorbell")
+	hqtttest.RequireField(t, payload, "event_types", []string{"press", "hold"})
+	hqtttest.RequireField(t, payload, "state_topic", "doorbell/event")
+	hqtttest.RequireNoField(t, payload, "value_template")
+}
@@ -0,0 +1,187 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Fan is a hqtt.Platform that implements the fan.mqtt integration for Home Assistant.
+//
+// See https://www.home-assistant.io/integrations/fan.mqtt/
+type Fan struct {
+	// Flag that defines if the fan works in optimistic mode. When set, commands received from Home Assistant are
+	// automatically written to the corresponding state value.
+	Optimistic bool
+
+	// The current state of the Fan
+	State *mqtt.Value[hass.PowerState]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	StateValueTemplate hass.Template
+	// Home Assistant will write commands for this entity to this value
+	Command *mqtt.RemoteValue[hass.PowerState] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The command is available as value.
+	CommandTemplate hass.Template
+
+	// Custom values to use for payload commands
+	CustomPowerStateValues hass.CustomPowerState
+
+	// The current speed of the fan, between SpeedRangeMin and SpeedRangeMax
+	Percentage *mqtt.Value[uint]
+	// Extracts the speed from messages published to Percentage
+	PercentageValueTemplate hass.Template
+	// Home Assistant will write the desired speed to this value
+	PercentageCommand *mqtt.RemoteValue[uint]
+	// Renders the payload Home Assistant writes to PercentageCommand. The speed is available as value.
+	PercentageCommandTemplate hass.Template
+	// The speed that represents the lowest speed of the fan. Defaults to 1 if not set.
+	SpeedRangeMin uint
+	// The speed that represents the highest speed of the fan. Defaults to 100 if not set.
+	SpeedRangeMax uint
+
+	// The current preset mode
+	PresetMode *mqtt.Value[string]
+	// Extracts the preset mode from messages published to PresetMode
+	PresetModeValueTemplate hass.Template
+	// Home Assistant will write the desired preset mode to this value
+	PresetModeCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to PresetModeCommand. The preset mode is available as value.
+	PresetModeCommandTemplate hass.Template
+	// The list of preset modes this fan supports
+	PresetModes []string
+
+	// Whether the fan is currently oscillating
+	Oscillation *mqtt.Value[hass.Oscillation]
+	// Extracts the oscillation from messages published to Oscillation
+	OscillationValueTemplate hass.Template
+	// Home Assistant will write the desired oscillation to this value
+	OscillationCommand *mqtt.RemoteValue[hass.Oscillation]
+	// Renders the payload Home Assistant writes to OscillationCommand. The oscillation is available as value.
+	OscillationCommandTemplate hass.Template
+	// Custom values to use for oscillation payloads
+	CustomOscillationValues hass.CustomOscillation
+
+	// The current direction of the fan
+	Direction *mqtt.Value[hass.FanDirection]
+	// Extracts the direction from messages published to Direction
+	DirectionValueTemplate hass.Template
+	// Home Assistant will write the desired direction to this value
+	DirectionCommand *mqtt.RemoteValue[hass.FanDirection]
+	// Renders the payload Home Assistant writes to DirectionCommand. The direction is available as value.
+	DirectionCommandTemplate hass.Template
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+	echoes     map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error
+}
+
+func (f *Fan) PlatformName() string {
+	return "fan"
+}
+
+func (f *Fan) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+
+	result = f.Command.AppendSubscribeOptions(result, prefix)
+	result = f.PercentageCommand.AppendSubscribeOptions(result, prefix)
+	result = f.PresetModeCommand.AppendSubscribeOptions(result, prefix)
+	result = f.OscillationCommand.AppendSubscribeOptions(result, prefix)
+	result = f.DirectionCommand.AppendSubscribeOptions(result, prefix)
+
+	return result
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by routing it to the command value with a
+// matching topic. It is up to the user to ensure each configured mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
+func (f *Fan) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	f.routing()
+	f.routes.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the command received on the specified topic to the
+// corresponding state value if Optimistic is set.
+func (f *Fan) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !f.Optimistic {
+		return nil
+	}
+
+	f.routing()
+	if echo, ok := f.echoes[topic]; ok {
+		return echo(ctx, w, prefix)
+	}
+
+	return nil
+}
+
+// routing builds the tables used by ServeMQTT and EchoCommand to dispatch commands with a single lookup.
+func (f *Fan) routing() {
+	f.routesOnce.Do(func() {
+		f.routes = mqtt.Routes{}
+		f.echoes = map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error{}
+
+		addFanRoute(f, f.Command, f.State)
+		addFanRoute(f, f.PercentageCommand, f.Percentage)
+		addFanRoute(f, f.PresetModeCommand, f.PresetMode)
+		addFanRoute(f, f.OscillationCommand, f.Oscillation)
+		addFanRoute(f, f.DirectionCommand, f.Direction)
+	})
+}
+
+// addFanRoute routes the provided command to its RemoteValue, echoing it to the provided state in optimistic mode.
+func addFanRoute[T any](f *Fan, command *mqtt.RemoteValue[T], state *mqtt.Value[T]) {
+	if !command.AddRoute(f.routes) || state == nil {
+		return
+	}
+
+	f.echoes[command.FullyQualifiedTopic("")] = func(ctx context.Context, w mqtt.Writer, prefix string) error {
+		return mqtt.Echo(ctx, w, prefix, command, state)
+	}
+}
+
+func (f *Fan) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, f.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, f.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateValueTemplate, f.StateValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, f.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, f.CommandTemplate),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOn, f.CustomPowerStateValues.On),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOff, f.CustomPowerStateValues.Off),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldPercentageStateTopic, f.Percentage, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPercentageValueTemplate, f.PercentageValueTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldPercentageCommandTopic, f.PercentageCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPercentageCommandTemplate, f.PercentageCommandTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldSpeedRangeMin, f.SpeedRangeMin),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldSpeedRangeMax, f.SpeedRangeMax),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldPresetModeStateTopic, f.PresetMode, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPresetModeValueTemplate, f.PresetModeValueTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldPresetModeCommandTopic, f.PresetModeCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPresetModeCommandTemplate, f.PresetModeCommandTemplate),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldPresetModes, f.PresetModes),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldOscillationStateTopic, f.Oscillation, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOscillationValueTemplate, f.OscillationValueTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldOscillationCommandTopic, f.OscillationCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOscillationCommandTemplate, f.OscillationCommandTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOscillationOn, f.CustomOscillationValues.On),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOscillationOff, f.CustomOscillationValues.Off),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldDirectionStateTopic, f.Direction, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDirectionValueTemplate, f.DirectionValueTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldDirectionCommandTopic, f.DirectionCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDirectionCommandTemplate, f.DirectionCommandTemplate),
+	)
+}
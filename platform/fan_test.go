@@ -0,0 +1,97 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newFan() *platform.Fan {
+	return &platform.Fan{
+		State:              mqtt.NewValue("state", hass.PowerStateMarshaler),
+		Command:            mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+		Percentage:         mqtt.NewValue("percentage", mqtt.UintMarshaler),
+		PercentageCommand:  mqtt.NewRemoteValue("percentage/set", mqtt.UintUnmarshaler),
+		PresetMode:         mqtt.NewValue("preset", mqtt.StringMarshaler),
+		PresetModeCommand:  mqtt.NewRemoteValue("preset/set", mqtt.StringUnmarshaler),
+		Oscillation:        mqtt.NewValue("oscillation", hass.OscillationMarshaler),
+		OscillationCommand: mqtt.NewRemoteValue("oscillation/set", hass.OscillationUnmarshaler),
+		Direction:          mqtt.NewValue("direction", hass.FanDirectionMarshaler),
+		DirectionCommand:   mqtt.NewRemoteValue("direction/set", hass.FanDirectionUnmarshaler),
+	}
+}
+
+func TestFan_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newFan(), "fan")
+}
+
+func TestFan_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newFan()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		for _, tt := range []struct {
+			command string
+			state   string
+			payload string
+		}{
+			{command: "command", state: "fan/state", payload: "ON"},
+			{command: "percentage/set", state: "fan/percentage", payload: "66"},
+			{command: "preset/set", state: "fan/preset", payload: "breeze"},
+			{command: "oscillation/set", state: "fan/oscillation", payload: "oscillate_on"},
+			{command: "direction/set", state: "fan/direction", payload: "reverse"},
+		} {
+			sut.ServeMQTT(w, tt.command, []byte(tt.payload))
+			require.NoError(t, sut.EchoCommand(t.Context(), w, "fan", tt.command))
+			w.AssertPublished(t, tt.state, []byte(tt.payload))
+		}
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newFan()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "percentage/set", []byte("66"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "fan", "percentage/set"))
+		w.AssertNotPublished(t, "fan/percentage")
+	})
+}
+
+func TestFan_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Fan{}, "fan")
+		require.Error(t, err)
+	})
+
+	sut := newFan()
+	sut.SpeedRangeMin, sut.SpeedRangeMax = 1, 3
+	sut.PresetModes = []string{"breeze", "sleep"}
+	sut.CustomOscillationValues = hass.CustomOscillation{On: "1", Off: "0"}
+
+	payload, err := marshalDiscovery(t, sut, "fan")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "fan/state")
+	hqtttest.RequireField(t, payload, "command_topic", "fan/command")
+	hqtttest.RequireField(t, payload, "percentage_state_topic", "fan/percentage")
+	hqtttest.RequireField(t, payload, "percentage_command_topic", "fan/percentage/set")
+	hqtttest.RequireField(t, payload, "speed_range_min", 1)
+	hqtttest.RequireField(t, payload, "speed_range_max", 3)
+	hqtttest.RequireField(t, payload, "preset_mode_state_topic", "fan/preset")
+	hqtttest.RequireField(t, payload, "preset_mode_command_topic", "fan/preset/set")
+	hqtttest.RequireField(t, payload, "preset_modes", []string{"breeze", "sleep"})
+	hqtttest.RequireField(t, payload, "oscillation_state_topic", "fan/oscillation")
+	hqtttest.RequireField(t, payload, "oscillation_command_topic", "fan/oscillation/set")
+	hqtttest.RequireField(t, payload, "payload_oscillation_on", "1")
+	hqtttest.RequireField(t, payload, "payload_oscillation_off", "0")
+	hqtttest.RequireField(t, payload, "direction_state_topic", "fan/direction")
+	hqtttest.RequireField(t, payload, "direction_command_topic", "fan/direction/set")
+	hqtttest.RequireNoField(t, payload, "payload_on")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
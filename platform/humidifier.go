@@ -0,0 +1,179 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Device classes for Humidifier. See https://www.home-assistant.io/integrations/humidifier.mqtt/#device_class.
+const (
+	HumidifierDeviceClassHumidifier   = "humidifier"
+	HumidifierDeviceClassDehumidifier = "dehumidifier"
+)
+
+// Humidifier is a hqtt.Platform that implements the humidifier.mqtt integration for Home Assistant.
+//
+// See https://www.home-assistant.io/integrations/humidifier.mqtt/
+type Humidifier struct {
+	// Flag that defines if the humidifier works in optimistic mode. When set, commands received from Home Assistant
+	// are automatically written to the corresponding state value.
+	Optimistic bool
+
+	// The type/class of the humidifier, see HumidifierDeviceClassHumidifier and HumidifierDeviceClassDehumidifier.
+	// Home Assistant assumes HumidifierDeviceClassHumidifier if not set.
+	DeviceClass string
+
+	// What the humidifier is currently doing
+	Action *mqtt.Value[hass.HumidifierAction]
+	// Extracts the action from messages published to Action
+	ActionValueTemplate hass.Template
+
+	// The current state of the humidifier
+	State *mqtt.Value[hass.PowerState]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	StateValueTemplate hass.Template
+	// Home Assistant will write commands for this entity to this value
+	Command *mqtt.RemoteValue[hass.PowerState] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The command is available as value.
+	CommandTemplate hass.Template
+
+	// Custom values to use for payload commands
+	CustomPowerStateValues hass.CustomPowerState
+
+	// The current humidity measured by the humidifier
+	CurrentHumidity *mqtt.Value[float64]
+	// Extracts the humidity from messages published to CurrentHumidity
+	CurrentHumidityValueTemplate hass.Template
+
+	// The target humidity
+	TargetHumidity *mqtt.Value[float64]
+	// Extracts the target humidity from messages published to TargetHumidity
+	TargetHumidityValueTemplate hass.Template
+	// Home Assistant will write the desired target humidity to this value
+	TargetHumidityCommand *mqtt.RemoteValue[float64] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to TargetHumidityCommand. The humidity is available as value.
+	TargetHumidityCommandTemplate hass.Template
+	// The minimum target humidity. Home Assistant uses 0 if not set.
+	MinHumidity *float64
+	// The maximum target humidity. Home Assistant uses 100 if not set.
+	MaxHumidity *float64
+
+	// The current mode of the humidifier
+	Mode *mqtt.Value[string]
+	// Extracts the mode from messages published to Mode
+	ModeValueTemplate hass.Template
+	// Home Assistant will write the desired mode to this value
+	ModeCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to ModeCommand. The mode is available as value.
+	ModeCommandTemplate hass.Template
+	// The list of modes this humidifier supports
+	Modes []string
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+	echoes     map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error
+}
+
+func (h *Humidifier) PlatformName() string {
+	return "humidifier"
+}
+
+func (h *Humidifier) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+
+	result = h.Command.AppendSubscribeOptions(result, prefix)
+	result = h.TargetHumidityCommand.AppendSubscribeOptions(result, prefix)
+	result = h.ModeCommand.AppendSubscribeOptions(result, prefix)
+
+	return result
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by routing it to the command value with a
+// matching topic. It is up to the user to ensure each configured mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
+func (h *Humidifier) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	h.routing()
+	h.routes.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the command received on the specified topic to the
+// corresponding state value if Optimistic is set.
+func (h *Humidifier) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !h.Optimistic {
+		return nil
+	}
+
+	h.routing()
+	if echo, ok := h.echoes[topic]; ok {
+		return echo(ctx, w, prefix)
+	}
+
+	return nil
+}
+
+// routing builds the tables used by ServeMQTT and EchoCommand to dispatch commands with a single lookup.
+func (h *Humidifier) routing() {
+	h.routesOnce.Do(func() {
+		h.routes = mqtt.Routes{}
+		h.echoes = map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error{}
+
+		addHumidifierRoute(h, h.Command, h.State)
+		addHumidifierRoute(h, h.TargetHumidityCommand, h.TargetHumidity)
+		addHumidifierRoute(h, h.ModeCommand, h.Mode)
+	})
+}
+
+// addHumidifierRoute routes the provided command to its RemoteValue, echoing it to the provided state in optimistic
+// mode.
+func addHumidifierRoute[T any](h *Humidifier, command *mqtt.RemoteValue[T], state *mqtt.Value[T]) {
+	if !command.AddRoute(h.routes) || state == nil {
+		return
+	}
+
+	h.echoes[command.FullyQualifiedTopic("")] = func(ctx context.Context, w mqtt.Writer, prefix string) error {
+		return mqtt.Echo(ctx, w, prefix, command, state)
+	}
+}
+
+func (h *Humidifier) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, h.Optimistic),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, h.DeviceClass),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldActionTopic, h.Action, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldActionTemplate, h.ActionValueTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, h.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateValueTemplate, h.StateValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, h.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, h.CommandTemplate),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOn, h.CustomPowerStateValues.On),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOff, h.CustomPowerStateValues.Off),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldCurrentHumidityTopic, h.CurrentHumidity, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCurrentHumidityTemplate, h.CurrentHumidityValueTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldTargetHumidityStateTopic, h.TargetHumidity, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTargetHumidityStateTemplate, h.TargetHumidityValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("target humidity command", e, discovery.FieldTargetHumidityCommandTopic, h.TargetHumidityCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTargetHumidityCommandTemplate, h.TargetHumidityCommandTemplate),
+		discovery.MaybeMarshalStd(e, discovery.FieldMinHumidity, h.MinHumidity),
+		discovery.MaybeMarshalStd(e, discovery.FieldMaxHumidity, h.MaxHumidity),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldModeStateTopic, h.Mode, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldModeStateTemplate, h.ModeValueTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldModeCommandTopic, h.ModeCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldModeCommandTemplate, h.ModeCommandTemplate),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldModes, h.Modes),
+	)
+}
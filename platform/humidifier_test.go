@@ -0,0 +1,102 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newHumidifier() *platform.Humidifier {
+	return &platform.Humidifier{
+		Action:                mqtt.NewValue("action", hass.HumidifierActionMarshaler),
+		State:                 mqtt.NewValue("state", hass.PowerStateMarshaler),
+		Command:               mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+		CurrentHumidity:       mqtt.NewValue("humidity/current", mqtt.FloatMarshaler),
+		TargetHumidity:        mqtt.NewValue("humidity", mqtt.FloatMarshaler),
+		TargetHumidityCommand: mqtt.NewRemoteValue("humidity/set", mqtt.FloatUnmarshaler),
+		Mode:                  mqtt.NewValue("mode", mqtt.StringMarshaler),
+		ModeCommand:           mqtt.NewRemoteValue("mode/set", mqtt.StringUnmarshaler),
+	}
+}
+
+func TestHumidifier_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newHumidifier(), "humidifier")
+}
+
+func TestHumidifier_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newHumidifier()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		for _, tt := range []struct {
+			command string
+			state   string
+			payload string
+		}{
+			{command: "command", state: "humidifier/state", payload: "ON"},
+			{command: "humidity/set", state: "humidifier/humidity", payload: "45"},
+			{command: "mode/set", state: "humidifier/mode", payload: "eco"},
+		} {
+			sut.ServeMQTT(w, tt.command, []byte(tt.payload))
+			require.NoError(t, sut.EchoCommand(t.Context(), w, "humidifier", tt.command))
+			w.AssertPublished(t, tt.state, []byte(tt.payload))
+		}
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newHumidifier()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "humidity/set", []byte("45"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "humidifier", "humidity/set"))
+		w.AssertNotPublished(t, "humidifier/humidity")
+	})
+}
+
+func TestHumidifier_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		sut := newHumidifier()
+		sut.Command = nil
+
+		_, err := marshalDiscovery(t, sut, "humidifier")
+		require.Error(t, err)
+	})
+
+	t.Run("Target Humidity Command Required", func(t *testing.T) {
+		sut := newHumidifier()
+		sut.TargetHumidityCommand = nil
+
+		_, err := marshalDiscovery(t, sut, "humidifier")
+		require.Error(t, err)
+	})
+
+	minHumidity, maxHumidity := 0.0, 80.0
+	sut := newHumidifier()
+	sut.DeviceClass = platform.HumidifierDeviceClassDehumidifier
+	sut.MinHumidity, sut.MaxHumidity = &minHumidity, &maxHumidity
+	sut.Modes = []string{"eco", "boost"}
+
+	payload, err := marshalDiscovery(t, sut, "humidifier")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "device_class", "dehumidifier")
+	hqtttest.RequireField(t, payload, "action_topic", "humidifier/action")
+	hqtttest.RequireField(t, payload, "state_topic", "humidifier/state")
+	hqtttest.RequireField(t, payload, "command_topic", "humidifier/command")
+	hqtttest.RequireField(t, payload, "current_humidity_topic", "humidifier/humidity/current")
+	hqtttest.RequireField(t, payload, "target_humidity_state_topic", "humidifier/humidity")
+	hqtttest.RequireField(t, payload, "target_humidity_command_topic", "humidifier/humidity/set")
+	hqtttest.RequireField(t, payload, "min_humidity", 0)
+	hqtttest.RequireField(t, payload, "max_humidity", 80)
+	hqtttest.RequireField(t, payload, "mode_state_topic", "humidifier/mode")
+	hqtttest.RequireField(t, payload, "mode_command_topic", "humidifier/mode/set")
+	hqtttest.RequireField(t, payload, "modes", []string{"eco", "boost"})
+	hqtttest.RequireNoField(t, payload, "optimistic")
+	hqtttest.RequireNoField(t, payload, "payload_on")
+}
@@ -0,0 +1,53 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Image is a hqtt.Platform that implements the image.mqtt integration for Home Assistant. Exactly one of Image and URL
+// must be configured: either publish the image itself to Image, or publish the URL Home Assistant should download the
+// image from to URL.
+//
+// See https://www.home-assistant.io/integrations/image.mqtt/
+type Image struct {
+	// The latest image, published as-is with mqtt.BytesMarshaler or base64 encoded with mqtt.Base64Marshaler
+	Image *mqtt.Value[[]byte]
+	// How images published to Image are encoded, see CameraImageEncodingBase64. If not set, Home Assistant expects raw
+	// image bytes.
+	ImageEncoding string
+	// The content type of images published to Image. Home Assistant uses image/jpeg if not set.
+	ContentType string
+
+	// The URL of the latest image
+	URL *mqtt.Value[string]
+	// Extracts the URL from messages published to URL
+	URLValueTemplate hass.Template
+}
+
+func (i *Image) PlatformName() string {
+	return "image"
+}
+
+func (i *Image) Subscriptions(_ string) []mqtt.Subscription {
+	return nil
+}
+
+func (i *Image) ServeMQTT(_ mqtt.Writer, _ string, _ []byte) {}
+
+func (i *Image) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalExclusiveTopic(
+			"image or url", e,
+			discovery.FieldImageTopic, i.Image.FullyQualifiedTopic(prefix),
+			discovery.FieldURLTopic, i.URL.FullyQualifiedTopic(prefix),
+		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldImageEncoding, i.ImageEncoding),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldContentType, i.ContentType),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldURLTemplate, i.URLValueTemplate),
+	)
+}
@@ -0,0 +1,54 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func TestImage_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Image or URL Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Image{}, "image")
+		require.ErrorIs(t, err, discovery.ErrTopicRequired)
+	})
+
+	t.Run("Image and URL Conflict", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Image{
+			Image: mqtt.NewValue("image", mqtt.BytesMarshaler),
+			URL:   mqtt.NewValue("url", mqtt.StringMarshaler),
+		}, "image")
+		require.ErrorIs(t, err, discovery.ErrConflictingTopics)
+	})
+
+	t.Run("Image", func(t *testing.T) {
+		payload, err := marshalDiscovery(t, &platform.Image{
+			Image:         mqtt.NewValue("image", mqtt.Base64Marshaler),
+			ImageEncoding: platform.CameraImageEncodingBase64,
+			ContentType:   "image/png",
+		}, "image")
+		require.NoError(t, err)
+
+		hqtttest.RequireField(t, payload, "image_topic", "image/image")
+		hqtttest.RequireField(t, payload, "image_encoding", "b64")
+		hqtttest.RequireField(t, payload, "content_type", "image/png")
+		hqtttest.RequireNoField(t, payload, "url_topic")
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		payload, err := marshalDiscovery(t, &platform.Image{
+			URL:              mqtt.NewValue("url", mqtt.StringMarshaler),
+			URLValueTemplate: "{{ value_json.url }}",
+		}, "image")
+		require.NoError(t, err)
+
+		hqtttest.RequireField(t, payload, "url_topic", "image/url")
+		hqtttest.RequireField(t, payload, "url_template", "{{ value_json.url }}")
+		hqtttest.RequireNoField(t, payload, "image_topic")
+		hqtttest.RequireNoField(t, payload, "content_type")
+	})
+}
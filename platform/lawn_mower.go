@@ -0,0 +1,118 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// LawnMower is a hqtt.Platform that implements the lawn_mower.mqtt integration for Home Assistant. Each command has
+// its own topic, use mqtt.RemoteValue.Watch to act on them. Unless a command template is configured, Home Assistant
+// writes start_mowing, pause, and dock to the command values.
+//
+// See https://www.home-assistant.io/integrations/lawn_mower.mqtt/
+type LawnMower struct {
+	// Flag that defines if the lawn mower works in optimistic mode. When set, the activity corresponding to commands
+	// received from Home Assistant is automatically written to Activity.
+	Optimistic bool
+
+	// What the lawn mower is currently doing
+	Activity *mqtt.Value[hass.LawnMowerActivity]
+	// Extracts the activity from messages published to Activity
+	ActivityValueTemplate hass.Template
+
+	// Home Assistant will write to this value when the lawn mower should start mowing
+	StartMowingCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to StartMowingCommand
+	StartMowingCommandTemplate hass.Template
+	// Home Assistant will write to this value when the lawn mower should pause
+	PauseCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to PauseCommand
+	PauseCommandTemplate hass.Template
+	// Home Assistant will write to this value when the lawn mower should return to its dock
+	DockCommand *mqtt.RemoteValue[string]
+	// Renders the payload Home Assistant writes to DockCommand
+	DockCommandTemplate hass.Template
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+	echoes     map[string]hass.LawnMowerActivity
+}
+
+func (l *LawnMower) PlatformName() string {
+	return "lawn_mower"
+}
+
+func (l *LawnMower) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+
+	result = l.StartMowingCommand.AppendSubscribeOptions(result, prefix)
+	result = l.PauseCommand.AppendSubscribeOptions(result, prefix)
+	result = l.DockCommand.AppendSubscribeOptions(result, prefix)
+
+	return result
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by routing it to the command value with a
+// matching topic. It is up to the user to ensure each configured mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
+func (l *LawnMower) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	l.routing()
+	l.routes.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the activity corresponding to the command received on the
+// specified topic to Activity if Optimistic is set. The command starts the lawn mower mowing, pauses it, or docks it.
+func (l *LawnMower) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !l.Optimistic || l.Activity == nil {
+		return nil
+	}
+
+	l.routing()
+	if activity, ok := l.echoes[topic]; ok {
+		return mqtt.Error(l.Activity.Write(ctx, w, prefix, activity))
+	}
+
+	return nil
+}
+
+// routing builds the tables used by ServeMQTT and EchoCommand to dispatch commands with a single lookup.
+func (l *LawnMower) routing() {
+	l.routesOnce.Do(func() {
+		l.routes = mqtt.Routes{}
+		l.echoes = map[string]hass.LawnMowerActivity{}
+
+		addLawnMowerRoute(l, l.StartMowingCommand, hass.LawnMowerActivityMowing)
+		addLawnMowerRoute(l, l.PauseCommand, hass.LawnMowerActivityPaused)
+		addLawnMowerRoute(l, l.DockCommand, hass.LawnMowerActivityDocked)
+	})
+}
+
+func addLawnMowerRoute(l *LawnMower, command *mqtt.RemoteValue[string], activity hass.LawnMowerActivity) {
+	if command.AddRoute(l.routes) {
+		l.echoes[command.FullyQualifiedTopic("")] = activity
+	}
+}
+
+func (l *LawnMower) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, l.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldActivityStateTopic, l.Activity, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldActivityValueTemplate, l.ActivityValueTemplate),
+
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldStartMowingCommandTopic, l.StartMowingCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStartMowingCommandTemplate, l.StartMowingCommandTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldPauseCommandTopic, l.PauseCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPauseCommandTemplate, l.PauseCommandTemplate),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldDockCommandTopic, l.DockCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDockCommandTemplate, l.DockCommandTemplate),
+	)
+}
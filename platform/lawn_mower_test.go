@@ -0,0 +1,78 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newLawnMower() *platform.LawnMower {
+	return &platform.LawnMower{
+		Activity:           mqtt.NewValue("activity", hass.LawnMowerActivityMarshaler),
+		StartMowingCommand: mqtt.NewRemoteValue("start_mowing", mqtt.StringUnmarshaler),
+		PauseCommand:       mqtt.NewRemoteValue("pause", mqtt.StringUnmarshaler),
+		DockCommand:        mqtt.NewRemoteValue("dock", mqtt.StringUnmarshaler),
+	}
+}
+
+func TestLawnMower_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newLawnMower(), "lawn_mower")
+}
+
+func TestLawnMower_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newLawnMower()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		for _, tt := range []struct {
+			command  string
+			activity string
+		}{
+			{command: "start_mowing", activity: "mowing"},
+			{command: "pause", activity: "paused"},
+			{command: "dock", activity: "docked"},
+		} {
+			sut.ServeMQTT(w, tt.command, []byte(tt.command))
+			require.NoError(t, sut.EchoCommand(t.Context(), w, "lawn_mower", tt.command))
+			w.AssertPublished(t, "lawn_mower/activity", []byte(tt.activity))
+		}
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newLawnMower()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "dock", []byte("dock"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "lawn_mower", "dock"))
+		w.AssertNotPublished(t, "lawn_mower/activity")
+	})
+}
+
+func TestLawnMower_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	sut := newLawnMower()
+	sut.DockCommandTemplate = `{"action": "{{ value }}"}`
+
+	require.NoError(t, sut.MarshalDiscoveryTo(e, "lawn_mower"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "activity_state_topic", "lawn_mower/activity")
+	hqtttest.RequireField(t, payload, "start_mowing_command_topic", "lawn_mower/start_mowing")
+	hqtttest.RequireField(t, payload, "pause_command_topic", "lawn_mower/pause")
+	hqtttest.RequireField(t, payload, "dock_command_topic", "lawn_mower/dock")
+	hqtttest.RequireField(t, payload, "dock_command_template", `{"action": "{{ value }}"}`)
+	hqtttest.RequireNoField(t, payload, "optimistic")
+	hqtttest.RequireNoField(t, payload, "start_mowing_command_template")
+}
@@ -1,12 +1,14 @@
 package platform
 
 import (
+	"context"
 	"encoding/json/jsontext"
 	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/nlowe/hqtt/discovery"
 	"github.com/nlowe/hqtt/hass"
@@ -41,6 +43,11 @@ func (h HueSat) LogValue() slog.Value {
 	)
 }
 
+// DescribePayload implements mqtt.PayloadDescriber. Home Assistant encodes hue and saturation as "h,s".
+func (h HueSat) DescribePayload() *mqtt.PayloadSchema {
+	return &mqtt.PayloadSchema{Type: "string", Pattern: commaSeparated(2, decimalPattern)}
+}
+
 // RGB holds 8-bit Red, Green, and Blue values for a Light. It implements fmt.Stringer and slog.LogValuer.
 type RGB struct {
 	R, G, B uint8
@@ -59,6 +66,11 @@ func (r RGB) LogValue() slog.Value {
 	)
 }
 
+// DescribePayload implements mqtt.PayloadDescriber. Home Assistant encodes RGB colors as "r,g,b", see RGBMarshaler.
+func (r RGB) DescribePayload() *mqtt.PayloadSchema {
+	return &mqtt.PayloadSchema{Type: "string", Pattern: commaSeparated(3, uintPattern)}
+}
+
 var (
 	RGBMarshaler mqtt.ValueMarshaler[RGB] = func(v RGB) ([]byte, error) {
 		return []byte(fmt.Sprintf("%d,%d,%d", v.R, v.G, v.B)), nil
@@ -97,6 +109,11 @@ func (r RGBW) LogValue() slog.Value {
 	)
 }
 
+// DescribePayload implements mqtt.PayloadDescriber. Home Assistant encodes RGBW colors as "r,g,b,w".
+func (r RGBW) DescribePayload() *mqtt.PayloadSchema {
+	return &mqtt.PayloadSchema{Type: "string", Pattern: commaSeparated(4, uintPattern)}
+}
+
 // RGBWW holds an additional 8-bit White value in addition to RGBW values for a Light. It implements fmt.Stringer and
 // slog.LogValuer.
 type RGBWW struct {
@@ -119,6 +136,11 @@ func (r RGBWW) LogValue() slog.Value {
 	)
 }
 
+// DescribePayload implements mqtt.PayloadDescriber. Home Assistant encodes RGBWW colors as "r,g,b,c,w".
+func (r RGBWW) DescribePayload() *mqtt.PayloadSchema {
+	return &mqtt.PayloadSchema{Type: "string", Pattern: commaSeparated(5, uintPattern)}
+}
+
 type XY struct {
 	X float64
 	Y float64
@@ -131,6 +153,21 @@ func (xy XY) LogValue() slog.Value {
 	)
 }
 
+// DescribePayload implements mqtt.PayloadDescriber. Home Assistant encodes XY colors as "x,y".
+func (xy XY) DescribePayload() *mqtt.PayloadSchema {
+	return &mqtt.PayloadSchema{Type: "string", Pattern: commaSeparated(2, decimalPattern)}
+}
+
+const (
+	uintPattern    = `\d+`
+	decimalPattern = `\d+(\.\d+)?`
+)
+
+// commaSeparated returns a regular expression matching n comma separated values matching pattern.
+func commaSeparated(n int, pattern string) string {
+	return "^" + strings.Repeat(pattern+",", n-1) + pattern + "$"
+}
+
 // Light is a hqtt.Platform that implements the light.mqtt integration for Home Assistant.
 //
 // See https://www.home-assistant.io/integrations/light.mqtt/
@@ -138,11 +175,15 @@ type Light struct {
 	// Defines when on the payload_on is sent.
 	OnCommandType LightOnCommandType
 
-	// Flag that defines if switch works in optimistic mode.
+	// Flag that defines if switch works in optimistic mode. When set, commands received from Home Assistant are
+	// automatically written to the corresponding state value.
 	Optimistic bool
 
 	// The current state of the Light
 	State *mqtt.Value[hass.PowerState]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	StateValueTemplate hass.Template
 	// Home Assistant will write commands for this entity to this value
 	Command *mqtt.RemoteValue[hass.PowerState] `hqtt:"required"`
 
@@ -153,6 +194,8 @@ type Light struct {
 	// Assistant according to the last received valid color or color temperature. The unit used is mireds, or if
 	// ColorTemperatureInKelvin is set to true, in Kelvin.
 	ColorMode *mqtt.Value[hass.ColorMode]
+	// Extracts the color mode from messages published to ColorMode
+	ColorModeValueTemplate hass.Template
 	// Home Assistant will write the desired color mode to this value
 	ColorModeCommand *mqtt.RemoteValue[hass.ColorMode]
 	// The color modes supported by this light
@@ -162,6 +205,10 @@ type Light struct {
 	Brightness *mqtt.Value[uint]
 	// Home Assistant will write desired brightness to this value
 	BrightnessCommand *mqtt.RemoteValue[uint]
+	// Extracts the brightness from messages published to Brightness
+	BrightnessValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to BrightnessCommand. The brightness is available as value.
+	BrightnessCommandTemplate hass.Template
 	// Defines the maximum brightness value (i.e., 100%). HomeAssistant will use 255 if not otherwise specified.
 	BrightnessScale uint
 
@@ -169,6 +216,10 @@ type Light struct {
 	ColorTemperature *mqtt.Value[uint]
 	// Home Assistant will write desired color temperature to this value
 	ColorTemperatureCommand *mqtt.RemoteValue[uint]
+	// Extracts the color temperature from messages published to ColorTemperature
+	ColorTemperatureValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to ColorTemperatureCommand. The color temperature is available as value.
+	ColorTemperatureCommandTemplate hass.Template
 	// Whether color temperature is in Kelvin (true) or mireds (false)
 	ColorTemperatureInKelvin bool
 	// The maximum color temperature in Kelvin. Defaults to 6535.
@@ -184,26 +235,48 @@ type Light struct {
 	HueSat *mqtt.Value[HueSat]
 	// Home Assistant will write the desired Hue and Saturation values to this value
 	HueSatCommand *mqtt.RemoteValue[HueSat]
+	// Extracts the Hue and Saturation values from messages published to HueSat
+	HueSatValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to HueSatCommand. The values are available as hue and sat.
+	HueSatCommandTemplate hass.Template
 
 	// The current XY values for this light
 	XY *mqtt.Value[XY]
 	// Home Assistant will write desired XY values to this value
 	XYCommand *mqtt.RemoteValue[XY]
+	// Extracts the XY values from messages published to XY
+	XYValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to XYCommand. The values are available as x and y.
+	XYCommandTemplate hass.Template
 
 	// The current RGB Value for this light
 	RGB *mqtt.Value[RGB]
 	// Home Assistant will write desired RGB values to this value
 	RGBCommand *mqtt.RemoteValue[RGB]
+	// Extracts the RGB values from messages published to RGB
+	RGBValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to RGBCommand. The values are available as red, green, and blue.
+	RGBCommandTemplate hass.Template
 
 	// The current RGBW Value for this light
 	RGBW *mqtt.Value[RGBW]
 	// Home Assistant will write desired RGBW values to this value
 	RGBWCommand *mqtt.RemoteValue[RGBW]
+	// Extracts the RGBW values from messages published to RGBW
+	RGBWValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to RGBWCommand. The values are available as red, green, blue, and
+	// white.
+	RGBWCommandTemplate hass.Template
 
 	// The current RGBWW Value for this light
 	RGBWW *mqtt.Value[RGBWW]
 	// Home Assistant will write desired RGBWW values to this value
 	RGBWWCommand *mqtt.RemoteValue[RGBWW]
+	// Extracts the RGBWW values from messages published to RGBWW
+	RGBWWValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to RGBWWCommand. The values are available as red, green, blue,
+	// cold_white, and warm_white.
+	RGBWWCommandTemplate hass.Template
 
 	// Home Assistant writes brightness values to this value when the light should operate in white mode.
 	WhiteBrightnessCommand *mqtt.RemoteValue[uint]
@@ -214,8 +287,16 @@ type Light struct {
 	Effect *mqtt.Value[string]
 	// Home Assistant will write the desired effect to this value
 	EffectCommand *mqtt.RemoteValue[string]
+	// Extracts the effect from messages published to Effect
+	EffectValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to EffectCommand. The effect is available as value.
+	EffectCommandTemplate hass.Template
 	// The list of possible effects this device supports
 	PossibleEffects []string
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+	echoes     map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error
 }
 
 func (l *Light) PlatformName() string {
@@ -243,32 +324,58 @@ func (l *Light) Subscriptions(prefix string) []mqtt.Subscription {
 // ServeMQTT handles the mqtt payload received on the specified topic suffix. It will route the payload to the first
 // non-nil mqtt.RemoveValue that has a matching topic for the light. It is up to the user to ensure each configured
 // mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
 func (l *Light) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
-	switch topic {
-	case l.Command.FullyQualifiedTopic(""):
-		l.Command.ServeMQTT(w, topic, payload)
-	case l.ColorModeCommand.FullyQualifiedTopic(""):
-		l.ColorModeCommand.ServeMQTT(w, topic, payload)
-	case l.BrightnessCommand.FullyQualifiedTopic(""):
-		l.BrightnessCommand.ServeMQTT(w, topic, payload)
-	case l.ColorTemperatureCommand.FullyQualifiedTopic(""):
-		l.ColorTemperatureCommand.ServeMQTT(w, topic, payload)
-	case l.HueSatCommand.FullyQualifiedTopic(""):
-		l.HueSatCommand.ServeMQTT(w, topic, payload)
-	case l.XYCommand.FullyQualifiedTopic(""):
-		l.XYCommand.ServeMQTT(w, topic, payload)
-	case l.RGBCommand.FullyQualifiedTopic(""):
-		l.RGBCommand.ServeMQTT(w, topic, payload)
-	case l.RGBWCommand.FullyQualifiedTopic(""):
-		l.RGBWCommand.ServeMQTT(w, topic, payload)
-	case l.RGBWWCommand.FullyQualifiedTopic(""):
-		l.RGBWWCommand.ServeMQTT(w, topic, payload)
-	case l.WhiteBrightnessCommand.FullyQualifiedTopic(""):
-		l.WhiteBrightnessCommand.ServeMQTT(w, topic, payload)
-	case l.EffectCommand.FullyQualifiedTopic(""):
-		l.EffectCommand.ServeMQTT(w, topic, payload)
-	default:
-		// TODO: Log?
+	l.routing()
+	l.routes.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the command received on the specified topic to the
+// corresponding state value if Optimistic is set.
+func (l *Light) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !l.Optimistic {
+		return nil
+	}
+
+	l.routing()
+	if echo, ok := l.echoes[topic]; ok {
+		return echo(ctx, w, prefix)
+	}
+
+	return nil
+}
+
+// routing builds the tables used by ServeMQTT and EchoCommand to dispatch commands with a single lookup.
+func (l *Light) routing() {
+	l.routesOnce.Do(func() {
+		l.routes = mqtt.Routes{}
+		l.echoes = map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error{}
+
+		addLightRoute(l, l.Command, l.State)
+		addLightRoute(l, l.ColorModeCommand, l.ColorMode)
+		addLightRoute(l, l.BrightnessCommand, l.Brightness)
+		addLightRoute(l, l.ColorTemperatureCommand, l.ColorTemperature)
+		addLightRoute(l, l.HueSatCommand, l.HueSat)
+		addLightRoute(l, l.XYCommand, l.XY)
+		addLightRoute(l, l.RGBCommand, l.RGB)
+		addLightRoute(l, l.RGBWCommand, l.RGBW)
+		addLightRoute(l, l.RGBWWCommand, l.RGBWW)
+		// White brightness has no corresponding state value to echo to
+		addLightRoute(l, l.WhiteBrightnessCommand, nil)
+		addLightRoute(l, l.EffectCommand, l.Effect)
+	})
+}
+
+// addLightRoute routes the provided command to its RemoteValue, echoing it to the provided state in optimistic mode.
+func addLightRoute[T any](l *Light, command *mqtt.RemoteValue[T], state *mqtt.Value[T]) {
+	if !command.AddRoute(l.routes) || state == nil {
+		return
+	}
+
+	l.echoes[command.FullyQualifiedTopic("")] = func(ctx context.Context, w mqtt.Writer, prefix string) error {
+		return mqtt.Echo(ctx, w, prefix, command, state)
 	}
 }
 
@@ -279,6 +386,7 @@ func (l *Light) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, l.Optimistic),
 
 		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, l.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateValueTemplate, l.StateValueTemplate),
 		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, l.Command, prefix),
 
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOn, l.CustomPowerStateValues.On),
@@ -290,6 +398,7 @@ func (l *Light) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 			discovery.FieldColorModeCommandTopic, l.ColorModeCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldColorModeValueTemplate, l.ColorModeValueTemplate),
 		discovery.MaybeMarshalStd(e, discovery.FieldSupportedColorModes, &l.SupportedColorModes),
 		discovery.MaybeMarshalStateAndCommandTopics(
 			"brightness", e,
@@ -297,6 +406,8 @@ func (l *Light) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 			discovery.FieldBrightnessCommandTopic, l.BrightnessCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldBrightnessValueTemplate, l.BrightnessValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldBrightnessCommandTemplate, l.BrightnessCommandTemplate),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldBrightnessScale, l.BrightnessScale),
 
 		discovery.MaybeMarshalStateAndCommandTopics(
@@ -305,6 +416,8 @@ func (l *Light) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 			discovery.FieldColorTemperatureCommandTopic, l.ColorTemperatureCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldColorTemperatureValueTemplate, l.ColorTemperatureValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldColorTemperatureCommandTemplate, l.ColorTemperatureCommandTemplate),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldColorTemperatureInKelvin, l.ColorTemperatureInKelvin),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldMaxKelvin, l.MaxKelvin),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldMinKelvin, l.MinKelvin),
@@ -313,17 +426,21 @@ func (l *Light) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 
 		discovery.MaybeMarshalStateAndCommandTopics(
 			"hue sat", e,
-			discovery.FieldHueSatStateTopic, l.ColorTemperature,
-			discovery.FieldHueSatCommandTopic, l.ColorTemperatureCommand,
+			discovery.FieldHueSatStateTopic, l.HueSat,
+			discovery.FieldHueSatCommandTopic, l.HueSatCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldHueSatValueTemplate, l.HueSatValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldHueSatCommandTemplate, l.HueSatCommandTemplate),
 
 		discovery.MaybeMarshalStateAndCommandTopics(
 			"xy", e,
-			discovery.FieldXYCommandTopic, l.XY,
-			discovery.FieldXYStateTopic, l.XYCommand,
+			discovery.FieldXYStateTopic, l.XY,
+			discovery.FieldXYCommandTopic, l.XYCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldXYValueTemplate, l.XYValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldXYCommandTemplate, l.XYCommandTemplate),
 
 		discovery.MaybeMarshalStateAndCommandTopics(
 			"rgb", e,
@@ -331,18 +448,24 @@ func (l *Light) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 			discovery.FieldRGBCommandTopic, l.RGBCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldRGBValueTemplate, l.RGBValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldRGBCommandTemplate, l.RGBCommandTemplate),
 		discovery.MaybeMarshalStateAndCommandTopics(
 			"rgbw", e,
 			discovery.FieldRGBWStateTopic, l.RGBW,
 			discovery.FieldRGBWCommandTopic, l.RGBWCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldRGBWValueTemplate, l.RGBWValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldRGBWCommandTemplate, l.RGBWCommandTemplate),
 		discovery.MaybeMarshalStateAndCommandTopics(
 			"rgbww", e,
 			discovery.FieldRGBWWStateTopic, l.RGBWW,
 			discovery.FieldRGBWWCommandTopic, l.RGBWWCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldRGBWWValueTemplate, l.RGBWWValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldRGBWWCommandTemplate, l.RGBWWCommandTemplate),
 
 		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldWhiteCommandTopic, l.WhiteBrightnessCommand, prefix),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldWhiteScale, l.WhiteScale),
@@ -353,6 +476,8 @@ func (l *Light) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 			discovery.FieldEffectCommandTopic, l.EffectCommand,
 			prefix,
 		),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldEffectValueTemplate, l.EffectValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldEffectCommandTemplate, l.EffectCommandTemplate),
 		discovery.MaybeMarshalStdSlice(e, discovery.FieldEffectList, l.PossibleEffects),
 	)
 }
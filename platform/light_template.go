@@ -0,0 +1,140 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// TemplateLight is a hqtt.Platform that implements the light.mqtt integration for Home Assistant using the template
+// schema. Unlike Light, which has a topic for each attribute, Home Assistant renders every command with
+// CommandOnTemplate or CommandOffTemplate and writes it to Command, and extracts every attribute from messages
+// published to State with the configured templates. TState is the type of the state published by the light, and
+// TCommand is the type of the command rendered by the templates.
+//
+// See https://www.home-assistant.io/integrations/light.mqtt/#template-schema
+type TemplateLight[TState, TCommand any] struct {
+	// Flag that defines if the light works in optimistic mode. Defaults to true if State is not configured. If set,
+	// commands received on Command are also written to State, see EchoState.
+	Optimistic bool
+	// Converts a command received on Command to the state written to State in optimistic mode, reporting false if the
+	// command should not be echoed. If nil, commands are written to State as is when TState and TCommand are the same
+	// type, which works when the state templates extract attributes from the payloads rendered by the command
+	// templates.
+	EchoState func(command TCommand) (TState, bool)
+
+	// The current state of the light
+	State *mqtt.Value[TState]
+	// Extracts the power state (on or off) from messages published to State
+	StateTemplate hass.Template
+	// Extracts the brightness from messages published to State
+	BrightnessTemplate hass.Template
+	// Extracts the red color component from messages published to State
+	RedTemplate hass.Template
+	// Extracts the green color component from messages published to State
+	GreenTemplate hass.Template
+	// Extracts the blue color component from messages published to State
+	BlueTemplate hass.Template
+	// Extracts the color temperature from messages published to State. The unit used is mireds, or if
+	// ColorTemperatureInKelvin is set to true, in Kelvin.
+	ColorTemperatureTemplate hass.Template
+	// Extracts the effect from messages published to State
+	EffectTemplate hass.Template
+
+	// Home Assistant will write commands for this entity to this value
+	Command *mqtt.RemoteValue[TCommand] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command to turn the light on. The requested state is available as
+	// state, brightness, red, green, blue, hue, sat, color_temp, flash, transition, and effect.
+	CommandOnTemplate hass.Template
+	// Renders the payload Home Assistant writes to Command to turn the light off. The requested state is available as
+	// state, flash, and transition.
+	CommandOffTemplate hass.Template
+
+	// Whether color temperature is in Kelvin (true) or mireds (false)
+	ColorTemperatureInKelvin bool
+	// The maximum color temperature in Kelvin. Defaults to 6535.
+	MaxKelvin uint
+	// The minimum color temperature in Kelvin. Defaults to 2000.
+	MinKelvin uint
+	// The maximum color temperature in mireds.
+	MaxMireds uint
+	// The minimum color temperature in mireds.
+	MinMireds uint
+
+	// The list of possible effects this device supports
+	PossibleEffects []string
+}
+
+func (l *TemplateLight[TState, TCommand]) PlatformName() string {
+	return "light"
+}
+
+func (l *TemplateLight[TState, TCommand]) Subscriptions(prefix string) []mqtt.Subscription {
+	return l.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (l *TemplateLight[TState, TCommand]) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	l.Command.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the last command received on Command to State when
+// Optimistic is set, converting it with EchoState.
+func (l *TemplateLight[TState, TCommand]) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !l.Optimistic || l.Command == nil || l.State == nil || topic != l.Command.FullyQualifiedTopic("") {
+		return nil
+	}
+
+	command, ok := l.Command.LastReceived()
+	if !ok {
+		return nil
+	}
+
+	state, ok := l.echoState(command)
+	if !ok {
+		return nil
+	}
+
+	return mqtt.Error(l.State.Write(ctx, w, prefix, state))
+}
+
+func (l *TemplateLight[TState, TCommand]) echoState(command TCommand) (TState, bool) {
+	if l.EchoState != nil {
+		return l.EchoState(command)
+	}
+
+	state, ok := any(command).(TState)
+	return state, ok
+}
+
+func (l *TemplateLight[TState, TCommand]) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalStdComparable("schema", e, discovery.FieldSchema, "template"),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, l.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, l.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateTemplate, l.StateTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldBrightnessTemplate, l.BrightnessTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldRedTemplate, l.RedTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldGreenTemplate, l.GreenTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldBlueTemplate, l.BlueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldColorTemperatureTemplate, l.ColorTemperatureTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldEffectTemplate, l.EffectTemplate),
+
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, l.Command, prefix),
+		discovery.MarshalStdComparable("command on template", e, discovery.FieldCommandOnTemplate, l.CommandOnTemplate),
+		discovery.MarshalStdComparable("command off template", e, discovery.FieldCommandOffTemplate, l.CommandOffTemplate),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldColorTemperatureInKelvin, l.ColorTemperatureInKelvin),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMaxKelvin, l.MaxKelvin),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMinKelvin, l.MinKelvin),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMaxMireds, l.MaxMireds),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMinMireds, l.MinMireds),
+
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldEffectList, l.PossibleEffects),
+	)
+}
@@ -0,0 +1,132 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newTemplateLight() *platform.TemplateLight[string, string] {
+	return &platform.TemplateLight[string, string]{
+		State:              mqtt.NewValue("state", mqtt.StringMarshaler),
+		Command:            mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler),
+		CommandOnTemplate:  `{"state": "on"{% if brightness is defined %}, "brightness": {{ brightness }}{% endif %}}`,
+		CommandOffTemplate: `{"state": "off"}`,
+	}
+}
+
+func TestTemplateLight_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newTemplateLight(), "light")
+}
+
+func TestTemplateLight_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newTemplateLight()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte(`{"state": "on"}`))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "light", "unknown"))
+		w.AssertNotPublished(t, "light/state")
+
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "light", "command"))
+		w.AssertPublished(t, "light/state", []byte(`{"state": "on"}`))
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newTemplateLight()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte(`{"state": "on"}`))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "light", "command"))
+		w.AssertNotPublished(t, "light/state")
+	})
+
+	t.Run("EchoState", func(t *testing.T) {
+		sut := &platform.TemplateLight[hass.PowerState, string]{
+			Optimistic: true,
+			State:      mqtt.NewValue("state", hass.PowerStateMarshaler),
+			Command:    mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler),
+			EchoState: func(command string) (hass.PowerState, bool) {
+				switch command {
+				case "on":
+					return hass.PowerStateOn, true
+				case "off":
+					return hass.PowerStateOff, true
+				default:
+					return "", false
+				}
+			},
+		}
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("off"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "light", "command"))
+		w.AssertPublished(t, "light/state", []byte("OFF"))
+
+		w.Reset()
+		sut.ServeMQTT(w, "command", []byte("blink"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "light", "command"))
+		w.AssertNotPublished(t, "light/state")
+	})
+
+	t.Run("Different Types", func(t *testing.T) {
+		sut := &platform.TemplateLight[hass.PowerState, string]{
+			Optimistic: true,
+			State:      mqtt.NewValue("state", hass.PowerStateMarshaler),
+			Command:    mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler),
+		}
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("on"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "light", "command"))
+		w.AssertNotPublished(t, "light/state")
+	})
+}
+
+func TestTemplateLight_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		sut := newTemplateLight()
+		sut.Command = nil
+
+		_, err := marshalDiscovery(t, sut, "light")
+		require.Error(t, err)
+	})
+
+	t.Run("Command Templates Required", func(t *testing.T) {
+		sut := newTemplateLight()
+		sut.CommandOffTemplate = ""
+
+		_, err := marshalDiscovery(t, sut, "light")
+		require.ErrorIs(t, err, discovery.ErrValueRequired)
+	})
+
+	sut := newTemplateLight()
+	sut.StateTemplate = "{{ value_json.state }}"
+	sut.BrightnessTemplate = "{{ value_json.brightness }}"
+	sut.RedTemplate = "{{ value_json.color[0] }}"
+	sut.GreenTemplate = "{{ value_json.color[1] }}"
+	sut.BlueTemplate = "{{ value_json.color[2] }}"
+	sut.ColorTemperatureInKelvin = true
+	sut.PossibleEffects = []string{"rainbow"}
+
+	payload, err := marshalDiscovery(t, sut, "light")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "schema", "template")
+	hqtttest.RequireField(t, payload, "state_topic", "light/state")
+	hqtttest.RequireField(t, payload, "command_topic", "light/command")
+	hqtttest.RequireField(t, payload, "command_off_template", `{"state": "off"}`)
+	hqtttest.RequireField(t, payload, "state_template", "{{ value_json.state }}")
+	hqtttest.RequireField(t, payload, "brightness_template", "{{ value_json.brightness }}")
+	hqtttest.RequireField(t, payload, "red_template", "{{ value_json.color[0] }}")
+	hqtttest.RequireField(t, payload, "color_temp_kelvin", true)
+	hqtttest.RequireNoField(t, payload, "color_temp_template")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
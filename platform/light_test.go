@@ -0,0 +1,175 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func FuzzRGBUnmarshaler(f *testing.F) {
+	hqtttest.FuzzUnmarshaler(f, platform.RGBUnmarshaler, platform.RGBMarshaler, []byte("0,0,0"), []byte("01,2,+3"))
+}
+
+func TestLight_DescribePayload(t *testing.T) {
+	payload, err := platform.RGBMarshaler(platform.RGB{R: 255, G: 128})
+	require.NoError(t, err)
+	assert.Regexp(t, platform.RGB{}.DescribePayload().Pattern, string(payload))
+
+	for name, tt := range map[string]struct {
+		describer mqtt.PayloadDescriber
+		valid     string
+		invalid   string
+	}{
+		"HueSat": {describer: platform.HueSat{}, valid: "300,52.5", invalid: "300"},
+		"XY":     {describer: platform.XY{}, valid: "0.25,0.5", invalid: "0.25,0.5,1"},
+		"RGB":    {describer: platform.RGB{}, valid: "255,128,0", invalid: "255,128,0,0"},
+		"RGBW":   {describer: platform.RGBW{}, valid: "255,128,0,0", invalid: "255,128,0"},
+		"RGBWW":  {describer: platform.RGBWW{}, valid: "255,128,0,0,64", invalid: "255,128,0.5,0,64"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			schema := tt.describer.DescribePayload()
+			require.Equal(t, "string", schema.Type)
+			assert.Regexp(t, schema.Pattern, tt.valid)
+			assert.NotRegexp(t, schema.Pattern, tt.invalid)
+		})
+	}
+}
+
+func newBenchmarkLight() *platform.Light {
+	return &platform.Light{
+		State:             mqtt.NewValue("state", hass.PowerStateMarshaler),
+		Command:           mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+		Brightness:        mqtt.NewValue("brightness", mqtt.UintMarshaler),
+		BrightnessCommand: mqtt.NewRemoteValue("brightness/set", mqtt.UintUnmarshaler),
+		RGB:               mqtt.NewValue("rgb", platform.RGBMarshaler),
+		RGBCommand:        mqtt.NewRemoteValue("rgb/set", platform.RGBUnmarshaler),
+		Effect:            mqtt.NewValue("effect", mqtt.StringMarshaler),
+		EffectCommand:     mqtt.NewRemoteValue("effect/set", mqtt.StringUnmarshaler),
+	}
+}
+
+func BenchmarkLight_ServeMQTT(b *testing.B) {
+	sut := newBenchmarkLight()
+
+	payload := []byte("fire")
+	for b.Loop() {
+		sut.ServeMQTT(nil, "effect/set", payload)
+	}
+}
+
+func BenchmarkLight_MarshalDiscoveryTo(b *testing.B) {
+	sut := newBenchmarkLight()
+
+	var buf bytes.Buffer
+	for b.Loop() {
+		buf.Reset()
+
+		e := jsontext.NewEncoder(&buf)
+		_ = e.WriteToken(jsontext.BeginObject)
+		if err := sut.MarshalDiscoveryTo(e, "light"); err != nil {
+			b.Fatal(err)
+		}
+		_ = e.WriteToken(jsontext.EndObject)
+	}
+}
+
+func TestLight_Routing(t *testing.T) {
+	sut := &platform.Light{
+		Command:                 mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+		ColorModeCommand:        mqtt.NewRemoteValue("color_mode/set", hass.ColorModeUnmarshaler),
+		BrightnessCommand:       mqtt.NewRemoteValue("brightness/set", mqtt.UintUnmarshaler),
+		ColorTemperatureCommand: mqtt.NewRemoteValue("color_temp/set", mqtt.UintUnmarshaler),
+		HueSatCommand:           mqtt.NewRemoteValue("hs/set", mqtt.JsonValueUnmarshaler[platform.HueSat]()),
+		XYCommand:               mqtt.NewRemoteValue("xy/set", mqtt.JsonValueUnmarshaler[platform.XY]()),
+		RGBCommand:              mqtt.NewRemoteValue("rgb/set", platform.RGBUnmarshaler),
+		RGBWCommand:             mqtt.NewRemoteValue("rgbw/set", mqtt.JsonValueUnmarshaler[platform.RGBW]()),
+		RGBWWCommand:            mqtt.NewRemoteValue("rgbww/set", mqtt.JsonValueUnmarshaler[platform.RGBWW]()),
+		WhiteBrightnessCommand:  mqtt.NewRemoteValue("white/set", mqtt.UintUnmarshaler),
+		EffectCommand:           mqtt.NewRemoteValue("effect/set", mqtt.StringUnmarshaler).Absolute(),
+	}
+
+	hqtttest.AssertRouting(t, sut, "light")
+}
+
+func TestLight_EchoCommand(t *testing.T) {
+	sut := newBenchmarkLight()
+	sut.Optimistic = true
+
+	w := &hqtttest.Writer{}
+	sut.ServeMQTT(w, "brightness/set", []byte("128"))
+	sut.ServeMQTT(w, "unknown/set", []byte("128"))
+
+	if err := sut.EchoCommand(t.Context(), w, "light", "brightness/set"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sut.EchoCommand(t.Context(), w, "light", "unknown/set"); err != nil {
+		t.Fatal(err)
+	}
+
+	w.AssertPublished(t, "light/brightness", []byte("128"))
+	w.AssertNotPublished(t, "light/state")
+}
+
+func TestLight_ServeMQTT_DuplicateTopics(t *testing.T) {
+	sut := &platform.Light{
+		Command:       mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+		EffectCommand: mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler),
+	}
+
+	// The first configured value for a topic receives its messages
+	sut.ServeMQTT(nil, "command", []byte("ON"))
+
+	if v, ok := sut.Command.Get(); !ok || v != hass.PowerStateOn {
+		t.Errorf("Command: got %v (%t), want %v", v, ok, hass.PowerStateOn)
+	}
+
+	if _, ok := sut.EffectCommand.Get(); ok {
+		t.Error("EffectCommand should not receive messages for a topic routed to Command")
+	}
+}
+
+func TestLight_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	sut := &platform.Light{
+		Command:                mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+		ColorModeValueTemplate: "{{ value_json.mode }}",
+		HueSat:                 mqtt.NewValue("hs", mqtt.JsonValueMarshaler[platform.HueSat]()),
+		HueSatCommand:          mqtt.NewRemoteValue("hs/set", mqtt.JsonValueUnmarshaler[platform.HueSat]()),
+		HueSatValueTemplate:    "{{ value_json.hs | join(',') }}",
+		HueSatCommandTemplate:  "{{ hue }},{{ sat }}",
+		XY:                     mqtt.NewValue("xy", mqtt.JsonValueMarshaler[platform.XY]()),
+		XYCommand:              mqtt.NewRemoteValue("xy/set", mqtt.JsonValueUnmarshaler[platform.XY]()),
+		RGB:                    mqtt.NewValue("rgb", platform.RGBMarshaler),
+		RGBCommand:             mqtt.NewRemoteValue("rgb/set", platform.RGBUnmarshaler),
+		RGBValueTemplate:       "{{ value_json.color | join(',') }}",
+		RGBCommandTemplate:     `{"color":[{{ red }},{{ green }},{{ blue }}]}`,
+	}
+
+	require.NoError(t, sut.MarshalDiscoveryTo(e, "light"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "color_mode_value_template", "{{ value_json.mode }}")
+	hqtttest.RequireField(t, payload, "hs_state_topic", "light/hs")
+	hqtttest.RequireField(t, payload, "hs_command_topic", "light/hs/set")
+	hqtttest.RequireField(t, payload, "hs_value_template", "{{ value_json.hs | join(',') }}")
+	hqtttest.RequireField(t, payload, "hs_command_template", "{{ hue }},{{ sat }}")
+	hqtttest.RequireField(t, payload, "xy_state_topic", "light/xy")
+	hqtttest.RequireField(t, payload, "xy_command_topic", "light/xy/set")
+	hqtttest.RequireField(t, payload, "rgb_value_template", "{{ value_json.color | join(',') }}")
+	hqtttest.RequireField(t, payload, "rgb_command_template", `{"color":[{{ red }},{{ green }},{{ blue }}]}`)
+	hqtttest.RequireNoField(t, payload, "xy_value_template")
+	hqtttest.RequireNoField(t, payload, "color_temp_state_topic")
+}
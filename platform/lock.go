@@ -0,0 +1,120 @@
+package platform
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Lock is a hqtt.Platform that implements the lock.mqtt integration for Home Assistant.
+//
+// See https://www.home-assistant.io/integrations/lock.mqtt/
+type Lock struct {
+	// Flag that defines if the lock works in optimistic mode. When set, lock, unlock, and open commands received from
+	// Home Assistant are automatically written to State as the locked, unlocked, and open states.
+	Optimistic bool
+
+	// The current state of the lock
+	State *mqtt.Value[hass.LockState]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+	// Home Assistant will write commands for this entity to this value
+	Command *mqtt.RemoteValue[hass.LockCommand] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The command is available as value, and the code entered by
+	// the user as code.
+	CommandTemplate hass.Template
+
+	// A regular expression the code entered by the user must match before a command is sent. If not set, no code is
+	// requested. Use CommandTemplate to include the code in the command.
+	CodeFormat string
+
+	// Whether the lock can be opened, like a door latch. When set, Home Assistant offers to open the lock by writing
+	// the open command to Command.
+	SupportsOpen bool
+
+	// Custom values to use for payload commands. The Open command is only used if SupportsOpen is set.
+	CustomCommandValues hass.CustomLockCommand
+	// Custom values to use for states
+	CustomStateValues hass.CustomLockState
+}
+
+func (l *Lock) PlatformName() string {
+	return "lock"
+}
+
+func (l *Lock) Subscriptions(prefix string) []mqtt.Subscription {
+	return l.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (l *Lock) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	l.Command.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the state corresponding to the command received on the
+// specified topic to State if Optimistic is set.
+func (l *Lock) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !l.Optimistic || l.Command == nil || l.State == nil || topic != l.Command.FullyQualifiedTopic("") {
+		return nil
+	}
+
+	command, ok := l.Command.LastReceived()
+	if !ok {
+		return nil
+	}
+
+	var state hass.LockState
+	switch command {
+	case cmp.Or(l.CustomCommandValues.Lock, hass.LockCommandLock):
+		state = cmp.Or(l.CustomStateValues.Locked, hass.LockStateLocked)
+	case cmp.Or(l.CustomCommandValues.Unlock, hass.LockCommandUnlock):
+		state = cmp.Or(l.CustomStateValues.Unlocked, hass.LockStateUnlocked)
+	case l.openCommand():
+		state = cmp.Or(l.CustomStateValues.Open, hass.LockStateOpen)
+	default:
+		return nil
+	}
+
+	return mqtt.Error(l.State.Write(ctx, w, prefix, state))
+}
+
+// openCommand returns the command Home Assistant writes to open the lock, or an empty command if SupportsOpen is not
+// set.
+func (l *Lock) openCommand() hass.LockCommand {
+	if !l.SupportsOpen {
+		return ""
+	}
+
+	return cmp.Or(l.CustomCommandValues.Open, hass.LockCommandOpen)
+}
+
+func (l *Lock) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, l.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, l.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, l.ValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, l.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, l.CommandTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCodeFormat, l.CodeFormat),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadLock, l.CustomCommandValues.Lock),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadUnlock, l.CustomCommandValues.Unlock),
+		// Home Assistant only supports opening the lock if the payload is present
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOpen, l.openCommand()),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateLocked, l.CustomStateValues.Locked),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateLocking, l.CustomStateValues.Locking),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateUnlocked, l.CustomStateValues.Unlocked),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateUnlocking, l.CustomStateValues.Unlocking),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateJammed, l.CustomStateValues.Jammed),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOpen, l.CustomStateValues.Open),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOpening, l.CustomStateValues.Opening),
+	)
+}
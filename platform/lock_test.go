@@ -0,0 +1,139 @@
+package platform_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newLock() *platform.Lock {
+	return &platform.Lock{
+		State:   mqtt.NewValue("state", hass.LockStateMarshaler),
+		Command: mqtt.NewRemoteValue("command", hass.LockCommandUnmarshaler),
+	}
+}
+
+func TestLock_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newLock(), "lock")
+}
+
+func TestLock_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newLock()
+		sut.Optimistic = true
+		sut.SupportsOpen = true
+
+		w := &hqtttest.Writer{}
+		for _, tt := range []struct {
+			command string
+			state   string
+		}{
+			{command: "LOCK", state: "LOCKED"},
+			{command: "UNLOCK", state: "UNLOCKED"},
+			{command: "OPEN", state: "OPEN"},
+		} {
+			sut.ServeMQTT(w, "command", []byte(tt.command))
+			require.NoError(t, sut.EchoCommand(t.Context(), w, "lock", "command"))
+			w.AssertPublished(t, "lock/state", []byte(tt.state))
+		}
+	})
+
+	t.Run("Open Not Supported", func(t *testing.T) {
+		sut := newLock()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("OPEN"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "lock", "command"))
+		w.AssertNotPublished(t, "lock/state")
+	})
+
+	t.Run("Custom Values", func(t *testing.T) {
+		sut := newLock()
+		sut.Optimistic = true
+		sut.CustomCommandValues = hass.CustomLockCommand{Lock: "1"}
+		sut.CustomStateValues = hass.CustomLockState{Locked: "closed"}
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("1"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "lock", "command"))
+		w.AssertPublished(t, "lock/state", []byte("closed"))
+	})
+
+	t.Run("Invalid Command", func(t *testing.T) {
+		sut := newLock()
+		sut.Optimistic = true
+		sut.Command = mqtt.NewRemoteValue("command", func(payload []byte) (hass.LockCommand, error) {
+			if len(payload) == 0 {
+				return "", errors.New("empty command")
+			}
+
+			return hass.LockCommandUnmarshaler(payload)
+		})
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("LOCK"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "lock", "command"))
+		w.Reset()
+
+		sut.ServeMQTT(w, "command", []byte(""))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "lock", "command"))
+		w.AssertNotPublished(t, "lock/state")
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newLock()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("LOCK"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "lock", "command"))
+		w.AssertNotPublished(t, "lock/state")
+	})
+}
+
+func TestLock_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Lock{}, "lock")
+		require.Error(t, err)
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		sut := newLock()
+
+		payload, err := marshalDiscovery(t, sut, "lock")
+		require.NoError(t, err)
+		hqtttest.RequireNoField(t, payload, "payload_open")
+
+		sut.SupportsOpen = true
+		payload, err = marshalDiscovery(t, sut, "lock")
+		require.NoError(t, err)
+		hqtttest.RequireField(t, payload, "payload_open", "OPEN")
+
+		sut.CustomCommandValues.Open = "UNLATCH"
+		payload, err = marshalDiscovery(t, sut, "lock")
+		require.NoError(t, err)
+		hqtttest.RequireField(t, payload, "payload_open", "UNLATCH")
+	})
+
+	sut := newLock()
+	sut.CodeFormat = `^\d{4}$`
+	sut.CustomStateValues = hass.CustomLockState{Jammed: "MOTOR_JAMMED", Locking: "BUSY"}
+
+	payload, err := marshalDiscovery(t, sut, "lock")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "lock/state")
+	hqtttest.RequireField(t, payload, "command_topic", "lock/command")
+	hqtttest.RequireField(t, payload, "code_format", `^\d{4}$`)
+	hqtttest.RequireField(t, payload, "state_jammed", "MOTOR_JAMMED")
+	hqtttest.RequireField(t, payload, "state_locking", "BUSY")
+	hqtttest.RequireNoField(t, payload, "state_unlocking")
+	hqtttest.RequireNoField(t, payload, "payload_lock")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
@@ -0,0 +1,41 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Notify is a hqtt.Platform that implements the notify.mqtt integration for Home Assistant. Notifications have no
+// state, use mqtt.RemoteValue.Watch on Command to deliver the messages sent with the notify.send_message action.
+//
+// See https://www.home-assistant.io/integrations/notify.mqtt/
+type Notify struct {
+	// Home Assistant will write notification messages to this value
+	Command *mqtt.RemoteValue[string] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The message is available as value.
+	CommandTemplate hass.Template
+}
+
+func (n *Notify) PlatformName() string {
+	return "notify"
+}
+
+func (n *Notify) Subscriptions(prefix string) []mqtt.Subscription {
+	return n.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (n *Notify) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	n.Command.ServeMQTT(w, topic, payload)
+}
+
+func (n *Notify) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, n.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, n.CommandTemplate),
+	)
+}
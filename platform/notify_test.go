@@ -0,0 +1,50 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newNotify() *platform.Notify {
+	return &platform.Notify{Command: mqtt.NewRemoteValue("message", mqtt.StringUnmarshaler)}
+}
+
+func TestNotify_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newNotify(), "notify")
+}
+
+func TestNotify_Message(t *testing.T) {
+	sut := newNotify()
+
+	var messages []string
+	sut.Command.Watch(func(message string) {
+		messages = append(messages, message)
+	})
+
+	w := &hqtttest.Writer{}
+	sut.ServeMQTT(w, "message", []byte("Washing machine is done"))
+	sut.ServeMQTT(w, "message", []byte("Washing machine is done"))
+	assert.Equal(t, []string{"Washing machine is done", "Washing machine is done"}, messages)
+}
+
+func TestNotify_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Notify{}, "display")
+		require.Error(t, err)
+	})
+
+	sut := newNotify()
+	sut.CommandTemplate = `{"text": "{{ value }}"}`
+
+	payload, err := marshalDiscovery(t, sut, "display")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "command_topic", "display/message")
+	hqtttest.RequireField(t, payload, "command_template", `{"text": "{{ value }}"}`)
+}
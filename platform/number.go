@@ -0,0 +1,107 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Numeric is the set of types a Number can hold.
+type Numeric interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// NumberMode controls how Home Assistant displays a Number in the frontend.
+type NumberMode string
+
+const (
+	// NumberModeAuto lets Home Assistant pick the display mode. This is the default.
+	NumberModeAuto NumberMode = "auto"
+	// NumberModeBox displays the number as an input box.
+	NumberModeBox NumberMode = "box"
+	// NumberModeSlider displays the number as a slider.
+	NumberModeSlider NumberMode = "slider"
+)
+
+// Number is a hqtt.Platform that implements the number.mqtt integration for Home Assistant. The state and commands of
+// this number have a type of T.
+//
+// See https://www.home-assistant.io/integrations/number.mqtt/
+type Number[T Numeric] struct {
+	// Flag that defines if the number works in optimistic mode. When set, commands received from Home Assistant are
+	// automatically written to State.
+	Optimistic bool
+
+	// The type/class of the number to set the icon in the frontend. See
+	// https://www.home-assistant.io/integrations/number/#device-class.
+	DeviceClass string
+
+	// The current value of the number
+	State *mqtt.Value[T]
+	// Extracts the value from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+	// Home Assistant will write the desired value to this value
+	Command *mqtt.RemoteValue[T] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The number is available as value.
+	CommandTemplate hass.Template
+
+	// The minimum value. Defaults to 1 if nil.
+	Min *T
+	// The maximum value. Defaults to 100 if nil.
+	Max *T
+	// The step size between values. Defaults to 1 if not set.
+	Step T
+	// How the number is displayed in the frontend. Defaults to NumberModeAuto if not set.
+	Mode NumberMode
+
+	// Defines the units used by this number
+	UnitOfMeasurement string
+}
+
+func (n *Number[T]) PlatformName() string {
+	return "number"
+}
+
+func (n *Number[T]) Subscriptions(prefix string) []mqtt.Subscription {
+	return n.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (n *Number[T]) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	n.Command.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the command received on the specified topic to State if
+// Optimistic is set.
+func (n *Number[T]) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !n.Optimistic || n.Command == nil || topic != n.Command.FullyQualifiedTopic("") {
+		return nil
+	}
+
+	return mqtt.Echo(ctx, w, prefix, n.Command, n.State)
+}
+
+func (n *Number[T]) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, n.Optimistic),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, n.DeviceClass),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, n.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, n.ValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, n.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, n.CommandTemplate),
+
+		discovery.MaybeMarshalStd(e, discovery.FieldMin, n.Min),
+		discovery.MaybeMarshalStd(e, discovery.FieldMax, n.Max),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStep, n.Step),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMode, n.Mode),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldUnitOfMeasurement, n.UnitOfMeasurement),
+	)
+}
@@ -0,0 +1,72 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newNumber() *platform.Number[float64] {
+	return &platform.Number[float64]{
+		State:   mqtt.NewValue("state", mqtt.FloatMarshaler),
+		Command: mqtt.NewRemoteValue("command", mqtt.FloatUnmarshaler),
+	}
+}
+
+func TestNumber_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newNumber(), "number")
+}
+
+func TestNumber_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newNumber()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("2.5"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "number", "unknown"))
+		w.AssertNotPublished(t, "number/state")
+
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "number", "command"))
+		w.AssertPublished(t, "number/state", []byte("2.5"))
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newNumber()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("2.5"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "number", "command"))
+		w.AssertNotPublished(t, "number/state")
+	})
+}
+
+func TestNumber_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Number[float64]{}, "number")
+		require.Error(t, err)
+	})
+
+	minValue := 0.0
+	sut := newNumber()
+	sut.Min = &minValue
+	sut.Step = 0.5
+	sut.Mode = platform.NumberModeSlider
+	sut.UnitOfMeasurement = "°C"
+
+	payload, err := marshalDiscovery(t, sut, "number")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "number/state")
+	hqtttest.RequireField(t, payload, "command_topic", "number/command")
+	hqtttest.RequireField(t, payload, "min", 0)
+	hqtttest.RequireField(t, payload, "step", 0.5)
+	hqtttest.RequireField(t, payload, "mode", "slider")
+	hqtttest.RequireField(t, payload, "unit_of_measurement", "°C")
+	hqtttest.RequireNoField(t, payload, "max")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
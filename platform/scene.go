@@ -0,0 +1,45 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// DefaultScenePayloadOn is the payload Home Assistant writes to Scene.Command when PayloadOn is not set.
+const DefaultScenePayloadOn = "ON"
+
+// Scene is a hqtt.Platform that implements the scene.mqtt integration for Home Assistant. Scenes have no state, use
+// mqtt.RemoteValue.Watch on Command to activate the scene. Watchers are called every time the scene is activated, even
+// though the payload does not change.
+//
+// See https://www.home-assistant.io/integrations/scene.mqtt/
+type Scene struct {
+	// Home Assistant will write PayloadOn to this value when the scene is activated
+	Command *mqtt.RemoteValue[string] `hqtt:"required"`
+	// The payload Home Assistant writes to Command when the scene is activated. Defaults to DefaultScenePayloadOn if
+	// not set.
+	PayloadOn string
+}
+
+func (s *Scene) PlatformName() string {
+	return "scene"
+}
+
+func (s *Scene) Subscriptions(prefix string) []mqtt.Subscription {
+	return s.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (s *Scene) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	s.Command.ServeMQTT(w, topic, payload)
+}
+
+func (s *Scene) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, s.Command, prefix),
+		discovery.MarshalStdIfNot(DefaultScenePayloadOn, e, discovery.FieldPayloadOn, s.PayloadOn),
+	)
+}
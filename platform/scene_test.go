@@ -0,0 +1,54 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newScene() *platform.Scene {
+	return &platform.Scene{Command: mqtt.NewRemoteValue("activate", mqtt.StringUnmarshaler)}
+}
+
+func TestScene_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newScene(), "scene")
+}
+
+func TestScene_Activate(t *testing.T) {
+	sut := newScene()
+
+	var activations int
+	sut.Command.Watch(func(string) {
+		activations++
+	})
+
+	w := &hqtttest.Writer{}
+	sut.ServeMQTT(w, "activate", []byte("ON"))
+	sut.ServeMQTT(w, "activate", []byte("ON"))
+	assert.Equal(t, 2, activations)
+}
+
+func TestScene_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Scene{}, "scene")
+		require.Error(t, err)
+	})
+
+	sut := newScene()
+
+	payload, err := marshalDiscovery(t, sut, "scene")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "command_topic", "scene/activate")
+	hqtttest.RequireNoField(t, payload, "payload_on")
+
+	sut.PayloadOn = "movie"
+	payload, err = marshalDiscovery(t, sut, "scene")
+	require.NoError(t, err)
+	hqtttest.RequireField(t, payload, "payload_on", "movie")
+}
@@ -0,0 +1,71 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Select is a hqtt.Platform that implements the select.mqtt integration for Home Assistant, for enumerated settings of
+// a device. The options of this select have a type of T, which is usually a string type with a constant for each
+// option.
+//
+// See https://www.home-assistant.io/integrations/select.mqtt/
+type Select[T ~string] struct {
+	// Flag that defines if the select works in optimistic mode. When set, options selected in Home Assistant are
+	// automatically written to State.
+	Optimistic bool
+
+	// The currently selected option
+	State *mqtt.Value[T]
+	// Extracts the option from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+	// Home Assistant will write the selected option to this value
+	Command *mqtt.RemoteValue[T] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The option is available as value.
+	CommandTemplate hass.Template
+
+	// The list of options that can be selected. It must not be empty.
+	Options []T
+}
+
+func (s *Select[T]) PlatformName() string {
+	return "select"
+}
+
+func (s *Select[T]) Subscriptions(prefix string) []mqtt.Subscription {
+	return s.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (s *Select[T]) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	s.Command.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the option received on the specified topic to State if
+// Optimistic is set.
+func (s *Select[T]) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !s.Optimistic || s.Command == nil || topic != s.Command.FullyQualifiedTopic("") {
+		return nil
+	}
+
+	return mqtt.Echo(ctx, w, prefix, s.Command, s.State)
+}
+
+func (s *Select[T]) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, s.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, s.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, s.ValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, s.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, s.CommandTemplate),
+
+		discovery.MarshalStdSlice("options", e, discovery.FieldOptions, s.Options),
+	)
+}
@@ -0,0 +1,81 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+type fanSpeed string
+
+func newSelect() *platform.Select[fanSpeed] {
+	return &platform.Select[fanSpeed]{
+		State: mqtt.NewValue("state", func(v fanSpeed) ([]byte, error) {
+			return mqtt.StringMarshaler(string(v))
+		}),
+		Command: mqtt.NewRemoteValue("command", func(bytes []byte) (fanSpeed, error) {
+			v, err := mqtt.StringUnmarshaler(bytes)
+			return fanSpeed(v), err
+		}),
+		Options: []fanSpeed{"low", "high"},
+	}
+}
+
+func TestSelect_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newSelect(), "select")
+}
+
+func TestSelect_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newSelect()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("high"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "select", "unknown"))
+		w.AssertNotPublished(t, "select/state")
+
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "select", "command"))
+		w.AssertPublished(t, "select/state", []byte("high"))
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newSelect()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("high"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "select", "command"))
+		w.AssertNotPublished(t, "select/state")
+	})
+}
+
+func TestSelect_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		sut := newSelect()
+		sut.Command = nil
+
+		_, err := marshalDiscovery(t, sut, "select")
+		require.ErrorIs(t, err, discovery.ErrTopicRequired)
+	})
+
+	t.Run("Options Required", func(t *testing.T) {
+		sut := newSelect()
+		sut.Options = nil
+
+		_, err := marshalDiscovery(t, sut, "select")
+		require.ErrorIs(t, err, discovery.ErrValueRequired)
+	})
+
+	payload, err := marshalDiscovery(t, newSelect(), "select")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "select/state")
+	hqtttest.RequireField(t, payload, "command_topic", "select/command")
+	hqtttest.RequireField(t, payload, "options", []string{"low", "high"})
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
@@ -1,10 +1,12 @@
 package platform
 
 import (
+	"context"
 	"encoding/json/jsontext"
 	"errors"
 	"time"
 
+	"github.com/nlowe/hqtt/clock"
 	"github.com/nlowe/hqtt/discovery"
 	"github.com/nlowe/hqtt/hass"
 	"github.com/nlowe/hqtt/mqtt"
@@ -24,6 +26,14 @@ type Sensor[TValue, TAttributes any] struct {
 	// unavailable.
 	ExpireMeasurementsAfter time.Duration
 
+	// The Clock used by RunHeartbeat and by BinarySensor.Trigger. If nil, clock.Real is used.
+	Clock clock.Clock
+
+	// The type/class of the sensor to set the icon in the frontend. See
+	// https://www.home-assistant.io/integrations/sensor/#device-class for sensors and
+	// https://www.home-assistant.io/integrations/binary_sensor/#device-class for binary sensors.
+	DeviceClass string
+
 	// Instruct Home Assistant to calculate update events even if the value hasn’t changed. Useful if you want to have
 	// meaningful value graphs in history.
 	ForceUpdate bool
@@ -33,6 +43,8 @@ type Sensor[TValue, TAttributes any] struct {
 	// mqtt.JsonValueMarshaler for the mqtt.ValueMarshaler for this value. When using a custom marshaler, the resulting
 	// byte slice must be a json string.
 	Attributes *mqtt.Value[TAttributes]
+	// Extracts the attributes from messages published to Attributes. The result must be a json dictionary.
+	AttributesTemplate hass.Template
 
 	// List of allowed sensor state value. The sensor’s device_class must be set to enum. The options option cannot be
 	// used together with state_class or unit_of_measurement.
@@ -49,6 +61,9 @@ type Sensor[TValue, TAttributes any] struct {
 
 	// The current value of the sensor
 	State *mqtt.Value[TValue] `hqtt:"required"`
+	// Extracts the value from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect (for example, as a field of a json object)
+	ValueTemplate hass.Template
 
 	// Defines the units used by this sensor
 	// TODO: Can/should we type this and grab constants from Home Assistant?
@@ -65,15 +80,26 @@ func (s *Sensor[TValue, TAttributes]) Subscriptions(_ string) []mqtt.Subscriptio
 
 func (s *Sensor[TValue, TAttributes]) ServeMQTT(_ mqtt.Writer, _ string, _ []byte) {}
 
+// RunHeartbeat republishes State at half of ExpireMeasurementsAfter until the provided context is done, so Home Assistant
+// does not expire the state of a sensor that rarely changes while the application is running. The cause of the
+// cancellation is returned. If ExpireMeasurementsAfter is not positive, RunHeartbeat returns nil immediately. See
+// mqtt.Heartbeat for details.
+func (s *Sensor[TValue, TAttributes]) RunHeartbeat(ctx context.Context, w mqtt.Writer, prefix string) error {
+	return mqtt.Heartbeat(ctx, s.Clock, w, prefix, s.State, s.ExpireMeasurementsAfter/2)
+}
+
 func (s *Sensor[TValue, TAttributes]) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
 	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, s.DeviceClass),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldExpireMeasurementsAfter, s.ExpireMeasurementsAfter),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldForceUpdate, s.ForceUpdate),
 		discovery.MaybeMarshalValueTopic(e, discovery.FieldAttributesTopic, s.Attributes, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldAttributesTemplate, s.AttributesTemplate),
 		discovery.MaybeMarshalStdSlice(e, discovery.FieldOptions, s.EnumOptions),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldSuggestedDisplayPrecision, s.SuggestedDisplayPrecision),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateClass, s.StateClass),
 		discovery.MarshalRequiredValueTopic("state", e, discovery.FieldStateTopic, s.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, s.ValueTemplate),
 		discovery.MaybeMarshalStdComparable(e, discovery.FieldUnitOfMeasurement, s.UnitOfMeasurement),
 	)
 }
@@ -0,0 +1,127 @@
+package platform
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"log/slog"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// SirenCommand is the payload Home Assistant writes to Siren.Command when Siren.CommandTemplate is not set. State is
+// the configured on or off payload, and the remaining fields are the parameters of the siren.turn_on action, which are
+// only set if provided by the caller and supported by the siren. It implements slog.LogValuer.
+type SirenCommand struct {
+	State       hass.PowerState `json:"state"`
+	Tone        string          `json:"tone,omitzero"`
+	Duration    uint            `json:"duration,omitzero"`
+	VolumeLevel float64         `json:"volume_level,omitzero"`
+}
+
+func (c SirenCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("state", string(c.State)),
+		slog.String("tone", c.Tone),
+		slog.Uint64("duration", uint64(c.Duration)),
+		slog.Float64("volume_level", c.VolumeLevel),
+	)
+}
+
+// Siren is a hqtt.Platform that implements the siren.mqtt integration for Home Assistant.
+//
+// See https://www.home-assistant.io/integrations/siren.mqtt/
+type Siren struct {
+	// Flag that defines if the siren works in optimistic mode. When set, the state of commands received from Home
+	// Assistant is automatically written to State.
+	Optimistic bool
+
+	// The current state of the siren
+	State *mqtt.Value[hass.PowerState]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	StateValueTemplate hass.Template
+	// Home Assistant will write commands for this entity to this value. Use mqtt.JsonValueUnmarshaler unless
+	// CommandTemplate and CommandOffTemplate render a different payload.
+	Command *mqtt.RemoteValue[SirenCommand] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The on or off payload is available as value, and the
+	// parameters of the siren.turn_on action as tone, duration, and volume_level.
+	CommandTemplate hass.Template
+	// Renders the payload Home Assistant writes to Command when the siren is turned off. Defaults to CommandTemplate if
+	// not set.
+	CommandOffTemplate hass.Template
+
+	// Custom values to use for payload commands
+	CustomPowerStateValues hass.CustomPowerState
+	// Custom values to use for states. Defaults to CustomPowerStateValues if not set.
+	CustomStateValues hass.CustomPowerState
+
+	// The tones this siren supports. If empty, tones cannot be selected in Home Assistant.
+	AvailableTones []string
+	// Whether the siren supports setting a duration. Defaults to true if nil.
+	SupportDuration *bool
+	// Whether the siren supports setting a volume level. Defaults to true if nil.
+	SupportVolumeSet *bool
+}
+
+func (s *Siren) PlatformName() string {
+	return "siren"
+}
+
+func (s *Siren) Subscriptions(prefix string) []mqtt.Subscription {
+	return s.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (s *Siren) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	s.Command.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the state corresponding to the command received on the
+// specified topic to State if Optimistic is set.
+func (s *Siren) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !s.Optimistic || s.Command == nil || s.State == nil || topic != s.Command.FullyQualifiedTopic("") {
+		return nil
+	}
+
+	command, ok := s.Command.LastReceived()
+	if !ok {
+		return nil
+	}
+
+	var state hass.PowerState
+	switch command.State {
+	case cmp.Or(s.CustomPowerStateValues.On, hass.PowerStateOn):
+		state = cmp.Or(s.CustomStateValues.On, s.CustomPowerStateValues.On, hass.PowerStateOn)
+	case cmp.Or(s.CustomPowerStateValues.Off, hass.PowerStateOff):
+		state = cmp.Or(s.CustomStateValues.Off, s.CustomPowerStateValues.Off, hass.PowerStateOff)
+	default:
+		return nil
+	}
+
+	return mqtt.Error(s.State.Write(ctx, w, prefix, state))
+}
+
+func (s *Siren) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, s.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, s.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateValueTemplate, s.StateValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, s.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, s.CommandTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandOffTemplate, s.CommandOffTemplate),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOn, s.CustomPowerStateValues.On),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOff, s.CustomPowerStateValues.Off),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOn, s.CustomStateValues.On),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOff, s.CustomStateValues.Off),
+
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldAvailableTones, s.AvailableTones),
+		discovery.MaybeMarshalStd(e, discovery.FieldSupportDuration, s.SupportDuration),
+		discovery.MaybeMarshalStd(e, discovery.FieldSupportVolumeSet, s.SupportVolumeSet),
+	)
+}
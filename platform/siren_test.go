@@ -0,0 +1,99 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newSiren() *platform.Siren {
+	return &platform.Siren{
+		State:   mqtt.NewValue("state", hass.PowerStateMarshaler),
+		Command: mqtt.NewRemoteValue("command", mqtt.JsonValueUnmarshaler[platform.SirenCommand]()),
+	}
+}
+
+func TestSiren_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newSiren(), "siren")
+}
+
+func TestSiren_Command(t *testing.T) {
+	sut := newSiren()
+
+	sut.ServeMQTT(&hqtttest.Writer{}, "command", []byte(`{"state": "ON", "tone": "ping", "duration": 5, "volume_level": 0.5}`))
+
+	got, ok := sut.Command.Get()
+	require.True(t, ok)
+	assert.Equal(t, platform.SirenCommand{State: hass.PowerStateOn, Tone: "ping", Duration: 5, VolumeLevel: 0.5}, got)
+}
+
+func TestSiren_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newSiren()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte(`{"state": "ON", "tone": "ping"}`))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "siren", "command"))
+		w.AssertPublished(t, "siren/state", []byte("ON"))
+
+		sut.ServeMQTT(w, "command", []byte(`{"state": "OFF"}`))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "siren", "command"))
+		w.AssertPublished(t, "siren/state", []byte("OFF"))
+	})
+
+	t.Run("Custom Values", func(t *testing.T) {
+		sut := newSiren()
+		sut.Optimistic = true
+		sut.CustomPowerStateValues = hass.CustomPowerState{On: "1", Off: "0"}
+		sut.CustomStateValues = hass.CustomPowerState{On: "sounding"}
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte(`{"state": "1"}`))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "siren", "command"))
+		w.AssertPublished(t, "siren/state", []byte("sounding"))
+
+		// The state defaults to the payload
+		sut.ServeMQTT(w, "command", []byte(`{"state": "0"}`))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "siren", "command"))
+		w.AssertPublished(t, "siren/state", []byte("0"))
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newSiren()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte(`{"state": "ON"}`))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "siren", "command"))
+		w.AssertNotPublished(t, "siren/state")
+	})
+}
+
+func TestSiren_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Siren{}, "siren")
+		require.Error(t, err)
+	})
+
+	supportVolumeSet := false
+	sut := newSiren()
+	sut.AvailableTones = []string{"ping", "siren"}
+	sut.SupportVolumeSet = &supportVolumeSet
+
+	payload, err := marshalDiscovery(t, sut, "siren")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "siren/state")
+	hqtttest.RequireField(t, payload, "command_topic", "siren/command")
+	hqtttest.RequireField(t, payload, "available_tones", []string{"ping", "siren"})
+	hqtttest.RequireField(t, payload, "support_volume_set", false)
+	hqtttest.RequireNoField(t, payload, "support_duration")
+	hqtttest.RequireNoField(t, payload, "command_template")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
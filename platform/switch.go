@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Device classes for Switch. See https://www.home-assistant.io/integrations/switch/#device-class.
+const (
+	SwitchDeviceClassOutlet = "outlet"
+	SwitchDeviceClassSwitch = "switch"
+)
+
+// Switch is a hqtt.Platform that implements the switch.mqtt integration for Home Assistant.
+//
+// See https://www.home-assistant.io/integrations/switch.mqtt/
+type Switch struct {
+	// Flag that defines if switch works in optimistic mode. When set, commands received from Home Assistant are
+	// automatically written to State.
+	Optimistic bool
+
+	// The type/class of the switch to set the icon in the frontend, see SwitchDeviceClassOutlet and
+	// SwitchDeviceClassSwitch.
+	DeviceClass string
+
+	// The current state of the Switch. If nil, Home Assistant operates the switch in optimistic mode.
+	State *mqtt.Value[hass.PowerState]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+	// Home Assistant will write commands for this entity to this value
+	Command *mqtt.RemoteValue[hass.PowerState] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The command is available as value.
+	CommandTemplate hass.Template
+
+	// Custom values to use for payload commands
+	CustomPowerStateValues hass.CustomPowerState
+}
+
+func (s *Switch) PlatformName() string {
+	return "switch"
+}
+
+func (s *Switch) Subscriptions(prefix string) []mqtt.Subscription {
+	return s.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (s *Switch) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	s.Command.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the command received on the specified topic to State if
+// Optimistic is set.
+func (s *Switch) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !s.Optimistic || s.Command == nil || topic != s.Command.FullyQualifiedTopic("") {
+		return nil
+	}
+
+	return mqtt.Echo(ctx, w, prefix, s.Command, s.State)
+}
+
+func (s *Switch) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, s.Optimistic),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, s.DeviceClass),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, s.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, s.ValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, s.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, s.CommandTemplate),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOn, s.CustomPowerStateValues.On),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOff, s.CustomPowerStateValues.Off),
+	)
+}
@@ -0,0 +1,68 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newSwitch() *platform.Switch {
+	return &platform.Switch{
+		State:   mqtt.NewValue("state", hass.PowerStateMarshaler),
+		Command: mqtt.NewRemoteValue("command", hass.PowerStateUnmarshaler),
+	}
+}
+
+func TestSwitch_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newSwitch(), "switch")
+}
+
+func TestSwitch_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newSwitch()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("ON"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "switch", "unknown"))
+		w.AssertNotPublished(t, "switch/state")
+
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "switch", "command"))
+		w.AssertPublished(t, "switch/state", []byte("ON"))
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newSwitch()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("ON"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "switch", "command"))
+		w.AssertNotPublished(t, "switch/state")
+	})
+}
+
+func TestSwitch_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Switch{}, "switch")
+		require.Error(t, err)
+	})
+
+	sut := newSwitch()
+	sut.DeviceClass = platform.SwitchDeviceClassOutlet
+	sut.CustomPowerStateValues = hass.CustomPowerState{On: "1", Off: "0"}
+
+	payload, err := marshalDiscovery(t, sut, "switch")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "switch/state")
+	hqtttest.RequireField(t, payload, "command_topic", "switch/command")
+	hqtttest.RequireField(t, payload, "device_class", "outlet")
+	hqtttest.RequireField(t, payload, "payload_on", "1")
+	hqtttest.RequireField(t, payload, "payload_off", "0")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
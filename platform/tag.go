@@ -0,0 +1,46 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Tag is a hqtt.Platform that implements the tag.mqtt integration for Home Assistant, for tag scanners like NFC
+// readers. Home Assistant does not create an entity for tag scanners, use Scan to publish the ID of a scanned tag.
+//
+// See https://www.home-assistant.io/integrations/tag.mqtt/
+type Tag struct {
+	// The IDs of scanned tags are published to this value
+	Topic *mqtt.Value[string] `hqtt:"required"`
+	// Extracts the tag ID from messages published to Topic, for devices that publish scans in a format Home Assistant
+	// does not expect
+	ValueTemplate hass.Template
+}
+
+func (t *Tag) PlatformName() string {
+	return "tag"
+}
+
+func (t *Tag) Subscriptions(_ string) []mqtt.Subscription {
+	return nil
+}
+
+func (t *Tag) ServeMQTT(_ mqtt.Writer, _ string, _ []byte) {}
+
+// Scan publishes the ID of a scanned tag to Topic. Home Assistant handles every scan, even if the same tag is scanned
+// again.
+func (t *Tag) Scan(ctx context.Context, w mqtt.Writer, prefix, id string) error {
+	return mqtt.Error(t.Topic.Write(ctx, w, prefix, id))
+}
+
+func (t *Tag) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MarshalRequiredValueTopic("topic", e, discovery.FieldTopic, t.Topic, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, t.ValueTemplate),
+	)
+}
@@ -0,0 +1,39 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newTag() *platform.Tag {
+	return &platform.Tag{Topic: mqtt.NewValue("scanned", mqtt.StringMarshaler)}
+}
+
+func TestTag_Scan(t *testing.T) {
+	sut := newTag()
+
+	w := &hqtttest.Writer{}
+	require.NoError(t, sut.Scan(t.Context(), w, "reader", "04:a2:3b:c1"))
+	w.AssertPublished(t, "reader/scanned", []byte("04:a2:3b:c1"))
+}
+
+func TestTag_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Topic Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Tag{}, "reader")
+		require.Error(t, err)
+	})
+
+	sut := newTag()
+	sut.ValueTemplate = "{{ value_json.id }}"
+
+	payload, err := marshalDiscovery(t, sut, "reader")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "topic", "reader/scanned")
+	hqtttest.RequireField(t, payload, "value_template", "{{ value_json.id }}")
+}
@@ -0,0 +1,72 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// TextMode controls how Home Assistant displays a Text in the frontend.
+type TextMode string
+
+const (
+	// TextModeText displays the value as plain text. This is the default.
+	TextModeText TextMode = "text"
+	// TextModePassword hides the value in the frontend.
+	TextModePassword TextMode = "password"
+)
+
+// Text is a hqtt.Platform that implements the text.mqtt integration for Home Assistant, for free-form text settings of
+// a device. If State is nil, Home Assistant operates the text in optimistic mode.
+//
+// See https://www.home-assistant.io/integrations/text.mqtt/
+type Text struct {
+	// The current value of the text
+	State *mqtt.Value[string]
+	// Extracts the value from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+	// Home Assistant will write the desired value to this value
+	Command *mqtt.RemoteValue[string] `hqtt:"required"`
+	// Renders the payload Home Assistant writes to Command. The text is available as value.
+	CommandTemplate hass.Template
+
+	// The minimum length of the value. Defaults to 0 if not set.
+	MinLength uint
+	// The maximum length of the value. Defaults to 255 if not set.
+	MaxLength uint
+	// A regular expression the value must match before Home Assistant writes it to Command
+	Pattern string
+	// How the value is displayed in the frontend. Defaults to TextModeText if not set.
+	Mode TextMode
+}
+
+func (t *Text) PlatformName() string {
+	return "text"
+}
+
+func (t *Text) Subscriptions(prefix string) []mqtt.Subscription {
+	return t.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (t *Text) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	t.Command.ServeMQTT(w, topic, payload)
+}
+
+func (t *Text) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, t.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, t.ValueTemplate),
+		discovery.MarshalRequiredRemoteValueTopic("command", e, discovery.FieldCommandTopic, t.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, t.CommandTemplate),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMin, t.MinLength),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMax, t.MaxLength),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPattern, t.Pattern),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldMode, t.Mode),
+	)
+}
@@ -0,0 +1,44 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newText() *platform.Text {
+	return &platform.Text{
+		State:   mqtt.NewValue("state", mqtt.StringMarshaler),
+		Command: mqtt.NewRemoteValue("command", mqtt.StringUnmarshaler),
+	}
+}
+
+func TestText_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newText(), "text")
+}
+
+func TestText_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("Command Required", func(t *testing.T) {
+		_, err := marshalDiscovery(t, &platform.Text{}, "text")
+		require.Error(t, err)
+	})
+
+	sut := newText()
+	sut.MaxLength = 32
+	sut.Pattern = "^[a-z]+$"
+	sut.Mode = platform.TextModePassword
+
+	payload, err := marshalDiscovery(t, sut, "text")
+	require.NoError(t, err)
+
+	hqtttest.RequireField(t, payload, "state_topic", "text/state")
+	hqtttest.RequireField(t, payload, "command_topic", "text/command")
+	hqtttest.RequireField(t, payload, "max", 32)
+	hqtttest.RequireField(t, payload, "pattern", "^[a-z]+$")
+	hqtttest.RequireField(t, payload, "mode", "password")
+	hqtttest.RequireNoField(t, payload, "min")
+}
@@ -0,0 +1,130 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"log/slog"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// UpdateDeviceClassFirmware marks an Update as a firmware update. See
+// https://www.home-assistant.io/integrations/update/#device-classes.
+const UpdateDeviceClassFirmware = "firmware"
+
+// UpdateState is the payload published to Update.State. Empty fields are omitted, and Home Assistant keeps their
+// previous value. It implements slog.LogValuer.
+type UpdateState struct {
+	// The version currently installed
+	InstalledVersion string `json:"installed_version,omitzero"`
+	// The latest version available. Home Assistant offers to install it if it differs from InstalledVersion.
+	LatestVersion string `json:"latest_version,omitzero"`
+	// Overrides Update.Title
+	Title string `json:"title,omitzero"`
+	// Overrides Update.ReleaseSummary
+	ReleaseSummary string `json:"release_summary,omitzero"`
+	// Overrides Update.ReleaseURL
+	ReleaseURL string `json:"release_url,omitzero"`
+
+	// Whether an update is being installed. It is always published, so Home Assistant stops showing progress once it
+	// is cleared.
+	InProgress bool `json:"in_progress"`
+	// How far along the update is, between 0 and 100. If nil, Home Assistant shows progress without a percentage.
+	UpdatePercentage *float64 `json:"update_percentage,omitzero"`
+}
+
+func (s UpdateState) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("installed_version", s.InstalledVersion),
+		slog.String("latest_version", s.LatestVersion),
+		slog.Bool("in_progress", s.InProgress),
+	}
+
+	if s.UpdatePercentage != nil {
+		attrs = append(attrs, slog.Float64("update_percentage", *s.UpdatePercentage))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// Update is a hqtt.Platform that implements the update.mqtt integration for Home Assistant, using the json state
+// schema. Home Assistant writes PayloadInstall to Command when the user installs the latest version, use
+// ReportProgress to show the progress of the installation.
+//
+// See https://www.home-assistant.io/integrations/update.mqtt/
+type Update struct {
+	// The type/class of the update, see UpdateDeviceClassFirmware
+	DeviceClass string
+
+	// The installed version, and optionally the latest version and installation progress. Use mqtt.JsonValueMarshaler
+	// to publish it in the format Home Assistant expects.
+	State *mqtt.Value[UpdateState]
+	// Extracts the state from messages published to State, for devices that publish their state in a format Home
+	// Assistant does not expect
+	ValueTemplate hass.Template
+
+	// The latest version available, for devices that publish it separately from State
+	LatestVersion *mqtt.Value[string]
+	// Extracts the latest version from messages published to LatestVersion
+	LatestVersionTemplate hass.Template
+
+	// Home Assistant will write PayloadInstall to this value to install the latest version
+	Command *mqtt.RemoteValue[string]
+	// The payload Home Assistant writes to Command. If not set, Home Assistant writes an empty payload.
+	PayloadInstall string
+
+	// The title of the software or firmware
+	Title string
+	// A summary of the changes in the latest version, up to 255 characters
+	ReleaseSummary string
+	// A link to the full release notes of the latest version
+	ReleaseURL string
+	// The number of decimals UpdateState.UpdatePercentage is displayed with
+	DisplayPrecision uint
+}
+
+func (u *Update) PlatformName() string {
+	return "update"
+}
+
+func (u *Update) Subscriptions(prefix string) []mqtt.Subscription {
+	return u.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to Command.
+func (u *Update) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	u.Command.ServeMQTT(w, topic, payload)
+}
+
+// ReportProgress writes the last value written to State with UpdateState.InProgress set and the provided percentage.
+// Write the new UpdateState.InstalledVersion with UpdateState.InProgress cleared to State once the installation
+// completes.
+func (u *Update) ReportProgress(ctx context.Context, w mqtt.Writer, prefix string, percentage float64) error {
+	state, _ := u.State.Get()
+	state.InProgress = true
+	state.UpdatePercentage = &percentage
+
+	return mqtt.Error(u.State.Write(ctx, w, prefix, state))
+}
+
+func (u *Update) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, u.DeviceClass),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, u.State, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, u.ValueTemplate),
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldLatestVersionTopic, u.LatestVersion, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldLatestVersionTemplate, u.LatestVersionTemplate),
+
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldCommandTopic, u.Command, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadInstall, u.PayloadInstall),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTitle, u.Title),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldReleaseSummary, u.ReleaseSummary),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldReleaseURL, u.ReleaseURL),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDisplayPrecision, u.DisplayPrecision),
+	)
+}
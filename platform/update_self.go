@@ -0,0 +1,202 @@
+package platform
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/v2"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+	"github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// DefaultSelfUpdateInterval is how often SelfUpdate.Run checks for a new release if SelfUpdate.Interval is not set.
+const DefaultSelfUpdateInterval = 6 * time.Hour
+
+// Home Assistant truncates release summaries longer than this
+const maxReleaseSummary = 255
+
+var selfUpdateLog = log.ForComponent("platform.update")
+
+// Release describes the latest release of a program, as reported by a ReleaseSource.
+type Release struct {
+	// The version of the release, compared as-is with SelfUpdate.InstalledVersion by Home Assistant
+	Version string
+	// The name of the release
+	Title string
+	// A summary of the changes in the release, truncated to 255 characters when published
+	Summary string
+	// A link to the full release notes
+	URL string
+}
+
+// ReleaseSource looks up the latest release of a program. See GitHubReleases.
+type ReleaseSource interface {
+	LatestRelease(ctx context.Context) (Release, error)
+}
+
+// ReleaseSourceFunc adapts a function to a ReleaseSource.
+type ReleaseSourceFunc func(ctx context.Context) (Release, error)
+
+func (f ReleaseSourceFunc) LatestRelease(ctx context.Context) (Release, error) {
+	return f(ctx)
+}
+
+// GitHubReleases is a ReleaseSource that reads the latest published release of a GitHub repository from the GitHub REST
+// API. Drafts and pre-releases are ignored by GitHub. The tag of the release is used as its version.
+//
+// See https://docs.github.com/en/rest/releases/releases#get-the-latest-release
+type GitHubReleases struct {
+	// The owner and name of the repository, for example nlowe/hqtt
+	Owner string
+	Repo  string
+
+	// An optional token, to raise the rate limit of the API or read private repositories
+	Token string
+
+	// The client used to call the API. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// The base URL of the API, for GitHub Enterprise. If empty, https://api.github.com is used.
+	BaseURL string
+}
+
+type gitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (g *GitHubReleases) LatestRelease(ctx context.Context) (Release, error) {
+	u, err := url.JoinPath(cmp.Or(g.BaseURL, "https://api.github.com"), "repos", g.Owner, g.Repo, "releases", "latest")
+	if err != nil {
+		return Release{}, fmt.Errorf("github: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Release{}, fmt.Errorf("github: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Release{}, fmt.Errorf("github: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("github: latest release of %s/%s: %s", g.Owner, g.Repo, resp.Status)
+	}
+
+	var r gitHubRelease
+	if err = json.UnmarshalRead(resp.Body, &r); err != nil {
+		return Release{}, fmt.Errorf("github: decode latest release of %s/%s: %w", g.Owner, g.Repo, err)
+	}
+
+	return Release{Version: r.TagName, Title: r.Name, Summary: r.Body, URL: r.HTMLURL}, nil
+}
+
+// SelfUpdate publishes the installed and latest versions of the bridge itself to an Update, so Home Assistant notifies
+// users when a bridge needs upgrading. Run checks Source for the latest release periodically. If the bridge can upgrade
+// itself, watch Update.Command, report progress with Update.ReportProgress, and call Installed once the new version is
+// running. Otherwise, leave Update.Command unset so Home Assistant only shows that an update is available.
+type SelfUpdate struct {
+	// The Update the versions are published to. Its State must be set.
+	Update *Update
+	// Where the latest release is looked up, for example GitHubReleases
+	Source ReleaseSource
+
+	// The version of the running bridge, for example the main module version from debug.ReadBuildInfo
+	InstalledVersion string
+
+	// How often Run checks for a new release. If not positive, DefaultSelfUpdateInterval is used.
+	Interval time.Duration
+	// The Clock used by Run. If nil, clock.Real is used.
+	Clock clock.Clock
+}
+
+// Check looks up the latest release and publishes it to Update.State along with InstalledVersion. Progress reported
+// with Update.ReportProgress is kept, so a periodic check does not hide an installation from Home Assistant.
+func (s *SelfUpdate) Check(ctx context.Context, w mqtt.Writer, prefix string) error {
+	release, err := s.Source.LatestRelease(ctx)
+	if err != nil {
+		return fmt.Errorf("check for update: %w", err)
+	}
+
+	state, _ := s.Update.State.Get()
+	state.InstalledVersion = cmp.Or(state.InstalledVersion, s.InstalledVersion)
+	state.LatestVersion = release.Version
+	state.Title = release.Title
+	state.ReleaseSummary = truncate(release.Summary, maxReleaseSummary)
+	state.ReleaseURL = release.URL
+
+	return mqtt.Error(s.Update.State.Write(ctx, w, prefix, state))
+}
+
+// Installed publishes the provided version as installed, and clears the progress reported with Update.ReportProgress.
+// Call it once the bridge upgraded itself without restarting.
+func (s *SelfUpdate) Installed(ctx context.Context, w mqtt.Writer, prefix string, version string) error {
+	state, _ := s.Update.State.Get()
+	state.InstalledVersion = version
+	state.InProgress = false
+	state.UpdatePercentage = nil
+
+	return mqtt.Error(s.Update.State.Write(ctx, w, prefix, state))
+}
+
+// Run calls Check immediately and then every Interval until the provided context is done, at which point the cause of
+// the cancellation is returned. Errors from Check are logged and do not stop checking, so an outage of Source or of
+// the broker only delays the next update notification.
+func (s *SelfUpdate) Run(ctx context.Context, w mqtt.Writer, prefix string) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSelfUpdateInterval
+	}
+
+	t := clock.Or(s.Clock).NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := s.Check(ctx, w, prefix); err != nil && ctx.Err() == nil {
+			selfUpdateLog.With(slog.String("topic", s.Update.State.FullyQualifiedTopic(prefix)), log.Error(err)).WarnContext(ctx, "Failed to check for update")
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-t.C():
+		}
+	}
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n])
+}
+
+var (
+	_ ReleaseSource = &GitHubReleases{}
+	_ ReleaseSource = ReleaseSourceFunc(nil)
+)
@@ -0,0 +1,144 @@
+package platform_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func TestGitHubReleases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/nlowe/hqtt/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+
+		assert.Equal(t, "application/vnd.github+json", r.Header.Get("Accept"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"tag_name":"v1.2.0","name":"hqtt 1.2.0","body":"Fixes","html_url":"https://github.com/nlowe/hqtt/releases/tag/v1.2.0","draft":false}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	sut := &platform.GitHubReleases{Owner: "nlowe", Repo: "hqtt", Token: "secret", Client: srv.Client(), BaseURL: srv.URL}
+
+	release, err := sut.LatestRelease(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, platform.Release{
+		Version: "v1.2.0",
+		Title:   "hqtt 1.2.0",
+		Summary: "Fixes",
+		URL:     "https://github.com/nlowe/hqtt/releases/tag/v1.2.0",
+	}, release)
+
+	t.Run("Not Found", func(t *testing.T) {
+		sut := &platform.GitHubReleases{Owner: "nlowe", Repo: "missing", Client: srv.Client(), BaseURL: srv.URL}
+
+		_, err := sut.LatestRelease(t.Context())
+		require.ErrorContains(t, err, "404 Not Found")
+	})
+}
+
+func TestSelfUpdate_Check(t *testing.T) {
+	u := newUpdate()
+	w := &hqtttest.Writer{}
+
+	sut := &platform.SelfUpdate{
+		Update:           u,
+		InstalledVersion: "v1.0.0",
+		Source: platform.ReleaseSourceFunc(func(context.Context) (platform.Release, error) {
+			return platform.Release{Version: "v1.1.0", Summary: strings.Repeat("a", 300), URL: "https://example.com/v1.1.0"}, nil
+		}),
+	}
+
+	require.NoError(t, sut.Check(t.Context(), w, "bridge"))
+	state, ok := u.State.Get()
+	require.True(t, ok)
+	assert.Equal(t, "v1.0.0", state.InstalledVersion)
+	assert.Equal(t, "v1.1.0", state.LatestVersion)
+	assert.Equal(t, "https://example.com/v1.1.0", state.ReleaseURL)
+	assert.Len(t, state.ReleaseSummary, 255)
+
+	t.Run("Progress", func(t *testing.T) {
+		require.NoError(t, u.ReportProgress(t.Context(), w, "bridge", 50))
+		require.NoError(t, sut.Check(t.Context(), w, "bridge"))
+
+		// A check while installing does not hide the progress
+		state, _ := u.State.Get()
+		assert.True(t, state.InProgress)
+		require.NotNil(t, state.UpdatePercentage)
+		assert.InDelta(t, 50, *state.UpdatePercentage, 0)
+
+		require.NoError(t, sut.Installed(t.Context(), w, "bridge", "v1.1.0"))
+		w.AssertPublished(t, "bridge/state", []byte(`{"installed_version":"v1.1.0","latest_version":"v1.1.0","release_summary":"`+strings.Repeat("a", 255)+`","release_url":"https://example.com/v1.1.0","in_progress":false}`))
+
+		// The installed version is not reset to the version the bridge started with
+		require.NoError(t, sut.Check(t.Context(), w, "bridge"))
+		state, _ = u.State.Get()
+		assert.Equal(t, "v1.1.0", state.InstalledVersion)
+	})
+
+	t.Run("Source Fails", func(t *testing.T) {
+		boom := errors.New("boom")
+		sut := &platform.SelfUpdate{
+			Update: newUpdate(),
+			Source: platform.ReleaseSourceFunc(func(context.Context) (platform.Release, error) {
+				return platform.Release{}, boom
+			}),
+		}
+
+		w := &hqtttest.Writer{}
+		require.ErrorIs(t, sut.Check(t.Context(), w, "bridge"), boom)
+		assert.Empty(t, w.Messages())
+	})
+}
+
+func TestSelfUpdate_Run(t *testing.T) {
+	var latest atomic.Value
+	latest.Store("v1.0.0")
+
+	c := hqtttest.NewClock(time.Now())
+	w := &hqtttest.Writer{}
+	sut := &platform.SelfUpdate{
+		Update:           newUpdate(),
+		InstalledVersion: "v1.0.0",
+		Interval:         time.Hour,
+		Clock:            c,
+		Source: platform.ReleaseSourceFunc(func(context.Context) (platform.Release, error) {
+			return platform.Release{Version: latest.Load().(string)}, nil
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- sut.Run(ctx, w, "bridge")
+	}()
+
+	// The first check happens immediately
+	require.NoError(t, c.BlockUntil(t.Context(), 1))
+	require.Eventually(t, func() bool {
+		return len(w.Messages()) == 1
+	}, time.Second, time.Millisecond)
+	w.AssertPublished(t, "bridge/state", []byte(`{"installed_version":"v1.0.0","latest_version":"v1.0.0","in_progress":false}`))
+
+	latest.Store("v1.1.0")
+	c.Advance(time.Hour)
+	require.Eventually(t, func() bool {
+		return len(w.Messages()) == 2
+	}, time.Second, time.Millisecond)
+	w.AssertPublished(t, "bridge/state", []byte(`{"installed_version":"v1.0.0","latest_version":"v1.1.0","in_progress":false}`))
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
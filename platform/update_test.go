@@ -0,0 +1,59 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newUpdate() *platform.Update {
+	return &platform.Update{
+		DeviceClass: platform.UpdateDeviceClassFirmware,
+		State:       mqtt.NewValue("state", mqtt.JsonValueMarshaler[platform.UpdateState]()),
+		Command:     mqtt.NewRemoteValue("install", mqtt.StringUnmarshaler),
+	}
+}
+
+func TestUpdate_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newUpdate(), "update")
+}
+
+func TestUpdate_ReportProgress(t *testing.T) {
+	sut := newUpdate()
+
+	w := &hqtttest.Writer{}
+	_, err := sut.State.Write(t.Context(), w, "firmware", platform.UpdateState{InstalledVersion: "1.0.0", LatestVersion: "1.1.0"})
+	require.NoError(t, err)
+	w.AssertPublished(t, "firmware/state", []byte(`{"installed_version":"1.0.0","latest_version":"1.1.0","in_progress":false}`))
+
+	require.NoError(t, sut.ReportProgress(t.Context(), w, "firmware", 42))
+	w.AssertPublished(t, "firmware/state", []byte(`{"installed_version":"1.0.0","latest_version":"1.1.0","in_progress":true,"update_percentage":42}`))
+}
+
+func TestUpdate_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	sut := newUpdate()
+	sut.PayloadInstall = "install"
+	sut.ReleaseURL = "https://example.com/releases"
+
+	require.NoError(t, sut.MarshalDiscoveryTo(e, "firmware"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "device_class", "firmware")
+	hqtttest.RequireField(t, payload, "state_topic", "firmware/state")
+	hqtttest.RequireField(t, payload, "command_topic", "firmware/install")
+	hqtttest.RequireField(t, payload, "payload_install", "install")
+	hqtttest.RequireField(t, payload, "release_url", "https://example.com/releases")
+	hqtttest.RequireNoField(t, payload, "latest_version_topic")
+	hqtttest.RequireNoField(t, payload, "display_precision")
+}
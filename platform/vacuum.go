@@ -0,0 +1,125 @@
+package platform
+
+import (
+	"encoding/json/jsontext"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// VacuumFeature is a feature a Vacuum supports. See
+// https://www.home-assistant.io/integrations/vacuum.mqtt/#supported_features.
+type VacuumFeature string
+
+const (
+	VacuumFeatureStart       VacuumFeature = "start"
+	VacuumFeatureStop        VacuumFeature = "stop"
+	VacuumFeaturePause       VacuumFeature = "pause"
+	VacuumFeatureReturnHome  VacuumFeature = "return_home"
+	VacuumFeatureBattery     VacuumFeature = "battery"
+	VacuumFeatureStatus      VacuumFeature = "status"
+	VacuumFeatureLocate      VacuumFeature = "locate"
+	VacuumFeatureCleanSpot   VacuumFeature = "clean_spot"
+	VacuumFeatureFanSpeed    VacuumFeature = "fan_speed"
+	VacuumFeatureSendCommand VacuumFeature = "send_command"
+)
+
+// VacuumState is the payload published to Vacuum.State. BatteryLevel and FanSpeed are only used by Home Assistant if
+// VacuumFeatureBattery and VacuumFeatureFanSpeed are supported. It implements slog.LogValuer.
+type VacuumState struct {
+	State        hass.VacuumActivity `json:"state"`
+	BatteryLevel uint                `json:"battery_level,omitzero"`
+	FanSpeed     string              `json:"fan_speed,omitzero"`
+}
+
+func (s VacuumState) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("state", string(s.State)),
+		slog.Uint64("battery_level", uint64(s.BatteryLevel)),
+		slog.String("fan_speed", s.FanSpeed),
+	)
+}
+
+// Vacuum is a hqtt.Platform that implements the vacuum.mqtt integration for Home Assistant, using the state schema.
+//
+// See https://www.home-assistant.io/integrations/vacuum.mqtt/
+type Vacuum struct {
+	// The features this vacuum supports. Home Assistant assumes start, stop, return_home, status, and clean_spot if
+	// not set.
+	SupportedFeatures []VacuumFeature
+
+	// The current state of the vacuum. Use mqtt.JsonValueMarshaler to publish it in the format Home Assistant expects.
+	State *mqtt.Value[VacuumState]
+	// Home Assistant will write start, pause, stop, return to base, clean spot, and locate commands to this value
+	Command *mqtt.RemoteValue[hass.VacuumCommand]
+	// Custom values to use for payload commands
+	CustomCommandValues hass.CustomVacuumCommand
+
+	// Home Assistant will write the desired fan speed to this value
+	FanSpeedCommand *mqtt.RemoteValue[string]
+	// The fan speeds this vacuum supports
+	FanSpeeds []string
+
+	// Home Assistant will write the commands sent with the vacuum.send_command action to this value. The payload is
+	// the command if no parameters are provided, or a json object with command and params otherwise.
+	SendCommand *mqtt.RemoteValue[string]
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+}
+
+func (v *Vacuum) PlatformName() string {
+	return "vacuum"
+}
+
+func (v *Vacuum) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+
+	result = v.Command.AppendSubscribeOptions(result, prefix)
+	result = v.FanSpeedCommand.AppendSubscribeOptions(result, prefix)
+	result = v.SendCommand.AppendSubscribeOptions(result, prefix)
+
+	return result
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by routing it to the command value with a
+// matching topic. It is up to the user to ensure each configured mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
+func (v *Vacuum) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	v.routesOnce.Do(func() {
+		v.routes = mqtt.Routes{}
+
+		v.Command.AddRoute(v.routes)
+		v.FanSpeedCommand.AddRoute(v.routes)
+		v.SendCommand.AddRoute(v.routes)
+	})
+
+	v.routes.ServeMQTT(w, topic, payload)
+}
+
+func (v *Vacuum) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldSupportedFeatures, v.SupportedFeatures),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, v.State, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldCommandTopic, v.Command, prefix),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadStart, v.CustomCommandValues.Start),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadPause, v.CustomCommandValues.Pause),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadStop, v.CustomCommandValues.Stop),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadReturnToBase, v.CustomCommandValues.ReturnToBase),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadCleanSpot, v.CustomCommandValues.CleanSpot),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadLocate, v.CustomCommandValues.Locate),
+
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldSetFanSpeedTopic, v.FanSpeedCommand, prefix),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldFanSpeedList, v.FanSpeeds),
+
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldSendCommandTopic, v.SendCommand, prefix),
+	)
+}
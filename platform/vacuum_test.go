@@ -0,0 +1,61 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newVacuum() *platform.Vacuum {
+	return &platform.Vacuum{
+		State:           mqtt.NewValue("state", mqtt.JsonValueMarshaler[platform.VacuumState]()),
+		Command:         mqtt.NewRemoteValue("command", hass.VacuumCommandUnmarshaler),
+		FanSpeedCommand: mqtt.NewRemoteValue("fan_speed/set", mqtt.StringUnmarshaler),
+		SendCommand:     mqtt.NewRemoteValue("send_command", mqtt.StringUnmarshaler),
+	}
+}
+
+func TestVacuum_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newVacuum(), "vacuum")
+}
+
+func TestVacuum_State(t *testing.T) {
+	sut := newVacuum()
+
+	w := &hqtttest.Writer{}
+	_, err := sut.State.Write(t.Context(), w, "vacuum", platform.VacuumState{State: hass.VacuumActivityDocked, BatteryLevel: 61})
+	require.NoError(t, err)
+
+	w.AssertPublished(t, "vacuum/state", []byte(`{"state":"docked","battery_level":61}`))
+}
+
+func TestVacuum_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	sut := newVacuum()
+	sut.SupportedFeatures = []platform.VacuumFeature{platform.VacuumFeatureStart, platform.VacuumFeatureFanSpeed}
+	sut.FanSpeeds = []string{"min", "max"}
+	sut.CustomCommandValues = hass.CustomVacuumCommand{ReturnToBase: "dock"}
+
+	require.NoError(t, sut.MarshalDiscoveryTo(e, "vacuum"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "supported_features", []string{"start", "fan_speed"})
+	hqtttest.RequireField(t, payload, "state_topic", "vacuum/state")
+	hqtttest.RequireField(t, payload, "command_topic", "vacuum/command")
+	hqtttest.RequireField(t, payload, "payload_return_to_base", "dock")
+	hqtttest.RequireField(t, payload, "set_fan_speed_topic", "vacuum/fan_speed/set")
+	hqtttest.RequireField(t, payload, "fan_speed_list", []string{"min", "max"})
+	hqtttest.RequireField(t, payload, "send_command_topic", "vacuum/send_command")
+	hqtttest.RequireNoField(t, payload, "payload_start")
+}
@@ -0,0 +1,168 @@
+package platform
+
+import (
+	"cmp"
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// Device classes for Valve. See https://www.home-assistant.io/integrations/valve/#device-class.
+const (
+	ValveDeviceClassWater = "water"
+	ValveDeviceClassGas   = "gas"
+)
+
+// Valve is a hqtt.Platform that implements the valve.mqtt integration for Home Assistant.
+//
+// Home Assistant uses a single state topic and a single command topic for valves. If ReportsPosition is set, they carry
+// positions and are configured with Position and PositionCommand, otherwise they carry states and commands and are
+// configured with State and Command. The values of the other mode are ignored.
+//
+// See https://www.home-assistant.io/integrations/valve.mqtt/
+type Valve struct {
+	// Flag that defines if the valve works in optimistic mode. When set, commands received from Home Assistant are
+	// automatically written to State as the open and closed states, or to Position if ReportsPosition is set.
+	Optimistic bool
+
+	// The type/class of the valve to set the icon in the frontend, see ValveDeviceClassWater and ValveDeviceClassGas.
+	DeviceClass string
+
+	// Whether the valve reports its position rather than its state
+	ReportsPosition bool
+
+	// The current state of the valve, if ReportsPosition is not set
+	State *mqtt.Value[hass.ValveState]
+	// Home Assistant will write open, close, and stop commands for this entity to this value, if ReportsPosition is
+	// not set
+	Command *mqtt.RemoteValue[hass.ValveCommand]
+
+	// The current position of the valve between PositionClosed and PositionOpen, if ReportsPosition is set
+	Position *mqtt.Value[uint]
+	// Home Assistant will write the desired position to this value, if ReportsPosition is set
+	PositionCommand *mqtt.RemoteValue[uint]
+	// The position that represents an open valve. Defaults to 100 if nil.
+	PositionOpen *uint
+	// The position that represents a closed valve. Defaults to 0 if nil.
+	PositionClosed *uint
+
+	// Extracts the state or position from messages published to State or Position
+	ValueTemplate hass.Template
+	// Renders the payload Home Assistant writes to Command or PositionCommand. The command or position is available as
+	// value.
+	CommandTemplate hass.Template
+
+	// Custom values to use for payload commands. Home Assistant only supports stopping the valve if Stop is set. If
+	// ReportsPosition is set, Open and Close are not used, and stop commands are written to Command, which must have
+	// the same topic as PositionCommand.
+	CustomCommandValues hass.CustomValveCommand
+	// Custom values to use for states
+	CustomStateValues hass.CustomValveState
+}
+
+func (v *Valve) PlatformName() string {
+	return "valve"
+}
+
+func (v *Valve) Subscriptions(prefix string) []mqtt.Subscription {
+	if v.ReportsPosition {
+		return v.PositionCommand.AppendSubscribeOptions(nil, prefix)
+	}
+
+	return v.Command.AppendSubscribeOptions(nil, prefix)
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by passing it to PositionCommand if
+// ReportsPosition is set, or Command otherwise. Stop commands are always passed to Command.
+func (v *Valve) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	if v.ReportsPosition && !v.isStop(payload) {
+		v.PositionCommand.ServeMQTT(w, topic, payload)
+		return
+	}
+
+	v.Command.ServeMQTT(w, topic, payload)
+}
+
+// isStop reports whether the provided payload is the configured stop command.
+func (v *Valve) isStop(payload []byte) bool {
+	return v.CustomCommandValues.Stop != "" && string(payload) == string(v.CustomCommandValues.Stop)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform if Optimistic is set. Positions are written to Position, and open and
+// close commands are written to State as the open and closed states.
+func (v *Valve) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !v.Optimistic {
+		return nil
+	}
+
+	if v.ReportsPosition {
+		if v.PositionCommand == nil || topic != v.PositionCommand.FullyQualifiedTopic("") {
+			return nil
+		}
+
+		return mqtt.Echo(ctx, w, prefix, v.PositionCommand, v.Position)
+	}
+
+	if v.Command == nil || v.State == nil || topic != v.Command.FullyQualifiedTopic("") {
+		return nil
+	}
+
+	command, ok := v.Command.LastReceived()
+	if !ok {
+		return nil
+	}
+
+	var state hass.ValveState
+	switch command {
+	case cmp.Or(v.CustomCommandValues.Open, hass.ValveCommandOpen):
+		state = cmp.Or(v.CustomStateValues.Open, hass.ValveStateOpen)
+	case cmp.Or(v.CustomCommandValues.Close, hass.ValveCommandClose):
+		state = cmp.Or(v.CustomStateValues.Closed, hass.ValveStateClosed)
+	default:
+		return nil
+	}
+
+	return mqtt.Error(v.State.Write(ctx, w, prefix, state))
+}
+
+func (v *Valve) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	var topics error
+	if v.ReportsPosition {
+		topics = errors.Join(
+			discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, v.Position, prefix),
+			discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldCommandTopic, v.PositionCommand, prefix),
+		)
+	} else {
+		// Home Assistant rejects the open and close payloads for valves that report their position
+		topics = errors.Join(
+			discovery.MaybeMarshalValueTopic(e, discovery.FieldStateTopic, v.State, prefix),
+			discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldCommandTopic, v.Command, prefix),
+			discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadOpen, v.CustomCommandValues.Open),
+			discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadClose, v.CustomCommandValues.Close),
+		)
+	}
+
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, v.Optimistic),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldDeviceClass, v.DeviceClass),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldReportsPosition, v.ReportsPosition),
+
+		topics,
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldValueTemplate, v.ValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCommandTemplate, v.CommandTemplate),
+
+		discovery.MaybeMarshalStd(e, discovery.FieldPositionOpen, v.PositionOpen),
+		discovery.MaybeMarshalStd(e, discovery.FieldPositionClosed, v.PositionClosed),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPayloadStop, v.CustomCommandValues.Stop),
+
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOpen, v.CustomStateValues.Open),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateOpening, v.CustomStateValues.Opening),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateClosed, v.CustomStateValues.Closed),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldStateClosing, v.CustomStateValues.Closing),
+	)
+}
@@ -0,0 +1,125 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newValve() *platform.Valve {
+	return &platform.Valve{
+		State:   mqtt.NewValue("state", hass.ValveStateMarshaler),
+		Command: mqtt.NewRemoteValue("command", hass.ValveCommandUnmarshaler),
+	}
+}
+
+func newPositionValve() *platform.Valve {
+	return &platform.Valve{
+		ReportsPosition: true,
+		Position:        mqtt.NewValue("position", mqtt.UintMarshaler),
+		PositionCommand: mqtt.NewRemoteValue("command", mqtt.UintUnmarshaler),
+	}
+}
+
+func TestValve_Routing(t *testing.T) {
+	t.Run("State", func(t *testing.T) {
+		hqtttest.AssertRouting(t, newValve(), "valve")
+	})
+
+	t.Run("Position", func(t *testing.T) {
+		hqtttest.AssertRouting(t, newPositionValve(), "valve")
+	})
+
+	t.Run("Position Stop", func(t *testing.T) {
+		sut := newPositionValve()
+		sut.Command = mqtt.NewRemoteValue("command", hass.ValveCommandUnmarshaler)
+		sut.CustomCommandValues.Stop = "STOP"
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("42"))
+		sut.ServeMQTT(w, "command", []byte("STOP"))
+
+		position, ok := sut.PositionCommand.Get()
+		require.True(t, ok)
+		assert.Equal(t, uint(42), position)
+
+		command, ok := sut.Command.Get()
+		require.True(t, ok)
+		assert.Equal(t, hass.ValveCommand("STOP"), command)
+	})
+}
+
+func TestValve_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newValve()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("OPEN"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "valve", "command"))
+		w.AssertPublished(t, "valve/state", []byte("open"))
+
+		sut.ServeMQTT(w, "command", []byte("CLOSE"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "valve", "command"))
+		w.AssertPublished(t, "valve/state", []byte("closed"))
+	})
+
+	t.Run("Optimistic Position", func(t *testing.T) {
+		sut := newPositionValve()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("42"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "valve", "command"))
+		w.AssertPublished(t, "valve/position", []byte("42"))
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newValve()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "command", []byte("OPEN"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "valve", "command"))
+		w.AssertNotPublished(t, "valve/state")
+	})
+}
+
+func TestValve_MarshalDiscoveryTo(t *testing.T) {
+	t.Run("State", func(t *testing.T) {
+		sut := newValve()
+		sut.DeviceClass = platform.ValveDeviceClassWater
+		sut.CustomCommandValues = hass.CustomValveCommand{Open: "1", Close: "0"}
+
+		payload, err := marshalDiscovery(t, sut, "valve")
+		require.NoError(t, err)
+		hqtttest.RequireField(t, payload, "device_class", "water")
+		hqtttest.RequireField(t, payload, "state_topic", "valve/state")
+		hqtttest.RequireField(t, payload, "command_topic", "valve/command")
+		hqtttest.RequireField(t, payload, "payload_open", "1")
+		hqtttest.RequireField(t, payload, "payload_close", "0")
+		hqtttest.RequireNoField(t, payload, "reports_position")
+		hqtttest.RequireNoField(t, payload, "payload_stop")
+	})
+
+	t.Run("Position", func(t *testing.T) {
+		closed := uint(10)
+		sut := newPositionValve()
+		sut.PositionClosed = &closed
+		sut.CustomCommandValues = hass.CustomValveCommand{Open: "1", Stop: "STOP"}
+
+		payload, err := marshalDiscovery(t, sut, "valve")
+		require.NoError(t, err)
+		hqtttest.RequireField(t, payload, "reports_position", true)
+		hqtttest.RequireField(t, payload, "state_topic", "valve/position")
+		hqtttest.RequireField(t, payload, "command_topic", "valve/command")
+		hqtttest.RequireField(t, payload, "position_closed", 10)
+		hqtttest.RequireField(t, payload, "payload_stop", "STOP")
+		hqtttest.RequireNoField(t, payload, "payload_open")
+	})
+}
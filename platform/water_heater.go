@@ -0,0 +1,147 @@
+package platform
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"errors"
+	"sync"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// WaterHeater is a hqtt.Platform that implements the water_heater.mqtt integration for Home Assistant.
+//
+// See https://www.home-assistant.io/integrations/water_heater.mqtt/
+type WaterHeater struct {
+	// Flag that defines if the water heater works in optimistic mode. When set, commands received from Home Assistant
+	// are automatically written to the corresponding state value.
+	Optimistic bool
+
+	// The current temperature of the water
+	CurrentTemperature *mqtt.Value[float64]
+	// Extracts the temperature from messages published to CurrentTemperature
+	CurrentTemperatureValueTemplate hass.Template
+
+	// The current operation mode of the water heater
+	Mode *mqtt.Value[hass.WaterHeaterMode]
+	// Extracts the mode from messages published to Mode
+	ModeValueTemplate hass.Template
+	// Home Assistant will write the desired mode to this value
+	ModeCommand *mqtt.RemoteValue[hass.WaterHeaterMode]
+	// Renders the payload Home Assistant writes to ModeCommand. The mode is available as value.
+	ModeCommandTemplate hass.Template
+	// The list of modes this water heater supports. Home Assistant assumes all modes are supported if not set.
+	Modes []hass.WaterHeaterMode
+
+	// The target temperature
+	TargetTemperature *mqtt.Value[float64]
+	// Extracts the target temperature from messages published to TargetTemperature
+	TargetTemperatureValueTemplate hass.Template
+	// Home Assistant will write the desired target temperature to this value
+	TargetTemperatureCommand *mqtt.RemoteValue[float64]
+	// Renders the payload Home Assistant writes to TargetTemperatureCommand. The temperature is available as value.
+	TargetTemperatureCommandTemplate hass.Template
+
+	// The minimum target temperature. Home Assistant uses 43.3°C or 110°F if nil.
+	MinTemperature *float64
+	// The maximum target temperature. Home Assistant uses 60°C or 140°F if nil.
+	MaxTemperature *float64
+	// The precision temperatures are displayed with, one of 0.1, 0.5, or 1. Home Assistant uses 0.1 for Celsius and 1
+	// for Fahrenheit if not set.
+	Precision float64
+	// The unit temperatures are published and received in, see ClimateTemperatureUnitCelsius and
+	// ClimateTemperatureUnitFahrenheit. Home Assistant uses its configured unit system if not set.
+	TemperatureUnit string
+
+	routesOnce sync.Once
+	routes     mqtt.Routes
+	echoes     map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error
+}
+
+func (h *WaterHeater) PlatformName() string {
+	return "water_heater"
+}
+
+func (h *WaterHeater) Subscriptions(prefix string) []mqtt.Subscription {
+	var result []mqtt.Subscription
+
+	result = h.ModeCommand.AppendSubscribeOptions(result, prefix)
+	result = h.TargetTemperatureCommand.AppendSubscribeOptions(result, prefix)
+
+	return result
+}
+
+// ServeMQTT handles the mqtt payload received on the specified topic suffix by routing it to the command value with a
+// matching topic. It is up to the user to ensure each configured mqtt.RemoteValue has a unique Topic configured.
+//
+// The routing table is built from the configured command values the first time a message is received, so they must
+// not be changed afterwards.
+func (h *WaterHeater) ServeMQTT(w mqtt.Writer, topic string, payload []byte) {
+	h.routing()
+	h.routes.ServeMQTT(w, topic, payload)
+}
+
+// EchoCommand implements hqtt.OptimisticPlatform by writing the command received on the specified topic to the
+// corresponding state value if Optimistic is set.
+func (h *WaterHeater) EchoCommand(ctx context.Context, w mqtt.Writer, prefix, topic string) error {
+	if !h.Optimistic {
+		return nil
+	}
+
+	h.routing()
+	if echo, ok := h.echoes[topic]; ok {
+		return echo(ctx, w, prefix)
+	}
+
+	return nil
+}
+
+// routing builds the tables used by ServeMQTT and EchoCommand to dispatch commands with a single lookup.
+func (h *WaterHeater) routing() {
+	h.routesOnce.Do(func() {
+		h.routes = mqtt.Routes{}
+		h.echoes = map[string]func(ctx context.Context, w mqtt.Writer, prefix string) error{}
+
+		addWaterHeaterRoute(h, h.ModeCommand, h.Mode)
+		addWaterHeaterRoute(h, h.TargetTemperatureCommand, h.TargetTemperature)
+	})
+}
+
+// addWaterHeaterRoute routes the provided command to its RemoteValue, echoing it to the provided state in optimistic
+// mode.
+func addWaterHeaterRoute[T any](h *WaterHeater, command *mqtt.RemoteValue[T], state *mqtt.Value[T]) {
+	if !command.AddRoute(h.routes) || state == nil {
+		return
+	}
+
+	h.echoes[command.FullyQualifiedTopic("")] = func(ctx context.Context, w mqtt.Writer, prefix string) error {
+		return mqtt.Echo(ctx, w, prefix, command, state)
+	}
+}
+
+func (h *WaterHeater) MarshalDiscoveryTo(e *jsontext.Encoder, prefix string) error {
+	return errors.Join(
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldOptimistic, h.Optimistic),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldCurrentTemperatureTopic, h.CurrentTemperature, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldCurrentTemperatureTemplate, h.CurrentTemperatureValueTemplate),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldModeStateTopic, h.Mode, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldModeCommandTopic, h.ModeCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldModeStateTemplate, h.ModeValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldModeCommandTemplate, h.ModeCommandTemplate),
+		discovery.MaybeMarshalStdSlice(e, discovery.FieldModes, h.Modes),
+
+		discovery.MaybeMarshalValueTopic(e, discovery.FieldTemperatureStateTopic, h.TargetTemperature, prefix),
+		discovery.MaybeMarshalRemoteValueTopic(e, discovery.FieldTemperatureCommandTopic, h.TargetTemperatureCommand, prefix),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureStateTemplate, h.TargetTemperatureValueTemplate),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureCommandTemplate, h.TargetTemperatureCommandTemplate),
+
+		discovery.MaybeMarshalStd(e, discovery.FieldMinTemperature, h.MinTemperature),
+		discovery.MaybeMarshalStd(e, discovery.FieldMaxTemperature, h.MaxTemperature),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldPrecision, h.Precision),
+		discovery.MaybeMarshalStdComparable(e, discovery.FieldTemperatureUnit, h.TemperatureUnit),
+	)
+}
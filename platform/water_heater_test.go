@@ -0,0 +1,87 @@
+package platform_test
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func newWaterHeater() *platform.WaterHeater {
+	return &platform.WaterHeater{
+		CurrentTemperature:       mqtt.NewValue("temperature/current", mqtt.FloatMarshaler),
+		Mode:                     mqtt.NewValue("mode", hass.WaterHeaterModeMarshaler),
+		ModeCommand:              mqtt.NewRemoteValue("mode/set", hass.WaterHeaterModeUnmarshaler),
+		TargetTemperature:        mqtt.NewValue("temperature", mqtt.FloatMarshaler),
+		TargetTemperatureCommand: mqtt.NewRemoteValue("temperature/set", mqtt.FloatUnmarshaler),
+	}
+}
+
+func TestWaterHeater_Routing(t *testing.T) {
+	hqtttest.AssertRouting(t, newWaterHeater(), "water_heater")
+}
+
+func TestWaterHeater_EchoCommand(t *testing.T) {
+	t.Run("Optimistic", func(t *testing.T) {
+		sut := newWaterHeater()
+		sut.Optimistic = true
+
+		w := &hqtttest.Writer{}
+		for _, tt := range []struct {
+			command string
+			state   string
+			payload string
+		}{
+			{command: "mode/set", state: "water_heater/mode", payload: "eco"},
+			{command: "temperature/set", state: "water_heater/temperature", payload: "52.5"},
+		} {
+			sut.ServeMQTT(w, tt.command, []byte(tt.payload))
+			require.NoError(t, sut.EchoCommand(t.Context(), w, "water_heater", tt.command))
+			w.AssertPublished(t, tt.state, []byte(tt.payload))
+		}
+	})
+
+	t.Run("Not Optimistic", func(t *testing.T) {
+		sut := newWaterHeater()
+
+		w := &hqtttest.Writer{}
+		sut.ServeMQTT(w, "mode/set", []byte("eco"))
+		require.NoError(t, sut.EchoCommand(t.Context(), w, "water_heater", "mode/set"))
+		w.AssertNotPublished(t, "water_heater/mode")
+	})
+}
+
+func TestWaterHeater_MarshalDiscoveryTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := jsontext.NewEncoder(&buf)
+	require.NoError(t, e.WriteToken(jsontext.BeginObject))
+
+	maxTemperature := 60.0
+	sut := newWaterHeater()
+	sut.Modes = []hass.WaterHeaterMode{hass.WaterHeaterModeOff, hass.WaterHeaterModeEco}
+	sut.MaxTemperature = &maxTemperature
+	sut.Precision = 0.5
+	sut.TemperatureUnit = platform.ClimateTemperatureUnitCelsius
+
+	require.NoError(t, sut.MarshalDiscoveryTo(e, "water_heater"))
+	require.NoError(t, e.WriteToken(jsontext.EndObject))
+	payload := buf.Bytes()
+
+	hqtttest.RequireField(t, payload, "current_temperature_topic", "water_heater/temperature/current")
+	hqtttest.RequireField(t, payload, "mode_state_topic", "water_heater/mode")
+	hqtttest.RequireField(t, payload, "mode_command_topic", "water_heater/mode/set")
+	hqtttest.RequireField(t, payload, "modes", []string{"off", "eco"})
+	hqtttest.RequireField(t, payload, "temperature_state_topic", "water_heater/temperature")
+	hqtttest.RequireField(t, payload, "temperature_command_topic", "water_heater/temperature/set")
+	hqtttest.RequireField(t, payload, "max_temp", 60)
+	hqtttest.RequireNoField(t, payload, "min_temp")
+	hqtttest.RequireField(t, payload, "precision", 0.5)
+	hqtttest.RequireField(t, payload, "temperature_unit", "C")
+	hqtttest.RequireNoField(t, payload, "optimistic")
+}
@@ -0,0 +1,155 @@
+package hqtt
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// discoveryEncoder pairs an encoder with the buffer it writes to so both can be reused.
+type discoveryEncoder struct {
+	buf bytes.Buffer
+	e   jsontext.Encoder
+}
+
+// maxPooledDiscoveryBuffer is the capacity above which discovery encoders are not returned to the pool, so rendering one
+// unusually large device does not pin its buffer in memory forever.
+const maxPooledDiscoveryBuffer = 1 << 20
+
+var discoveryEncoders = sync.Pool{New: func() any { return new(discoveryEncoder) }}
+
+// releaseDiscoveryEncoder returns de to the pool unless its buffer grew beyond maxPooledDiscoveryBuffer.
+func releaseDiscoveryEncoder(de *discoveryEncoder) {
+	if de.buf.Cap() > maxPooledDiscoveryBuffer {
+		return
+	}
+
+	discoveryEncoders.Put(de)
+}
+
+// discoveryRenderer encodes the members of a discovery payload and collects the errors returned while encoding them. It
+// is the render path shared by Device.AppendDiscovery and Component.RenderDiscovery, see renderDiscovery.
+//
+// In fail-fast mode (see Device.FailFastDiscovery), nothing else is encoded once a member fails, and only the first
+// error returned for that member is kept. The errors that follow it are usually caused by the payload being left
+// incomplete.
+type discoveryRenderer struct {
+	e        *jsontext.Encoder
+	failFast bool
+	err      error
+}
+
+// failed reports whether the renderer is in fail-fast mode and a member already failed.
+func (r *discoveryRenderer) failed() bool {
+	return r.failFast && r.err != nil
+}
+
+// field encodes one or more members of the payload being rendered with the provided function.
+func (r *discoveryRenderer) field(marshal func(e *jsontext.Encoder) error) {
+	if r.failed() {
+		return
+	}
+
+	r.record("", marshal(r.e))
+}
+
+// components encodes the provided components as an object named k, ordered by key so the error reported in fail-fast
+// mode does not depend on map iteration order. Errors are annotated with a JSON Pointer to the component that failed.
+func (r *discoveryRenderer) components(k string, components map[string]json.MarshalerTo) {
+	r.field(func(e *jsontext.Encoder) error {
+		return errors.Join(e.WriteToken(jsontext.String(k)), e.WriteToken(jsontext.BeginObject))
+	})
+
+	for _, ck := range slices.Sorted(maps.Keys(components)) {
+		if r.failed() {
+			return
+		}
+
+		if err := errors.Join(r.e.WriteToken(jsontext.String(ck)), components[ck].MarshalJSONTo(r.e)); err != nil {
+			r.record(jsontext.Pointer("").AppendToken(k).AppendToken(ck), err)
+		}
+	}
+
+	r.field(func(e *jsontext.Encoder) error {
+		return e.WriteToken(jsontext.EndObject)
+	})
+}
+
+// record adds err to the errors returned by the renderer, annotated with the provided pointer if it is not empty.
+func (r *discoveryRenderer) record(pointer jsontext.Pointer, err error) {
+	if err == nil {
+		return
+	}
+
+	if r.failFast {
+		err = firstError(err)
+	}
+
+	if pointer != "" {
+		err = fmt.Errorf("%s: %w", pointer, err)
+	}
+
+	r.err = errors.Join(r.err, err)
+}
+
+// firstError returns the first error joined into err (see errors.Join), descending into nested joins.
+func firstError(err error) error {
+	for {
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			return err
+		}
+
+		errs := joined.Unwrap()
+		if len(errs) == 0 {
+			return err
+		}
+
+		err = errs[0]
+	}
+}
+
+// renderDiscovery appends a discovery payload object to dst, using render to encode its members. The payload is
+// canonicalized (object keys are sorted and numbers are normalized, see jsontext.Value.Canonicalize) so it is stable
+// across calls. If failFast is set, rendering stops at the first member that fails, see discoveryRenderer.
+func renderDiscovery(dst []byte, failFast bool, render func(r *discoveryRenderer)) ([]byte, error) {
+	de := discoveryEncoders.Get().(*discoveryEncoder)
+	defer releaseDiscoveryEncoder(de)
+
+	de.buf.Reset()
+	de.e.Reset(
+		&de.buf,
+		jsontext.CanonicalizeRawInts(true),
+		jsontext.CanonicalizeRawFloats(true),
+	)
+
+	r := discoveryRenderer{e: &de.e, failFast: failFast}
+	r.field(func(e *jsontext.Encoder) error {
+		return e.WriteToken(jsontext.BeginObject)
+	})
+
+	render(&r)
+
+	r.field(func(e *jsontext.Encoder) error {
+		return e.WriteToken(jsontext.EndObject)
+	})
+
+	if r.err != nil {
+		return dst, fmt.Errorf("marshal discovery config: %w", r.err)
+	}
+
+	start := len(dst)
+	dst = append(dst, bytes.TrimSpace(de.buf.Bytes())...)
+
+	v := jsontext.Value(dst[start:])
+	if err := v.Canonicalize(); err != nil {
+		return dst[:start], fmt.Errorf("canonicalize discovery config: %w", err)
+	}
+
+	return append(dst[:start], v...), nil
+}
@@ -0,0 +1,160 @@
+package hqtt
+
+import (
+	"encoding/json/v2"
+	"fmt"
+	"reflect"
+
+	"github.com/nlowe/hqtt/discovery"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+// DeviceSchema describes the MQTT contract of a Device and its components: where its discovery payload is published,
+// and every topic it publishes to or subscribes to along with the payloads it expects. It is meant to be marshaled to
+// JSON for external systems and documentation generators. See Device.Schema.
+type DeviceSchema struct {
+	// The ID of the device, see Device.ID
+	ID string `json:"id"`
+	// The topic the discovery payload of the device is published to
+	DiscoveryTopic string `json:"discovery_topic"`
+	// Topics of values owned by the device itself, like its availability
+	Topics []TopicSchema `json:"topics,omitempty"`
+	// The schema of each component, by the key it is configured with
+	Components map[string]ComponentSchema `json:"components"`
+}
+
+// ComponentSchema describes the MQTT contract of a Component. See Component.Schema.
+type ComponentSchema struct {
+	UniqueID string `json:"unique_id"`
+	Platform string `json:"platform"`
+	// The discovery config of the component with every key expanded to its full name, as Home Assistant interprets it
+	Discovery map[string]any `json:"discovery"`
+	// Every topic the component publishes to or subscribes to, in the order its fields are declared
+	Topics []TopicSchema `json:"topics"`
+}
+
+// TopicSchema describes a single topic of a Device or Component.
+type TopicSchema struct {
+	// The name of the field holding the value, for example "State" or "BrightnessCommand"
+	Field string `json:"field"`
+	// The fully qualified topic
+	Topic string `json:"topic"`
+	// Whether the bridge publishes to the topic, or receives messages from Home Assistant on it
+	Direction mqtt.AuditDirection `json:"direction"`
+	// The QoS messages are published with, or the maximum QoS subscribed with
+	QoS mqtt.QualityOfService `json:"qos"`
+	// Whether messages are published with the retain flag
+	Retain bool `json:"retain,omitzero"`
+	// The name of the Go type of the value
+	GoType string `json:"go_type"`
+	// A JSON Schema of the payload, see mqtt.NewPayloadSchema
+	Payload *mqtt.PayloadSchema `json:"payload"`
+}
+
+// schemaDescriber is implemented by Component so Device.Schema can describe components without knowing their platform.
+type schemaDescriber interface {
+	Schema() (ComponentSchema, error)
+}
+
+// Schema describes the MQTT contract of this Device and the provided components, see DeviceSchema. Components that are
+// not a Component (like RemoveComponent) are not included. The device must pass validation performed by Device.Valid.
+func (d *Device) Schema(discoveryPrefix string, components map[string]json.MarshalerTo) (DeviceSchema, error) {
+	if err := d.Valid(); err != nil {
+		return DeviceSchema{}, err
+	}
+
+	result := DeviceSchema{
+		ID:             d.ID(),
+		DiscoveryTopic: d.DiscoveryTopic(discoveryPrefix),
+		Topics:         appendTopicSchema(nil, "Availability", d.Availability, d.TopicPrefix),
+		Components:     make(map[string]ComponentSchema, len(components)),
+	}
+
+	for k, c := range components {
+		s, ok := c.(schemaDescriber)
+		if !ok {
+			continue
+		}
+
+		cs, err := s.Schema()
+		if err != nil {
+			return DeviceSchema{}, fmt.Errorf("component %s: %w", k, err)
+		}
+
+		result.Components[k] = cs
+	}
+
+	return result, nil
+}
+
+// Schema describes the MQTT contract of this Component, see ComponentSchema. Topics are found by looking for exported
+// fields of the Platform (and structs embedded in it) that implement mqtt.Inspector, like mqtt.Value and
+// mqtt.RemoteValue.
+func (c *Component[TPlatform]) Schema() (ComponentSchema, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return ComponentSchema{}, fmt.Errorf("render discovery: %w", err)
+	}
+
+	expanded, err := discovery.Expand(payload)
+	if err != nil {
+		return ComponentSchema{}, err
+	}
+
+	result := ComponentSchema{
+		UniqueID:  c.UniqueID,
+		Platform:  c.Platform.PlatformName(),
+		Discovery: expanded,
+		Topics:    appendTopicSchema(nil, "Availability", c.Availability, c.TopicPrefix),
+	}
+
+	valueFields(reflect.ValueOf(c.Platform), func(field string, i mqtt.Inspector) {
+		result.Topics = appendTopicSchema(result.Topics, field, i, c.TopicPrefix)
+	})
+
+	return result, nil
+}
+
+// valueFields calls yield with the name and value of every exported field of the struct v (or the struct it points to)
+// that implements mqtt.Inspector, like mqtt.Value and mqtt.RemoteValue, including the fields of embedded structs like
+// the Sensor embedded in a BinarySensor.
+func valueFields(v reflect.Value, yield func(field string, i mqtt.Inspector)) {
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if inspector, ok := v.Field(i).Interface().(mqtt.Inspector); ok {
+			yield(f.Name, inspector)
+		} else if f.Anonymous {
+			valueFields(v.Field(i), yield)
+		}
+	}
+}
+
+// appendTopicSchema appends the TopicSchema of d to existing if it has a topic.
+func appendTopicSchema(existing []TopicSchema, field string, d mqtt.Describer, prefix string) []TopicSchema {
+	td, ok := d.Describe(prefix)
+	if !ok {
+		return existing
+	}
+
+	return append(existing, TopicSchema{
+		Field:     field,
+		Topic:     td.Topic,
+		Direction: td.Direction,
+		QoS:       td.QoS,
+		Retain:    td.Retain,
+		GoType:    td.Type.String(),
+		Payload:   mqtt.NewPayloadSchema(td.Type),
+	})
+}
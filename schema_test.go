@@ -0,0 +1,78 @@
+package hqtt
+
+import (
+	"encoding/json/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+func TestDevice_Schema(t *testing.T) {
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := (&Device{}).Schema("", nil)
+		require.ErrorIs(t, err, ErrInvalidDevice)
+	})
+
+	d := &Device{
+		Identifiers:  []string{"lamp"},
+		TopicPrefix:  "lamp",
+		Availability: mqtt.NewValueWithOptions("available", hass.AvailabilityMarshaler, mqtt.WriteOptions{Retain: true}),
+	}
+
+	retained := mqtt.WriteOptions{QoS: mqtt.QOSAtLeastOnce, Retain: true}
+	components := map[string]json.MarshalerTo{
+		"removed": RemoveComponent{Platform: "sensor"},
+		"light": &Component[*platform.Light]{
+			UniqueID:    "lamp.light",
+			TopicPrefix: "lamp/light",
+			Platform: &platform.Light{
+				State:           mqtt.NewValueWithOptions("state", hass.PowerStateMarshaler, retained),
+				Command:         mqtt.NewRemoteValue("set", hass.PowerStateUnmarshaler),
+				RGB:             mqtt.NewValueWithOptions("rgb", platform.RGBMarshaler, retained),
+				RGBCommand:      mqtt.NewRemoteValue("rgb/set", platform.RGBUnmarshaler),
+				BrightnessScale: 100,
+			},
+		},
+	}
+
+	got, err := d.Schema("", components)
+	require.NoError(t, err)
+
+	assert.Equal(t, "lamp", got.ID)
+	assert.Equal(t, "homeassistant/device/lamp/config", got.DiscoveryTopic)
+	assert.Equal(t, []TopicSchema{{
+		Field:     "Availability",
+		Topic:     "lamp/available",
+		Direction: mqtt.AuditDirectionPublish,
+		Retain:    true,
+		GoType:    "hass.Availability",
+		Payload:   &mqtt.PayloadSchema{Type: "string"},
+	}}, got.Topics)
+
+	require.Len(t, got.Components, 1)
+	light := got.Components["light"]
+	assert.Equal(t, "lamp.light", light.UniqueID)
+	assert.Equal(t, "light", light.Platform)
+	assert.Equal(t, "lamp/light/set", light.Discovery["command_topic"])
+	assert.InDelta(t, 100, light.Discovery["brightness_scale"], 0)
+
+	rgb := &mqtt.PayloadSchema{Type: "string", Pattern: `^\d+,\d+,\d+$`}
+	assert.Equal(t, []TopicSchema{
+		{Field: "State", Topic: "lamp/light/state", Direction: mqtt.AuditDirectionPublish, QoS: mqtt.QOSAtLeastOnce, Retain: true, GoType: "hass.PowerState", Payload: &mqtt.PayloadSchema{Type: "string"}},
+		{Field: "Command", Topic: "lamp/light/set", Direction: mqtt.AuditDirectionReceive, GoType: "hass.PowerState", Payload: &mqtt.PayloadSchema{Type: "string"}},
+		{Field: "RGB", Topic: "lamp/light/rgb", Direction: mqtt.AuditDirectionPublish, QoS: mqtt.QOSAtLeastOnce, Retain: true, GoType: "platform.RGB", Payload: rgb},
+		{Field: "RGBCommand", Topic: "lamp/light/rgb/set", Direction: mqtt.AuditDirectionReceive, GoType: "platform.RGB", Payload: rgb},
+	}, light.Topics)
+
+	t.Run("Invalid Component", func(t *testing.T) {
+		_, err := d.Schema("", map[string]json.MarshalerTo{
+			"sensor": &Component[*platform.Sensor[string, any]]{UniqueID: "sensor", Platform: &platform.Sensor[string, any]{}},
+		})
+		require.ErrorContains(t, err, "component sensor: render discovery: ")
+	})
+}
@@ -0,0 +1,140 @@
+package hqtt
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/nlowe/hqtt/clock"
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/log"
+	"github.com/nlowe/hqtt/mqtt"
+	"github.com/nlowe/hqtt/platform"
+)
+
+// DefaultWatchdogTimeout is the timeout used by NewWatchdog when the provided timeout is not positive.
+const DefaultWatchdogTimeout = time.Minute
+
+// Watchdog exposes the health of the application itself to Home Assistant as a binary_sensor with the problem device
+// class. Application loops call Feed periodically. If the watchdog is not fed within Timeout, the sensor flips to
+// hass.PowerStateOn (a problem is detected) so Home Assistant can alert on hung bridges. Add Component to the
+// components map of your Device to include it in discovery, and call Run to start monitoring.
+type Watchdog struct {
+	// The Component to include in Device discovery.
+	Component *Component[*platform.BinarySensor[any]]
+
+	// How long the watchdog may go without being fed before reporting a problem.
+	Timeout time.Duration
+
+	// The Clock used to track when the watchdog was fed and to schedule checks. If nil, clock.Real is used. Call Feed
+	// after changing the Clock so the watchdog is not considered hungry.
+	Clock clock.Clock
+
+	lastFed atomic.Int64
+
+	// The state last written successfully by Run. State.Get reports a state even if writing it failed, so it cannot be
+	// used to decide whether the state needs to be written again.
+	published    hass.PowerState
+	hasPublished bool
+
+	log *slog.Logger
+}
+
+// NewWatchdog constructs a Watchdog with the specified unique ID and topic prefix. The state and availability values
+// are retained and written to the "state" and "available" topics under the prefix. If timeout is not positive,
+// DefaultWatchdogTimeout is used.
+func NewWatchdog(uniqueID, topicPrefix string, timeout time.Duration) *Watchdog {
+	if timeout <= 0 {
+		timeout = DefaultWatchdogTimeout
+	}
+
+	sensor := platform.NewBinarySensor[any](
+		mqtt.NewValueWithOptions("state", hass.PowerStateMarshaler, mqtt.WriteOptions{Retain: true}),
+		nil,
+	)
+	sensor.DeviceClass = platform.BinarySensorDeviceClassProblem
+
+	wd := &Watchdog{
+		Component: &Component[*platform.BinarySensor[any]]{
+			Platform:    sensor,
+			TopicPrefix: topicPrefix,
+
+			Name:           "Watchdog",
+			EntityCategory: hass.EntityCategoryDiagnostic,
+
+			Availability: mqtt.NewValueWithOptions("available", hass.AvailabilityMarshaler, mqtt.WriteOptions{Retain: true}),
+
+			UniqueID: uniqueID,
+		},
+
+		Timeout: timeout,
+
+		log: log.ForComponent("watchdog").With(slog.String(log.UniqueIDKey, uniqueID)),
+	}
+
+	wd.Feed()
+	return wd
+}
+
+// Feed resets the watchdog timer. Call it from application loops to signal they are still making progress.
+func (wd *Watchdog) Feed() {
+	wd.lastFed.Store(clock.Or(wd.Clock).Now().UnixNano())
+}
+
+// Healthy returns true if the watchdog was fed within Timeout.
+func (wd *Watchdog) Healthy() bool {
+	return clock.Or(wd.Clock).Now().Sub(time.Unix(0, wd.lastFed.Load())) <= wd.Timeout
+}
+
+// Run marks the watchdog as available and periodically checks whether it has been fed, writing the result to the state
+// topic whenever it changes. It blocks until the provided context is done, marking the watchdog as unavailable before
+// returning the cause of the cancellation.
+func (wd *Watchdog) Run(ctx context.Context, w mqtt.Writer) error {
+	interval := max(wd.Timeout/4, time.Second)
+
+	if err := mqtt.Error(wd.Component.Availability.Write(ctx, w, wd.Component.TopicPrefix, hass.Available)); err != nil {
+		wd.log.With(log.Error(err)).WarnContext(ctx, "Failed to write watchdog availability")
+	}
+
+	t := clock.Or(wd.Clock).NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := wd.check(ctx, w); err != nil {
+			wd.log.With(log.Error(err)).WarnContext(ctx, "Failed to write watchdog state")
+		}
+
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), interval)
+			_ = mqtt.Error(wd.Component.Availability.Write(shutdownCtx, w, wd.Component.TopicPrefix, hass.Unavailable))
+			cancel()
+
+			return context.Cause(ctx)
+		case <-t.C():
+		}
+	}
+}
+
+func (wd *Watchdog) check(ctx context.Context, w mqtt.Writer) error {
+	state := hass.PowerStateOff
+	if !wd.Healthy() {
+		state = hass.PowerStateOn
+	}
+
+	if wd.hasPublished && wd.published == state {
+		return nil
+	}
+
+	if state == hass.PowerStateOn {
+		wd.log.With(slog.Duration("timeout", wd.Timeout)).WarnContext(ctx, "Watchdog was not fed in time")
+	}
+
+	if err := mqtt.Error(wd.Component.Platform.State.Write(ctx, w, wd.Component.TopicPrefix, state)); err != nil {
+		return err
+	}
+
+	wd.published, wd.hasPublished = state, true
+	return nil
+}
@@ -0,0 +1,94 @@
+package hqtt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nlowe/hqtt/hass"
+	"github.com/nlowe/hqtt/hqtttest"
+	"github.com/nlowe/hqtt/mqtt"
+)
+
+type recordingWriter map[string]string
+
+func (r recordingWriter) WriteTopic(_ context.Context, topic string, _ mqtt.WriteOptions, value []byte) error {
+	r[topic] = string(value)
+	return nil
+}
+
+func TestWatchdog(t *testing.T) {
+	w := recordingWriter{}
+	sut := NewWatchdog("watchdog", "app", time.Minute)
+
+	require.True(t, sut.Healthy())
+	require.NoError(t, sut.check(t.Context(), w))
+	assert.Equal(t, string(hass.PowerStateOff), w["app/state"])
+
+	sut.lastFed.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	require.False(t, sut.Healthy())
+	require.NoError(t, sut.check(t.Context(), w))
+	assert.Equal(t, string(hass.PowerStateOn), w["app/state"])
+
+	sut.Feed()
+	require.NoError(t, sut.check(t.Context(), w))
+	assert.Equal(t, string(hass.PowerStateOff), w["app/state"])
+}
+
+func TestWatchdog_WriteFailure(t *testing.T) {
+	boom := errors.New("boom")
+	w := &failingWriter{fail: map[string]error{"app/state": boom}}
+	sut := NewWatchdog("watchdog", "app", time.Minute)
+
+	require.ErrorIs(t, sut.check(t.Context(), w), boom)
+
+	// The state was never published, so it should be written again on the next check
+	delete(w.fail, "app/state")
+	require.NoError(t, sut.check(t.Context(), w))
+	assert.Equal(t, []string{"app/state", "app/state"}, w.topics)
+
+	require.NoError(t, sut.check(t.Context(), w))
+	assert.Len(t, w.topics, 2, "an unchanged state should not be written again")
+}
+
+func TestWatchdog_Run(t *testing.T) {
+	c := hqtttest.NewClock(time.Now())
+	w := &hqtttest.Writer{}
+
+	sut := NewWatchdog("watchdog", "app", time.Minute)
+	sut.Clock = c
+	sut.Feed()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- sut.Run(ctx, w)
+	}()
+
+	require.NoError(t, c.BlockUntil(t.Context(), 1))
+	requireState := func(want hass.PowerState) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			m, ok := w.LastWrite("app/state")
+			return ok && string(m.Payload) == string(want)
+		}, time.Second, time.Millisecond)
+	}
+
+	requireState(hass.PowerStateOff)
+
+	c.Advance(time.Minute + 15*time.Second)
+	requireState(hass.PowerStateOn)
+
+	sut.Feed()
+	c.Advance(15 * time.Second)
+	requireState(hass.PowerStateOff)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	w.AssertPublished(t, "app/available", []byte(hass.Unavailable))
+}